@@ -0,0 +1,65 @@
+package core
+
+import (
+	"sort"
+)
+
+const achievementsFile = "achievements.jsonl"
+
+// AchievementKind names something worth celebrating
+type AchievementKind string
+
+const (
+	AchievementAllTimeBest  AchievementKind = "all_time_best"
+	AchievementPersonalBest AchievementKind = "personal_best"
+)
+
+// Achievement is one earned record, kept in an append-only log
+type Achievement struct {
+	Kind   AchievementKind `json:"kind"`
+	Player string          `json:"player"`
+	Score  int             `json:"score"`
+	Date   string          `json:"date"` // 2006-01-02
+}
+
+// NewBest reports whether score beats the leaderboard (top, best first) or
+// the player's personalBest from before the game. The first scoring game on
+// an empty board counts as an all-time best.
+func NewBest(score, personalBest int, top []ScoreEntry) (AchievementKind, bool) {
+	switch {
+	case score <= 0:
+		return "", false
+	case len(top) == 0 || score > top[0].Score:
+		return AchievementAllTimeBest, true
+	case score > personalBest:
+		return AchievementPersonalBest, true
+	}
+	return "", false
+}
+
+// Podium places entry among the leaderboard's top n, returning the new top n
+// and entry's index in it, or -1 if it didn't make it. Ties go to the
+// earlier score.
+func Podium(top []ScoreEntry, entry ScoreEntry, n int) ([]ScoreEntry, int) {
+	place := sort.Search(len(top), func(i int) bool { return top[i].Score < entry.Score })
+	ranked := append(append(append([]ScoreEntry(nil), top[:place]...), entry), top[place:]...)
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	if place >= n {
+		place = -1
+	}
+	return ranked, place
+}
+
+// RecordAchievement appends a to the achievements log
+func (dm *DataManager) RecordAchievement(a Achievement) error {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	line, err := encodeRecord(achievementsFile, a)
+	if err != nil {
+		return err
+	}
+	return dm.storage().Append(achievementsFile, append(line, '\n'))
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const adsbdbCallsignURL = "https://api.adsbdb.com/v0/callsign/%s"
+
+// adsbdbAirport is an airport as adsbdb.com describes it
+type adsbdbAirport struct {
+	Name         string  `json:"name"`
+	Municipality string  `json:"municipality"`
+	CountryISO   string  `json:"country_iso_name"`
+	CountryName  string  `json:"country_name"`
+	ICAO         string  `json:"icao_code"`
+	Lat          float64 `json:"latitude"`
+	Lon          float64 `json:"longitude"`
+}
+
+// displayName renders the airport as "City, Country" like the other resolvers
+func (a adsbdbAirport) displayName() string {
+	name := a.Municipality
+	if name == "" {
+		name = a.Name
+	}
+	if name == "" {
+		return ""
+	}
+	if c, ok := countryNames[a.CountryISO]; ok {
+		return name + ", " + c
+	}
+	if a.CountryName != "" {
+		return name + ", " + a.CountryName
+	}
+	return name
+}
+
+// AdsbdbResolver looks up routes in adsbdb.com's free, keyless API. Like
+// RouteResolver it only knows the route, not the aircraft, so Model is empty.
+type AdsbdbResolver struct {
+	client *http.Client
+}
+
+func NewAdsbdbResolver() *AdsbdbResolver {
+	return &AdsbdbResolver{client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// FetchFlightDetails returns the origin and destination of callsign's route
+func (r *AdsbdbResolver) FetchFlightDetails(ctx context.Context, callsign string) (*ResolvedDetails, error) {
+	apiURL := fmt.Sprintf(adsbdbCallsignURL, url.PathEscape(strings.TrimSpace(callsign)))
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Unknown callsigns come back as 404 with {"response": "unknown callsign"}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrRouteUnknown
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("adsbdb: status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Response struct {
+			FlightRoute *struct {
+				Airline *struct {
+					Name string `json:"name"`
+				} `json:"airline"`
+				Origin      adsbdbAirport `json:"origin"`
+				Destination adsbdbAirport `json:"destination"`
+			} `json:"flightroute"`
+		} `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("adsbdb: %w", err)
+	}
+	route := body.Response.FlightRoute
+	if route == nil {
+		return nil, ErrRouteUnknown
+	}
+	origin, dest := route.Origin.displayName(), route.Destination.displayName()
+	if origin == "" || dest == "" {
+		return nil, ErrRouteUnknown
+	}
+	d := &ResolvedDetails{
+		Destination:     dest,
+		RealDestination: dest,
+		Origin:          origin,
+		DestLat:         route.Destination.Lat,
+		DestLon:         route.Destination.Lon,
+		OrigLat:         route.Origin.Lat,
+		OrigLon:         route.Origin.Lon,
+	}
+	if route.Airline != nil {
+		d.Airline = route.Airline.Name
+	}
+	return d, nil
+}
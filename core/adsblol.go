@@ -0,0 +1,211 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	adsbLolURL      = "https://api.adsb.lol/v2/point/%f/%f/%d"
+	adsbLolMaxRange = 250 // nautical miles, API limit
+)
+
+// AdsbLolClient fetches flights from the free adsb.lol community API
+type AdsbLolClient struct {
+	httpClient *http.Client
+	cache      fetchCache
+	mu         sync.Mutex
+
+	// Guarded by limitMu rather than mu so the UI can read the throttle
+	// while a fetch holds mu waiting on the API
+	limitMu        sync.Mutex
+	throttledUntil time.Time
+}
+
+func NewAdsbLolClient() *AdsbLolClient {
+	return &AdsbLolClient{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *AdsbLolClient) Name() string {
+	return "adsblol"
+}
+
+func (c *AdsbLolClient) RateLimitInfo() RateLimitInfo {
+	c.limitMu.Lock()
+	defer c.limitMu.Unlock()
+	return RateLimitInfo{Remaining: -1, ThrottledUntil: c.throttledUntil}
+}
+
+// readsbAircraft is one entry of a readsb/dump1090 style aircraft list,
+// as served by adsb.lol ("ac") and local receivers ("aircraft")
+type readsbAircraft struct {
+	Hex      string          `json:"hex"`
+	Flight   string          `json:"flight"`
+	Lat      *float64        `json:"lat"`
+	Lon      *float64        `json:"lon"`
+	AltBaro  json.RawMessage `json:"alt_baro"` // feet, or the string "ground"
+	GS       *float64        `json:"gs"`       // knots
+	Track    *float64        `json:"track"`
+	BaroRate *float64        `json:"baro_rate"` // ft/min
+	GeomRate *float64        `json:"geom_rate"` // ft/min, preferred when present
+	AltGeom  *float64        `json:"alt_geom"`  // feet
+	Squawk   string          `json:"squawk"`
+	Category string          `json:"category"` // e.g. "A3"
+	SeenPos  *float64        `json:"seen_pos"` // seconds since the position was received
+}
+
+func (c *AdsbLolClient) FetchFlights(ctx context.Context, centerLat, centerLon, radiusDeg float64) ([]Flight, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := boxKey(centerLat, centerLon, radiusDeg)
+	if cached, ok := c.cache.get(key, cacheDuration); ok {
+		return cached, nil
+	}
+
+	// One degree of latitude is 60 nautical miles
+	radiusNm := int(math.Ceil(radiusDeg * 60))
+	if radiusNm > adsbLolMaxRange {
+		radiusNm = adsbLolMaxRange
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf(adsbLolURL, centerLat, centerLon, radiusNm), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		c.limitMu.Lock()
+		c.throttledUntil = time.Now().Add(60 * time.Second)
+		c.limitMu.Unlock()
+		return nil, fmt.Errorf("rate limit exceeded (429)")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Aircraft []readsbAircraft `json:"ac"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	flights := flightsFromReadsb(result.Aircraft, centerLat, centerLon, radiusDeg, c.Name())
+
+	c.cache.put(key, flights)
+
+	return flights, nil
+}
+
+// flightsFromReadsb converts positioned aircraft inside the radius box into Flights
+func flightsFromReadsb(aircraft []readsbAircraft, centerLat, centerLon, radiusDeg float64, source string) []Flight {
+	now := time.Now()
+	var flights []Flight
+	for _, a := range aircraft {
+		if a.Lat == nil || a.Lon == nil {
+			continue
+		}
+		// Point queries are circles; keep the same square box OpenSky returns
+		if math.Abs(*a.Lat-centerLat) > radiusDeg || math.Abs(*a.Lon-centerLon) > radiusDeg {
+			continue
+		}
+
+		callsign := strings.TrimSpace(a.Flight)
+		if callsign == "" {
+			callsign = "N/A"
+		}
+
+		altFt, onGround := 0, false
+		if string(a.AltBaro) == `"ground"` {
+			onGround = true
+		} else if len(a.AltBaro) > 0 {
+			var alt float64
+			if err := json.Unmarshal(a.AltBaro, &alt); err == nil {
+				altFt = int(alt)
+			}
+		}
+
+		velKts, heading := 0, 0.0
+		if a.GS != nil {
+			velKts = int(*a.GS)
+		}
+		if a.Track != nil {
+			heading = *a.Track
+		}
+		vertRate, geoAlt := 0, 0
+		if a.GeomRate != nil {
+			vertRate = int(*a.GeomRate)
+		} else if a.BaroRate != nil {
+			vertRate = int(*a.BaroRate)
+		}
+		if a.AltGeom != nil {
+			geoAlt = int(*a.AltGeom)
+		}
+		var posAt time.Time
+		if a.SeenPos != nil {
+			posAt = now.Add(-time.Duration(*a.SeenPos * float64(time.Second)))
+		}
+
+		flights = append(flights, Flight{
+			Icao24:      strings.ToLower(strings.TrimPrefix(a.Hex, "~")),
+			Callsign:    callsign,
+			Lon:         *a.Lon,
+			Lat:         *a.Lat,
+			VelocityKts: velKts,
+			Heading:     heading,
+			AltitudeFt:  altFt,
+			OnGround:    onGround,
+			Category:    emitterCategory(a.Category),
+			Source:      source,
+			PositionAt:  posAt,
+
+			VerticalRateFpm: vertRate,
+			GeoAltitudeFt:   geoAlt,
+
+			Squawk:    a.Squawk,
+			Emergency: SquawkEmergency(a.Squawk),
+		})
+	}
+	return flights
+}
+
+// emitterCategory maps an ADS-B emitter category ("A3", "B1", ...) onto the
+// same names OpenSky's numeric category uses
+func emitterCategory(code string) string {
+	if len(code) != 2 || code[1] < '0' || code[1] > '7' {
+		return "Unknown"
+	}
+	n := int(code[1] - '0')
+	var idx int
+	switch code[0] {
+	case 'A':
+		idx = n + 1 // A1 Light = 2
+	case 'B':
+		idx = n + 8 // B1 Glider = 9
+	case 'C':
+		idx = n + 15 // C1 Emergency = 16
+	default:
+		return "Unknown"
+	}
+	if n == 0 {
+		return categoryMap[0]
+	}
+	if val, ok := categoryMap[idx]; ok {
+		return val
+	}
+	return "Unknown"
+}
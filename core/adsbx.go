@@ -0,0 +1,143 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	adsbxHost = "adsbexchange-com1.p.rapidapi.com"
+	adsbxURL  = "https://" + adsbxHost + "/v2/lat/%f/lon/%f/dist/%d/"
+	// RapidAPI plans are metered per request; poll more gently than OpenSky
+	adsbxPollInterval = 15 * time.Second
+	adsbxMaxRange     = 250 // nautical miles, API limit
+)
+
+// AdsbxClient fetches flights from the ADS-B Exchange API on RapidAPI.
+// Requires ADSBX_API_KEY.
+type AdsbxClient struct {
+	httpClient *http.Client
+	apiKey     string
+	cache      fetchCache
+	mu         sync.Mutex // guards cache only; never held across a request
+
+	// Guarded by limitMu so the UI can read the quota every frame while a
+	// fetch is waiting on the API
+	limitMu        sync.Mutex
+	remaining      int
+	throttledUntil time.Time
+}
+
+func NewAdsbxClient() (*AdsbxClient, error) {
+	key := os.Getenv("ADSBX_API_KEY")
+	if key == "" {
+		return nil, fmt.Errorf("adsbx provider needs ADSBX_API_KEY")
+	}
+	return &AdsbxClient{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		apiKey:     key,
+		remaining:  -1,
+	}, nil
+}
+
+func (c *AdsbxClient) Name() string {
+	return "adsbx"
+}
+
+func (c *AdsbxClient) RateLimitInfo() RateLimitInfo {
+	c.limitMu.Lock()
+	defer c.limitMu.Unlock()
+	return RateLimitInfo{Remaining: c.remaining, ThrottledUntil: c.throttledUntil}
+}
+
+func (c *AdsbxClient) PollInterval() time.Duration {
+	return adsbxPollInterval
+}
+
+func (c *AdsbxClient) FetchFlights(ctx context.Context, centerLat, centerLon, radiusDeg float64) ([]Flight, error) {
+	key := boxKey(centerLat, centerLon, radiusDeg)
+	c.mu.Lock()
+	cached, ok := c.cache.get(key, adsbxPollInterval)
+	c.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+	if until := c.RateLimitInfo().ThrottledUntil; time.Now().Before(until) {
+		return nil, fmt.Errorf("rate limited until %s", until.Format("15:04:05"))
+	}
+
+	radiusNm := int(math.Ceil(radiusDeg * 60))
+	if radiusNm > adsbxMaxRange {
+		radiusNm = adsbxMaxRange
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf(adsbxURL, centerLat, centerLon, radiusNm), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-RapidAPI-Key", c.apiKey)
+	req.Header.Set("X-RapidAPI-Host", adsbxHost)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	c.updateRateLimit(resp)
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		c.limitMu.Lock()
+		if c.throttledUntil.Before(time.Now()) {
+			c.throttledUntil = time.Now().Add(60 * time.Second)
+		}
+		c.limitMu.Unlock()
+		return nil, fmt.Errorf("rate limit exceeded (429)")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Aircraft []readsbAircraft `json:"ac"`
+		Msg      string           `json:"msg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if result.Msg != "" && result.Msg != "No error" {
+		return nil, fmt.Errorf("adsbx: %s", result.Msg)
+	}
+
+	flights := flightsFromReadsb(result.Aircraft, centerLat, centerLon, radiusDeg, c.Name())
+
+	c.mu.Lock()
+	c.cache.put(key, flights)
+	c.mu.Unlock()
+
+	return flights, nil
+}
+
+// updateRateLimit reads RapidAPI's quota headers. When the quota is used up
+// we stop polling until it resets rather than burning paid overage requests.
+func (c *AdsbxClient) updateRateLimit(resp *http.Response) {
+	if v, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Requests-Remaining")); err == nil {
+		c.limitMu.Lock()
+		defer c.limitMu.Unlock()
+		c.remaining = v
+		if v <= 0 {
+			reset := time.Hour
+			if secs, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Requests-Reset")); err == nil {
+				reset = time.Duration(secs) * time.Second
+			}
+			c.throttledUntil = time.Now().Add(reset)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package core
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	aircraftDBURL  = "https://opensky-network.org/datasets/metadata/aircraftDatabase.csv"
+	aircraftDBFile = "aircraftDatabase.csv"
+
+	// The database is rebuilt monthly and is around 100 MB, so a cached copy
+	// is only downloaded again once it is this old
+	aircraftDBMaxAge = 30 * 24 * time.Hour
+)
+
+// AircraftInfo is what OpenSky's aircraft database knows about an airframe
+type AircraftInfo struct {
+	Registration string // e.g. "OH-LZA"
+	TypeCode     string // ICAO type designator, e.g. "A321"
+	Operator     string
+	FirstFlight  time.Time // or when it was built, zero if unknown
+}
+
+// AircraftDB enriches flights with registration, type and operator by
+// icao24, from a cached copy of OpenSky's aircraft database
+type AircraftDB struct {
+	client *http.Client
+
+	mu    sync.Mutex
+	byHex map[string]AircraftInfo
+}
+
+func NewAircraftDB() *AircraftDB {
+	return &AircraftDB{
+		client: &http.Client{Timeout: 5 * time.Minute},
+		byHex:  make(map[string]AircraftInfo),
+	}
+}
+
+// AircraftDBPath is AIRCRAFT_DB if set, otherwise aircraftDatabase.csv in
+// the data dir
+func AircraftDBPath() string {
+	if p := os.Getenv("AIRCRAFT_DB"); p != "" {
+		return p
+	}
+	return dataPath(aircraftDBFile)
+}
+
+// Download fetches the database to path unless a copy younger than
+// aircraftDBMaxAge is already there. It reports whether it downloaded. The
+// file is written beside path and renamed into place, so an interrupted
+// download never replaces a good copy.
+func (db *AircraftDB) Download(ctx context.Context, path string) (bool, error) {
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < aircraftDBMaxAge {
+		return false, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", aircraftDBURL, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := db.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("aircraft database: status %d", resp.StatusCode)
+	}
+
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return false, err
+	}
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return false, err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return false, err
+	}
+	return true, os.Rename(tmp, path)
+}
+
+// Load reads the database CSV, replacing anything loaded before. Columns are
+// found by header name ("icao24", "registration", "typecode", "operator",
+// "firstflightdate", "built"), since OpenSky has added columns over the years. Returns the number of
+// aircraft with anything worth knowing.
+func (db *AircraftDB) Load(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.ReuseRecord = true
+	header, err := r.Read()
+	if err != nil {
+		return 0, err
+	}
+	col := func(name string) int {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				return i
+			}
+		}
+		return -1
+	}
+	icaoCol, regCol, typeCol, opCol := col("icao24"), col("registration"), col("typecode"), col("operator")
+	firstFlightCol, builtCol := col("firstflightdate"), col("built")
+	if icaoCol < 0 {
+		return 0, fmt.Errorf("%s: no icao24 column in header", path)
+	}
+
+	field := func(rec []string, i int) string {
+		if i < 0 || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	byHex := make(map[string]AircraftInfo)
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		hexID := strings.ToLower(field(rec, icaoCol))
+		if !isHexCode(hexID) {
+			continue
+		}
+		info := AircraftInfo{
+			Registration: field(rec, regCol),
+			TypeCode:     strings.ToUpper(field(rec, typeCol)),
+			Operator:     field(rec, opCol),
+			FirstFlight:  aircraftDate(field(rec, firstFlightCol)),
+		}
+		if info.FirstFlight.IsZero() {
+			info.FirstFlight = aircraftDate(field(rec, builtCol))
+		}
+		if info == (AircraftInfo{}) {
+			continue // most rows are bare addresses
+		}
+		byHex[hexID] = info
+	}
+
+	db.mu.Lock()
+	db.byHex = byHex
+	db.mu.Unlock()
+	return len(byHex), nil
+}
+
+// Len is the number of aircraft loaded
+func (db *AircraftDB) Len() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return len(db.byHex)
+}
+
+// Lookup returns what is known about an aircraft
+func (db *AircraftDB) Lookup(icao24 string) (AircraftInfo, bool) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	info, ok := db.byHex[strings.ToLower(icao24)]
+	return info, ok
+}
+
+// Enrich fills in registration, type and operator on flights the database
+// knows, keeping anything the provider already reported
+func (db *AircraftDB) Enrich(flights []Flight) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.byHex) == 0 {
+		return
+	}
+	for i := range flights {
+		f := &flights[i]
+		info, ok := db.byHex[f.Icao24]
+		if !ok {
+			continue
+		}
+		if f.Registration == "" {
+			f.Registration = info.Registration
+		}
+		if f.TypeCode == "" {
+			f.TypeCode = info.TypeCode
+		}
+		if f.Operator == "" {
+			f.Operator = info.Operator
+		}
+		if f.FirstFlight.IsZero() {
+			f.FirstFlight = info.FirstFlight
+		}
+	}
+}
+
+// aircraftDate reads a date column, a full date or just a year; anything
+// else is unknown
+func aircraftDate(s string) time.Time {
+	for _, layout := range []string{"2006-01-02", "2006"} {
+		if t, err := time.Parse(layout, s); err == nil && t.Year() > 1900 {
+			return t
+		}
+	}
+	return time.Time{}
+}
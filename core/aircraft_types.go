@@ -0,0 +1,106 @@
+package core
+
+import (
+	"math/rand"
+	"strings"
+	"unicode"
+)
+
+// SizeClass groups aircraft types that are hard to tell apart at a glance
+type SizeClass int
+
+const (
+	ClassLight SizeClass = iota
+	ClassRegional
+	ClassNarrowBody
+	ClassWideBody
+)
+
+// AircraftType is one answer of the "name the type" round
+type AircraftType struct {
+	Name  string
+	Match []string // designators to look for in a model string
+	Class SizeClass
+}
+
+// Types common enough to be worth quizzing on. Match entries are checked in
+// order, so longer designators must come before their prefixes.
+var aircraftTypes = []AircraftType{
+	{"Cessna 172", []string{"172"}, ClassLight},
+	{"Piper PA-28", []string{"PA-28", "PA28"}, ClassLight},
+	{"Pilatus PC-12", []string{"PC-12", "PC12"}, ClassLight},
+	{"Beechcraft King Air", []string{"King Air", "B350", "BE20"}, ClassLight},
+	{"ATR 72", []string{"ATR 72", "ATR72", "AT76", "AT75"}, ClassRegional},
+	{"ATR 42", []string{"ATR 42", "ATR42", "AT45"}, ClassRegional},
+	{"Dash 8", []string{"Dash 8", "DHC-8", "DH8"}, ClassRegional},
+	{"Embraer E175", []string{"E175", "ERJ-175", "ERJ 175", "170-200"}, ClassRegional},
+	{"Embraer E190", []string{"E190", "E195", "ERJ-190", "ERJ 190", "190-"}, ClassRegional},
+	{"Bombardier CRJ900", []string{"CRJ"}, ClassRegional},
+	{"Airbus A220", []string{"A220", "BCS3", "BCS1", "CS300"}, ClassNarrowBody},
+	{"Airbus A319", []string{"A319"}, ClassNarrowBody},
+	{"Airbus A320", []string{"A320"}, ClassNarrowBody},
+	{"Airbus A321", []string{"A321"}, ClassNarrowBody},
+	{"Boeing 737 MAX", []string{"737 MAX", "737-8", "737-9", "B38M", "B39M"}, ClassNarrowBody},
+	{"Boeing 737", []string{"737"}, ClassNarrowBody},
+	{"Boeing 757", []string{"757"}, ClassNarrowBody},
+	{"Airbus A330", []string{"A330", "A332", "A333", "A339"}, ClassWideBody},
+	{"Airbus A350", []string{"A350", "A359", "A35K"}, ClassWideBody},
+	{"Airbus A380", []string{"A380", "A388"}, ClassWideBody},
+	{"Boeing 767", []string{"767"}, ClassWideBody},
+	{"Boeing 777", []string{"777"}, ClassWideBody},
+	{"Boeing 787", []string{"787"}, ClassWideBody},
+	{"Boeing 747", []string{"747"}, ClassWideBody},
+}
+
+// IdentifyType maps a free-text model (e.g. "Airbus A320-251N") onto a quiz type
+func IdentifyType(model string) (AircraftType, bool) {
+	upper := strings.ToUpper(model)
+	for _, t := range aircraftTypes {
+		for _, m := range t.Match {
+			if strings.Contains(upper, strings.ToUpper(m)) {
+				return t, true
+			}
+		}
+	}
+	return AircraftType{}, false
+}
+
+// TypeOptions returns n shuffled answers: the correct type plus distractors
+// of the same size class, topped up from neighbouring classes if needed
+func TypeOptions(correct AircraftType, n int) []string {
+	opts := []string{correct.Name}
+	for dist := 0; dist <= int(ClassWideBody) && len(opts) < n; dist++ {
+		var pool []string
+		for _, t := range aircraftTypes {
+			d := int(t.Class) - int(correct.Class)
+			if (d == dist || d == -dist) && t.Name != correct.Name {
+				pool = append(pool, t.Name)
+			}
+		}
+		rand.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+		for _, name := range pool {
+			if len(opts) >= n {
+				break
+			}
+			opts = append(opts, name)
+		}
+	}
+	rand.Shuffle(len(opts), func(i, j int) { opts[i], opts[j] = opts[j], opts[i] })
+	return opts
+}
+
+// MaskModel hides most of a model string as a hint: the manufacturer and the
+// first character of each later word stay, e.g. "Airbus A320-251N" -> "Airbus A___-____"
+func MaskModel(model string) string {
+	words := strings.Fields(model)
+	for i := 1; i < len(words); i++ {
+		runes := []rune(words[i])
+		for j := 1; j < len(runes); j++ {
+			if unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) {
+				runes[j] = '_'
+			}
+		}
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// An airframe that first flew less than this long ago is brand new
+const newAirframeAge = 365 * 24 * time.Hour
+
+// WithAirframe is d with the registration and first flight date filled in
+// from f, the aircraft database's view of the airframe, where the resolver
+// didn't know them. d itself is left alone: it may be a cached answer
+// shared by every flight with the callsign.
+func (d *ResolvedDetails) WithAirframe(f Flight) *ResolvedDetails {
+	if d == nil {
+		return nil
+	}
+	needReg := d.Registration == "" && f.Registration != ""
+	needAge := d.FirstFlight.IsZero() && !f.FirstFlight.IsZero()
+	if !needReg && !needAge {
+		return d
+	}
+	out := *d
+	if needReg {
+		out.Registration = f.Registration
+	}
+	if needAge {
+		out.FirstFlight = f.FirstFlight
+	}
+	return &out
+}
+
+// NewAirframe reports whether the airframe first flew within the last year
+func (d *ResolvedDetails) NewAirframe(now time.Time) bool {
+	return d != nil && !d.FirstFlight.IsZero() && now.Sub(d.FirstFlight) < newAirframeAge
+}
+
+// AgeLabel describes the airframe's age for the info panel, e.g. "12 yrs
+// old (2013)" or "Brand new (Mar 2026)"; "" when unknown
+func (d *ResolvedDetails) AgeLabel(now time.Time) string {
+	if d == nil || d.FirstFlight.IsZero() {
+		return ""
+	}
+	first := d.FirstFlight
+	when := fmt.Sprint(first.Year())
+	// A bare year from the database reads as the 1st of January
+	if first.Month() != time.January || first.Day() != 1 {
+		when = first.Format("Jan 2006")
+	}
+	if d.NewAirframe(now) {
+		return "Brand new (" + when + ")"
+	}
+	years := int(now.Sub(first).Hours() / (24 * 365.25))
+	unit := "yrs"
+	if years == 1 {
+		unit = "yr"
+	}
+	return fmt.Sprintf("%d %s old (%s)", years, unit, when)
+}
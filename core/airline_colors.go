@@ -0,0 +1,106 @@
+package core
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+// Colours for carriers common around the home airport, as 0xRRGGBBAA. They
+// are picked to be told apart on the dark map rather than to match liveries.
+var airlineColors = map[string]uint32{
+	"FIN": 0x60a5faff, // Finnair
+	"SAS": 0x3b82f6ff, // SAS
+	"NSZ": 0xef4444ff, // Norwegian
+	"NOZ": 0xef4444ff,
+	"DLH": 0xfacc15ff, // Lufthansa
+	"KLM": 0x22d3eeff, // KLM
+	"AFR": 0x6366f1ff, // Air France
+	"BAW": 0xe2e8f0ff, // British Airways
+	"RYR": 0xfde047ff, // Ryanair
+	"EZY": 0xfb923cff, // easyJet
+	"AEE": 0x0ea5e9ff, // Aegean
+	"THY": 0xdc2626ff, // Turkish Airlines
+	"UAE": 0xf59e0bff, // Emirates
+	"QTR": 0x9f1239ff, // Qatar Airways
+	"BTI": 0x84cc16ff, // airBaltic
+	"WZZ": 0xd946efff, // Wizz Air
+	"TVS": 0x10b981ff, // Smartwings
+	"FDX": 0x7c3aedff, // FedEx
+	"UPS": 0xa16207ff, // UPS
+}
+
+// Colours handed out by hash to carriers missing from airlineColors
+var airlinePalette = []uint32{
+	0xf87171ff, 0xfb923cff, 0xfbbf24ff, 0xa3e635ff, 0x34d399ff, 0x2dd4bfff,
+	0x38bdf8ff, 0x818cf8ff, 0xc084fcff, 0xf472b6ff, 0xfda4afff, 0x94a3b8ff,
+}
+
+// AirlineCode is the ICAO airline designator a callsign starts with, e.g.
+// "FIN" for "FIN7LV", or "" for registrations and other non-airline
+// callsigns
+func AirlineCode(callsign string) string {
+	if len(callsign) < 4 {
+		return ""
+	}
+	for i := 0; i < 3; i++ {
+		if callsign[i] < 'A' || callsign[i] > 'Z' {
+			return ""
+		}
+	}
+	if callsign[3] < '0' || callsign[3] > '9' {
+		return ""
+	}
+	return callsign[:3]
+}
+
+// AirlineColor is the colour a flight's carrier is drawn in. Known carriers
+// get their own colour; any other airline gets a stable one from the
+// palette, the same on every run.
+func AirlineColor(callsign string) (uint32, bool) {
+	code := AirlineCode(callsign)
+	if code == "" {
+		return 0, false
+	}
+	return airlineCodeColor(code), true
+}
+
+func airlineCodeColor(code string) uint32 {
+	if c, ok := airlineColors[code]; ok {
+		return c
+	}
+	h := fnv.New32a()
+	h.Write([]byte(code))
+	return airlinePalette[h.Sum32()%uint32(len(airlinePalette))]
+}
+
+// LegendEntry is one carrier in the airline colour legend
+type LegendEntry struct {
+	Code  string
+	Color uint32
+	Count int
+}
+
+// AirlineLegend lists the n carriers with most flights in flights, busiest
+// first
+func AirlineLegend(flights []Flight, n int) []LegendEntry {
+	counts := make(map[string]int)
+	for _, f := range flights {
+		if code := AirlineCode(f.Callsign); code != "" {
+			counts[code]++
+		}
+	}
+	legend := make([]LegendEntry, 0, len(counts))
+	for code, count := range counts {
+		legend = append(legend, LegendEntry{Code: code, Color: airlineCodeColor(code), Count: count})
+	}
+	sort.Slice(legend, func(i, j int) bool {
+		if legend[i].Count != legend[j].Count {
+			return legend[i].Count > legend[j].Count
+		}
+		return legend[i].Code < legend[j].Code
+	})
+	if len(legend) > n {
+		legend = legend[:n]
+	}
+	return legend
+}
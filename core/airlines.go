@@ -0,0 +1,161 @@
+package core
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// Airline names by ICAO designator, the three letters an airline callsign
+// starts with. Weighted towards carriers seen over Europe; anything missing
+// is left to whatever name the route resolvers report.
+var airlineNames = map[string]string{
+	"AAL": "American Airlines",
+	"ABR": "ASL Airlines Ireland",
+	"ABY": "Air Arabia",
+	"ACA": "Air Canada",
+	"AEA": "Air Europa",
+	"AEE": "Aegean Airlines",
+	"AFL": "Aeroflot",
+	"AFR": "Air France",
+	"AHY": "Azerbaijan Airlines",
+	"AIC": "Air India",
+	"AMX": "Aeromexico",
+	"ANA": "All Nippon Airways",
+	"ANZ": "Air New Zealand",
+	"ASA": "Alaska Airlines",
+	"AUA": "Austrian Airlines",
+	"AVA": "Avianca",
+	"BAW": "British Airways",
+	"BCS": "European Air Transport",
+	"BEL": "Brussels Airlines",
+	"BOX": "AeroLogic",
+	"BTI": "airBaltic",
+	"CAL": "China Airlines",
+	"CCA": "Air China",
+	"CES": "China Eastern",
+	"CFG": "Condor",
+	"CHH": "Hainan Airlines",
+	"CLH": "Lufthansa CityLine",
+	"CLX": "Cargolux",
+	"CPA": "Cathay Pacific",
+	"CSN": "China Southern",
+	"CTN": "Croatia Airlines",
+	"DAL": "Delta Air Lines",
+	"DLA": "Air Dolomiti",
+	"DLH": "Lufthansa",
+	"EIN": "Aer Lingus",
+	"EJU": "easyJet Europe",
+	"ELY": "El Al",
+	"ENT": "Enter Air",
+	"ETD": "Etihad Airways",
+	"ETH": "Ethiopian Airlines",
+	"EVA": "EVA Air",
+	"EWG": "Eurowings",
+	"EXS": "Jet2",
+	"EZS": "easyJet Switzerland",
+	"EZY": "easyJet",
+	"FDB": "flydubai",
+	"FDX": "FedEx",
+	"FIN": "Finnair",
+	"FLI": "Atlantic Airways",
+	"GEC": "Lufthansa Cargo",
+	"GFA": "Gulf Air",
+	"GTI": "Atlas Air",
+	"IBE": "Iberia",
+	"ICE": "Icelandair",
+	"IGO": "IndiGo",
+	"ITY": "ITA Airways",
+	"JAL": "Japan Airlines",
+	"JBU": "JetBlue",
+	"KAC": "Kuwait Airways",
+	"KAL": "Korean Air",
+	"KLC": "KLM Cityhopper",
+	"KLM": "KLM",
+	"KQA": "Kenya Airways",
+	"KZR": "Air Astana",
+	"LGL": "Luxair",
+	"LOT": "LOT Polish Airlines",
+	"MAS": "Malaysia Airlines",
+	"MEA": "Middle East Airlines",
+	"MSR": "EgyptAir",
+	"NAX": "Norwegian",
+	"NJE": "NetJets Europe",
+	"NOZ": "Norwegian",
+	"NSZ": "Norwegian",
+	"OAW": "Helvetic Airways",
+	"OMA": "Oman Air",
+	"PGT": "Pegasus Airlines",
+	"QFA": "Qantas",
+	"QTR": "Qatar Airways",
+	"RAM": "Royal Air Maroc",
+	"RJA": "Royal Jordanian",
+	"RUK": "Ryanair UK",
+	"RYR": "Ryanair",
+	"SAA": "South African Airways",
+	"SAS": "SAS",
+	"SIA": "Singapore Airlines",
+	"SVA": "Saudia",
+	"SWA": "Southwest Airlines",
+	"SWR": "Swiss",
+	"SXS": "SunExpress",
+	"SZS": "SAS Connect",
+	"TAP": "TAP Air Portugal",
+	"THA": "Thai Airways",
+	"THY": "Turkish Airlines",
+	"TOM": "TUI Airways",
+	"TRA": "Transavia",
+	"TSC": "Air Transat",
+	"TUI": "TUIfly",
+	"TVF": "Transavia France",
+	"TVS": "Smartwings",
+	"UAE": "Emirates",
+	"UAL": "United Airlines",
+	"UPS": "UPS",
+	"UZB": "Uzbekistan Airways",
+	"VIR": "Virgin Atlantic",
+	"VLG": "Vueling",
+	"WIF": "Wideroe",
+	"WJA": "WestJet",
+	"WMT": "Wizz Air Malta",
+	"WUK": "Wizz Air UK",
+	"WZZ": "Wizz Air",
+}
+
+// AirlineName is the carrier a callsign belongs to, e.g. "Finnair" for
+// "FIN123", or "" when the prefix isn't in the table
+func AirlineName(callsign string) string {
+	return airlineNames[AirlineCode(callsign)]
+}
+
+// AirlineOptions returns n airline names including correct, shuffled.
+// Better players get the carriers common around here as distractors rather
+// than any airline in the world.
+func AirlineOptions(correct string, n int, level float64) []string {
+	pool := func(local bool) []string {
+		var names []string
+		for code, name := range airlineNames {
+			if _, ok := airlineColors[code]; ok == local {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names) // map order would make the shuffle unrepeatable
+		rand.Shuffle(len(names), func(i, j int) { names[i], names[j] = names[j], names[i] })
+		return names
+	}
+	candidates := append(pool(false), pool(true)...)
+	if rand.Float64() < level {
+		candidates = append(pool(true), pool(false)...)
+	}
+
+	opts := []string{correct}
+	for _, name := range candidates {
+		if len(opts) >= n {
+			break
+		}
+		if !containsFold(opts, name) {
+			opts = append(opts, name)
+		}
+	}
+	rand.Shuffle(len(opts), func(i, j int) { opts[i], opts[j] = opts[j], opts[i] })
+	return opts
+}
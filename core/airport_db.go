@@ -0,0 +1,292 @@
+package core
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	airportDBFile = "airport_db.json"
+	routeDBFile   = "route_db.json"
+
+	// The OpenFlights files ImportOpenFlights reads from its directory
+	openFlightsAirports = "airports.dat"
+	openFlightsRoutes   = "routes.dat"
+)
+
+// Airport is one airport from the airport database
+type Airport struct {
+	Name    string  `json:"name"`
+	City    string  `json:"city"`
+	Country string  `json:"country"`
+	IATA    string  `json:"iata,omitempty"`
+	ICAO    string  `json:"icao,omitempty"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+}
+
+// Code is the airport's ICAO code, or its IATA code when it has none
+func (a Airport) Code() string {
+	if a.ICAO != "" {
+		return a.ICAO
+	}
+	return a.IATA
+}
+
+// Place is how the quiz names the airport, "City, Country" like the
+// resolvers do
+func (a Airport) Place() string {
+	switch {
+	case a.City == "":
+		return a.Name
+	case a.Country == "":
+		return a.City
+	}
+	return a.City + ", " + a.Country
+}
+
+// AirportDB is the airports and scheduled routes imported from OpenFlights
+type AirportDB struct {
+	airports []Airport
+	byCode   map[string]int      // IATA and ICAO code -> index into airports
+	byName   map[string]int      // placeKey of airport name and city -> index into airports
+	routes   map[string][]string // airport code -> codes of the airports it has flights to
+}
+
+func newAirportDB(airports []Airport, routes map[string][]string) *AirportDB {
+	db := &AirportDB{
+		airports: airports,
+		byCode:   make(map[string]int, 2*len(airports)),
+		byName:   make(map[string]int, 2*len(airports)),
+		routes:   routes,
+	}
+	for i, a := range airports {
+		// The first airport listed for a city stands for the city
+		for _, key := range []string{placeKey(a.Name), placeKey(a.City)} {
+			if _, ok := db.byName[key]; !ok && key != "" {
+				db.byName[key] = i
+			}
+		}
+		if a.IATA != "" {
+			db.byCode[a.IATA] = i
+		}
+		if a.ICAO != "" {
+			db.byCode[a.ICAO] = i
+		}
+	}
+	return db
+}
+
+// Len is the number of airports; 0 until a database has been imported
+func (db *AirportDB) Len() int {
+	if db == nil {
+		return 0
+	}
+	return len(db.airports)
+}
+
+// Lookup finds an airport by IATA or ICAO code
+func (db *AirportDB) Lookup(code string) (Airport, bool) {
+	if db == nil {
+		return Airport{}, false
+	}
+	i, ok := db.byCode[strings.ToUpper(strings.TrimSpace(code))]
+	if !ok {
+		return Airport{}, false
+	}
+	return db.airports[i], true
+}
+
+// Destinations is the airports with scheduled flights from the airport
+// with the given code
+func (db *AirportDB) Destinations(code string) []Airport {
+	from, ok := db.Lookup(code)
+	if !ok {
+		return nil
+	}
+	var out []Airport
+	for _, to := range db.routes[from.Code()] {
+		if a, ok := db.Lookup(to); ok {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// airportDBDoc is the airport database as stored in the data directory
+type airportDBDoc struct {
+	Airports []Airport `json:"airports"`
+}
+
+// LoadAirportDB reads the imported airport database. Before anything is
+// imported it is empty, not an error.
+func (dm *DataManager) LoadAirportDB() (*AirportDB, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	var doc airportDBDoc
+	if err := dm.readDocument(airportDBFile, &doc); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	routes := make(map[string][]string)
+	if err := dm.readDocument(routeDBFile, &routes); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return newAirportDB(doc.Airports, routes), nil
+}
+
+// ImportReport is what ImportOpenFlights stored
+type ImportReport struct {
+	Airports int
+	Routes   int
+}
+
+func (r ImportReport) String() string {
+	return fmt.Sprintf("imported %d airports and %d routes", r.Airports, r.Routes)
+}
+
+// ImportOpenFlights reads airports.dat and, if present, routes.dat from
+// dir, as downloaded from the OpenFlights data repository, and replaces
+// the airport database with them
+func (dm *DataManager) ImportOpenFlights(dir string) (ImportReport, error) {
+	f, err := os.Open(filepath.Join(dir, openFlightsAirports))
+	if err != nil {
+		return ImportReport{}, err
+	}
+	airports, err := ParseOpenFlightsAirports(f)
+	f.Close()
+	if err != nil {
+		return ImportReport{}, fmt.Errorf("%s: %w", openFlightsAirports, err)
+	}
+	db := newAirportDB(airports, nil)
+
+	routes := make(map[string][]string)
+	report := ImportReport{Airports: len(airports)}
+	if f, err := os.Open(filepath.Join(dir, openFlightsRoutes)); err == nil {
+		routes, report.Routes, err = parseOpenFlightsRoutes(f, db)
+		f.Close()
+		if err != nil {
+			return ImportReport{}, fmt.Errorf("%s: %w", openFlightsRoutes, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return ImportReport{}, err
+	}
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	if err := dm.writeDocument(airportDBFile, airportDBDoc{Airports: airports}); err != nil {
+		return ImportReport{}, err
+	}
+	return report, dm.writeDocument(routeDBFile, routes)
+}
+
+// openFlightsReader reads the OpenFlights CSV dialect, where \N is null
+func openFlightsReader(r io.Reader) *csv.Reader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.ReuseRecord = true
+	return cr
+}
+
+func openFlightsField(rec []string, i int) string {
+	if i >= len(rec) || rec[i] == `\N` {
+		return ""
+	}
+	return strings.TrimSpace(rec[i])
+}
+
+// ParseOpenFlightsAirports reads airports.dat: id, name, city, country,
+// IATA, ICAO, latitude, longitude, then altitude and time zone columns that
+// aren't kept. Rows typed as something other than an airport (stations,
+// ports) and rows without a position are skipped.
+func ParseOpenFlightsAirports(r io.Reader) ([]Airport, error) {
+	cr := openFlightsReader(r)
+	var out []Airport
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if t := openFlightsField(rec, 12); t != "" && t != "airport" {
+			continue
+		}
+		lat, err1 := strconv.ParseFloat(openFlightsField(rec, 6), 64)
+		lon, err2 := strconv.ParseFloat(openFlightsField(rec, 7), 64)
+		if err1 != nil || err2 != nil || (lat == 0 && lon == 0) {
+			continue
+		}
+		a := Airport{
+			Name:    openFlightsField(rec, 1),
+			City:    openFlightsField(rec, 2),
+			Country: openFlightsField(rec, 3),
+			IATA:    strings.ToUpper(openFlightsField(rec, 4)),
+			ICAO:    strings.ToUpper(openFlightsField(rec, 5)),
+			Lat:     lat,
+			Lon:     lon,
+		}
+		if a.Name == "" || a.Code() == "" {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+// parseOpenFlightsRoutes reads routes.dat: airline, airline id, source
+// airport code, its id, destination airport code, its id, codeshare, stops
+// and equipment. It keeps which airports have non-stop flights between
+// them, keyed by the codes db knows them by, and counts the pairs.
+func parseOpenFlightsRoutes(r io.Reader, db *AirportDB) (map[string][]string, int, error) {
+	cr := openFlightsReader(r)
+	seen := make(map[[2]string]bool)
+	routes := make(map[string][]string)
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if stops := openFlightsField(rec, 7); stops != "" && stops != "0" {
+			continue
+		}
+		from, ok1 := db.Lookup(openFlightsField(rec, 2))
+		to, ok2 := db.Lookup(openFlightsField(rec, 4))
+		if !ok1 || !ok2 || from.Code() == to.Code() {
+			continue
+		}
+		pair := [2]string{from.Code(), to.Code()}
+		if seen[pair] {
+			continue
+		}
+		seen[pair] = true
+		routes[pair[0]] = append(routes[pair[0]], pair[1])
+	}
+	for _, to := range routes {
+		sort.Strings(to)
+	}
+	return routes, len(seen), nil
+}
+
+// QuizPool is the airports the quiz draws wrong answers from: those seen
+// in lookups, plus the places with flights from the home airport once a
+// database has been imported, so early games aren't limited to the few
+// airports seen so far
+func (db *AirportDB) QuizPool(seen []Airport) []string {
+	pool := append([]Airport(nil), seen...)
+	for _, a := range db.Destinations(HomeAirport().Code()) {
+		pool = mergeAirport(pool, a)
+	}
+	return AirportPlaces(pool)
+}
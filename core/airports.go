@@ -0,0 +1,168 @@
+package core
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// Words that name the kind of place rather than which one it is, left out
+// when comparing names
+var placeNoise = map[string]bool{"airport": true, "international": true, "intl": true, "intl.": true}
+
+// placeKey reduces an airport or city name to what its near-duplicates
+// share: "Helsinki-Vantaa International" becomes "helsinki vantaa"
+func placeKey(name string) string {
+	name = strings.ToLower(strings.ReplaceAll(name, "-", " "))
+	var words []string
+	for _, w := range strings.Fields(name) {
+		if !placeNoise[w] {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// keysOverlap reports whether two place keys name the same place: equal,
+// or one the other with more words after it, like "helsinki" and
+// "helsinki vantaa"
+func keysOverlap(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	return a == b || strings.HasPrefix(b, a+" ")
+}
+
+// splitPlace splits a resolver's "City, Country" into its two parts
+func splitPlace(place string) (head, country string) {
+	head, country, _ = strings.Cut(strings.TrimSpace(place), ",")
+	return strings.TrimSpace(head), strings.TrimSpace(country)
+}
+
+// sameAirport reports whether a and b would be the same quiz answer: the
+// same airport, or airports of the same city
+func sameAirport(a, b Airport) bool {
+	if a.Code() != "" && a.Code() == b.Code() {
+		return true
+	}
+	if countriesDiffer(a.Country, b.Country) {
+		return false
+	}
+	return keysOverlap(placeKey(a.City), placeKey(b.City))
+}
+
+// countriesDiffer reports whether two countries, as resolvers give them,
+// are certainly different. A code like "FI" isn't compared with a name like
+// "Finland", and one missing matches anything.
+func countriesDiffer(a, b string) bool {
+	if a == "" || b == "" || (len(a) > 2) != (len(b) > 2) {
+		return false
+	}
+	return !strings.EqualFold(a, b)
+}
+
+// SamePlace reports whether two resolved place names are the same quiz
+// answer, like "Helsinki, Finland" and "Helsinki-Vantaa"
+func SamePlace(a, b string) bool {
+	return sameAirport(placeAirport(a), placeAirport(b))
+}
+
+// placeAirport is the airport a resolved place name stands for when the
+// airport database doesn't know it
+func placeAirport(place string) Airport {
+	head, country := splitPlace(place)
+	return Airport{Name: head, City: head, Country: country}
+}
+
+// Canonical is the airport a place name scraped or looked up by a resolver
+// stands for. Names the database knows by code, airport name or city become
+// its entry, so "Helsinki-Vantaa" and "Helsinki, Finland" are one airport;
+// others are kept as they were named.
+func (db *AirportDB) Canonical(place string) Airport {
+	fallback := placeAirport(place)
+	if db.Len() == 0 || fallback.City == "" {
+		return fallback
+	}
+	var a Airport
+	ok := false
+	if len(fallback.City) <= 4 {
+		a, ok = db.Lookup(fallback.City)
+	}
+	if !ok {
+		i, found := db.byName[placeKey(fallback.City)]
+		if !found {
+			return fallback
+		}
+		a = db.airports[i]
+	}
+	// "Portland, Maine" isn't the first Portland in the file; country codes
+	// like "FI" can't be told apart from state codes, so only names are checked
+	if len(fallback.Country) > 2 && !strings.EqualFold(fallback.Country, a.Country) {
+		return fallback
+	}
+	return a
+}
+
+// sanitizeAirports drops blank and placeholder names and merges airports
+// that would be the same quiz answer, which would otherwise turn up as
+// near-duplicate options
+func sanitizeAirports(airports []Airport) []Airport {
+	var clean []Airport
+	for _, a := range airports {
+		if !knownPlace(a.City) || a.City == "N/A" {
+			continue
+		}
+		clean = mergeAirport(clean, a)
+	}
+	slices.SortFunc(clean, func(a, b Airport) int { return strings.Compare(a.Place(), b.Place()) })
+	return clean
+}
+
+// mergeAirport adds a to airports unless one of them is the same answer.
+// Then the better name is kept: a database entry over a scraped name, a
+// city over one of its airports.
+func mergeAirport(airports []Airport, a Airport) []Airport {
+	for i, b := range airports {
+		if !sameAirport(a, b) {
+			continue
+		}
+		switch {
+		case b.Code() != "":
+		case a.Code() != "" || len(placeKey(a.City)) < len(placeKey(b.City)):
+			if a.Country == "" {
+				a.Country = b.Country
+			}
+			airports[i] = a
+		case b.Country == "":
+			airports[i].Country = a.Country
+		}
+		return airports
+	}
+	return append(airports, a)
+}
+
+// AirportPlaces is the airports as the quiz names them
+func AirportPlaces(airports []Airport) []string {
+	places := make([]string, 0, len(airports))
+	for _, a := range airports {
+		places = append(places, a.Place())
+	}
+	return places
+}
+
+// airportNamesToRecords is the 1 -> 2 step for airports.json: the bare
+// names resolvers gave become airport records
+func airportNamesToRecords(data json.RawMessage) (json.RawMessage, error) {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, err
+	}
+	airports := make([]Airport, 0, len(names))
+	for _, n := range names {
+		airports = append(airports, placeAirport(n))
+	}
+	return json.Marshal(airports)
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// ArrivalBonusPoints is the most the arrival bonus round scores, for
+	// a guess on the minute
+	ArrivalBonusPoints = 150
+
+	// Guesses this far off, or half the true time if that is more, score
+	// nothing
+	arrivalToleranceMin = 10
+
+	// Slower than this the plane is taxiing or the speed is junk
+	arrivalMinSpeedKts = 80
+	// Further out than this the straight-line estimate means little
+	arrivalMaxTime = 6 * time.Hour
+	// No scheduled flight is longer; times spanning more are a mix-up
+	maxScheduledFlight = 20 * time.Hour
+	// A flight landing later than its estimate by more than this is
+	// probably being read from a cached answer about an earlier flight
+	arrivalOverrun = 30 * time.Minute
+)
+
+// DestinationPosition is where the destination airport is, as reported by
+// the resolver or, for arrivals at the home airport, known already
+func (d *ResolvedDetails) DestinationPosition() (lat, lon float64, ok bool) {
+	if d.DestLat != 0 || d.DestLon != 0 {
+		return d.DestLat, d.DestLon, true
+	}
+	if isHomeAirport(d.RealDestination) {
+		home := HomeAirport()
+		return home.Lat, home.Lon, true
+	}
+	return 0, 0, false
+}
+
+// schedule is d's departure and arrival times when they plausibly belong
+// to the flight in the air at now: departed, not long overdue, and no
+// longer than any real flight
+func (d *ResolvedDetails) schedule(now time.Time) (dep, arr time.Time, ok bool) {
+	dep, arr = d.DepartureTime, d.ArrivalTime
+	if dep.IsZero() || arr.IsZero() || !arr.After(dep) || arr.Sub(dep) > maxScheduledFlight {
+		return dep, arr, false
+	}
+	return dep, arr, !dep.After(now) && now.Sub(arr) < arrivalOverrun
+}
+
+// TimeToArrival estimates how long f has left to its destination: the
+// resolver's estimated arrival time when it gave one, or else the
+// great-circle distance at its current ground speed. The latter doesn't
+// allow for the approach, so it is a little short for distant flights.
+func TimeToArrival(f Flight, d *ResolvedDetails, now time.Time) (time.Duration, bool) {
+	if d == nil || f.OnGround || f.VelocityKts < arrivalMinSpeedKts {
+		return 0, false
+	}
+	if _, arr, ok := d.schedule(now); ok {
+		return max(arr.Sub(now), 0), true
+	}
+	lat, lon, ok := d.DestinationPosition()
+	if !ok {
+		return 0, false
+	}
+	hours := Distance(f.Lat, f.Lon, lat, lon) / (float64(f.VelocityKts) * ktsToKmh)
+	eta := time.Duration(hours * float64(time.Hour))
+	if eta > arrivalMaxTime {
+		return 0, false
+	}
+	return eta, true
+}
+
+// FlightProgress is how far along its route a flight is
+type FlightProgress struct {
+	ETA      time.Time
+	Fraction float64 // 0 at departure, 1 at arrival; negative when unknown
+}
+
+// Progress works out f's arrival time and share of the route flown, from
+// the schedule when the resolver gave one and otherwise from where f is
+// between its origin and destination
+func (d *ResolvedDetails) Progress(f Flight, now time.Time) (FlightProgress, bool) {
+	eta, ok := TimeToArrival(f, d, now)
+	if !ok {
+		return FlightProgress{}, false
+	}
+	p := FlightProgress{ETA: now.Add(eta), Fraction: -1}
+	if dep, arr, ok := d.schedule(now); ok {
+		p.Fraction = float64(now.Sub(dep)) / float64(arr.Sub(dep))
+	} else if d.OrigLat != 0 || d.OrigLon != 0 {
+		lat, lon, _ := d.DestinationPosition()
+		flown := Distance(d.OrigLat, d.OrigLon, f.Lat, f.Lon)
+		left := Distance(f.Lat, f.Lon, lat, lon)
+		if flown+left > 0 {
+			p.Fraction = flown / (flown + left)
+		}
+	}
+	if p.Fraction >= 0 {
+		p.Fraction = min(p.Fraction, 1)
+	}
+	return p, true
+}
+
+// String renders the progress for the info panel, e.g. "ETA 14:32, 78%
+// complete"
+func (p FlightProgress) String() string {
+	s := "ETA " + p.ETA.Local().Format("15:04")
+	if p.Fraction >= 0 {
+		s += fmt.Sprintf(", %.0f%% complete", p.Fraction*100)
+	}
+	return s
+}
+
+// ArrivalBonusScore scores a guess of minutes left against the estimate:
+// full points on the minute, falling to none at the tolerance
+func ArrivalBonusScore(guessMin int, actual time.Duration) int {
+	actualMin := math.Round(actual.Minutes())
+	tolerance := math.Max(arrivalToleranceMin, actualMin/2)
+	miss := math.Abs(float64(guessMin) - actualMin)
+	return int(math.Max(0, ArrivalBonusPoints*(1-miss/tolerance)))
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+)
+
+const (
+	// How far out the approach corridor is drawn and aircraft counted as
+	// established on it
+	ApproachCorridorKm = 25.0
+	// Half the corridor's width at its far end; a localizer's beam widens
+	// the same way
+	approachHalfAngleDeg = 3.0
+	// The corridor is at least this wide either side near the runway
+	approachMinHalfKm = 0.3
+	// Established aircraft fly within this of the final approach course
+	approachCourseTolDeg = 30.0
+
+	// Inbound aircraft are counted this far out and this low
+	arrivalQueueRadiusKm = 60.0
+	arrivalQueueMaxFt    = 12000
+	// Aircraft not yet on final join it this far out, so that is added to
+	// their distance to go
+	approachJoinKm = 15.0
+	// Inbound aircraft head within this of the way to the airport
+	inboundTolDeg = 100.0
+)
+
+// QueuedArrival is an aircraft in the queue for a runway
+type QueuedArrival struct {
+	Flight      Flight
+	Runway      string
+	Number      int     // place in the queue for the runway, from 1
+	Established bool    // on the final approach course, inside the corridor
+	ToGoKm      float64 // flying distance left to touchdown, following the approach
+	SpacingKm   float64 // behind the aircraft ahead on the same runway, 0 for the first
+}
+
+// Line describes q for the queue list, e.g. "2. FIN7LV  11.0 km  +5.0".
+// Distances of aircraft not yet on final are estimates, marked with ~.
+func (q QueuedArrival) Line(withRunway bool) string {
+	s := fmt.Sprintf("%d. %-8s", q.Number, q.Flight.Callsign)
+	if withRunway {
+		s = q.Runway + " " + s
+	}
+	if q.Established {
+		s += fmt.Sprintf(" %5.1f km", q.ToGoKm)
+	} else {
+		s += fmt.Sprintf("~%5.1f km", q.ToGoKm)
+	}
+	if q.Number > 1 {
+		s += fmt.Sprintf("  +%.1f", q.SpacingKm)
+	}
+	return s
+}
+
+// CorridorHalfWidthKm is how far either side of the extended centreline
+// the approach corridor reaches, outKm from touchdown
+func CorridorHalfWidthKm(outKm float64) float64 {
+	return max(approachMinHalfKm, outKm*math.Tan(approachHalfAngleDeg*math.Pi/180))
+}
+
+// along places a position relative to an approach: how far out along the
+// extended centreline it is, and how far to the right of it
+func (a Approach) along(lat, lon float64) (outKm, sideKm float64) {
+	d := Distance(a.Lat, a.Lon, lat, lon)
+	rel := (Bearing(a.Lat, a.Lon, lat, lon) - (a.CourseDeg + 180)) * math.Pi / 180
+	// Looking along final, right is to the left seen from the runway
+	return d * math.Cos(rel), -d * math.Sin(rel)
+}
+
+// Established reports whether f is on the final approach onto a: inside
+// the corridor, flying its course
+func (a Approach) Established(f Flight) bool {
+	out, side := a.along(f.Lat, f.Lon)
+	if out < 0 || out > ApproachCorridorKm {
+		return false
+	}
+	return math.Abs(side) <= CorridorHalfWidthKm(out) && courseDiff(f.Heading, a.CourseDeg) <= approachCourseTolDeg
+}
+
+// toGo is the distance f has left to fly to touchdown on a: straight down
+// final when established, or else to where it will join final and then
+// down it. False when f isn't headed that way.
+func (a Approach) toGo(f Flight) (float64, bool) {
+	if a.Established(f) {
+		out, _ := a.along(f.Lat, f.Lon)
+		return out, true
+	}
+	joinLat, joinLon := a.Point(approachJoinKm, 0)
+	if courseDiff(f.Heading, Bearing(f.Lat, f.Lon, a.Lat, a.Lon)) > inboundTolDeg {
+		return 0, false
+	}
+	return Distance(f.Lat, f.Lon, joinLat, joinLon) + approachJoinKm, true
+}
+
+// ArrivalQueue lines up the aircraft inbound to the approaches at airport,
+// as a tower would see them: nearest to touchdown first, each numbered in
+// the queue for the runway it is nearest to landing on, with the spacing
+// to the one ahead of it there. Aircraft count when established on final,
+// or when descending towards the airport within arrivalQueueRadiusKm.
+func ArrivalQueue(flights []Flight, approaches []Approach, airport Airport) []QueuedArrival {
+	if len(approaches) == 0 {
+		return nil
+	}
+	var queue []QueuedArrival
+	for _, f := range flights {
+		if f.OnGround || f.Stale || f.AltitudeFt <= 0 || f.AltitudeFt > arrivalQueueMaxFt ||
+			Distance(airport.Lat, airport.Lon, f.Lat, f.Lon) > arrivalQueueRadiusKm {
+			continue
+		}
+		best := QueuedArrival{Flight: f, ToGoKm: math.Inf(1)}
+		for _, a := range approaches {
+			established := a.Established(f)
+			if !established && f.Trend() != TrendDescending {
+				continue
+			}
+			if d, ok := a.toGo(f); ok && d < best.ToGoKm {
+				best.Runway, best.Established, best.ToGoKm = a.Runway, established, d
+			}
+		}
+		if best.Runway != "" {
+			queue = append(queue, best)
+		}
+	}
+	slices.SortFunc(queue, func(a, b QueuedArrival) int {
+		return cmp.Or(cmp.Compare(a.ToGoKm, b.ToGoKm), cmp.Compare(a.Flight.Icao24, b.Flight.Icao24))
+	})
+	ahead := make(map[string]*QueuedArrival)
+	for i := range queue {
+		q := &queue[i]
+		if prev := ahead[q.Runway]; prev != nil {
+			q.Number, q.SpacingKm = prev.Number+1, q.ToGoKm-prev.ToGoKm
+		} else {
+			q.Number = 1
+		}
+		ahead[q.Runway] = q
+	}
+	return queue
+}
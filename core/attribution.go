@@ -0,0 +1,67 @@
+package core
+
+import (
+	"os"
+	"strings"
+)
+
+// DefaultMapAttribution is the credit the CARTO basemap tiles require
+const DefaultMapAttribution = "© OpenStreetMap contributors © CARTO"
+
+// Corner is a corner of the screen
+type Corner int
+
+const (
+	BottomRight Corner = iota
+	BottomLeft
+	TopRight
+	TopLeft
+)
+
+var cornerNames = map[string]Corner{
+	"bottom-right": BottomRight,
+	"bottom-left":  BottomLeft,
+	"top-right":    TopRight,
+	"top-left":     TopLeft,
+}
+
+// Attribution is the credit line drawn over a corner of the map for the
+// tiles under it
+type Attribution struct {
+	Text   string
+	Corner Corner
+}
+
+// MapAttribution is the overlay configured by MAP_ATTRIBUTION, replacing
+// the text, e.g. for an offline archive made from other tiles, and
+// MAP_ATTRIBUTION_CORNER, one of bottom-right (the default), bottom-left,
+// top-right or top-left. An empty text is ignored: the credit is a
+// condition of using the tiles.
+func MapAttribution() Attribution {
+	a := Attribution{Text: DefaultMapAttribution, Corner: BottomRight}
+	if t := strings.TrimSpace(os.Getenv("MAP_ATTRIBUTION")); t != "" {
+		a.Text = t
+	}
+	if c, ok := cornerNames[strings.ToLower(strings.TrimSpace(os.Getenv("MAP_ATTRIBUTION_CORNER")))]; ok {
+		a.Corner = c
+	}
+	return a
+}
+
+// ASCII is the text for fonts without ©, spelled (c)
+func (a Attribution) ASCII() string {
+	return strings.ReplaceAll(a.Text, "©", "(c)")
+}
+
+// Place is where a w by h box for the text goes on a screenW by screenH
+// screen, margin in from its corner
+func (a Attribution) Place(w, h, screenW, screenH, margin int) (x, y int) {
+	x, y = screenW-w-margin, screenH-h-margin
+	if a.Corner == BottomLeft || a.Corner == TopLeft {
+		x = margin
+	}
+	if a.Corner == TopLeft || a.Corner == TopRight {
+		y = margin
+	}
+	return x, y
+}
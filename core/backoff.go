@@ -0,0 +1,37 @@
+package core
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff computes exponential retry delays with jitter
+type Backoff struct {
+	Base time.Duration // delay after the first failure
+	Max  time.Duration // cap on the un-jittered delay
+
+	failures int
+}
+
+// Next records a failure and returns how long to wait before retrying.
+// The delay doubles each time and is jittered by up to +50% so several
+// clients don't retry in lockstep.
+func (b *Backoff) Next() time.Duration {
+	d := b.Base << b.failures
+	if d > b.Max || d <= 0 {
+		d = b.Max
+	} else {
+		b.failures++
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+// Failures is the number of consecutive failures recorded
+func (b *Backoff) Failures() int {
+	return b.failures
+}
+
+// Reset clears the failure count after a success
+func (b *Backoff) Reset() {
+	b.failures = 0
+}
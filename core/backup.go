@@ -0,0 +1,492 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	backupObject = "flight-monitor-backup.tar.gz"
+
+	defaultBackupInterval = 24 * time.Hour
+	// The first backup waits this long, out of the way of startup
+	backupStartDelay = 5 * time.Minute
+	// A failed backup is tried again this much sooner than the interval
+	backupRetryDelay = time.Hour
+	// Restores refuse archives bigger than this when unpacked
+	backupMaxSize = 2 << 30
+)
+
+// Left out of backups: large files that are downloaded or supplied again,
+// and caches that refill themselves
+var backupSkip = []string{aircraftDBFile, mbtilesFile, photosDir, capturesDir, trackHistoryDir}
+
+// ErrDataNotEmpty means a restore would overwrite player data already there
+var ErrDataNotEmpty = errors.New("the data directory already has player data; move it away first")
+
+// backupTarget is somewhere a backup can be stored and fetched back from
+type backupTarget interface {
+	put(ctx context.Context, name string, body []byte) error
+	get(ctx context.Context, name string) ([]byte, error)
+	String() string
+}
+
+// BackupStatus is how backups are going, for the status screen
+type BackupStatus struct {
+	Target    string
+	LastOK    time.Time
+	Size      int64 // bytes uploaded last time
+	LastErr   error
+	LastErrAt time.Time
+}
+
+// Backup uploads a snapshot of the data directory to a WebDAV folder or an
+// S3-compatible bucket now and then, so years of scores and logs survive the
+// SD card dying, and can restore it on a fresh install.
+//
+// It is configured by BACKUP_URL: an http(s) URL is a WebDAV folder,
+// logged into with BACKUP_USER and BACKUP_PASSWORD; s3://bucket/prefix is a
+// bucket, reached through BACKUP_S3_ENDPOINT (default AWS) in
+// BACKUP_S3_REGION (default us-east-1) with BACKUP_USER and
+// BACKUP_PASSWORD as the access key and secret, or else the usual AWS_
+// variables. BACKUP_INTERVAL is the hours between backups.
+//
+// A nil *Backup does nothing.
+type Backup struct {
+	target   backupTarget
+	interval time.Duration
+	dir      string // the data directory
+
+	mu     sync.Mutex
+	status BackupStatus
+}
+
+// NewBackup is configured from the environment. It returns nil when
+// BACKUP_URL isn't set or is "off".
+func NewBackup() (*Backup, error) {
+	raw := strings.TrimSpace(os.Getenv("BACKUP_URL"))
+	if raw == "" || strings.EqualFold(raw, "off") {
+		return nil, nil
+	}
+	target, err := parseBackupTarget(raw)
+	if err != nil {
+		return nil, err
+	}
+	b := &Backup{target: target, interval: defaultBackupInterval, dir: dataDir()}
+	if v := os.Getenv("BACKUP_INTERVAL"); v != "" {
+		hours, err := strconv.ParseFloat(v, 64)
+		if err != nil || hours <= 0 {
+			return nil, fmt.Errorf("BACKUP_INTERVAL %q: want hours above 0", v)
+		}
+		b.interval = time.Duration(hours * float64(time.Hour))
+	}
+	b.status.Target = target.String()
+	return b, nil
+}
+
+func parseBackupTarget(raw string) (backupTarget, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("BACKUP_URL: %w", err)
+	}
+	client := &http.Client{Timeout: 5 * time.Minute}
+	user, pass := os.Getenv("BACKUP_USER"), os.Getenv("BACKUP_PASSWORD")
+	switch u.Scheme {
+	case "http", "https":
+		if !strings.HasSuffix(u.Path, "/") {
+			u.Path += "/"
+		}
+		return &webDAVTarget{folder: u, user: user, pass: pass, client: client}, nil
+	case "s3":
+		if u.Host == "" {
+			return nil, errors.New("BACKUP_URL: s3:// needs a bucket")
+		}
+		t := &s3Target{
+			bucket: u.Host,
+			prefix: strings.Trim(u.Path, "/"),
+			region: os.Getenv("BACKUP_S3_REGION"),
+			key:    user,
+			secret: pass,
+			client: client,
+		}
+		if t.region == "" {
+			t.region = "us-east-1"
+		}
+		if t.key == "" {
+			t.key, t.secret = os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+		}
+		if t.key == "" || t.secret == "" {
+			return nil, errors.New("BACKUP_URL: s3 needs BACKUP_USER and BACKUP_PASSWORD (access key and secret)")
+		}
+		endpoint := os.Getenv("BACKUP_S3_ENDPOINT")
+		if endpoint == "" {
+			endpoint = "https://s3." + t.region + ".amazonaws.com"
+		}
+		if t.endpoint, err = url.Parse(endpoint); err != nil || t.endpoint.Host == "" {
+			return nil, fmt.Errorf("BACKUP_S3_ENDPOINT %q isn't a URL", endpoint)
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("BACKUP_URL: want an http(s) WebDAV folder or s3://bucket, got %q", raw)
+}
+
+// Run backs up every interval until ctx is cancelled, starting a few
+// minutes in
+func (b *Backup) Run(ctx context.Context) {
+	if b == nil {
+		return
+	}
+	wait := backupStartDelay
+	for {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+		wait = b.interval
+		if err := b.Now(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Println("Backup failed:", err)
+			wait = min(b.interval, backupRetryDelay)
+		}
+	}
+}
+
+// Now uploads a snapshot straight away
+func (b *Backup) Now(ctx context.Context) error {
+	var buf bytes.Buffer
+	err := writeBackup(&buf, b.dir)
+	if err == nil {
+		err = b.target.put(ctx, backupObject, buf.Bytes())
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		b.status.LastErr, b.status.LastErrAt = err, time.Now()
+		return err
+	}
+	b.status.LastOK, b.status.Size, b.status.LastErr = time.Now(), int64(buf.Len()), nil
+	log.Printf("Backed up %s to %s", FormatBytes(int64(buf.Len())), b.target)
+	return nil
+}
+
+// Status is how backups are going, or nil when they are off
+func (b *Backup) Status() *BackupStatus {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.status
+	return &s
+}
+
+func (s BackupStatus) String() string {
+	switch {
+	case s.LastErr != nil:
+		return fmt.Sprintf("failed at %s: %v", s.LastErrAt.Local().Format("15:04"), s.LastErr)
+	case s.LastOK.IsZero():
+		return "none yet, to " + s.Target
+	}
+	return fmt.Sprintf("%s at %s to %s", FormatBytes(s.Size), s.LastOK.Local().Format("Jan 2 15:04"), s.Target)
+}
+
+// Restore downloads the backup and unpacks it into the data directory,
+// which must not hold player data yet. It returns how many files were
+// written.
+func (b *Backup) Restore(ctx context.Context) (int, error) {
+	if _, err := os.Stat(filepath.Join(b.dir, usersFile)); err == nil {
+		return 0, ErrDataNotEmpty
+	}
+	data, err := b.target.get(ctx, backupObject)
+	if err != nil {
+		return 0, err
+	}
+	return readBackup(bytes.NewReader(data), b.dir)
+}
+
+// writeBackup writes the data directory as a gzipped tarball. Files are
+// replaced by renaming, so each is read whole, old or new.
+func writeBackup(w io.Writer, dir string) error {
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		for _, skip := range backupSkip {
+			if rel == skip {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if d.IsDir() || !d.Type().IsRegular() || strings.HasSuffix(rel, ".tmp") {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // pruned meanwhile
+		}
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{Name: rel, Mode: 0644, Size: int64(len(data)), ModTime: info.ModTime()}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// readBackup unpacks a backup into dir, each file written to a temporary
+// name first
+func readBackup(r io.Reader, dir string) (int, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, fmt.Errorf("not a backup: %w", err)
+	}
+	tr := tar.NewReader(zr)
+	n := 0
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(hdr.Name)) {
+			return n, fmt.Errorf("backup names a file outside the data directory: %q", hdr.Name)
+		}
+		if total += hdr.Size; total > backupMaxSize {
+			return n, errors.New("backup too large")
+		}
+		p := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return n, err
+		}
+		f, err := os.Create(p + ".tmp")
+		if err != nil {
+			return n, err
+		}
+		_, err = io.Copy(f, tr)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Rename(p+".tmp", p)
+		}
+		if err != nil {
+			os.Remove(p + ".tmp")
+			return n, err
+		}
+		n++
+	}
+}
+
+// webDAVTarget stores backups in a WebDAV folder, e.g. on Nextcloud
+type webDAVTarget struct {
+	folder     *url.URL // ends in a slash
+	user, pass string
+	client     *http.Client
+}
+
+func (t *webDAVTarget) String() string {
+	return t.folder.Redacted()
+}
+
+func (t *webDAVTarget) request(ctx context.Context, method, name string, body []byte) (*http.Response, error) {
+	u := t.folder.JoinPath(name)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if t.user != "" {
+		req.SetBasicAuth(t.user, t.pass)
+	}
+	return t.client.Do(req)
+}
+
+func (t *webDAVTarget) put(ctx context.Context, name string, body []byte) error {
+	resp, err := t.request(ctx, "PUT", name, body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusConflict {
+		// The folder doesn't exist yet
+		mk, err := t.request(ctx, "MKCOL", "", nil)
+		if err != nil {
+			return err
+		}
+		mk.Body.Close()
+		if resp, err = t.request(ctx, "PUT", name, body); err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webdav: %s", resp.Status)
+	}
+	return nil
+}
+
+func (t *webDAVTarget) get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := t.request(ctx, "GET", name, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("webdav: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// s3Target stores backups in an S3-compatible bucket (AWS, Backblaze B2,
+// Cloudflare R2, MinIO...), addressed path-style so any endpoint works
+type s3Target struct {
+	endpoint    *url.URL
+	bucket      string
+	prefix      string
+	region      string
+	key, secret string
+	client      *http.Client
+}
+
+func (t *s3Target) String() string {
+	return "s3://" + path.Join(t.bucket, t.prefix)
+}
+
+func (t *s3Target) do(ctx context.Context, method, name string, body []byte) (*http.Response, error) {
+	u := *t.endpoint
+	u.Path = "/" + path.Join(t.bucket, t.prefix, name)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	signS3(req, body, t.region, t.key, t.secret, time.Now())
+	return t.client.Do(req)
+}
+
+func (t *s3Target) put(ctx context.Context, name string, body []byte) error {
+	resp, err := t.do(ctx, "PUT", name, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("s3: %s %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func (t *s3Target) get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := t.do(ctx, "GET", name, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("s3: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// signS3 signs req with AWS Signature Version 4, covering the host and
+// every header already set
+func signS3(req *http.Request, body []byte, region, key, secret string, now time.Time) {
+	sum := sha256.Sum256(body)
+	payload := hex.EncodeToString(sum[:])
+	stamp := now.UTC().Format("20060102T150405Z")
+	day := stamp[:8]
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonHeaders.String(),
+		signed,
+		payload,
+	}, "\n")
+	scope := day + "/" + region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	mac := func(k []byte, s string) []byte {
+		h := hmac.New(sha256.New, k)
+		h.Write([]byte(s))
+		return h.Sum(nil)
+	}
+	k := mac([]byte("AWS4"+secret), day)
+	k = mac(k, region)
+	k = mac(k, "s3")
+	k = mac(k, "aws4_request")
+	sig := hex.EncodeToString(mac(k, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", key, scope, signed, sig))
+}
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A frame further apart than this from the previous one means the machine
+// was suspended or the process stalled, not that the game was slow
+const suspendGap = 5 * time.Second
+
+const (
+	// The system clock further than this from the servers' is wrong, not
+	// just drifting or behind a response that sat in a cache
+	clockSkewLimit = 10 * time.Minute
+)
+
+// No correct clock reads earlier than this. A Raspberry Pi has no
+// real-time clock, so until NTP syncs it starts at 1970 or at the time it
+// was last shut down.
+var clockFloor = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var errClockWrong = errors.New("the system clock is wrong")
+
+// serverClock is the last time a data server reported and when that
+// arrived, by this machine's clock
+type serverClock struct {
+	mu     sync.Mutex
+	server time.Time // zero until a server has reported
+	at     time.Time // time.Now on arrival, for its monotonic reading
+}
+
+var systemClock serverClock
+
+// ObserveServerTime records the time a server put in a response that has
+// just arrived, to check the system clock against
+func ObserveServerTime(server time.Time) {
+	if server.IsZero() {
+		return
+	}
+	systemClock.mu.Lock()
+	defer systemClock.mu.Unlock()
+	systemClock.server, systemClock.at = server, time.Now()
+}
+
+// ClockStatus is how the system clock compares with the servers'
+type ClockStatus struct {
+	Checked bool          // a server has reported its time
+	Skew    time.Duration // how far the system clock is ahead, negative when behind
+	Sane    bool          // fit for timestamps that are saved or compared with saved ones
+}
+
+// CheckClock compares now from the system clock with the time the servers
+// last reported, carried forward on the monotonic clock, which an NTP step
+// doesn't move. Without a report only a clock before clockFloor is known to
+// be wrong.
+func CheckClock(now time.Time) ClockStatus {
+	systemClock.mu.Lock()
+	server, at := systemClock.server, systemClock.at
+	systemClock.mu.Unlock()
+
+	st := ClockStatus{Sane: !now.Before(clockFloor)}
+	if !server.IsZero() {
+		st.Checked = true
+		st.Skew = now.Round(0).Sub(server.Add(now.Sub(at)))
+		st.Sane = st.Sane && st.Skew.Abs() <= clockSkewLimit
+	}
+	return st
+}
+
+// ClockSane reports whether now can be trusted for timestamps that are
+// saved, or compared with saved ones to tell whether they have expired.
+// Until it can, TTL caches are neither read nor written and scores are
+// held back rather than dated wrongly.
+func ClockSane(now time.Time) bool {
+	return CheckClock(now).Sane
+}
+
+func (s ClockStatus) String() string {
+	var off string
+	switch d := s.Skew.Abs().Round(time.Second); {
+	case s.Skew > 0:
+		off = d.String() + " ahead of the servers"
+	case s.Skew < 0:
+		off = d.String() + " behind the servers"
+	default:
+		off = "matches the servers"
+	}
+	switch {
+	case !s.Sane && (!s.Checked || s.Skew.Abs() <= clockSkewLimit):
+		return "not set yet; saving scores and caches waits for it"
+	case !s.Sane:
+		return off + "; saving scores and caches waits for it"
+	case !s.Checked:
+		return "not checked yet"
+	}
+	return fmt.Sprintf("ok, %s", off)
+}
+
+// Elapsed is how much real time has passed between two readings of
+// time.Now. Go's monotonic clock is immune to NTP steps but stops while the
+// device is suspended, and the wall clock does the opposite, so the larger
+// of the two is used: data fetched before a suspend counts as old, and a
+// clock set backwards can't make it look fresh.
+func Elapsed(from, to time.Time) time.Duration {
+	mono := to.Sub(from)
+	wall := to.Round(0).Sub(from.Round(0))
+	return max(mono, wall)
+}
+
+// GameClock is the time round timers run on. It advances with the frames
+// it is ticked on but skips over suspends and stalls, so a round paused by
+// closing the lid resumes with the time it had left rather than timing out.
+type GameClock struct {
+	last    time.Time
+	elapsed time.Duration
+}
+
+// Tick advances the clock to now. It returns the length of the gap when one
+// is detected; game time doesn't move across it.
+func (c *GameClock) Tick(now time.Time) time.Duration {
+	if c.last.IsZero() {
+		c.last = now
+		return 0
+	}
+	prev := c.last
+	c.last = now
+	if gap := Elapsed(prev, now); gap > suspendGap {
+		return gap
+	}
+	// Frames advance on the monotonic clock alone, never backwards
+	c.elapsed += max(now.Sub(prev), 0)
+	return 0
+}
+
+// Now is the current game time, only meaningful relative to other readings
+func (c *GameClock) Now() time.Duration {
+	return c.elapsed
+}
+
+// Since is the game time passed since an earlier reading of Now
+func (c *GameClock) Since(t time.Duration) time.Duration {
+	return c.elapsed - t
+}
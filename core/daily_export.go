@@ -0,0 +1,240 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	capturesDir = "captures"
+
+	exportSize       = 480
+	exportFrames     = 48 // one frame per half hour
+	exportFrameDelay = 15 // hundredths of a second
+	exportTrailAge   = time.Hour
+	exportTitle      = "VANTAA FLIGHTRADAR24"
+)
+
+// Palette indices for the exported GIF, matching the app's UI colors
+const (
+	palBg uint8 = iota
+	palTrailOld
+	palTrail
+	palText
+	palHome
+)
+
+var exportPalette = color.Palette{
+	color.RGBA{0x0f, 0x17, 0x2a, 0xff}, // colBgDark
+	color.RGBA{0x33, 0x41, 0x55, 0xff}, // colGlassLight
+	color.RGBA{0x38, 0xbd, 0xf8, 0xff}, // colAccent
+	color.RGBA{0xf1, 0xf5, 0xf9, 0xff}, // colText
+	color.RGBA{0x4a, 0xde, 0x80, 0xff}, // colSuccess
+}
+
+// DailyExporter renders a day's recorded tracks into an animated GIF for sharing
+type DailyExporter struct {
+	CenterLat, CenterLon float64
+	RadiusDeg            float64
+	WebhookURL           string // optional; the GIF is POSTed here as multipart "file"
+
+	store  Storage
+	client *http.Client
+}
+
+// NewDailyExporter saves the GIFs in dm's storage
+func NewDailyExporter(dm *DataManager, centerLat, centerLon, radiusDeg float64) *DailyExporter {
+	return &DailyExporter{
+		store:      dm.storage(),
+		CenterLat:  centerLat,
+		CenterLon:  centerLon,
+		RadiusDeg:  radiusDeg,
+		WebhookURL: os.Getenv("DAILY_GIF_WEBHOOK"),
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Export writes captures/traffic-<day>.gif and returns its path
+func (e *DailyExporter) Export(day string, tracks map[string][]TrackPoint) (string, error) {
+	if len(tracks) == 0 {
+		return "", fmt.Errorf("no tracks recorded for %s", day)
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", day, time.Local)
+	if err != nil {
+		return "", err
+	}
+
+	anim := e.render(date, tracks)
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return "", err
+	}
+
+	name := path.Join(capturesDir, fmt.Sprintf("traffic-%s.gif", day))
+	if err := e.store.WriteFile(name, buf.Bytes()); err != nil {
+		return "", err
+	}
+
+	if e.WebhookURL != "" {
+		if err := e.post(day, path.Base(name), buf.Bytes()); err != nil {
+			return displayPath(e.store, name), fmt.Errorf("webhook: %w", err)
+		}
+	}
+
+	return displayPath(e.store, name), nil
+}
+
+func (e *DailyExporter) render(date time.Time, tracks map[string][]TrackPoint) *gif.GIF {
+	// Project using the same Web Mercator math as the map, then fit the
+	// configured radius box into the frame.
+	const zoom = 10
+	minX, minY := LatLonToPixels(e.CenterLat+e.RadiusDeg, e.CenterLon-e.RadiusDeg, zoom)
+	maxX, maxY := LatLonToPixels(e.CenterLat-e.RadiusDeg, e.CenterLon+e.RadiusDeg, zoom)
+	scale := float64(exportSize) / maxFloat(maxX-minX, maxY-minY)
+
+	project := func(lat, lon float64) (int, int) {
+		x, y := LatLonToPixels(lat, lon, zoom)
+		return int((x - minX) * scale), int((y - minY) * scale)
+	}
+
+	anim := &gif.GIF{}
+	bounds := image.Rect(0, 0, exportSize, exportSize)
+	step := 24 * time.Hour / exportFrames
+
+	for i := 1; i <= exportFrames; i++ {
+		frameEnd := date.Add(time.Duration(i) * step)
+		img := image.NewPaletted(bounds, exportPalette)
+
+		for _, points := range tracks {
+			for j := 1; j < len(points); j++ {
+				prev, curr := points[j-1], points[j]
+				if curr.Time.After(frameEnd) {
+					break
+				}
+				idx := palTrailOld
+				if frameEnd.Sub(curr.Time) < exportTrailAge {
+					idx = palTrail
+				}
+				x0, y0 := project(prev.Lat, prev.Lon)
+				x1, y1 := project(curr.Lat, curr.Lon)
+				drawLine(img, x0, y0, x1, y1, idx)
+			}
+		}
+
+		hx, hy := project(e.CenterLat, e.CenterLon)
+		for dx := -2; dx <= 2; dx++ {
+			for dy := -2; dy <= 2; dy++ {
+				img.SetColorIndex(hx+dx, hy+dy, palHome)
+			}
+		}
+
+		drawLabel(img, 10, 20, exportTitle)
+		drawLabel(img, 10, 38, date.Format("Mon 2 Jan 2006"))
+		drawLabel(img, 10, exportSize-12, frameEnd.Format("15:04"))
+		drawLabel(img, exportSize-110, exportSize-12, fmt.Sprintf("%d aircraft", len(tracks)))
+
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, exportFrameDelay)
+	}
+
+	// Linger on the final frame
+	anim.Delay[len(anim.Delay)-1] = 300
+	return anim
+}
+
+func (e *DailyExporter) post(day, filename string, data []byte) error {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if err := w.WriteField("content", fmt.Sprintf("Overhead traffic for %s", day)); err != nil {
+		return err
+	}
+	part, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	resp, err := e.client.Post(e.WebhookURL, w.FormDataContentType(), &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func drawLabel(img *image.Paletted, x, y int, s string) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(exportPalette[palText]),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(s)
+}
+
+// drawLine plots a Bresenham line; out-of-bounds pixels are ignored by SetColorIndex
+func drawLine(img *image.Paletted, x0, y0, x1, y1 int, idx uint8) {
+	dx, dy := absInt(x1-x0), -absInt(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		img.SetColorIndex(x0, y0, idx)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,429 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// dataPath resolves a file inside the persistent data directory
+func dataPath(filename string) string {
+	return filepath.Join(dataDir(), filename)
+}
+
+// dataDir is the persistent data directory, created on first use
+func dataDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "." // Fallback to current dir
+	}
+	// Store data in a persistent subfolder hidden in home
+	dir := filepath.Join(home, ".flight-monitor-data")
+
+	// Create dir if not exists (check error to be safe, but ignore if exists)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		_ = os.MkdirAll(dir, 0755)
+	}
+	return dir
+}
+
+const (
+	scoresFile       = "scores.json" // legacy top-10 file, migrated into scoreHistoryFile
+	scoreHistoryFile = "score_history.jsonl"
+	usersFile        = "users.json"
+	airportsFile     = "airports.json"
+
+	leaderboardSize = 10
+)
+
+// UserStats represents a player's statistics
+type UserStats struct {
+	Name               string `json:"name"`
+	GamesPlayed        int    `json:"games_played"`
+	TotalScore         int    `json:"total_score"`
+	BestScore          int    `json:"best_score"`
+	PerformancePercent int    `json:"performance_percent,omitempty"`
+
+	// Difficulty carries the adaptive quiz level between sessions
+	Difficulty Difficulty `json:"difficulty"`
+}
+
+// ScoreEntry represents a single high score entry
+type ScoreEntry struct {
+	Name  string    `json:"name"`
+	Score int       `json:"score"`
+	Date  time.Time `json:"date"` // when the game finished, RFC3339
+}
+
+// Day is the local calendar date the game was played, e.g. "3 Oct"
+func (e ScoreEntry) Day() string {
+	if e.Date.IsZero() {
+		return ""
+	}
+	return e.Date.Local().Format("2 Jan")
+}
+
+// DayStats rolls up the games played on one local calendar day
+type DayStats struct {
+	Games int
+	Total int
+	Best  ScoreEntry // zero if no games
+}
+
+// DailyStats sums up the history entries played on day's date in the local
+// timezone, so a game just after local midnight counts for the new day
+// wherever the device is
+func DailyStats(history []ScoreEntry, day time.Time) DayStats {
+	day = day.Local()
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 0, 1) // not +24h, which is wrong on DST changes
+	var s DayStats
+	for _, e := range history {
+		if e.Date.Before(start) || !e.Date.Before(end) {
+			continue
+		}
+		s.Games++
+		s.Total += e.Score
+		if s.Games == 1 || e.Score > s.Best.Score {
+			s.Best = e
+		}
+	}
+	return s
+}
+
+// DataManager handles persistence for users and scores. The zero value
+// keeps its files in the data directory.
+type DataManager struct {
+	mu    sync.Mutex
+	store Storage // nil for FileStorage
+
+	// Scores finished while the system clock was wrong, dated with time.Now
+	// for its monotonic reading until StampHeldScores can date them properly
+	held []ScoreEntry
+}
+
+// NewDataManager keeps its files in store, e.g. a MemoryStorage in tests
+func NewDataManager(store Storage) *DataManager {
+	return &DataManager{store: store}
+}
+
+// storage is where dm's files are
+func (dm *DataManager) storage() Storage {
+	if dm.store == nil {
+		return FileStorage{}
+	}
+	return dm.store
+}
+
+var globalDataManager = &DataManager{}
+
+// LoadUsers reads the users.json file and returns a map of users
+func (dm *DataManager) LoadUsers() (map[string]UserStats, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	users := make(map[string]UserStats)
+	if err := dm.readDocument(usersFile, &users); err != nil {
+		if os.IsNotExist(err) {
+			return users, nil
+		}
+		return nil, err
+	}
+	return sanitizeUsers(users), nil
+}
+
+// sanitizeUsers repairs a users map read from disk: a "null" document gives a
+// nil map, hand edits can leave names out of step with their keys, and stats
+// must not go negative
+func sanitizeUsers(users map[string]UserStats) map[string]UserStats {
+	clean := make(map[string]UserStats, len(users))
+	for name, u := range users {
+		if name == "" {
+			continue
+		}
+		u.Name = name
+		u.GamesPlayed = max(u.GamesPlayed, 0)
+		u.TotalScore = max(u.TotalScore, 0)
+		u.BestScore = max(u.BestScore, 0)
+		u.Difficulty.Level = min(max(u.Difficulty.Level, 0), 1)
+		if n := len(u.Difficulty.Recent); n > difficultyWindow {
+			u.Difficulty.Recent = u.Difficulty.Recent[n-difficultyWindow:]
+		}
+		clean[name] = u
+	}
+	return clean
+}
+
+// SaveUser updates or creates a user's stats after a game
+func (dm *DataManager) SaveUser(name string, score int, difficulty Difficulty) (UserStats, error) {
+	// Load existing first to ensure we have latest state
+	users, err := dm.LoadUsers()
+	if err != nil {
+		return UserStats{}, err
+	}
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	user, ok := users[name]
+	if !ok {
+		user = UserStats{Name: name}
+	}
+
+	user.GamesPlayed++
+	user.TotalScore += score
+	if score > user.BestScore {
+		user.BestScore = score
+	}
+	user.Difficulty = difficulty
+
+	users[name] = user
+
+	if err := dm.writeDocument(usersFile, users); err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+// DeleteUser removes a user from the users.json file
+func (dm *DataManager) DeleteUser(name string) error {
+	// Note: Calling LoadUsers() here would deadlock if we held lock,
+	// but LoadUsers acquires its own lock.
+	// However, we shouldn't call public methods that lock from other methods.
+	// Refactoring to just load raw here for safety or trust the flow.
+	// Actually, LoadUsers is fine as long as we don't hold lock across it.
+
+	users, err := dm.LoadUsers()
+	if err != nil {
+		return err
+	}
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	if _, ok := users[name]; ok {
+		delete(users, name)
+
+		if err := dm.writeDocument(usersFile, users); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadScores returns the top scores computed from the full history
+func (dm *DataManager) LoadScores() ([]ScoreEntry, error) {
+	history, err := dm.LoadScoreHistory()
+	if err != nil {
+		return nil, err
+	}
+	return topScores(history, leaderboardSize), nil
+}
+
+// LoadScoreHistory returns every recorded score in the order they were played
+func (dm *DataManager) LoadScoreHistory() ([]ScoreEntry, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	if err := dm.migrateLegacyScores(); err != nil {
+		return nil, err
+	}
+	return dm.readScoreHistory()
+}
+
+// AddScore appends a new score to the history and returns the current top 10
+func (dm *DataManager) AddScore(entry ScoreEntry) ([]ScoreEntry, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	if err := dm.migrateLegacyScores(); err != nil {
+		return nil, err
+	}
+	if err := dm.appendScores([]ScoreEntry{entry}); err != nil {
+		return nil, err
+	}
+
+	history, err := dm.readScoreHistory()
+	if err != nil {
+		return nil, err
+	}
+	return topScores(history, leaderboardSize), nil
+}
+
+// readScoreHistory parses the JSON-lines history. Caller must hold dm.mu.
+func (dm *DataManager) readScoreHistory() ([]ScoreEntry, error) {
+	var scores []ScoreEntry
+	file, err := dm.storage().Open(scoreHistoryFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return scores, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry ScoreEntry
+		if err := decodeRecord(scoreHistoryFile, line, &entry); err != nil {
+			// Skip a torn line (e.g. power loss mid-write) rather than losing the whole history
+			continue
+		}
+		scores = append(scores, entry)
+	}
+	return scores, scanner.Err()
+}
+
+// appendScores writes entries to the end of the history. Caller must hold dm.mu.
+func (dm *DataManager) appendScores(entries []ScoreEntry) error {
+	var lines []byte
+	for _, e := range entries {
+		line, err := encodeRecord(scoreHistoryFile, e)
+		if err != nil {
+			return err
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	return dm.storage().Append(scoreHistoryFile, lines)
+}
+
+// migrateLegacyScores seeds the history from the old top-10 scores.json the
+// first time the history is accessed. Caller must hold dm.mu.
+func (dm *DataManager) migrateLegacyScores() error {
+	if dm.storage().Exists(scoreHistoryFile) {
+		return nil
+	}
+
+	data, err := dm.storage().ReadFile(scoresFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	// The old entries are unversioned history records; run them through the
+	// same migrations to convert their dates
+	legacy := make([]ScoreEntry, len(raw))
+	for i, r := range raw {
+		if err := decodeRecord(scoreHistoryFile, r, &legacy[i]); err != nil {
+			return err
+		}
+	}
+	return dm.appendScores(legacy)
+}
+
+// topScores returns the n best entries, highest first, without modifying history
+func topScores(history []ScoreEntry, n int) []ScoreEntry {
+	sorted := make([]ScoreEntry, len(history))
+	copy(sorted, history)
+
+	// Stable so earlier entries win ties, like the old append-then-sort behaviour
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Score > sorted[j].Score
+	})
+
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// GetLeaderboard returns high scores and user stats for display
+func (dm *DataManager) GetLeaderboard() ([]ScoreEntry, []UserStats, error) {
+	scores, err := dm.LoadScores()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	usersMap, err := dm.LoadUsers()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var userStatsList []UserStats
+	for _, u := range usersMap {
+		// Calculate performance
+		percentage := 0
+		if u.GamesPlayed > 0 {
+			// Max potential score roughly 1000 per game (200 * 5 rounds)
+			// Matches Python logic: percentage = int((total / (games * 1000)) * 100)
+			percentage = int((float64(u.TotalScore) / float64(u.GamesPlayed*1000)) * 100)
+			if percentage > 100 {
+				percentage = 100
+			} else if percentage < 0 {
+				percentage = 0
+			}
+		}
+		u.PerformancePercent = percentage
+		userStatsList = append(userStatsList, u)
+	}
+
+	// Sort users by best score desc
+	sort.Slice(userStatsList, func(i, j int) bool {
+		return userStatsList[i].BestScore > userStatsList[j].BestScore
+	})
+
+	return scores, userStatsList, nil
+}
+
+// LoadAirports reads the airports.json file
+func (dm *DataManager) LoadAirports() ([]Airport, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	var airports []Airport
+	if err := dm.readDocument(airportsFile, &airports); err != nil {
+		if os.IsNotExist(err) {
+			return airports, nil
+		}
+		return nil, err
+	}
+	return sanitizeAirports(airports), nil
+}
+
+// SaveAirport adds the airport a resolver named to the list if not present.
+// The name is looked up in db, which may be nil, so the list keeps one
+// entry per airport however differently it was named.
+func (dm *DataManager) SaveAirport(place string, db *AirportDB) error {
+	if place == "" || place == "Unknown" || place == "N/A" {
+		return nil
+	}
+	airport := db.Canonical(place)
+
+	// Load existing without lock first to avoid deadlock with SaveAirport calling LoadAirports
+	// Actually, LoadAirports uses lock. We should just call a helper or duplicate logic.
+	// Or just do it all inside SaveAirport.
+
+	// Better: We just load, check, modify, save.
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	var stored []Airport
+	if err := dm.readDocument(airportsFile, &stored); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	airports := sanitizeAirports(append(slices.Clone(stored), airport))
+	if !slices.Equal(airports, stored) {
+		return dm.writeDocument(airportsFile, airports)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package core
+
+import "time"
+
+// Positions older than this are not advanced further; a plane that has
+// stopped reporting should stall rather than fly off on a guess
+const maxExtrapolation = 15 * time.Second
+
+const ktsToKmh = 1.852
+
+// Extrapolate advances f from its reported position along its heading at its
+// ground speed for the time since fixAt, capped at maxExtrapolation
+func Extrapolate(f Flight, fixAt, now time.Time) (lat, lon float64) {
+	if f.OnGround || f.VelocityKts <= 0 || fixAt.IsZero() {
+		return f.Lat, f.Lon
+	}
+	age := min(max(Elapsed(fixAt, now), 0), maxExtrapolation)
+	distKm := float64(f.VelocityKts) * ktsToKmh * age.Hours()
+	return Destination(f.Lat, f.Lon, f.Heading, distKm)
+}
+
+// Position returns where f is estimated to be at now, dead-reckoned from
+// the poll that last reported it. Use it for drawing so icons glide between
+// polls instead of jumping.
+func (s *FlightStore) Position(f *Flight, now time.Time) (lat, lon float64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Extrapolate(*f, s.lastSeen[f.Icao24], now)
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// wmmCoeff is one Schmidt semi-normalized Gauss coefficient pair (nT) with its
+// secular variation (nT/year)
+type wmmCoeff struct {
+	n, m       int
+	g, h       float64
+	gDot, hDot float64
+}
+
+// World Magnetic Model 2020 main field, truncated to degree 4. The full model
+// goes to degree 12; the low-order terms carry almost all of the declination,
+// so this is good to within a few degrees, fine for a handheld compass.
+const wmmEpoch = 2020.0
+
+var wmmCoeffs = []wmmCoeff{
+	{1, 0, -29404.5, 0, 6.7, 0},
+	{1, 1, -1450.7, 4652.9, 7.7, -25.1},
+	{2, 0, -2500.0, 0, -11.5, 0},
+	{2, 1, 2982.0, -2991.6, -7.1, -30.2},
+	{2, 2, 1676.8, -734.8, -2.2, -23.9},
+	{3, 0, 1363.9, 0, 2.8, 0},
+	{3, 1, -2381.0, -82.2, -6.2, 5.7},
+	{3, 2, 1236.2, 241.8, 3.4, -1.0},
+	{3, 3, 525.7, -542.9, -12.2, 1.1},
+	{4, 0, 903.1, 0, -1.1, 0},
+	{4, 1, 809.4, 282.0, -1.6, 0.2},
+	{4, 2, 86.2, -158.4, -6.0, 6.9},
+	{4, 3, -309.4, 199.8, 5.4, 3.7},
+	{4, 4, 47.9, -350.1, -5.5, -5.6},
+}
+
+const wmmMaxDegree = 4
+
+var (
+	declinationOverride    float64
+	hasDeclinationOverride bool
+	declinationOnce        sync.Once
+)
+
+// MagneticDeclination returns the declination in degrees (east positive) at
+// lat/lon. MAG_DECLINATION overrides the model with a fixed local value.
+func MagneticDeclination(lat, lon float64, t time.Time) float64 {
+	declinationOnce.Do(func() {
+		if v, err := strconv.ParseFloat(os.Getenv("MAG_DECLINATION"), 64); err == nil {
+			declinationOverride, hasDeclinationOverride = v, true
+		}
+	})
+	if hasDeclinationOverride {
+		return declinationOverride
+	}
+
+	years := float64(t.Year()) + float64(t.YearDay())/365.25 - wmmEpoch
+	// Secular variation is only a linear fit; don't extrapolate it for decades
+	years = math.Max(0, math.Min(years, 10))
+
+	theta := (90 - lat) * math.Pi / 180 // colatitude
+	lambda := lon * math.Pi / 180
+
+	// Keep away from the poles where the east component divides by sin(theta)
+	theta = math.Max(1e-6, math.Min(math.Pi-1e-6, theta))
+
+	const dTheta = 1e-6
+	p := schmidtLegendre(theta)
+	pPlus := schmidtLegendre(theta + dTheta)
+	pMinus := schmidtLegendre(theta - dTheta)
+
+	var north, east float64
+	for _, c := range wmmCoeffs {
+		g := c.g + c.gDot*years
+		h := c.h + c.hDot*years
+		m := float64(c.m)
+		cosM, sinM := math.Cos(m*lambda), math.Sin(m*lambda)
+
+		dP := (pPlus[c.n][c.m] - pMinus[c.n][c.m]) / (2 * dTheta)
+		north += (g*cosM + h*sinM) * dP
+		east += m * (g*sinM - h*cosM) * p[c.n][c.m] / math.Sin(theta)
+	}
+
+	return math.Atan2(east, north) * 180 / math.Pi
+}
+
+// schmidtLegendre evaluates the Schmidt semi-normalized associated Legendre
+// functions P[n][m](cos theta) up to wmmMaxDegree
+func schmidtLegendre(theta float64) [wmmMaxDegree + 1][wmmMaxDegree + 1]float64 {
+	var p [wmmMaxDegree + 1][wmmMaxDegree + 1]float64
+	cosT, sinT := math.Cos(theta), math.Sin(theta)
+
+	p[0][0] = 1
+	for n := 1; n <= wmmMaxDegree; n++ {
+		if n == 1 {
+			p[1][1] = sinT
+		} else {
+			p[n][n] = math.Sqrt(1-1/(2*float64(n))) * sinT * p[n-1][n-1]
+		}
+		for m := 0; m < n; m++ {
+			nf, mf := float64(n), float64(m)
+			prev2 := 0.0
+			if n >= 2 {
+				prev2 = p[n-2][m]
+			}
+			p[n][m] = ((2*nf-1)*cosT*p[n-1][m] - math.Sqrt((nf-1)*(nf-1)-mf*mf)*prev2) / math.Sqrt(nf*nf-mf*mf)
+		}
+	}
+	return p
+}
+
+// FormatBearing renders a true bearing for display, converting it to magnetic
+// at the observer's position when requested
+func FormatBearing(trueDeg float64, magnetic bool, obsLat, obsLon float64) string {
+	if !magnetic {
+		return fmt.Sprintf("%03.0f T", math.Mod(trueDeg+360, 360))
+	}
+	mag := trueDeg - MagneticDeclination(obsLat, obsLon, time.Now())
+	return fmt.Sprintf("%03.0f M", math.Mod(mag+720, 360))
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How often the layout file is checked for changes
+const devLayoutPoll = time.Second
+
+// DevLayoutPath is the UI_LAYOUT file layout and theme tweaks are read
+// from, or "" when the debug mode is off
+func DevLayoutPath() string {
+	return strings.TrimSpace(os.Getenv("UI_LAYOUT"))
+}
+
+// devLayoutFile is the file's shape. Colours are "#rrggbb" or "#rrggbbaa".
+type devLayoutFile struct {
+	Values map[string]int    `json:"values"`
+	Colors map[string]string `json:"colors"`
+}
+
+// DevLayout is a developer aid for tuning the UI on the real display: the
+// frontends ask it for layout numbers and theme colours by name, passing the
+// built-in value, and it answers from a JSON file it reloads whenever the
+// file changes. If the file doesn't exist it is written with every name
+// asked for so far and its built-in value, as a starting point.
+// A nil *DevLayout is the debug mode switched off and answers the defaults.
+type DevLayout struct {
+	path string
+
+	mu       sync.Mutex
+	values   map[string]int
+	colors   map[string]uint32
+	defaults devLayoutFile // every name asked for, with its built-in value
+	modTime  time.Time
+}
+
+// NewDevLayout reads tweaks from the file at path once Run is started
+func NewDevLayout(path string) *DevLayout {
+	return &DevLayout{
+		path:     path,
+		defaults: devLayoutFile{Values: make(map[string]int), Colors: make(map[string]string)},
+	}
+}
+
+// Int is the layout value called name, or def
+func (l *DevLayout) Int(name string, def int) int {
+	if l == nil {
+		return def
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.defaults.Values[name] = def
+	if v, ok := l.values[name]; ok {
+		return v
+	}
+	return def
+}
+
+// Color is the theme colour called name as 0xRRGGBBAA, or def
+func (l *DevLayout) Color(name string, def uint32) uint32 {
+	if l == nil {
+		return def
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.defaults.Colors[name] = fmt.Sprintf("#%08x", def)
+	if c, ok := l.colors[name]; ok {
+		return c
+	}
+	return def
+}
+
+// Run reloads the file whenever it changes until ctx is cancelled. A file
+// that doesn't parse is reported and the last good values are kept, so a
+// half-saved edit doesn't throw the screen about.
+func (l *DevLayout) Run(ctx context.Context) {
+	log.Println("UI layout: reading tweaks from", l.path)
+	for {
+		select {
+		case <-time.After(devLayoutPoll):
+		case <-ctx.Done():
+			return
+		}
+		info, err := os.Stat(l.path)
+		if os.IsNotExist(err) {
+			if err := l.writeTemplate(); err != nil {
+				log.Println("UI layout:", err)
+			}
+			continue
+		}
+		if err != nil || info.ModTime().Equal(l.modTime) {
+			continue
+		}
+		l.modTime = info.ModTime()
+		if err := l.load(); err != nil {
+			log.Println("UI layout:", err)
+		} else {
+			log.Println("UI layout: reloaded", l.path)
+		}
+	}
+}
+
+func (l *DevLayout) load() error {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return err
+	}
+	var f devLayoutFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	colors := make(map[string]uint32, len(f.Colors))
+	for name, s := range f.Colors {
+		c, err := parseHexColor(s)
+		if err != nil {
+			return fmt.Errorf("colour %s: %w", name, err)
+		}
+		colors[name] = c
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.values, l.colors = f.Values, colors
+	return nil
+}
+
+// writeTemplate creates the file from the built-in values asked for so far
+func (l *DevLayout) writeTemplate() error {
+	l.mu.Lock()
+	data, err := json.MarshalIndent(l.defaults, "", "  ")
+	l.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	log.Println("UI layout: writing the built-in values to", l.path)
+	return os.WriteFile(l.path, append(data, '\n'), 0644)
+}
+
+// parseHexColor reads "#rrggbb" (opaque) or "#rrggbbaa"
+func parseHexColor(s string) (uint32, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return 0, fmt.Errorf("%q is not #rrggbb or #rrggbbaa", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not #rrggbb or #rrggbbaa", s)
+	}
+	return uint32(v), nil
+}
@@ -0,0 +1,220 @@
+package core
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	difficultyWindow  = 10 // answers in the rolling accuracy
+	difficultyMinSeen = 4  // don't adapt on the first couple of guesses
+	difficultyStep    = 0.1
+
+	// Keep players succeeding roughly this often
+	TargetAccuracyLow  = 0.6
+	TargetAccuracyHigh = 0.8
+
+	easyRoundTime = 30 * time.Second
+	hardRoundTime = 12 * time.Second
+)
+
+// Difficulty adapts the quiz to one player. Level runs from 0 (random
+// distractors, long timer) to 1 (the most plausible distractors, short timer).
+type Difficulty struct {
+	Level  float64 `json:"level"`
+	Recent []bool  `json:"recent,omitempty"` // newest last
+}
+
+// Record adds one answer and nudges the level to keep the rolling accuracy
+// inside the target band
+func (d *Difficulty) Record(correct bool) {
+	// Cap the slice so a stored copy of d never sees this append
+	d.Recent = append(d.Recent[:len(d.Recent):len(d.Recent)], correct)
+	if len(d.Recent) > difficultyWindow {
+		d.Recent = d.Recent[len(d.Recent)-difficultyWindow:]
+	}
+	if len(d.Recent) < difficultyMinSeen {
+		return
+	}
+
+	switch acc := d.Accuracy(); {
+	case acc > TargetAccuracyHigh:
+		d.Level += difficultyStep
+	case acc < TargetAccuracyLow:
+		d.Level -= difficultyStep
+	}
+	d.Level = math.Max(0, math.Min(1, d.Level))
+}
+
+// Accuracy is the share of correct answers in the window, or 0 with no answers
+func (d Difficulty) Accuracy() float64 {
+	if len(d.Recent) == 0 {
+		return 0
+	}
+	right := 0
+	for _, ok := range d.Recent {
+		if ok {
+			right++
+		}
+	}
+	return float64(right) / float64(len(d.Recent))
+}
+
+// RoundTime is how long the player gets to answer at the current level
+func (d Difficulty) RoundTime() time.Duration {
+	return easyRoundTime - time.Duration(d.Level*float64(easyRoundTime-hardRoundTime))
+}
+
+// Approximate city positions for judging how close two answers are. Only the
+// destinations commonly seen from Helsinki are listed; others are never "close".
+var cityCoords = map[string][2]float64{
+	"amsterdam":  {52.31, 4.76},
+	"athens":     {37.94, 23.94},
+	"barcelona":  {41.30, 2.08},
+	"berlin":     {52.37, 13.50},
+	"brussels":   {50.90, 4.48},
+	"budapest":   {47.44, 19.26},
+	"copenhagen": {55.62, 12.65},
+	"doha":       {25.27, 51.61},
+	"dubai":      {25.25, 55.36},
+	"dublin":     {53.42, -6.27},
+	"frankfurt":  {50.03, 8.57},
+	"gdansk":     {54.38, 18.47},
+	"gothenburg": {57.66, 12.28},
+	"hamburg":    {53.63, 9.99},
+	"helsinki":   {60.32, 24.96},
+	"istanbul":   {41.26, 28.74},
+	"kittilä":    {67.70, 24.85},
+	"kuopio":     {63.01, 27.80},
+	"lisbon":     {38.77, -9.13},
+	"london":     {51.47, -0.45},
+	"madrid":     {40.47, -3.57},
+	"malaga":     {36.67, -4.50},
+	"manchester": {53.35, -2.28},
+	"milan":      {45.63, 8.72},
+	"munich":     {48.35, 11.79},
+	"new york":   {40.64, -73.78},
+	"oslo":       {60.19, 11.10},
+	"oulu":       {64.93, 25.35},
+	"paris":      {49.01, 2.55},
+	"prague":     {50.10, 14.26},
+	"reykjavik":  {63.98, -22.63},
+	"riga":       {56.92, 23.97},
+	"rome":       {41.80, 12.25},
+	"rovaniemi":  {66.56, 25.83},
+	"stockholm":  {59.65, 17.92},
+	"tallinn":    {59.41, 24.83},
+	"tampere":    {61.41, 23.60},
+	"tokyo":      {35.55, 139.78},
+	"turku":      {60.51, 22.26},
+	"vaasa":      {63.05, 21.76},
+	"vienna":     {48.11, 16.57},
+	"vilnius":    {54.63, 25.29},
+	"warsaw":     {52.17, 20.97},
+	"zurich":     {47.46, 8.55},
+}
+
+// Hub cities of airlines commonly seen overhead, keyed by ICAO callsign prefix
+var airlineHubs = map[string][]string{
+	"FIN": {"helsinki"},
+	"SAS": {"copenhagen", "stockholm", "oslo"},
+	"NAX": {"oslo", "stockholm", "copenhagen"},
+	"NOZ": {"oslo", "stockholm", "copenhagen"},
+	"DLH": {"frankfurt", "munich"},
+	"AFR": {"paris"},
+	"KLM": {"amsterdam"},
+	"BAW": {"london"},
+	"THY": {"istanbul"},
+	"QTR": {"doha"},
+	"UAE": {"dubai"},
+	"AUA": {"vienna"},
+	"SWR": {"zurich"},
+	"LOT": {"warsaw"},
+	"BTI": {"riga"},
+	"ICE": {"reykjavik"},
+	"JAL": {"tokyo"},
+}
+
+// cityOf maps an airport name like "Stockholm, Sweden" or "Helsinki-Vantaa"
+// to a key of cityCoords, or ""
+func cityOf(name string) string {
+	name = strings.ToLower(name)
+	if i := strings.Index(name, ","); i >= 0 {
+		name = name[:i]
+	}
+	for city := range cityCoords {
+		if strings.Contains(name, city) {
+			return city
+		}
+	}
+	return ""
+}
+
+// DistractorPlausibility scores how believable candidate is as a wrong
+// answer: hubs of the flight's airline and airports near the correct one
+// score higher. Callsign may be empty.
+func DistractorPlausibility(correct, candidate, callsign string) float64 {
+	score := 0.0
+	city := cityOf(candidate)
+	if city == "" {
+		return score
+	}
+	if len(callsign) >= 3 {
+		for _, hub := range airlineHubs[strings.ToUpper(callsign[:3])] {
+			if hub == city {
+				score++
+				break
+			}
+		}
+	}
+	if want := cityOf(correct); want != "" && want != city {
+		a, b := cityCoords[want], cityCoords[city]
+		score += 1 / (1 + Distance(a[0], a[1], b[0], b[1])/1000)
+	}
+	return score
+}
+
+// PickDistractors chooses n wrong answers from pool. At level 0 they are
+// random; at level 1 they are the n most plausible; in between the share of
+// plausible picks scales with the level.
+func PickDistractors(correct, callsign string, pool []string, n int, level float64) []string {
+	return pickDistractors(correct, pool, n, level, func(c string) float64 {
+		return DistractorPlausibility(correct, c, callsign)
+	})
+}
+
+// pickDistractors is PickDistractors with plausibility judged by score
+func pickDistractors(correct string, pool []string, n int, level float64, score func(candidate string) float64) []string {
+	seen := map[string]bool{correct: true, "Unknown": true, "": true}
+	var candidates []string
+	for _, c := range pool {
+		// "Helsinki, Finland" is no wrong answer to "Helsinki-Vantaa"
+		if !seen[c] && !SamePlace(c, correct) {
+			seen[c] = true
+			candidates = append(candidates, c)
+		}
+	}
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	hard := int(math.Round(math.Max(0, math.Min(1, level)) * float64(n)))
+	if hard > 0 {
+		scores := make(map[string]float64, len(candidates))
+		for _, c := range candidates {
+			scores[c] = score(c)
+		}
+		// Stable so equally plausible candidates stay shuffled
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return scores[candidates[i]] > scores[candidates[j]]
+		})
+	}
+
+	// Reshuffle what follows the plausible picks so the remainder is random
+	rest := candidates[min(hard, len(candidates)):]
+	rand.Shuffle(len(rest), func(i, j int) { rest[i], rest[j] = rest[j], rest[i] })
+	return candidates[:min(n, len(candidates))]
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDifficultyRaisesLevelWhenTooEasy(t *testing.T) {
+	var d Difficulty
+	for i := 0; i < difficultyMinSeen-1; i++ {
+		d.Record(true)
+	}
+	if d.Level != 0 {
+		t.Fatalf("adapted after %d answers: level %v", len(d.Recent), d.Level)
+	}
+	for i := 0; i < 20; i++ {
+		d.Record(true)
+	}
+	if d.Level != 1 {
+		t.Errorf("level after a long streak = %v, want 1", d.Level)
+	}
+	if got := d.RoundTime(); got != hardRoundTime {
+		t.Errorf("round time at level 1 = %v, want %v", got, hardRoundTime)
+	}
+}
+
+func TestDifficultyLowersLevelWhenTooHard(t *testing.T) {
+	d := Difficulty{Level: 0.5}
+	for i := 0; i < 6; i++ {
+		d.Record(false)
+	}
+	if d.Level >= 0.5 {
+		t.Errorf("level after misses = %v, want below 0.5", d.Level)
+	}
+	for i := 0; i < 20; i++ {
+		d.Record(false)
+	}
+	if d.Level != 0 {
+		t.Errorf("level = %v, want clamped to 0", d.Level)
+	}
+	if got := d.RoundTime(); got != easyRoundTime {
+		t.Errorf("round time at level 0 = %v, want %v", got, easyRoundTime)
+	}
+}
+
+func TestDifficultyHoldsInsideTargetBand(t *testing.T) {
+	d := Difficulty{Level: 0.5}
+	// 7 of 10 correct sits inside the band
+	for _, ok := range []bool{true, true, false, true, true, false, true, true, false, true} {
+		d.Record(ok)
+	}
+	if d.Level < 0.3 || d.Level > 0.7 {
+		t.Errorf("level drifted to %v inside the target band", d.Level)
+	}
+	if acc := d.Accuracy(); acc != 0.7 {
+		t.Errorf("accuracy = %v, want 0.7", acc)
+	}
+}
+
+func TestDifficultyWindowIsRolling(t *testing.T) {
+	var d Difficulty
+	for i := 0; i < difficultyWindow; i++ {
+		d.Record(false)
+	}
+	for i := 0; i < difficultyWindow; i++ {
+		d.Record(true)
+	}
+	if len(d.Recent) != difficultyWindow {
+		t.Fatalf("kept %d answers, want %d", len(d.Recent), difficultyWindow)
+	}
+	if acc := d.Accuracy(); acc != 1 {
+		t.Errorf("accuracy = %v, want old misses to have rolled out", acc)
+	}
+}
+
+func TestRoundTimeShrinksWithLevel(t *testing.T) {
+	prev := time.Duration(1 << 62)
+	for _, level := range []float64{0, 0.25, 0.5, 0.75, 1} {
+		rt := Difficulty{Level: level}.RoundTime()
+		if rt >= prev {
+			t.Errorf("round time at level %v = %v, not shorter than %v", level, rt, prev)
+		}
+		prev = rt
+	}
+}
+
+func TestDistractorPlausibility(t *testing.T) {
+	near := DistractorPlausibility("Stockholm, Sweden", "Oslo, Norway", "")
+	far := DistractorPlausibility("Stockholm, Sweden", "Tokyo, Japan", "")
+	if near <= far {
+		t.Errorf("Oslo (%v) should be more plausible than Tokyo (%v) for Stockholm", near, far)
+	}
+
+	hub := DistractorPlausibility("Stockholm, Sweden", "Frankfurt, Germany", "DLH123")
+	nonHub := DistractorPlausibility("Stockholm, Sweden", "Frankfurt, Germany", "FIN123")
+	if hub <= nonHub {
+		t.Errorf("airline hub (%v) should beat the same airport for another airline (%v)", hub, nonHub)
+	}
+
+	if got := DistractorPlausibility("Stockholm, Sweden", "Nowhere", "DLH123"); got != 0 {
+		t.Errorf("unknown airport scored %v, want 0", got)
+	}
+}
+
+func TestPickDistractors(t *testing.T) {
+	pool := []string{"Tokyo, Japan", "Oslo, Norway", "New York, USA", "Copenhagen, Denmark",
+		"Stockholm, Sweden", "Unknown", "Oslo, Norway", "Dubai, UAE"}
+
+	hard := PickDistractors("Stockholm, Sweden", "", pool, 2, 1)
+	want := map[string]bool{"Oslo, Norway": true, "Copenhagen, Denmark": true}
+	if len(hard) != 2 || !want[hard[0]] || !want[hard[1]] {
+		t.Errorf("hard distractors = %v, want the two nearest airports", hard)
+	}
+
+	for i := 0; i < 20; i++ {
+		got := PickDistractors("Stockholm, Sweden", "", pool, 4, 0)
+		if len(got) != 4 {
+			t.Fatalf("got %d distractors, want 4", len(got))
+		}
+		seen := map[string]bool{}
+		for _, c := range got {
+			if c == "Stockholm, Sweden" || c == "Unknown" || seen[c] {
+				t.Fatalf("bad distractor list %v", got)
+			}
+			seen[c] = true
+		}
+	}
+
+	if got := PickDistractors("Oslo", "", []string{"Oslo", "Paris"}, 3, 0.5); len(got) != 1 {
+		t.Errorf("small pool gave %v, want just Paris", got)
+	}
+}
@@ -0,0 +1,31 @@
+package core
+
+// Squawk codes reserved for emergencies, and what each one means
+var emergencySquawks = map[string]string{
+	"7500": "hijack",
+	"7600": "radio failure",
+	"7700": "general emergency",
+}
+
+// SquawkEmergency returns what an emergency squawk means, or "" for any
+// other code
+func SquawkEmergency(squawk string) string {
+	return emergencySquawks[squawk]
+}
+
+// IsEmergency reports whether the flight is squawking an emergency code
+func (f Flight) IsEmergency() bool {
+	return f.Emergency != ""
+}
+
+// Emergencies picks the flights squawking an emergency out of flights,
+// leaving out ones that haven't been heard from lately
+func Emergencies(flights []Flight) []Flight {
+	var out []Flight
+	for _, f := range flights {
+		if f.IsEmergency() && !f.Stale {
+			out = append(out, f)
+		}
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DistractorExperiment is the A/B test of how wrong answers are picked for
+// route questions. Each player stays in one arm; the rounds they play are
+// tagged with it in the game log, so the arms' accuracy can be compared.
+const DistractorExperiment = "distractors"
+
+// DistractorStrategy is one way of picking wrong answers from the airports
+// seen so far
+type DistractorStrategy struct {
+	Name string
+	pick func(correct, callsign string, pool []string, n int, level float64) []string
+}
+
+// Pick chooses n wrong answers. The zero strategy is the control.
+func (s DistractorStrategy) Pick(correct, callsign string, pool []string, n int, level float64) []string {
+	if s.pick == nil {
+		return PickDistractors(correct, callsign, pool, n, level)
+	}
+	return s.pick(correct, callsign, pool, n, level)
+}
+
+// DistractorStrategies are the experiment's arms, the control first
+var DistractorStrategies = []DistractorStrategy{
+	{Name: "adaptive", pick: PickDistractors},
+	// Only closeness to the right airport makes a wrong one plausible: is
+	// the airline hub bonus helping, or just giving the answer away?
+	{Name: "distance", pick: func(correct, callsign string, pool []string, n int, level float64) []string {
+		return pickDistractors(correct, pool, n, level, func(c string) float64 {
+			return DistractorPlausibility(correct, c, "")
+		})
+	}},
+}
+
+// ExperimentsEnabled reports whether players are split between experiment
+// arms. EXPERIMENTS=off puts everyone in the control.
+func ExperimentsEnabled() bool {
+	return !strings.EqualFold(strings.TrimSpace(os.Getenv("EXPERIMENTS")), "off")
+}
+
+// AssignVariant picks one of n arms of experiment for player. The same
+// player always gets the same arm, on any device, and different
+// experiments split players independently.
+func AssignVariant(experiment, player string, n int) int {
+	if n <= 1 || !ExperimentsEnabled() {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(experiment + "\x00" + strings.ToLower(player)))
+	return int(h.Sum32() % uint32(n))
+}
+
+// DistractorStrategyFor is player's arm of the distractor experiment
+func DistractorStrategyFor(player string) DistractorStrategy {
+	return DistractorStrategies[AssignVariant(DistractorExperiment, player, len(DistractorStrategies))]
+}
+
+// StrategyResult is how players did against one distractor strategy
+type StrategyResult struct {
+	Strategy string
+	Rounds   int
+	Right    int
+}
+
+// Accuracy is the share of rounds answered right
+func (r StrategyResult) Accuracy() float64 {
+	if r.Rounds == 0 {
+		return 0
+	}
+	return float64(r.Right) / float64(r.Rounds)
+}
+
+func (r StrategyResult) String() string {
+	return fmt.Sprintf("%s: %.0f%% right over %d rounds", r.Strategy, r.Accuracy()*100, r.Rounds)
+}
+
+// DistractorResults totals the game log's tagged rounds by strategy: the
+// current arms in order, then any retired ones. Rounds from before the
+// experiment carry no strategy and are left out.
+func (dm *DataManager) DistractorResults() ([]StrategyResult, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	totals := make(map[string]*StrategyResult)
+	var names []string
+	tally := func(name string) *StrategyResult {
+		if totals[name] == nil {
+			totals[name] = &StrategyResult{Strategy: name}
+			names = append(names, name)
+		}
+		return totals[name]
+	}
+	for _, s := range DistractorStrategies {
+		tally(s.Name)
+	}
+	arms := len(names)
+
+	file, err := dm.storage().Open(gamesFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			var rec GameRecord
+			if err := decodeRecord(gamesFile, scanner.Bytes(), &rec); err != nil {
+				continue // torn line
+			}
+			for _, r := range rec.Rounds {
+				if r.Strategy == "" {
+					continue
+				}
+				t := tally(r.Strategy)
+				t.Rounds++
+				if r.Right {
+					t.Right++
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(names[arms:])
+	results := make([]StrategyResult, len(names))
+	for i, name := range names {
+		results[i] = *totals[name]
+	}
+	return results, nil
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FailoverProvider tries each provider in order and returns the first
+// successful result, so a throttled or unreachable source doesn't blank the map
+type FailoverProvider struct {
+	providers []FlightProvider
+
+	mu     sync.Mutex
+	active string // name of the provider that served the last fetch
+}
+
+func NewFailoverProvider(providers ...FlightProvider) *FailoverProvider {
+	return &FailoverProvider{providers: providers}
+}
+
+func (fp *FailoverProvider) Name() string {
+	names := make([]string, len(fp.providers))
+	for i, p := range fp.providers {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+// Active returns the provider that served the most recent fetch
+func (fp *FailoverProvider) Active() string {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	return fp.active
+}
+
+// RateLimitInfo reports the primary provider's limits
+func (fp *FailoverProvider) RateLimitInfo() RateLimitInfo {
+	if len(fp.providers) == 0 {
+		return RateLimitInfo{Remaining: -1}
+	}
+	return fp.providers[0].RateLimitInfo()
+}
+
+// RequestCost is the primary provider's, matching RateLimitInfo
+func (fp *FailoverProvider) RequestCost(radiusDeg float64) int {
+	if len(fp.providers) == 0 {
+		return 1
+	}
+	return RequestCost(fp.providers[0], radiusDeg)
+}
+
+func (fp *FailoverProvider) FetchFlights(ctx context.Context, centerLat, centerLon, radiusDeg float64) ([]Flight, error) {
+	var errs []string
+	now := time.Now()
+
+	for _, p := range fp.providers {
+		if until := p.RateLimitInfo().ThrottledUntil; now.Before(until) {
+			errs = append(errs, fmt.Sprintf("%s: throttled until %s", p.Name(), until.Format("15:04:05")))
+			continue
+		}
+
+		flights, err := p.FetchFlights(ctx, centerLat, centerLon, radiusDeg)
+		if ctx.Err() != nil {
+			return nil, ctx.Err() // shutting down, don't fail over
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", p.Name(), err))
+			continue
+		}
+
+		fp.mu.Lock()
+		if fp.active != p.Name() {
+			log.Printf("Flight data now served by %s", p.Name())
+			fp.active = p.Name()
+		}
+		fp.mu.Unlock()
+
+		for i := range flights {
+			if flights[i].Source == "" {
+				flights[i].Source = p.Name()
+			}
+		}
+		return flights, nil
+	}
+
+	return nil, fmt.Errorf("all providers failed: %s", strings.Join(errs, "; "))
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// FakeResolver is a DetailsResolver that answers from memory, so round
+// setup can be exercised without the network. Answers are the same every
+// time; callsigns and addresses it wasn't given get ErrRouteUnknown.
+type FakeResolver struct {
+	mu       sync.Mutex
+	routes   map[string]ResolvedDetails // callsign -> details
+	aircraft map[string]ResolvedDetails // icao24 -> details
+	asked    []string
+}
+
+func NewFakeResolver() *FakeResolver {
+	return &FakeResolver{
+		routes:   make(map[string]ResolvedDetails),
+		aircraft: make(map[string]ResolvedDetails),
+	}
+}
+
+// AddRoute makes callsign resolve to d
+func (r *FakeResolver) AddRoute(callsign string, d ResolvedDetails) *FakeResolver {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[strings.TrimSpace(callsign)] = d
+	return r
+}
+
+// AddAircraft makes the aircraft with address icao24 resolve to d
+func (r *FakeResolver) AddAircraft(icao24 string, d ResolvedDetails) *FakeResolver {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.aircraft[strings.ToLower(icao24)] = d
+	return r
+}
+
+func (r *FakeResolver) FetchFlightDetails(ctx context.Context, callsign string) (*ResolvedDetails, error) {
+	return r.answer(ctx, r.routes, strings.TrimSpace(callsign))
+}
+
+func (r *FakeResolver) FetchAircraftDetails(ctx context.Context, icao24 string) (*ResolvedDetails, error) {
+	return r.answer(ctx, r.aircraft, strings.ToLower(icao24))
+}
+
+// answer hands out a copy, so callers can't change what later ones get
+func (r *FakeResolver) answer(ctx context.Context, table map[string]ResolvedDetails, key string) (*ResolvedDetails, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.asked = append(r.asked, key)
+	d, ok := table[key]
+	if !ok {
+		return nil, ErrRouteUnknown
+	}
+	return &d, nil
+}
+
+// Asked is the callsigns and addresses looked up so far, in order
+func (r *FakeResolver) Asked() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.asked...)
+}
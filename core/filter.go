@@ -0,0 +1,116 @@
+package core
+
+import (
+	"slices"
+	"strings"
+)
+
+// FlightFilter narrows a flight list. Zero values mean "no restriction".
+type FlightFilter struct {
+	MinAltitudeFt    int      `json:"min_altitude_ft,omitempty"`
+	MaxAltitudeFt    int      `json:"max_altitude_ft,omitempty"`
+	ExcludeOnGround  bool     `json:"exclude_on_ground,omitempty"`
+	Categories       []string `json:"categories,omitempty"`        // whitelist, e.g. "Large", "Heavy"
+	CallsignPrefixes []string `json:"callsign_prefixes,omitempty"` // e.g. "FIN" for Finnair only
+}
+
+// QuizFilter keeps the game to airliner-like traffic that is actually flying:
+// no parked aircraft, gliders, balloons or drones
+var QuizFilter = FlightFilter{
+	MinAltitudeFt:   500,
+	ExcludeOnGround: true,
+	Categories:      []string{"No Info", "Unknown", "Small", "Large", "High Vortex", "Heavy", "High Perf"},
+}
+
+// CategoryPreset is a named set of aircraft categories for a filter, as
+// the settings screen offers them
+type CategoryPreset struct {
+	Name       string
+	Categories []string // nil for every category
+}
+
+// AlertCategoryPresets are the aircraft kinds alerts can be limited to
+var AlertCategoryPresets = []CategoryPreset{
+	{Name: "ALL"},
+	{Name: "JETS", Categories: []string{"Large", "High Vortex", "Heavy", "High Perf"}},
+	{Name: "HEAVY", Categories: []string{"High Vortex", "Heavy"}},
+	{Name: "LIGHT", Categories: []string{"Light", "Small", "Rotorcraft", "Glider", "Ultralight"}},
+}
+
+// presetIndex is the index of the preset with exactly categories, or -1
+func presetIndex(presets []CategoryPreset, categories []string) int {
+	for i, p := range presets {
+		if len(p.Categories) == len(categories) && slices.EqualFunc(p.Categories, categories, strings.EqualFold) {
+			return i
+		}
+	}
+	return -1
+}
+
+// CategoryPresetName names categories for the settings screen: the preset
+// they are, or CUSTOM when edited in settings.json
+func CategoryPresetName(presets []CategoryPreset, categories []string) string {
+	if i := presetIndex(presets, categories); i >= 0 {
+		return presets[i].Name
+	}
+	return "CUSTOM"
+}
+
+// NextCategoryPreset is the preset after the one categories are, wrapping
+// around; custom categories go back to the first
+func NextCategoryPreset(presets []CategoryPreset, categories []string) []string {
+	i := presetIndex(presets, categories)
+	if i < 0 {
+		return presets[0].Categories
+	}
+	return presets[(i+1)%len(presets)].Categories
+}
+
+// Match reports whether f passes the filter
+func (ff FlightFilter) Match(f Flight) bool {
+	if ff.ExcludeOnGround && f.OnGround {
+		return false
+	}
+	if ff.MinAltitudeFt > 0 && f.AltitudeFt < ff.MinAltitudeFt {
+		return false
+	}
+	if ff.MaxAltitudeFt > 0 && f.AltitudeFt > ff.MaxAltitudeFt {
+		return false
+	}
+	if len(ff.Categories) > 0 && !containsFold(ff.Categories, f.Category) {
+		return false
+	}
+	if len(ff.CallsignPrefixes) > 0 {
+		ok := false
+		for _, prefix := range ff.CallsignPrefixes {
+			if strings.HasPrefix(strings.ToUpper(f.Callsign), strings.ToUpper(prefix)) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the flights that pass the filter
+func (ff FlightFilter) Apply(flights []Flight) []Flight {
+	out := make([]Flight, 0, len(flights))
+	for _, f := range flights {
+		if ff.Match(f) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
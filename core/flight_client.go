@@ -0,0 +1,390 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Flight struct {
+	Icao24      string  `json:"icao24"`
+	Callsign    string  `json:"callsign"`
+	Lon         float64 `json:"lon"`
+	Lat         float64 `json:"lat"`
+	VelocityKts int     `json:"velocity_kts"`
+	Heading     float64 `json:"heading"`
+	AltitudeFt  int     `json:"altitude_ft"` // barometric
+	OnGround    bool    `json:"on_ground"`
+
+	VerticalRateFpm int `json:"vertical_rate_fpm,omitempty"` // positive when climbing
+	GeoAltitudeFt   int `json:"geo_altitude_ft,omitempty"`   // GNSS altitude, 0 if not reported
+
+	Squawk    string `json:"squawk,omitempty"`    // transponder code, e.g. "1000"
+	Emergency string `json:"emergency,omitempty"` // meaning of an emergency squawk, see SquawkEmergency
+
+	// Airframe details, filled in from the aircraft database (see AircraftDB)
+	Registration string    `json:"registration,omitempty"`
+	TypeCode     string    `json:"type_code,omitempty"` // ICAO designator, e.g. "A321"
+	Operator     string    `json:"operator,omitempty"`
+	FirstFlight  time.Time `json:"first_flight,omitzero"` // or build date
+
+	Origin      string    `json:"origin_country"`
+	Category    string    `json:"category"`
+	Destination string    `json:"destination"`          // Inferred
+	Source      string    `json:"source,omitempty"`     // Provider that reported this flight
+	PositionAt  time.Time `json:"position_at,omitzero"` // when the position was measured, zero if the source doesn't say
+	Stale       bool      `json:"-"`                    // not seen in recent polls (set by FlightStore)
+}
+
+const (
+	openSkyURL      = "https://opensky-network.org/api/states/all"
+	openSkyAuthURL  = "https://auth.opensky-network.org/auth/realms/opensky-network/protocol/openid-connect/token"
+	cacheDuration   = 10 * time.Second
+	credentialsPath = "./credentials.json"
+
+	// Refresh this long before the token's stated expiry so a request never
+	// goes out with a token that lapses in flight
+	tokenRefreshMargin = 60 * time.Second
+	// Don't hammer the auth server when credentials are rejected
+	authRetryDelay = time.Minute
+
+	// Network errors and 5xx are retried a couple of times within one poll
+	openSkyMaxRetries = 2
+	openSkyRetryBase  = time.Second
+	openSkyRetryMax   = 8 * time.Second
+)
+
+var categoryMap = map[int]string{
+	0: "No Info", 1: "No Info", 2: "Light", 3: "Small",
+	4: "Large", 5: "High Vortex", 6: "Heavy", 7: "High Perf",
+	8: "Rotorcraft", 9: "Glider", 10: "Lighter-than-air",
+	11: "Parachutist", 12: "Ultralight", 13: "Reserved",
+	14: "UAV", 15: "Space", 16: "Emergency", 17: "Service",
+	18: "Point Obstacle", 19: "Cluster", 20: "Line Obstacle",
+}
+
+type FlightClient struct {
+	httpClient *http.Client
+	cache      fetchCache
+	mu         sync.Mutex
+	token      string
+	clientID   string
+	clientSec  string
+
+	tokenExpiry time.Time
+	authRetryAt time.Time
+
+	// Guarded by limitMu rather than mu so the UI can read the throttle
+	// state while a fetch is sleeping between retries
+	limitMu        sync.Mutex
+	throttledUntil time.Time
+	cooldown       Backoff   // grows with each consecutive 429
+	credits        int       // from X-Rate-Limit-Remaining, -1 until seen
+	creditsResetAt time.Time // OpenSky refills credits daily at midnight UTC
+	auth           AuthState // mirrors the token state for AuthState
+
+	recorder *responseRecorder // nil unless recording raw responses
+}
+
+func NewFlightClient() *FlightClient {
+	fc := &FlightClient{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		cooldown:   Backoff{Base: 30 * time.Second, Max: 30 * time.Minute},
+		credits:    -1,
+	}
+	fc.loadCredentials()
+	fc.auth.Configured = fc.clientID != "" && fc.clientSec != ""
+	return fc
+}
+
+// Name identifies the provider in logs and the UI
+func (fc *FlightClient) Name() string {
+	return "opensky"
+}
+
+// RateLimitInfo reports the credits left and the last back-off OpenSky asked for
+func (fc *FlightClient) RateLimitInfo() RateLimitInfo {
+	fc.limitMu.Lock()
+	defer fc.limitMu.Unlock()
+	return RateLimitInfo{Remaining: fc.credits, ThrottledUntil: fc.throttledUntil, ResetAt: fc.creditsResetAt}
+}
+
+// AuthState reports whether we are signed in to OpenSky, without waiting
+// for a fetch in progress
+func (fc *FlightClient) AuthState() AuthState {
+	fc.limitMu.Lock()
+	defer fc.limitMu.Unlock()
+	return fc.auth
+}
+
+// RequestCost follows OpenSky's pricing of /states/all by the box's area in
+// square degrees
+func (fc *FlightClient) RequestCost(radiusDeg float64) int {
+	switch area := 4 * radiusDeg * radiusDeg; {
+	case area <= 25:
+		return 1
+	case area <= 100:
+		return 2
+	case area <= 400:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// noteCredits records the budget OpenSky reports with every API response
+func (fc *FlightClient) noteCredits(h http.Header) {
+	v, err := strconv.Atoi(h.Get("X-Rate-Limit-Remaining"))
+	if err != nil {
+		return
+	}
+	now := time.Now().UTC()
+	fc.limitMu.Lock()
+	fc.credits = v
+	fc.creditsResetAt = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	fc.limitMu.Unlock()
+}
+
+func (fc *FlightClient) loadCredentials() {
+	// Try Env vars first
+	id := os.Getenv("CLIENT_ID")
+	secret := os.Getenv("CLIENT_SECRET")
+
+	if id != "" && secret != "" {
+		fc.clientID = id
+		fc.clientSec = secret
+		return
+	}
+
+	fmt.Println("CLIENT_ID from env:", fc.clientID)
+
+	// Try loading from file
+	if _, err := os.Stat(credentialsPath); err == nil {
+		// Simple JSON parsing if file exists
+		file, err := os.Open(credentialsPath)
+		if err == nil {
+			defer file.Close()
+			var creds struct {
+				ClientID     string `json:"clientId"`
+				ClientSecret string `json:"clientSecret"`
+			}
+			if err := json.NewDecoder(file).Decode(&creds); err == nil {
+				fc.clientID = creds.ClientID
+				fc.clientSec = creds.ClientSecret
+			}
+		}
+	}
+
+	fmt.Println("CLIENT_ID from file:", fc.clientID)
+}
+
+func (fc *FlightClient) authenticate(ctx context.Context) error {
+	if fc.clientID == "" || fc.clientSec == "" {
+		return nil // No credentials, use anonymous
+	}
+
+	data := url.Values{}
+	data.Set("grant_type", "client_credentials")
+	data.Set("client_id", fc.clientID)
+	data.Set("client_secret", fc.clientSec)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", openSkyAuthURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := fc.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("auth failed: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"` // seconds
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+
+	fc.token = result.AccessToken
+	fc.tokenExpiry = time.Time{}
+	if result.ExpiresIn > 0 {
+		fc.tokenExpiry = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	}
+	return nil
+}
+
+// ensureToken (re)authenticates when there is no token or it is about to
+// expire. On failure we carry on anonymously and retry after authRetryDelay.
+func (fc *FlightClient) ensureToken(ctx context.Context) {
+	if fc.clientID == "" || fc.clientSec == "" {
+		return
+	}
+	valid := fc.token != "" && (fc.tokenExpiry.IsZero() || time.Until(fc.tokenExpiry) > tokenRefreshMargin)
+	if valid || time.Now().Before(fc.authRetryAt) {
+		return
+	}
+
+	fc.token = ""
+	err := fc.authenticate(ctx)
+	if err != nil {
+		fmt.Println("Warning: Authentication failed, falling back to anonymous:", err)
+		fc.authRetryAt = time.Now().Add(authRetryDelay)
+	}
+
+	fc.limitMu.Lock()
+	fc.auth.SignedIn = fc.token != ""
+	fc.auth.Expires = fc.tokenExpiry
+	fc.auth.Err = err
+	fc.auth.RetryAt = fc.authRetryAt
+	fc.limitMu.Unlock()
+}
+
+// authorizedGet performs an authorized API request, re-authenticating once
+// if OpenSky rejects the token (e.g. revoked or expired early)
+func (fc *FlightClient) authorizedGet(ctx context.Context, apiURL string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if fc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+fc.token)
+		}
+
+		resp, err := fc.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		fc.noteCredits(resp.Header)
+		if resp.StatusCode != http.StatusUnauthorized || fc.token == "" || attempt > 0 {
+			return resp, nil
+		}
+
+		resp.Body.Close()
+		fc.token = ""
+		fc.authRetryAt = time.Time{}
+		fc.ensureToken(ctx)
+	}
+}
+
+// RecordTo saves every /states/all response from now on into dir, created
+// if missing
+func (fc *FlightClient) RecordTo(dir string) error {
+	r, err := newResponseRecorder(dir)
+	if err != nil {
+		return err
+	}
+	fc.mu.Lock()
+	fc.recorder = r
+	fc.mu.Unlock()
+	return nil
+}
+
+func (fc *FlightClient) FetchFlights(ctx context.Context, centerLat, centerLon, radiusDeg float64) ([]Flight, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	// Return cached if fresh
+	key := boxKey(centerLat, centerLon, radiusDeg)
+	if cached, ok := fc.cache.get(key, cacheDuration); ok {
+		return cached, nil
+	}
+
+	if until := fc.RateLimitInfo().ThrottledUntil; time.Now().Before(until) {
+		return nil, fmt.Errorf("rate limited until %s", until.Format("15:04:05"))
+	}
+
+	fc.ensureToken(ctx)
+
+	lamin := centerLat - radiusDeg
+	lamax := centerLat + radiusDeg
+	lomin := centerLon - radiusDeg
+	lomax := centerLon + radiusDeg
+
+	apiURL := fmt.Sprintf("%s?lamin=%f&lomin=%f&lamax=%f&lomax=%f",
+		openSkyURL, lamin, lomin, lamax, lomax)
+
+	var resp *http.Response
+	var err error
+	retry := Backoff{Base: openSkyRetryBase, Max: openSkyRetryMax}
+	for attempt := 0; ; attempt++ {
+		resp, err = fc.authorizedGet(ctx, apiURL)
+		transient := err != nil || resp.StatusCode >= 500
+		if !transient || attempt == openSkyMaxRetries || ctx.Err() != nil {
+			break
+		}
+		if err == nil {
+			resp.Body.Close()
+		}
+		select {
+		case <-time.After(retry.Next()):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter := 60 * time.Second
+		if v, err := strconv.Atoi(resp.Header.Get("X-Rate-Limit-Retry-After-Seconds")); err == nil {
+			retryAfter = time.Duration(v) * time.Second
+		}
+		fc.limitMu.Lock()
+		// Repeated 429s mean the quota is exhausted; cool down harder each time
+		// rather than knocking every few seconds
+		if cool := fc.cooldown.Next(); cool > retryAfter {
+			retryAfter = cool
+		}
+		fc.throttledUntil = time.Now().Add(retryAfter)
+		fc.limitMu.Unlock()
+		return nil, fmt.Errorf("rate limit exceeded (429)")
+	}
+	fc.limitMu.Lock()
+	fc.cooldown.Reset()
+	fc.limitMu.Unlock()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if fc.recorder != nil {
+		if err := fc.recorder.save(fc.Name(), time.Now(), body); err != nil {
+			log.Println("Error recording OpenSky response:", err)
+		}
+	}
+
+	flights, warnings, at, err := parseOpenSkyResponse(body, fc.Name())
+	if err != nil {
+		return nil, err
+	}
+	ObserveServerTime(at)
+	if len(warnings) > 0 {
+		log.Printf("OpenSky: skipped %d malformed state vectors, e.g. %v", len(warnings), warnings[0])
+	}
+
+	fc.cache.put(key, flights)
+
+	return flights, nil
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"math"
+	"sort"
+)
+
+// Grid cell size in zoom-0 world pixels, roughly 8 km at mid latitudes
+const indexCellSize = 1.0 / 16
+
+type cellKey struct{ x, y int }
+
+// FlightIndex is a uniform grid over flight positions in Web Mercator world
+// pixels at zoom 0, so screen-space queries at any zoom only visit nearby
+// cells instead of scanning every flight
+type FlightIndex struct {
+	cells      map[cellKey][]int
+	xs, ys     []float64
+	lats, lons []float64
+}
+
+// NewFlightIndex indexes flights by position; results refer to slice indices
+func NewFlightIndex(flights []*Flight) *FlightIndex {
+	ix := &FlightIndex{
+		cells: make(map[cellKey][]int),
+		xs:    make([]float64, len(flights)),
+		ys:    make([]float64, len(flights)),
+		lats:  make([]float64, len(flights)),
+		lons:  make([]float64, len(flights)),
+	}
+	for i, f := range flights {
+		x, y := LatLonToPixels(f.Lat, f.Lon, 0)
+		ix.xs[i], ix.ys[i] = x, y
+		ix.lats[i], ix.lons[i] = f.Lat, f.Lon
+		k := ix.cellOf(x, y)
+		ix.cells[k] = append(ix.cells[k], i)
+	}
+	return ix
+}
+
+func (ix *FlightIndex) cellOf(x, y float64) cellKey {
+	return cellKey{int(math.Floor(x / indexCellSize)), int(math.Floor(y / indexCellSize))}
+}
+
+// InRect returns the flights inside a world-pixel rectangle at the given zoom,
+// in ascending index order so draw order stays stable between frames
+func (ix *FlightIndex) InRect(minX, minY, maxX, maxY float64, zoom float64) []int {
+	scale := math.Pow(2, zoom)
+	minX, minY, maxX, maxY = minX/scale, minY/scale, maxX/scale, maxY/scale
+
+	lo, hi := ix.cellOf(minX, minY), ix.cellOf(maxX, maxY)
+	var out []int
+	// Iterate whichever is smaller: the cells covered or the occupied cells
+	if (hi.x-lo.x+1)*(hi.y-lo.y+1) > len(ix.cells) {
+		for k, idxs := range ix.cells {
+			if k.x >= lo.x && k.x <= hi.x && k.y >= lo.y && k.y <= hi.y {
+				out = ix.appendInside(out, idxs, minX, minY, maxX, maxY)
+			}
+		}
+	} else {
+		for cx := lo.x; cx <= hi.x; cx++ {
+			for cy := lo.y; cy <= hi.y; cy++ {
+				out = ix.appendInside(out, ix.cells[cellKey{cx, cy}], minX, minY, maxX, maxY)
+			}
+		}
+	}
+	sort.Ints(out)
+	return out
+}
+
+func (ix *FlightIndex) appendInside(out, idxs []int, minX, minY, maxX, maxY float64) []int {
+	for _, i := range idxs {
+		if ix.xs[i] >= minX && ix.xs[i] <= maxX && ix.ys[i] >= minY && ix.ys[i] <= maxY {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// Nearest returns the flight closest to world pixel (x, y) at the given zoom
+// and within radiusPx, or -1
+func (ix *FlightIndex) Nearest(x, y float64, zoom float64, radiusPx float64) int {
+	best, bestDist := -1, radiusPx
+	scale := math.Pow(2, zoom)
+	for _, i := range ix.InRect(x-radiusPx, y-radiusPx, x+radiusPx, y+radiusPx, zoom) {
+		d := math.Hypot(ix.xs[i]*scale-x, ix.ys[i]*scale-y)
+		if d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// Within returns the flights within radiusKm of lat, lon along the ground, in
+// ascending index order. Zones such as the noise and overhead areas around
+// home use it instead of measuring to every flight.
+func (ix *FlightIndex) Within(lat, lon, radiusKm float64) []int {
+	// The box around the circle; a degree of longitude shrinks towards the
+	// pole, so size it for the box's poleward edge
+	dLat := radiusKm / (6371 * math.Pi / 180)
+	dLon := 180.0
+	if c := math.Cos(math.Min(math.Abs(lat)+dLat, 90) * math.Pi / 180); c*dLon > dLat {
+		dLon = dLat / c
+	}
+	minX, minY := LatLonToPixels(math.Min(lat+dLat, 85), lon-dLon, 0)
+	maxX, maxY := LatLonToPixels(math.Max(lat-dLat, -85), lon+dLon, 0)
+
+	var out []int
+	for _, i := range ix.InRect(minX, minY, maxX, maxY, 0) {
+		if Distance(lat, lon, ix.lats[i], ix.lons[i]) <= radiusKm {
+			out = append(out, i)
+		}
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Within finds the same flights as measuring to every one
+func TestFlightIndexWithin(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	flights := make([]*Flight, 2000)
+	for i := range flights {
+		flights[i] = &Flight{Lat: 60.3 + rng.Float64()*4 - 2, Lon: 24.9 + rng.Float64()*8 - 4}
+	}
+	ix := NewFlightIndex(flights)
+	for _, c := range []struct{ lat, lon, km float64 }{
+		{60.3, 24.9, 3}, {60.3, 24.9, 10}, {61.5, 26, 50}, {60.3, 24.9, 400}, {0, 0, 10},
+	} {
+		var want []int
+		for i, f := range flights {
+			if Distance(c.lat, c.lon, f.Lat, f.Lon) <= c.km {
+				want = append(want, i)
+			}
+		}
+		if got := ix.Within(c.lat, c.lon, c.km); !slices.Equal(got, want) {
+			t.Errorf("within %v km of %v,%v: %d flights, want %d", c.km, c.lat, c.lon, len(got), len(want))
+		}
+	}
+}
+
+// The zones around home are read through the snapshot's index
+func TestSnapshotZones(t *testing.T) {
+	const homeLat, homeLon = 60.3, 24.9
+	now := time.Date(2025, 6, 14, 12, 0, 0, 0, time.UTC)
+	p := NewFlightPipeline(homeLat, homeLon)
+	s := p.process(batch{at: now, flights: []Flight{
+		{Icao24: "far001", Callsign: "FAR1", Lat: 61.3, Lon: 24.9, AltitudeFt: 2000},
+		{Icao24: "low001", Callsign: "LOW1", Lat: 60.31, Lon: 24.9, AltitudeFt: 2000},
+		{Icao24: "mid001", Callsign: "MID1", Lat: 60.35, Lon: 24.9, AltitudeFt: 9000},
+	}})
+
+	if events := NewNoiseCounter(homeLat, homeLon).Observe(s, DefaultNoiseAltitudeFt); len(events) != 1 || events[0].Icao24 != "low001" {
+		t.Errorf("noise events = %+v, want low001", events)
+	}
+	arrived, _ := NewOverheadLog(homeLat, homeLon).Observe(s)
+	if len(arrived) != 2 || arrived[0].Icao24 != "low001" || arrived[1].Icao24 != "mid001" {
+		t.Errorf("overhead arrivals = %+v, want low001 and mid001", arrived)
+	}
+	if got := (&FlightSnapshot{}).Within(homeLat, homeLon, 10); got != nil {
+		t.Errorf("empty snapshot = %v", got)
+	}
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// A flight missing from polls for this long is drawn as stale...
+	flightStaleAfter = 30 * time.Second
+	// ...and dropped after this long
+	flightExpireAfter = 2 * time.Minute
+)
+
+// FlightStore merges poll results by icao24. Each aircraft keeps the same
+// *Flight for as long as it is tracked and is updated in place, so pointers
+// held by the UI (selected plane, quiz target) follow the aircraft instead of
+// going stale when a new poll arrives.
+//
+// The store is safe for concurrent use. The flights behind the returned
+// pointers are updated in place by Merge, so goroutines other than the one
+// calling Merge should read Snapshot() copies instead.
+type FlightStore struct {
+	mu       sync.RWMutex
+	byIcao   map[string]*Flight
+	lastSeen map[string]time.Time
+	list     []*Flight // in first-seen order
+	index    *FlightIndex
+}
+
+func NewFlightStore() *FlightStore {
+	return &FlightStore{
+		byIcao:   make(map[string]*Flight),
+		lastSeen: make(map[string]time.Time),
+		index:    NewFlightIndex(nil),
+	}
+}
+
+// Merge applies one poll: known aircraft are updated, new ones added, missing
+// ones marked stale and eventually expired
+func (s *FlightStore) Merge(flights []Flight, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, f := range flights {
+		if p, ok := s.byIcao[f.Icao24]; ok {
+			*p = f
+		} else {
+			p := new(Flight)
+			*p = f
+			s.byIcao[f.Icao24] = p
+			s.list = append(s.list, p)
+		}
+		s.lastSeen[f.Icao24] = now
+	}
+
+	kept := s.list[:0]
+	for _, p := range s.list {
+		age := Elapsed(s.lastSeen[p.Icao24], now)
+		if age > flightExpireAfter {
+			delete(s.byIcao, p.Icao24)
+			delete(s.lastSeen, p.Icao24)
+			p.Stale = true // anyone still holding it can tell
+			continue
+		}
+		p.Stale = age > flightStaleAfter
+		kept = append(kept, p)
+	}
+	clear(s.list[len(kept):])
+	s.list = kept
+	s.index = NewFlightIndex(s.list)
+}
+
+// Get returns the tracked flight for icao24, or nil
+func (s *FlightStore) Get(icao24 string) *Flight {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.byIcao[icao24]
+}
+
+// List returns the tracked flights in first-seen order
+func (s *FlightStore) List() []*Flight {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*Flight(nil), s.list...)
+}
+
+// Snapshot returns copies of the tracked flights, safe to read from any goroutine
+func (s *FlightStore) Snapshot() []Flight {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]Flight, len(s.list))
+	for i, p := range s.list {
+		out[i] = *p
+	}
+	return out
+}
+
+func (s *FlightStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.list)
+}
+
+// InRect returns the flights inside the world-pixel rectangle at zoom, see FlightIndex.InRect
+func (s *FlightStore) InRect(minX, minY, maxX, maxY float64, zoom float64) []*Flight {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	idx := s.index.InRect(minX, minY, maxX, maxY, zoom)
+	out := make([]*Flight, len(idx))
+	for i, j := range idx {
+		out[i] = s.list[j]
+	}
+	return out
+}
+
+// Nearest returns the flight closest to the world-pixel point within
+// radiusPx, or nil
+func (s *FlightStore) Nearest(x, y float64, zoom float64, radiusPx float64) *Flight {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if i := s.index.Nearest(x, y, zoom, radiusPx); i >= 0 {
+		return s.list[i]
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const openSkyTracksURL = "https://opensky-network.org/api/tracks/all?icao24=%s&time=0"
+
+// ErrTracksUnsupported is returned by FetchTrack for providers without a track endpoint
+var ErrTracksUnsupported = errors.New("provider has no track history")
+
+// TrackProvider is implemented by providers that can return the path an
+// aircraft has flown so far
+type TrackProvider interface {
+	FetchTrack(ctx context.Context, icao24 string) ([]TrackPoint, error)
+}
+
+// FetchTrack returns the flown path of icao24 from p, oldest point first
+func FetchTrack(ctx context.Context, p FlightProvider, icao24 string) ([]TrackPoint, error) {
+	if tp, ok := p.(TrackProvider); ok {
+		return tp.FetchTrack(ctx, icao24)
+	}
+	return nil, ErrTracksUnsupported
+}
+
+// FetchTrack uses the failover's first provider that supports tracks
+func (fp *FailoverProvider) FetchTrack(ctx context.Context, icao24 string) ([]TrackPoint, error) {
+	for _, p := range fp.providers {
+		if tp, ok := p.(TrackProvider); ok {
+			return tp.FetchTrack(ctx, icao24)
+		}
+	}
+	return nil, ErrTracksUnsupported
+}
+
+// FetchTrack fetches the aircraft's current flight from OpenSky's tracks endpoint
+func (fc *FlightClient) FetchTrack(ctx context.Context, icao24 string) ([]TrackPoint, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if until := fc.RateLimitInfo().ThrottledUntil; time.Now().Before(until) {
+		return nil, fmt.Errorf("rate limited until %s", until.Format("15:04:05"))
+	}
+	fc.ensureToken(ctx)
+
+	resp, err := fc.authorizedGet(ctx, fmt.Sprintf(openSkyTracksURL, strings.ToLower(icao24)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil // no track known for this aircraft
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tracks request failed with status: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		// [time, latitude, longitude, baro_altitude (m), true_track, on_ground]
+		Path [][]interface{} `json:"path"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	points := make([]TrackPoint, 0, len(result.Path))
+	for _, wp := range result.Path {
+		if len(wp) < 4 {
+			continue
+		}
+		t, okT := wp[0].(float64)
+		lat, okLat := wp[1].(float64)
+		lon, okLon := wp[2].(float64)
+		if !okT || !okLat || !okLon {
+			continue
+		}
+		altM, _ := wp[3].(float64)
+		points = append(points, TrackPoint{
+			Lat:        lat,
+			Lon:        lon,
+			AltitudeFt: int(altM * 3.28084),
+			Time:       time.Unix(int64(t), 0),
+		})
+	}
+	return points, nil
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"math"
+)
+
+const (
+	TileSize = 256
+)
+
+// placeholderLevels is how many zoom levels up the map looks for a cached
+// tile to stretch over one that is still loading
+const placeholderLevels = 3
+
+// TileAncestor is a cached tile higher up that can be stretched over one
+// still loading: the tile at Z/X/Y, Levels zoom levels up, of which the
+// loading tile is the square in column Col and row Row when it is cut into
+// 2^Levels squares a side
+type TileAncestor struct {
+	Z, X, Y  int
+	Levels   int
+	Col, Row int
+}
+
+// Sub is the square of an ancestor image size pixels a side that covers
+// the loading tile, as its corner and side; side 0 when the image is too
+// small to have a pixel for it
+func (a TileAncestor) Sub(size int) (x, y, side int) {
+	side = size >> a.Levels
+	return a.Col * side, a.Row * side, side
+}
+
+// TileAncestors lists the ancestors of tile z/x/y that may stand in for it,
+// nearest first
+func TileAncestors(z, x, y int) []TileAncestor {
+	var out []TileAncestor
+	for d := 1; d <= placeholderLevels && d <= z; d++ {
+		mask := 1<<d - 1
+		out = append(out, TileAncestor{Z: z - d, X: x >> d, Y: y >> d, Levels: d, Col: x & mask, Row: y & mask})
+	}
+	return out
+}
+
+// LatLonToPixels converts latitude and longitude to pixel coordinates at a given zoom level.
+// The zoom may be fractional, for a map scaled between two tile levels.
+func LatLonToPixels(lat, lon float64, zoom float64) (float64, float64) {
+	scale := math.Pow(2, zoom)
+	x := (lon + 180.0) / 360.0 * scale * float64(TileSize)
+
+	latRad := lat * math.Pi / 180.0
+	y := (1.0 - math.Log(math.Tan(latRad)+1.0/math.Cos(latRad))/math.Pi) / 2.0 * scale * float64(TileSize)
+
+	return x, y
+}
+
+// PixelsToLatLon converts pixel coordinates at a given zoom level to latitude and longitude.
+func PixelsToLatLon(x, y float64, zoom float64) (float64, float64) {
+	scale := math.Pow(2, zoom)
+	lon := (x / (scale * float64(TileSize)) * 360.0) - 180.0
+
+	n := math.Pi - 2.0*math.Pi*y/(scale*float64(TileSize))
+	lat := 180.0 / math.Pi * math.Atan(0.5*(math.Exp(n)-math.Exp(-n)))
+
+	return lat, lon
+}
+
+// Distance calculates distance between two lat/lon points in km (Haversine formula).
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	const R = 6371 // Earth radius in km
+	dLat := (lat2 - lat1) * math.Pi / 180.0
+	dLon := (lon2 - lon1) * math.Pi / 180.0
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180.0)*math.Cos(lat2*math.Pi/180.0)*
+			math.Sin(dLon/2)*math.Sin(dLon/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+	return R * c
+}
+
+// Bearing returns the initial great-circle bearing from point 1 to point 2 in degrees (0-360).
+func Bearing(lat1, lon1, lat2, lon2 float64) float64 {
+	lat1Rad := lat1 * math.Pi / 180.0
+	lat2Rad := lat2 * math.Pi / 180.0
+	dLon := (lon2 - lon1) * math.Pi / 180.0
+	y := math.Sin(dLon) * math.Cos(lat2Rad)
+	x := math.Cos(lat1Rad)*math.Sin(lat2Rad) - math.Sin(lat1Rad)*math.Cos(lat2Rad)*math.Cos(dLon)
+	return math.Mod(math.Atan2(y, x)*180.0/math.Pi+360.0, 360.0)
+}
+
+// Destination returns the point reached by travelling distKm from lat/lon along bearingDeg.
+func Destination(lat, lon, bearingDeg, distKm float64) (float64, float64) {
+	const R = 6371 // Earth radius in km
+	latRad := lat * math.Pi / 180.0
+	lonRad := lon * math.Pi / 180.0
+	brng := bearingDeg * math.Pi / 180.0
+	d := distKm / R
+	lat2 := math.Asin(math.Sin(latRad)*math.Cos(d) + math.Cos(latRad)*math.Sin(d)*math.Cos(brng))
+	lon2 := lonRad + math.Atan2(math.Sin(brng)*math.Sin(d)*math.Cos(latRad), math.Cos(d)-math.Sin(latRad)*math.Sin(lat2))
+	return lat2 * 180.0 / math.Pi, lon2 * 180.0 / math.Pi
+}
+
+// KmToDegrees converts a north-south distance in km to degrees of latitude.
+func KmToDegrees(km float64) float64 {
+	return km / 111.32
+}
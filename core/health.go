@@ -0,0 +1,266 @@
+package core
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FetchHealth keeps track of how polling the flight provider is going, for
+// the status screen. The polling goroutine records, the UI reads.
+type FetchHealth struct {
+	mu sync.Mutex
+	s  FetchStatus
+}
+
+// FetchStatus is what FetchHealth has seen so far
+type FetchStatus struct {
+	LastSuccess time.Time // zero until a fetch has worked
+	LastFlights int       // flights the last successful fetch returned
+	LastError   error     // from the most recent failed fetch
+	LastErrorAt time.Time
+	Failures    int // consecutive failed fetches
+}
+
+// Record notes the outcome of one fetch
+func (h *FetchHealth) Record(flights int, err error, at time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err != nil {
+		h.s.LastError, h.s.LastErrorAt = err, at
+		h.s.Failures++
+		return
+	}
+	h.s.LastSuccess, h.s.LastFlights = at, flights
+	h.s.Failures = 0
+}
+
+// Status returns a copy of what has been recorded
+func (h *FetchHealth) Status() FetchStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.s
+}
+
+// AuthState is how a provider is signed in to its API
+type AuthState struct {
+	Configured bool      // credentials were found
+	SignedIn   bool      // holding a token
+	Expires    time.Time // zero if the token doesn't say
+	Err        error     // why the last sign-in failed, nil if it didn't
+	RetryAt    time.Time // when a failed sign-in is tried again
+}
+
+func (a AuthState) String() string {
+	switch {
+	case !a.Configured:
+		return "anonymous (no credentials)"
+	case a.SignedIn && !a.Expires.IsZero():
+		return "signed in until " + a.Expires.Local().Format("15:04")
+	case a.SignedIn:
+		return "signed in"
+	case a.Err != nil:
+		return fmt.Sprintf("sign-in failed (%v), retry at %s", a.Err, a.RetryAt.Local().Format("15:04"))
+	}
+	return "not signed in yet"
+}
+
+// providerAuth finds the first provider in p that signs in to its API
+func providerAuth(p FlightProvider) (AuthState, bool) {
+	switch p := p.(type) {
+	case interface{ AuthState() AuthState }:
+		return p.AuthState(), true
+	case *FailoverProvider, *MergeProvider:
+		for _, inner := range innerProviders(p) {
+			if a, ok := providerAuth(inner); ok {
+				return a, true
+			}
+		}
+	}
+	return AuthState{}, false
+}
+
+// DirUsage is how much a directory holds
+type DirUsage struct {
+	Bytes int64
+	Files int
+}
+
+func (u DirUsage) String() string {
+	return fmt.Sprintf("%s in %d files", FormatBytes(u.Bytes), u.Files)
+}
+
+// DataDirUsage adds up the files in the persistent data directory
+func DataDirUsage() (DirUsage, error) {
+	var u DirUsage
+	err := filepath.WalkDir(dataDir(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		u.Bytes += info.Size()
+		u.Files++
+		return nil
+	})
+	return u, err
+}
+
+// FormatBytes renders a size as e.g. "12.3 MB"
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// CacheSize is the number of entries some in-memory cache holds
+type CacheSize struct {
+	Name    string
+	Entries int
+}
+
+// HealthReport gathers what the status screen shows: the first place to
+// look when no planes are showing
+type HealthReport struct {
+	Provider string
+	Active   string // the provider a failover chain is using, if any
+	Fetch    FetchStatus
+	Limits   RateLimitInfo
+	Auth     *AuthState // nil if the provider doesn't sign in
+	Caches   []CacheSize
+	DataDir  string
+	Disk     DirUsage
+	DiskErr  error
+	Build    BuildInfo
+	Startup  []string      // errors from loading at startup
+	Update   *Release      // newer release found by the update checker, if any
+	Backup   *BackupStatus // nil unless backups are configured
+	Clock    ClockStatus
+	// How players do against each distractor strategy; see
+	// DistractorExperiment
+	Experiments []StrategyResult
+	CheckedAt   time.Time
+}
+
+// CheckHealth reports on p and the data directory. The caller adds the
+// caches and startup errors it knows about. It walks the data directory, so
+// call it when the status screen opens rather than every frame.
+func CheckHealth(p FlightProvider, fetch *FetchHealth) HealthReport {
+	r := HealthReport{
+		Provider:  p.Name(),
+		Fetch:     fetch.Status(),
+		Limits:    p.RateLimitInfo(),
+		DataDir:   dataDir(),
+		Build:     Build(),
+		CheckedAt: time.Now(),
+	}
+	r.Clock = CheckClock(r.CheckedAt)
+	if fp, ok := p.(*FailoverProvider); ok {
+		r.Active = fp.Active()
+	}
+	if a, ok := providerAuth(p); ok {
+		r.Auth = &a
+	}
+	r.Disk, r.DiskErr = DataDirUsage()
+	return r
+}
+
+// StatusLine is one row of the status screen. Problem marks the rows that
+// explain why planes might be missing, for highlighting.
+type StatusLine struct {
+	Label, Value string
+	Problem      bool
+}
+
+// Lines renders the report as label/value rows
+func (r HealthReport) Lines() []StatusLine {
+	ago := func(t time.Time) string {
+		return Elapsed(t, r.CheckedAt).Round(time.Second).String() + " ago"
+	}
+
+	provider := r.Provider
+	if r.Active != "" {
+		provider += " (using " + r.Active + ")"
+	}
+	lines := []StatusLine{{Label: "Provider", Value: provider}}
+
+	switch f := r.Fetch; {
+	case f.LastSuccess.IsZero() && f.LastError == nil:
+		lines = append(lines, StatusLine{Label: "Last fetch", Value: "none yet"})
+	case f.LastSuccess.IsZero():
+		lines = append(lines, StatusLine{Label: "Last fetch", Value: "never succeeded", Problem: true})
+	default:
+		lines = append(lines, StatusLine{
+			Label: "Last fetch",
+			Value: fmt.Sprintf("%s, %d flights", ago(f.LastSuccess), f.LastFlights),
+			// Nothing in range is worth a look too: the box or filters may be off
+			Problem: f.LastFlights == 0,
+		})
+	}
+	if f := r.Fetch; f.LastError != nil {
+		lines = append(lines, StatusLine{
+			Label:   "Last error",
+			Value:   fmt.Sprintf("%s: %v", ago(f.LastErrorAt), f.LastError),
+			Problem: f.Failures > 0,
+		})
+	}
+
+	limits := "not reported"
+	if r.Limits.Remaining >= 0 {
+		limits = fmt.Sprintf("%d credits left", r.Limits.Remaining)
+		if !r.Limits.ResetAt.IsZero() {
+			limits += ", refill " + r.Limits.ResetAt.Local().Format("15:04")
+		}
+	}
+	throttled := r.CheckedAt.Before(r.Limits.ThrottledUntil)
+	if throttled {
+		limits = "throttled until " + r.Limits.ThrottledUntil.Local().Format("15:04:05")
+	}
+	lines = append(lines, StatusLine{Label: "Rate limit", Value: limits, Problem: throttled || r.Limits.Remaining == 0})
+	lines = append(lines, StatusLine{Label: "Clock", Value: r.Clock.String(), Problem: !r.Clock.Sane})
+
+	if r.Auth != nil {
+		lines = append(lines, StatusLine{Label: "Auth", Value: r.Auth.String(), Problem: r.Auth.Err != nil})
+	}
+
+	for _, c := range r.Caches {
+		lines = append(lines, StatusLine{Label: c.Name, Value: fmt.Sprintf("%d entries", c.Entries)})
+	}
+
+	disk := r.Disk.String()
+	if r.DiskErr != nil {
+		disk = r.DiskErr.Error()
+	}
+	lines = append(lines,
+		StatusLine{Label: "Data dir", Value: r.DataDir},
+		StatusLine{Label: "Disk usage", Value: disk, Problem: r.DiskErr != nil},
+	)
+	for _, e := range r.Startup {
+		lines = append(lines, StatusLine{Label: "Startup error", Value: e, Problem: true})
+	}
+	lines = append(lines, StatusLine{Label: "Version", Value: r.Build.String()})
+	if r.Build.GoVersion != "" {
+		lines = append(lines, StatusLine{Label: "Go", Value: r.Build.GoVersion})
+	}
+	if r.Update != nil {
+		lines = append(lines, StatusLine{Label: "Update", Value: r.Update.Tag + " available at " + r.Update.URL})
+	}
+	if r.Backup != nil {
+		lines = append(lines, StatusLine{Label: "Last backup", Value: r.Backup.String(), Problem: r.Backup.LastErr != nil})
+	}
+	for _, e := range r.Experiments {
+		lines = append(lines, StatusLine{Label: "Distractor A/B", Value: e.String()})
+	}
+	return lines
+}
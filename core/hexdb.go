@@ -0,0 +1,150 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	hexdbAircraftURL = "https://hexdb.io/api/v1/aircraft/%s"
+	hexdbRouteURL    = "https://hexdb.io/api/v1/route/icao/%s"
+	hexdbAirportURL  = "https://hexdb.io/api/v1/airport/icao/%s"
+)
+
+// HexdbResolver uses hexdb.io, which knows aircraft by their ICAO24 address
+// as well as routes by callsign. It is what identifies flights without a
+// usable callsign.
+type HexdbResolver struct {
+	client *http.Client
+
+	mu       sync.Mutex
+	airports map[string]hexdbAirport // by ICAO code
+}
+
+// hexdbAirport is a looked-up airport's display name and position
+type hexdbAirport struct {
+	name     string
+	lat, lon float64
+}
+
+func NewHexdbResolver() *HexdbResolver {
+	return &HexdbResolver{
+		client:   &http.Client{Timeout: 10 * time.Second},
+		airports: make(map[string]hexdbAirport),
+	}
+}
+
+// FetchAircraftDetails returns the type and registration of the aircraft
+// with address icao24. Route fields are left empty.
+func (r *HexdbResolver) FetchAircraftDetails(ctx context.Context, icao24 string) (*ResolvedDetails, error) {
+	var ac struct {
+		Registration string `json:"Registration"`
+		Manufacturer string `json:"Manufacturer"`
+		Type         string `json:"Type"`
+		TypeCode     string `json:"ICAOTypeCode"`
+	}
+	if err := r.getJSON(ctx, fmt.Sprintf(hexdbAircraftURL, url.PathEscape(strings.ToLower(icao24))), &ac); err != nil {
+		return nil, err
+	}
+	model := strings.TrimSpace(ac.Manufacturer + " " + ac.Type)
+	if model == "" {
+		model = ac.TypeCode
+	}
+	if model == "" && ac.Registration == "" {
+		return nil, ErrRouteUnknown
+	}
+	return &ResolvedDetails{Model: model, Registration: ac.Registration}, nil
+}
+
+// FetchFlightDetails returns the origin and destination of callsign's route
+func (r *HexdbResolver) FetchFlightDetails(ctx context.Context, callsign string) (*ResolvedDetails, error) {
+	var route struct {
+		Route string `json:"route"` // ICAO codes, e.g. "EFHK-ESSA"
+	}
+	if err := r.getJSON(ctx, fmt.Sprintf(hexdbRouteURL, url.PathEscape(strings.TrimSpace(callsign))), &route); err != nil {
+		return nil, err
+	}
+	stops := strings.Split(route.Route, "-")
+	if len(stops) < 2 {
+		return nil, ErrRouteUnknown
+	}
+	origin, err := r.airport(ctx, stops[0])
+	if err != nil {
+		return nil, err
+	}
+	dest, err := r.airport(ctx, stops[len(stops)-1])
+	if err != nil {
+		return nil, err
+	}
+	return &ResolvedDetails{
+		Destination:     dest.name,
+		RealDestination: dest.name,
+		Origin:          origin.name,
+		DestLat:         dest.lat,
+		DestLon:         dest.lon,
+		OrigLat:         origin.lat,
+		OrigLon:         origin.lon,
+	}, nil
+}
+
+// airport looks up an ICAO code, naming it "Airport, Country" and
+// remembering answers like RouteResolver does
+func (r *HexdbResolver) airport(ctx context.Context, icao string) (hexdbAirport, error) {
+	r.mu.Lock()
+	a, ok := r.airports[icao]
+	r.mu.Unlock()
+	if ok {
+		return a, nil
+	}
+
+	var ap struct {
+		Airport     string  `json:"airport"`
+		CountryCode string  `json:"country_code"`
+		Lat         float64 `json:"latitude"`
+		Lon         float64 `json:"longitude"`
+	}
+	if err := r.getJSON(ctx, fmt.Sprintf(hexdbAirportURL, url.PathEscape(icao)), &ap); err != nil {
+		return a, fmt.Errorf("airport %s: %w", icao, err)
+	}
+	name := strings.TrimSuffix(strings.TrimSpace(ap.Airport), " Airport")
+	if name == "" {
+		return a, fmt.Errorf("airport %s: %w", icao, ErrRouteUnknown)
+	}
+	if c, ok := countryNames[ap.CountryCode]; ok {
+		name += ", " + c
+	} else if ap.CountryCode != "" {
+		name += ", " + ap.CountryCode
+	}
+	a = hexdbAirport{name: name, lat: ap.Lat, lon: ap.Lon}
+
+	r.mu.Lock()
+	r.airports[icao] = a
+	r.mu.Unlock()
+	return a, nil
+}
+
+func (r *HexdbResolver) getJSON(ctx context.Context, apiURL string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrRouteUnknown
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("hexdb: status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
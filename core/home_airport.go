@@ -0,0 +1,119 @@
+package core
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// Airports with scheduled flights to fewer places are airfields the
+	// home airport shouldn't default to
+	majorAirportRoutes = 10
+	// How many of the nearest major airports the settings screen offers
+	homeAirportChoices = 5
+)
+
+// defaultHomeAirport is the reference airport when there is no airport
+// database to find a nearer one in
+var defaultHomeAirport = Airport{
+	Name:    "Helsinki Vantaa Airport",
+	City:    "Helsinki",
+	Country: "Finland",
+	IATA:    "HEL",
+	ICAO:    "EFHK",
+	Lat:     60.3172,
+	Lon:     24.9633,
+}
+
+var homeAirport atomic.Pointer[Airport]
+
+// HomeAirport is the reference airport: flights landing there are asked
+// about their origin, arrivals are timed to it and the weather is read
+// there
+func HomeAirport() Airport {
+	if a := homeAirport.Load(); a != nil {
+		return *a
+	}
+	return defaultHomeAirport
+}
+
+// SetHomeAirport makes a the reference airport
+func SetHomeAirport(a Airport) {
+	homeAirport.Store(&a)
+}
+
+// isHomeAirport spots flights arriving at the home airport, which are asked
+// about their origin instead of their destination
+func isHomeAirport(name string) bool {
+	home := HomeAirport()
+	return SamePlace(name, home.Place()) || SamePlace(name, home.Name) ||
+		(home.Code() != "" && strings.EqualFold(strings.TrimSpace(name), home.Code()))
+}
+
+// NearestMajorAirports is the n major airports nearest lat, lon, nearest
+// first. Before routes are imported every airport counts as major.
+func (db *AirportDB) NearestMajorAirports(lat, lon float64, n int) []Airport {
+	if db.Len() == 0 {
+		return nil
+	}
+	var major []Airport
+	for _, a := range db.airports {
+		if len(db.routes) == 0 || len(db.routes[a.Code()]) >= majorAirportRoutes {
+			major = append(major, a)
+		}
+	}
+	slices.SortFunc(major, func(a, b Airport) int {
+		return cmp.Compare(Distance(lat, lon, a.Lat, a.Lon), Distance(lat, lon, b.Lat, b.Lon))
+	})
+	return major[:min(n, len(major))]
+}
+
+// FindHomeAirport is the reference airport for a home at lat, lon: the one
+// with code override when the database knows it, otherwise the nearest
+// major airport, otherwise Helsinki-Vantaa
+func FindHomeAirport(db *AirportDB, lat, lon float64, override string) Airport {
+	if a, ok := db.Lookup(override); ok {
+		return a
+	}
+	if nearest := db.NearestMajorAirports(lat, lon, 1); len(nearest) > 0 {
+		return nearest[0]
+	}
+	return defaultHomeAirport
+}
+
+// HomeAirportChoices is what the home airport setting steps through: ""
+// for the detected airport, then the codes of the nearest major airports.
+// An override from further away stays among them.
+func HomeAirportChoices(db *AirportDB, lat, lon float64, current string) []string {
+	choices := []string{""}
+	for _, a := range db.NearestMajorAirports(lat, lon, homeAirportChoices) {
+		choices = append(choices, a.Code())
+	}
+	if current != "" && !slices.Contains(choices, current) {
+		choices = append(choices, current)
+	}
+	return choices
+}
+
+// StepChoice moves cur one step forward (dir > 0) or back through choices,
+// stopping at either end. A cur not among them steps from the first.
+func StepChoice[T comparable](choices []T, cur T, dir int) T {
+	i := max(slices.Index(choices, cur), 0)
+	if dir > 0 && i < len(choices)-1 {
+		i++
+	} else if dir < 0 && i > 0 {
+		i--
+	}
+	return choices[i]
+}
+
+// HomeAirportLabel describes the setting for the settings screen
+func HomeAirportLabel(override string) string {
+	code := HomeAirport().Code()
+	if override == "" {
+		return "Auto (" + code + ")"
+	}
+	return code
+}
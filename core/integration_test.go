@@ -0,0 +1,349 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// simProvider serves a fixed sky, standing in for a live provider
+type simProvider struct {
+	flights []Flight
+	calls   int
+}
+
+func (p *simProvider) Name() string                 { return "sim" }
+func (p *simProvider) RateLimitInfo() RateLimitInfo { return RateLimitInfo{Remaining: -1} }
+
+func (p *simProvider) FetchFlights(ctx context.Context, lat, lon, radiusDeg float64) ([]Flight, error) {
+	p.calls++
+	return append([]Flight(nil), p.flights...), nil
+}
+
+// stubResolver answers from a table instead of scraping; callsigns missing
+// from it fail like a blocked scrape
+type stubResolver struct {
+	details map[string]*ResolvedDetails
+	asked   []string
+}
+
+func (r *stubResolver) FetchFlightDetails(ctx context.Context, callsign string) (*ResolvedDetails, error) {
+	r.asked = append(r.asked, callsign)
+	d, ok := r.details[callsign]
+	if !ok {
+		return nil, errors.New("scrape blocked")
+	}
+	return d, nil
+}
+
+func airborne(icao, callsign string) Flight {
+	return Flight{Icao24: icao, Callsign: callsign, Lat: 60.3, Lon: 24.9, AltitudeFt: 20000, VelocityKts: 400, Category: "Large"}
+}
+
+// newTestSession wires a session to the simulated provider, the stub
+// resolver and in-memory storage
+func newTestSession(t *testing.T, p *simProvider, r *stubResolver) *Session {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir()) // for anything still reaching for the data directory
+	return &Session{
+		Provider: p,
+		Resolver: r,
+		Data:     NewDataManager(NewMemoryStorage()),
+		HomeLat:  60.3,
+		HomeLon:  24.9,
+		Player:   "alice",
+	}
+}
+
+func standardSky() (*simProvider, *stubResolver) {
+	p := &simProvider{flights: []Flight{
+		airborne("aaa001", "FIN1"),
+		airborne("aaa002", "SAS2"),
+		airborne("aaa003", "DLH3"),
+	}}
+	r := &stubResolver{details: map[string]*ResolvedDetails{
+		"FIN1": {Origin: "Helsinki-Vantaa, Finland", RealDestination: "Stockholm, Sweden", Model: "Airbus A321"},
+		"SAS2": {Origin: "Copenhagen, Denmark", RealDestination: "Helsinki-Vantaa, Finland", Model: "Airbus A320neo"},
+		"DLH3": {Origin: "Frankfurt, Germany", RealDestination: "Oslo, Norway", Model: "Boeing 737-800"},
+	}}
+	return p, r
+}
+
+// playGame answers every round with pick until the game is over
+func playGame(t *testing.T, s *Session, pick func(q Question) string, elapsed time.Duration) {
+	t.Helper()
+	ctx := context.Background()
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	for s.State != SessionOver {
+		if s.State != SessionPlaying {
+			t.Fatalf("round %d: state %v, want playing", s.Round, s.State)
+		}
+		if !slices.Contains(s.Question.Options, s.Question.Correct) {
+			t.Fatalf("round %d: options %v miss the answer %q", s.Round, s.Question.Options, s.Question.Correct)
+		}
+		if _, err := s.Answer(pick(s.Question), elapsed); err != nil {
+			t.Fatalf("Answer: %v", err)
+		}
+		if err := s.Next(ctx); err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+	}
+	if s.Round != RoundsPerGame+1 {
+		t.Errorf("game ended after round %d, want %d", s.Round-1, RoundsPerGame)
+	}
+}
+
+func correctAnswer(q Question) string { return q.Correct }
+
+func TestIntegrationPerfectGameIsScoredAndSaved(t *testing.T) {
+	p, r := standardSky()
+	s := newTestSession(t, p, r)
+
+	playGame(t, s, correctAnswer, 0)
+
+	if want := RoundsPerGame * (roundBasePoints + roundMaxBonus); s.Score != want {
+		t.Errorf("score = %d, want %d", s.Score, want)
+	}
+	u, err := s.Finish()
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if u.GamesPlayed != 1 || u.BestScore != s.Score || u.TotalScore != s.Score {
+		t.Errorf("saved stats %+v don't match the game", u)
+	}
+
+	// Everything must survive a fresh DataManager, as after a restart
+	dm := NewDataManager(s.Data.store)
+	users, err := dm.LoadUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users["alice"].BestScore != s.Score {
+		t.Errorf("users.json has %+v", users["alice"])
+	}
+	if len(users["alice"].Difficulty.Recent) != RoundsPerGame {
+		t.Errorf("difficulty not persisted: %+v", users["alice"].Difficulty)
+	}
+	top, _, err := dm.GetLeaderboard()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 1 || top[0].Name != "alice" || top[0].Score != s.Score {
+		t.Errorf("leaderboard = %+v", top)
+	}
+	airports, err := dm.LoadAirports()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(airports) == 0 {
+		t.Error("route rounds should have saved the airports they used")
+	}
+}
+
+func TestIntegrationInboundFlightsAskForOrigin(t *testing.T) {
+	p, r := standardSky()
+	p.flights = p.flights[1:2] // only SAS2, which lands at Helsinki
+	s := newTestSession(t, p, r)
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(s.Question.Text, "from") || s.Question.Correct != "Copenhagen, Denmark" {
+		t.Errorf("inbound question = %+v", s.Question)
+	}
+}
+
+func TestIntegrationWrongAnswersAndTimeoutsScoreNothing(t *testing.T) {
+	p, r := standardSky()
+	s := newTestSession(t, p, r)
+	s.Difficulty.Level = 0.5
+
+	n := 0
+	playGame(t, s, func(q Question) string {
+		n++
+		if n%2 == 0 {
+			return "" // ran out of time
+		}
+		for _, o := range q.Options {
+			if o != q.Correct {
+				return o
+			}
+		}
+		return ""
+	}, time.Second)
+
+	if s.Score != 0 {
+		t.Errorf("score = %d, want 0", s.Score)
+	}
+	if s.Difficulty.Level >= 0.5 {
+		t.Errorf("difficulty level %v should drop after a losing game", s.Difficulty.Level)
+	}
+	u, err := s.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.GamesPlayed != 1 || u.BestScore != 0 {
+		t.Errorf("saved stats = %+v", u)
+	}
+}
+
+func TestIntegrationSlowAnswersEarnLessBonus(t *testing.T) {
+	p, r := standardSky()
+	fast := newTestSession(t, p, r)
+	playGame(t, fast, correctAnswer, 0)
+
+	slow := newTestSession(t, p, r)
+	limit := slow.Difficulty.RoundTime()
+	playGame(t, slow, correctAnswer, limit/2)
+
+	if slow.Score >= fast.Score || slow.Score <= RoundsPerGame*roundBasePoints {
+		t.Errorf("slow score %d should sit between the base %d and the fast %d",
+			slow.Score, RoundsPerGame*roundBasePoints, fast.Score)
+	}
+}
+
+func TestIntegrationSkipsFlightsThatCantBeQuizzed(t *testing.T) {
+	p, r := standardSky()
+	grounded := airborne("bbb001", "FIN9")
+	grounded.OnGround = true
+	p.flights = append(p.flights,
+		grounded,
+		airborne("bbb002", "N/A"),
+		airborne("bbb003", "BLK4"), // scrape fails
+		airborne("bbb004", "UNK5"), // route unknown
+	)
+	r.details["FIN9"] = r.details["FIN1"]
+	r.details["UNK5"] = &ResolvedDetails{Origin: "Unknown", RealDestination: "Unknown", Model: "Mystery"}
+
+	s := newTestSession(t, p, r)
+	playGame(t, s, func(q Question) string {
+		if c := s.Target.Callsign; c != "FIN1" && c != "SAS2" && c != "DLH3" {
+			t.Errorf("round %d quizzed %s", s.Round, c)
+		}
+		return q.Correct
+	}, 0)
+
+	if slices.Contains(r.asked, "FIN9") || slices.Contains(r.asked, "N/A") {
+		t.Errorf("grounded or anonymous flights were resolved: %v", r.asked)
+	}
+}
+
+func TestIntegrationNoTargets(t *testing.T) {
+	p, _ := standardSky()
+	s := newTestSession(t, p, &stubResolver{})
+
+	if err := s.Start(context.Background()); !errors.Is(err, ErrNoTargets) {
+		t.Fatalf("Start with nothing resolvable = %v, want ErrNoTargets", err)
+	}
+	if s.State == SessionPlaying {
+		t.Error("session is playing without a question")
+	}
+}
+
+func TestIntegrationStateTransitions(t *testing.T) {
+	p, r := standardSky()
+	s := newTestSession(t, p, r)
+	ctx := context.Background()
+
+	if _, err := s.Answer("x", 0); err == nil {
+		t.Error("answered before the game started")
+	}
+	if err := s.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s.Round != 1 || s.State != SessionPlaying {
+		t.Fatalf("after Start: round %d state %v", s.Round, s.State)
+	}
+	if _, err := s.Finish(); err == nil {
+		t.Error("finished a game in progress")
+	}
+	if _, err := s.Answer(s.Question.Correct, 0); err != nil {
+		t.Fatal(err)
+	}
+	if s.State != SessionAnswered {
+		t.Errorf("state after answering = %v", s.State)
+	}
+	if _, err := s.Answer(s.Question.Correct, 0); err == nil {
+		t.Error("answered the same question twice")
+	}
+	if err := s.Next(ctx); err != nil || s.Round != 2 || s.State != SessionPlaying {
+		t.Errorf("Next: err %v round %d state %v", err, s.Round, s.State)
+	}
+}
+
+func TestIntegrationGamesAccumulatePerPlayer(t *testing.T) {
+	p, r := standardSky()
+	s := newTestSession(t, p, r)
+
+	playGame(t, s, correctAnswer, 0)
+	first := s.Score
+	if _, err := s.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	playGame(t, s, func(Question) string { return "" }, 0)
+	u, err := s.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u.GamesPlayed != 2 || u.TotalScore != first || u.BestScore != first {
+		t.Errorf("after two games stats = %+v", u)
+	}
+	top, _, err := s.Data.GetLeaderboard()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 2 || top[0].Score != first {
+		t.Errorf("leaderboard = %+v", top)
+	}
+}
+
+func TestIntegrationTypeRounds(t *testing.T) {
+	p, r := standardSky()
+	s := newTestSession(t, p, r)
+	s.TypeRounds = true
+
+	typeRounds := 0
+	for game := 0; game < 6; game++ {
+		playGame(t, s, func(q Question) string {
+			if q.IsTypeRound() {
+				typeRounds++
+				if !strings.Contains(q.Hint, "_") || strings.Contains(q.Hint, q.Correct) {
+					t.Errorf("hint %q gives %q away", q.Hint, q.Correct)
+				}
+			}
+			return q.Correct
+		}, 0)
+		if want := RoundsPerGame * (roundBasePoints + roundMaxBonus); s.Score != want {
+			t.Errorf("game %d score = %d, want %d", game, s.Score, want)
+		}
+	}
+	if typeRounds == 0 {
+		t.Error("no type rounds in 30 rounds with type rounds enabled")
+	}
+}
+
+func TestRoundScore(t *testing.T) {
+	limit := 20 * time.Second
+	cases := []struct {
+		correct bool
+		elapsed time.Duration
+		want    int
+	}{
+		{true, 0, 200},
+		{true, 10 * time.Second, 150},
+		{true, limit, 100},
+		{true, 2 * limit, 100},
+		{false, 0, 0},
+	}
+	for _, c := range cases {
+		if got := RoundScore(c.correct, c.elapsed, limit); got != c.want {
+			t.Errorf("RoundScore(%v, %v) = %d, want %d", c.correct, c.elapsed, got, c.want)
+		}
+	}
+}
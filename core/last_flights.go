@@ -0,0 +1,53 @@
+package core
+
+import (
+	"os"
+	"time"
+)
+
+const lastFlightsFile = "last_flights.json"
+
+const (
+	// Snapshots are saved at most this often, sparing SD cards a write per poll
+	LastFlightsSaveInterval = 30 * time.Second
+	// An older snapshot would show planes long gone; an empty map is better
+	lastFlightsMaxAge = 10 * time.Minute
+)
+
+// lastFlights is the saved form of the latest snapshot
+type lastFlights struct {
+	Provider  string    `json:"provider"` // so simulated planes never show up on a live map
+	FetchedAt time.Time `json:"fetched_at"`
+	Flights   []Flight  `json:"flights"`
+}
+
+// SaveLastFlights remembers a snapshot for LoadLastFlights to show at the
+// next start, before the first poll completes
+func (dm *DataManager) SaveLastFlights(provider string, s *FlightSnapshot) error {
+	if s.FetchedAt.IsZero() || !ClockSane(s.FetchedAt) {
+		return nil // nothing fetched yet, or no telling when
+	}
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	return dm.writeDocument(lastFlightsFile, lastFlights{Provider: provider, FetchedAt: s.FetchedAt, Flights: s.Flights})
+}
+
+// LoadLastFlights returns the flights saved by SaveLastFlights and when they
+// were fetched, or nothing if they came from another provider or are too old
+// to be worth showing
+func (dm *DataManager) LoadLastFlights(provider string, now time.Time) ([]Flight, time.Time, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	var saved lastFlights
+	if err := dm.readDocument(lastFlightsFile, &saved); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return nil, time.Time{}, err
+	}
+	if saved.Provider != provider || !ClockSane(now) || Elapsed(saved.FetchedAt, now) > lastFlightsMaxAge {
+		return nil, time.Time{}, nil
+	}
+	return saved.Flights, saved.FetchedAt, nil
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const learnedRoutesFile = "learned_routes.json"
+
+const (
+	// A callsign must have flown the same route on this many days before
+	// the route is answered from memory; one-off charters and flight
+	// numbers reused for other routes never get that far
+	learnedRouteMinDays = 2
+	// A learned route not confirmed by a lookup for this long is looked up
+	// again, so schedule changes are noticed within a couple of weeks
+	learnedRouteMaxAge = 14 * 24 * time.Hour
+	// Routes not seen for this long are forgotten
+	learnedRouteKeep = 90 * 24 * time.Hour
+)
+
+// learnedRoute is the route a callsign was last seen flying
+type learnedRoute struct {
+	Route    ResolvedDetails `json:"route"`     // route fields only, see routeOnly
+	Days     int             `json:"days"`      // distinct days it was looked up flying Route
+	LastDay  string          `json:"last_day"`  // local "2006-01-02" of the last of those
+	LastSeen time.Time       `json:"last_seen"` // when a lookup last confirmed it
+}
+
+// LearnedRoutes remembers the route every resolved callsign flies, in
+// learned_routes.json. Scheduled flights fly the same route every day, so
+// once a callsign has been seen on one route on a few days it is answered
+// from here, and the route APIs are only asked about new callsigns and
+// routes due a recheck. It is read from disk on first use.
+type LearnedRoutes struct {
+	dm *DataManager
+
+	mu      sync.Mutex
+	entries map[string]learnedRoute // callsign -> route; nil until loaded
+	hits    int                     // lookups answered since start
+}
+
+func NewLearnedRoutes(dm *DataManager) *LearnedRoutes {
+	return &LearnedRoutes{dm: dm}
+}
+
+// routeOnly is the part of d that is the same every day the callsign
+// flies: not the aircraft, nor the times
+func routeOnly(d *ResolvedDetails) ResolvedDetails {
+	return ResolvedDetails{
+		Destination:     d.Destination,
+		RealDestination: d.RealDestination,
+		Origin:          d.Origin,
+		Airline:         d.Airline,
+		DestLat:         d.DestLat,
+		DestLon:         d.DestLon,
+		OrigLat:         d.OrigLat,
+		OrigLon:         d.OrigLon,
+	}
+}
+
+// Lookup returns the route callsign has been learned to fly, if it has
+// flown it often and recently enough to be trusted. Nil-safe.
+func (l *LearnedRoutes) Lookup(callsign string, now time.Time) (*ResolvedDetails, bool) {
+	if l == nil {
+		return nil, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.load()
+	e, ok := l.entries[routeCacheKey(callsign)]
+	if !ok || !e.trusted(now) {
+		return nil, false
+	}
+	l.hits++
+	d := e.Route
+	return &d, true
+}
+
+// Knows reports whether Lookup would answer for callsign. Nil-safe.
+func (l *LearnedRoutes) Knows(callsign string, now time.Time) bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.load()
+	e, ok := l.entries[routeCacheKey(callsign)]
+	return ok && e.trusted(now)
+}
+
+func (e learnedRoute) trusted(now time.Time) bool {
+	return ClockSane(now) && e.Days >= learnedRouteMinDays && Elapsed(e.LastSeen, now) <= learnedRouteMaxAge
+}
+
+// Learn records that a lookup found callsign flying d's route at now. A
+// different route than the one learned starts the count over. Details
+// without a route are ignored, as is everything while the system clock is
+// wrong. Nil-safe.
+func (l *LearnedRoutes) Learn(callsign string, d *ResolvedDetails, now time.Time) {
+	if l == nil || d == nil || !ClockSane(now) || !knownPlace(d.Origin) || !knownPlace(d.RealDestination) {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.load()
+	key := routeCacheKey(callsign)
+	day := now.Local().Format("2006-01-02")
+	e, ok := l.entries[key]
+	switch {
+	case !ok || !SamePlace(e.Route.Origin, d.Origin) || !SamePlace(e.Route.RealDestination, d.RealDestination):
+		e = learnedRoute{Days: 1}
+	case e.LastDay != day:
+		e.Days++
+	}
+	e.Route, e.LastDay, e.LastSeen = routeOnly(d), day, now
+	l.entries[key] = e
+	for k, old := range l.entries {
+		if Elapsed(old.LastSeen, now) > learnedRouteKeep {
+			delete(l.entries, k)
+		}
+	}
+
+	l.dm.mu.Lock()
+	err := l.dm.writeDocument(learnedRoutesFile, l.entries)
+	l.dm.mu.Unlock()
+	if err != nil {
+		log.Println("Error saving learned routes:", err)
+	}
+}
+
+// Stats is how many callsigns are answered from memory and how many
+// lookups were since start, for the debug screen. Nil-safe.
+func (l *LearnedRoutes) Stats(now time.Time) (trusted, hits int) {
+	if l == nil {
+		return 0, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.load()
+	for _, e := range l.entries {
+		if e.trusted(now) {
+			trusted++
+		}
+	}
+	return trusted, l.hits
+}
+
+// load reads the file the first time it is needed. A missing or
+// unreadable file starts empty. Caller holds l.mu.
+func (l *LearnedRoutes) load() {
+	if l.entries != nil {
+		return
+	}
+	l.entries = make(map[string]learnedRoute)
+	l.dm.mu.Lock()
+	err := l.dm.readDocument(learnedRoutesFile, &l.entries)
+	l.dm.mu.Unlock()
+	if err != nil && !os.IsNotExist(err) {
+		log.Println("Error loading learned routes:", err)
+	}
+	if l.entries == nil { // the file held null
+		l.entries = make(map[string]learnedRoute)
+	}
+}
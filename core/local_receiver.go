@@ -0,0 +1,220 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	localPollInterval = 1 * time.Second
+	// Aircraft not heard from for this long are dropped from SBS results
+	sbsStaleAfter   = 60 * time.Second
+	sbsReconnectGap = 5 * time.Second
+)
+
+// LocalReceiverProvider reads flights straight from a dump1090/readsb receiver
+// on the local network, either by fetching aircraft.json over HTTP or by
+// listening to the BaseStation (SBS) TCP feed on port 30003
+type LocalReceiverProvider struct {
+	baseURL string // http mode: web root serving data/aircraft.json
+	sbsAddr string // sbs mode: host:port of the BaseStation feed
+
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	aircraft map[string]*sbsAircraft // sbs mode state, keyed by icao24
+}
+
+type sbsAircraft struct {
+	flight   Flight
+	hasPos   bool
+	lastSeen time.Time
+}
+
+// NewLocalReceiverProvider configures the provider from RECEIVER_SBS (host:port)
+// or, failing that, RECEIVER_URL (web root). The SBS feed is only listened
+// to while Run runs.
+func NewLocalReceiverProvider() (*LocalReceiverProvider, error) {
+	p := &LocalReceiverProvider{
+		baseURL:    strings.TrimRight(os.Getenv("RECEIVER_URL"), "/"),
+		sbsAddr:    os.Getenv("RECEIVER_SBS"),
+		httpClient: &http.Client{Timeout: 2 * time.Second},
+		aircraft:   make(map[string]*sbsAircraft),
+	}
+
+	if p.sbsAddr != "" {
+		return p, nil
+	}
+	if p.baseURL == "" {
+		return nil, fmt.Errorf("local provider needs RECEIVER_SBS or RECEIVER_URL")
+	}
+	return p, nil
+}
+
+func (p *LocalReceiverProvider) Name() string {
+	return "local"
+}
+
+// RateLimitInfo: a local receiver has no limits
+func (p *LocalReceiverProvider) RateLimitInfo() RateLimitInfo {
+	return RateLimitInfo{Remaining: -1}
+}
+
+// PollInterval lets the frontends refresh near real-time instead of every 5s
+func (p *LocalReceiverProvider) PollInterval() time.Duration {
+	return localPollInterval
+}
+
+func (p *LocalReceiverProvider) FetchFlights(ctx context.Context, centerLat, centerLon, radiusDeg float64) ([]Flight, error) {
+	if p.sbsAddr != "" {
+		return p.sbsSnapshot(centerLat, centerLon, radiusDeg), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/data/aircraft.json", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("aircraft.json: status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Aircraft []readsbAircraft `json:"aircraft"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return flightsFromReadsb(result.Aircraft, centerLat, centerLon, radiusDeg, p.Name()), nil
+}
+
+func (p *LocalReceiverProvider) sbsSnapshot(centerLat, centerLon, radiusDeg float64) []Flight {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var flights []Flight
+	for icao, a := range p.aircraft {
+		if Elapsed(a.lastSeen, now) > sbsStaleAfter {
+			delete(p.aircraft, icao)
+			continue
+		}
+		if !a.hasPos {
+			continue
+		}
+		if math.Abs(a.flight.Lat-centerLat) > radiusDeg || math.Abs(a.flight.Lon-centerLon) > radiusDeg {
+			continue
+		}
+		flights = append(flights, a.flight)
+	}
+	return flights
+}
+
+// Run keeps a connection to the BaseStation feed open, reconnecting on
+// failure, until ctx is cancelled. In HTTP mode there is nothing to run.
+func (p *LocalReceiverProvider) Run(ctx context.Context) {
+	if p.sbsAddr == "" {
+		return
+	}
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	for {
+		conn, err := dialer.DialContext(ctx, "tcp", p.sbsAddr)
+		if err == nil {
+			p.readSBS(ctx, conn)
+		} else if ctx.Err() == nil {
+			log.Println("SBS connect failed:", err)
+		}
+		select {
+		case <-time.After(sbsReconnectGap):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// readSBS applies the feed's lines until it closes or ctx is cancelled
+func (p *LocalReceiverProvider) readSBS(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	// Closing the connection is what gets a blocked read to return
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		p.handleSBSLine(scanner.Text())
+	}
+	if ctx.Err() == nil {
+		log.Println("SBS feed closed:", scanner.Err())
+	}
+}
+
+// handleSBSLine applies one "MSG,..." BaseStation record. Each transmission
+// type only carries some fields, so only non-empty fields overwrite state.
+func (p *LocalReceiverProvider) handleSBSLine(line string) {
+	fields := strings.Split(strings.TrimSpace(line), ",")
+	if len(fields) < 22 || fields[0] != "MSG" {
+		return
+	}
+
+	icao := strings.ToLower(fields[4])
+	if icao == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	a, ok := p.aircraft[icao]
+	if !ok {
+		a = &sbsAircraft{flight: Flight{Icao24: icao, Callsign: "N/A", Category: "Unknown", Source: p.Name()}}
+		p.aircraft[icao] = a
+	}
+	a.lastSeen = time.Now()
+
+	if cs := strings.TrimSpace(fields[10]); cs != "" {
+		a.flight.Callsign = cs
+	}
+	if v, err := strconv.ParseFloat(fields[11], 64); err == nil {
+		a.flight.AltitudeFt = int(v)
+	}
+	if v, err := strconv.ParseFloat(fields[12], 64); err == nil {
+		a.flight.VelocityKts = int(v)
+	}
+	if v, err := strconv.ParseFloat(fields[13], 64); err == nil {
+		a.flight.Heading = v
+	}
+	if v, err := strconv.ParseFloat(fields[16], 64); err == nil {
+		a.flight.VerticalRateFpm = int(v)
+	}
+	if sq := strings.TrimSpace(fields[17]); sq != "" {
+		a.flight.Squawk = sq
+		a.flight.Emergency = SquawkEmergency(sq)
+	}
+	lat, latErr := strconv.ParseFloat(fields[14], 64)
+	lon, lonErr := strconv.ParseFloat(fields[15], 64)
+	if latErr == nil && lonErr == nil {
+		a.flight.Lat, a.flight.Lon = lat, lon
+		a.flight.PositionAt = a.lastSeen
+		a.hasPos = true
+	}
+	if fields[21] != "" {
+		// Flags are "-1" (true) or "0"
+		a.flight.OnGround = fields[21] == "-1" || fields[21] == "1"
+	}
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// Run reads the SBS feed until cancelled, then hangs up and returns
+func TestLocalReceiverRunStops(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	closed := make(chan struct{})
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		io.WriteString(conn, "MSG,3,1,1,4601F6,1,,,,,FIN7LV,3500,,,60.31,24.96,,,0,0,0,0\r\n")
+		io.Copy(io.Discard, conn) // until the provider hangs up
+		close(closed)
+	}()
+
+	t.Setenv("RECEIVER_SBS", ln.Addr().String())
+	p, err := NewLocalReceiverProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		RunProvider(ctx, NewMergeProvider(p, NewSimulatedProvider()))
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(p.sbsSnapshot(60.31, 24.96, 1)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no flight read from the feed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	for name, ch := range map[string]chan struct{}{"Run": done, "the connection": closed} {
+		select {
+		case <-ch:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s still open after cancelling", name)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// The same basemap the frontends draw
+	mapExportTileURL = "https://basemaps.cartocdn.com/dark_all/%d/%d/%d.png"
+
+	defaultMapExportInterval = time.Minute
+	defaultMapExportW        = 800
+	defaultMapExportH        = 480
+	// The zoom the export tries first; it zooms out until the search
+	// radius fits
+	mapExportMaxZoom = 12
+	// Tiles kept between frames; home doesn't move, so a handful is plenty
+	mapExportTileCache = 64
+	mapExportPlaneSize = 7
+)
+
+// MapExporter renders a clean map of the traffic around home, without any
+// UI, to a PNG now and then, for e-ink dashboards and other displays that
+// show the latest snapshot
+type MapExporter struct {
+	lat, lon float64
+	target   string // file path, or http(s) URL the PNG is POSTed to
+	w, h     int
+	gray     bool
+	interval time.Duration
+	client   *http.Client
+
+	tiles map[[3]int]image.Image // z, x, y; only touched by Run
+}
+
+// NewMapExporter is configured by MAP_EXPORT, a file path or an http(s)
+// URL; MAP_EXPORT_SIZE, e.g. "800x480"; MAP_EXPORT_INTERVAL in seconds
+// and MAP_EXPORT_GRAY=1 for greyscale panels. It returns nil when
+// MAP_EXPORT isn't set.
+func NewMapExporter(lat, lon float64) *MapExporter {
+	target := strings.TrimSpace(os.Getenv("MAP_EXPORT"))
+	if target == "" {
+		return nil
+	}
+	e := &MapExporter{
+		lat:      lat,
+		lon:      lon,
+		target:   target,
+		w:        defaultMapExportW,
+		h:        defaultMapExportH,
+		gray:     os.Getenv("MAP_EXPORT_GRAY") == "1",
+		interval: defaultMapExportInterval,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		tiles:    make(map[[3]int]image.Image),
+	}
+	if v := os.Getenv("MAP_EXPORT_SIZE"); v != "" {
+		if w, h, err := parseSize(v); err == nil {
+			e.w, e.h = w, h
+		} else {
+			log.Println("Ignoring MAP_EXPORT_SIZE:", err)
+		}
+	}
+	if v, err := strconv.Atoi(os.Getenv("MAP_EXPORT_INTERVAL")); err == nil && v > 0 {
+		e.interval = time.Duration(v) * time.Second
+	}
+	return e
+}
+
+// parseSize reads "WIDTHxHEIGHT"
+func parseSize(s string) (int, int, error) {
+	ws, hs, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	w, err1 := strconv.Atoi(ws)
+	h, err2 := strconv.Atoi(hs)
+	if !ok || err1 != nil || err2 != nil || w <= 0 || h <= 0 || w > 8192 || h > 8192 {
+		return 0, 0, fmt.Errorf("%q is not a size like 800x480", s)
+	}
+	return w, h, nil
+}
+
+// Run exports the pipeline's latest snapshot every interval until ctx is
+// cancelled, covering the search radius in settings
+func (e *MapExporter) Run(ctx context.Context, pipeline *FlightPipeline, settings *SettingsStore) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		if s := pipeline.Snapshot(); s != nil {
+			img := e.Render(ctx, s, settings.Get().RadiusKm)
+			if err := e.write(ctx, img); err != nil && ctx.Err() == nil {
+				log.Println("Map export failed:", err)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// zoomFor is the closest zoom at which radiusKm around home fits the frame
+func (e *MapExporter) zoomFor(radiusKm float64) int {
+	edgeLat, edgeLon := Destination(e.lat, e.lon, 90, radiusKm)
+	for z := mapExportMaxZoom; z > 1; z-- {
+		hx, _ := LatLonToPixels(e.lat, e.lon, float64(z))
+		ex, _ := LatLonToPixels(edgeLat, edgeLon, float64(z))
+		if 2*(ex-hx) <= float64(min(e.w, e.h)) {
+			return z
+		}
+	}
+	return 1
+}
+
+// Render draws the map tiles, home and the snapshot's flights
+func (e *MapExporter) Render(ctx context.Context, s *FlightSnapshot, radiusKm float64) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, e.w, e.h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(rgba(ColBgDark)), image.Point{}, draw.Src)
+
+	z := e.zoomFor(radiusKm)
+	cx, cy := LatLonToPixels(e.lat, e.lon, float64(z))
+	x0, y0 := int(cx)-e.w/2, int(cy)-e.h/2
+	project := func(lat, lon float64) (int, int) {
+		x, y := LatLonToPixels(lat, lon, float64(z))
+		return int(x) - x0, int(y) - y0
+	}
+
+	n := 1 << z
+	fetch := true // after one failed fetch, this frame makes do with cached tiles
+	for ty := floorDiv(y0, TileSize); ty*TileSize < y0+e.h; ty++ {
+		for tx := floorDiv(x0, TileSize); tx*TileSize < x0+e.w; tx++ {
+			if ty < 0 || ty >= n {
+				continue
+			}
+			wx := ((tx % n) + n) % n // the map repeats east and west
+			tile, ok := e.tiles[[3]int{z, wx, ty}]
+			if !ok && fetch {
+				tile, fetch = e.fetchTile(ctx, z, wx, ty)
+			}
+			if tile == nil {
+				continue
+			}
+			at := image.Pt(tx*TileSize-x0, ty*TileSize-y0)
+			draw.Draw(img, image.Rectangle{Min: at, Max: at.Add(tile.Bounds().Size())}, tile, tile.Bounds().Min, draw.Over)
+		}
+	}
+
+	hx, hy := project(e.lat, e.lon)
+	draw.Draw(img, image.Rect(hx-2, hy-2, hx+3, hy+3), image.NewUniform(rgba(ColSuccess)), image.Point{}, draw.Src)
+
+	for _, f := range s.Flights {
+		x, y := project(f.Lat, f.Lon)
+		if x < 0 || y < 0 || x >= e.w || y >= e.h {
+			continue
+		}
+		col := uint32(ColAccent)
+		if f.OnGround {
+			col = ColTextMuted
+		}
+		drawPlane(img, x, y, f.Heading, rgba(col))
+		if f.HasCallsign() {
+			drawText(img, x+mapExportPlaneSize+2, y+4, f.Callsign, rgba(ColText))
+		}
+	}
+	// Displays that update rarely should say how old the picture is
+	drawText(img, 6, e.h-6, s.FetchedAt.Local().Format("15:04"), rgba(ColText))
+
+	if e.gray {
+		g := image.NewGray(img.Bounds())
+		draw.Draw(g, g.Bounds(), img, image.Point{}, draw.Src)
+		return g
+	}
+	return img
+}
+
+// fetchTile downloads a basemap tile into the cache, which is simply
+// emptied when full. It reports false when the tile server can't be
+// reached.
+func (e *MapExporter) fetchTile(ctx context.Context, z, x, y int) (image.Image, bool) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf(mapExportTileURL, z, x, y), nil)
+	if err != nil {
+		return nil, false
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, true // this tile is missing; others may not be
+	}
+	t, _, err := image.Decode(resp.Body)
+	if err != nil {
+		return nil, true
+	}
+	if len(e.tiles) >= mapExportTileCache {
+		clear(e.tiles)
+	}
+	e.tiles[[3]int{z, x, y}] = t
+	return t, true
+}
+
+// write saves the PNG to the target path, through a temporary file so a
+// display never reads half of one, or POSTs it to the target URL
+func (e *MapExporter) write(ctx context.Context, img image.Image) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(e.target, "http://") && !strings.HasPrefix(e.target, "https://") {
+		tmp := e.target + ".tmp"
+		if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+		return os.Rename(tmp, e.target)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", e.target, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "image/png")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// drawPlane fills a triangle at (x, y) pointing along heading
+func drawPlane(img *image.RGBA, x, y int, heading float64, col color.RGBA) {
+	var pts [3][2]float64
+	for i, a := range []float64{0, 140, 220} {
+		r := float64(mapExportPlaneSize)
+		if i > 0 {
+			r *= 0.7
+		}
+		rad := (heading + a) * math.Pi / 180
+		pts[i] = [2]float64{float64(x) + r*math.Sin(rad), float64(y) - r*math.Cos(rad)}
+	}
+	// Point-in-triangle by the sign of each edge's cross product
+	edge := func(a, b [2]float64, px, py float64) float64 {
+		return (b[0]-a[0])*(py-a[1]) - (b[1]-a[1])*(px-a[0])
+	}
+	for py := y - mapExportPlaneSize; py <= y+mapExportPlaneSize; py++ {
+		for px := x - mapExportPlaneSize; px <= x+mapExportPlaneSize; px++ {
+			fx, fy := float64(px)+0.5, float64(py)+0.5
+			d0, d1, d2 := edge(pts[0], pts[1], fx, fy), edge(pts[1], pts[2], fx, fy), edge(pts[2], pts[0], fx, fy)
+			if (d0 >= 0 && d1 >= 0 && d2 >= 0) || (d0 <= 0 && d1 <= 0 && d2 <= 0) {
+				img.SetRGBA(px, py, col)
+			}
+		}
+	}
+}
+
+func drawText(img draw.Image, x, y int, s string, col color.RGBA) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(col),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(s)
+}
+
+// rgba converts a palette colour
+func rgba(c uint32) color.RGBA {
+	return color.RGBA{R: uint8(c >> 24), G: uint8(c >> 16), B: uint8(c >> 8), A: uint8(c)}
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const mbtilesFile = "map.mbtiles"
+
+// MBTilesPath is MBTILES if set, otherwise map.mbtiles in the data dir;
+// "" when MBTILES=off
+func MBTilesPath() string {
+	p := strings.TrimSpace(os.Getenv("MBTILES"))
+	if strings.EqualFold(p, "off") {
+		return ""
+	}
+	if p != "" {
+		return p
+	}
+	return dataPath(mbtilesFile)
+}
+
+// MBTiles is an offline archive of raster map tiles in the MBTiles format
+// (https://github.com/mapbox/mbtiles-spec), e.g. exported for the area
+// around home, so a kiosk without internet still has a map. Both the plain
+// layout with a tiles table and the deduplicated one with map and images
+// tables are read. Tiles it doesn't have are left to the network.
+//
+// Lookups are safe for concurrent use. A nil *MBTiles has no tiles.
+type MBTiles struct {
+	f      *os.File
+	tiles  *sqliteTable // tiles, or map in the deduplicated layout
+	images *sqliteTable // nil in the plain layout
+	data   int          // column of tiles (or images) holding the image
+
+	Name             string
+	Format           string // "png" or "jpg"
+	MinZoom, MaxZoom int    // -1 when the metadata doesn't say
+}
+
+// OpenMBTiles opens an archive for reading. Vector tile archives are
+// refused since the map draws raster tiles.
+func OpenMBTiles(path string) (*MBTiles, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := readMBTiles(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+func readMBTiles(f *os.File) (*MBTiles, error) {
+	db, err := openSQLite(f)
+	if err != nil {
+		return nil, err
+	}
+	m := &MBTiles{f: f, Format: "png", MinZoom: -1, MaxZoom: -1}
+
+	if meta, err := db.table("metadata"); err == nil {
+		name, value := meta.column("name"), meta.column("value")
+		err := db.scanTable(meta.root, func(_ int64, rec []any) error {
+			if name < 0 || value < 0 || max(name, value) >= len(rec) {
+				return nil
+			}
+			v := sqliteText(rec[value])
+			switch sqliteText(rec[name]) {
+			case "name":
+				m.Name = v
+			case "format":
+				m.Format = strings.ToLower(v)
+			case "minzoom":
+				m.MinZoom, _ = strconv.Atoi(v)
+			case "maxzoom":
+				m.MaxZoom, _ = strconv.Atoi(v)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch m.Format {
+	case "png", "jpg", "jpeg":
+	default:
+		return nil, fmt.Errorf("%s tiles aren't supported, only raster (png or jpg)", m.Format)
+	}
+
+	if m.tiles, err = db.table("tiles"); err == nil {
+		m.data = m.tiles.column("tile_data")
+	} else {
+		// The deduplicated layout, where tiles is a view over these
+		if m.tiles, err = db.table("map"); err != nil {
+			return nil, fmt.Errorf("no tiles table: %w", err)
+		}
+		if m.images, err = db.table("images"); err != nil {
+			return nil, err
+		}
+		m.data = m.images.column("tile_data")
+	}
+	if m.data < 0 {
+		return nil, fmt.Errorf("no tile_data column")
+	}
+	return m, nil
+}
+
+// Tile is the image for a tile in the usual XYZ scheme, as the tile
+// servers number them. MBTiles numbers rows from the south (TMS), so the
+// row is flipped.
+func (m *MBTiles) Tile(z, x, y int) ([]byte, bool) {
+	if m == nil || (m.MinZoom >= 0 && z < m.MinZoom) || (m.MaxZoom >= 0 && z > m.MaxZoom) {
+		return nil, false
+	}
+	row := int64(1)<<z - 1 - int64(y)
+	rec, ok, err := m.tiles.find([]string{"zoom_level", "tile_column", "tile_row"}, []any{int64(z), int64(x), row})
+	if err == nil && ok && m.images != nil {
+		id := m.tiles.column("tile_id")
+		if id < 0 || id >= len(rec) {
+			return nil, false
+		}
+		rec, ok, err = m.images.find([]string{"tile_id"}, []any{rec[id]})
+	}
+	if err != nil || !ok || m.data >= len(rec) {
+		return nil, false
+	}
+	data, _ := rec[m.data].([]byte)
+	return data, len(data) > 0
+}
+
+// Ext is the file extension of the tile images, for decoders that want a
+// hint
+func (m *MBTiles) Ext() string {
+	if m.Format == "jpeg" {
+		return ".jpg"
+	}
+	return "." + m.Format
+}
+
+func (m *MBTiles) Close() error {
+	if m == nil {
+		return nil
+	}
+	return m.f.Close()
+}
@@ -0,0 +1,175 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// The fixtures are small MBTiles archives written by SQLite with 512 byte
+// pages, so their tables and indexes span interior pages. Each tile's data
+// starts "z<z>x<x>y<y>;", and at zoom 3 those with x+y a multiple of 7 run
+// on for 1500 bytes, onto overflow pages. tiles_dedup.mbtiles has zooms 0
+// to 2 in the deduplicated layout, where tiles with an odd x+y share the
+// image "sea".
+
+func fixtureTile(z, x, y int) []byte {
+	b := []byte(fmt.Sprintf("z%dx%dy%d;", z, x, y))
+	if z == 3 && (x+y)%7 == 0 {
+		for i := range 1500 {
+			b = append(b, byte((i*31+z)%256))
+		}
+	}
+	return b
+}
+
+func openFixture(t *testing.T, name string) *MBTiles {
+	t.Helper()
+	m, err := OpenMBTiles("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestMBTiles(t *testing.T) {
+	m := openFixture(t, "tiles.mbtiles")
+	if m.Name != "Test tiles" || m.Ext() != ".png" || m.MinZoom != 0 || m.MaxZoom != 3 {
+		t.Errorf("metadata = %q %s zooms %d-%d", m.Name, m.Ext(), m.MinZoom, m.MaxZoom)
+	}
+	if m.images != nil {
+		t.Error("read as the deduplicated layout")
+	}
+	if _, ok := m.tiles.index([]string{"zoom_level", "tile_column", "tile_row"}); !ok {
+		t.Error("tile_index not used for lookups")
+	}
+	for z := 0; z <= 3; z++ {
+		for x := 0; x < 1<<z; x++ {
+			for y := 0; y < 1<<z; y++ {
+				data, ok := m.Tile(z, x, y)
+				if want := fixtureTile(z, x, y); !ok || !bytes.Equal(data, want) {
+					t.Errorf("tile %d/%d/%d = %.12q (%d bytes), want %.12q (%d bytes)", z, x, y, data, len(data), want, len(want))
+				}
+			}
+		}
+	}
+	for _, c := range [][3]int{{4, 0, 0}, {2, 4, 0}, {1, 0, -1}} {
+		if data, ok := m.Tile(c[0], c[1], c[2]); ok {
+			t.Errorf("tile %v = %q, want none", c, data)
+		}
+	}
+}
+
+func TestMBTilesDeduplicated(t *testing.T) {
+	m := openFixture(t, "tiles_dedup.mbtiles")
+	if m.images == nil || m.Ext() != ".jpg" || m.MaxZoom != -1 {
+		t.Fatalf("read %q as plain %s with max zoom %d", m.Name, m.Ext(), m.MaxZoom)
+	}
+	for z := 0; z <= 2; z++ {
+		for x := 0; x < 1<<z; x++ {
+			for y := 0; y < 1<<z; y++ {
+				want := fixtureTile(z, x, y)
+				if (x+y)%2 == 1 {
+					want = []byte("sea")
+				}
+				if data, ok := m.Tile(z, x, y); !ok || !bytes.Equal(data, want) {
+					t.Errorf("tile %d/%d/%d = %q, want %q", z, x, y, data, want)
+				}
+			}
+		}
+	}
+	if data, ok := m.Tile(3, 0, 0); ok {
+		t.Errorf("tile 3/0/0 = %q, want none", data)
+	}
+}
+
+func TestSQLiteRowAndSeek(t *testing.T) {
+	f, err := os.Open("testdata/tiles.mbtiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	db, err := openSQLite(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tiles, err := db.table("tiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every row by rowid, the same as a scan finds it
+	rows := 0
+	err = db.scanTable(tiles.root, func(rowid int64, rec []any) error {
+		rows++
+		got, ok, err := db.row(tiles.root, rowid)
+		if err != nil || !ok || len(got) != len(rec) || !bytes.Equal(got[3].([]byte), rec[3].([]byte)) {
+			return fmt.Errorf("row %d = %v, %v, want the scanned one", rowid, ok, err)
+		}
+		return nil
+	})
+	if err != nil || rows != 85 {
+		t.Fatalf("scanned %d rows: %v", rows, err)
+	}
+	if _, ok, err := db.row(tiles.root, 1000); ok || err != nil {
+		t.Errorf("found row 1000: %v", err)
+	}
+
+	idx, ok := db.object("index", "tile_index")
+	if !ok {
+		t.Fatal("no tile_index")
+	}
+	entry, ok, err := db.seek(idx.Root, []any{int64(3), int64(5), int64(2)})
+	if err != nil || !ok || len(entry) != 4 {
+		t.Fatalf("seek = %v, %v, %v", entry, ok, err)
+	}
+	if _, ok, err := db.seek(idx.Root, []any{int64(3), int64(8), int64(0)}); ok || err != nil {
+		t.Errorf("found a tile past the edge: %v", err)
+	}
+}
+
+// A damaged archive is an error, never a panic
+func TestSQLiteCorrupt(t *testing.T) {
+	good, err := os.ReadFile("testdata/tiles.mbtiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookupAll := func(data []byte) {
+		db, err := openSQLite(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		tiles, err := db.table("tiles")
+		if err != nil {
+			return
+		}
+		db.scanTable(tiles.root, func(int64, []any) error { return nil })
+		for x := range 8 {
+			tiles.find([]string{"zoom_level", "tile_column", "tile_row"}, []any{int64(3), int64(x), int64(x)})
+		}
+	}
+
+	// Cut short at every page
+	for n := 512; n < len(good); n += 512 {
+		lookupAll(good[:n])
+	}
+	// Bytes all through the file overwritten in turn
+	data := bytes.Clone(good)
+	for i := 0; i < len(data); i += 3 {
+		for _, b := range []byte{0x00, 0xff} {
+			data[i] = b
+			lookupAll(data)
+		}
+		data[i] = good[i]
+	}
+
+	if _, err := sqliteRecord([]byte{0x00, 0x01}); !errors.Is(err, errSQLiteCorrupt) {
+		t.Errorf("header shorter than its length: %v", err)
+	}
+	if _, err := sqliteRecord([]byte{0x02, 0xff}); !errors.Is(err, errSQLiteCorrupt) {
+		t.Errorf("header ending inside a varint: %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MergeProvider polls several providers side by side, e.g. a local
+// receiver and OpenSky, and merges their flights by icao24. Where more than
+// one reports a flight, the freshest position wins and the others fill in
+// what it lacks, such as a callsign the receiver hasn't decoded yet. Each
+// provider is asked no more often than its own poll interval and credit
+// budget allow, so OpenSky isn't polled at the receiver's pace.
+type MergeProvider struct {
+	providers []FlightProvider
+
+	mu      sync.Mutex
+	results map[string]mergeResult // last answer per provider and box
+	errs    []string               // last error per provider, to log changes only
+}
+
+// mergeResult is one provider's answer for one box
+type mergeResult struct {
+	flights []Flight
+	at      time.Time
+}
+
+func NewMergeProvider(providers ...FlightProvider) *MergeProvider {
+	return &MergeProvider{
+		providers: providers,
+		results:   make(map[string]mergeResult),
+		errs:      make([]string, len(providers)),
+	}
+}
+
+func (mp *MergeProvider) Name() string {
+	names := make([]string, len(mp.providers))
+	for i, p := range mp.providers {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// RateLimitInfo only reports a throttle when every provider is throttled;
+// each provider's own budget paces how often it is asked
+func (mp *MergeProvider) RateLimitInfo() RateLimitInfo {
+	now := time.Now()
+	var until time.Time
+	for _, p := range mp.providers {
+		t := p.RateLimitInfo().ThrottledUntil
+		if !now.Before(t) {
+			return RateLimitInfo{Remaining: -1}
+		}
+		if until.IsZero() || t.Before(until) {
+			until = t
+		}
+	}
+	return RateLimitInfo{Remaining: -1, ThrottledUntil: until}
+}
+
+// PollInterval is the fastest provider's; slower ones are served from their
+// last answer in between
+func (mp *MergeProvider) PollInterval() time.Duration {
+	interval := defaultPollInterval
+	for i, p := range mp.providers {
+		if pi := PollInterval(p); i == 0 || pi < interval {
+			interval = pi
+		}
+	}
+	return interval
+}
+
+func (mp *MergeProvider) FetchFlights(ctx context.Context, centerLat, centerLon, radiusDeg float64) ([]Flight, error) {
+	box := boxKey(centerLat, centerLon, radiusDeg)
+	answers := make([]mergeResult, len(mp.providers))
+	errs := make([]error, len(mp.providers))
+
+	var wg sync.WaitGroup
+	for i, p := range mp.providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			answers[i], errs[i] = mp.fetchOne(ctx, i, p, box, centerLat, centerLon, radiusDeg)
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	mp.noteErrors(errs)
+	ok := false
+	for _, err := range errs {
+		ok = ok || err == nil
+	}
+	if !ok {
+		return nil, fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+	}
+	return mergeFlights(answers), nil
+}
+
+// fetchOne asks provider i for the box, unless its last answer is still
+// within the provider's poll interval or it is throttled
+func (mp *MergeProvider) fetchOne(ctx context.Context, i int, p FlightProvider, box string, centerLat, centerLon, radiusDeg float64) (mergeResult, error) {
+	key := fmt.Sprintf("%d/%s", i, box)
+	now := time.Now()
+	info := p.RateLimitInfo()
+
+	mp.mu.Lock()
+	last, seen := mp.results[key]
+	mp.mu.Unlock()
+	pace := BudgetedInterval(PollInterval(p), info, RequestCost(p, radiusDeg), now)
+	if seen && Elapsed(last.at, now) < pace {
+		return last, nil
+	}
+	if until := info.ThrottledUntil; now.Before(until) {
+		return mergeResult{}, fmt.Errorf("%s: throttled until %s", p.Name(), until.Format("15:04:05"))
+	}
+
+	flights, err := p.FetchFlights(ctx, centerLat, centerLon, radiusDeg)
+	if err != nil {
+		return mergeResult{}, fmt.Errorf("%s: %w", p.Name(), err)
+	}
+	for j := range flights {
+		if flights[j].Source == "" {
+			flights[j].Source = p.Name()
+		}
+	}
+	r := mergeResult{flights: flights, at: now}
+	mp.mu.Lock()
+	mp.results[key] = r
+	mp.mu.Unlock()
+	return r, nil
+}
+
+// noteErrors logs a provider failing or recovering, once per change rather
+// than on every poll
+func (mp *MergeProvider) noteErrors(errs []error) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	for i, err := range errs {
+		msg := ""
+		if err != nil {
+			msg = err.Error()
+		}
+		switch {
+		case msg == mp.errs[i]:
+		case msg == "":
+			log.Printf("Merge: %s is back", mp.providers[i].Name())
+		default:
+			log.Printf("Merge: carrying on without %s", msg)
+		}
+		mp.errs[i] = msg
+	}
+}
+
+// mergeFlights combines the providers' answers by icao24. A position
+// without its own timestamp counts as measured when it was fetched.
+func mergeFlights(answers []mergeResult) []Flight {
+	var merged []Flight
+	index := make(map[string]int)
+	var posAt []time.Time
+	for _, a := range answers {
+		for _, f := range a.flights {
+			at := f.PositionAt
+			if at.IsZero() {
+				at = a.at
+			}
+			i, dup := index[f.Icao24]
+			switch {
+			case !dup:
+				index[f.Icao24] = len(merged)
+				merged = append(merged, f)
+				posAt = append(posAt, at)
+			case at.After(posAt[i]):
+				fillGaps(&f, merged[i])
+				merged[i], posAt[i] = f, at
+			default:
+				fillGaps(&merged[i], f)
+			}
+		}
+	}
+	return merged
+}
+
+// SourceLabel names the provider the flight's position came from and, when
+// the provider says, how old that position is, e.g. "local, 2s old"
+func (f Flight) SourceLabel(now time.Time) string {
+	if f.PositionAt.IsZero() {
+		return f.Source
+	}
+	// A receiver clock slightly ahead of ours mustn't read as negative
+	age := max(Elapsed(f.PositionAt, now), 0)
+	return fmt.Sprintf("%s, %s old", f.Source, age.Round(time.Second))
+}
+
+// fillGaps copies into dst the details it is missing and other has
+func fillGaps(dst *Flight, other Flight) {
+	fill := func(field *string, value string, placeholders ...string) {
+		missing := func(s string) bool { return s == "" || slices.Contains(placeholders, s) }
+		if missing(*field) && !missing(value) {
+			*field = value
+		}
+	}
+	fill(&dst.Callsign, other.Callsign, "N/A")
+	fill(&dst.Category, other.Category, "Unknown", "No Info")
+	fill(&dst.Origin, other.Origin)
+	fill(&dst.Destination, other.Destination)
+	fill(&dst.Registration, other.Registration)
+	fill(&dst.TypeCode, other.TypeCode)
+	fill(&dst.Operator, other.Operator)
+	if dst.Squawk == "" {
+		dst.Squawk, dst.Emergency = other.Squawk, other.Emergency
+	}
+	if dst.GeoAltitudeFt == 0 {
+		dst.GeoAltitudeFt = other.GeoAltitudeFt
+	}
+	if dst.FirstFlight.IsZero() {
+		dst.FirstFlight = other.FirstFlight
+	}
+}
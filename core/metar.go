@@ -0,0 +1,212 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	metarURL = "https://aviationweather.gov/api/data/metar"
+	// Stations report every 30 or 60 minutes
+	metarInterval = 30 * time.Minute
+	// How far from home to look for the nearest reporting station
+	metarSearchDeg = 1.0
+	// The home airport's own report is used when it is at most this far
+	// from home; further out the nearest station says more about the sky
+	metarHomeAirportKm = 60.0
+	// Visibility reported as "10+" statute miles or CAVOK
+	metarUnlimitedVisKm = 10 * 1.609
+)
+
+// Sky is the latest weather report from a station near home
+type Sky struct {
+	Station      string
+	Observed     time.Time
+	VisibilityKm float64 // horizontal visibility
+	CeilingFt    int     // lowest broken or overcast layer above ground, 0 if none
+	CeilingCover string  // BKN, OVC or VV (sky obscured)
+	WindDirDeg   int     // true direction the wind blows from, -1 when variable
+	WindKt       int
+	Raw          string
+}
+
+func (s Sky) String() string {
+	vis := fmt.Sprintf("vis %.0f km", s.VisibilityKm)
+	if s.VisibilityKm >= metarUnlimitedVisKm {
+		vis = "vis 10+ km"
+	}
+	if s.CeilingFt == 0 {
+		return s.Station + " " + vis + ", no ceiling"
+	}
+	return fmt.Sprintf("%s %s, %s %d ft", s.Station, vis, s.CeilingCover, s.CeilingFt)
+}
+
+// metarReport is one entry of aviationweather.gov's JSON METAR feed
+type metarReport struct {
+	Station string          `json:"icaoId"`
+	ObsTime int64           `json:"obsTime"` // unix seconds
+	Lat     float64         `json:"lat"`
+	Lon     float64         `json:"lon"`
+	Visib   json.RawMessage `json:"visib"` // statute miles, a number or e.g. "10+"
+	VertVis *int            `json:"vertVis"`
+	Wdir    json.RawMessage `json:"wdir"` // degrees true, a number or "VRB"
+	Wspd    int             `json:"wspd"` // knots
+	RawOb   string          `json:"rawOb"`
+	Clouds  []metarCloud    `json:"clouds"`
+}
+
+type metarCloud struct {
+	Cover string `json:"cover"`
+	Base  *int   `json:"base"` // ft above ground
+}
+
+// sky converts the report, reading visibility in statute miles
+func (m metarReport) sky() (Sky, error) {
+	s := Sky{
+		Station:  m.Station,
+		Observed: time.Unix(m.ObsTime, 0),
+		WindKt:   m.Wspd,
+		Raw:      m.RawOb,
+	}
+	// Variable, or missing from a report without wind
+	s.WindDirDeg = -1
+	if dir, err := strconv.Atoi(strings.Trim(string(m.Wdir), `"`)); err == nil && dir >= 0 && dir <= 360 {
+		s.WindDirDeg = dir % 360
+	}
+	vis := strings.Trim(string(m.Visib), `"`)
+	miles, err := strconv.ParseFloat(strings.TrimSuffix(vis, "+"), 64)
+	if err != nil {
+		return s, fmt.Errorf("metar %s: visibility %q: %w", m.Station, vis, err)
+	}
+	s.VisibilityKm = miles * 1.609
+	if strings.HasSuffix(vis, "+") {
+		s.VisibilityKm = max(s.VisibilityKm, metarUnlimitedVisKm)
+	}
+
+	for _, c := range m.Clouds {
+		if (c.Cover == "BKN" || c.Cover == "OVC") && c.Base != nil && (s.CeilingFt == 0 || *c.Base < s.CeilingFt) {
+			s.CeilingFt, s.CeilingCover = *c.Base, c.Cover
+		}
+	}
+	if m.VertVis != nil && (s.CeilingFt == 0 || *m.VertVis < s.CeilingFt) {
+		s.CeilingFt, s.CeilingCover = max(*m.VertVis, 1), "VV"
+	}
+	return s, nil
+}
+
+// MetarClient keeps the sky report for home up to date. METAR_STATION picks
+// the station, e.g. "EFHK"; otherwise the home airport's is used when it is
+// nearby, and the nearest one reporting when not. "off" disables it.
+type MetarClient struct {
+	client   *http.Client
+	station  string
+	lat, lon float64
+
+	mu  sync.Mutex
+	sky *Sky
+}
+
+// NewMetarClient returns nil when METAR_STATION is "off"
+func NewMetarClient(homeLat, homeLon float64) *MetarClient {
+	station := strings.ToUpper(strings.TrimSpace(os.Getenv("METAR_STATION")))
+	if station == "OFF" {
+		return nil
+	}
+	return &MetarClient{
+		client:  &http.Client{Timeout: 10 * time.Second},
+		station: station,
+		lat:     homeLat,
+		lon:     homeLon,
+	}
+}
+
+// Run fetches the report now and then every half hour until ctx is cancelled
+func (mc *MetarClient) Run(ctx context.Context) {
+	for {
+		sky, err := mc.Fetch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Println("METAR fetch failed:", err)
+		} else {
+			mc.mu.Lock()
+			mc.sky = sky
+			mc.mu.Unlock()
+		}
+		select {
+		case <-time.After(metarInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Fetch gets the latest report from the configured station, or from the
+// reporting station nearest home
+func (mc *MetarClient) Fetch(ctx context.Context) (*Sky, error) {
+	q := url.Values{"format": {"json"}}
+	station := mc.station
+	if home := HomeAirport(); station == "" && home.ICAO != "" && Distance(mc.lat, mc.lon, home.Lat, home.Lon) <= metarHomeAirportKm {
+		station = home.ICAO
+	}
+	if station != "" {
+		q.Set("ids", station)
+	} else {
+		q.Set("bbox", fmt.Sprintf("%.2f,%.2f,%.2f,%.2f",
+			mc.lat-metarSearchDeg, mc.lon-metarSearchDeg, mc.lat+metarSearchDeg, mc.lon+metarSearchDeg))
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", metarURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := mc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metar: status %d", resp.StatusCode)
+	}
+
+	var reports []metarReport
+	if err := json.NewDecoder(resp.Body).Decode(&reports); err != nil {
+		return nil, err
+	}
+	var nearest *metarReport
+	best := math.Inf(1)
+	for i, r := range reports {
+		if d := Distance(mc.lat, mc.lon, r.Lat, r.Lon); d < best {
+			nearest, best = &reports[i], d
+		}
+	}
+	if nearest == nil {
+		return nil, fmt.Errorf("metar: no station reporting near home")
+	}
+	sky, err := nearest.sky()
+	if err != nil {
+		return nil, err
+	}
+	return &sky, nil
+}
+
+// Sky is the latest report, nil until one has arrived or when the client is
+// off (nil)
+func (mc *MetarClient) Sky() *Sky {
+	if mc == nil {
+		return nil
+	}
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.sky
+}
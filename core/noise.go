@@ -0,0 +1,206 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"time"
+)
+
+const (
+	noiseFile = "noise_events.jsonl"
+
+	// DefaultNoiseAltitudeFt is the ceiling for a noise event when the
+	// settings don't give one
+	DefaultNoiseAltitudeFt = 3000
+	// A flight passing within this ground distance of home counts as overhead
+	NoiseRadiusKm = 3.0
+	// The same aircraft counts again only after being away this long, so a
+	// circling helicopter is one event rather than one per poll
+	noiseRearmAfter = 10 * time.Minute
+)
+
+// NoiseAltitudeSteps are the ceilings offered on the noise screen
+var NoiseAltitudeSteps = []int{1000, 2000, 3000, 4000, 5000, 8000}
+
+// NoiseEvent is one aircraft passing low overhead
+type NoiseEvent struct {
+	Icao24     string    `json:"icao24"`
+	Callsign   string    `json:"callsign"`
+	At         time.Time `json:"at"`
+	AltitudeFt int       `json:"altitude_ft"` // lowest seen on entering the zone
+	DistanceKm float64   `json:"distance_km"` // from home, along the ground
+}
+
+// NoiseCeiling is the configured noise event ceiling, or the default
+func (s Settings) NoiseCeiling() int {
+	if s.NoiseAltitudeFt <= 0 {
+		return DefaultNoiseAltitudeFt
+	}
+	return s.NoiseAltitudeFt
+}
+
+// NoiseCounter spots aircraft passing overhead below a ceiling. It is fed
+// every poll from one goroutine.
+type NoiseCounter struct {
+	homeLat, homeLon float64
+	seen             map[string]time.Time // icao24 -> last poll it was in the zone
+}
+
+func NewNoiseCounter(homeLat, homeLon float64) *NoiseCounter {
+	return &NoiseCounter{homeLat: homeLat, homeLon: homeLon, seen: make(map[string]time.Time)}
+}
+
+// Observe returns the aircraft that entered the zone in the poll s
+func (nc *NoiseCounter) Observe(s *FlightSnapshot, ceilingFt int) []NoiseEvent {
+	now := s.FetchedAt
+	var events []NoiseEvent
+	for _, f := range s.Within(nc.homeLat, nc.homeLon, NoiseRadiusKm) {
+		if f.OnGround || f.AltitudeFt <= 0 || f.AltitudeFt > ceilingFt {
+			continue
+		}
+		d := Distance(nc.homeLat, nc.homeLon, f.Lat, f.Lon)
+		if last, ok := nc.seen[f.Icao24]; !ok || Elapsed(last, now) > noiseRearmAfter {
+			events = append(events, NoiseEvent{
+				Icao24:     f.Icao24,
+				Callsign:   f.Callsign,
+				At:         now,
+				AltitudeFt: f.AltitudeFt,
+				DistanceKm: d,
+			})
+		}
+		nc.seen[f.Icao24] = now
+	}
+	for icao, last := range nc.seen {
+		if Elapsed(last, now) > noiseRearmAfter {
+			delete(nc.seen, icao)
+		}
+	}
+	return events
+}
+
+// SaveNoiseEvents appends events to the noise log
+func (dm *DataManager) SaveNoiseEvents(events []NoiseEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	var lines []byte
+	for _, e := range events {
+		line, err := encodeRecord(noiseFile, e)
+		if err != nil {
+			return err
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	return dm.storage().Append(noiseFile, lines)
+}
+
+// LoadNoiseEvents reads the logged events at or after since, oldest first
+func (dm *DataManager) LoadNoiseEvents(since time.Time) ([]NoiseEvent, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	file, err := dm.storage().Open(noiseFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []NoiseEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e NoiseEvent
+		if err := decodeRecord(noiseFile, scanner.Bytes(), &e); err != nil {
+			continue // torn line
+		}
+		if !e.At.Before(since) {
+			events = append(events, e)
+		}
+	}
+	return events, scanner.Err()
+}
+
+// NoiseDay is the number of events on one local calendar day
+type NoiseDay struct {
+	Day   time.Time // local midnight
+	Count int
+}
+
+// NoiseStats is what the noise chart shows
+type NoiseStats struct {
+	Hours [24]int    // events per local hour of the last day
+	Days  []NoiseDay // events per day, oldest first, ending today
+}
+
+// localMidnight is the start of t's day in the local timezone
+func localMidnight(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// NoiseSince is the start of the period SummarizeNoise covers for days days
+// ending with now's
+func NoiseSince(now time.Time, days int) time.Time {
+	return localMidnight(now).AddDate(0, 0, 1-days)
+}
+
+// SummarizeNoise buckets events into the hours of now's local day and the
+// days days ending with it
+func SummarizeNoise(events []NoiseEvent, now time.Time, days int) NoiseStats {
+	s := NoiseStats{Days: make([]NoiseDay, days)}
+	first := NoiseSince(now, days)
+	for i := range s.Days {
+		s.Days[i].Day = first.AddDate(0, 0, i)
+	}
+	today := localMidnight(now)
+	for _, e := range events {
+		day := localMidnight(e.At)
+		// Count calendar days rather than 24h spans, which DST changes break
+		for i := range s.Days {
+			if s.Days[i].Day.Equal(day) {
+				s.Days[i].Count++
+				break
+			}
+		}
+		if day.Equal(today) {
+			s.Hours[e.At.Local().Hour()]++
+		}
+	}
+	return s
+}
+
+// ExportNoiseCSV writes events to captures/noise-<today>.csv for handing to
+// the authorities, and returns where it is
+func (dm *DataManager) ExportNoiseCSV(events []NoiseEvent, now time.Time) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"time", "icao24", "callsign", "altitude_ft", "distance_km"})
+	for _, e := range events {
+		w.Write([]string{
+			e.At.Local().Format(time.RFC3339),
+			e.Icao24,
+			e.Callsign,
+			strconv.Itoa(e.AltitudeFt),
+			strconv.FormatFloat(e.DistanceKm, 'f', 2, 64),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	name := path.Join(capturesDir, fmt.Sprintf("noise-%s.csv", now.Local().Format("2006-01-02")))
+	if err := dm.storage().WriteFile(name, buf.Bytes()); err != nil {
+		return "", err
+	}
+	return displayPath(dm.storage(), name), nil
+}
@@ -0,0 +1,207 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+const (
+	// The odd one out flies at least this far above all three others, so
+	// it stays "much higher" while the player looks
+	oddAltitudeGapFt = 8000
+	// The odd one out climbs at least this fast, well clear of level
+	// flight, so it is still climbing when the player checks
+	oddClimbMinFpm = 2 * levelFlightFpm
+)
+
+// ErrNoOddOneOut means the flights in view can't make a fair puzzle
+var ErrNoOddOneOut = errors.New("not enough varied traffic for a puzzle")
+
+// OddOneOutKind is the criterion an odd-one-out puzzle turns on
+type OddOneOutKind int
+
+const (
+	OddAirline  OddOneOutKind = iota // one flies for a different airline
+	OddAltitude                      // one is much higher than the rest
+	OddClimbing                      // one is the only one climbing
+)
+
+var oddOneOutKinds = []OddOneOutKind{OddAirline, OddAltitude, OddClimbing}
+
+// OddOneOut is a trivia puzzle built from live traffic: four flights in
+// view, one of which doesn't match the others
+type OddOneOut struct {
+	Kind    OddOneOutKind
+	Flights []Flight // the four, in the order shown
+	Odd     int      // index into Flights of the answer
+}
+
+// Prompt is the question, e.g. "Which one is the only one climbing?"
+func (q OddOneOut) Prompt() string {
+	switch q.Kind {
+	case OddAirline:
+		return "Which one flies for a different airline?"
+	case OddAltitude:
+		return "Which one is much higher than the rest?"
+	}
+	return "Which one is the only one climbing?"
+}
+
+// Explain says why the answer is right, for after the player has picked
+func (q OddOneOut) Explain() string {
+	odd := q.Flights[q.Odd]
+	switch q.Kind {
+	case OddAirline:
+		other := q.Flights[(q.Odd+1)%len(q.Flights)]
+		return fmt.Sprintf("%s is %s, the rest %s", odd.Callsign, airlineLabel(odd.Callsign), airlineLabel(other.Callsign))
+	case OddAltitude:
+		highest := 0
+		for i, f := range q.Flights {
+			if i != q.Odd {
+				highest = max(highest, f.AltitudeFt)
+			}
+		}
+		return fmt.Sprintf("%s is at %d ft, the rest at %d ft or lower", odd.Callsign, odd.AltitudeFt, highest)
+	}
+	return fmt.Sprintf("%s is climbing at %d fpm", odd.Callsign, odd.VerticalRateFpm)
+}
+
+// airlineLabel names the airline of callsign, or its designator when the
+// name isn't known
+func airlineLabel(callsign string) string {
+	if name := AirlineName(callsign); name != "" {
+		return name
+	}
+	return AirlineCode(callsign)
+}
+
+// Answer reports whether the flight at index i is the odd one out
+func (q OddOneOut) Answer(i int) bool {
+	return i == q.Odd
+}
+
+// Verify checks that the puzzle has exactly one right answer: the odd
+// flight meets the criterion with margin to spare and none of the others
+// does
+func (q OddOneOut) Verify() bool {
+	if len(q.Flights) != 4 || q.Odd < 0 || q.Odd >= len(q.Flights) {
+		return false
+	}
+	odd := q.Flights[q.Odd]
+	oddCode := AirlineCode(odd.Callsign)
+	restCode := AirlineCode(q.Flights[(q.Odd+1)%len(q.Flights)].Callsign)
+	seen := make(map[string]bool)
+	for _, f := range q.Flights {
+		if !oddEligible(f) || seen[f.Callsign] {
+			return false
+		}
+		seen[f.Callsign] = true
+	}
+	for i, f := range q.Flights {
+		if i == q.Odd {
+			continue
+		}
+		switch q.Kind {
+		case OddAirline:
+			if oddCode == "" || restCode == "" || oddCode == restCode || AirlineCode(f.Callsign) != restCode {
+				return false
+			}
+		case OddAltitude:
+			if odd.AltitudeFt-f.AltitudeFt < oddAltitudeGapFt {
+				return false
+			}
+		case OddClimbing:
+			if odd.VerticalRateFpm < oddClimbMinFpm || f.Trend() == TrendClimbing {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// oddEligible reports whether f can appear in a puzzle: airborne, fresh
+// and labelled, so the player can find it on the map
+func oddEligible(f Flight) bool {
+	return !f.Stale && !f.OnGround && f.Callsign != "" && f.AltitudeFt > 0
+}
+
+// NewOddOneOut builds a puzzle from the flights in view, trying the
+// criteria in random order until one of them can be told apart fairly
+func NewOddOneOut(flights []Flight) (OddOneOut, error) {
+	var pool []Flight
+	seen := make(map[string]bool)
+	for _, f := range flights {
+		if oddEligible(f) && !seen[f.Callsign] {
+			seen[f.Callsign] = true
+			pool = append(pool, f)
+		}
+	}
+	rand.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+
+	for _, i := range rand.Perm(len(oddOneOutKinds)) {
+		kind := oddOneOutKinds[i]
+		odd, rest, ok := pickOddOneOut(kind, pool)
+		if !ok {
+			continue
+		}
+		q := OddOneOut{Kind: kind, Flights: append(rest, odd)}
+		rand.Shuffle(len(q.Flights), func(i, j int) { q.Flights[i], q.Flights[j] = q.Flights[j], q.Flights[i] })
+		for i, f := range q.Flights {
+			if f.Icao24 == odd.Icao24 {
+				q.Odd = i
+			}
+		}
+		if q.Verify() {
+			return q, nil
+		}
+	}
+	return OddOneOut{}, ErrNoOddOneOut
+}
+
+// pickOddOneOut finds an odd flight for kind in pool and three flights it
+// stands apart from
+func pickOddOneOut(kind OddOneOutKind, pool []Flight) (Flight, []Flight, bool) {
+	first3 := func(keep func(Flight) bool) []Flight {
+		var rest []Flight
+		for _, f := range pool {
+			if len(rest) < 3 && keep(f) {
+				rest = append(rest, f)
+			}
+		}
+		return rest
+	}
+	for _, odd := range pool {
+		var rest []Flight
+		switch kind {
+		case OddAirline:
+			code := AirlineCode(odd.Callsign)
+			if code == "" {
+				continue
+			}
+			// Three of any one other airline
+			for _, f := range pool {
+				other := AirlineCode(f.Callsign)
+				if other == "" || other == code {
+					continue
+				}
+				if rest = first3(func(g Flight) bool { return AirlineCode(g.Callsign) == other }); len(rest) == 3 {
+					break
+				}
+			}
+		case OddAltitude:
+			rest = first3(func(f Flight) bool { return odd.AltitudeFt-f.AltitudeFt >= oddAltitudeGapFt })
+		case OddClimbing:
+			if odd.VerticalRateFpm < oddClimbMinFpm {
+				continue
+			}
+			rest = first3(func(f Flight) bool { return f.Trend() != TrendClimbing })
+		}
+		if len(rest) == 3 {
+			return odd, rest, true
+		}
+	}
+	return Flight{}, nil, false
+}
@@ -0,0 +1,202 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// stateVector is one entry of OpenSky's /states/all response. The API sends
+// each as a positional JSON array in which most fields may be null; the
+// nullable ones are pointers here.
+type stateVector struct {
+	Icao24        string
+	Callsign      *string
+	OriginCountry string
+	TimePosition  *float64 // Unix seconds of the last position update
+	Lon           *float64
+	Lat           *float64
+	BaroAltitude  *float64 // metres
+	OnGround      bool
+	Velocity      *float64 // m/s
+	TrueTrack     *float64 // degrees clockwise from north
+	VerticalRate  *float64 // m/s, positive climbing
+	GeoAltitude   *float64 // metres
+	Squawk        *string
+	Category      *int // only sent with extended=1
+}
+
+// Positions of the fields used, see the OpenSky REST API docs
+const (
+	stateIcao24 = iota
+	stateCallsign
+	stateOriginCountry
+	stateTimePosition
+	stateLastContact
+	stateLon
+	stateLat
+	stateBaroAltitude
+	stateOnGround
+	stateVelocity
+	stateTrueTrack
+	stateVerticalRate
+	stateSensors
+	stateGeoAltitude
+	stateSquawk
+	stateCategory = 17
+)
+
+var errNoPosition = errors.New("no position")
+
+// UnmarshalJSON decodes the positional array. Fields past the end of a short
+// array are left unset; a field of the wrong type is an error.
+func (v *stateVector) UnmarshalJSON(b []byte) error {
+	var fields []json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	decode := func(i int, name string, out interface{}) error {
+		if i >= len(fields) {
+			return nil
+		}
+		if err := json.Unmarshal(fields[i], out); err != nil {
+			return fmt.Errorf("%s: unexpected value %s", name, fields[i])
+		}
+		return nil
+	}
+	var catCode *float64 // categories arrive as JSON numbers
+	err := errors.Join(
+		decode(stateIcao24, "icao24", &v.Icao24),
+		decode(stateCallsign, "callsign", &v.Callsign),
+		decode(stateOriginCountry, "origin_country", &v.OriginCountry),
+		decode(stateTimePosition, "time_position", &v.TimePosition),
+		decode(stateLon, "longitude", &v.Lon),
+		decode(stateLat, "latitude", &v.Lat),
+		decode(stateBaroAltitude, "baro_altitude", &v.BaroAltitude),
+		decode(stateOnGround, "on_ground", &v.OnGround),
+		decode(stateVelocity, "velocity", &v.Velocity),
+		decode(stateTrueTrack, "true_track", &v.TrueTrack),
+		decode(stateVerticalRate, "vertical_rate", &v.VerticalRate),
+		decode(stateGeoAltitude, "geo_altitude", &v.GeoAltitude),
+		decode(stateSquawk, "squawk", &v.Squawk),
+		decode(stateCategory, "category", &catCode),
+	)
+	if catCode != nil && *catCode >= 0 && *catCode < 100 {
+		c := int(*catCode)
+		v.Category = &c
+	}
+	return err
+}
+
+// flight converts the vector, rejecting ones that can't be placed on the map
+func (v stateVector) flight(source string) (Flight, error) {
+	if v.Icao24 == "" {
+		return Flight{}, errors.New("no icao24")
+	}
+	if v.Lat == nil || v.Lon == nil {
+		return Flight{}, errNoPosition
+	}
+	lat, lon := *v.Lat, *v.Lon
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return Flight{}, fmt.Errorf("position %v,%v out of range", lat, lon)
+	}
+
+	callsign := "N/A"
+	if v.Callsign != nil && strings.TrimSpace(*v.Callsign) != "" {
+		callsign = strings.TrimSpace(*v.Callsign)
+	}
+	catStr := "Unknown"
+	if v.Category != nil {
+		if val, ok := categoryMap[*v.Category]; ok {
+			catStr = val
+		}
+	}
+
+	// Out-of-range readings are treated like nulls
+	altM := inRange(v.BaroAltitude, -1000, 30000)
+	velMs := inRange(v.Velocity, 0, 1500)
+	vrMs := inRange(v.VerticalRate, -150, 150)
+	geoM := inRange(v.GeoAltitude, -1000, 30000)
+	squawk := ""
+	if v.Squawk != nil {
+		squawk = strings.TrimSpace(*v.Squawk)
+	}
+	var posAt time.Time
+	if v.TimePosition != nil && *v.TimePosition > 0 {
+		posAt = time.Unix(int64(*v.TimePosition), 0)
+	}
+
+	return Flight{
+		Icao24:      v.Icao24,
+		Callsign:    callsign,
+		Lon:         lon,
+		Lat:         lat,
+		VelocityKts: int(velMs * 1.94384),
+		Heading:     inRange(v.TrueTrack, 0, 360),
+		AltitudeFt:  int(altM * 3.28084),
+		OnGround:    v.OnGround,
+		Origin:      v.OriginCountry,
+		Category:    catStr,
+		Source:      source,
+		PositionAt:  posAt,
+
+		VerticalRateFpm: int(vrMs * 196.85),
+		GeoAltitudeFt:   int(geoM * 3.28084),
+
+		Squawk:    squawk,
+		Emergency: SquawkEmergency(squawk),
+	}, nil
+}
+
+// inRange dereferences an optional reading, giving 0 for nil or values
+// outside [lo, hi]
+func inRange(f *float64, lo, hi float64) float64 {
+	if f == nil || *f < lo || *f > hi {
+		return 0
+	}
+	return *f
+}
+
+// parseOpenSkyStates converts a /states/all response into flights. A bad
+// state vector is skipped and reported in warnings rather than failing the
+// whole poll; only a response that isn't the expected document is an error.
+// Aircraft without a position are common and skipped silently.
+func parseOpenSkyStates(body []byte, source string) (flights []Flight, warnings []error, err error) {
+	flights, warnings, _, err = parseOpenSkyResponse(body, source)
+	return flights, warnings, err
+}
+
+// parseOpenSkyResponse is parseOpenSkyStates that also gives the time the
+// server stamped the states with, zero if it didn't
+func parseOpenSkyResponse(body []byte, source string) (flights []Flight, warnings []error, at time.Time, err error) {
+	var result struct {
+		Time   int64             `json:"time"`
+		States []json.RawMessage `json:"states"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, nil, time.Time{}, err
+	}
+	if result.Time > 0 {
+		at = time.Unix(result.Time, 0)
+	}
+
+	for i, raw := range result.States {
+		var v stateVector
+		if err := json.Unmarshal(raw, &v); err != nil {
+			warnings = append(warnings, fmt.Errorf("state %d: %w", i, err))
+			continue
+		}
+		f, err := v.flight(source)
+		if errors.Is(err, errNoPosition) {
+			continue
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Errorf("state %d (%s): %w", i, v.Icao24, err))
+			continue
+		}
+		flights = append(flights, f)
+	}
+	return flights, warnings, at, nil
+}
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// Orientation is how far the landscape UI is turned, clockwise, to fit
+// the display it is drawn on
+type Orientation int
+
+const (
+	OrientAuto             Orientation = -1 // picked from the display's shape
+	OrientLandscape        Orientation = 0
+	OrientPortrait         Orientation = 90 // a landscape UI on a display mounted on its side
+	OrientLandscapeFlipped Orientation = 180
+	OrientPortraitFlipped  Orientation = 270
+)
+
+var orientationNames = map[string]Orientation{
+	"auto":              OrientAuto,
+	"landscape":         OrientLandscape,
+	"portrait":          OrientPortrait,
+	"landscape-flipped": OrientLandscapeFlipped,
+	"portrait-flipped":  OrientPortraitFlipped,
+}
+
+// ParseOrientation reads an orientation by name, as the ORIENTATION
+// environment variable and -orientation flag give it
+func ParseOrientation(s string) (Orientation, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return OrientAuto, nil
+	}
+	if o, ok := orientationNames[s]; ok {
+		return o, nil
+	}
+	return OrientAuto, fmt.Errorf("unknown orientation %q (auto, landscape, portrait, landscape-flipped or portrait-flipped)", s)
+}
+
+// DefaultOrientation is ORIENTATION, or auto when it isn't set or valid
+func DefaultOrientation() string {
+	if _, err := ParseOrientation(os.Getenv("ORIENTATION")); err != nil {
+		return "auto"
+	}
+	return strings.TrimSpace(os.Getenv("ORIENTATION"))
+}
+
+func (o Orientation) String() string {
+	for name, v := range orientationNames {
+		if v == o {
+			return name
+		}
+	}
+	return fmt.Sprintf("%d°", int(o))
+}
+
+// For is the orientation to use on a w×h display: o itself, or when o is
+// auto, portrait on a display taller than it is wide
+func (o Orientation) For(w, h int) Orientation {
+	switch {
+	case o != OrientAuto:
+		return o
+	case w < h:
+		return OrientPortrait
+	}
+	return OrientLandscape
+}
+
+// Sideways reports whether o swaps the UI's width and height
+func (o Orientation) Sideways() bool {
+	return o == OrientPortrait || o == OrientPortraitFlipped
+}
+
+// WindowSize is the window that shows a w×h UI turned to o
+func (o Orientation) WindowSize(w, h int) (int, int) {
+	if o.Sideways() {
+		return h, w
+	}
+	return w, h
+}
+
+// ScreenTransform maps the logical landscape canvas the frontends draw on
+// to the display: turned to the orientation, scaled to fit and centred.
+// Both frontends draw and read input through one, so a single build works
+// on a desktop monitor and a display mounted in portrait.
+type ScreenTransform struct {
+	Orientation      Orientation // resolved, never auto
+	LogicalW         int
+	LogicalH         int
+	ScreenW, ScreenH int
+}
+
+// NewScreenTransform fits a logicalW×logicalH canvas on a screenW×screenH
+// display in orientation o, which may be auto
+func NewScreenTransform(o Orientation, logicalW, logicalH, screenW, screenH int) ScreenTransform {
+	return ScreenTransform{
+		Orientation: o.For(screenW, screenH),
+		LogicalW:    logicalW,
+		LogicalH:    logicalH,
+		ScreenW:     screenW,
+		ScreenH:     screenH,
+	}
+}
+
+// Scale is how many screen pixels a logical pixel covers
+func (t ScreenTransform) Scale() float64 {
+	w, h := t.Orientation.WindowSize(t.LogicalW, t.LogicalH)
+	if w <= 0 || h <= 0 || t.ScreenW <= 0 || t.ScreenH <= 0 {
+		return 1
+	}
+	return min(float64(t.ScreenW)/float64(w), float64(t.ScreenH)/float64(h))
+}
+
+// Radians is the clockwise turn, as drawing libraries that take radians
+// want it
+func (t ScreenTransform) Radians() float64 {
+	return float64(t.Orientation) * math.Pi / 180
+}
+
+// ToScreen maps a point on the canvas to the screen
+func (t ScreenTransform) ToScreen(x, y float64) (float64, float64) {
+	dx, dy := x-float64(t.LogicalW)/2, y-float64(t.LogicalH)/2
+	switch t.Orientation {
+	case OrientPortrait:
+		dx, dy = -dy, dx
+	case OrientLandscapeFlipped:
+		dx, dy = -dx, -dy
+	case OrientPortraitFlipped:
+		dx, dy = dy, -dx
+	}
+	s := t.Scale()
+	return float64(t.ScreenW)/2 + dx*s, float64(t.ScreenH)/2 + dy*s
+}
+
+// ToLogical maps a point on the screen, e.g. a touch, to the canvas
+func (t ScreenTransform) ToLogical(x, y float64) (float64, float64) {
+	s := t.Scale()
+	dx, dy := (x-float64(t.ScreenW)/2)/s, (y-float64(t.ScreenH)/2)/s
+	// Undo the clockwise turn; y grows downwards
+	switch t.Orientation {
+	case OrientPortrait:
+		dx, dy = dy, -dx
+	case OrientLandscapeFlipped:
+		dx, dy = -dx, -dy
+	case OrientPortraitFlipped:
+		dx, dy = -dy, dx
+	}
+	return float64(t.LogicalW)/2 + dx, float64(t.LogicalH)/2 + dy
+}
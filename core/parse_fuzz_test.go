@@ -0,0 +1,157 @@
+package core
+
+import (
+	"testing"
+)
+
+func FuzzParseOpenSkyStates(f *testing.F) {
+	f.Add([]byte(`{"time":1700000000,"states":[["4601f6","FIN7LV  ","Finland",1700000000,1700000000,24.96,60.31,3500.5,false,210.3,87.2,-5.5,null,3600,"1000",false,0,4]]}`))
+	f.Add([]byte(`{"time":1700000000,"states":[["4601f6",null,"Finland",null,null,null,60.31,null,false,null,null]]}`))
+	f.Add([]byte(`{"time":1700000000,"states":[["4601f6"],[],null,[1,2,3,4,5,6,7,8,9,10,11]]}`))
+	f.Add([]byte(`{"time":1700000000,"states":null}`))
+	f.Add([]byte(`{"states":[["x","y","z",0,0,999,999,1e308,"yes",-1,720,0,0,0,0,0,0,1e300]]}`))
+	f.Add([]byte(`[]`))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		flights, warnings, err := parseOpenSkyStates(body, "opensky")
+		if err != nil {
+			if flights != nil || warnings != nil {
+				t.Errorf("results returned alongside %v", err)
+			}
+			return
+		}
+		for _, w := range warnings {
+			if w == nil {
+				t.Error("nil warning")
+			}
+		}
+		for _, fl := range flights {
+			if fl.Icao24 == "" || fl.Callsign == "" {
+				t.Errorf("flight without identity: %+v", fl)
+			}
+			if fl.Lat < -90 || fl.Lat > 90 || fl.Lon < -180 || fl.Lon > 180 {
+				t.Errorf("impossible position: %+v", fl)
+			}
+			if fl.Heading < 0 || fl.Heading > 360 || fl.VelocityKts < 0 {
+				t.Errorf("impossible motion: %+v", fl)
+			}
+		}
+	})
+}
+
+func FuzzParseTrackpollBootstrap(f *testing.F) {
+	f.Add(`<script>var trackpollBootstrap = {"flights":{"FIN7-1700000000-schedule-0001":{"activityLog":{"flights":[{"origin":{"friendlyLocation":"Helsinki, Finland"},"destination":{"friendlyLocation":"Stockholm, Sweden","iata":"ARN"},"aircraft":{"friendlyType":"Airbus A321","type":"A321"}}]}}}};</script>`)
+	f.Add(`trackpollBootstrap = {"flights":{"x":{"activityLog":{"flights":[{"destination":{"iata":"ARN"},"aircraft":{"type":"A321"}}]}}}};`)
+	f.Add(`trackpollBootstrap = {"flights":{"x":{"activityLog":{"flights":[null,{}]}}}};`)
+	f.Add(`trackpollBootstrap = {"flights":[]};`)
+	f.Add(`trackpollBootstrap = {"a":"};"};`)
+	f.Add(`<html>blocked</html>`)
+
+	f.Fuzz(func(t *testing.T, page string) {
+		d, err := parseTrackpollBootstrap(page)
+		if err != nil {
+			if d != nil {
+				t.Errorf("details %+v returned with error %v", d, err)
+			}
+			return
+		}
+		if d == nil {
+			t.Fatal("nil details without an error")
+		}
+		if d.RealDestination == "" && d.Model == "" && d.Origin == "" {
+			t.Errorf("empty details accepted from %q", page)
+		}
+	})
+}
+
+func FuzzLoadUsers(f *testing.F) {
+	f.Add([]byte(`{"schemaVersion":1,"data":{"alice":{"name":"alice","games_played":3,"total_score":900,"best_score":500,"difficulty":{"level":0.4,"recent":[true,false,true]}}}}`))
+	f.Add([]byte(`{"bob":{"name":"bob","games_played":1,"total_score":100,"best_score":100}}`))
+	f.Add([]byte(`{"schemaVersion":1,"data":null}`))
+	f.Add([]byte(`{"schemaVersion":1,"data":{"":{"name":"x"},"eve":{"name":"mallory","games_played":-4,"best_score":-1,"difficulty":{"level":7}}}}`))
+	f.Add([]byte(`{"schemaVersion":99,"data":{}}`))
+	f.Add([]byte(`null`))
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		var users map[string]UserStats
+		if err := decodeRecord(usersFile, raw, &users); err != nil {
+			return
+		}
+		users = sanitizeUsers(users)
+		if users == nil {
+			t.Fatal("nil users map would panic on the next save")
+		}
+		for name, u := range users {
+			if name == "" || u.Name != name {
+				t.Errorf("user %q stored under %q", u.Name, name)
+			}
+			if u.GamesPlayed < 0 || u.TotalScore < 0 || u.BestScore < 0 {
+				t.Errorf("negative stats: %+v", u)
+			}
+			if u.Difficulty.Level < 0 || u.Difficulty.Level > 1 || len(u.Difficulty.Recent) > difficultyWindow {
+				t.Errorf("difficulty out of range: %+v", u.Difficulty)
+			}
+			// The adaptive timer must stay within its bounds too
+			if rt := u.Difficulty.RoundTime(); rt < hardRoundTime || rt > easyRoundTime {
+				t.Errorf("round time %v from %+v", rt, u.Difficulty)
+			}
+		}
+	})
+}
+
+func FuzzLoadAirports(f *testing.F) {
+	f.Add([]byte(`{"schemaVersion":1,"data":["Oslo, Norway","Stockholm, Sweden"]}`))
+	f.Add([]byte(`["Paris","Paris","","Unknown","N/A"]`))
+	f.Add([]byte(`{"schemaVersion":1,"data":null}`))
+	f.Add([]byte(`{"schemaVersion":1,"data":[1,2]}`))
+	f.Add([]byte(`["Helsinki, Finland","Helsinki-Vantaa","Helsinki"]`))
+	f.Add([]byte(`{"schemaVersion":2,"data":[{"name":"Oslo Gardermoen","city":"Oslo","country":"Norway","icao":"ENGM"}]}`))
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		var airports []Airport
+		if err := decodeRecord(airportsFile, raw, &airports); err != nil {
+			return
+		}
+		clean := sanitizeAirports(airports)
+		for i, a := range clean {
+			if a.City == "" || a.City == "Unknown" || a.City == "N/A" {
+				t.Errorf("bad airport %+v kept from %q", a, raw)
+			}
+			for _, b := range clean[:i] {
+				if sameAirport(a, b) {
+					t.Errorf("near-duplicates %+v and %+v kept from %q", b, a, raw)
+				}
+			}
+		}
+	})
+}
+
+func FuzzSQLiteRecord(f *testing.F) {
+	f.Add([]byte{0x05, 0x01, 0x0f, 0x08, 0x07, 0x2a, 'a', 0x3f, 0xf0, 0, 0, 0, 0, 0, 0})
+	f.Add([]byte{0x03, 0x06, 0x10, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe, 0x01, 0x02})
+	f.Add([]byte{0x01})
+	f.Add([]byte{0x00, 0x01})
+	f.Add([]byte{0x02, 0x81})
+	f.Add([]byte{0x02, 0x0a})
+	f.Add([]byte{0x0a, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		rec, err := sqliteRecord(data)
+		if err != nil {
+			if rec != nil {
+				t.Errorf("values returned alongside %v", err)
+			}
+			return
+		}
+		if len(rec) > len(data) {
+			t.Errorf("%d values from %d bytes", len(rec), len(data))
+		}
+		for _, v := range rec {
+			switch v.(type) {
+			case nil, int64, float64, string, []byte:
+			default:
+				t.Errorf("value %#v of an unexpected type", v)
+			}
+		}
+	})
+}
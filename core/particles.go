@@ -0,0 +1,194 @@
+package core
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Confetti colours as 0xRRGGBBAA, like the frontends' palette constants
+var confettiColors = []uint32{0xf87171ff, 0xfbbf24ff, 0x34d399ff, 0x38bdf8ff, 0xa78bfaff, 0xf472b6ff}
+
+// maxParticles caps the pool; effects emitted while it is full are dropped
+// rather than growing it
+const maxParticles = 512
+
+// ParticleKind decides how a particle moves and how it is drawn
+type ParticleKind int
+
+const (
+	ParticleConfetti ParticleKind = iota // spinning rectangle that falls
+	ParticleSpark                        // small square flying outwards
+	ParticlePulse                        // ring growing from a point
+)
+
+// Gravity (px/s²) and drag (share of velocity kept per second) per kind
+var particleMotion = [...]struct{ gravity, drag float64 }{
+	ParticleConfetti: {260, 0.6},
+	ParticleSpark:    {0, 0.05},
+	ParticlePulse:    {0, 1},
+}
+
+// Particle is one particle in screen pixels
+type Particle struct {
+	Kind   ParticleKind
+	X, Y   float64
+	VX, VY float64 // px/s
+	Angle  float64 // radians
+	Spin   float64 // radians/s
+	Age    float64 // seconds; negative while waiting to appear
+	Life   float64
+	Size   float64 // width, or a pulse's final radius
+	Color  uint32
+}
+
+// Alpha is the particle's opacity: confetti fades over the last third of
+// its life, sparks and pulses fade all the way
+func (p Particle) Alpha() float64 {
+	if p.Age < 0 {
+		return 0
+	}
+	left := (p.Life - p.Age) / p.Life
+	if p.Kind == ParticleConfetti {
+		left *= 3
+	}
+	return math.Max(0, math.Min(1, left))
+}
+
+// Radius is how far a pulse has grown
+func (p Particle) Radius() float64 {
+	return p.Size * math.Max(0, p.Age) / p.Life
+}
+
+// ParticleRenderer draws particles with one frontend's graphics API
+type ParticleRenderer interface {
+	// Rect draws a w×h rectangle centred on (x, y), rotated by angle radians
+	Rect(x, y, w, h, angle float64, color uint32, alpha float64)
+	// Ring draws a thin circle outline
+	Ring(x, y, radius float64, color uint32, alpha float64)
+}
+
+// Particles is a pool of live particles shared by all effects. The
+// frontends step it from Update and draw it through a ParticleRenderer.
+type Particles struct {
+	List     []Particle
+	Disabled bool // drop every effect, for low-memory devices
+}
+
+func (ps *Particles) emit(p Particle) {
+	if ps.Disabled {
+		return
+	}
+	if ps.List == nil {
+		ps.List = make([]Particle, 0, maxParticles)
+	}
+	if len(ps.List) < maxParticles {
+		ps.List = append(ps.List, p)
+	}
+}
+
+// Confetti bursts n pieces upwards from (x, y), for celebrations
+func (ps *Particles) Confetti(x, y float64, n int) {
+	for i := 0; i < n; i++ {
+		angle := -math.Pi/2 + (rand.Float64()-0.5)*math.Pi*0.8
+		speed := 150 + rand.Float64()*250
+		ps.emit(Particle{
+			Kind:  ParticleConfetti,
+			X:     x,
+			Y:     y,
+			VX:    math.Cos(angle) * speed,
+			VY:    math.Sin(angle) * speed,
+			Angle: rand.Float64() * 2 * math.Pi,
+			Spin:  (rand.Float64() - 0.5) * 12,
+			Life:  2.5 * (0.7 + rand.Float64()*0.3),
+			Size:  8,
+			Color: confettiColors[rand.Intn(len(confettiColors))],
+		})
+	}
+}
+
+// Sparks throws n short-lived sparks out in all directions, e.g. when a
+// plane is selected
+func (ps *Particles) Sparks(x, y float64, n int, color uint32) {
+	for i := 0; i < n; i++ {
+		angle := rand.Float64() * 2 * math.Pi
+		speed := 60 + rand.Float64()*80
+		ps.emit(Particle{
+			Kind:  ParticleSpark,
+			X:     x,
+			Y:     y,
+			VX:    math.Cos(angle) * speed,
+			VY:    math.Sin(angle) * speed,
+			Life:  0.4 + rand.Float64()*0.3,
+			Size:  3,
+			Color: color,
+		})
+	}
+}
+
+// Pulse sends rings expanding from (x, y) one after another, to draw the
+// eye to an alert
+func (ps *Particles) Pulse(x, y float64, color uint32, rings int) {
+	for i := 0; i < rings; i++ {
+		ps.emit(Particle{
+			Kind:  ParticlePulse,
+			X:     x,
+			Y:     y,
+			Age:   -0.4 * float64(i),
+			Life:  1.2,
+			Size:  40,
+			Color: color,
+		})
+	}
+}
+
+// Update advances every particle by dt seconds and drops the expired ones,
+// keeping the pool's storage for reuse
+func (ps *Particles) Update(dt float64) {
+	kept := ps.List[:0]
+	for _, p := range ps.List {
+		p.Age += dt
+		if p.Age >= p.Life {
+			continue
+		}
+		if p.Age > 0 {
+			m := particleMotion[p.Kind]
+			drag := math.Pow(m.drag, dt)
+			p.VY += m.gravity * dt
+			p.VX *= drag
+			p.VY *= drag
+			p.X += p.VX * dt
+			p.Y += p.VY * dt
+			p.Angle += p.Spin * dt
+		}
+		kept = append(kept, p)
+	}
+	ps.List = kept
+}
+
+// Draw renders every visible particle through r
+func (ps *Particles) Draw(r ParticleRenderer) {
+	for _, p := range ps.List {
+		a := p.Alpha()
+		if a <= 0 {
+			continue
+		}
+		switch p.Kind {
+		case ParticleConfetti:
+			r.Rect(p.X, p.Y, p.Size, p.Size*0.6, p.Angle, p.Color, a)
+		case ParticleSpark:
+			r.Rect(p.X, p.Y, p.Size, p.Size, 0, p.Color, a)
+		case ParticlePulse:
+			r.Ring(p.X, p.Y, p.Radius(), p.Color, a)
+		}
+	}
+}
+
+// Active reports whether anything is left to draw
+func (ps *Particles) Active() bool {
+	return len(ps.List) > 0
+}
+
+// Clear removes all particles
+func (ps *Particles) Clear() {
+	ps.List = ps.List[:0]
+}
@@ -0,0 +1,387 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	partyCookie     = "party"
+	partyMaxPlayers = 20
+	partyMaxName    = 12
+)
+
+// PartyAddr is where PARTY_ADDR asks the party server to listen, e.g.
+// ":8080", or "" when party mode is off
+func PartyAddr() string {
+	return strings.TrimSpace(os.Getenv("PARTY_ADDR"))
+}
+
+// PartyPlayer is a phone that has joined the party
+type PartyPlayer struct {
+	Name     string
+	Score    int
+	Answered bool // in the current round
+	Correct  bool // in the last round that closed
+}
+
+// PartyStanding is one row of the scoreboard
+type PartyStanding struct {
+	Name  string
+	Score int
+}
+
+// partyRound is the question the phones are shown
+type partyRound struct {
+	Number  int
+	Text    string
+	Options []string
+	Start   time.Time
+	Limit   time.Duration
+	Open    bool
+	Correct string // once closed
+}
+
+type partyAnswer struct {
+	choice string
+	after  time.Duration
+}
+
+// PartyServer lets phones on the local network play along: the kiosk shows
+// the question, each phone picks an answer from its browser before the
+// timer runs out, and scores are kept per phone. The game goroutine asks
+// and closes rounds; the HTTP handlers run on their own goroutines.
+// A nil *PartyServer is party mode switched off, and its methods do nothing.
+type PartyServer struct {
+	addr string
+
+	mu      sync.Mutex
+	players map[string]*PartyPlayer // by cookie token
+	round   partyRound
+	answers map[string]partyAnswer
+}
+
+func NewPartyServer(addr string) *PartyServer {
+	return &PartyServer{
+		addr:    addr,
+		players: make(map[string]*PartyPlayer),
+		answers: make(map[string]partyAnswer),
+	}
+}
+
+// Run serves the phone page until ctx is cancelled
+func (ps *PartyServer) Run(ctx context.Context) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", ps.handlePage)
+	mux.HandleFunc("POST /join", ps.handleJoin)
+	mux.HandleFunc("GET /state", ps.handleState)
+	mux.HandleFunc("POST /answer", ps.handleAnswer)
+	srv := &http.Server{Addr: ps.addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	log.Println("Party mode: phones can join at http://<this device>" + ps.addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Println("Party server stopped:", err)
+	}
+}
+
+// Players is how many phones have joined
+func (ps *PartyServer) Players() int {
+	if ps == nil {
+		return 0
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return len(ps.players)
+}
+
+// NewGame zeroes everyone's score, keeping the players
+func (ps *PartyServer) NewGame() {
+	if ps == nil {
+		return
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for _, p := range ps.players {
+		p.Score, p.Answered, p.Correct = 0, false, false
+	}
+	ps.round = partyRound{}
+}
+
+// Ask opens a round on the phones
+func (ps *PartyServer) Ask(number int, text string, options []string, limit time.Duration) {
+	if ps == nil {
+		return
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.round = partyRound{
+		Number:  number,
+		Text:    text,
+		Options: slices.Clone(options),
+		Start:   time.Now(),
+		Limit:   limit,
+		Open:    true,
+	}
+	clear(ps.answers)
+	for _, p := range ps.players {
+		p.Answered = false
+	}
+}
+
+// Close ends the round and scores every phone's answer like the kiosk
+// player's: a base for being right and a bonus for being quick
+func (ps *PartyServer) Close(correct string) {
+	if ps == nil {
+		return
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if !ps.round.Open {
+		return
+	}
+	ps.round.Open, ps.round.Correct = false, correct
+	for token, p := range ps.players {
+		a, ok := ps.answers[token]
+		p.Correct = ok && a.choice == correct
+		p.Score += RoundScore(p.Correct, a.after, ps.round.Limit)
+	}
+}
+
+// Progress is how many phones have answered the open round, out of all
+func (ps *PartyServer) Progress() (answered, players int) {
+	if ps == nil {
+		return 0, 0
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return len(ps.answers), len(ps.players)
+}
+
+// AllAnswered reports whether every phone has answered, so the round can
+// end early
+func (ps *PartyServer) AllAnswered() bool {
+	answered, players := ps.Progress()
+	return players > 0 && answered == players
+}
+
+// Standings is the scoreboard, highest first
+func (ps *PartyServer) Standings() []PartyStanding {
+	if ps == nil {
+		return nil
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	out := make([]PartyStanding, 0, len(ps.players))
+	for _, p := range ps.players {
+		out = append(out, PartyStanding{Name: p.Name, Score: p.Score})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// player finds the phone the request came from; callers hold mu
+func (ps *PartyServer) player(r *http.Request) (string, *PartyPlayer) {
+	c, err := r.Cookie(partyCookie)
+	if err != nil {
+		return "", nil
+	}
+	return c.Value, ps.players[c.Value]
+}
+
+func (ps *PartyServer) handlePage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(partyPage))
+}
+
+// handleJoin adds the phone as a player, or renames it if it has already
+// joined, so joining again can't take up more places
+func (ps *PartyServer) handleJoin(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.FormValue("name"))
+	if runes := []rune(name); len(runes) > partyMaxName {
+		// By letters, so ä and ö aren't cut in half
+		name = strings.TrimSpace(string(runes[:partyMaxName]))
+	}
+	if name == "" {
+		http.Error(w, "name required", http.StatusBadRequest)
+		return
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	token := hex.EncodeToString(b[:])
+
+	ps.mu.Lock()
+	_, known := ps.player(r)
+	full := known == nil && len(ps.players) >= partyMaxPlayers
+	switch {
+	case known != nil:
+		known.Name = name
+	case !full:
+		ps.players[token] = &PartyPlayer{Name: name}
+	}
+	ps.mu.Unlock()
+	if full {
+		http.Error(w, "party is full", http.StatusServiceUnavailable)
+		return
+	}
+	if known == nil {
+		http.SetCookie(w, &http.Cookie{Name: partyCookie, Value: token, Path: "/", HttpOnly: true, SameSite: http.SameSiteStrictMode})
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// partyState is what a phone polls for
+type partyState struct {
+	Joined    bool     `json:"joined"`
+	Name      string   `json:"name,omitempty"`
+	Score     int      `json:"score"`
+	Round     int      `json:"round"`
+	Question  string   `json:"question,omitempty"`
+	Options   []string `json:"options,omitempty"`
+	Open      bool     `json:"open"`
+	Remaining float64  `json:"remaining"` // seconds
+	Answered  bool     `json:"answered"`
+	Correct   bool     `json:"correct"`          // this phone got the last closed round right
+	Answer    string   `json:"answer,omitempty"` // the right answer, once closed
+}
+
+func (ps *PartyServer) handleState(w http.ResponseWriter, r *http.Request) {
+	ps.mu.Lock()
+	_, p := ps.player(r)
+	s := partyState{Round: ps.round.Number, Question: ps.round.Text, Options: ps.round.Options, Open: ps.round.Open}
+	if ps.round.Open {
+		s.Remaining = max(0, (ps.round.Limit - time.Since(ps.round.Start)).Seconds())
+	} else {
+		s.Answer = ps.round.Correct
+	}
+	if p != nil {
+		s.Joined, s.Name, s.Score, s.Answered, s.Correct = true, p.Name, p.Score, p.Answered, p.Correct
+	}
+	ps.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(s)
+}
+
+func (ps *PartyServer) handleAnswer(w http.ResponseWriter, r *http.Request) {
+	choice := r.FormValue("choice")
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	token, p := ps.player(r)
+	switch {
+	case p == nil:
+		http.Error(w, "join first", http.StatusForbidden)
+	case !ps.round.Open:
+		http.Error(w, "round is over", http.StatusConflict)
+	case p.Answered:
+		http.Error(w, "already answered", http.StatusConflict)
+	case !slices.Contains(ps.round.Options, choice):
+		http.Error(w, "not an option", http.StatusBadRequest)
+	case time.Since(ps.round.Start) > ps.round.Limit:
+		// The kiosk closes the round a moment after its timer runs out
+		http.Error(w, "time is up", http.StatusConflict)
+	default:
+		ps.answers[token] = partyAnswer{choice: choice, after: time.Since(ps.round.Start)}
+		p.Answered = true
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// partyPage is the whole phone client: join with a name, then poll the
+// state every second and show the options as big buttons
+const partyPage = `<!DOCTYPE html>
+<html><head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Flight Monitor party</title>
+<style>
+body { font-family: sans-serif; background: #0f172a; color: #f1f5f9; margin: 0; padding: 16px; }
+h1 { font-size: 20px; color: #38bdf8; }
+button, input { font-size: 20px; width: 100%; padding: 14px; margin: 6px 0; border: 0; border-radius: 8px; box-sizing: border-box; }
+button { background: #f1f5f9; color: #0f172a; }
+button:disabled { opacity: 0.4; }
+.muted { color: #94a3b8; }
+.right { color: #4ade80; } .wrong { color: #f87171; }
+</style>
+</head><body>
+<h1>Flight Monitor party</h1>
+<div id="join">
+<input id="name" maxlength="12" placeholder="Your name">
+<button onclick="join()">JOIN</button>
+</div>
+<div id="game" hidden>
+<p class="muted"><span id="who"></span> &middot; <span id="score"></span> points</p>
+<h2 id="question">Waiting for the first question...</h2>
+<p id="status" class="muted"></p>
+<div id="options"></div>
+</div>
+<script>
+let shown = "";
+async function join() {
+  const name = document.getElementById("name").value.trim();
+  if (!name) return;
+  const r = await fetch("join", {method: "POST", body: new URLSearchParams({name})});
+  if (!r.ok) { alert(await r.text()); return; }
+  poll();
+}
+async function answer(choice) {
+  await fetch("answer", {method: "POST", body: new URLSearchParams({choice})});
+  poll();
+}
+async function poll() {
+  const s = await (await fetch("state")).json();
+  document.getElementById("join").hidden = s.joined;
+  document.getElementById("game").hidden = !s.joined;
+  if (!s.joined) return;
+  document.getElementById("who").textContent = s.name;
+  document.getElementById("score").textContent = s.score;
+  if (s.round) document.getElementById("question").textContent = "Round " + s.round + ": " + s.question;
+  const status = document.getElementById("status");
+  status.className = "muted";
+  if (s.open && s.answered) status.textContent = "Answer in! Watch the big screen.";
+  else if (s.open) status.textContent = Math.ceil(s.remaining) + " s left";
+  else if (s.answer) { status.textContent = (s.correct ? "Right! " : "Wrong. ") + "It was " + s.answer; status.className = s.correct ? "right" : "wrong"; }
+  const key = s.round + "/" + s.open + "/" + s.answered;
+  if (key === shown) return;
+  shown = key;
+  const opts = document.getElementById("options");
+  opts.textContent = "";
+  for (const o of (s.open ? s.options : [])) {
+    const b = document.createElement("button");
+    b.textContent = o;
+    b.disabled = s.answered;
+    b.onclick = () => answer(o);
+    opts.appendChild(b);
+  }
+}
+setInterval(poll, 1000);
+poll();
+</script>
+</body></html>
+`
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// partyPost sends a form to ps as the phone holding cookie, returning the
+// response and the phone's cookie afterwards
+func partyPost(ps *PartyServer, path string, form url.Values, cookie *http.Cookie) (*httptest.ResponseRecorder, *http.Cookie) {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	if path == "/join" {
+		ps.handleJoin(w, r)
+	} else {
+		ps.handleAnswer(w, r)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == partyCookie {
+			cookie = c
+		}
+	}
+	return w, cookie
+}
+
+func TestPartyJoin(t *testing.T) {
+	ps := NewPartyServer("")
+
+	w, phone := partyPost(ps, "/join", url.Values{"name": {"Ääliö Örkkiläinen"}}, nil)
+	if w.Code != http.StatusNoContent || phone == nil {
+		t.Fatalf("join = %d %q", w.Code, w.Body)
+	}
+	if got := ps.Standings()[0].Name; got != "Ääliö Örkkil" {
+		t.Errorf("name = %q, want the first 12 letters whole", got)
+	}
+
+	// Joining again from the same phone renames it rather than adding one
+	for range partyMaxPlayers + 1 {
+		if w, again := partyPost(ps, "/join", url.Values{"name": {"Matti"}}, phone); w.Code != http.StatusNoContent || again.Value != phone.Value {
+			t.Fatalf("rejoin = %d, cookie %v", w.Code, again)
+		}
+	}
+	if n := ps.Players(); n != 1 || ps.Standings()[0].Name != "Matti" {
+		t.Errorf("%d players %+v after rejoining, want Matti alone", n, ps.Standings())
+	}
+
+	for i := 1; i < partyMaxPlayers; i++ {
+		partyPost(ps, "/join", url.Values{"name": {"phone"}}, nil)
+	}
+	if w, _ := partyPost(ps, "/join", url.Values{"name": {"late"}}, nil); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("joining a full party = %d", w.Code)
+	}
+	if w, _ := partyPost(ps, "/join", url.Values{"name": {"Maija"}}, phone); w.Code != http.StatusNoContent {
+		t.Errorf("renaming in a full party = %d", w.Code)
+	}
+}
+
+func TestPartyAnswerDeadline(t *testing.T) {
+	ps := NewPartyServer("")
+	_, early := partyPost(ps, "/join", url.Values{"name": {"early"}}, nil)
+	_, late := partyPost(ps, "/join", url.Values{"name": {"late"}}, nil)
+
+	ps.Ask(1, "Where is it flying to?", []string{"Oslo", "Riga"}, 10*time.Second)
+	if w, _ := partyPost(ps, "/answer", url.Values{"choice": {"Oslo"}}, early); w.Code != http.StatusNoContent {
+		t.Fatalf("answer in time = %d %q", w.Code, w.Body)
+	}
+	ps.mu.Lock()
+	ps.round.Start = time.Now().Add(-11 * time.Second)
+	ps.mu.Unlock()
+	if w, _ := partyPost(ps, "/answer", url.Values{"choice": {"Oslo"}}, late); w.Code != http.StatusConflict {
+		t.Errorf("answer after the time limit = %d, want refused", w.Code)
+	}
+
+	ps.Close("Oslo")
+	for _, s := range ps.Standings() {
+		if (s.Name == "early") != (s.Score > 0) {
+			t.Errorf("%s scored %d", s.Name, s.Score)
+		}
+	}
+}
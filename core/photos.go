@@ -0,0 +1,241 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg" // planespotters thumbnails
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	planespottersHexURL = "https://api.planespotters.net/pub/photos/hex/%s"
+	planespottersRegURL = "https://api.planespotters.net/pub/photos/reg/%s"
+
+	photosDir = "photos" // <icao24>.jpg and <icao24>.json per aircraft inside the data dir
+
+	// Photos on disk are looked up again after this, in case a better one
+	// has been uploaded
+	photoMaxAge = 30 * 24 * time.Hour
+	// Aircraft without a photo aren't asked about again for this long
+	photoMissingRetry = 6 * time.Hour
+	// Decoded photos kept in memory; the rest are reloaded from disk
+	maxPhotosInMemory = 32
+)
+
+// AircraftPhoto is a thumbnail of an airframe from planespotters.net. Their
+// terms ask for the photographer to be credited wherever it is shown.
+type AircraftPhoto struct {
+	Image        image.Image `json:"-"`
+	Photographer string      `json:"photographer"`
+	Link         string      `json:"link"` // the photo's page on planespotters.net
+}
+
+// Credit is the attribution line to draw under the photo
+func (p *AircraftPhoto) Credit() string {
+	return "(c) " + p.Photographer + " / planespotters.net"
+}
+
+// PhotoCache fetches aircraft thumbnails in the background and keeps them
+// in memory and on disk. The UI asks every frame; the first ask starts the
+// fetch and later ones get the photo once it is in.
+type PhotoCache struct {
+	ctx    context.Context
+	client *http.Client
+	store  Storage
+
+	mu      sync.Mutex
+	photos  map[string]*AircraftPhoto // by icao24
+	pending map[string]bool
+	missing map[string]time.Time // when planespotters last had nothing
+}
+
+// NewPhotoCache saves the photos in dm's storage
+func NewPhotoCache(ctx context.Context, dm *DataManager) *PhotoCache {
+	return &PhotoCache{
+		ctx:     ctx,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		store:   dm.storage(),
+		photos:  make(map[string]*AircraftPhoto),
+		pending: make(map[string]bool),
+		missing: make(map[string]time.Time),
+	}
+}
+
+// Photo returns f's photo if it has been loaded, starting a fetch if not.
+// It is nil until then, and for aircraft planespotters has no photo of.
+func (pc *PhotoCache) Photo(f Flight) *AircraftPhoto {
+	key := strings.ToLower(f.Icao24)
+	if key == "" {
+		return nil
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if p, ok := pc.photos[key]; ok {
+		return p
+	}
+	if pc.pending[key] || time.Since(pc.missing[key]) < photoMissingRetry || pc.ctx.Err() != nil {
+		return nil
+	}
+	pc.pending[key] = true
+	go pc.load(key, f.Registration)
+	return nil
+}
+
+// Len is the number of photos held in memory
+func (pc *PhotoCache) Len() int {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return len(pc.photos)
+}
+
+// load reads the photo from disk or fetches it, and files the result
+func (pc *PhotoCache) load(key, registration string) {
+	p, err := pc.readDisk(key)
+	if err != nil {
+		p, err = pc.fetch(key, registration)
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	delete(pc.pending, key)
+	if err != nil {
+		if err != ErrRouteUnknown && pc.ctx.Err() == nil {
+			log.Printf("Photo for %s: %v", key, err)
+		}
+		pc.missing[key] = time.Now()
+		return
+	}
+	for k := range pc.photos {
+		if len(pc.photos) < maxPhotosInMemory {
+			break
+		}
+		delete(pc.photos, k) // any will do; it is on disk
+	}
+	pc.photos[key] = p
+}
+
+// readDisk loads a photo saved within photoMaxAge
+func (pc *PhotoCache) readDisk(key string) (*AircraftPhoto, error) {
+	base := path.Join(photosDir, key)
+	info, err := pc.store.Stat(base + ".jpg")
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(info.ModTime) > photoMaxAge {
+		return nil, fmt.Errorf("photo out of date")
+	}
+	meta, err := pc.store.ReadFile(base + ".json")
+	if err != nil {
+		return nil, err
+	}
+	var p AircraftPhoto
+	if err := json.Unmarshal(meta, &p); err != nil {
+		return nil, err
+	}
+	file, err := pc.store.Open(base + ".jpg")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	p.Image, _, err = image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// fetch asks planespotters for the aircraft by address, then by
+// registration, downloads the thumbnail and saves it
+func (pc *PhotoCache) fetch(key, registration string) (*AircraftPhoto, error) {
+	thumb, p, err := pc.lookup(fmt.Sprintf(planespottersHexURL, url.PathEscape(key)))
+	if err == ErrRouteUnknown && registration != "" {
+		thumb, p, err = pc.lookup(fmt.Sprintf(planespottersRegURL, url.PathEscape(registration)))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := pc.get(thumb)
+	if err != nil {
+		return nil, fmt.Errorf("thumbnail: %w", err)
+	}
+	p.Image, _, err = image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("thumbnail: %w", err)
+	}
+
+	// A photo that can't be saved is still worth showing this time
+	if err := pc.save(key, data, p); err != nil {
+		log.Println("Error saving photo:", err)
+	}
+	return p, nil
+}
+
+// lookup returns the thumbnail URL and credit of the first photo the API
+// call lists, or ErrRouteUnknown if it lists none
+func (pc *PhotoCache) lookup(apiURL string) (string, *AircraftPhoto, error) {
+	data, err := pc.get(apiURL)
+	if err != nil {
+		return "", nil, err
+	}
+	var body struct {
+		Photos []struct {
+			Thumbnail struct {
+				Src string `json:"src"`
+			} `json:"thumbnail"`
+			Link         string `json:"link"`
+			Photographer string `json:"photographer"`
+		} `json:"photos"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return "", nil, err
+	}
+	if len(body.Photos) == 0 || body.Photos[0].Thumbnail.Src == "" {
+		return "", nil, ErrRouteUnknown
+	}
+	ph := body.Photos[0]
+	return ph.Thumbnail.Src, &AircraftPhoto{Photographer: ph.Photographer, Link: ph.Link}, nil
+}
+
+func (pc *PhotoCache) get(u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(pc.ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	// planespotters asks API users to say who they are
+	req.Header.Set("User-Agent", UserAgent())
+	resp, err := pc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrRouteUnknown
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+}
+
+func (pc *PhotoCache) save(key string, jpeg []byte, p *AircraftPhoto) error {
+	meta, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	base := path.Join(photosDir, key)
+	if err := pc.store.WriteFile(base+".json", meta); err != nil {
+		return err
+	}
+	return pc.store.WriteFile(base+".jpg", jpeg)
+}
@@ -0,0 +1,173 @@
+package core
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Below this many flights splitting the per-flight work isn't worth the goroutines
+const pipelineChunkSize = 64
+
+// FlightSnapshot is the processed result of one poll. It is never modified
+// after being published, so the UI can read it without locking.
+type FlightSnapshot struct {
+	Flights    []Flight  // sorted by distance from home, nearest first
+	DistanceKm []float64 // parallel to Flights
+	FetchedAt  time.Time
+
+	index *FlightIndex // over Flights; nil in the empty first snapshot
+}
+
+// Within returns the flights within radiusKm of lat, lon, nearest home first
+func (s *FlightSnapshot) Within(lat, lon, radiusKm float64) []*Flight {
+	if s.index == nil {
+		return nil
+	}
+	idx := s.index.Within(lat, lon, radiusKm)
+	out := make([]*Flight, len(idx))
+	for i, j := range idx {
+		out[i] = &s.Flights[j]
+	}
+	return out
+}
+
+// Closest returns the nearest airborne flight, or nil
+func (s *FlightSnapshot) Closest() *Flight {
+	for i := range s.Flights {
+		if !s.Flights[i].OnGround {
+			return &s.Flights[i]
+		}
+	}
+	return nil
+}
+
+// Find returns the flight with the given icao24, or nil
+func (s *FlightSnapshot) Find(icao24 string) *Flight {
+	for i := range s.Flights {
+		if s.Flights[i].Icao24 == icao24 {
+			return &s.Flights[i]
+		}
+	}
+	return nil
+}
+
+// PipelineStage consumes each published snapshot on the pipeline goroutine,
+// e.g. to append tracks. Stages must not modify the snapshot.
+type PipelineStage func(s *FlightSnapshot)
+
+// FlightPipeline processes polled flight batches off the render thread and
+// publishes an immutable snapshot for the frontends to draw
+type FlightPipeline struct {
+	homeLat, homeLon float64
+	workers          int
+	stages           []PipelineStage
+
+	pending chan batch
+	current atomic.Pointer[FlightSnapshot]
+}
+
+type batch struct {
+	flights []Flight
+	at      time.Time
+}
+
+func NewFlightPipeline(homeLat, homeLon float64) *FlightPipeline {
+	p := &FlightPipeline{
+		homeLat: homeLat,
+		homeLon: homeLon,
+		workers: runtime.NumCPU(),
+		pending: make(chan batch, 1),
+	}
+	p.current.Store(&FlightSnapshot{})
+	return p
+}
+
+// AddStage registers a consumer; call before Run
+func (p *FlightPipeline) AddStage(s PipelineStage) {
+	p.stages = append(p.stages, s)
+}
+
+// Submit queues a batch without blocking. If the previous batch hasn't been
+// picked up yet it is replaced, since only the newest positions matter.
+func (p *FlightPipeline) Submit(flights []Flight, at time.Time) {
+	b := batch{flights: flights, at: at}
+	for {
+		select {
+		case p.pending <- b:
+			return
+		default:
+		}
+		select {
+		case <-p.pending:
+		default:
+		}
+	}
+}
+
+// Snapshot returns the latest published snapshot (never nil)
+func (p *FlightPipeline) Snapshot() *FlightSnapshot {
+	return p.current.Load()
+}
+
+// Run processes submitted batches until ctx is cancelled. A batch already
+// being processed is finished, so stages never see a half-written snapshot.
+func (p *FlightPipeline) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case b := <-p.pending:
+			s := p.process(b)
+			p.current.Store(s)
+			for _, stage := range p.stages {
+				stage(s)
+			}
+		}
+	}
+}
+
+func (p *FlightPipeline) process(b batch) *FlightSnapshot {
+	// Copy so the provider's cached slice is never shared with the UI
+	flights := append([]Flight(nil), b.flights...)
+	dist := make([]float64, len(flights))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, p.workers)
+	for start := 0; start < len(flights); start += pipelineChunkSize {
+		end := min(start+pipelineChunkSize, len(flights))
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(start, end int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			for i := start; i < end; i++ {
+				dist[i] = Distance(p.homeLat, p.homeLon, flights[i].Lat, flights[i].Lon)
+			}
+		}(start, end)
+	}
+	wg.Wait()
+
+	order := make([]int, len(flights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return dist[order[a]] < dist[order[b]] })
+
+	s := &FlightSnapshot{
+		Flights:    make([]Flight, len(flights)),
+		DistanceKm: make([]float64, len(flights)),
+		FetchedAt:  b.at,
+	}
+	ptrs := make([]*Flight, len(flights))
+	for i, j := range order {
+		s.Flights[i] = flights[j]
+		s.DistanceKm[i] = dist[j]
+		ptrs[i] = &s.Flights[i]
+	}
+	s.index = NewFlightIndex(ptrs)
+	return s
+}
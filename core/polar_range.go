@@ -0,0 +1,105 @@
+package core
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	polarRangeFile = "polar_range.json"
+
+	PolarSectors     = 72 // 5 degrees each
+	polarSaveEvery   = time.Minute
+	polarMaxRangeKm  = 600 // ignore bogus positions beyond ADS-B line-of-sight
+	polarSectorWidth = 360.0 / PolarSectors
+)
+
+// PolarRange tracks the furthest aircraft seen from home in each bearing
+// sector, the usual way receiver operators judge antenna coverage
+type PolarRange struct {
+	homeLat, homeLon float64
+
+	mu       sync.Mutex
+	maxKm    []float64
+	dirty    bool
+	lastSave time.Time
+}
+
+func NewPolarRange(homeLat, homeLon float64) *PolarRange {
+	return &PolarRange{
+		homeLat: homeLat,
+		homeLon: homeLon,
+		maxKm:   make([]float64, PolarSectors),
+	}
+}
+
+// Add updates the sectors with the positions from one poll
+func (pr *PolarRange) Add(flights []Flight) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	for _, f := range flights {
+		d := Distance(pr.homeLat, pr.homeLon, f.Lat, f.Lon)
+		if d > polarMaxRangeKm {
+			continue
+		}
+		sector := int(Bearing(pr.homeLat, pr.homeLon, f.Lat, f.Lon)/polarSectorWidth) % PolarSectors
+		if d > pr.maxKm[sector] {
+			pr.maxKm[sector] = d
+			pr.dirty = true
+		}
+	}
+}
+
+// Sectors returns the max range in km for each sector, starting at north and going clockwise
+func (pr *PolarRange) Sectors() []float64 {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	return append([]float64(nil), pr.maxKm...)
+}
+
+// SectorBearing is the bearing through the middle of sector i
+func SectorBearing(i int) float64 {
+	return (float64(i) + 0.5) * polarSectorWidth
+}
+
+// Load restores previously accumulated ranges
+func (pr *PolarRange) Load(dm *DataManager) error {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	var saved []float64
+	if err := dm.readDocument(polarRangeFile, &saved); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	for i := 0; i < len(saved) && i < PolarSectors; i++ {
+		if saved[i] > pr.maxKm[i] {
+			pr.maxKm[i] = saved[i]
+		}
+	}
+	return nil
+}
+
+// SaveIfDirty persists the ranges at most once a minute when they have grown
+func (pr *PolarRange) SaveIfDirty(dm *DataManager) error {
+	pr.mu.Lock()
+	if !pr.dirty || time.Since(pr.lastSave) < polarSaveEvery {
+		pr.mu.Unlock()
+		return nil
+	}
+	sectors := append([]float64(nil), pr.maxKm...)
+	pr.dirty = false
+	pr.lastSave = time.Now()
+	pr.mu.Unlock()
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	return dm.writeDocument(polarRangeFile, sectors)
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// How often the power source is looked at again
+	powerCheckInterval = time.Minute
+	// Polls are this much further apart while saving energy, and never
+	// closer than ecoMinPoll
+	ecoPollFactor = 3
+	ecoMinPoll    = 30 * time.Second
+)
+
+// EcoMode is the player's choice about saving energy: by default only on
+// battery, or always, or never
+type EcoMode string
+
+const (
+	EcoAuto   EcoMode = ""
+	EcoAlways EcoMode = "always"
+	EcoNever  EcoMode = "never"
+)
+
+// Toggle is the mode that flips whether energy is being saved now, for the
+// on-screen eco switch: on battery that is Never or back to Auto, on mains
+// Always or back to Auto
+func (m EcoMode) Toggle(onBattery bool) EcoMode {
+	if m.Active(onBattery) {
+		if onBattery {
+			return EcoNever
+		}
+		return EcoAuto
+	}
+	if onBattery {
+		return EcoAuto
+	}
+	return EcoAlways
+}
+
+// Next steps through the modes for the settings screen
+func (m EcoMode) Next() EcoMode {
+	switch m {
+	case EcoAuto:
+		return EcoAlways
+	case EcoAlways:
+		return EcoNever
+	}
+	return EcoAuto
+}
+
+// Active reports whether the mode saves energy now
+func (m EcoMode) Active(onBattery bool) bool {
+	switch m {
+	case EcoAlways:
+		return true
+	case EcoNever:
+		return false
+	}
+	return onBattery
+}
+
+// Label describes the mode for the settings screen
+func (m EcoMode) Label(onBattery bool) string {
+	switch m {
+	case EcoAlways:
+		return "ALWAYS"
+	case EcoNever:
+		return "NEVER"
+	}
+	if onBattery {
+		return "ON BATTERY (NOW)"
+	}
+	return "ON BATTERY"
+}
+
+// EcoPollInterval stretches a poll interval for saving energy
+func EcoPollInterval(interval time.Duration) time.Duration {
+	return max(interval*ecoPollFactor, ecoMinPoll)
+}
+
+// PowerMonitor keeps track of whether the machine is running on battery,
+// so a laptop or tablet can poll less, skip prefetching and draw fewer
+// frames until it is plugged in. Machines without a battery, or where the
+// platform can't tell, count as on mains. A nil *PowerMonitor is always on
+// mains.
+type PowerMonitor struct {
+	mu        sync.Mutex
+	onBattery bool
+}
+
+// NewPowerMonitor reads the power source once, so the first frames already
+// know it
+func NewPowerMonitor() *PowerMonitor {
+	p := &PowerMonitor{}
+	p.check()
+	return p
+}
+
+// Run looks at the power source again every minute until ctx is cancelled
+func (p *PowerMonitor) Run(ctx context.Context) {
+	t := time.NewTicker(powerCheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			p.check()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *PowerMonitor) check() {
+	on, err := onBattery()
+	if err != nil {
+		on = false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if on != p.onBattery {
+		log.Println("Power: on battery:", on)
+	}
+	p.onBattery = on
+}
+
+// OnBattery reports whether the machine was on battery when last checked
+func (p *PowerMonitor) OnBattery() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.onBattery
+}
+
+// Eco reports whether to save energy now under mode
+func (p *PowerMonitor) Eco(mode EcoMode) bool {
+	return mode.Active(p.OnBattery())
+}
@@ -0,0 +1,50 @@
+//go:build !windows
+
+package core
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// onBattery asks the platform whether the machine runs on battery: the
+// kernel's power supply class on Linux, pmset on macOS
+func onBattery() (bool, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return linuxOnBattery("/sys/class/power_supply")
+	case "darwin":
+		out, err := exec.Command("pmset", "-g", "batt").Output()
+		if err != nil {
+			return false, err
+		}
+		return strings.Contains(string(out), "'Battery Power'"), nil
+	}
+	return false, nil
+}
+
+// linuxOnBattery reports whether any battery under dir is discharging.
+// Charging or full batteries mean mains power, and a Pi on a USB supply
+// has no battery at all.
+func linuxOnBattery(dir string) (bool, error) {
+	supplies, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	read := func(supply, name string) string {
+		b, _ := os.ReadFile(filepath.Join(dir, supply, name))
+		return strings.TrimSpace(string(b))
+	}
+	for _, s := range supplies {
+		if read(s.Name(), "type") == "Battery" && read(s.Name(), "status") == "Discharging" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
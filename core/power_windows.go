@@ -0,0 +1,27 @@
+package core
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// systemPowerStatus is SYSTEM_POWER_STATUS from the Windows API
+type systemPowerStatus struct {
+	ACLineStatus        byte
+	BatteryFlag         byte
+	BatteryLifePercent  byte
+	SystemStatusFlag    byte
+	BatteryLifeTime     uint32
+	BatteryFullLifeTime uint32
+}
+
+var getSystemPowerStatus = syscall.NewLazyDLL("kernel32.dll").NewProc("GetSystemPowerStatus")
+
+// onBattery asks Windows whether the machine is off its AC adapter
+func onBattery() (bool, error) {
+	var s systemPowerStatus
+	if r, _, err := getSystemPowerStatus.Call(uintptr(unsafe.Pointer(&s))); r == 0 {
+		return false, err
+	}
+	return s.ACLineStatus == 0, nil
+}
@@ -0,0 +1,151 @@
+package core
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPrefetchPerMin = 20
+	// A flight none of the route APIs knew isn't asked about again for this
+	// long
+	prefetchRetry = 15 * time.Minute
+	// Lookups taking longer are given up; the flight will be asked about
+	// again when it is tapped
+	prefetchTimeout = 10 * time.Second
+	// Only this many flights queue at once; the rest wait for the next pass
+	prefetchQueueLen = 32
+)
+
+// Prefetcher looks up the routes of the flights on screen in the
+// background, a few a minute, so tapping one or starting a round on it
+// finds the details already in the route cache. It asks only the route
+// APIs: FlightAware's scrape budget is kept for the flights actually tapped.
+type Prefetcher struct {
+	cache    *RouteCache
+	learned  *LearnedRoutes
+	resolver DetailsResolver
+	interval time.Duration
+
+	mu    sync.Mutex
+	queue []string             // callsigns, most wanted first
+	tried map[string]time.Time // callsign -> when it was last looked up
+}
+
+// NewPrefetcher prefetches into cache and learned, PREFETCH_PER_MIN lookups
+// a minute. It returns nil when PREFETCH_PER_MIN is "off".
+func NewPrefetcher(cache *RouteCache, learned *LearnedRoutes) *Prefetcher {
+	perMin := defaultPrefetchPerMin
+	v := strings.TrimSpace(os.Getenv("PREFETCH_PER_MIN"))
+	if strings.EqualFold(v, "off") {
+		return nil
+	}
+	if n, err := strconv.Atoi(v); err == nil && n > 0 {
+		perMin = n
+	}
+	return &Prefetcher{
+		cache:   cache,
+		learned: learned,
+		resolver: NewResolverChain(cache).Learn(learned).
+			Add("OpenSky routes", NewRouteResolver()).
+			Add("adsbdb", NewAdsbdbResolver()).
+			Add("hexdb", NewHexdbResolver()),
+		interval: time.Minute / time.Duration(perMin),
+		tried:    make(map[string]time.Time),
+	}
+}
+
+// Want replaces the queue with flights, most wanted first, leaving out
+// those already cached, learned or recently tried. Flights without a
+// callsign are skipped: answers by address aren't cached.
+func (p *Prefetcher) Want(flights []Flight) {
+	if p == nil {
+		return
+	}
+	now := time.Now()
+	var queue []string
+	for _, f := range flights {
+		if len(queue) >= prefetchQueueLen {
+			break
+		}
+		if !f.HasCallsign() {
+			continue
+		}
+		if _, ok := p.cache.Get(f.Callsign, now); ok || p.learned.Knows(f.Callsign, now) {
+			continue
+		}
+		queue = append(queue, f.Callsign)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queue = queue[:0]
+	for _, cs := range queue {
+		if now.Sub(p.tried[cs]) >= prefetchRetry {
+			p.queue = append(p.queue, cs)
+		}
+	}
+}
+
+// Cached is f's details when they are in the route cache, or its learned
+// route, so a selection can show them without waiting for a lookup
+func (p *Prefetcher) Cached(f Flight) (*ResolvedDetails, bool) {
+	if p == nil || !f.HasCallsign() {
+		return nil, false
+	}
+	now := time.Now()
+	d, ok := p.cache.Get(f.Callsign, now)
+	if !ok {
+		d, ok = p.learned.Lookup(f.Callsign, now)
+	}
+	if !ok {
+		return nil, false
+	}
+	nameAirline(d, f.Callsign)
+	return d.WithAirframe(f), true
+}
+
+// next takes the most wanted callsign off the queue
+func (p *Prefetcher) next(now time.Time) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.queue) > 0 {
+		cs := p.queue[0]
+		p.queue = p.queue[1:]
+		if now.Sub(p.tried[cs]) < prefetchRetry {
+			continue
+		}
+		p.tried[cs] = now
+		for c, at := range p.tried {
+			if now.Sub(at) >= prefetchRetry {
+				delete(p.tried, c)
+			}
+		}
+		return cs, true
+	}
+	return "", false
+}
+
+// Run looks up one queued flight per interval until ctx is cancelled
+func (p *Prefetcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		cs, ok := p.next(time.Now())
+		if !ok {
+			continue
+		}
+		lookupCtx, cancel := context.WithTimeout(ctx, prefetchTimeout)
+		p.resolver.FetchFlightDetails(lookupCtx, cs) // the chain caches what it finds
+		cancel()
+	}
+}
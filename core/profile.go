@@ -0,0 +1,42 @@
+package core
+
+import "time"
+
+// ResourceProfile sets how much memory and CPU the frontends spend on
+// extras, so the app stays usable on the weakest kiosk hardware
+type ResourceProfile struct {
+	Name           string
+	MaxTiles       int           // map tiles kept in memory, least recently drawn dropped first
+	TileResolution int           // pixels per side tiles are stored at, scaled up to TileSize when drawn
+	TileFetches    int           // tile downloads running at once
+	Trails         bool          // draw the selected flight's track
+	History        bool          // record the day's tracks for time-lapses, heatmaps and the daily GIF
+	Particles      bool          // confetti, sparks and alert pulses
+	MinPoll        time.Duration // floor under the poll interval
+}
+
+var (
+	StandardProfile = ResourceProfile{
+		Name:           "standard",
+		MaxTiles:       512,
+		TileResolution: TileSize,
+		TileFetches:    6,
+		Trails:         true,
+		History:        true,
+		Particles:      true,
+	}
+	// LowMemoryProfile is for Raspberry Pi Zero class devices: about 3 MB of
+	// tiles instead of up to 128, nothing recorded and a slower poll
+	LowMemoryProfile = ResourceProfile{
+		Name:           "lowmem",
+		MaxTiles:       48,
+		TileResolution: TileSize / 2,
+		TileFetches:    2,
+		MinPoll:        15 * time.Second,
+	}
+)
+
+// PollInterval raises interval to the profile's floor
+func (rp ResourceProfile) PollInterval(interval time.Duration) time.Duration {
+	return max(interval, rp.MinPoll)
+}
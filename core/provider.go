@@ -0,0 +1,184 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultPollInterval is how often the frontends poll a provider that doesn't say otherwise
+const defaultPollInterval = 5 * time.Second
+
+// RateLimitInfo describes how much a provider can still be polled
+type RateLimitInfo struct {
+	Remaining      int       // request credits left, -1 if the source doesn't report it
+	ThrottledUntil time.Time // zero unless the source has told us to back off
+	ResetAt        time.Time // when Remaining refills, zero if unknown
+}
+
+// FlightProvider is a source of live flight positions around a point
+type FlightProvider interface {
+	Name() string
+	// FetchFlights returns the flights within radiusDeg of the point. It
+	// gives up early when ctx is cancelled.
+	FetchFlights(ctx context.Context, centerLat, centerLon, radiusDeg float64) ([]Flight, error)
+	RateLimitInfo() RateLimitInfo
+}
+
+// providerFactories maps the -provider flag value to a constructor
+var providerFactories = map[string]func() (FlightProvider, error){
+	"opensky": func() (FlightProvider, error) { return NewFlightClient(), nil },
+	"adsblol": func() (FlightProvider, error) { return NewAdsbLolClient(), nil },
+	// ADS-B Exchange via RapidAPI, needs ADSBX_API_KEY
+	"adsbx": func() (FlightProvider, error) { return NewAdsbxClient() },
+	// OpenSky first, falling back to adsb.lol when it is throttled or down
+	"auto": func() (FlightProvider, error) {
+		return NewFailoverProvider(NewFlightClient(), NewAdsbLolClient()), nil
+	},
+	// Own dump1090/readsb receiver via RECEIVER_SBS or RECEIVER_URL
+	"local": func() (FlightProvider, error) { return NewLocalReceiverProvider() },
+	// Invented traffic around home for offline development and demos
+	"sim": func() (FlightProvider, error) { return NewSimulatedProvider(), nil },
+	// Own receiver and OpenSky (failing over to adsb.lol) merged by icao24
+	"merge": func() (FlightProvider, error) {
+		local, err := NewLocalReceiverProvider()
+		if err != nil {
+			return nil, err
+		}
+		return NewMergeProvider(local, NewFailoverProvider(NewFlightClient(), NewAdsbLolClient())), nil
+	},
+}
+
+// innerProviders lists the providers a combining provider wraps
+func innerProviders(p FlightProvider) []FlightProvider {
+	switch p := p.(type) {
+	case *FailoverProvider:
+		return p.providers
+	case *MergeProvider:
+		return p.providers
+	}
+	return nil
+}
+
+// RunProvider runs the background work of p and the providers it wraps,
+// such as keeping a receiver's feed connected, until ctx is cancelled. The
+// frontends start it with the other background workers.
+func RunProvider(ctx context.Context, p FlightProvider) {
+	var wg sync.WaitGroup
+	for _, inner := range innerProviders(p) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			RunProvider(ctx, inner)
+		}()
+	}
+	if r, ok := p.(interface{ Run(context.Context) }); ok {
+		r.Run(ctx)
+	}
+	wg.Wait()
+}
+
+// NewProvider builds the named provider
+func NewProvider(name string) (FlightProvider, error) {
+	factory, ok := providerFactories[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q (available: %s)", name, strings.Join(ProviderNames(), ", "))
+	}
+	return factory()
+}
+
+// ProviderNames lists the registered provider names
+func ProviderNames() []string {
+	names := make([]string, 0, len(providerFactories))
+	for name := range providerFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fetchCache remembers recent results per query box, so polling several
+// regions (or changing the radius) never serves one box's flights for another.
+// Callers guard it with their own mutex.
+type fetchCache struct {
+	entries map[string]cachedFetch
+}
+
+type cachedFetch struct {
+	flights []Flight
+	at      time.Time
+}
+
+func boxKey(centerLat, centerLon, radiusDeg float64) string {
+	return fmt.Sprintf("%.4f,%.4f,%.4f", centerLat, centerLon, radiusDeg)
+}
+
+// get returns the cached flights for key if younger than maxAge
+func (c *fetchCache) get(key string, maxAge time.Duration) ([]Flight, bool) {
+	e, ok := c.entries[key]
+	if !ok || Elapsed(e.at, time.Now()) >= maxAge || len(e.flights) == 0 {
+		return nil, false
+	}
+	return e.flights, true
+}
+
+func (c *fetchCache) put(key string, flights []Flight) {
+	if c.entries == nil {
+		c.entries = make(map[string]cachedFetch)
+	}
+	now := time.Now()
+	// Drop stale boxes so switching radius repeatedly doesn't grow the map
+	for k, e := range c.entries {
+		if now.Sub(e.at) > time.Hour {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cachedFetch{flights: flights, at: now}
+}
+
+// PollInterval returns how often a provider should be polled; providers can
+// override the default by implementing PollInterval() time.Duration
+func PollInterval(p FlightProvider) time.Duration {
+	if pi, ok := p.(interface{ PollInterval() time.Duration }); ok {
+		return pi.PollInterval()
+	}
+	return defaultPollInterval
+}
+
+const (
+	// Credits kept back so a long game doesn't drain the budget to zero
+	creditReserve = 20
+	// However low the budget, poll at least this often so the map still moves
+	// (a throttled provider is skipped by the failover anyway)
+	maxBudgetInterval = 5 * time.Minute
+)
+
+// RequestCost is how many credits one fetch of a box costs; providers that
+// charge by area implement RequestCost(radiusDeg float64) int
+func RequestCost(p FlightProvider, radiusDeg float64) int {
+	if rc, ok := p.(interface{ RequestCost(float64) int }); ok {
+		return rc.RequestCost(radiusDeg)
+	}
+	return 1
+}
+
+// BudgetedInterval stretches base so that polls costing credits each last
+// until the provider's budget refills. It returns base when the provider
+// doesn't report a budget or has plenty left.
+func BudgetedInterval(base time.Duration, info RateLimitInfo, credits int, now time.Time) time.Duration {
+	if info.Remaining < 0 || info.ResetAt.IsZero() || credits <= 0 {
+		return base
+	}
+	untilReset := info.ResetAt.Sub(now)
+	if untilReset <= 0 {
+		return base
+	}
+	polls := (info.Remaining - creditReserve) / credits
+	if polls <= 0 {
+		return max(base, maxBudgetInterval)
+	}
+	return max(base, min(untilReset/time.Duration(polls), maxBudgetInterval))
+}
@@ -0,0 +1,181 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"time"
+)
+
+const (
+	RoundsPerGame = 5
+
+	// Chance that a round asks for the aircraft type when the model is recognised
+	TypeRoundChance = 1.0 / 3
+	// Chance that a round asks for the airline when the callsign names one
+	AirlineRoundChance = 1.0 / 5
+
+	roundBasePoints = 100
+	roundMaxBonus   = 100 // for an instant answer, falling to 0 when the timer runs out
+)
+
+// Used to pad the options when too few airports have been seen yet
+var fallbackAirports = []string{"London", "Paris", "Berlin", "Helsinki", "Tokyo", "New York"}
+
+// ErrUnusableDetails means the scraped details can't make a fair question
+var ErrUnusableDetails = errors.New("flight details too incomplete for a question")
+
+// RoundKind is what a round asks about
+type RoundKind int
+
+const (
+	RoundRoute   RoundKind = iota // where the flight is going or coming from
+	RoundType                     // the aircraft type
+	RoundAirline                  // the airline flying it
+)
+
+// PickRoundKind draws what the next round asks about. Without variety
+// every round asks for the route.
+func PickRoundKind(variety bool) RoundKind {
+	if !variety {
+		return RoundRoute
+	}
+	switch r := rand.Float64(); {
+	case r < TypeRoundChance:
+		return RoundType
+	case r < TypeRoundChance+AirlineRoundChance:
+		return RoundAirline
+	}
+	return RoundRoute
+}
+
+// Question is one quiz round ready to be shown
+type Question struct {
+	Text    string
+	Correct string
+	Options []string // shuffled, includes Correct
+	Hint    string   // masked model string; set only for type rounds
+	// Strategy names the distractor strategy that picked the wrong
+	// answers; set only for route rounds
+	Strategy string
+}
+
+// IsTypeRound reports whether the question asks for the aircraft type
+func (q Question) IsTypeRound() bool {
+	return q.Hint != ""
+}
+
+// BuildQuestion turns scraped details for the flight with callsign into a
+// round of the given kind, falling back to the route when the details can't
+// answer it. When the route is unknown it asks for the aircraft type when
+// the model is recognised, or else the airline. Distractors come from
+// airports, picked by strategy, and get harder with level.
+func BuildQuestion(callsign string, d *ResolvedDetails, airports []string, level float64, kind RoundKind, strategy DistractorStrategy) (Question, error) {
+	if d == nil {
+		return Question{}, ErrUnusableDetails
+	}
+	hasRoute := knownPlace(d.RealDestination) && knownPlace(d.Origin)
+	if t, ok := IdentifyType(d.Model); ok && (kind == RoundType || !hasRoute) {
+		return Question{
+			Text:    fmt.Sprintf("What type is %s?", callsign),
+			Correct: t.Name,
+			Options: TypeOptions(t, 4),
+			Hint:    MaskModel(d.Model),
+		}, nil
+	}
+
+	if d.Airline != "" && (kind == RoundAirline || !hasRoute) {
+		return Question{
+			Text:    fmt.Sprintf("Which airline flies %s?", callsign),
+			Correct: d.Airline,
+			Options: AirlineOptions(d.Airline, 4, level),
+		}, nil
+	}
+
+	if !hasRoute {
+		return Question{}, ErrUnusableDetails
+	}
+
+	q := Question{Text: fmt.Sprintf("Where is %s going?", callsign), Correct: d.RealDestination}
+	if isHomeAirport(d.RealDestination) {
+		q = Question{Text: fmt.Sprintf("Where is %s from?", callsign), Correct: d.Origin}
+	}
+
+	// Better players get nearby airports and airline hubs as distractors
+	opts := append([]string{q.Correct}, strategy.Pick(q.Correct, callsign, airports, 3, level)...)
+	for _, c := range fallbackAirports {
+		if len(opts) >= 4 {
+			break
+		}
+		if !slices.ContainsFunc(opts, func(o string) bool { return SamePlace(o, c) }) {
+			opts = append(opts, c)
+		}
+	}
+	rand.Shuffle(len(opts), func(i, j int) { opts[i], opts[j] = opts[j], opts[i] })
+	q.Options = opts
+	q.Strategy = strategy.Name
+	return q, nil
+}
+
+func knownPlace(s string) bool {
+	return s != "" && s != "Unknown"
+}
+
+// RoundScore is the points for one answer: a base for being right plus a
+// bonus that shrinks over the round's time limit
+func RoundScore(correct bool, elapsed, limit time.Duration) int {
+	if !correct {
+		return 0
+	}
+	bonus := 0
+	if limit > 0 && elapsed < limit {
+		bonus = int(float64(roundMaxBonus) * float64(limit-elapsed) / float64(limit))
+	}
+	return roundBasePoints + max(bonus, 0)
+}
+
+// FinishGame records a played game: the player's totals and difficulty, and
+// the score history behind the leaderboard. While the system clock is wrong
+// the score is held back from the history rather than misdated; see
+// StampHeldScores.
+func (dm *DataManager) FinishGame(name string, score int, difficulty Difficulty, at time.Time) (UserStats, error) {
+	u, err := dm.SaveUser(name, score, difficulty)
+	if err != nil {
+		return u, err
+	}
+	entry := ScoreEntry{Name: name, Score: score, Date: at}
+	if !ClockSane(at) {
+		dm.mu.Lock()
+		dm.held = append(dm.held, entry)
+		dm.mu.Unlock()
+		return u, nil
+	}
+	entry.Date = at.Truncate(time.Second)
+	_, err = dm.AddScore(entry)
+	return u, err
+}
+
+// StampHeldScores adds the scores FinishGame held back to the history once
+// the system clock can be trusted, dated by how long ago on the monotonic
+// clock they finished. It does nothing while the clock is still wrong.
+func (dm *DataManager) StampHeldScores(now time.Time) error {
+	if !ClockSane(now) {
+		return nil
+	}
+	dm.mu.Lock()
+	held := dm.held
+	dm.held = nil
+	dm.mu.Unlock()
+
+	for i, e := range held {
+		e.Date = now.Add(-now.Sub(e.Date)).Round(0).Truncate(time.Second)
+		if _, err := dm.AddScore(e); err != nil {
+			dm.mu.Lock()
+			dm.held = append(held[i:], dm.held...)
+			dm.mu.Unlock()
+			return err
+		}
+	}
+	return nil
+}
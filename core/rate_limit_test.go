@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// stalledTransport holds every request until release is closed
+type stalledTransport struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (t stalledTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.started <- struct{}{}
+	select {
+	case <-t.release:
+	case <-r.Context().Done():
+	}
+	return nil, r.Context().Err()
+}
+
+// The UI reads RateLimitInfo every frame, so it must answer while a fetch
+// is waiting on the API
+func TestRateLimitInfoDuringFetch(t *testing.T) {
+	clients := map[string]func(*http.Client) FlightProvider{
+		"adsbx": func(hc *http.Client) FlightProvider {
+			return &AdsbxClient{httpClient: hc, apiKey: "test", remaining: -1}
+		},
+		"adsblol": func(hc *http.Client) FlightProvider {
+			return &AdsbLolClient{httpClient: hc}
+		},
+	}
+	for name, newClient := range clients {
+		t.Run(name, func(t *testing.T) {
+			stall := stalledTransport{started: make(chan struct{}), release: make(chan struct{})}
+			p := newClient(&http.Client{Transport: stall})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go p.FetchFlights(ctx, 60.3, 24.9, 1)
+			<-stall.started
+
+			done := make(chan struct{})
+			go func() {
+				p.RateLimitInfo()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("RateLimitInfo blocked behind the fetch")
+			}
+			close(stall.release)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	receiverPollInterval = 10 * time.Second
+	// The feed counts as dead if no new messages have arrived for this long
+	receiverDeadAfter = 60 * time.Second
+)
+
+// ReceiverStatus summarizes a local dump1090/readsb feeder
+type ReceiverStatus struct {
+	MessageRate  float64 // messages per second over the last minute, or since the last poll without stats.json
+	Aircraft     int
+	MaxRangeKm   float64
+	LastMessages time.Time // last time the message counter advanced
+	LastPoll     time.Time
+	Err          error
+}
+
+// Dead reports whether the feed has stopped delivering messages
+func (s ReceiverStatus) Dead(now time.Time) bool {
+	if s.LastPoll.IsZero() {
+		return false // not polled yet
+	}
+	return now.Sub(s.LastMessages) > receiverDeadAfter
+}
+
+// ReceiverMonitor polls a dump1090/readsb web root (the directory serving
+// data/aircraft.json and data/stats.json) for feeder health
+type ReceiverMonitor struct {
+	baseURL          string
+	homeLat, homeLon float64
+	client           *http.Client
+
+	mu           sync.Mutex
+	status       ReceiverStatus
+	lastMsgCount int64
+	lastMsgAt    time.Time // when lastMsgCount was read, by the receiver's clock if it says
+}
+
+func NewReceiverMonitor(baseURL string, homeLat, homeLon float64) *ReceiverMonitor {
+	return &ReceiverMonitor{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		homeLat: homeLat,
+		homeLon: homeLon,
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Status returns the latest polled status
+func (m *ReceiverMonitor) Status() ReceiverStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// Run polls the receiver until ctx is cancelled
+func (m *ReceiverMonitor) Run(ctx context.Context) {
+	for {
+		m.Poll(ctx)
+		select {
+		case <-time.After(receiverPollInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Poll fetches aircraft.json and stats.json once and updates the status
+func (m *ReceiverMonitor) Poll(ctx context.Context) {
+	var aircraft struct {
+		Now      float64 `json:"now"` // Unix seconds
+		Messages int64   `json:"messages"`
+		Aircraft []struct {
+			Lat *float64 `json:"lat"`
+			Lon *float64 `json:"lon"`
+		} `json:"aircraft"`
+	}
+	err := m.getJSON(ctx, "/data/aircraft.json", &aircraft)
+
+	// stats.json is optional (not every build serves it); prefer its rate and range when present
+	var stats struct {
+		Last1Min struct {
+			Start       float64 `json:"start"`
+			End         float64 `json:"end"`
+			Messages    int64   `json:"messages"`
+			MaxDistance float64 `json:"max_distance"` // meters
+			Local       struct {
+				Accepted []int64 `json:"accepted"`
+			} `json:"local"`
+		} `json:"last1min"`
+	}
+	statsErr := err
+	if err == nil {
+		statsErr = m.getJSON(ctx, "/data/stats.json", &stats)
+	}
+
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.status.LastPoll = now
+	m.status.Err = err
+	if err != nil {
+		return
+	}
+
+	if m.status.LastMessages.IsZero() || aircraft.Messages > m.lastMsgCount {
+		m.status.LastMessages = now
+	}
+	// Without stats.json the rate comes from the total message counter
+	// between polls; a counter going back means the receiver restarted
+	at := now
+	if aircraft.Now > 0 {
+		at = time.Unix(0, int64(aircraft.Now*float64(time.Second)))
+	}
+	if statsErr != nil && !m.lastMsgAt.IsZero() && aircraft.Messages >= m.lastMsgCount {
+		if span := at.Sub(m.lastMsgAt).Seconds(); span > 0 {
+			m.status.MessageRate = float64(aircraft.Messages-m.lastMsgCount) / span
+		}
+	}
+	m.lastMsgCount = aircraft.Messages
+	m.lastMsgAt = at
+	m.status.Aircraft = len(aircraft.Aircraft)
+
+	// Range: furthest positioned aircraft currently visible
+	maxKm := 0.0
+	for _, a := range aircraft.Aircraft {
+		if a.Lat == nil || a.Lon == nil {
+			continue
+		}
+		if d := Distance(m.homeLat, m.homeLon, *a.Lat, *a.Lon); d > maxKm {
+			maxKm = d
+		}
+	}
+	m.status.MaxRangeKm = maxKm
+
+	if statsErr == nil {
+		l := stats.Last1Min
+		msgs := l.Messages
+		if msgs == 0 {
+			for _, n := range l.Local.Accepted {
+				msgs += n
+			}
+		}
+		if span := l.End - l.Start; span > 0 {
+			m.status.MessageRate = float64(msgs) / span
+		}
+		if l.MaxDistance > 0 {
+			m.status.MaxRangeKm = l.MaxDistance / 1000
+		}
+	}
+}
+
+func (m *ReceiverMonitor) getJSON(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", m.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: status %d", path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Receivers serving only aircraft.json get their rate from the message counter
+func TestReceiverRateWithoutStats(t *testing.T) {
+	var messages int64
+	now := 1718352000.0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/data/aircraft.json" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"now":%.1f,"messages":%d,"aircraft":[{"lat":60.4,"lon":24.9}]}`, now, messages)
+	}))
+	defer srv.Close()
+
+	m := NewReceiverMonitor(srv.URL, 60.3, 24.9)
+	for _, c := range []struct {
+		messages int64
+		now      float64
+		want     float64
+	}{
+		{1000, 0, 0},   // nothing to compare with yet
+		{1500, 10, 50}, // 500 messages in 10s
+		{20, 20, 50},   // the receiver restarted; keep the last rate
+		{2020, 30, 200},
+		{2020, 40, 0}, // gone quiet
+	} {
+		messages, now = c.messages, 1718352000+c.now
+		m.Poll(context.Background())
+		st := m.Status()
+		if st.Err != nil {
+			t.Fatal(st.Err)
+		}
+		if math.Abs(st.MessageRate-c.want) > 1e-9 {
+			t.Errorf("at %v with %d messages: rate %v, want %v", c.now, c.messages, st.MessageRate, c.want)
+		}
+	}
+}
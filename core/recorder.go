@@ -0,0 +1,57 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// responseRecorder saves raw API responses, one file per response named
+// after the source and the time it arrived
+type responseRecorder struct {
+	dir string
+}
+
+func newResponseRecorder(dir string) (*responseRecorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &responseRecorder{dir: dir}, nil
+}
+
+// save writes body as e.g. opensky-20260102T150405.123Z.json. The name
+// avoids colons so recordings copy onto any filesystem, and sorts by time.
+func (r *responseRecorder) save(source string, at time.Time, body []byte) error {
+	name := fmt.Sprintf("%s-%s.json", source, at.UTC().Format("20060102T150405.000Z"))
+	return os.WriteFile(filepath.Join(r.dir, name), body, 0644)
+}
+
+// RecordResponses makes the providers in p that support it save every raw
+// response they receive into dir, for debugging, replays and statistics.
+// Only OpenSky does so far; it is an error if p has no such provider.
+func RecordResponses(p FlightProvider, dir string) error {
+	n, err := recordResponses(p, dir)
+	if err == nil && n == 0 {
+		err = fmt.Errorf("provider %s can't record responses", p.Name())
+	}
+	return err
+}
+
+func recordResponses(p FlightProvider, dir string) (int, error) {
+	switch p := p.(type) {
+	case interface{ RecordTo(dir string) error }:
+		return 1, p.RecordTo(dir)
+	case *FailoverProvider, *MergeProvider:
+		n := 0
+		var errs []error
+		for _, inner := range innerProviders(p) {
+			k, err := recordResponses(inner, dir)
+			n += k
+			errs = append(errs, err)
+		}
+		return n, errors.Join(errs...)
+	}
+	return 0, nil
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Region is a watched area, e.g. home or the cottage
+type Region struct {
+	Name     string  `json:"name"`
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	RadiusKm float64 `json:"radius_km,omitempty"` // 0 = the global search radius
+}
+
+// WatchRegions lists home first, followed by the configured extra regions
+func (s Settings) WatchRegions(homeLat, homeLon float64) []Region {
+	return append([]Region{{Name: "Home", Lat: homeLat, Lon: homeLon}}, s.Regions...)
+}
+
+// RegionRadiusDeg is the search box for r, falling back to the global radius
+func (s Settings) RegionRadiusDeg(r Region) float64 {
+	if r.RadiusKm > 0 {
+		return KmToDegrees(r.RadiusKm)
+	}
+	return s.RadiusDeg()
+}
+
+// ParseRegions reads the WATCH_REGIONS format "Name:lat,lon[,radiusKm];..."
+func ParseRegions(spec string) ([]Region, error) {
+	var regions []Region
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, coords, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("region %q: expected Name:lat,lon", part)
+		}
+		fields := strings.Split(coords, ",")
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("region %q: expected lat,lon[,radiusKm]", part)
+		}
+		var vals [3]float64
+		for i, f := range fields {
+			v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+			if err != nil {
+				return nil, fmt.Errorf("region %q: %w", part, err)
+			}
+			vals[i] = v
+		}
+		regions = append(regions, Region{Name: strings.TrimSpace(name), Lat: vals[0], Lon: vals[1], RadiusKm: vals[2]})
+	}
+	return regions, nil
+}
+
+// FetchRegions queries every region's box and merges the results, keeping the
+// first report of each aircraft that passes the settings' filter. It only
+// fails if no region could be fetched.
+func FetchRegions(ctx context.Context, p FlightProvider, s Settings, regions []Region) ([]Flight, error) {
+	var merged []Flight
+	seen := make(map[string]bool)
+	var errs []string
+
+	for _, r := range regions {
+		flights, err := p.FetchFlights(ctx, r.Lat, r.Lon, s.RegionRadiusDeg(r))
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", r.Name, err))
+			continue
+		}
+		for _, f := range flights {
+			if seen[f.Icao24] || !s.Filter.Match(f) {
+				continue
+			}
+			seen[f.Icao24] = true
+			merged = append(merged, f)
+		}
+	}
+
+	if len(errs) == len(regions) && len(regions) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	if len(errs) > 0 {
+		log.Println("Some regions failed:", strings.Join(errs, "; "))
+	}
+	return merged, nil
+}
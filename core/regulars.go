@@ -0,0 +1,252 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	overheadFile = "overhead.jsonl"
+
+	// A flight within this ground distance of home is passing overhead
+	overheadRadiusKm = 10.0
+	// A pass is over once the flight hasn't been in range for this long
+	overheadPassGap = 3 * time.Minute
+
+	// RegularsWindowDays is how many days of passes regulars are found in
+	RegularsWindowDays = 14
+	// A callsign is a regular once it has passed on this many days...
+	regularMinDays = 4
+	// ...within this many minutes of its usual time
+	regularSpreadMin = 30
+	// A regular this many minutes off its usual time is early or late...
+	regularAlertMin = 10
+	// ...and further off than this it is some other service on the callsign
+	regularAlertMaxMin = 120
+)
+
+// OverheadPass is one flight passing near home, timed at its closest approach
+type OverheadPass struct {
+	Callsign   string    `json:"callsign"`
+	Icao24     string    `json:"icao24"`
+	At         time.Time `json:"at"`
+	DistanceKm float64   `json:"distance_km"`
+	AltitudeFt int       `json:"altitude_ft"`
+}
+
+type activePass struct {
+	closest  OverheadPass
+	lastSeen time.Time
+}
+
+// OverheadLog follows flights through the area around home to log one pass
+// per overflight. It is fed every poll from one goroutine.
+type OverheadLog struct {
+	homeLat, homeLon float64
+	active           map[string]*activePass // by icao24
+}
+
+func NewOverheadLog(homeLat, homeLon float64) *OverheadLog {
+	return &OverheadLog{homeLat: homeLat, homeLon: homeLon, active: make(map[string]*activePass)}
+}
+
+// Observe returns the flights that came into range in the poll s, and the
+// passes that have ended
+func (l *OverheadLog) Observe(s *FlightSnapshot) (arrived []Flight, ended []OverheadPass) {
+	now := s.FetchedAt
+	for _, f := range s.Within(l.homeLat, l.homeLon, overheadRadiusKm) {
+		if f.OnGround || !f.HasCallsign() {
+			continue
+		}
+		d := Distance(l.homeLat, l.homeLon, f.Lat, f.Lon)
+		p, ok := l.active[f.Icao24]
+		if !ok {
+			p = &activePass{closest: OverheadPass{DistanceKm: math.Inf(1)}}
+			l.active[f.Icao24] = p
+			arrived = append(arrived, *f)
+		}
+		p.lastSeen = now
+		if d < p.closest.DistanceKm {
+			p.closest = OverheadPass{Callsign: f.Callsign, Icao24: f.Icao24, At: now, DistanceKm: d, AltitudeFt: f.AltitudeFt}
+		}
+	}
+	for icao, p := range l.active {
+		if Elapsed(p.lastSeen, now) > overheadPassGap {
+			ended = append(ended, p.closest)
+			delete(l.active, icao)
+		}
+	}
+	return arrived, ended
+}
+
+// SaveOverheadPasses appends passes to the overhead log
+func (dm *DataManager) SaveOverheadPasses(passes []OverheadPass) error {
+	if len(passes) == 0 {
+		return nil
+	}
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	var lines []byte
+	for _, p := range passes {
+		line, err := encodeRecord(overheadFile, p)
+		if err != nil {
+			return err
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	return dm.storage().Append(overheadFile, lines)
+}
+
+// LoadOverheadPasses reads the logged passes at or after since
+func (dm *DataManager) LoadOverheadPasses(since time.Time) ([]OverheadPass, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	file, err := dm.storage().Open(overheadFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var passes []OverheadPass
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var p OverheadPass
+		if err := decodeRecord(overheadFile, scanner.Bytes(), &p); err != nil {
+			continue // torn line
+		}
+		if !p.At.Before(since) {
+			passes = append(passes, p)
+		}
+	}
+	return passes, scanner.Err()
+}
+
+// Regular is a flight that passes at about the same time most days
+type Regular struct {
+	Callsign string
+	Minute   int // usual time, in minutes after local midnight
+	Days     int // days it was seen near its usual time
+	Route    string
+}
+
+// UsualTime is the regular's usual time of day, e.g. "17:40"
+func (r Regular) UsualTime() string {
+	return fmt.Sprintf("%02d:%02d", r.Minute/60, r.Minute%60)
+}
+
+// Offset is how many minutes after its usual time at is, negative when early
+func (r Regular) Offset(at time.Time) int {
+	return minuteDiff(minuteOfDay(at), r.Minute)
+}
+
+func minuteOfDay(t time.Time) int {
+	t = t.Local()
+	return t.Hour()*60 + t.Minute()
+}
+
+// minuteDiff is a-b in minutes of the day, wrapped into ±12h so 23:50 is
+// 20 minutes before 00:10
+func minuteDiff(a, b int) int {
+	d := (a - b) % 1440
+	switch {
+	case d > 720:
+		d -= 1440
+	case d < -720:
+		d += 1440
+	}
+	return d
+}
+
+// meanMinute averages times of day on the clock face, so passes either
+// side of midnight average to midnight rather than noon
+func meanMinute(minutes []int) int {
+	var x, y float64
+	for _, m := range minutes {
+		a := float64(m) / 1440 * 2 * math.Pi
+		x += math.Cos(a)
+		y += math.Sin(a)
+	}
+	a := math.Atan2(y, x)
+	if a < 0 {
+		a += 2 * math.Pi
+	}
+	return int(math.Round(a/(2*math.Pi)*1440)) % 1440
+}
+
+// FindRegulars picks the callsigns that passed on at least regularMinDays
+// days within regularSpreadMin minutes of their usual time, earliest in the
+// day first. routes names each one's last known route, or nil.
+func FindRegulars(passes []OverheadPass, routes func(callsign string) (ResolvedDetails, bool)) []Regular {
+	byCallsign := make(map[string][]OverheadPass)
+	for _, p := range passes {
+		byCallsign[p.Callsign] = append(byCallsign[p.Callsign], p)
+	}
+
+	var regulars []Regular
+	for callsign, ps := range byCallsign {
+		minutes := make([]int, len(ps))
+		for i, p := range ps {
+			minutes[i] = minuteOfDay(p.At)
+		}
+		usual := meanMinute(minutes)
+
+		// Settle on the usual time from the passes near it, so an odd
+		// diversion doesn't drag it off
+		days := make(map[string]bool)
+		var near []int
+		for i, p := range ps {
+			if absInt(minuteDiff(minutes[i], usual)) <= regularSpreadMin {
+				days[p.At.Local().Format("2006-01-02")] = true
+				near = append(near, minutes[i])
+			}
+		}
+		if len(days) < regularMinDays {
+			continue
+		}
+		r := Regular{Callsign: callsign, Minute: meanMinute(near), Days: len(days)}
+		if routes != nil {
+			if d, ok := routes(callsign); ok && d.Origin != "" && d.RealDestination != "" {
+				r.Route = d.Origin + " - " + d.RealDestination
+			}
+		}
+		regulars = append(regulars, r)
+	}
+	sort.Slice(regulars, func(i, j int) bool {
+		if regulars[i].Minute != regulars[j].Minute {
+			return regulars[i].Minute < regulars[j].Minute
+		}
+		return regulars[i].Callsign < regulars[j].Callsign
+	})
+	return regulars
+}
+
+// RegularAlert says when a regular shows up well off its usual time, e.g.
+// "The 17:40 DLH2AB is 12 min early today". The time is when it came into
+// range, a few minutes before its closest approach, so the threshold leaves
+// room for that.
+func RegularAlert(regulars []Regular, f Flight, now time.Time) (string, bool) {
+	for _, r := range regulars {
+		if r.Callsign != f.Callsign {
+			continue
+		}
+		off := r.Offset(now)
+		switch {
+		case absInt(off) > regularAlertMaxMin:
+		case off <= -regularAlertMin:
+			return fmt.Sprintf("The %s %s is %d min early today", r.UsualTime(), r.Callsign, -off), true
+		case off >= regularAlertMin:
+			return fmt.Sprintf("The %s %s is %d min late today", r.UsualTime(), r.Callsign, off), true
+		}
+		return "", false
+	}
+	return "", false
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"fmt"
+	"image"
+)
+
+// The UI palette as 0xRRGGBBAA, shared by the frontends and by UI drawn
+// through a Renderer
+const (
+	ColBgDark     = 0x0f172aff
+	ColAccent     = 0x38bdf8ff
+	ColGlass      = 0x0f172af2 // 95% opacity
+	ColGlassLight = 0x334155ff
+	ColText       = 0xf1f5f9ff
+	ColTextMuted  = 0x94a3b8ff
+	ColSuccess    = 0x4ade80ff
+	ColDanger     = 0xf87171ff
+	ColGold       = 0xfbbf24ff
+	ColAlert      = 0xe879f9ff
+	ColWhite      = 0xffffffff
+)
+
+// TextSize is a size of UI text. Each frontend picks the font size for it:
+// the ebiten one has a single bitmap font, the raylib one several sizes.
+type TextSize int
+
+const (
+	TextSmall TextSize = iota // captions, chart labels
+	TextNormal
+	TextLarge // headings
+)
+
+// Renderer is what UI written once for every frontend draws with. Each
+// frontend implements it over its graphics library, in its own logical
+// pixels, so shared widgets lay themselves out with Measure rather than
+// fixed sizes.
+type Renderer interface {
+	DrawRect(x, y, w, h int, col uint32)
+	// DrawTexture draws img scaled into the w×h box. Backends keep the
+	// uploaded texture while the same img keeps being drawn, so pass the
+	// same pointer each frame rather than a copy.
+	DrawTexture(img image.Image, x, y, w, h int)
+	// DrawText draws s with its top left corner at (x, y)
+	DrawText(s string, x, y int, size TextSize, col uint32)
+	Measure(s string, size TextSize) (w, h int)
+	// BeginClip limits drawing to the box until the returned func is called
+	BeginClip(x, y, w, h int) (end func())
+}
+
+// DrawBars draws values as a bar chart in the w×h box, scaled to the
+// largest, with each bar's count on top and its label underneath
+func DrawBars(r Renderer, x, y, w, h int, values []int, label func(i int) string) {
+	if len(values) == 0 {
+		return
+	}
+	r.DrawRect(x, y+h, w, 1, ColTextMuted)
+	top := 1
+	for _, v := range values {
+		top = max(top, v)
+	}
+	bw := w / len(values)
+	gap := max(bw/8, 1)
+	for i, v := range values {
+		bx := x + i*bw
+		bh := h * v / top
+		if v > 0 {
+			r.DrawRect(bx+gap, y+h-bh, bw-2*gap, bh, ColAccent)
+			count := fmt.Sprint(v)
+			cw, ch := r.Measure(count, TextSmall)
+			r.DrawText(count, bx+(bw-cw)/2, y+h-bh-ch-2, TextSmall, ColWhite)
+		}
+		if l := label(i); l != "" {
+			lw, _ := r.Measure(l, TextSmall)
+			r.DrawText(l, bx+(bw-lw)/2, y+h+4, TextSmall, ColTextMuted)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+)
+
+const gamesFile = "games.jsonl"
+
+// Share codes are spelled with letters only so they can be typed on the
+// on-screen keyboard; the look-alikes I, L and O are left out
+const shareAlphabet = "ABCDEFGHJKMNPRST"
+
+// Bit layout of a share code, 48 bits in 12 letters
+const (
+	shareDayBits   = 12 // days since shareEpoch, good for ~11 years
+	shareSeedBits  = 16
+	shareScoreBits = 10
+	shareCheckBits = 10
+)
+
+var shareEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var ErrGameNotFound = errors.New("game not found in local log")
+
+// RoundRecord is one played question, enough to pose it again later
+type RoundRecord struct {
+	Callsign string   `json:"callsign"`
+	Lat      float64  `json:"lat"`
+	Lon      float64  `json:"lon"`
+	Question string   `json:"question"`
+	Correct  string   `json:"correct"`
+	Options  []string `json:"options"`        // in the order they were shown
+	Hint     string   `json:"hint,omitempty"` // masked model for type rounds
+	// The distractor experiment arm that picked the options, and whether
+	// the player got it right; see DistractorResults
+	Strategy string `json:"strategy,omitempty"`
+	Right    bool   `json:"right,omitempty"`
+}
+
+// GameRecord is a finished game in the local log. Date and Seed together
+// identify it for share codes.
+type GameRecord struct {
+	Date   string        `json:"date"` // 2006-01-02
+	Seed   uint16        `json:"seed"`
+	Player string        `json:"player"`
+	Score  int           `json:"score"`
+	Rounds []RoundRecord `json:"rounds"`
+}
+
+// ShareInfo is what a share code carries
+type ShareInfo struct {
+	Date  string
+	Seed  uint16
+	Score int
+}
+
+// NewGameSeed picks the seed that tells a game apart from others on the same day
+func NewGameSeed() uint16 {
+	return uint16(rand.Intn(1 << shareSeedBits))
+}
+
+// ShareCode encodes the game's date, seed and score as e.g. "ABCD-EFGH-JKMN".
+// Scores above the encodable maximum are clamped.
+func ShareCode(rec GameRecord) (string, error) {
+	day, err := time.Parse("2006-01-02", rec.Date)
+	if err != nil {
+		return "", err
+	}
+	days := int(day.Sub(shareEpoch).Hours() / 24)
+	if days < 0 || days >= 1<<shareDayBits {
+		return "", fmt.Errorf("date %s outside the share code range", rec.Date)
+	}
+	score := min(max(rec.Score, 0), 1<<shareScoreBits-1)
+
+	payload := uint64(days)<<(shareSeedBits+shareScoreBits) | uint64(rec.Seed)<<shareScoreBits | uint64(score)
+	v := payload<<shareCheckBits | shareChecksum(payload)
+
+	var sb strings.Builder
+	for i := 11; i >= 0; i-- {
+		sb.WriteByte(shareAlphabet[(v>>(4*i))&0xf])
+		if i == 8 || i == 4 {
+			sb.WriteByte('-')
+		}
+	}
+	return sb.String(), nil
+}
+
+// ParseShareCode decodes a code from ShareCode, ignoring case and dashes
+func ParseShareCode(code string) (ShareInfo, error) {
+	code = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	if len(code) != 12 {
+		return ShareInfo{}, fmt.Errorf("share code must have 12 letters")
+	}
+	var v uint64
+	for _, c := range code {
+		i := strings.IndexRune(shareAlphabet, c)
+		if i < 0 {
+			return ShareInfo{}, fmt.Errorf("invalid letter %q in share code", c)
+		}
+		v = v<<4 | uint64(i)
+	}
+
+	payload := v >> shareCheckBits
+	if v&(1<<shareCheckBits-1) != shareChecksum(payload) {
+		return ShareInfo{}, fmt.Errorf("share code checksum mismatch")
+	}
+	days := int(payload >> (shareSeedBits + shareScoreBits))
+	return ShareInfo{
+		Date:  shareEpoch.AddDate(0, 0, days).Format("2006-01-02"),
+		Seed:  uint16(payload >> shareScoreBits),
+		Score: int(payload & (1<<shareScoreBits - 1)),
+	}, nil
+}
+
+func shareChecksum(payload uint64) uint64 {
+	var b [8]byte
+	for i := range b {
+		b[i] = byte(payload >> (8 * i))
+	}
+	return uint64(crc32.ChecksumIEEE(b[:])) & (1<<shareCheckBits - 1)
+}
+
+// SaveGame appends a finished game to the local log
+func (dm *DataManager) SaveGame(rec GameRecord) error {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	line, err := encodeRecord(gamesFile, rec)
+	if err != nil {
+		return err
+	}
+	return dm.storage().Append(gamesFile, append(line, '\n'))
+}
+
+// FindGame looks up a logged game by the date and seed from a share code
+func (dm *DataManager) FindGame(date string, seed uint16) (GameRecord, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	file, err := dm.storage().Open(gamesFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return GameRecord{}, ErrGameNotFound
+		}
+		return GameRecord{}, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var rec GameRecord
+		if err := decodeRecord(gamesFile, scanner.Bytes(), &rec); err != nil {
+			continue // torn line
+		}
+		if rec.Date == date && rec.Seed == seed {
+			return rec, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return GameRecord{}, err
+	}
+	return GameRecord{}, ErrGameNotFound
+}
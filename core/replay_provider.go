@@ -0,0 +1,168 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Recordings this close together are the regions of one poll
+	replayFrameGap = 2 * time.Second
+	// However fast the replay, don't poll more often than this
+	minReplayPoll = 200 * time.Millisecond
+)
+
+// replayFrame is the traffic of one recorded poll
+type replayFrame struct {
+	at      time.Time
+	flights []Flight
+}
+
+// ReplayProvider plays back a directory written with RecordResponses,
+// speed times faster than it was recorded, looping at the end. It drives
+// the map and game like a live provider, for reproducing bugs or watching
+// yesterday's traffic as a time-lapse.
+type ReplayProvider struct {
+	speed  float64
+	frames []replayFrame
+
+	mu      sync.Mutex
+	started time.Time // wall time playback began, zero until the first fetch
+}
+
+// NewReplayProvider loads every recording in dir. A speed of 1 replays in
+// real time; 60 plays an hour in a minute.
+func NewReplayProvider(dir string, speed float64) (*ReplayProvider, error) {
+	if speed <= 0 {
+		return nil, fmt.Errorf("replay speed must be positive, got %v", speed)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ReplayProvider{speed: speed}
+	for _, e := range entries {
+		source, at, ok := parseRecordingName(e.Name())
+		if e.IsDir() || !ok {
+			continue
+		}
+		body, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		flights, _, err := parseOpenSkyStates(body, source)
+		if err != nil {
+			log.Printf("Replay: skipping %s: %v", e.Name(), err)
+			continue
+		}
+		p.frames = append(p.frames, replayFrame{at: at, flights: flights})
+	}
+	if len(p.frames) == 0 {
+		return nil, fmt.Errorf("no recordings in %s", dir)
+	}
+
+	sort.Slice(p.frames, func(i, j int) bool { return p.frames[i].at.Before(p.frames[j].at) })
+	merged := p.frames[:1]
+	for _, f := range p.frames[1:] {
+		last := &merged[len(merged)-1]
+		if f.at.Sub(last.at) > replayFrameGap {
+			merged = append(merged, f)
+			continue
+		}
+		// Overlapping regions both report the flights they share
+		for _, fl := range f.flights {
+			if !slices.ContainsFunc(last.flights, func(o Flight) bool { return o.Icao24 == fl.Icao24 }) {
+				last.flights = append(last.flights, fl)
+			}
+		}
+	}
+	p.frames = merged
+	return p, nil
+}
+
+// parseRecordingName splits a responseRecorder file name into its source
+// and the time the response arrived
+func parseRecordingName(name string) (source string, at time.Time, ok bool) {
+	base, found := strings.CutSuffix(name, ".json")
+	i := strings.LastIndex(base, "-")
+	if !found || i < 1 {
+		return "", time.Time{}, false
+	}
+	at, err := time.Parse("20060102T150405.000Z", base[i+1:])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return base[:i], at, true
+}
+
+func (p *ReplayProvider) Name() string { return "replay" }
+
+// RateLimitInfo never limits: the recording is already on disk
+func (p *ReplayProvider) RateLimitInfo() RateLimitInfo {
+	return RateLimitInfo{Remaining: -1}
+}
+
+// PollInterval polls as often as the recording was made, sped up, so no
+// frame of a time-lapse is skipped
+func (p *ReplayProvider) PollInterval() time.Duration {
+	return max(time.Duration(float64(p.recordedInterval())/p.speed), minReplayPoll)
+}
+
+// recordedInterval is the typical gap between recorded polls
+func (p *ReplayProvider) recordedInterval() time.Duration {
+	if len(p.frames) < 2 {
+		return defaultPollInterval
+	}
+	gaps := make([]time.Duration, 0, len(p.frames)-1)
+	for i := 1; i < len(p.frames); i++ {
+		gaps = append(gaps, p.frames[i].at.Sub(p.frames[i-1].at))
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+	return gaps[len(gaps)/2]
+}
+
+// Span is the recorded period, first poll to last
+func (p *ReplayProvider) Span() (from, to time.Time) {
+	return p.frames[0].at, p.frames[len(p.frames)-1].at
+}
+
+// FetchFlights returns the flights in the box from the recorded poll
+// playing at the moment
+func (p *ReplayProvider) FetchFlights(ctx context.Context, centerLat, centerLon, radiusDeg float64) ([]Flight, error) {
+	frame := p.frameAt(time.Now())
+
+	var flights []Flight
+	for _, f := range frame.flights {
+		if math.Abs(f.Lat-centerLat) <= radiusDeg && math.Abs(f.Lon-centerLon) <= radiusDeg {
+			flights = append(flights, f)
+		}
+	}
+	return flights, nil
+}
+
+// frameAt picks the last frame recorded by the playback position at now
+func (p *ReplayProvider) frameAt(now time.Time) replayFrame {
+	p.mu.Lock()
+	if p.started.IsZero() {
+		p.started = now
+	}
+	elapsed := time.Duration(float64(Elapsed(p.started, now)) * p.speed)
+	p.mu.Unlock()
+
+	from, to := p.Span()
+	// Loop, leaving the last frame up for one poll's worth before restarting
+	length := to.Sub(from) + p.recordedInterval()
+	pos := from.Add(elapsed % length)
+	i := sort.Search(len(p.frames), func(i int) bool { return p.frames[i].at.After(pos) })
+	return p.frames[max(i-1, 0)]
+}
@@ -0,0 +1,225 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// A resolver failing this many times in a row is demoted...
+	chainDemoteAfter = 5
+	// ...to the back of the chain for this long, then gets its place back
+	chainDemoteFor = 10 * time.Minute
+	// Weight of the newest call in the latency average
+	chainLatencyWeight = 0.2
+)
+
+// ResolverStats is how one resolver in a chain has been doing since start
+type ResolverStats struct {
+	Name      string
+	Attempts  int
+	Hits      int           // answered
+	Misses    int           // had no answer for the flight, or was too busy to look
+	Failures  int           // errors: network, HTTP, parsing
+	Latency   time.Duration // moving average over answered and missed calls
+	Streak    int           // failures in a row
+	LastError string
+	Demoted   time.Time // back at the front after this; zero when healthy
+}
+
+// HitRate is the share of attempts answered
+func (s ResolverStats) HitRate() float64 {
+	if s.Attempts == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Attempts)
+}
+
+// IsDemoted reports whether the resolver is at the back of the chain at now
+func (s ResolverStats) IsDemoted(now time.Time) bool {
+	return now.Before(s.Demoted)
+}
+
+// String is a one-line summary for the debug screen
+func (s ResolverStats) String() string {
+	return fmt.Sprintf("%d tries, %.0f%% hits, %d misses, %d errors, %s avg",
+		s.Attempts, s.HitRate()*100, s.Misses, s.Failures, s.Latency.Round(time.Millisecond))
+}
+
+type chainLink struct {
+	r     DetailsResolver
+	stats ResolverStats
+}
+
+// ResolverChain asks its resolvers in order and returns the first answer,
+// remembering answers in the route cache when it has one, and the routes
+// in the learned routes when it has those, which then answer first. It keeps score of
+// each resolver, and one that keeps failing is moved behind the others for
+// a while so every lookup doesn't wait on its timeout first.
+type ResolverChain struct {
+	cache   *RouteCache    // nil to always ask
+	learned *LearnedRoutes // nil to not learn routes
+
+	mu    sync.Mutex
+	links []*chainLink // in configured order
+}
+
+// NewResolverChain chains resolvers in the order added with Add
+func NewResolverChain(cache *RouteCache) *ResolverChain {
+	return &ResolverChain{cache: cache}
+}
+
+// Learn makes the chain learn routes into l and answer from it
+func (c *ResolverChain) Learn(l *LearnedRoutes) *ResolverChain {
+	c.learned = l
+	return c
+}
+
+// Add appends r to the chain under name, for the stats
+func (c *ResolverChain) Add(name string, r DetailsResolver) *ResolverChain {
+	c.links = append(c.links, &chainLink{r: r, stats: ResolverStats{Name: name}})
+	return c
+}
+
+// NewDetailsResolver returns the resolver the game uses: OpenSky's routes
+// database first, then adsbdb.com and hexdb.io, and only for callsigns none
+// of them knows the FlightAware scraper. Answers are kept in cache so repeat
+// lookups are instant, and routes callsigns keep flying are answered from
+// learned. Flights without a callsign are looked up on hexdb.io. The
+// scraper's workers stop when ctx is cancelled.
+func NewDetailsResolver(ctx context.Context, cache *RouteCache, learned *LearnedRoutes) DetailsResolver {
+	return NewResolverChain(cache).Learn(learned).
+		Add("OpenSky routes", NewRouteResolver()).
+		Add("adsbdb", NewAdsbdbResolver()).
+		Add("hexdb", NewHexdbResolver()).
+		Add("FlightAware", NewScraper(ctx))
+}
+
+// order is the links to try now: the healthy ones in configured order, then
+// the demoted ones
+func (c *ResolverChain) order(now time.Time) []*chainLink {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]*chainLink, 0, len(c.links))
+	var demoted []*chainLink
+	for _, l := range c.links {
+		if l.stats.IsDemoted(now) {
+			demoted = append(demoted, l)
+		} else {
+			out = append(out, l)
+		}
+	}
+	return append(out, demoted...)
+}
+
+// record files the outcome of one call to l
+func (c *ResolverChain) record(l *chainLink, took time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := &l.stats
+	s.Attempts++
+	switch {
+	case err == nil:
+		s.Hits++
+	case errors.Is(err, ErrRouteUnknown) || errors.Is(err, ErrScrapeBusy):
+		s.Misses++
+	default:
+		s.Failures++
+		s.Streak++
+		s.LastError = err.Error()
+		if s.Streak >= chainDemoteAfter {
+			s.Demoted = time.Now().Add(chainDemoteFor)
+		}
+		return // a timeout's latency says nothing about the service
+	}
+	s.Streak, s.Demoted = 0, time.Time{}
+	if s.Latency == 0 {
+		s.Latency = took
+	} else {
+		s.Latency += time.Duration(chainLatencyWeight * float64(took-s.Latency))
+	}
+}
+
+// ask calls each link that fn accepts in turn until one answers
+func (c *ResolverChain) ask(ctx context.Context, fn func(r DetailsResolver) (func() (*ResolvedDetails, error), bool)) (*ResolvedDetails, error) {
+	var errs []error
+	for _, l := range c.order(time.Now()) {
+		call, ok := fn(l.r)
+		if !ok {
+			continue
+		}
+		start := time.Now()
+		d, err := call()
+		if ctx.Err() != nil {
+			return nil, ctx.Err() // cancelled, not the resolver's fault
+		}
+		c.record(l, time.Since(start), err)
+		if err == nil {
+			return d, nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, ErrRouteUnknown
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (c *ResolverChain) FetchFlightDetails(ctx context.Context, callsign string) (*ResolvedDetails, error) {
+	if c.cache != nil {
+		if d, ok := c.cache.Get(callsign, time.Now()); ok {
+			nameAirline(d, callsign)
+			return d, nil
+		}
+	}
+	now := time.Now()
+	if d, ok := c.learned.Lookup(callsign, now); ok {
+		nameAirline(d, callsign)
+		return d, nil
+	}
+	d, err := c.ask(ctx, func(r DetailsResolver) (func() (*ResolvedDetails, error), bool) {
+		return func() (*ResolvedDetails, error) { return r.FetchFlightDetails(ctx, callsign) }, true
+	})
+	if err != nil {
+		return nil, err
+	}
+	nameAirline(d, callsign)
+	c.learned.Learn(callsign, d, now)
+	if c.cache != nil {
+		c.cache.Put(callsign, d, now)
+	}
+	return d, nil
+}
+
+// FetchAircraftDetails asks each resolver that knows aircraft by address
+func (c *ResolverChain) FetchAircraftDetails(ctx context.Context, icao24 string) (*ResolvedDetails, error) {
+	return c.ask(ctx, func(r DetailsResolver) (func() (*ResolvedDetails, error), bool) {
+		ar, ok := r.(AircraftResolver)
+		if !ok {
+			return nil, false
+		}
+		return func() (*ResolvedDetails, error) { return ar.FetchAircraftDetails(ctx, icao24) }, true
+	})
+}
+
+// Stats is each resolver's record, in configured order
+func (c *ResolverChain) Stats() []ResolverStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]ResolverStats, len(c.links))
+	for i, l := range c.links {
+		out[i] = l.stats
+	}
+	return out
+}
+
+// ResolverHealth is the stats of r's resolvers when it is a chain, or nil
+func ResolverHealth(r DetailsResolver) []ResolverStats {
+	if c, ok := r.(*ResolverChain); ok {
+		return c.Stats()
+	}
+	return nil
+}
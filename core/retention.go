@@ -0,0 +1,211 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Default days kept of each kind of data that grows while the app runs
+const (
+	DefaultOverheadDays = 90
+	DefaultGameDays     = 365
+	DefaultCaptureDays  = 30
+)
+
+// RetentionSteps are the choices offered on the storage screen; 0 is the
+// default
+var RetentionSteps = []int{0, 7, 14, 30, 60, 90, 180, 365}
+
+// Retention is how many days of each kind of growing data to keep, 0 for
+// the default. Scores and player stats are never pruned: the leaderboard
+// is all-time.
+type Retention struct {
+	OverheadDays int `json:"overhead_days,omitempty"` // overhead passes and noise events
+	TrackDays    int `json:"track_days,omitempty"`    // track history
+	GameDays     int `json:"game_days,omitempty"`     // played games, replayable by share code
+	CaptureDays  int `json:"capture_days,omitempty"`  // exported GIFs and CSVs
+}
+
+// Overhead is the days of overhead passes and noise events kept. The
+// regulars are found in the last RegularsWindowDays, so never fewer.
+func (r Retention) Overhead() int {
+	return max(orDefault(r.OverheadDays, DefaultOverheadDays), RegularsWindowDays)
+}
+
+// Tracks is the days of track history kept
+func (r Retention) Tracks() int {
+	return orDefault(r.TrackDays, defaultTrackDays())
+}
+
+// Games is the days of played games kept
+func (r Retention) Games() int {
+	return orDefault(r.GameDays, DefaultGameDays)
+}
+
+// Captures is the days exported files are kept
+func (r Retention) Captures() int {
+	return orDefault(r.CaptureDays, DefaultCaptureDays)
+}
+
+func orDefault(days, def int) int {
+	if days <= 0 {
+		return def
+	}
+	return days
+}
+
+// PruneReport is what a prune removed
+type PruneReport struct {
+	Records int   // lines dropped from the logs
+	Files   int   // whole files deleted
+	Freed   int64 // bytes
+}
+
+func (p PruneReport) String() string {
+	if p.Records == 0 && p.Files == 0 {
+		return "nothing to prune"
+	}
+	return fmt.Sprintf("pruned %d records and %d files, freeing %s", p.Records, p.Files, FormatBytes(p.Freed))
+}
+
+// Prune drops whatever is older than r allows from the overhead log, noise
+// log, game log and captures. Track history prunes itself; see
+// TrackHistory.SetRetentionDays.
+func (dm *DataManager) Prune(r Retention, now time.Time) (PruneReport, error) {
+	var report PruneReport
+	if !ClockSane(now) {
+		// A clock years ahead would make everything look expired
+		return report, errClockWrong
+	}
+	stamped := func(line []byte, name string) (time.Time, bool) {
+		var rec struct {
+			At time.Time `json:"at"`
+		}
+		if err := decodeRecord(name, line, &rec); err != nil || rec.At.IsZero() {
+			return time.Time{}, false
+		}
+		return rec.At, true
+	}
+
+	overheadCutoff := now.AddDate(0, 0, -r.Overhead())
+	for _, name := range []string{overheadFile, noiseFile} {
+		if err := dm.pruneRecords(name, overheadCutoff, stamped, &report); err != nil {
+			return report, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	gameDay := func(line []byte, name string) (time.Time, bool) {
+		var g GameRecord
+		if err := decodeRecord(name, line, &g); err != nil {
+			return time.Time{}, false
+		}
+		day, err := time.ParseInLocation("2006-01-02", g.Date, time.Local)
+		return day, err == nil
+	}
+	if err := dm.pruneRecords(gamesFile, now.AddDate(0, 0, -r.Games()), gameDay, &report); err != nil {
+		return report, fmt.Errorf("%s: %w", gamesFile, err)
+	}
+
+	if err := pruneFiles(dm.storage(), capturesDir, now.AddDate(0, 0, -r.Captures()), &report); err != nil {
+		return report, fmt.Errorf("%s: %w", capturesDir, err)
+	}
+	return report, nil
+}
+
+// pruneRecords rewrites a JSON lines file without the records stamped
+// before cutoff. Lines without a readable stamp are kept: they may be from
+// a newer version of the app.
+func (dm *DataManager) pruneRecords(name string, cutoff time.Time, stamp func(line []byte, name string) (time.Time, bool), report *PruneReport) error {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	file, err := dm.storage().Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var kept []byte
+	var dropped int
+	var freed int64
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if at, ok := stamp(line, name); ok && at.Before(cutoff) {
+			dropped++
+			freed += int64(len(line)) + 1
+			continue
+		}
+		kept = append(append(kept, line...), '\n')
+	}
+	file.Close()
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if dropped == 0 {
+		return nil
+	}
+
+	// Storage writes are all-or-nothing, so a crash mid-prune can't lose the log
+	if err := dm.storage().WriteFile(name, kept); err != nil {
+		return err
+	}
+	report.Records += dropped
+	report.Freed += freed
+	return nil
+}
+
+// pruneFiles deletes the files in dir of store last written before cutoff
+func pruneFiles(store Storage, dir string, cutoff time.Time, report *PruneReport) error {
+	files, err := store.List(dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if !f.ModTime.Before(cutoff) {
+			continue
+		}
+		if err := store.Remove(f.Name); err != nil {
+			return err
+		}
+		report.Files++
+		report.Freed += f.Size
+	}
+	return nil
+}
+
+// DirEntryUsage is what one file or folder at the top of the data
+// directory takes up
+type DirEntryUsage struct {
+	Name string
+	DirUsage
+}
+
+// DataDirBreakdown is the data directory's usage by top-level file and
+// folder, biggest first, for the storage screen
+func (dm *DataManager) DataDirBreakdown() ([]DirEntryUsage, error) {
+	files, err := dm.storage().List("")
+	byName := make(map[string]*DirEntryUsage)
+	for _, f := range files {
+		top, _, _ := strings.Cut(f.Name, "/")
+		u := byName[top]
+		if u == nil {
+			u = &DirEntryUsage{Name: top}
+			byName[top] = u
+		}
+		u.Bytes += f.Size
+		u.Files++
+	}
+	out := make([]DirEntryUsage, 0, len(byName))
+	for _, u := range byName {
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Bytes > out[j].Bytes })
+	return out, err
+}
@@ -0,0 +1,42 @@
+package core
+
+import "log"
+
+// QuizCandidates is the flights a round can be about: airliner-like traffic
+// in the air, still being seen, that r can look up
+func QuizCandidates(flights []Flight, r DetailsResolver) []Flight {
+	var out []Flight
+	for _, f := range flights {
+		if QuizFilter.Match(f) && CanResolve(r, f) && !f.Stale {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// RoundConfig is how a round's question is put together
+type RoundConfig struct {
+	Airports []string // wrong answers for route rounds are drawn from these
+	Level    float64  // difficulty, see Difficulty
+	Kind     RoundKind
+	Strategy DistractorStrategy
+}
+
+// SetupRound poses the question about target from its resolved details.
+// Airports a route round names are saved, through db when it isn't nil,
+// to be wrong answers in later games. An error means the details can't
+// make a question and another flight should be tried.
+func SetupRound(dm *DataManager, db *AirportDB, target Flight, d *ResolvedDetails, cfg RoundConfig) (Question, error) {
+	q, err := BuildQuestion(QuizName(target, d), d, cfg.Airports, cfg.Level, cfg.Kind, cfg.Strategy)
+	if err != nil {
+		return Question{}, err
+	}
+	if !q.IsTypeRound() {
+		for _, a := range []string{d.RealDestination, d.Origin} {
+			if err := dm.SaveAirport(a, db); err != nil {
+				log.Println("Error saving airport:", err)
+			}
+		}
+	}
+	return q, nil
+}
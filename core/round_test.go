@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func roundFlight(icao, callsign string) Flight {
+	return Flight{Icao24: icao, Callsign: callsign, AltitudeFt: 30000, Category: "Large"}
+}
+
+func TestQuizCandidates(t *testing.T) {
+	fresh := roundFlight("aaa001", "FIN123")
+	stale := roundFlight("aaa002", "FIN124")
+	stale.Stale = true
+	ground := roundFlight("aaa003", "FIN125")
+	ground.OnGround = true
+	light := roundFlight("aaa004", "OHABC")
+	light.Category = "Light"
+	noCallsign := roundFlight("aaa005", "")
+
+	got := QuizCandidates([]Flight{fresh, stale, ground, light, noCallsign}, NewFakeResolver())
+	var ids []string
+	for _, f := range got {
+		ids = append(ids, f.Icao24)
+	}
+	// The fake resolves by address too, so flights without a callsign count
+	if want := []string{"aaa001", "aaa005"}; !slices.Equal(ids, want) {
+		t.Errorf("candidates = %v, want %v", ids, want)
+	}
+}
+
+func TestSetupRoundRoute(t *testing.T) {
+	dm := NewDataManager(NewMemoryStorage())
+	r := NewFakeResolver().AddRoute("FIN123", ResolvedDetails{
+		Origin:          "Helsinki-Vantaa, Finland",
+		RealDestination: "London, United Kingdom",
+	})
+	target := roundFlight("aaa001", "FIN123")
+
+	d, err := ResolveFlight(context.Background(), r, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := SetupRound(dm, nil, target, d, RoundConfig{
+		Airports: []string{"Paris, France", "Berlin, Germany", "Oslo, Norway", "Rome, Italy"},
+		Strategy: DistractorStrategies[0],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Correct != "London, United Kingdom" {
+		t.Errorf("correct = %q, want the destination", q.Correct)
+	}
+	if len(q.Options) != 4 || !slices.Contains(q.Options, q.Correct) {
+		t.Errorf("options = %v, want 4 including %q", q.Options, q.Correct)
+	}
+
+	saved, err := dm.LoadAirports()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if places := AirportPlaces(saved); len(places) != 2 {
+		t.Errorf("saved airports = %v, want origin and destination", places)
+	}
+}
+
+func TestSetupRoundAsksInboundFlightsForOrigin(t *testing.T) {
+	d := &ResolvedDetails{Origin: "Paris, France", RealDestination: "Helsinki-Vantaa, Finland"}
+
+	q, err := SetupRound(NewDataManager(NewMemoryStorage()), nil, roundFlight("aaa001", "AFR1"), d, RoundConfig{Strategy: DistractorStrategies[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Correct != "Paris, France" {
+		t.Errorf("correct = %q, want the origin", q.Correct)
+	}
+}
+
+func TestSetupRoundRejectsUnusableDetails(t *testing.T) {
+	d := &ResolvedDetails{Origin: "Unknown", RealDestination: "Unknown"}
+
+	if _, err := SetupRound(NewDataManager(NewMemoryStorage()), nil, roundFlight("aaa001", "XYZ1"), d, RoundConfig{}); !errors.Is(err, ErrUnusableDetails) {
+		t.Errorf("err = %v, want ErrUnusableDetails", err)
+	}
+}
+
+func TestFakeResolver(t *testing.T) {
+	r := NewFakeResolver().AddAircraft("ABC123", ResolvedDetails{Registration: "OH-LVA"})
+	ctx := context.Background()
+
+	if _, err := r.FetchFlightDetails(ctx, "NOPE1"); !errors.Is(err, ErrRouteUnknown) {
+		t.Errorf("unknown callsign err = %v, want ErrRouteUnknown", err)
+	}
+	d, err := r.FetchAircraftDetails(ctx, "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Registration = "changed"
+	if again, _ := r.FetchAircraftDetails(ctx, "abc123"); again.Registration != "OH-LVA" {
+		t.Errorf("registration = %q after a caller changed its copy", again.Registration)
+	}
+	if want := []string{"NOPE1", "abc123", "abc123"}; !slices.Equal(r.Asked(), want) {
+		t.Errorf("asked = %v, want %v", r.Asked(), want)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := r.FetchAircraftDetails(cancelled, "abc123"); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled err = %v, want context.Canceled", err)
+	}
+}
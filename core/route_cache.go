@@ -0,0 +1,115 @@
+package core
+
+import (
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const routeCacheFile = "route_cache.json"
+
+const (
+	// Routes change rarely within a day, and the same aircraft fly past
+	// again and again; a few hours spares the route APIs and FlightAware
+	// most repeat lookups
+	routeCacheTTL = 6 * time.Hour
+	// Older answers are kept this long as the last known route, e.g. for
+	// the regulars list, but not served as lookups
+	routeCacheKeep = 30 * 24 * time.Hour
+)
+
+// cachedRoute is one scraped answer and when it was fetched
+type cachedRoute struct {
+	Details   ResolvedDetails `json:"details"`
+	FetchedAt time.Time       `json:"fetched_at"`
+}
+
+// RouteCache remembers resolved flight details per callsign in
+// route_cache.json, so a callsign selected again is answered instantly, even
+// after a restart. It is read from disk on first use.
+type RouteCache struct {
+	dm  *DataManager
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedRoute // nil until loaded
+}
+
+func NewRouteCache(dm *DataManager) *RouteCache {
+	return &RouteCache{dm: dm, ttl: routeCacheTTL}
+}
+
+// Known returns the last details stored for callsign, however old
+func (c *RouteCache) Known(callsign string) (ResolvedDetails, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+	e, ok := c.entries[routeCacheKey(callsign)]
+	return e.Details, ok
+}
+
+func routeCacheKey(callsign string) string {
+	return strings.ToUpper(strings.TrimSpace(callsign))
+}
+
+// Get returns the cached details for callsign if they are fresh. Nothing is
+// fresh while the system clock is wrong.
+func (c *RouteCache) Get(callsign string, now time.Time) (*ResolvedDetails, bool) {
+	if !ClockSane(now) {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+	e, ok := c.entries[routeCacheKey(callsign)]
+	if !ok || Elapsed(e.FetchedAt, now) > c.ttl {
+		return nil, false
+	}
+	d := e.Details
+	return &d, true
+}
+
+// Put stores details for callsign and saves the cache, dropping entries
+// past keeping on the way. It waits for the system clock to be right, so a
+// wrong one can't date entries or drop them as too old.
+func (c *RouteCache) Put(callsign string, d *ResolvedDetails, now time.Time) {
+	if !ClockSane(now) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+	c.entries[routeCacheKey(callsign)] = cachedRoute{Details: *d, FetchedAt: now}
+	for k, e := range c.entries {
+		if Elapsed(e.FetchedAt, now) > routeCacheKeep {
+			delete(c.entries, k)
+		}
+	}
+
+	c.dm.mu.Lock()
+	err := c.dm.writeDocument(routeCacheFile, c.entries)
+	c.dm.mu.Unlock()
+	if err != nil {
+		log.Println("Error saving route cache:", err)
+	}
+}
+
+// load reads the cache file the first time it is needed. A missing or
+// unreadable file starts an empty cache. Caller holds c.mu.
+func (c *RouteCache) load() {
+	if c.entries != nil {
+		return
+	}
+	c.entries = make(map[string]cachedRoute)
+	c.dm.mu.Lock()
+	err := c.dm.readDocument(routeCacheFile, &c.entries)
+	c.dm.mu.Unlock()
+	if err != nil && !os.IsNotExist(err) {
+		log.Println("Error loading route cache:", err)
+	}
+	if c.entries == nil { // the file held null
+		c.entries = make(map[string]cachedRoute)
+	}
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	openSkyRoutesURL   = "https://opensky-network.org/api/routes?callsign=%s"
+	openSkyAirportsURL = "https://opensky-network.org/api/airports/?icao=%s"
+)
+
+// ErrRouteUnknown means a resolver has nothing on the callsign: the routes
+// database has no entry, or FlightAware no flight page
+var ErrRouteUnknown = errors.New("route not known")
+
+// Country names for the ISO codes OpenSky's airport records carry, so names
+// read like FlightAware's "City, Country". Unlisted codes are shown as-is.
+var countryNames = map[string]string{
+	"AE": "UAE", "AT": "Austria", "BE": "Belgium", "BG": "Bulgaria",
+	"CA": "Canada", "CH": "Switzerland", "CN": "China", "CY": "Cyprus",
+	"CZ": "Czech Republic", "DE": "Germany", "DK": "Denmark", "EE": "Estonia",
+	"EG": "Egypt", "ES": "Spain", "FI": "Finland", "FR": "France",
+	"GB": "United Kingdom", "GR": "Greece", "HR": "Croatia", "HU": "Hungary",
+	"IE": "Ireland", "IN": "India", "IS": "Iceland", "IT": "Italy",
+	"JP": "Japan", "KR": "South Korea", "LT": "Lithuania", "LV": "Latvia",
+	"NL": "Netherlands", "NO": "Norway", "PL": "Poland", "PT": "Portugal",
+	"QA": "Qatar", "RO": "Romania", "SE": "Sweden", "SG": "Singapore",
+	"TH": "Thailand", "TR": "Turkey", "US": "USA",
+}
+
+// RouteResolver looks up a callsign's scheduled route in OpenSky's routes
+// database. It is far sturdier than scraping FlightAware but knows nothing
+// about the aircraft, so Model is always empty.
+type RouteResolver struct {
+	client *http.Client
+
+	mu       sync.Mutex
+	airports map[string]string // ICAO code -> display name
+}
+
+func NewRouteResolver() *RouteResolver {
+	return &RouteResolver{
+		client:   &http.Client{Timeout: 10 * time.Second},
+		airports: make(map[string]string),
+	}
+}
+
+// FetchFlightDetails returns the origin and destination of callsign's route
+func (r *RouteResolver) FetchFlightDetails(ctx context.Context, callsign string) (*ResolvedDetails, error) {
+	var route struct {
+		Callsign string   `json:"callsign"`
+		Route    []string `json:"route"` // ICAO airport codes, origin first
+	}
+	if err := r.getJSON(ctx, fmt.Sprintf(openSkyRoutesURL, url.QueryEscape(strings.TrimSpace(callsign))), &route); err != nil {
+		return nil, err
+	}
+	if len(route.Route) < 2 {
+		return nil, ErrRouteUnknown
+	}
+
+	// Multi-leg routes list every stop; the quiz only cares about the ends
+	origin, err := r.airportName(ctx, route.Route[0])
+	if err != nil {
+		return nil, err
+	}
+	dest, err := r.airportName(ctx, route.Route[len(route.Route)-1])
+	if err != nil {
+		return nil, err
+	}
+	return &ResolvedDetails{
+		Destination:     dest,
+		RealDestination: dest,
+		Origin:          origin,
+	}, nil
+}
+
+// airportName turns an ICAO code into "City, Country", remembering answers
+// since the same handful of airports come up all day
+func (r *RouteResolver) airportName(ctx context.Context, icao string) (string, error) {
+	r.mu.Lock()
+	name, ok := r.airports[icao]
+	r.mu.Unlock()
+	if ok {
+		return name, nil
+	}
+
+	var ap struct {
+		Name    string `json:"name"`
+		City    string `json:"city"`
+		Country string `json:"country"`
+	}
+	if err := r.getJSON(ctx, fmt.Sprintf(openSkyAirportsURL, url.QueryEscape(icao)), &ap); err != nil {
+		return "", fmt.Errorf("airport %s: %w", icao, err)
+	}
+	name = ap.City
+	if name == "" {
+		name = ap.Name
+	}
+	if name == "" {
+		return "", fmt.Errorf("airport %s: %w", icao, ErrRouteUnknown)
+	}
+	if c, ok := countryNames[ap.Country]; ok {
+		name += ", " + c
+	} else if ap.Country != "" {
+		name += ", " + ap.Country
+	}
+
+	r.mu.Lock()
+	r.airports[icao] = name
+	r.mu.Unlock()
+	return name, nil
+}
+
+func (r *RouteResolver) getJSON(ctx context.Context, apiURL string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrRouteUnknown
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// nameAirline names the airline from the callsign's prefix. The bundled
+// table wins over a resolver's own name so the same carrier is always
+// spelled the same way in quiz options.
+func nameAirline(d *ResolvedDetails, callsign string) {
+	if name := AirlineName(callsign); name != "" {
+		d.Airline = name
+	}
+}
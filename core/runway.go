@@ -0,0 +1,539 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Arrivals are followed on final: this close to the home airport and
+	// this low
+	finalRadiusKm = 15.0
+	finalMaxFt    = 4000
+	// The shortest stretch of final worth reading a course from
+	finalMinKm = 2.0
+	// Final descends at least this steeply, about half a usual glide
+	// path, which level overflights don't
+	finalMinDescentFtPerKm = 80
+	// A final approach is straight to within this, and the line it flies
+	// passes at most runwayMaxOffsetKm from the airport's reference point
+	finalStraightKm   = 0.4
+	runwayMaxOffsetKm = 3.0
+	// An approach not seen for this long has landed, or gone around
+	finalLostAfter = time.Minute
+
+	// Landings on courses this close are on the same runway
+	runwayCourseTolDeg = 15.0
+	// Parallel runways are further apart than this, so approach lines
+	// nearer each other are onto the same one
+	parallelMinSepKm = 0.6
+	// Landings this recent say which runways are in use
+	runwayActiveWindow = 20 * time.Minute
+	// Landings this recent show which runways and parallels there are, so
+	// a direction not flown for a while can still be named
+	runwayLayoutWindow = 12 * time.Hour
+	// Lighter wind doesn't decide which way a runway is used
+	runwayWindMinKt = 5
+	// Where arrivals are taken to touch down on a runway not yet landed on
+	// that way: this far before abeam the airport's reference point
+	defaultTouchdownKm = 1.0
+)
+
+// Landing is an arrival's final approach into the home airport: the
+// straight line it flew down to the runway
+type Landing struct {
+	CourseDeg float64   // true course along final
+	OffsetKm  float64   // how far right of the airport's reference point the line passes
+	EndKm     float64   // where it was last seen along the line, km past abeam the reference point
+	Time      time.Time // last seen on final
+}
+
+// localKm places a point on a flat map around the airport, x east and y
+// north in km; fine for the few km around a runway
+func localKm(airport Airport, lat, lon float64) (x, y float64) {
+	return (lon - airport.Lon) * 111.32 * math.Cos(airport.Lat*math.Pi/180), (lat - airport.Lat) * 110.57
+}
+
+// ReadLanding finds the final approach at the end of a track near airport.
+// The track has to end low and close in, descending along a straight line
+// towards the airport; the turn onto final is left out. False for
+// departures, overflights and go-arounds.
+func ReadLanding(track []TrackPoint, airport Airport) (Landing, bool) {
+	// The last stretch of the track inside the final zone
+	start := len(track)
+	for start > 0 {
+		p := track[start-1]
+		if p.AltitudeFt <= 0 || p.AltitudeFt > finalMaxFt || Distance(airport.Lat, airport.Lon, p.Lat, p.Lon) > finalRadiusKm {
+			break
+		}
+		start--
+	}
+	final := track[start:]
+
+	for ; len(final) >= 2; final = final[1:] {
+		first, last := final[0], final[len(final)-1]
+		ax, ay := localKm(airport, first.Lat, first.Lon)
+		bx, by := localKm(airport, last.Lat, last.Lon)
+		length := math.Hypot(bx-ax, by-ay)
+		if length < finalMinKm {
+			return Landing{}, false
+		}
+		if float64(first.AltitudeFt-last.AltitudeFt) < length*finalMinDescentFtPerKm || math.Hypot(bx, by) >= math.Hypot(ax, ay) {
+			return Landing{}, false // not descending, or moving away
+		}
+		ux, uy := (bx-ax)/length, (by-ay)/length
+		straight := true
+		for _, p := range final[1 : len(final)-1] {
+			x, y := localKm(airport, p.Lat, p.Lon)
+			if math.Abs((x-ax)*uy-(y-ay)*ux) > finalStraightKm {
+				straight = false
+				break
+			}
+		}
+		if !straight {
+			continue // still turning onto final
+		}
+		// Right of the course is (uy, -ux), and the airport is at 0, 0
+		offset := ax*uy - ay*ux
+		if math.Abs(offset) > runwayMaxOffsetKm {
+			return Landing{}, false
+		}
+		course := math.Mod(math.Atan2(ux, uy)*180/math.Pi+360, 360)
+		return Landing{CourseDeg: course, OffsetKm: offset, EndKm: bx*ux + by*uy, Time: last.Time}, true
+	}
+	return Landing{}, false
+}
+
+// courseDiff is how far apart two courses are, 0 to 180 degrees
+func courseDiff(a, b float64) float64 {
+	return math.Abs(math.Mod(a-b+540, 360) - 180)
+}
+
+// runwayNumber is the number a runway landed on along a true course is
+// painted with: the magnetic heading in tens of degrees, 36 for north
+func runwayNumber(courseDeg, declination float64) int {
+	n := int(math.Round(math.Mod(courseDeg-declination+720, 360)/10)) % 36
+	if n == 0 {
+		n = 36
+	}
+	return n
+}
+
+// runwayAxis is a runway, or a set of parallel runways, as seen from the
+// landings on it in both directions. Courses and offsets are taken along
+// the direction of the first landing.
+type runwayAxis struct {
+	sumX, sumY float64 // unit vectors of the courses, to average them
+	landings   []Landing
+	lanes      []runwayLane // the parallel runways, left to right
+}
+
+// runwayLane is one of a set of parallel runways
+type runwayLane struct {
+	offset float64    // km right of the airport's reference point along the axis
+	endSum [2]float64 // of the landings along the axis, and the other way round
+	ends   [2]int
+}
+
+// touchdown is where landings on the lane end, km past abeam the airport's
+// reference point in the direction landed
+func (l runwayLane) touchdown(reverse bool) float64 {
+	i := 0
+	if reverse {
+		i = 1
+	}
+	if l.ends[i] == 0 {
+		return -defaultTouchdownKm
+	}
+	return l.endSum[i] / float64(l.ends[i])
+}
+
+func (a *runwayAxis) course() float64 {
+	return math.Mod(math.Atan2(a.sumX, a.sumY)*180/math.Pi+360, 360)
+}
+
+// runwayLayout groups landings onto runways and their parallels
+type runwayLayout struct {
+	axes []*runwayAxis
+}
+
+// place finds the axis a landing is on and whether it is flown the other
+// way round, adding one for a runway not seen before
+func (l *runwayLayout) place(ld Landing) (*runwayAxis, bool) {
+	for _, a := range l.axes {
+		if courseDiff(ld.CourseDeg, a.course()) <= runwayCourseTolDeg {
+			return a, false
+		}
+		if courseDiff(ld.CourseDeg+180, a.course()) <= runwayCourseTolDeg {
+			return a, true
+		}
+	}
+	a := &runwayAxis{}
+	l.axes = append(l.axes, a)
+	return a, false
+}
+
+func newRunwayLayout(landings []Landing) *runwayLayout {
+	l := &runwayLayout{}
+	for _, ld := range landings {
+		a, reverse := l.place(ld)
+		course := ld.CourseDeg
+		if reverse {
+			course += 180
+		}
+		a.sumX += math.Sin(course * math.Pi / 180)
+		a.sumY += math.Cos(course * math.Pi / 180)
+		a.landings = append(a.landings, ld)
+	}
+	// Approach lines further apart than parallels can be are separate
+	// runways; each is where its landings lined up on average
+	for _, a := range l.axes {
+		type line struct {
+			offset  float64
+			ld      Landing
+			reverse bool
+		}
+		lines := make([]line, len(a.landings))
+		for i, ld := range a.landings {
+			_, reverse := l.place(ld)
+			lines[i] = line{ld.OffsetKm, ld, reverse}
+			if reverse {
+				lines[i].offset = -ld.OffsetKm
+			}
+		}
+		slices.SortFunc(lines, func(p, q line) int { return cmp.Compare(p.offset, q.offset) })
+		var lane runwayLane
+		n := 0
+		for i, ln := range lines {
+			if i > 0 && ln.offset-lines[i-1].offset > parallelMinSepKm {
+				lane.offset /= float64(n)
+				a.lanes = append(a.lanes, lane)
+				lane, n = runwayLane{}, 0
+			}
+			dir := 0
+			if ln.reverse {
+				dir = 1
+			}
+			lane.offset += ln.offset
+			lane.endSum[dir] += ln.ld.EndKm
+			lane.ends[dir]++
+			n++
+		}
+		lane.offset /= float64(n)
+		a.lanes = append(a.lanes, lane)
+	}
+	return l
+}
+
+// laneSuffixes are the letters parallel runways are told apart by, left
+// to right, by how many there are
+var laneSuffixes = map[int][]string{
+	2: {"L", "R"},
+	3: {"L", "C", "R"},
+}
+
+// name is the designator of the runway a landing is on, e.g. "22L"
+func (l *runwayLayout) name(ld Landing, declination float64) string {
+	a, reverse := l.place(ld)
+	return a.designator(reverse, a.lane(ld.OffsetKm, reverse), declination)
+}
+
+// approach is the final approach onto a landing's runway
+func (l *runwayLayout) approach(ld Landing, airport Airport, declination float64) Approach {
+	a, reverse := l.place(ld)
+	return a.approach(reverse, a.lane(ld.OffsetKm, reverse), airport, declination)
+}
+
+// lane is the index, left to right along the axis, of the parallel an
+// approach line at offset is onto
+func (a *runwayAxis) lane(offset float64, reverse bool) int {
+	if reverse {
+		offset = -offset
+	}
+	best := 0
+	for i, ln := range a.lanes {
+		if math.Abs(ln.offset-offset) < math.Abs(a.lanes[best].offset-offset) {
+			best = i
+		}
+	}
+	return best
+}
+
+// approach is the final approach onto lane, landing along the axis or the
+// other way round
+func (a *runwayAxis) approach(reverse bool, lane int, airport Airport, declination float64) Approach {
+	course, offset := a.course(), a.lanes[lane].offset
+	if reverse {
+		course, offset = math.Mod(course+180, 360), -offset
+	}
+	ux, uy := math.Sin(course*math.Pi/180), math.Cos(course*math.Pi/180)
+	end := a.lanes[lane].touchdown(reverse)
+	x, y := offset*uy+end*ux, -offset*ux+end*uy
+	return Approach{
+		Runway:    a.designator(reverse, lane, declination),
+		CourseDeg: course,
+		Lat:       airport.Lat + y/110.57,
+		Lon:       airport.Lon + x/(111.32*math.Cos(airport.Lat*math.Pi/180)),
+	}
+}
+
+// designator names lane landed on along the axis, or the other way round
+func (a *runwayAxis) designator(reverse bool, lane int, declination float64) string {
+	course := a.course()
+	if reverse {
+		// Left and right swap when landing the other way
+		course, lane = course+180, len(a.lanes)-1-lane
+	}
+	name := fmt.Sprintf("%02d", runwayNumber(course, declination))
+	if suffixes, ok := laneSuffixes[len(a.lanes)]; ok {
+		name += suffixes[lane]
+	}
+	return name
+}
+
+// headwind is the wind along a course, negative for a tailwind; false
+// when there is no steady wind to speak of
+func headwind(sky *Sky, courseDeg float64) (float64, bool) {
+	if sky == nil || sky.WindDirDeg < 0 || sky.WindKt < runwayWindMinKt {
+		return 0, false
+	}
+	return float64(sky.WindKt) * math.Cos((float64(sky.WindDirDeg)-courseDeg)*math.Pi/180), true
+}
+
+// Approach is the final approach onto a runway: the extended centreline
+// arrivals fly down to touch down at Lat, Lon
+type Approach struct {
+	Runway    string
+	CourseDeg float64 // true course along final
+	Lat, Lon  float64 // where arrivals touch down, as near as the tracks show
+}
+
+// Point is distKm out along the extended centreline from touchdown, and
+// sideKm to the right of it looking along final
+func (a Approach) Point(distKm, sideKm float64) (lat, lon float64) {
+	lat, lon = Destination(a.Lat, a.Lon, a.CourseDeg+180, distKm)
+	if sideKm != 0 {
+		lat, lon = Destination(lat, lon, a.CourseDeg+90, sideKm)
+	}
+	return lat, lon
+}
+
+// RunwaysInUse is which runways arrivals at the home airport are using
+type RunwaysInUse struct {
+	Arrivals   []string   // busiest first, e.g. "22L"
+	Approaches []Approach // onto each of Arrivals
+	Landings   int        // recent landings it was read from, 0 when only the wind says
+	HeadwindKt int        // along the busiest runway, negative for a tailwind
+}
+
+// String is e.g. "Arrivals RWY 22L", "" when it isn't known
+func (r RunwaysInUse) String() string {
+	if len(r.Arrivals) == 0 {
+		return ""
+	}
+	s := "Arrivals RWY " + strings.Join(r.Arrivals, "/")
+	if r.Landings == 0 {
+		s += " (by wind)"
+	}
+	return s
+}
+
+// InferRunways works out the runways in use at airport from its landings.
+// Those of the last runwayActiveWindow say which runways are in use, and
+// all of them which runways and parallels there are. When recent arrivals
+// came in from both ends of a runway, as they do while it is turned
+// around, the end facing the wind wins; when none have landed lately, the
+// runway end facing the wind is assumed.
+func InferRunways(landings []Landing, airport Airport, sky *Sky, now time.Time) RunwaysInUse {
+	var known, recent []Landing
+	for _, ld := range landings {
+		age := Elapsed(ld.Time, now)
+		if age <= runwayLayoutWindow {
+			known = append(known, ld)
+		}
+		if age <= runwayActiveWindow {
+			recent = append(recent, ld)
+		}
+	}
+	if len(known) == 0 {
+		return RunwaysInUse{}
+	}
+	layout := newRunwayLayout(known)
+	declination := MagneticDeclination(airport.Lat, airport.Lon, now)
+
+	if len(recent) == 0 {
+		return windRunways(layout, airport, sky, declination)
+	}
+
+	// Which way each runway is being landed on, by the majority unless
+	// the wind says otherwise
+	type use struct{ ahead, reverse int }
+	uses := make(map[*runwayAxis]*use)
+	for _, ld := range recent {
+		a, reverse := layout.place(ld)
+		if uses[a] == nil {
+			uses[a] = &use{}
+		}
+		if reverse {
+			uses[a].reverse++
+		} else {
+			uses[a].ahead++
+		}
+	}
+	wantReverse := make(map[*runwayAxis]bool)
+	for a, u := range uses {
+		wantReverse[a] = u.reverse > u.ahead
+		if u.ahead > 0 && u.reverse > 0 {
+			if wind, ok := headwind(sky, a.course()); ok {
+				wantReverse[a] = wind < 0
+			}
+		}
+	}
+
+	counts := make(map[string]int)
+	approaches := make(map[string]Approach)
+	var r RunwaysInUse
+	for _, ld := range recent {
+		a, reverse := layout.place(ld)
+		if reverse != wantReverse[a] {
+			continue
+		}
+		name := layout.name(ld, declination)
+		if counts[name] == 0 {
+			approaches[name] = layout.approach(ld, airport, declination)
+		}
+		counts[name]++
+		r.Landings++
+	}
+	for name := range counts {
+		r.Arrivals = append(r.Arrivals, name)
+	}
+	slices.SortFunc(r.Arrivals, func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b))
+	})
+	for _, name := range r.Arrivals {
+		r.Approaches = append(r.Approaches, approaches[name])
+	}
+
+	// The wind along the busiest, from any landing on it
+	for _, ld := range recent {
+		if layout.name(ld, declination) == r.Arrivals[0] {
+			if wind, ok := headwind(sky, ld.CourseDeg); ok {
+				r.HeadwindKt = int(math.Round(wind))
+			}
+			break
+		}
+	}
+	return r
+}
+
+// windRunways is the runway end facing the wind most squarely among those
+// landed on before, with all its parallels
+func windRunways(layout *runwayLayout, airport Airport, sky *Sky, declination float64) RunwaysInUse {
+	var best *runwayAxis
+	var bestReverse bool
+	bestWind := 0.0
+	for _, a := range layout.axes {
+		wind, ok := headwind(sky, a.course())
+		if !ok {
+			return RunwaysInUse{}
+		}
+		if math.Abs(wind) > bestWind {
+			best, bestReverse, bestWind = a, wind < 0, math.Abs(wind)
+		}
+	}
+	if best == nil {
+		return RunwaysInUse{}
+	}
+	r := RunwaysInUse{HeadwindKt: int(math.Round(bestWind))}
+	for lane := range best.lanes {
+		r.Approaches = append(r.Approaches, best.approach(bestReverse, lane, airport, declination))
+	}
+	slices.SortFunc(r.Approaches, func(a, b Approach) int { return cmp.Compare(a.Runway, b.Runway) })
+	for _, a := range r.Approaches {
+		r.Arrivals = append(r.Arrivals, a.Runway)
+	}
+	return r
+}
+
+// RunwayMonitor follows arrivals onto final at the home airport to tell
+// which runways are in use. Observe is fed every poll from one goroutine;
+// InUse can be called from any. A nil *RunwayMonitor knows nothing.
+type RunwayMonitor struct {
+	mu         sync.Mutex
+	airport    string                  // code of the airport the landings are at
+	approaches map[string][]TrackPoint // icao24 -> positions on final so far
+	landings   []Landing               // oldest first
+}
+
+func NewRunwayMonitor() *RunwayMonitor {
+	return &RunwayMonitor{approaches: make(map[string][]TrackPoint)}
+}
+
+// Observe follows the flights on final in one poll. An approach is read
+// once the aircraft is on the ground or hasn't been seen on final for
+// finalLostAfter.
+func (m *RunwayMonitor) Observe(flights []Flight, now time.Time) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	home := HomeAirport()
+	if code := home.Code(); code != m.airport {
+		// Landings elsewhere say nothing about the new airport
+		m.airport, m.landings = code, nil
+		clear(m.approaches)
+	}
+	for _, f := range flights {
+		track, following := m.approaches[f.Icao24]
+		if f.OnGround {
+			if following {
+				m.finish(f.Icao24, home)
+			}
+			continue
+		}
+		if f.AltitudeFt <= 0 || f.AltitudeFt > finalMaxFt || Distance(home.Lat, home.Lon, f.Lat, f.Lon) > finalRadiusKm {
+			continue
+		}
+		m.approaches[f.Icao24] = append(track, TrackPoint{Lat: f.Lat, Lon: f.Lon, AltitudeFt: f.AltitudeFt, Time: now})
+	}
+	for icao, track := range m.approaches {
+		if Elapsed(track[len(track)-1].Time, now) > finalLostAfter {
+			m.finish(icao, home)
+		}
+	}
+
+	drop := 0
+	for drop < len(m.landings) && Elapsed(m.landings[drop].Time, now) > runwayLayoutWindow {
+		drop++
+	}
+	m.landings = m.landings[drop:]
+}
+
+// finish stops following an approach, keeping it if it was a landing.
+// Caller must hold m.mu.
+func (m *RunwayMonitor) finish(icao24 string, airport Airport) {
+	if ld, ok := ReadLanding(m.approaches[icao24], airport); ok {
+		m.landings = append(m.landings, ld)
+	}
+	delete(m.approaches, icao24)
+}
+
+// InUse is the runways in use now, going by the landings seen and the
+// wind in sky, which may be nil
+func (m *RunwayMonitor) InUse(sky *Sky, now time.Time) RunwaysInUse {
+	if m == nil {
+		return RunwaysInUse{}
+	}
+	m.mu.Lock()
+	landings := slices.Clone(m.landings)
+	m.mu.Unlock()
+	return InferRunways(landings, HomeAirport(), sky, now)
+}
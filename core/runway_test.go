@@ -0,0 +1,248 @@
+package core
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
+// The fixtures were recorded around Helsinki-Vantaa, the default home
+// airport, and end at runwayFixtureNow. Arrivals land on the parallels
+// 22L and 22R, or the other way round on 04R and 04L.
+var runwayFixtureNow = time.Date(2025, 6, 14, 12, 0, 0, 0, time.UTC)
+
+func loadRunwayTracks(t *testing.T, name string) map[string][]TrackPoint {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tracks map[string][]TrackPoint
+	if err := json.Unmarshal(data, &tracks); err != nil {
+		t.Fatal(err)
+	}
+	return tracks
+}
+
+func readLandings(t *testing.T, name string) []Landing {
+	t.Helper()
+	var landings []Landing
+	for _, track := range loadRunwayTracks(t, name) {
+		if ld, ok := ReadLanding(track, defaultHomeAirport); ok {
+			landings = append(landings, ld)
+		}
+	}
+	slices.SortFunc(landings, func(a, b Landing) int { return a.Time.Compare(b.Time) })
+	return landings
+}
+
+func TestReadLanding(t *testing.T) {
+	tracks := loadRunwayTracks(t, "runway_south_flow.json")
+	for icao, track := range tracks {
+		ld, ok := ReadLanding(track, defaultHomeAirport)
+		switch icao {
+		case "461d22", "406b1f":
+			if ok {
+				t.Errorf("%s: read a landing from a departure or overflight: %+v", icao, ld)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%s: no landing read from an arrival", icao)
+			continue
+		}
+		if courseDiff(ld.CourseDeg, 232) > 3 {
+			t.Errorf("%s: course %.0f, want about 232 despite the turn onto final", icao, ld.CourseDeg)
+		}
+		if want := track[len(track)-1].Time; !ld.Time.Equal(want) {
+			t.Errorf("%s: time %v, want the last point's %v", icao, ld.Time, want)
+		}
+	}
+}
+
+func TestReadLandingRejectsShortTracks(t *testing.T) {
+	track := loadRunwayTracks(t, "runway_south_flow.json")["461e01"]
+	if _, ok := ReadLanding(track[len(track)-2:], defaultHomeAirport); ok {
+		t.Error("read a landing from under a km of final")
+	}
+	if _, ok := ReadLanding(nil, defaultHomeAirport); ok {
+		t.Error("read a landing from no track")
+	}
+}
+
+func TestInferRunwaysParallels(t *testing.T) {
+	landings := readLandings(t, "runway_south_flow.json")
+	r := InferRunways(landings, defaultHomeAirport, nil, runwayFixtureNow)
+	if want := []string{"22L", "22R"}; !slices.Equal(r.Arrivals, want) {
+		t.Fatalf("arrivals = %v, want %v", r.Arrivals, want)
+	}
+	if r.Landings != 6 {
+		t.Errorf("read from %d landings, want 6", r.Landings)
+	}
+	if got, want := r.String(), "Arrivals RWY 22L/22R"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestInferRunwaysHeadwind(t *testing.T) {
+	landings := readLandings(t, "runway_south_flow.json")
+	sky := &Sky{WindDirDeg: 230, WindKt: 12}
+	r := InferRunways(landings, defaultHomeAirport, sky, runwayFixtureNow)
+	if r.HeadwindKt < 11 || r.HeadwindKt > 12 {
+		t.Errorf("headwind = %d kt, want nearly all of 12", r.HeadwindKt)
+	}
+	sky.WindDirDeg = 50
+	if r := InferRunways(landings, defaultHomeAirport, sky, runwayFixtureNow); r.HeadwindKt > -11 {
+		t.Errorf("headwind = %d kt, want a tailwind of about 12", r.HeadwindKt)
+	}
+}
+
+func TestInferRunwaysTurnaround(t *testing.T) {
+	landings := readLandings(t, "runway_changeover.json")
+
+	// Two of the last three landed north, onto the same runway as 22L
+	r := InferRunways(landings, defaultHomeAirport, nil, runwayFixtureNow)
+	if want := []string{"04R"}; !slices.Equal(r.Arrivals, want) {
+		t.Errorf("arrivals without wind = %v, want %v", r.Arrivals, want)
+	}
+	r = InferRunways(landings, defaultHomeAirport, &Sky{WindDirDeg: 40, WindKt: 14}, runwayFixtureNow)
+	if want := []string{"04R"}; !slices.Equal(r.Arrivals, want) || r.Landings != 2 {
+		t.Errorf("arrivals with a northerly = %v from %d, want %v from 2", r.Arrivals, r.Landings, want)
+	}
+	// The wind has the last word while both ends are in use
+	r = InferRunways(landings, defaultHomeAirport, &Sky{WindDirDeg: 220, WindKt: 9}, runwayFixtureNow)
+	if want := []string{"22L"}; !slices.Equal(r.Arrivals, want) {
+		t.Errorf("arrivals with a southerly = %v, want %v", r.Arrivals, want)
+	}
+}
+
+func TestInferRunwaysByWind(t *testing.T) {
+	landings := readLandings(t, "runway_south_flow.json")
+	later := runwayFixtureNow.Add(time.Hour)
+
+	r := InferRunways(landings, defaultHomeAirport, &Sky{WindDirDeg: 60, WindKt: 15}, later)
+	if want := []string{"04L", "04R"}; !slices.Equal(r.Arrivals, want) {
+		t.Fatalf("arrivals = %v, want %v", r.Arrivals, want)
+	}
+	if got, want := r.String(), "Arrivals RWY 04L/04R (by wind)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	for _, sky := range []*Sky{nil, {WindDirDeg: 60, WindKt: 3}, {WindDirDeg: -1, WindKt: 8}} {
+		if r := InferRunways(landings, defaultHomeAirport, sky, later); r.String() != "" {
+			t.Errorf("sky %+v: %q, want nothing without a steady wind", sky, r)
+		}
+	}
+	if r := InferRunways(landings, defaultHomeAirport, &Sky{WindDirDeg: 60, WindKt: 15}, runwayFixtureNow.Add(runwayLayoutWindow+time.Hour)); r.String() != "" {
+		t.Errorf("%q from landings older than the layout is kept", r)
+	}
+}
+
+func TestRunwayNumber(t *testing.T) {
+	for _, c := range []struct {
+		course, declination float64
+		want                int
+	}{
+		{232, 12, 22},
+		{52, 12, 4},
+		{4, 0, 36},
+		{2, 10, 35},
+		{355, -8, 36},
+		{185, 5, 18},
+	} {
+		if got := runwayNumber(c.course, c.declination); got != c.want {
+			t.Errorf("runwayNumber(%v, %v) = %d, want %d", c.course, c.declination, got, c.want)
+		}
+	}
+}
+
+func TestRunwayMonitor(t *testing.T) {
+	tracks := loadRunwayTracks(t, "runway_south_flow.json")
+
+	// Replay the fixture poll by poll, as flights, ending each arrival on
+	// the ground
+	type sample struct {
+		icao string
+		p    TrackPoint
+	}
+	var samples []sample
+	for icao, track := range tracks {
+		for _, p := range track {
+			samples = append(samples, sample{icao, p})
+		}
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].p.Time.Before(samples[j].p.Time) })
+
+	m := NewRunwayMonitor()
+	for i := 0; i < len(samples); {
+		at := samples[i].p.Time
+		var flights []Flight
+		for ; i < len(samples) && samples[i].p.Time.Equal(at); i++ {
+			s := samples[i]
+			flights = append(flights, Flight{Icao24: s.icao, Lat: s.p.Lat, Lon: s.p.Lon, AltitudeFt: s.p.AltitudeFt})
+		}
+		m.Observe(flights, at)
+	}
+	m.Observe(nil, runwayFixtureNow.Add(2*finalLostAfter))
+
+	r := m.InUse(nil, runwayFixtureNow)
+	if want := []string{"22L", "22R"}; !slices.Equal(r.Arrivals, want) || r.Landings != 6 {
+		t.Errorf("arrivals = %v from %d, want %v from 6", r.Arrivals, r.Landings, want)
+	}
+
+	var nilMonitor *RunwayMonitor
+	nilMonitor.Observe([]Flight{{Icao24: "461e01"}}, runwayFixtureNow)
+	if r := nilMonitor.InUse(nil, runwayFixtureNow); r.String() != "" {
+		t.Errorf("nil monitor = %q", r)
+	}
+}
+
+func TestCourseDiff(t *testing.T) {
+	for _, c := range [][3]float64{{10, 350, 20}, {350, 10, 20}, {232, 52, 180}, {90, 90, 0}} {
+		if got := courseDiff(c[0], c[1]); math.Abs(got-c[2]) > 1e-9 {
+			t.Errorf("courseDiff(%v, %v) = %v, want %v", c[0], c[1], got, c[2])
+		}
+	}
+}
+
+func TestArrivalQueue(t *testing.T) {
+	r := InferRunways(readLandings(t, "runway_south_flow.json"), defaultHomeAirport, nil, runwayFixtureNow)
+	final := r.Approaches[0]
+	if final.Runway != "22L" {
+		t.Fatalf("first approach onto %s, want 22L", final.Runway)
+	}
+	if d := Distance(final.Lat, final.Lon, defaultHomeAirport.Lat, defaultHomeAirport.Lon); d > 3 {
+		t.Errorf("touchdown %.1f km from the airport", d)
+	}
+
+	onFinal := func(icao string, outKm float64) Flight {
+		lat, lon := final.Point(outKm, 0.1)
+		return Flight{Icao24: icao, Callsign: icao, Lat: lat, Lon: lon, AltitudeFt: int(outKm * 300), Heading: final.CourseDeg, VerticalRateFpm: -700}
+	}
+	lat, lon := final.Point(40, 10)
+	joining := Flight{Icao24: "join", Callsign: "join", Lat: lat, Lon: lon, AltitudeFt: 9000, Heading: final.CourseDeg + 40, VerticalRateFpm: -1200}
+	lat, lon = final.Point(5, 0)
+	departing := Flight{Icao24: "dep", Callsign: "dep", Lat: lat, Lon: lon, AltitudeFt: 3000, Heading: final.CourseDeg + 180, VerticalRateFpm: 2500}
+
+	queue := ArrivalQueue([]Flight{onFinal("third", 18), onFinal("first", 6), departing, joining, onFinal("second", 11)}, r.Approaches[:1], defaultHomeAirport)
+	var got []string
+	for _, q := range queue {
+		got = append(got, q.Flight.Icao24)
+	}
+	if want := []string{"first", "second", "third", "join"}; !slices.Equal(got, want) {
+		t.Fatalf("queue = %v, want %v", got, want)
+	}
+	if q := queue[1]; !q.Established || q.Number != 2 || math.Abs(q.SpacingKm-5) > 0.1 {
+		t.Errorf("second in line = %+v, want established 5 km behind the first", q)
+	}
+	if q := queue[3]; q.Established || q.ToGoKm < 40 {
+		t.Errorf("joining = %+v, want not yet established and further than its 40 km out", q)
+	}
+	if got, want := queue[1].Line(false), "2. second    11.0 km  +5.0"; got != want {
+		t.Errorf("Line() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Migration upgrades a stored payload by exactly one schema version
+type Migration func(data json.RawMessage) (json.RawMessage, error)
+
+// migrations lists the upgrade steps for every persisted file. The step at
+// index v moves a payload from version v to v+1, so a file's current version
+// is simply len(migrations[file]). Version 0 is the original bare JSON written
+// before versioning existed.
+//
+// To change a stored struct: append a step here that rewrites the old JSON
+// into the new shape. Old files are upgraded transparently on next load.
+var migrations = map[string][]Migration{
+	usersFile:            {wrapLegacy},
+	airportsFile:         {wrapLegacy, airportNamesToRecords},
+	scoreHistoryFile:     {wrapLegacy, scoreDateTimestamp},
+	polarRangeFile:       {wrapLegacy},
+	settingsFile:         {wrapLegacy},
+	trackHistoryRecord:   {wrapLegacy},
+	gamesFile:            {wrapLegacy},
+	achievementsFile:     {wrapLegacy},
+	lastFlightsFile:      {wrapLegacy},
+	routeCacheFile:       {wrapLegacy},
+	noiseFile:            {wrapLegacy},
+	overheadFile:         {wrapLegacy},
+	spottingFile:         {wrapLegacy},
+	airportDBFile:        {wrapLegacy},
+	routeDBFile:          {wrapLegacy},
+	learnedRoutesFile:    {wrapLegacy},
+	touchCalibrationFile: {wrapLegacy},
+}
+
+// versionedDoc is the on-disk envelope for every persisted payload
+type versionedDoc struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// wrapLegacy is the 0 -> 1 step; the payload shape is unchanged, it only
+// gains the envelope.
+func wrapLegacy(data json.RawMessage) (json.RawMessage, error) {
+	return data, nil
+}
+
+// scoreDateTimestamp is the 1 -> 2 step for score history lines: the date
+// was a bare "2006-01-02" in the device's timezone and becomes an RFC3339
+// timestamp at that local midnight
+func scoreDateTimestamp(data json.RawMessage) (json.RawMessage, error) {
+	var entry map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	var date string
+	if err := json.Unmarshal(entry["date"], &date); err != nil {
+		return data, nil // not a string, leave it alone
+	}
+	day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		delete(entry, "date") // unparseable; a zero time beats a failed load
+	} else {
+		entry["date"], _ = json.Marshal(day.Format(time.RFC3339))
+	}
+	return json.Marshal(entry)
+}
+
+func schemaVersion(name string) int {
+	return len(migrations[name])
+}
+
+// decodeVersioned splits raw file content into its version and payload.
+// Content without an envelope is treated as version 0.
+func decodeVersioned(raw []byte) (int, json.RawMessage) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &probe); err == nil {
+		v, hasVersion := probe["schemaVersion"]
+		data, hasData := probe["data"]
+		if hasVersion && hasData && len(probe) == 2 {
+			var version int
+			if err := json.Unmarshal(v, &version); err == nil {
+				return version, data
+			}
+		}
+	}
+	return 0, raw
+}
+
+// migrate runs the registered steps to bring payload up to the current version
+func migrate(name string, version int, payload json.RawMessage) (json.RawMessage, error) {
+	steps := migrations[name]
+	if version > len(steps) {
+		return nil, fmt.Errorf("%s has schema version %d, newer than supported %d", name, version, len(steps))
+	}
+	if version < 0 {
+		return nil, fmt.Errorf("%s has invalid schema version %d", name, version)
+	}
+
+	for v := version; v < len(steps); v++ {
+		upgraded, err := steps[v](payload)
+		if err != nil {
+			return nil, fmt.Errorf("migrating %s from v%d: %w", name, v, err)
+		}
+		payload = upgraded
+	}
+	return payload, nil
+}
+
+// decodeRecord migrates and unmarshals a single versioned value, e.g. one line of a log
+func decodeRecord(name string, raw []byte, out interface{}) error {
+	version, payload := decodeVersioned(raw)
+	payload, err := migrate(name, version, payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(payload, out)
+}
+
+// encodeRecord marshals v inside an envelope at the current version
+func encodeRecord(name string, v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(versionedDoc{SchemaVersion: schemaVersion(name), Data: data})
+}
+
+// readDocument loads a versioned JSON file into out, upgrading it on disk if it
+// was written by an older version. A missing file leaves out untouched and
+// returns os.ErrNotExist. Caller must hold dm.mu.
+func (dm *DataManager) readDocument(name string, out interface{}) error {
+	raw, err := dm.storage().ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	version, payload := decodeVersioned(raw)
+	payload, err = migrate(name, version, payload)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(payload, out); err != nil {
+		return err
+	}
+
+	if version < schemaVersion(name) {
+		// Persist the upgrade so the migration only runs once
+		if err := dm.writeDocument(name, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeDocument saves v as a versioned JSON file. Caller must hold dm.mu.
+func (dm *DataManager) writeDocument(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	doc, err := json.MarshalIndent(versionedDoc{SchemaVersion: schemaVersion(name), Data: data}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return dm.storage().WriteFile(name, doc)
+}
@@ -0,0 +1,29 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+// A version out of range is an error from every loader, never a panic
+func TestSchemaVersionOutOfRange(t *testing.T) {
+	for _, version := range []string{"-1", "-9223372036854775808", "99"} {
+		doc := `{"schemaVersion":` + version + `,"data":{}}`
+
+		store := NewMemoryStorage()
+		store.WriteFile(usersFile, []byte(doc))
+		store.WriteFile(settingsFile, []byte(doc))
+		dm := NewDataManager(store)
+
+		if _, err := dm.LoadUsers(); err == nil || !strings.Contains(err.Error(), version) {
+			t.Errorf("users at version %s: %v", version, err)
+		}
+		if _, err := dm.LoadSettings(); err == nil {
+			t.Errorf("settings at version %s loaded", version)
+		}
+		var entry ScoreEntry
+		if err := decodeRecord(scoreHistoryFile, []byte(doc), &entry); err == nil {
+			t.Errorf("score at version %s decoded", version)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const installKeyFile = "install_key.json"
+
+// Why a submission was refused by ScoreSubmission.Verify
+var (
+	ErrBadSignature    = errors.New("score signature doesn't match")
+	ErrSessionMismatch = errors.New("session hash doesn't match the game")
+	ErrImplausible     = errors.New("score is more than the game's answers could earn")
+)
+
+// installKey is the saved form of the key pair
+type installKey struct {
+	Seed []byte `json:"seed"` // ed25519 private key seed
+}
+
+// InstallKey is this install's signing key, made on first use and kept in
+// the data directory. A remote leaderboard can tie the scores it receives
+// to the install that sent them, and refuse any that aren't signed by a
+// key it has seen before or that is banned.
+func (dm *DataManager) InstallKey() (ed25519.PrivateKey, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	var k installKey
+	err := dm.readDocument(installKeyFile, &k)
+	if err == nil && len(k.Seed) == ed25519.SeedSize {
+		return ed25519.NewKeyFromSeed(k.Seed), nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	k.Seed = make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(k.Seed); err != nil {
+		return nil, err
+	}
+	if err := dm.writeDocument(installKeyFile, k); err != nil {
+		return nil, err
+	}
+	return ed25519.NewKeyFromSeed(k.Seed), nil
+}
+
+// SessionHash fingerprints a finished game: who played it, when, its
+// score and every question with the options shown and whether it was
+// answered right. Changing any of them after the fact changes the hash.
+func SessionHash(rec GameRecord) string {
+	// Marshalling a struct is deterministic: fields in declaration order
+	b, _ := json.Marshal(rec)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// MaxGameScore is the most a game with these rounds could score: full
+// time bonus on every right answer, plus the arrival bonus
+func MaxGameScore(rec GameRecord) int {
+	right := 0
+	for _, r := range rec.Rounds {
+		if r.Right {
+			right++
+		}
+	}
+	return right*(roundBasePoints+roundMaxBonus) + ArrivalBonusPoints
+}
+
+// ScoreSubmission is a score as sent to a remote leaderboard: the game it
+// was earned in, its session hash, and a signature over both by the
+// install's key, so the server can reject scores edited on the way or
+// made up without playing
+type ScoreSubmission struct {
+	Game        GameRecord `json:"game"`
+	SessionHash string     `json:"session_hash"`
+	PublicKey   string     `json:"public_key"` // base64 ed25519
+	Signature   string     `json:"signature"`  // base64, over signedPayload
+}
+
+// signedPayload is what a submission's signature covers
+func (s ScoreSubmission) signedPayload() []byte {
+	return fmt.Appendf(nil, "flight-monitor score v1\n%s\n%s\n%s\n%d\n%d\n%s",
+		s.Game.Player, s.Game.Date, s.PublicKey, s.Game.Seed, s.Game.Score, s.SessionHash)
+}
+
+// SignScore prepares rec for submission, signed with key
+func SignScore(rec GameRecord, key ed25519.PrivateKey) ScoreSubmission {
+	s := ScoreSubmission{
+		Game:        rec,
+		SessionHash: SessionHash(rec),
+		PublicKey:   base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+	}
+	s.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, s.signedPayload()))
+	return s
+}
+
+// Verify is the server's side: the signature must be by the key the
+// submission names, the session hash must be the game's, and the score
+// must be one the game's answers could earn. Whether the key is trusted
+// is for the server to decide.
+func (s ScoreSubmission) Verify() error {
+	pub, err := base64.StdEncoding.DecodeString(s.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return fmt.Errorf("bad public key: %w", ErrBadSignature)
+	}
+	sig, err := base64.StdEncoding.DecodeString(s.Signature)
+	if err != nil || !ed25519.Verify(pub, s.signedPayload(), sig) {
+		return ErrBadSignature
+	}
+	if SessionHash(s.Game) != s.SessionHash {
+		return ErrSessionMismatch
+	}
+	if s.Game.Score < 0 || s.Game.Score > MaxGameScore(s.Game) {
+		return ErrImplausible
+	}
+	return nil
+}
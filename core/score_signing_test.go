@@ -0,0 +1,152 @@
+package core
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func signingGame() GameRecord {
+	rounds := []RoundRecord{
+		{Callsign: "FIN7LV", Lat: 60.3, Lon: 24.9, Question: "Where is it flying from?", Correct: "Oslo", Options: []string{"Riga", "Oslo", "Tallinn", "Umeå"}, Right: true},
+		{Callsign: "SAS1719", Lat: 60.4, Lon: 25.1, Question: "Where is it flying to?", Correct: "Stockholm", Options: []string{"Stockholm", "Turku", "Kemi", "Vaasa"}},
+	}
+	return GameRecord{Date: "2025-06-14", Seed: 1234, Player: "Äijä", Score: 180, Rounds: rounds}
+}
+
+func TestInstallKeyIsKept(t *testing.T) {
+	dm := NewDataManager(NewMemoryStorage())
+	k1, err := dm.InstallKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k2, err := dm.InstallKey()
+	if err != nil || !k1.Equal(k2) {
+		t.Errorf("second InstallKey = a different key, %v", err)
+	}
+}
+
+func TestScoreSubmissionRoundTrip(t *testing.T) {
+	key, err := NewDataManager(NewMemoryStorage()).InstallKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := SignScore(signingGame(), key)
+	if err := sub.Verify(); err != nil {
+		t.Fatalf("signed submission: %v", err)
+	}
+
+	// Through JSON, as the server gets it
+	b, err := json.Marshal(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got ScoreSubmission
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := got.Verify(); err != nil {
+		t.Errorf("after JSON: %v", err)
+	}
+}
+
+func TestScoreSubmissionTampered(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(nil)
+	for _, c := range []struct {
+		name   string
+		tamper func(*ScoreSubmission)
+		want   error
+	}{
+		{"score raised", func(s *ScoreSubmission) { s.Game.Score = 190 }, ErrBadSignature},
+		{"player renamed", func(s *ScoreSubmission) { s.Game.Player = "Eve" }, ErrBadSignature},
+		{"hash swapped", func(s *ScoreSubmission) { s.SessionHash = SessionHash(GameRecord{}) }, ErrBadSignature},
+		{"round flipped", func(s *ScoreSubmission) { s.Game.Rounds[1].Right = true }, ErrSessionMismatch},
+		{"options edited", func(s *ScoreSubmission) { s.Game.Rounds[0].Options[0] = "Oslo" }, ErrSessionMismatch},
+		{"signature cut", func(s *ScoreSubmission) { s.Signature = s.Signature[:20] }, ErrBadSignature},
+	} {
+		sub := SignScore(signingGame(), key)
+		c.tamper(&sub)
+		if err := sub.Verify(); !errors.Is(err, c.want) {
+			t.Errorf("%s: %v, want %v", c.name, err, c.want)
+		}
+	}
+
+	// Signed, but more than the answers could earn
+	rec := signingGame()
+	rec.Score = MaxGameScore(rec) + 1
+	if err := SignScore(rec, key).Verify(); !errors.Is(err, ErrImplausible) {
+		t.Errorf("score of %d from one right answer: %v", rec.Score, err)
+	}
+}
+
+func TestScoreSubmissionWrongKey(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(nil)
+	_, other, _ := ed25519.GenerateKey(nil)
+	sub := SignScore(signingGame(), key)
+
+	// Claiming another install's key
+	sub.PublicKey = SignScore(signingGame(), other).PublicKey
+	if err := sub.Verify(); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("signed by one key, naming another: %v", err)
+	}
+	sub.PublicKey = "not base64!"
+	if err := sub.Verify(); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("bad public key: %v", err)
+	}
+}
+
+func TestScoreSync(t *testing.T) {
+	var got []ScoreSubmission
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var sub ScoreSubmission
+		if err := json.NewDecoder(r.Body).Decode(&sub); err != nil || sub.Verify() != nil {
+			http.Error(w, "bad submission", http.StatusBadRequest)
+			return
+		}
+		if status == http.StatusOK {
+			got = append(got, sub)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	_, key, _ := ed25519.GenerateKey(nil)
+	s := newScoreSync(srv.URL, key)
+	first, second := signingGame(), signingGame()
+	second.Seed, second.Score = 99, 0
+	s.Submit(first)
+	s.Submit(second)
+
+	// Kept while the server is down, then sent in order
+	s.flush(context.Background())
+	if s.Pending() != 2 || len(got) != 0 {
+		t.Fatalf("%d pending and %d sent while the server is down, want 2 and 0", s.Pending(), len(got))
+	}
+	status = http.StatusOK
+	s.flush(context.Background())
+	if s.Pending() != 0 || !slices.EqualFunc(got, []GameRecord{first, second}, func(sub ScoreSubmission, rec GameRecord) bool {
+		return sub.Game.Seed == rec.Seed && sub.SessionHash == SessionHash(rec)
+	}) {
+		t.Errorf("%d pending, sent %+v", s.Pending(), got)
+	}
+
+	// A refused submission isn't sent again
+	status = http.StatusForbidden
+	s.Submit(first)
+	s.flush(context.Background())
+	if s.Pending() != 0 {
+		t.Errorf("%d pending after the server refused it", s.Pending())
+	}
+
+	var nilSync *ScoreSync
+	nilSync.Submit(first)
+	if nilSync.Pending() != 0 {
+		t.Error("nil sync has pending submissions")
+	}
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Unsent submissions are tried again this often
+	scoreSyncRetry = 5 * time.Minute
+	// At most this many are kept while the leaderboard can't be reached,
+	// dropping the oldest
+	scoreSyncMaxPending = 100
+)
+
+// ScoreSync sends every finished game to a remote leaderboard, signed with
+// the install's key and with its session hash (see ScoreSubmission), so the
+// server can reject scores that were forged or edited on the way.
+//
+// It is configured by LEADERBOARD_URL, which each submission is POSTed to
+// as JSON. Submissions the server can't be reached for are kept and tried
+// again for as long as the app runs; ones it refuses are dropped.
+//
+// A nil *ScoreSync does nothing.
+type ScoreSync struct {
+	url    string
+	key    ed25519.PrivateKey
+	client *http.Client
+	wake   chan struct{}
+
+	mu      sync.Mutex
+	pending []ScoreSubmission
+}
+
+// NewScoreSync is configured from the environment, signing with dm's
+// install key. It returns nil when LEADERBOARD_URL isn't set or is "off".
+func NewScoreSync(dm *DataManager) (*ScoreSync, error) {
+	url := strings.TrimSpace(os.Getenv("LEADERBOARD_URL"))
+	if url == "" || strings.EqualFold(url, "off") {
+		return nil, nil
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("LEADERBOARD_URL %q: want an http(s) URL", url)
+	}
+	key, err := dm.InstallKey()
+	if err != nil {
+		return nil, fmt.Errorf("install key: %w", err)
+	}
+	return newScoreSync(url, key), nil
+}
+
+func newScoreSync(url string, key ed25519.PrivateKey) *ScoreSync {
+	return &ScoreSync{
+		url:    url,
+		key:    key,
+		client: &http.Client{Timeout: 30 * time.Second},
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Submit signs rec and queues it to be sent
+func (s *ScoreSync) Submit(rec GameRecord) {
+	if s == nil {
+		return
+	}
+	sub := SignScore(rec, s.key)
+	s.mu.Lock()
+	s.pending = append(s.pending, sub)
+	if len(s.pending) > scoreSyncMaxPending {
+		s.pending = s.pending[len(s.pending)-scoreSyncMaxPending:]
+	}
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Pending is how many submissions are waiting to be sent
+func (s *ScoreSync) Pending() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending)
+}
+
+// Run sends submissions as they are queued until ctx is cancelled
+func (s *ScoreSync) Run(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	for {
+		select {
+		case <-s.wake:
+		case <-time.After(scoreSyncRetry):
+		case <-ctx.Done():
+			return
+		}
+		s.flush(ctx)
+	}
+}
+
+// flush sends the queued submissions in order, stopping at the first the
+// server can't be reached for
+func (s *ScoreSync) flush(ctx context.Context) {
+	for {
+		s.mu.Lock()
+		if len(s.pending) == 0 {
+			s.mu.Unlock()
+			return
+		}
+		sub := s.pending[0]
+		s.mu.Unlock()
+
+		retry, err := s.send(ctx, sub)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Leaderboard: %s's score of %d: %v", sub.Game.Player, sub.Game.Score, err)
+			if retry {
+				return
+			}
+		}
+		s.mu.Lock()
+		if len(s.pending) > 0 && s.pending[0].Signature == sub.Signature {
+			s.pending = s.pending[1:]
+		}
+		s.mu.Unlock()
+	}
+}
+
+// send POSTs one submission. retry is false when the server refused it,
+// as sending it again won't change its mind.
+func (s *ScoreSync) send(ctx context.Context, sub ScoreSubmission) (retry bool, err error) {
+	body, err := json.Marshal(sub)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", UserAgent())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	switch {
+	case resp.StatusCode/100 == 2:
+		return false, nil
+	case resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests:
+		return false, fmt.Errorf("refused: %s", resp.Status)
+	}
+	return true, fmt.Errorf("server: %s", resp.Status)
+}
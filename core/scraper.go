@@ -0,0 +1,371 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Defaults for the scraper pool: a couple of pages at a time and six a
+// minute sustained, with room for a short burst of clicks
+const (
+	defaultScrapeWorkers = 2
+	defaultScrapePerMin  = 6
+	scrapeBurst          = 3
+	scrapeQueueLen       = 16
+
+	// A callsign FlightAware had nothing on isn't scraped again for this
+	// long: it won't have appeared since, and asking again only holds up
+	// the next round
+	scrapeMissTTL = 15 * time.Minute
+)
+
+// ErrScrapeBusy is returned when the scrape queue is full; the lookup is
+// dropped rather than piling more requests onto FlightAware
+var ErrScrapeBusy = errors.New("scraper busy")
+
+// ResolvedDetails contains the scraped flight information
+type ResolvedDetails struct {
+	Destination     string    `json:"destination"`
+	RealDestination string    `json:"real_destination"`
+	Model           string    `json:"model"`
+	Origin          string    `json:"origin"`
+	Registration    string    `json:"registration,omitempty"` // from resolvers that know the airframe
+	FirstFlight     time.Time `json:"first_flight,omitzero"`  // likewise, or from the aircraft database
+	Airline         string    `json:"airline,omitempty"`
+	DestLat         float64   `json:"dest_lat,omitempty"` // destination airport, from resolvers that know it
+	DestLon         float64   `json:"dest_lon,omitempty"`
+	OrigLat         float64   `json:"orig_lat,omitempty"` // origin airport, likewise
+	OrigLon         float64   `json:"orig_lon,omitempty"`
+	DepartureTime   time.Time `json:"departure_time,omitzero"` // actual or estimated, from resolvers that know the schedule
+	ArrivalTime     time.Time `json:"arrival_time,omitzero"`
+}
+
+// Scraper handles fetching data from external websites. Lookups go
+// through a small pool of workers that share a token bucket, so a burst of
+// selections can't get the IP blocked.
+type Scraper struct {
+	ctx    context.Context // the pool's life
+	client *http.Client
+	jobs   chan scrapeJob
+	bucket *tokenBucket
+
+	mu     sync.Mutex
+	misses map[string]time.Time // callsign -> when to try it again
+}
+
+// scrapeJob is one queued lookup; the worker answers on done
+type scrapeJob struct {
+	ctx      context.Context
+	callsign string
+	done     chan scrapeResult
+}
+
+type scrapeResult struct {
+	details *ResolvedDetails
+	err     error
+}
+
+// NewScraper starts the worker pool, which stops when ctx is cancelled.
+// SCRAPER_WORKERS sets how many pages are fetched at once and
+// SCRAPER_PER_MIN how many may be fetched a minute.
+func NewScraper(ctx context.Context) *Scraper {
+	workers, perMin := defaultScrapeWorkers, defaultScrapePerMin
+	if v, err := strconv.Atoi(os.Getenv("SCRAPER_WORKERS")); err == nil && v > 0 {
+		workers = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SCRAPER_PER_MIN")); err == nil && v > 0 {
+		perMin = v
+	}
+	s := &Scraper{
+		ctx: ctx,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		jobs:   make(chan scrapeJob, scrapeQueueLen),
+		bucket: newTokenBucket(scrapeBurst, time.Minute/time.Duration(perMin)),
+		misses: make(map[string]time.Time),
+	}
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+	return s
+}
+
+// FetchFlightDetails scrapes FlightAware for destination and model info.
+// The lookup waits its turn in the pool; it is abandoned, queued or not,
+// when ctx is cancelled. Callsigns that recently had no data are answered
+// with ErrRouteUnknown without a fetch.
+func (s *Scraper) FetchFlightDetails(ctx context.Context, callsign string) (*ResolvedDetails, error) {
+	if err := s.ctx.Err(); err != nil {
+		return nil, err // the pool has stopped
+	}
+	if s.missed(callsign, time.Now()) {
+		return nil, fmt.Errorf("%w: no FlightAware data for %s lately", ErrRouteUnknown, callsign)
+	}
+	job := scrapeJob{ctx: ctx, callsign: callsign, done: make(chan scrapeResult, 1)}
+	select {
+	case s.jobs <- job:
+	default:
+		return nil, ErrScrapeBusy
+	}
+	select {
+	case r := <-job.done:
+		return r.details, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+// work runs queued lookups until the pool's ctx is cancelled, then answers
+// whatever is still queued with its error
+func (s *Scraper) work() {
+	for {
+		select {
+		case job := <-s.jobs:
+			s.run(job)
+		case <-s.ctx.Done():
+			for {
+				select {
+				case job := <-s.jobs:
+					job.done <- scrapeResult{err: s.ctx.Err()}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// run does one lookup, skipping it if the caller has given up so a stale
+// click doesn't spend a token
+func (s *Scraper) run(job scrapeJob) {
+	if err := job.ctx.Err(); err != nil {
+		job.done <- scrapeResult{err: err}
+		return
+	}
+	if err := s.bucket.Wait(job.ctx); err != nil {
+		job.done <- scrapeResult{err: err}
+		return
+	}
+	if s.missed(job.callsign, time.Now()) { // a lookup queued ahead found nothing
+		job.done <- scrapeResult{err: fmt.Errorf("%w: no FlightAware data for %s lately", ErrRouteUnknown, job.callsign)}
+		return
+	}
+	d, err := s.scrape(job.ctx, job.callsign)
+	if errors.Is(err, ErrRouteUnknown) {
+		s.remember(job.callsign, time.Now())
+	}
+	job.done <- scrapeResult{d, err}
+}
+
+// missed reports whether callsign had no data within scrapeMissTTL of now
+func (s *Scraper) missed(callsign string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return now.Before(s.misses[callsign])
+}
+
+// remember notes that callsign had no data at now, forgetting misses that
+// have expired so the map doesn't grow for the life of the app
+func (s *Scraper) remember(callsign string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c, until := range s.misses {
+		if !now.Before(until) {
+			delete(s.misses, c)
+		}
+	}
+	s.misses[callsign] = now.Add(scrapeMissTTL)
+}
+
+// scrape fetches and parses one flight page
+func (s *Scraper) scrape(ctx context.Context, callsign string) (*ResolvedDetails, error) {
+	url := fmt.Sprintf("https://www.flightaware.com/live/flight/%s", callsign)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mimic headers to avoid being blocked
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: no FlightAware page for %s", ErrRouteUnknown, callsign)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseTrackpollBootstrap(string(bodyBytes))
+}
+
+// flightTime reads the first of FlightAware's times objects that has a
+// time, preferring its actual time over the estimate and the estimate over
+// the schedule. The times are Unix seconds.
+func flightTime(objs ...interface{}) time.Time {
+	for _, obj := range objs {
+		m, _ := obj.(map[string]interface{})
+		for _, k := range []string{"actual", "estimated", "scheduled"} {
+			if s, ok := m[k].(float64); ok && s > 0 {
+				return time.Unix(int64(s), 0)
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// tokenBucket allows burst requests at once and then one per interval
+type tokenBucket struct {
+	mu       sync.Mutex
+	tokens   float64
+	burst    float64
+	interval time.Duration
+	last     time.Time
+}
+
+func newTokenBucket(burst int, interval time.Duration) *tokenBucket {
+	return &tokenBucket{tokens: float64(burst), burst: float64(burst), interval: interval, last: time.Now()}
+}
+
+// Wait takes a token, sleeping until one is free or ctx is cancelled
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens = min(b.burst, b.tokens+float64(now.Sub(b.last))/float64(b.interval))
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - b.tokens) * float64(b.interval))
+		b.mu.Unlock()
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Matches the trackpollBootstrap JSON object: trackpollBootstrap = { ... };
+var trackpollRe = regexp.MustCompile(`(?:var\s+)?trackpollBootstrap\s*=\s*({.+?});`)
+
+// parseTrackpollBootstrap pulls the latest flight's route and aircraft out
+// of a FlightAware flight page. A page without them is ErrRouteUnknown.
+func parseTrackpollBootstrap(page string) (*ResolvedDetails, error) {
+	matches := trackpollRe.FindStringSubmatch(page)
+	if len(matches) < 2 {
+		return nil, fmt.Errorf("%w: no data found in page", ErrRouteUnknown)
+	}
+
+	jsonStr := matches[1]
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonStr), &data); err != nil {
+		return nil, fmt.Errorf("failed to parse json: %v", err)
+	}
+
+	flightsData, ok := data["flights"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: no flights data structure", ErrRouteUnknown)
+	}
+
+	// Iterate over flight IDs (keys are opaque strings)
+	for _, flightData := range flightsData {
+		fd, ok := flightData.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		actLog, ok := fd["activityLog"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		fLog, ok := actLog["flights"].([]interface{})
+		if !ok || len(fLog) == 0 {
+			continue
+		}
+
+		// Use the first (latest) flight log entry
+		latest, ok := fLog[0].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		destData, _ := latest["destination"].(map[string]interface{})
+		aircraftData, _ := latest["aircraft"].(map[string]interface{})
+		originData, _ := latest["origin"].(map[string]interface{})
+
+		destName := ""
+		if v, ok := destData["friendlyLocation"].(string); ok {
+			destName = v
+		} else if v, ok := destData["iata"].(string); ok {
+			destName = v
+		}
+
+		model := ""
+		if v, ok := aircraftData["friendlyType"].(string); ok {
+			model = v
+		} else if v, ok := aircraftData["type"].(string); ok {
+			model = v
+		}
+
+		originName := ""
+		if v, ok := originData["friendlyLocation"].(string); ok {
+			originName = v
+		}
+
+		// Takeoff and landing are nearer the truth than the gate times
+		departure := flightTime(latest["takeoffTimes"], latest["gateDepartureTimes"])
+		arrival := flightTime(latest["landingTimes"], latest["gateArrivalTimes"])
+
+		airlineName := ""
+		if airlineData, ok := fd["airline"].(map[string]interface{}); ok {
+			if v, ok := airlineData["shortName"].(string); ok {
+				airlineName = v
+			}
+		}
+
+		// A record with nothing usable in it is as good as no record
+		if destName == "" && model == "" && originName == "" {
+			continue
+		}
+
+		// Return raw data, game logic handled in main.go
+		return &ResolvedDetails{
+			Destination:     destName, // Just use the real destination here
+			RealDestination: destName,
+			Model:           model,
+			Origin:          originName,
+			Airline:         airlineName,
+			DepartureTime:   departure,
+			ArrivalTime:     arrival,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: details not found in flight data", ErrRouteUnknown)
+}
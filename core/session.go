@@ -0,0 +1,182 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// ErrNoTargets means no flight in range could be turned into a question
+var ErrNoTargets = errors.New("no suitable flights for a question")
+
+// DetailsResolver looks up a flight's route and aircraft; *Scraper is one
+type DetailsResolver interface {
+	FetchFlightDetails(ctx context.Context, callsign string) (*ResolvedDetails, error)
+}
+
+// AircraftResolver can also identify an aircraft by its ICAO24 address,
+// for flights with a blank or generic callsign
+type AircraftResolver interface {
+	FetchAircraftDetails(ctx context.Context, icao24 string) (*ResolvedDetails, error)
+}
+
+// HasCallsign reports whether f's callsign names a flight that can be looked up
+func (f Flight) HasCallsign() bool {
+	cs := strings.TrimSpace(f.Callsign)
+	return cs != "" && cs != "N/A"
+}
+
+// CanResolve reports whether r can look f up: by callsign, or by address
+// when r is an AircraftResolver
+func CanResolve(r DetailsResolver, f Flight) bool {
+	_, byAddress := r.(AircraftResolver)
+	return f.HasCallsign() || byAddress
+}
+
+// ResolveFlight looks f up by callsign, or by ICAO24 address when it has
+// no usable callsign. Registration and age the resolvers don't know are
+// taken from f.
+func ResolveFlight(ctx context.Context, r DetailsResolver, f Flight) (*ResolvedDetails, error) {
+	var d *ResolvedDetails
+	var err error
+	switch ar, ok := r.(AircraftResolver); {
+	case f.HasCallsign():
+		d, err = r.FetchFlightDetails(ctx, f.Callsign)
+	case ok:
+		d, err = ar.FetchAircraftDetails(ctx, f.Icao24)
+	default:
+		return nil, ErrRouteUnknown
+	}
+	if err != nil || d == nil {
+		return d, err
+	}
+	return d.WithAirframe(f), nil
+}
+
+// QuizName is what a question calls f: its callsign, or failing that its
+// registration or address
+func QuizName(f Flight, d *ResolvedDetails) string {
+	switch {
+	case f.HasCallsign():
+		return f.Callsign
+	case d != nil && d.Registration != "":
+		return d.Registration
+	case f.Registration != "":
+		return f.Registration
+	}
+	return strings.ToUpper(f.Icao24)
+}
+
+type SessionState int
+
+const (
+	SessionIdle     SessionState = iota
+	SessionPlaying               // a question is waiting for an answer
+	SessionAnswered              // answered; call Next
+	SessionOver                  // all rounds played; call Finish
+)
+
+// Session runs the quiz without any UI: pick a live flight, resolve it, pose
+// a question, score the answer. The frontends drive the same steps
+// asynchronously around their render loops; headless tools and tests drive a
+// Session directly.
+type Session struct {
+	Provider FlightProvider
+	Resolver DetailsResolver
+	Data     *DataManager
+	Settings Settings
+	HomeLat  float64
+	HomeLon  float64
+	Player   string
+	// Airports names saved airports canonically; nil without an imported
+	// database
+	Airports *AirportDB
+
+	// TypeRounds mixes in aircraft type and airline questions, see PickRoundKind
+	TypeRounds bool
+
+	State      SessionState
+	Round      int // 1-based once started
+	Score      int
+	Difficulty Difficulty
+	Target     Flight
+	Question   Question
+}
+
+// Start resets the score and poses the first question
+func (s *Session) Start(ctx context.Context) error {
+	s.Round = 0
+	s.Score = 0
+	s.State = SessionIdle
+	return s.Next(ctx)
+}
+
+// Next moves to the following round, or to SessionOver after the last one
+func (s *Session) Next(ctx context.Context) error {
+	s.Round++
+	if s.Round > RoundsPerGame {
+		s.State = SessionOver
+		return nil
+	}
+
+	flights, err := FetchRegions(ctx, s.Provider, s.Settings, s.Settings.WatchRegions(s.HomeLat, s.HomeLon))
+	if err != nil {
+		return err
+	}
+	candidates := QuizCandidates(flights, s.Resolver)
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+
+	airports, err := s.Data.LoadAirports()
+	if err != nil {
+		return err
+	}
+
+	// Like the frontends, move on to another flight when one can't be resolved
+	for _, f := range candidates {
+		details, err := ResolveFlight(ctx, s.Resolver, f)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+		q, err := SetupRound(s.Data, s.Airports, f, details, RoundConfig{
+			Airports: s.Airports.QuizPool(airports),
+			Level:    s.Difficulty.Level,
+			Kind:     PickRoundKind(s.TypeRounds),
+			Strategy: DistractorStrategyFor(s.Player),
+		})
+		if err != nil {
+			continue
+		}
+		s.Target = f
+		s.Question = q
+		s.State = SessionPlaying
+		return nil
+	}
+	return ErrNoTargets
+}
+
+// Answer scores choice for the current question, taking elapsed from when it
+// was shown. An empty choice is a timeout.
+func (s *Session) Answer(choice string, elapsed time.Duration) (bool, error) {
+	if s.State != SessionPlaying {
+		return false, fmt.Errorf("no question to answer")
+	}
+	correct := choice == s.Question.Correct
+	s.Score += RoundScore(correct, elapsed, s.Difficulty.RoundTime())
+	s.Difficulty.Record(correct)
+	s.State = SessionAnswered
+	return correct, nil
+}
+
+// Finish saves the game for the player
+func (s *Session) Finish() (UserStats, error) {
+	if s.State != SessionOver {
+		return UserStats{}, fmt.Errorf("game not over")
+	}
+	return s.Data.FinishGame(s.Player, s.Score, s.Difficulty, time.Now())
+}
@@ -0,0 +1,220 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	settingsFile = "settings.json"
+
+	// DefaultRadiusKm is about the original 1 degree search box
+	DefaultRadiusKm = 111.0
+)
+
+// Choices offered by the settings screen. A poll interval of 0 means the
+// provider's own default.
+var (
+	PollIntervalSteps = []int{0, 1, 2, 5, 10, 15, 30, 60}
+	RadiusStepsKm     = []float64{25, 50, 75, 111, 150, 200, 300}
+	MinAltitudeSteps  = []int{0, 1000, 5000, 10000, 20000, 30000}
+	// Ceilings for alerts; 0 alerts at any altitude
+	AlertAltitudeSteps = []int{0, 2000, 4000, 6000, 10000, 20000}
+)
+
+// Settings holds user preferences that persist across restarts
+type Settings struct {
+	MagneticBearings bool         `json:"magnetic_bearings"`           // show bearings relative to magnetic north
+	PollIntervalSec  int          `json:"poll_interval_sec,omitempty"` // 0 = provider default
+	RadiusKm         float64      `json:"radius_km,omitempty"`
+	Regions          []Region     `json:"regions,omitempty"`           // extra watch regions besides home
+	Filter           FlightFilter `json:"filter"`                      // declutters what is fetched and shown
+	AlertFilter      FlightFilter `json:"alert_filter"`                // which shown flights may raise watchlist, tag and regular alerts
+	AlertInteresting bool         `json:"alert_interesting,omitempty"` // alert on military/test/livery aircraft
+	AirlineColors    bool         `json:"airline_colors,omitempty"`    // tint planes by carrier, with a legend
+	NoiseAltitudeFt  int          `json:"noise_altitude_ft,omitempty"` // ceiling for noise events, 0 = default
+	AlertRegulars    bool         `json:"alert_regulars,omitempty"`    // alert when a regular flight is early or late
+	ArrivalBonus     bool         `json:"arrival_bonus,omitempty"`     // end games with a guess-the-landing-time round
+	Retention        Retention    `json:"retention,omitzero"`          // days of logs, tracks, games and captures kept
+	HomeAirport      string       `json:"home_airport,omitempty"`      // code of the reference airport, "" = nearest major one
+	EcoMode          EcoMode      `json:"eco_mode,omitempty"`          // save energy on battery (default), always or never
+
+	env *envLayer // the environment overrides applied on loading, nil if none
+}
+
+// envLayer remembers the settings as read from settings.json and as
+// overridden by the environment, so the overrides are never saved
+type envLayer struct {
+	file, applied Settings
+}
+
+// RadiusDeg is the search radius as the degree box the providers take
+func (s Settings) RadiusDeg() float64 {
+	if s.RadiusKm <= 0 {
+		return KmToDegrees(DefaultRadiusKm)
+	}
+	return KmToDegrees(s.RadiusKm)
+}
+
+// PollInterval is the configured interval, or the provider's when unset,
+// stretched when the provider's credit budget wouldn't last at that rate
+func (s Settings) PollInterval(p FlightProvider) time.Duration {
+	return BudgetedInterval(s.ConfiguredPollInterval(p), p.RateLimitInfo(), s.pollCredits(p), time.Now())
+}
+
+// ConfiguredPollInterval is the interval before any budget stretching
+func (s Settings) ConfiguredPollInterval(p FlightProvider) time.Duration {
+	if s.PollIntervalSec <= 0 {
+		return PollInterval(p)
+	}
+	return time.Duration(s.PollIntervalSec) * time.Second
+}
+
+// pollCredits is what one poll of every watched region costs
+func (s Settings) pollCredits(p FlightProvider) int {
+	credits := 0
+	for _, r := range s.WatchRegions(0, 0) {
+		credits += RequestCost(p, s.RegionRadiusDeg(r))
+	}
+	return credits
+}
+
+// PollIntervalLabel describes the interval for the settings screen
+func (s Settings) PollIntervalLabel(p FlightProvider) string {
+	label := fmt.Sprintf("%ds", s.PollIntervalSec)
+	base := s.ConfiguredPollInterval(p)
+	if s.PollIntervalSec <= 0 {
+		label = fmt.Sprintf("Auto (%s)", base)
+	}
+	if actual := s.PollInterval(p); actual > base {
+		label += fmt.Sprintf(" > %s", actual.Round(time.Second))
+	}
+	return label
+}
+
+// AlertCeilingLabel describes the altitude alerts are limited to for the
+// settings screen
+func (s Settings) AlertCeilingLabel() string {
+	if s.AlertFilter.MaxAltitudeFt <= 0 {
+		return "Any"
+	}
+	return fmt.Sprintf("< %d ft", s.AlertFilter.MaxAltitudeFt)
+}
+
+// StepSetting moves cur one step up (dir > 0) or down through steps,
+// stopping at either end
+func StepSetting[T cmp.Ordered](steps []T, cur T, dir int) T {
+	i := 0
+	for i < len(steps)-1 && steps[i] < cur {
+		i++
+	}
+	if dir > 0 && i < len(steps)-1 {
+		i++
+	} else if dir < 0 && i > 0 {
+		i--
+	}
+	return steps[i]
+}
+
+// applyEnv lets POLL_INTERVAL (seconds), SEARCH_RADIUS_KM, WATCH_REGIONS and
+// HOME_AIRPORT override the file for this run
+func (s *Settings) applyEnv() {
+	file := *s
+	defer func() { s.env = &envLayer{file: file, applied: *s} }()
+	if v, err := strconv.Atoi(os.Getenv("POLL_INTERVAL")); err == nil && v >= 0 {
+		s.PollIntervalSec = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("SEARCH_RADIUS_KM"), 64); err == nil && v > 0 {
+		s.RadiusKm = v
+	}
+	if spec := os.Getenv("WATCH_REGIONS"); spec != "" {
+		if regions, err := ParseRegions(spec); err == nil {
+			s.Regions = regions
+		} else {
+			log.Println("Ignoring WATCH_REGIONS:", err)
+		}
+	}
+	if v := strings.ToUpper(strings.TrimSpace(os.Getenv("HOME_AIRPORT"))); v != "" {
+		s.HomeAirport = v
+	}
+	if s.RadiusKm <= 0 {
+		s.RadiusKm = DefaultRadiusKm
+	}
+}
+
+// LoadSettings reads settings.json, returning defaults if it doesn't exist yet.
+// Environment overrides are applied on top.
+func (dm *DataManager) LoadSettings() (Settings, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	var s Settings
+	if err := dm.readDocument(settingsFile, &s); err != nil && !os.IsNotExist(err) {
+		s.applyEnv()
+		return s, err
+	}
+	s.applyEnv()
+	return s, nil
+}
+
+// SaveSettings writes settings.json. Fields still holding an environment
+// override keep the file's own value; ones changed since loading are saved.
+func (dm *DataManager) SaveSettings(s Settings) error {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	return dm.writeDocument(settingsFile, s.withoutEnv())
+}
+
+// withoutEnv undoes the environment overrides on the fields left as they were
+func (s Settings) withoutEnv() Settings {
+	out := s
+	out.env = nil
+	if s.env == nil {
+		return out
+	}
+	file, applied := s.env.file, s.env.applied
+	if s.PollIntervalSec == applied.PollIntervalSec {
+		out.PollIntervalSec = file.PollIntervalSec
+	}
+	if s.RadiusKm == applied.RadiusKm {
+		out.RadiusKm = file.RadiusKm
+	}
+	if slices.Equal(s.Regions, applied.Regions) {
+		out.Regions = file.Regions
+	}
+	if s.HomeAirport == applied.HomeAirport {
+		out.HomeAirport = file.HomeAirport
+	}
+	return out
+}
+
+// SettingsStore shares the live settings between the UI and the poll loop
+type SettingsStore struct {
+	mu sync.RWMutex
+	s  Settings
+}
+
+func NewSettingsStore(s Settings) *SettingsStore {
+	return &SettingsStore{s: s}
+}
+
+func (ss *SettingsStore) Get() Settings {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	return ss.s
+}
+
+// Update applies fn to the settings and returns the result
+func (ss *SettingsStore) Update(fn func(*Settings)) Settings {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	fn(&ss.s)
+	return ss.s
+}
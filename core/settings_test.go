@@ -0,0 +1,41 @@
+package core
+
+import "testing"
+
+// Environment overrides apply for the run but never reach settings.json
+func TestSettingsEnvNotSaved(t *testing.T) {
+	dm := NewDataManager(NewMemoryStorage())
+	if err := dm.SaveSettings(Settings{PollIntervalSec: 30, RadiusKm: 75, HomeAirport: "EFHK"}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("POLL_INTERVAL", "2")
+	t.Setenv("SEARCH_RADIUS_KM", "300")
+	t.Setenv("HOME_AIRPORT", "essa")
+	t.Setenv("WATCH_REGIONS", "Tallinn:59.41,24.83")
+	s, err := dm.LoadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.PollIntervalSec != 2 || s.RadiusKm != 300 || s.HomeAirport != "ESSA" || len(s.Regions) != 1 {
+		t.Fatalf("overrides not applied: %+v", s)
+	}
+
+	// Saving another change keeps the file's values under the overrides,
+	// and a field changed in the app is saved even though it was overridden
+	s.AirlineColors = true
+	s.RadiusKm = 200
+	if err := dm.SaveSettings(s); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"POLL_INTERVAL", "SEARCH_RADIUS_KM", "HOME_AIRPORT", "WATCH_REGIONS"} {
+		t.Setenv(v, "")
+	}
+	saved, err := dm.LoadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.PollIntervalSec != 30 || saved.RadiusKm != 200 || saved.HomeAirport != "EFHK" || saved.Regions != nil || !saved.AirlineColors {
+		t.Errorf("after the overrides are unset: %+v", saved)
+	}
+}
@@ -0,0 +1,260 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	simFlightCount = 14
+	// A poll after a longer pause than this moves the sky on by this much only
+	simMaxStep = time.Minute
+)
+
+// simRoute is a destination the simulated traffic flies to or from
+type simRoute struct {
+	airline string  // ICAO designator used for callsigns
+	city    string  // as the resolvers would name it
+	bearing float64 // direction from home, degrees
+	model   string
+	typ     string
+}
+
+var simRoutes = []simRoute{
+	{"FIN", "Stockholm, Sweden", 265, "Airbus A321", "A321"},
+	{"FIN", "London, United Kingdom", 240, "Airbus A350-900", "A359"},
+	{"FIN", "Oulu, Finland", 355, "ATR 72-500", "AT75"},
+	{"FIN", "Tokyo, Japan", 40, "Airbus A350-900", "A359"},
+	{"SAS", "Copenhagen, Denmark", 240, "Airbus A320neo", "A20N"},
+	{"NSZ", "Oslo, Norway", 275, "Boeing 737-800", "B738"},
+	{"DLH", "Frankfurt, Germany", 220, "Airbus A321", "A321"},
+	{"KLM", "Amsterdam, Netherlands", 235, "Boeing 737-800", "B738"},
+	{"AFR", "Paris, France", 230, "Airbus A320", "A320"},
+	{"BTI", "Riga, Latvia", 200, "Airbus A220-300", "BCS3"},
+	{"AEE", "Athens, Greece", 190, "Airbus A321neo", "A21N"},
+	{"THY", "Istanbul, Turkey", 170, "Boeing 737 MAX 8", "B38M"},
+	{"QTR", "Doha, Qatar", 155, "Boeing 787-8", "B788"},
+	{"RYR", "Dublin, Ireland", 250, "Boeing 737-800", "B738"},
+}
+
+type simKind int
+
+const (
+	simDeparture simKind = iota
+	simArrival
+	simOverflight
+)
+
+// simFlight is one simulated aircraft with where it is heading
+type simFlight struct {
+	Flight
+	kind         simKind
+	origin, dest string
+	model        string
+	targetAltFt  int
+	targetKts    int
+}
+
+// SimulatedProvider invents believable traffic around the first point it is
+// asked about: departures climbing out of home, arrivals descending into it
+// and overflights crossing at cruise, each changing speed and height as a
+// real flight would. It also answers route lookups for its own callsigns, so
+// the quiz works without internet or API credentials.
+type SimulatedProvider struct {
+	mu        sync.Mutex
+	rng       *rand.Rand
+	flights   []*simFlight
+	byCall    map[string]*simFlight
+	lat, lon  float64
+	radiusKm  float64
+	last      time.Time
+	nextIcao  int
+	generated bool
+}
+
+func NewSimulatedProvider() *SimulatedProvider {
+	return &SimulatedProvider{
+		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		byCall:   make(map[string]*simFlight),
+		nextIcao: 0xf00000,
+	}
+}
+
+func (p *SimulatedProvider) Name() string { return "sim" }
+
+// RateLimitInfo never limits: the sky is free
+func (p *SimulatedProvider) RateLimitInfo() RateLimitInfo {
+	return RateLimitInfo{Remaining: -1}
+}
+
+// FetchFlights advances the simulation to now and returns the flights in the
+// box. The sky is built around the first box asked about; other boxes see
+// whatever strays into them.
+func (p *SimulatedProvider) FetchFlights(ctx context.Context, centerLat, centerLon, radiusDeg float64) ([]Flight, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if !p.generated {
+		p.generated = true
+		p.lat, p.lon = centerLat, centerLon
+		p.radiusKm = radiusDeg * 111.32
+		p.last = now
+		for i := 0; i < simFlightCount; i++ {
+			// Spread the first flights along their paths so the map isn't
+			// empty in the middle while the first arrivals fly in
+			p.spawn(p.rng.Float64())
+		}
+	}
+	if dt := min(Elapsed(p.last, now), simMaxStep); dt > 0 {
+		p.step(dt.Seconds())
+	}
+	p.last = now
+
+	var flights []Flight
+	for _, f := range p.flights {
+		if math.Abs(f.Lat-centerLat) <= radiusDeg && math.Abs(f.Lon-centerLon) <= radiusDeg {
+			flights = append(flights, f.Flight)
+		}
+	}
+	return flights, nil
+}
+
+// FetchFlightDetails answers for the simulated callsigns, so the provider
+// can stand in for the resolvers too
+func (p *SimulatedProvider) FetchFlightDetails(ctx context.Context, callsign string) (*ResolvedDetails, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	f, ok := p.byCall[strings.TrimSpace(callsign)]
+	if !ok {
+		return nil, ErrRouteUnknown
+	}
+	return &ResolvedDetails{
+		Destination:     f.dest,
+		RealDestination: f.dest,
+		Origin:          f.origin,
+		Model:           f.model,
+		Airline:         AirlineName(callsign),
+	}, nil
+}
+
+// spawn adds a flight, progress (0-1) of the way along its path through the area
+func (p *SimulatedProvider) spawn(progress float64) {
+	route := simRoutes[p.rng.Intn(len(simRoutes))]
+	f := &simFlight{model: route.model}
+	f.Icao24 = fmt.Sprintf("%06x", p.nextIcao)
+	p.nextIcao++
+	f.Category = "Large"
+	f.Source = p.Name()
+	f.TypeCode = route.typ
+	for {
+		f.Callsign = fmt.Sprintf("%s%d", route.airline, 100+p.rng.Intn(900))
+		if _, taken := p.byCall[f.Callsign]; !taken {
+			break
+		}
+	}
+
+	// Departures leave from the home airport and arrivals land there, named
+	// like the resolvers name it so inbound questions still work
+	home := HomeAirport().Place()
+	span := 2 * p.radiusKm
+	switch f.kind = simKind(p.rng.Intn(3)); f.kind {
+	case simDeparture:
+		f.origin, f.dest = home, route.city
+		f.Heading = route.bearing
+		f.Lat, f.Lon = Destination(p.lat, p.lon, f.Heading, progress*p.radiusKm)
+		f.AltitudeFt = 1500 + int(progress*30000)
+		f.VelocityKts = 220 + int(progress*200)
+		f.targetAltFt, f.targetKts = 34000+1000*p.rng.Intn(5), 440+p.rng.Intn(40)
+	case simArrival:
+		f.origin, f.dest = route.city, home
+		f.Lat, f.Lon = Destination(p.lat, p.lon, route.bearing, (1-progress)*p.radiusKm)
+		f.Heading = math.Mod(route.bearing+180, 360)
+		f.AltitudeFt = 3000 + int((1-progress)*22000)
+		f.VelocityKts = 180 + int((1-progress)*240)
+		f.targetAltFt, f.targetKts = 2000, 160
+	case simOverflight:
+		// Enter on the route's side and cross the area at a slant
+		other := route
+		for other.city == route.city {
+			other = simRoutes[p.rng.Intn(len(simRoutes))]
+		}
+		f.origin, f.dest = route.city, other.city
+		f.Heading = math.Mod(route.bearing+180+p.rng.Float64()*60-30, 360)
+		f.Lat, f.Lon = Destination(p.lat, p.lon, route.bearing, p.radiusKm)
+		f.Lat, f.Lon = Destination(f.Lat, f.Lon, f.Heading, progress*span)
+		f.AltitudeFt = 33000 + 2000*p.rng.Intn(4)
+		f.VelocityKts = 430 + p.rng.Intn(60)
+		f.targetAltFt, f.targetKts = f.AltitudeFt, f.VelocityKts
+	}
+	f.GeoAltitudeFt = f.AltitudeFt + 150
+	p.flights = append(p.flights, f)
+	p.byCall[f.Callsign] = f
+}
+
+// step moves every flight on by dt seconds, retires those that have landed
+// or left the area, and spawns replacements
+func (p *SimulatedProvider) step(dt float64) {
+	kept := p.flights[:0]
+	for _, f := range p.flights {
+		// Occasionally ATC asks for a different speed or level
+		if f.kind == simOverflight && p.rng.Float64() < dt/120 {
+			f.targetKts += p.rng.Intn(41) - 20
+			f.targetAltFt = min(max(f.targetAltFt+2000*(p.rng.Intn(3)-1), 29000), 41000)
+		}
+		if f.kind == simArrival {
+			// Arrivals home in on the airport, slowing and descending on a
+			// profile that reaches the circuit as they get there
+			share := Distance(f.Lat, f.Lon, p.lat, p.lon) / p.radiusKm
+			f.targetKts = 160 + int(260*share)
+			f.targetAltFt = min(2000+int(24000*share), f.AltitudeFt)
+			want := Bearing(f.Lat, f.Lon, p.lat, p.lon)
+			turn := math.Mod(want-f.Heading+540, 360) - 180
+			f.Heading = math.Mod(f.Heading+math.Max(-3*dt, math.Min(3*dt, turn))+360, 360)
+		}
+
+		f.VelocityKts = approach(f.VelocityKts, f.targetKts, 2*dt)
+		rate := 0.0
+		if f.AltitudeFt != f.targetAltFt {
+			rate = 1800
+			if f.targetAltFt < f.AltitudeFt {
+				rate = -1200
+			}
+		}
+		before := f.AltitudeFt
+		f.AltitudeFt = approach(f.AltitudeFt, f.targetAltFt, math.Abs(rate)*dt/60)
+		f.GeoAltitudeFt = f.AltitudeFt + 150
+		f.VerticalRateFpm = int(math.Round(float64(f.AltitudeFt-before) * 60 / dt))
+		f.Lat, f.Lon = Destination(f.Lat, f.Lon, f.Heading, float64(f.VelocityKts)*1.852*dt/3600)
+
+		dist := Distance(p.lat, p.lon, f.Lat, f.Lon)
+		if dist > p.radiusKm*1.2 || (f.kind == simArrival && dist < 3) {
+			delete(p.byCall, f.Callsign)
+			continue
+		}
+		kept = append(kept, f)
+	}
+	clear(p.flights[len(kept):])
+	p.flights = kept
+
+	for len(p.flights) < simFlightCount {
+		p.spawn(0)
+	}
+}
+
+// approach moves cur towards target by at most maxStep
+func approach(cur, target int, maxStep float64) int {
+	step := int(math.Max(1, maxStep))
+	switch {
+	case cur < target:
+		return min(cur+step, target)
+	case cur > target:
+		return max(cur-step, target)
+	}
+	return cur
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// Past this slant range an airliner is a dot even in clear air
+	spotMaxRangeKm = 40
+	// Closer to the horizon than this, trees and buildings are in the way
+	spotMinElevationDeg = 3
+	// A report older than this says little about the sky now
+	skyMaxAge = 3 * time.Hour
+)
+
+var compassPoints = [...]string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"}
+
+// CompassPoint names the 8-point compass direction of a bearing, e.g. "NE"
+func CompassPoint(bearingDeg float64) string {
+	return compassPoints[int(math.Mod(bearingDeg+22.5+360, 360)/45)%8]
+}
+
+// Spot says whether a flight should be visible with the naked eye from home
+type Spot struct {
+	Visible bool
+	Reason  string // e.g. "visible low to the NE" or "above clouds (BKN 2500 ft)"
+}
+
+func (s Spot) String() string {
+	return s.Reason
+}
+
+// Spotability combines the flight's altitude and position relative to home
+// with the sky report. A nil or stale sky is taken as clear, and said so.
+func Spotability(f Flight, sky *Sky, homeLat, homeLon float64, now time.Time) Spot {
+	if f.OnGround {
+		return Spot{Reason: "on the ground"}
+	}
+	if sky != nil && Elapsed(sky.Observed, now) > skyMaxAge {
+		sky = nil
+	}
+
+	groundKm := Distance(homeLat, homeLon, f.Lat, f.Lon)
+	altKm := float64(f.AltitudeFt) * 0.0003048
+	slantKm := math.Hypot(groundKm, altKm)
+	elevation := math.Atan2(altKm, groundKm) * 180 / math.Pi
+
+	switch {
+	case elevation < spotMinElevationDeg:
+		return Spot{Reason: "too low on the horizon"}
+	case sky != nil && sky.CeilingFt > 0 && f.AltitudeFt > sky.CeilingFt:
+		return Spot{Reason: fmt.Sprintf("above clouds (%s %d ft)", sky.CeilingCover, sky.CeilingFt)}
+	case sky != nil && slantKm > sky.VisibilityKm:
+		return Spot{Reason: fmt.Sprintf("lost in haze (vis %.0f km)", sky.VisibilityKm)}
+	case slantKm > spotMaxRangeKm:
+		return Spot{Reason: fmt.Sprintf("too far to see (%.0f km)", slantKm)}
+	}
+
+	where := "low to the " + CompassPoint(Bearing(homeLat, homeLon, f.Lat, f.Lon))
+	switch {
+	case elevation >= 70:
+		where = "overhead"
+	case elevation >= 30:
+		where = "high to the " + CompassPoint(Bearing(homeLat, homeLon, f.Lat, f.Lon))
+	}
+	if sky == nil {
+		return Spot{Visible: true, Reason: where + ", if clear"}
+	}
+	return Spot{Visible: true, Reason: "visible " + where}
+}
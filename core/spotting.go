@@ -0,0 +1,274 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png" // photos picked from disk
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	spottingFile = "spotting.jsonl"
+	spottingDir  = "spotting" // photos attached to diary entries, inside the data dir
+
+	// Photos bigger than this are not something a phone or camera export
+	// should produce; refuse rather than fill the disk
+	maxSpottingPhotoBytes = 20 << 20
+
+	// SpottingNoteMax is the longest note kept, in bytes
+	SpottingNoteMax = 140
+)
+
+// SpottingEntry is a note, and optionally a photo, about one overhead pass:
+// the personal side of the overhead log. Entries are appended; the latest
+// one for a pass replaces the rest, and one with neither note nor photo
+// removes it from the diary.
+type SpottingEntry struct {
+	Callsign    string    `json:"callsign"`
+	Icao24      string    `json:"icao24"`
+	At          time.Time `json:"at"` // the pass's closest approach
+	Note        string    `json:"note,omitempty"`
+	Photo       string    `json:"photo,omitempty"`        // file name in the spotting dir
+	PhotoCredit string    `json:"photo_credit,omitempty"` // for fetched thumbnails
+	Written     time.Time `json:"written"`
+}
+
+// SpottingEntryFor finds the diary's entry about pass, or starts an empty
+// one
+func SpottingEntryFor(diary []SpottingEntry, pass OverheadPass) SpottingEntry {
+	e := SpottingEntry{Callsign: pass.Callsign, Icao24: pass.Icao24, At: pass.At}
+	for _, d := range diary {
+		if d.key() == e.key() {
+			return d
+		}
+	}
+	return e
+}
+
+// key identifies the pass an entry is about
+func (e SpottingEntry) key() string {
+	return e.Icao24 + "@" + e.At.UTC().Format(time.RFC3339)
+}
+
+// Empty reports whether the entry has nothing worth keeping
+func (e SpottingEntry) Empty() bool {
+	return strings.TrimSpace(e.Note) == "" && e.Photo == ""
+}
+
+// SaveSpottingEntry appends e to the diary
+func (dm *DataManager) SaveSpottingEntry(e SpottingEntry) error {
+	e.Note = strings.TrimSpace(e.Note)
+	if len(e.Note) > SpottingNoteMax {
+		e.Note = strings.ToValidUTF8(e.Note[:SpottingNoteMax], "")
+	}
+	e.Written = time.Now()
+	line, err := encodeRecord(spottingFile, e)
+	if err != nil {
+		return err
+	}
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	return dm.storage().Append(spottingFile, append(line, '\n'))
+}
+
+// LoadSpottingDiary reads the diary, newest pass first, with each pass's
+// latest entry
+func (dm *DataManager) LoadSpottingDiary() ([]SpottingEntry, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	file, err := dm.storage().Open(spottingFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	latest := make(map[string]SpottingEntry)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e SpottingEntry
+		if err := decodeRecord(spottingFile, scanner.Bytes(), &e); err != nil {
+			continue // torn line
+		}
+		latest[e.key()] = e
+	}
+	var entries []SpottingEntry
+	for _, e := range latest {
+		if !e.Empty() {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].At.After(entries[j].At) })
+	return entries, scanner.Err()
+}
+
+// SpottingGroup is a heading in the diary and the entries under it
+type SpottingGroup struct {
+	Title   string
+	Entries []SpottingEntry
+}
+
+// GroupSpottingByDate groups entries by the local day of the pass, newest
+// first, keeping the order within each day
+func GroupSpottingByDate(entries []SpottingEntry) []SpottingGroup {
+	var groups []SpottingGroup
+	for _, e := range entries {
+		day := e.At.Local().Format("Mon 2 Jan 2006")
+		if len(groups) == 0 || groups[len(groups)-1].Title != day {
+			groups = append(groups, SpottingGroup{Title: day})
+		}
+		groups[len(groups)-1].Entries = append(groups[len(groups)-1].Entries, e)
+	}
+	return groups
+}
+
+// GroupSpottingByAircraft groups entries by airframe, the most often
+// spotted first. The title is the airframe's latest callsign.
+func GroupSpottingByAircraft(entries []SpottingEntry) []SpottingGroup {
+	index := make(map[string]int)
+	var groups []SpottingGroup
+	for _, e := range entries {
+		i, ok := index[e.Icao24]
+		if !ok {
+			i = len(groups)
+			index[e.Icao24] = i
+			groups = append(groups, SpottingGroup{Title: fmt.Sprintf("%s (%s)", e.Callsign, strings.ToUpper(e.Icao24))})
+		}
+		groups[i].Entries = append(groups[i].Entries, e)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i].Entries) > len(groups[j].Entries) })
+	return groups
+}
+
+// SpottingPhotoDir is where photos to attach are picked from: the
+// SPOTTING_PHOTOS directory, or the user's Pictures folder
+func SpottingPhotoDir() string {
+	if dir := os.Getenv("SPOTTING_PHOTOS"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "."
+	}
+	return filepath.Join(home, "Pictures")
+}
+
+// SpottingPhotoCandidates lists the JPEG and PNG files in dir, newest
+// first, for the photo picker
+func SpottingPhotoCandidates(dir string) ([]string, error) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	type candidate struct {
+		path string
+		mod  time.Time
+	}
+	var cands []candidate
+	for _, de := range des {
+		switch strings.ToLower(filepath.Ext(de.Name())) {
+		case ".jpg", ".jpeg", ".png":
+		default:
+			continue
+		}
+		info, err := de.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		cands = append(cands, candidate{filepath.Join(dir, de.Name()), info.ModTime()})
+	}
+	sort.Slice(cands, func(i, j int) bool { return cands[i].mod.After(cands[j].mod) })
+	paths := make([]string, len(cands))
+	for i, c := range cands {
+		paths[i] = c.path
+	}
+	return paths, nil
+}
+
+// spottingPhotoName is the file a photo for e is stored under
+func spottingPhotoName(e SpottingEntry, ext string) string {
+	return fmt.Sprintf("%s-%d%s", strings.ToLower(e.Icao24), e.At.Unix(), ext)
+}
+
+// AttachSpottingFile copies the photo at src into the diary and attaches it
+// to e. It has to decode as an image, so a stray file can't be attached.
+func (dm *DataManager) AttachSpottingFile(e *SpottingEntry, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	data, err := io.ReadAll(io.LimitReader(in, maxSpottingPhotoBytes+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxSpottingPhotoBytes {
+		return fmt.Errorf("%s is too big for the diary", filepath.Base(src))
+	}
+	if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(src), err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(src))
+	if ext == ".jpeg" {
+		ext = ".jpg"
+	}
+	name := spottingPhotoName(*e, ext)
+	if err := dm.writeSpottingPhoto(name, data); err != nil {
+		return err
+	}
+	e.Photo, e.PhotoCredit = name, ""
+	return nil
+}
+
+// AttachSpottingThumbnail saves a fetched aircraft photo into the diary and
+// attaches it to e, keeping the photographer's credit with it
+func (dm *DataManager) AttachSpottingThumbnail(e *SpottingEntry, p *AircraftPhoto) error {
+	if p == nil || p.Image == nil {
+		return fmt.Errorf("no photo to attach")
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, p.Image, &jpeg.Options{Quality: 90}); err != nil {
+		return err
+	}
+	name := spottingPhotoName(*e, ".jpg")
+	if err := dm.writeSpottingPhoto(name, buf.Bytes()); err != nil {
+		return err
+	}
+	e.Photo, e.PhotoCredit = name, p.Credit()
+	return nil
+}
+
+func (dm *DataManager) writeSpottingPhoto(name string, data []byte) error {
+	return dm.storage().WriteFile(path.Join(spottingDir, name), data)
+}
+
+// LoadSpottingPhoto decodes the photo attached to e
+func (dm *DataManager) LoadSpottingPhoto(e SpottingEntry) (image.Image, error) {
+	if e.Photo == "" {
+		return nil, fmt.Errorf("no photo attached")
+	}
+	file, err := dm.storage().Open(path.Join(spottingDir, path.Base(filepath.ToSlash(e.Photo))))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	return img, err
+}
@@ -0,0 +1,685 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+)
+
+// A read-only reader for the SQLite file format, enough to look rows up in
+// an MBTiles archive without a cgo driver: table and index b-trees,
+// overflow pages and records. It doesn't read journals or WAL files, so
+// the database must not be written while it is open.
+
+const sqliteMagic = "SQLite format 3\x00"
+
+// B-tree page types
+const (
+	sqliteInteriorIndex = 2
+	sqliteInteriorTable = 5
+	sqliteLeafIndex     = 10
+	sqliteLeafTable     = 13
+)
+
+var errSQLiteCorrupt = errors.New("sqlite: malformed database")
+
+type sqliteDB struct {
+	r        io.ReaderAt
+	pageSize int
+	usable   int // page size less the reserved bytes at the end of each page
+	schema   []sqliteObject
+}
+
+// sqliteObject is a row of sqlite_master
+type sqliteObject struct {
+	Type  string // "table", "index" or "view"
+	Name  string
+	Table string
+	Root  int
+	SQL   string
+}
+
+func openSQLite(r io.ReaderAt) (*sqliteDB, error) {
+	hdr := make([]byte, 100)
+	if _, err := r.ReadAt(hdr, 0); err != nil {
+		return nil, fmt.Errorf("sqlite: reading header: %w", err)
+	}
+	if string(hdr[:16]) != sqliteMagic {
+		return nil, errors.New("sqlite: not a database")
+	}
+	if enc := binary.BigEndian.Uint32(hdr[56:]); enc > 1 {
+		return nil, errors.New("sqlite: only UTF-8 databases are supported")
+	}
+	db := &sqliteDB{r: r, pageSize: int(binary.BigEndian.Uint16(hdr[16:]))}
+	if db.pageSize == 1 {
+		db.pageSize = 65536
+	}
+	db.usable = db.pageSize - int(hdr[20])
+	if db.pageSize < 512 || db.usable < 480 {
+		return nil, errSQLiteCorrupt
+	}
+
+	err := db.scanTable(1, func(_ int64, rec []any) error {
+		if len(rec) < 5 {
+			return errSQLiteCorrupt
+		}
+		o := sqliteObject{Type: sqliteText(rec[0]), Name: sqliteText(rec[1]), Table: sqliteText(rec[2]), SQL: sqliteText(rec[4])}
+		if root, ok := rec[3].(int64); ok {
+			o.Root = int(root)
+		}
+		db.schema = append(db.schema, o)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func (db *sqliteDB) object(typ, name string) (sqliteObject, bool) {
+	for _, o := range db.schema {
+		if o.Type == typ && strings.EqualFold(o.Name, name) {
+			return o, true
+		}
+	}
+	return sqliteObject{}, false
+}
+
+// page reads page n, counting from 1
+func (db *sqliteDB) page(n int) ([]byte, error) {
+	if n < 1 {
+		return nil, errSQLiteCorrupt
+	}
+	p := make([]byte, db.pageSize)
+	if _, err := db.r.ReadAt(p, int64(n-1)*int64(db.pageSize)); err != nil {
+		return nil, fmt.Errorf("sqlite: reading page %d: %w", n, err)
+	}
+	return p, nil
+}
+
+// btreePage is a parsed b-tree page header
+type btreePage struct {
+	data   []byte
+	typ    byte
+	cells  []int   // offsets of the cells in data
+	rowids []int64 // keys of the cells; table pages only
+	right  int     // right-most child; interior pages only
+}
+
+func (db *sqliteDB) btree(n int) (btreePage, error) {
+	data, err := db.page(n)
+	if err != nil {
+		return btreePage{}, err
+	}
+	off := 0
+	if n == 1 {
+		off = 100 // past the file header
+	}
+	p := btreePage{data: data, typ: data[off]}
+	hdrLen := 8
+	switch p.typ {
+	case sqliteInteriorIndex, sqliteInteriorTable:
+		hdrLen = 12
+		p.right = int(binary.BigEndian.Uint32(data[off+8:]))
+	case sqliteLeafIndex, sqliteLeafTable:
+	default:
+		return btreePage{}, errSQLiteCorrupt
+	}
+	count := int(binary.BigEndian.Uint16(data[off+3:]))
+	ptrs := off + hdrLen
+	if ptrs+2*count > len(data) {
+		return btreePage{}, errSQLiteCorrupt
+	}
+	p.cells = make([]int, count)
+	if p.typ == sqliteInteriorTable || p.typ == sqliteLeafTable {
+		p.rowids = make([]int64, count)
+	}
+	for i := range p.cells {
+		off := int(binary.BigEndian.Uint16(data[ptrs+2*i:]))
+		p.cells[i] = off
+		// Check the start of the cell, up to its payload, is on the page
+		if p.typ == sqliteInteriorIndex || p.typ == sqliteInteriorTable {
+			off += 4 // the left child
+		}
+		if off > len(data) {
+			return btreePage{}, errSQLiteCorrupt
+		}
+		if p.typ != sqliteInteriorTable {
+			_, n := sqliteVarint(data[off:]) // payload size
+			if n == 0 {
+				return btreePage{}, errSQLiteCorrupt
+			}
+			off += n
+		}
+		if p.rowids != nil {
+			v, n := sqliteVarint(data[off:])
+			if n == 0 {
+				return btreePage{}, errSQLiteCorrupt
+			}
+			p.rowids[i] = int64(v)
+		}
+	}
+	return p, nil
+}
+
+// child is the page an interior cell points left to
+func (p btreePage) child(i int) int {
+	return int(binary.BigEndian.Uint32(p.data[p.cells[i]:]))
+}
+
+// rowid is the key of a table cell
+func (p btreePage) rowid(i int) int64 {
+	return p.rowids[i]
+}
+
+// payload is the record held by a leaf table cell or any index cell,
+// gathered from its overflow pages when it doesn't fit on the page
+func (db *sqliteDB) payload(p btreePage, i int) ([]byte, error) {
+	off := p.cells[i]
+	if p.typ == sqliteInteriorIndex {
+		off += 4
+	}
+	// btree has checked these varints are whole
+	size, n := sqliteVarint(p.data[off:])
+	off += n
+	if p.typ == sqliteLeafTable {
+		_, n = sqliteVarint(p.data[off:])
+		off += n
+	}
+	if size > math.MaxInt32 {
+		return nil, errSQLiteCorrupt
+	}
+
+	// How much of the payload is stored on the page, as the file format
+	// defines it
+	u := db.usable
+	maxLocal := u - 35
+	if p.typ != sqliteLeafTable {
+		maxLocal = (u-12)*64/255 - 23
+	}
+	minLocal := (u-12)*32/255 - 23
+	local := int(size)
+	if local > maxLocal {
+		local = minLocal + (int(size)-minLocal)%(u-4)
+		if local > maxLocal {
+			local = minLocal
+		}
+	}
+	if off+local > len(p.data) {
+		return nil, errSQLiteCorrupt
+	}
+	// The size is only as good as the file, so don't trust it with much
+	// memory up front
+	out := make([]byte, 0, min(int(size), 1<<20))
+	out = append(out, p.data[off:off+local]...)
+	if local == int(size) {
+		return out, nil
+	}
+
+	if off+local+4 > len(p.data) {
+		return nil, errSQLiteCorrupt
+	}
+	next := int(binary.BigEndian.Uint32(p.data[off+local:]))
+	for len(out) < int(size) {
+		if next == 0 {
+			return nil, errSQLiteCorrupt
+		}
+		data, err := db.page(next)
+		if err != nil {
+			return nil, err
+		}
+		next = int(binary.BigEndian.Uint32(data))
+		out = append(out, data[4:min(u, 4+int(size)-len(out))]...)
+	}
+	return out, nil
+}
+
+// scanTable calls fn for every row of the table b-tree at root, in rowid
+// order, stopping at the first error
+func (db *sqliteDB) scanTable(root int, fn func(rowid int64, rec []any) error) error {
+	return db.scanPage(root, fn, 0)
+}
+
+// sqliteMaxDepth is deeper than any real b-tree, so a corrupt one that
+// loops back on itself is caught
+const sqliteMaxDepth = 64
+
+func (db *sqliteDB) scanPage(n int, fn func(rowid int64, rec []any) error, depth int) error {
+	if depth >= sqliteMaxDepth {
+		return errSQLiteCorrupt
+	}
+	p, err := db.btree(n)
+	if err != nil {
+		return err
+	}
+	switch p.typ {
+	case sqliteInteriorTable:
+		for i := range p.cells {
+			if err := db.scanPage(p.child(i), fn, depth+1); err != nil {
+				return err
+			}
+		}
+		return db.scanPage(p.right, fn, depth+1)
+	case sqliteLeafTable:
+		for i := range p.cells {
+			data, err := db.payload(p, i)
+			if err != nil {
+				return err
+			}
+			rec, err := sqliteRecord(data)
+			if err != nil {
+				return err
+			}
+			if err := fn(p.rowid(i), rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return errSQLiteCorrupt
+}
+
+// row finds a row of the table b-tree at root by rowid
+func (db *sqliteDB) row(root int, rowid int64) ([]any, bool, error) {
+	for depth := 0; depth < sqliteMaxDepth; depth++ {
+		p, err := db.btree(root)
+		if err != nil {
+			return nil, false, err
+		}
+		switch p.typ {
+		case sqliteInteriorTable:
+			root = p.right
+			for i := range p.cells {
+				if rowid <= p.rowid(i) {
+					root = p.child(i)
+					break
+				}
+			}
+		case sqliteLeafTable:
+			for i := range p.cells {
+				if p.rowid(i) != rowid {
+					continue
+				}
+				data, err := db.payload(p, i)
+				if err != nil {
+					return nil, false, err
+				}
+				rec, err := sqliteRecord(data)
+				return rec, err == nil, err
+			}
+			return nil, false, nil
+		default:
+			return nil, false, errSQLiteCorrupt
+		}
+	}
+	return nil, false, errSQLiteCorrupt
+}
+
+// seek finds an entry of the index b-tree at root whose leading columns
+// equal key. Index entries end with the rowid of their row.
+func (db *sqliteDB) seek(root int, key []any) ([]any, bool, error) {
+	for depth := 0; depth < sqliteMaxDepth; depth++ {
+		p, err := db.btree(root)
+		if err != nil {
+			return nil, false, err
+		}
+		if p.typ != sqliteInteriorIndex && p.typ != sqliteLeafIndex {
+			return nil, false, errSQLiteCorrupt
+		}
+		next := p.right
+		for i := range p.cells {
+			data, err := db.payload(p, i)
+			if err != nil {
+				return nil, false, err
+			}
+			rec, err := sqliteRecord(data)
+			if err != nil {
+				return nil, false, err
+			}
+			c := sqliteCompare(rec, key)
+			if c < 0 {
+				continue
+			}
+			if c == 0 {
+				// Interior entries are index entries too. In a non-unique
+				// index equal ones may lie to the left, but any will do.
+				return rec, true, nil
+			}
+			next = p.child(i)
+			break
+		}
+		if p.typ == sqliteLeafIndex {
+			return nil, false, nil
+		}
+		root = next
+	}
+	return nil, false, errSQLiteCorrupt
+}
+
+// sqliteCompare orders an index entry against a key by the key's columns
+func sqliteCompare(rec, key []any) int {
+	for i, k := range key {
+		if i >= len(rec) {
+			return -1
+		}
+		if c := sqliteCompareValue(rec[i], k); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// sqliteCompareValue orders two values as SQLite does: NULLs, then
+// numbers, then text, then blobs. Text compares bytewise, as in the
+// default BINARY collation.
+func sqliteCompareValue(a, b any) int {
+	rank := func(v any) int {
+		switch v.(type) {
+		case nil:
+			return 0
+		case int64, float64:
+			return 1
+		case string:
+			return 2
+		}
+		return 3
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+	switch a := a.(type) {
+	case int64, float64:
+		fa, fb := sqliteFloat(a), sqliteFloat(b)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(a, b.(string))
+	case []byte:
+		return bytes.Compare(a, b.([]byte))
+	}
+	return 0
+}
+
+func sqliteFloat(v any) float64 {
+	if i, ok := v.(int64); ok {
+		return float64(i)
+	}
+	return v.(float64)
+}
+
+func sqliteText(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+// sqliteVarint decodes a big-endian varint of up to 9 bytes, returning it
+// and its length; 0 when b ends before the varint does
+func sqliteVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9 && i < len(b); i++ {
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// sqliteRecord decodes a record into nil, int64, float64, string and
+// []byte values
+func sqliteRecord(data []byte) ([]any, error) {
+	hdrLen, n := sqliteVarint(data)
+	if n == 0 || hdrLen < uint64(n) || hdrLen > uint64(len(data)) {
+		return nil, errSQLiteCorrupt
+	}
+	hdr, body := data[n:hdrLen], data[hdrLen:]
+	var rec []any
+	for len(hdr) > 0 {
+		typ, n := sqliteVarint(hdr)
+		if n == 0 {
+			return nil, errSQLiteCorrupt
+		}
+		hdr = hdr[n:]
+		size := 0
+		switch {
+		case typ >= 12:
+			if (typ-12)/2 > uint64(len(body)) {
+				return nil, errSQLiteCorrupt
+			}
+			size = int((typ - 12) / 2)
+		case typ >= 1 && typ <= 4:
+			size = int(typ)
+		case typ == 5:
+			size = 6
+		case typ == 6 || typ == 7:
+			size = 8
+		}
+		if size > len(body) {
+			return nil, errSQLiteCorrupt
+		}
+		v := body[:size]
+		body = body[size:]
+		switch {
+		case typ == 0:
+			rec = append(rec, nil)
+		case typ <= 6:
+			// Big-endian two's complement, sign-extended
+			var i int64
+			if size > 0 && v[0]&0x80 != 0 {
+				i = -1
+			}
+			for _, c := range v {
+				i = i<<8 | int64(c)
+			}
+			rec = append(rec, i)
+		case typ == 7:
+			rec = append(rec, math.Float64frombits(binary.BigEndian.Uint64(v)))
+		case typ == 8 || typ == 9:
+			rec = append(rec, int64(typ-8))
+		case typ >= 12 && typ%2 == 0:
+			rec = append(rec, bytes.Clone(v))
+		case typ >= 13:
+			rec = append(rec, string(v))
+		default:
+			return nil, errSQLiteCorrupt
+		}
+	}
+	return rec, nil
+}
+
+// sqliteTable looks rows up in one table by the values of some of its
+// columns, through an index on them when the database has one and
+// otherwise through a map of the table built on first use
+type sqliteTable struct {
+	db      *sqliteDB
+	root    int
+	columns []string // lower case
+	pk      int      // column that aliases the rowid, or -1
+
+	mu    sync.Mutex
+	byKey map[string]map[string]int64 // lookup columns, then values, to rowid
+}
+
+func (db *sqliteDB) table(name string) (*sqliteTable, error) {
+	o, ok := db.object("table", name)
+	if !ok {
+		return nil, fmt.Errorf("sqlite: no table %q", name)
+	}
+	t := &sqliteTable{db: db, root: o.Root, pk: -1}
+	for i, def := range sqliteDefinitions(o.SQL) {
+		words := strings.Fields(strings.ToLower(def))
+		if len(words) == 0 {
+			continue
+		}
+		switch words[0] {
+		case "constraint", "primary", "unique", "check", "foreign":
+			continue // a table constraint, not a column
+		}
+		t.columns = append(t.columns, strings.Trim(words[0], "\"`[]'"))
+		if strings.Contains(strings.Join(words[1:], " "), "integer primary key") {
+			t.pk = i
+		}
+	}
+	return t, nil
+}
+
+// column is the index of the named column, or -1
+func (t *sqliteTable) column(name string) int {
+	for i, c := range t.columns {
+		if c == strings.ToLower(name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// find is the row whose columns cols hold vals
+func (t *sqliteTable) find(cols []string, vals []any) ([]any, bool, error) {
+	if len(cols) == 1 && t.column(cols[0]) == t.pk && t.pk >= 0 {
+		id, ok := vals[0].(int64)
+		if !ok {
+			return nil, false, nil
+		}
+		return t.get(id)
+	}
+	if idx, ok := t.index(cols); ok {
+		entry, found, err := t.db.seek(idx.Root, vals)
+		if err != nil || !found {
+			return nil, false, err
+		}
+		id, ok := entry[len(entry)-1].(int64)
+		if !ok {
+			return nil, false, errSQLiteCorrupt
+		}
+		return t.get(id)
+	}
+
+	// No index: map the table once
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	colKey := strings.Join(cols, ",")
+	if t.byKey == nil {
+		t.byKey = make(map[string]map[string]int64)
+	}
+	m, ok := t.byKey[colKey]
+	if !ok {
+		m = make(map[string]int64)
+		pos := make([]int, len(cols))
+		for i, c := range cols {
+			if pos[i] = t.column(c); pos[i] < 0 {
+				return nil, false, fmt.Errorf("sqlite: no column %q", c)
+			}
+		}
+		err := t.db.scanTable(t.root, func(rowid int64, rec []any) error {
+			key := make([]any, len(pos))
+			for i, p := range pos {
+				if p < len(rec) {
+					key[i] = rec[p]
+				}
+			}
+			m[fmt.Sprint(key...)] = rowid
+			return nil
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		t.byKey[colKey] = m
+	}
+	id, ok := m[fmt.Sprint(vals...)]
+	if !ok {
+		return nil, false, nil
+	}
+	return t.get(id)
+}
+
+// get is the row with rowid id, with a rowid alias column filled in
+func (t *sqliteTable) get(id int64) ([]any, bool, error) {
+	rec, ok, err := t.db.row(t.root, id)
+	if ok && t.pk >= 0 && t.pk < len(rec) {
+		rec[t.pk] = id
+	}
+	return rec, ok, err
+}
+
+// index is an index on the table whose leading columns are cols
+func (t *sqliteTable) index(cols []string) (sqliteObject, bool) {
+	for _, o := range t.db.schema {
+		if o.Type != "index" || o.SQL == "" || t.db.rootTable(o.Table) != t.root {
+			continue
+		}
+		open := strings.Index(o.SQL, "(")
+		if open < 0 {
+			continue
+		}
+		defs := sqliteDefinitions(o.SQL[open:])
+		if len(defs) < len(cols) {
+			continue
+		}
+		match := true
+		for i, c := range cols {
+			words := strings.Fields(strings.ToLower(defs[i]))
+			if len(words) == 0 || strings.Trim(words[0], "\"`[]'") != strings.ToLower(c) {
+				match = false
+			} else if len(words) > 1 && (len(words) > 2 || words[1] != "asc") {
+				match = false // sorted by a collation or order seek doesn't know
+			}
+		}
+		if match {
+			return o, true
+		}
+	}
+	return sqliteObject{}, false
+}
+
+func (db *sqliteDB) rootTable(name string) int {
+	if o, ok := db.object("table", name); ok {
+		return o.Root
+	}
+	return 0
+}
+
+// sqliteDefinitions splits the first parenthesised list in a CREATE
+// statement at its top-level commas
+func sqliteDefinitions(sql string) []string {
+	open := strings.Index(sql, "(")
+	if open < 0 {
+		return nil
+	}
+	var defs []string
+	depth, start := 0, open+1
+	for i := open; i < len(sql); i++ {
+		switch sql[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return append(defs, strings.TrimSpace(sql[start:i]))
+			}
+		case ',':
+			if depth == 1 {
+				defs = append(defs, strings.TrimSpace(sql[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return defs
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// StartupStep is one piece of launch work done behind the splash screen
+type StartupStep struct {
+	Name string // shown while it runs, e.g. "Loading players"
+	Run  func(ctx context.Context) error
+}
+
+// StartupProgress is a snapshot of how far startup has got
+type StartupProgress struct {
+	Step     string // the step running now
+	Done     int
+	Total    int
+	Errors   []string // one per failed step, prefixed with its name
+	Finished bool
+}
+
+// Fraction is the share of steps done, for a progress bar
+func (p StartupProgress) Fraction() float64 {
+	if p.Total == 0 {
+		return 0
+	}
+	return float64(p.Done) / float64(p.Total)
+}
+
+// Startup runs the launch steps off the UI thread and reports progress, so
+// a slow disk or network shows a splash screen rather than a frozen window.
+// A failed step is recorded and startup carries on, since the app is still
+// usable without any one of them.
+type Startup struct {
+	mu sync.Mutex
+	p  StartupProgress
+}
+
+// Run runs steps in order, stopping early only if ctx is cancelled
+func (s *Startup) Run(ctx context.Context, steps []StartupStep) {
+	s.mu.Lock()
+	s.p = StartupProgress{Total: len(steps)}
+	s.mu.Unlock()
+
+	for _, step := range steps {
+		if ctx.Err() != nil {
+			break
+		}
+		s.mu.Lock()
+		s.p.Step = step.Name
+		s.mu.Unlock()
+
+		err := step.Run(ctx)
+
+		s.mu.Lock()
+		s.p.Done++
+		if err != nil {
+			s.p.Errors = append(s.p.Errors, fmt.Sprintf("%s: %v", step.Name, err))
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	s.p.Step = ""
+	s.p.Finished = true
+	s.mu.Unlock()
+}
+
+// Progress returns the current progress; safe to call from any goroutine
+func (s *Startup) Progress() StartupProgress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.p
+	p.Errors = append([]string(nil), s.p.Errors...)
+	return p
+}
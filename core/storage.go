@@ -0,0 +1,258 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Storage is where a DataManager keeps its files, by name relative to the
+// data directory, e.g. "users.json" or "spotting/abc.jpg". A name that
+// doesn't exist gives an error os.IsNotExist recognises.
+type Storage interface {
+	ReadFile(name string) ([]byte, error)
+	// WriteFile replaces name with data; a reader sees the old contents or
+	// the new, never half of them
+	WriteFile(name string, data []byte) error
+	// Append adds data to the end of name, creating it if needed
+	Append(name string, data []byte) error
+	// Open streams name, for logs too long to read whole
+	Open(name string) (io.ReadCloser, error)
+	Exists(name string) bool
+	Stat(name string) (StoredFile, error)
+	// List is every file in dir and the folders below it, sorted by name;
+	// "" lists them all. A dir that doesn't exist holds nothing.
+	List(dir string) ([]StoredFile, error)
+	Remove(name string) error
+}
+
+// StoredFile is a file in a Storage
+type StoredFile struct {
+	Name    string // relative to the data directory, with / between folders
+	Size    int64
+	ModTime time.Time
+}
+
+// FileStorage keeps files in a directory, by default ~/.flight-monitor-data
+type FileStorage struct {
+	Dir string // "" for the data directory
+}
+
+func (s FileStorage) path(name string) string {
+	if s.Dir == "" {
+		return dataPath(name)
+	}
+	return filepath.Join(s.Dir, name)
+}
+
+func (s FileStorage) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(s.path(name))
+}
+
+// WriteFile writes through a temporary file and renames it over name
+func (s FileStorage) WriteFile(name string, data []byte) error {
+	path := s.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s FileStorage) Append(name string, data []byte) error {
+	path := s.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(data)
+	return err
+}
+
+func (s FileStorage) Open(name string) (io.ReadCloser, error) {
+	return os.Open(s.path(name))
+}
+
+func (s FileStorage) Exists(name string) bool {
+	_, err := os.Stat(s.path(name))
+	return err == nil
+}
+
+func (s FileStorage) Stat(name string) (StoredFile, error) {
+	info, err := os.Stat(s.path(name))
+	if err != nil {
+		return StoredFile{}, err
+	}
+	return StoredFile{Name: name, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (s FileStorage) List(dir string) ([]StoredFile, error) {
+	root := s.path("")
+	var files []StoredFile
+	err := filepath.WalkDir(s.path(dir), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == s.path(dir) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, StoredFile{Name: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	return files, err
+}
+
+func (s FileStorage) Remove(name string) error {
+	return os.Remove(s.path(name))
+}
+
+// displayPath is where name is, to tell the user: a path on disk for a
+// FileStorage
+func displayPath(s Storage, name string) string {
+	if f, ok := s.(FileStorage); ok {
+		return f.path(name)
+	}
+	return name
+}
+
+// MemoryStorage keeps files in memory, for tests that shouldn't touch the
+// data directory. The zero value is empty and ready to use.
+type MemoryStorage struct {
+	mu    sync.Mutex
+	files map[string]*memoryFile
+}
+
+type memoryFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{}
+}
+
+func (s *MemoryStorage) ReadFile(name string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return bytes.Clone(f.data), nil
+}
+
+func (s *MemoryStorage) WriteFile(name string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.files == nil {
+		s.files = make(map[string]*memoryFile)
+	}
+	s.files[name] = &memoryFile{data: bytes.Clone(data), modTime: time.Now()}
+	return nil
+}
+
+func (s *MemoryStorage) Append(name string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.files == nil {
+		s.files = make(map[string]*memoryFile)
+	}
+	f := s.files[name]
+	if f == nil {
+		f = &memoryFile{}
+		s.files[name] = f
+	}
+	f.data = append(f.data, data...)
+	f.modTime = time.Now()
+	return nil
+}
+
+// Open reads a snapshot of name; later writes don't show through it
+func (s *MemoryStorage) Open(name string) (io.ReadCloser, error) {
+	data, err := s.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *MemoryStorage) Exists(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.files[name]
+	return ok
+}
+
+func (s *MemoryStorage) Stat(name string) (StoredFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.files[name]
+	if !ok {
+		return StoredFile{}, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return StoredFile{Name: name, Size: int64(len(f.data)), ModTime: f.modTime}, nil
+}
+
+func (s *MemoryStorage) List(dir string) ([]StoredFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prefix := ""
+	if dir = path.Clean(dir); dir != "." {
+		prefix = dir + "/"
+	}
+	var files []StoredFile
+	for name, f := range s.files {
+		if strings.HasPrefix(name, prefix) {
+			files = append(files, StoredFile{Name: name, Size: int64(len(f.data)), ModTime: f.modTime})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+func (s *MemoryStorage) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(s.files, name)
+	return nil
+}
+
+// Chtimes sets when name was last written, as os.Chtimes does for a file
+func (s *MemoryStorage) Chtimes(name string, modTime time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.files[name]
+	if !ok {
+		return &fs.PathError{Op: "chtimes", Path: name, Err: fs.ErrNotExist}
+	}
+	f.modTime = modTime
+	return nil
+}
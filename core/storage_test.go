@@ -0,0 +1,183 @@
+package core
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+// Both backends must behave the same for everything DataManager does
+func TestStorageBackends(t *testing.T) {
+	backends := map[string]Storage{
+		"file":   FileStorage{Dir: t.TempDir()},
+		"memory": NewMemoryStorage(),
+	}
+	for name, s := range backends {
+		t.Run(name, func(t *testing.T) {
+			if _, err := s.ReadFile("missing.json"); !os.IsNotExist(err) {
+				t.Errorf("reading a missing file: err = %v, want not-exist", err)
+			}
+			if _, err := s.Open("missing.json"); !os.IsNotExist(err) {
+				t.Errorf("opening a missing file: err = %v, want not-exist", err)
+			}
+			if s.Exists("log.jsonl") {
+				t.Error("log exists before it was written")
+			}
+
+			if err := s.Append("log.jsonl", []byte("a\n")); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Append("log.jsonl", []byte("b\n")); err != nil {
+				t.Fatal(err)
+			}
+			f, err := s.Open("log.jsonl")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := io.ReadAll(f)
+			f.Close()
+			if string(got) != "a\nb\n" {
+				t.Errorf("log = %q, want both appends", got)
+			}
+
+			if err := s.WriteFile("spotting/photo.jpg", []byte("old")); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.WriteFile("spotting/photo.jpg", []byte("new")); err != nil {
+				t.Fatal(err)
+			}
+			if data, err := s.ReadFile("spotting/photo.jpg"); err != nil || string(data) != "new" {
+				t.Errorf("photo = %q, %v, want the second write", data, err)
+			}
+			if !s.Exists("spotting/photo.jpg") {
+				t.Error("photo doesn't exist after writing it")
+			}
+
+			if f, err := s.Stat("spotting/photo.jpg"); err != nil || f.Size != 3 || time.Since(f.ModTime) > time.Minute {
+				t.Errorf("stat photo = %+v, %v", f, err)
+			}
+			if _, err := s.Stat("missing.json"); !os.IsNotExist(err) {
+				t.Errorf("stat of a missing file: err = %v, want not-exist", err)
+			}
+			if files, err := s.List("spotting"); err != nil || len(files) != 1 || files[0].Name != "spotting/photo.jpg" {
+				t.Errorf("spotting = %+v, %v, want the photo", files, err)
+			}
+			if files, err := s.List("missing"); err != nil || len(files) != 0 {
+				t.Errorf("missing folder = %+v, %v, want nothing", files, err)
+			}
+			if files, err := s.List(""); err != nil || len(files) != 2 || files[0].Name != "log.jsonl" {
+				t.Errorf("everything = %+v, %v, want the log and the photo", files, err)
+			}
+			if err := s.Remove("spotting/photo.jpg"); err != nil || s.Exists("spotting/photo.jpg") {
+				t.Errorf("photo still there after removing it: %v", err)
+			}
+			if err := s.Remove("spotting/photo.jpg"); !os.IsNotExist(err) {
+				t.Errorf("removing it again: err = %v, want not-exist", err)
+			}
+		})
+	}
+}
+
+func TestMemoryStorageKeepsDataDirectoryUntouched(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dm := NewDataManager(NewMemoryStorage())
+
+	if _, err := dm.FinishGame("alice", 900, Difficulty{}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	NewRouteCache(dm).Put("FIN1", &ResolvedDetails{Origin: "Helsinki, Finland"}, time.Now())
+
+	users, err := dm.LoadUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users["alice"].BestScore != 900 {
+		t.Errorf("alice = %+v, want the finished game", users["alice"])
+	}
+	if _, ok := NewRouteCache(dm).Known("FIN1"); !ok {
+		t.Error("route cache didn't read back from memory")
+	}
+	entries, err := os.ReadDir(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("home has %d entries, want none", len(entries))
+	}
+}
+
+// Pruning, exports and the track history all go through the DataManager's
+// storage, so with a MemoryStorage none of them can touch real data
+func TestPruneUsesStorage(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	store := NewMemoryStorage()
+	dm := NewDataManager(store)
+	now := time.Date(2025, 6, 14, 12, 0, 0, 0, time.Local)
+	old := now.AddDate(-2, 0, 0)
+
+	if err := dm.SaveNoiseEvents([]NoiseEvent{{Icao24: "aaa001", At: old}, {Icao24: "aaa002", At: now}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, day := range []time.Time{old, now} {
+		if err := dm.SaveGame(GameRecord{Date: day.Format("2006-01-02"), Player: "alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	csv, err := dm.ExportNoiseCSV(nil, now)
+	if err != nil || csv != "captures/noise-2025-06-14.csv" {
+		t.Fatalf("noise CSV at %q, %v", csv, err)
+	}
+	store.WriteFile("captures/traffic-2023-06-14.gif", []byte("gif"))
+	store.Chtimes("captures/traffic-2023-06-14.gif", old)
+
+	h := NewTrackHistory(dm)
+	for _, at := range []time.Time{old, now} {
+		h.lastCompact = at // no compaction in the background
+		if err := h.Append([]Flight{{Icao24: "aaa001", Lat: 60.3, Lon: 24.9, AltitudeFt: 3000}}, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if days, err := h.days(); err != nil || len(days) != 2 {
+		t.Fatalf("track days = %v, %v", days, err)
+	}
+
+	r, err := dm.Prune(Retention{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Records != 2 || r.Files != 1 {
+		t.Errorf("pruned %+v, want the old noise event and game, and the old GIF", r)
+	}
+	if !store.Exists("captures/noise-2025-06-14.csv") || store.Exists("captures/traffic-2023-06-14.gif") {
+		t.Error("pruned the wrong captures")
+	}
+	if err := h.prune(now); err != nil {
+		t.Fatal(err)
+	}
+	if days, err := h.days(); err != nil || len(days) != 1 || days[0] != "2025-06-14" {
+		t.Errorf("track days after pruning = %v, %v, want today's", days, err)
+	}
+
+	usage, err := dm.DataDirBreakdown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[string]int)
+	for _, u := range usage {
+		found[u.Name] = u.Files
+	}
+	if found[capturesDir] != 1 || found[trackHistoryDir] != 1 || found[gamesFile] != 1 {
+		t.Errorf("breakdown = %+v", usage)
+	}
+
+	entries, err := os.ReadDir(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("home has %d entries, want none", len(entries))
+	}
+}
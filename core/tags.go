@@ -0,0 +1,245 @@
+package core
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const tagDBFile = "plane-alert-db.csv"
+
+// Tag marks an aircraft as interesting
+type Tag string
+
+const (
+	TagMilitary   Tag = "military"
+	TagGovernment Tag = "government"
+	TagPolice     Tag = "police"
+	TagTest       Tag = "test"
+	TagLivery     Tag = "livery"
+)
+
+// Badge is the one-letter marker drawn next to a tagged aircraft
+func (t Tag) Badge() string {
+	switch t {
+	case TagMilitary:
+		return "M"
+	case TagGovernment:
+		return "G"
+	case TagPolice:
+		return "P"
+	case TagTest:
+		return "T"
+	case TagLivery:
+		return "L"
+	}
+	return "?"
+}
+
+// TagInfo is what the database knows about a tagged aircraft
+type TagInfo struct {
+	Tag      Tag
+	Operator string
+}
+
+// Military ICAO address blocks, as used by readsb's military flag, so
+// military traffic is tagged even without a database
+var militaryRanges = [][2]uint32{
+	{0xadf7c8, 0xafffff}, // United States
+	{0x010070, 0x01008f},
+	{0x0a4000, 0x0a4fff},
+	{0x33ff00, 0x33ffff}, // Italy
+	{0x350000, 0x37ffff}, // Spain
+	{0x3aa000, 0x3affff}, // France
+	{0x3b7000, 0x3bffff}, // France
+	{0x3ea000, 0x3ebfff}, // Germany
+	{0x3f4000, 0x3fbfff}, // Germany
+	{0x400000, 0x40003f}, // United Kingdom
+	{0x43c000, 0x43cfff}, // United Kingdom
+	{0x444000, 0x446fff}, // Austria
+	{0x44f000, 0x44ffff}, // Belgium
+	{0x457000, 0x457fff}, // Bulgaria
+	{0x45f400, 0x45f4ff}, // Denmark
+	{0x468000, 0x4683ff}, // Greece
+	{0x473c00, 0x473c0f}, // Hungary
+	{0x478100, 0x4781ff}, // Norway
+	{0x480000, 0x480fff}, // Netherlands
+	{0x48d800, 0x48d87f}, // Poland
+	{0x497c00, 0x497cff}, // Portugal
+	{0x498420, 0x49842f}, // Czechia
+	{0x4b7000, 0x4b7fff}, // Switzerland
+	{0x4b8200, 0x4b82ff}, // Turkey
+	{0x7cf800, 0x7cfaff}, // Australia
+	{0xc20000, 0xc3ffff}, // Canada
+	{0xe40000, 0xe41fff}, // Brazil
+}
+
+// TagDB tags military, government, test and special-livery aircraft from a
+// plane-alert-db style CSV, falling back to known military address blocks
+type TagDB struct {
+	mu     sync.Mutex
+	byHex  map[string]TagInfo
+	alerts arrivalTracker
+}
+
+func NewTagDB() *TagDB {
+	return &TagDB{byHex: make(map[string]TagInfo)}
+}
+
+// TagDBPath is TAG_DB if set, otherwise plane-alert-db.csv in the data dir
+func TagDBPath() string {
+	if p := os.Getenv("TAG_DB"); p != "" {
+		return p
+	}
+	return dataPath(tagDBFile)
+}
+
+// Load reads a plane-alert-db CSV. Columns are found by header name: the
+// first column containing "ICAO", "CMPG" (Civ/Mil/Pol/Gov), "Operator" and
+// "Category". Returns the number of tagged aircraft.
+func (db *TagDB) Load(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return 0, err
+	}
+	col := func(name string) int {
+		for i, h := range header {
+			if strings.Contains(strings.ToLower(h), name) {
+				return i
+			}
+		}
+		return -1
+	}
+	icaoCol, cmpgCol, opCol, catCol := col("icao"), col("cmpg"), col("operator"), col("category")
+	if icaoCol < 0 {
+		return 0, fmt.Errorf("%s: no ICAO column in header", path)
+	}
+
+	field := func(rec []string, i int) string {
+		if i < 0 || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	n := 0
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+		hexID := strings.ToLower(field(rec, icaoCol))
+		if !isHexCode(hexID) {
+			continue
+		}
+		tag := classifyTag(field(rec, cmpgCol), field(rec, catCol))
+		if tag == "" {
+			continue
+		}
+		db.byHex[hexID] = TagInfo{Tag: tag, Operator: field(rec, opCol)}
+		n++
+	}
+	return n, nil
+}
+
+func classifyTag(cmpg, category string) Tag {
+	category = strings.ToLower(category)
+	switch {
+	case strings.Contains(category, "test"):
+		return TagTest
+	case strings.Contains(category, "livery") || strings.Contains(category, "special"):
+		return TagLivery
+	}
+	switch strings.ToLower(cmpg) {
+	case "mil":
+		return TagMilitary
+	case "gov":
+		return TagGovernment
+	case "pol":
+		return TagPolice
+	}
+	return ""
+}
+
+// Len is the number of tagged aircraft loaded from the CSV
+func (db *TagDB) Len() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return len(db.byHex)
+}
+
+// Lookup returns the tag for an aircraft, if any
+func (db *TagDB) Lookup(icao24 string) (TagInfo, bool) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.lookup(icao24)
+}
+
+func (db *TagDB) lookup(icao24 string) (TagInfo, bool) {
+	icao24 = strings.ToLower(icao24)
+	if info, ok := db.byHex[icao24]; ok {
+		return info, true
+	}
+	if addr, err := strconv.ParseUint(icao24, 16, 32); err == nil {
+		for _, r := range militaryRanges {
+			if uint32(addr) >= r[0] && uint32(addr) <= r[1] {
+				return TagInfo{Tag: TagMilitary}, true
+			}
+		}
+	}
+	return TagInfo{}, false
+}
+
+// TagHit is a tagged aircraft that has just come into range
+type TagHit struct {
+	Info   TagInfo
+	Flight Flight
+}
+
+// Arrivals returns tagged aircraft that weren't in range at the previous poll
+func (db *TagDB) Arrivals(flights []Flight, now time.Time) []TagHit {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var hits []TagHit
+	for _, f := range flights {
+		if info, ok := db.lookup(f.Icao24); ok && db.alerts.arrived(f.Icao24, now) {
+			hits = append(hits, TagHit{Info: info, Flight: f})
+		}
+	}
+	return hits
+}
+
+// arrivalTracker reports an aircraft once when it comes into range, and again
+// only after it has been gone for watchRealertAfter. Callers hold their own lock.
+type arrivalTracker struct {
+	lastSeen map[string]time.Time
+}
+
+func (a *arrivalTracker) arrived(icao24 string, now time.Time) bool {
+	if a.lastSeen == nil {
+		a.lastSeen = make(map[string]time.Time)
+	}
+	last, seen := a.lastSeen[icao24]
+	a.lastSeen[icao24] = now
+	return !seen || now.Sub(last) > watchRealertAfter
+}
@@ -0,0 +1,82 @@
+package core
+
+import "math"
+
+const (
+	// The zoom levels the map can be set to
+	MapMinZoom = 4
+	MapMaxZoom = 18
+)
+
+// ClampZoom keeps a map zoom within MapMinZoom..MapMaxZoom
+func ClampZoom(zoom float64) float64 {
+	return min(max(zoom, MapMinZoom), MapMaxZoom)
+}
+
+// TileLevel is the tile zoom level to draw a fractional map zoom from and
+// how much its tiles are scaled for it. The nearest level is used, so a
+// tile is never drawn at more than about 1.41 times or less than 0.71
+// times its size.
+func TileLevel(zoom float64) (int, float64) {
+	level := int(math.Round(zoom))
+	return level, math.Pow(2, zoom-float64(level))
+}
+
+// TileCoord names a map tile in the usual XYZ scheme
+type TileCoord struct {
+	Z, X, Y int
+}
+
+// ViewTiles lists the tiles covering a view w by h pixels centred on
+// lat, lon at zoom z, grown by margin tiles on every side. Columns wrap
+// around the antimeridian; rows past the poles are left out.
+func ViewTiles(lat, lon float64, z int, w, h float64, margin int) []TileCoord {
+	cx, cy := LatLonToPixels(lat, lon, float64(z))
+	minX := int(math.Floor((cx-w/2)/TileSize)) - margin
+	maxX := int(math.Floor((cx+w/2)/TileSize)) + margin
+	minY := int(math.Floor((cy-h/2)/TileSize)) - margin
+	maxY := int(math.Floor((cy+h/2)/TileSize)) + margin
+
+	n := 1 << z
+	var out []TileCoord
+	for y := max(minY, 0); y <= min(maxY, n-1); y++ {
+		for x := minX; x <= maxX; x++ {
+			out = append(out, TileCoord{z, ((x % n) + n) % n, y})
+		}
+	}
+	return out
+}
+
+// PrefetchTiles lists the tiles worth loading before they are needed for
+// a view like ViewTiles(lat, lon, z, w, h, 0): a ring of one tile around
+// it for panning, then the same view a zoom level out and a level in. The
+// visible tiles aren't listed, and no more are than would bring the total
+// to budget, so the prefetched tiles never push the visible ones out of a
+// cache that size.
+func PrefetchTiles(lat, lon float64, z int, w, h float64, budget int) []TileCoord {
+	visible := ViewTiles(lat, lon, z, w, h, 0)
+	seen := make(map[TileCoord]bool, len(visible))
+	for _, t := range visible {
+		seen[t] = true
+	}
+
+	candidates := ViewTiles(lat, lon, z, w, h, 1)
+	if z > MapMinZoom {
+		candidates = append(candidates, ViewTiles(lat, lon, z-1, w, h, 0)...)
+	}
+	if z < MapMaxZoom {
+		candidates = append(candidates, ViewTiles(lat, lon, z+1, w, h, 0)...)
+	}
+
+	var out []TileCoord
+	for _, t := range candidates {
+		if len(visible)+len(out) >= budget {
+			break
+		}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
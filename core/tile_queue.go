@@ -0,0 +1,208 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// A tile that failed to download is left alone this long, doubling
+	// with each failure in a row up to tileRetryMax
+	tileRetryBase = 2 * time.Second
+	tileRetryMax  = 2 * time.Minute
+	// Failures are forgotten once this many have piled up and they are
+	// past their wait, for tiles that were never asked for again
+	tileFailuresKept = 256
+)
+
+// tileFailure is a tile whose last fetch failed and when to try it again
+type tileFailure struct {
+	backoff Backoff
+	retryAt time.Time
+}
+
+// tileJob is a tile waiting in a TileQueue
+type tileJob struct {
+	tile   TileCoord
+	urgent bool // on screen, rather than prefetched
+}
+
+// TileQueue runs tile fetches at most a few at a time, so panning quickly
+// across the map can't start dozens of downloads at once. Tiles on screen
+// go ahead of prefetched ones, and tiles the view has moved away from can
+// be dropped before they start or cancelled while they download.
+//
+// Each tile is fetched once however often it is added while waiting or
+// running; the fetch function stores the result wherever it goes. A tile
+// whose fetch failed isn't queued again until a backoff has passed, so a
+// dropped connection heals without every tile on screen being asked for
+// every frame.
+type TileQueue struct {
+	ctx   context.Context
+	limit int
+	fetch func(ctx context.Context, t TileCoord) error
+
+	mu      sync.Mutex
+	waiting []tileJob
+	running map[TileCoord]context.CancelFunc
+	failed  map[TileCoord]*tileFailure
+}
+
+// NewTileQueue fetches tiles with fetch, limit at a time. fetch gets a
+// context cancelled if the tile is no longer wanted or ctx is.
+func NewTileQueue(ctx context.Context, limit int, fetch func(ctx context.Context, t TileCoord) error) *TileQueue {
+	return &TileQueue{
+		ctx:     ctx,
+		limit:   max(limit, 1),
+		fetch:   fetch,
+		running: make(map[TileCoord]context.CancelFunc),
+		failed:  make(map[TileCoord]*tileFailure),
+	}
+}
+
+// Add queues t for fetching unless it is queued or being fetched already.
+// An urgent tile goes ahead of those that aren't, and a tile already
+// waiting is moved up if it has become urgent. It reports whether t is
+// now queued or being fetched: not while a failed tile waits out its
+// backoff, nor once ctx is cancelled.
+func (q *TileQueue) Add(t TileCoord, urgent bool) bool {
+	if q.ctx.Err() != nil {
+		return false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.running[t]; ok {
+		return true
+	}
+	if q.urge(t, urgent) {
+		return true
+	}
+	if f, ok := q.failed[t]; ok && time.Now().Before(f.retryAt) {
+		return false
+	}
+	q.insert(tileJob{t, urgent})
+	q.start()
+	return true
+}
+
+// Fail records that t couldn't be used although its fetch succeeded, e.g.
+// the image didn't decode, so it backs off like a failed fetch
+func (q *TileQueue) Fail(t TileCoord) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.fail(t)
+}
+
+// fail starts or lengthens t's backoff. Callers hold mu.
+func (q *TileQueue) fail(t TileCoord) {
+	now := time.Now()
+	if len(q.failed) >= tileFailuresKept {
+		for k, f := range q.failed {
+			if now.After(f.retryAt) {
+				delete(q.failed, k)
+			}
+		}
+	}
+	f, ok := q.failed[t]
+	if !ok {
+		f = &tileFailure{backoff: Backoff{Base: tileRetryBase, Max: tileRetryMax}}
+		q.failed[t] = f
+	}
+	f.retryAt = now.Add(f.backoff.Next())
+}
+
+// Urge moves t ahead of the tiles that aren't urgent if it is waiting, and
+// does nothing otherwise
+func (q *TileQueue) Urge(t TileCoord) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.urge(t, true)
+}
+
+// urge finds t among the waiting tiles, moving it up if it has become
+// urgent. Callers hold mu.
+func (q *TileQueue) urge(t TileCoord, urgent bool) bool {
+	for i, j := range q.waiting {
+		if j.tile == t {
+			if urgent && !j.urgent {
+				q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+				q.insert(tileJob{t, true})
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// insert puts job behind the others like it: urgent jobs after the last
+// urgent one, others at the end. Callers hold mu.
+func (q *TileQueue) insert(job tileJob) {
+	at := len(q.waiting)
+	if job.urgent {
+		at = 0
+		for at < len(q.waiting) && q.waiting[at].urgent {
+			at++
+		}
+	}
+	q.waiting = append(q.waiting, tileJob{})
+	copy(q.waiting[at+1:], q.waiting[at:])
+	q.waiting[at] = job
+}
+
+// start runs waiting jobs while there is room. Callers hold mu.
+func (q *TileQueue) start() {
+	for len(q.running) < q.limit && len(q.waiting) > 0 {
+		job := q.waiting[0]
+		q.waiting = q.waiting[1:]
+		ctx, cancel := context.WithCancel(q.ctx)
+		q.running[job.tile] = cancel
+		go q.run(ctx, cancel, job.tile)
+	}
+}
+
+func (q *TileQueue) run(ctx context.Context, cancel context.CancelFunc, t TileCoord) {
+	defer cancel()
+	err := q.fetch(ctx, t)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.running, t)
+	switch {
+	case err == nil:
+		delete(q.failed, t)
+	case ctx.Err() == nil: // not dropped or quitting, so a real failure
+		q.fail(t)
+	}
+	q.start()
+}
+
+// Retain drops the tiles keep rejects, e.g. when the view has moved away
+// from them: waiting ones are removed and returned, running ones have
+// their fetch cancelled.
+func (q *TileQueue) Retain(keep func(TileCoord) bool) (dropped []TileCoord) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	kept := q.waiting[:0]
+	for _, j := range q.waiting {
+		if keep(j.tile) {
+			kept = append(kept, j)
+		} else {
+			dropped = append(dropped, j.tile)
+		}
+	}
+	q.waiting = kept
+	for t, cancel := range q.running {
+		if !keep(t) {
+			cancel()
+		}
+	}
+	return dropped
+}
+
+// Len is the number of tiles waiting and being fetched
+func (q *TileQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiting) + len(q.running)
+}
@@ -0,0 +1,191 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+const touchCalibrationFile = "touch_calibration.json"
+
+const (
+	// Targets sit this far in from the edges, as a share of the canvas;
+	// resistive panels are least accurate right at the edge
+	touchTargetInset = 0.1
+	// A fit that misses any target by more than this share of the screen
+	// diagonal means a tap went astray, and the run is repeated
+	touchFitMaxError = 0.03
+)
+
+// ErrCalibrationPoor means the taps don't fit one correction, usually
+// because one of them missed its target
+var ErrCalibrationPoor = errors.New("taps too far from the targets, try again")
+
+// TouchCalibration corrects where a touch panel reports taps, in screen
+// pixels before any rotation: a tap reported at (x, y) was meant for
+// (A*x + B*y + C, D*x + E*y + F). Cheap resistive panels are often offset,
+// scaled or slightly skewed, and turning the UI for a portrait display
+// turns those errors with it. The zero value leaves taps as reported.
+type TouchCalibration struct {
+	A float64 `json:"a"`
+	B float64 `json:"b"`
+	C float64 `json:"c"`
+	D float64 `json:"d"`
+	E float64 `json:"e"`
+	F float64 `json:"f"`
+}
+
+// Apply corrects a reported tap
+func (c TouchCalibration) Apply(x, y float64) (float64, float64) {
+	if c == (TouchCalibration{}) {
+		return x, y
+	}
+	return c.A*x + c.B*y + c.C, c.D*x + c.E*y + c.F
+}
+
+// FitTouchCalibration is the correction that takes the reported taps
+// closest to the points they were aimed at, by least squares. It needs three
+// or more taps not all in a line.
+func FitTouchCalibration(reported, aimed [][2]float64) (TouchCalibration, error) {
+	if len(reported) != len(aimed) || len(reported) < 3 {
+		return TouchCalibration{}, fmt.Errorf("need 3 or more taps, got %d", len(reported))
+	}
+	// Normal equations: M [a b c] = v for each output coordinate
+	var m [3][3]float64
+	var vx, vy [3]float64
+	for i, r := range reported {
+		row := [3]float64{r[0], r[1], 1}
+		for j := range 3 {
+			for k := range 3 {
+				m[j][k] += row[j] * row[k]
+			}
+			vx[j] += row[j] * aimed[i][0]
+			vy[j] += row[j] * aimed[i][1]
+		}
+	}
+	abc, ok1 := solve3(m, vx)
+	def, ok2 := solve3(m, vy)
+	if !ok1 || !ok2 {
+		return TouchCalibration{}, errors.New("taps are all in a line")
+	}
+	return TouchCalibration{A: abc[0], B: abc[1], C: abc[2], D: def[0], E: def[1], F: def[2]}, nil
+}
+
+// solve3 solves m x = v by Cramer's rule
+func solve3(m [3][3]float64, v [3]float64) ([3]float64, bool) {
+	det := func(a [3][3]float64) float64 {
+		return a[0][0]*(a[1][1]*a[2][2]-a[1][2]*a[2][1]) -
+			a[0][1]*(a[1][0]*a[2][2]-a[1][2]*a[2][0]) +
+			a[0][2]*(a[1][0]*a[2][1]-a[1][1]*a[2][0])
+	}
+	d := det(m)
+	if math.Abs(d) < 1e-9 {
+		return [3]float64{}, false
+	}
+	var x [3]float64
+	for col := range 3 {
+		mc := m
+		for row := range 3 {
+			mc[row][col] = v[row]
+		}
+		x[col] = det(mc) / d
+	}
+	return x, true
+}
+
+// TouchCalibrator leads the player through tapping five targets, the
+// corners and the centre, and works out the correction from where the
+// taps landed
+type TouchCalibrator struct {
+	targets [][2]float64 // on the logical canvas
+	taps    [][2]float64 // as the panel reported them, in screen pixels
+}
+
+// NewTouchCalibrator places the targets on a w×h canvas
+func NewTouchCalibrator(w, h int) *TouchCalibrator {
+	x0, y0 := float64(w)*touchTargetInset, float64(h)*touchTargetInset
+	x1, y1 := float64(w)-x0, float64(h)-y0
+	return &TouchCalibrator{targets: [][2]float64{
+		{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}, {float64(w) / 2, float64(h) / 2},
+	}}
+}
+
+// Target is the canvas point to tap next and its number, counting from 1,
+// or false once every target has been tapped
+func (c *TouchCalibrator) Target() (x, y float64, n int, ok bool) {
+	if c.Done() {
+		return 0, 0, 0, false
+	}
+	t := c.targets[len(c.taps)]
+	return t[0], t[1], len(c.taps) + 1, true
+}
+
+// Targets is how many taps a run takes
+func (c *TouchCalibrator) Targets() int {
+	return len(c.targets)
+}
+
+// Tap records where the panel reported the tap on the current target,
+// uncorrected, in screen pixels
+func (c *TouchCalibrator) Tap(x, y float64) {
+	if !c.Done() {
+		c.taps = append(c.taps, [2]float64{x, y})
+	}
+}
+
+func (c *TouchCalibrator) Done() bool {
+	return len(c.taps) >= len(c.targets)
+}
+
+// Restart forgets the taps for another run
+func (c *TouchCalibrator) Restart() {
+	c.taps = c.taps[:0]
+}
+
+// Fit is the correction from a finished run, for a screen shown through
+// xf. It fails with ErrCalibrationPoor when a tap clearly missed.
+func (c *TouchCalibrator) Fit(xf ScreenTransform) (TouchCalibration, error) {
+	if !c.Done() {
+		return TouchCalibration{}, errors.New("calibration not finished")
+	}
+	aimed := make([][2]float64, len(c.targets))
+	for i, t := range c.targets {
+		aimed[i][0], aimed[i][1] = xf.ToScreen(t[0], t[1])
+	}
+	cal, err := FitTouchCalibration(c.taps, aimed)
+	if err != nil {
+		return TouchCalibration{}, err
+	}
+	limit := touchFitMaxError * math.Hypot(float64(xf.ScreenW), float64(xf.ScreenH))
+	for i, t := range c.taps {
+		x, y := cal.Apply(t[0], t[1])
+		if math.Hypot(x-aimed[i][0], y-aimed[i][1]) > limit {
+			return TouchCalibration{}, ErrCalibrationPoor
+		}
+	}
+	return cal, nil
+}
+
+// LoadTouchCalibration reads the saved correction; none saved, or
+// TOUCH_CALIBRATION=off, is the zero value
+func (dm *DataManager) LoadTouchCalibration() (TouchCalibration, error) {
+	var c TouchCalibration
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("TOUCH_CALIBRATION")), "off") {
+		return c, nil
+	}
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	if err := dm.readDocument(touchCalibrationFile, &c); err != nil && !os.IsNotExist(err) {
+		return TouchCalibration{}, err
+	}
+	return c, nil
+}
+
+// SaveTouchCalibration stores c for the next start
+func (dm *DataManager) SaveTouchCalibration(c TouchCalibration) error {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	return dm.writeDocument(touchCalibrationFile, c)
+}
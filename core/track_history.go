@@ -0,0 +1,393 @@
+package core
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	trackHistoryDir = "tracks" // one <date>.jsonl per day inside the data dir
+	// Schema name for the records in the per-day files
+	trackHistoryRecord = "tracks.jsonl"
+
+	historyCompactEvery       = time.Hour
+	defaultTrackRetentionDays = 30
+	defaultTrackMaxMB         = 200
+)
+
+// Points younger than the first tier are kept at full resolution; older ones
+// are thinned to at most one per spacing for each aircraft
+var historyTiers = []struct {
+	olderThan time.Duration
+	spacing   time.Duration
+}{
+	{time.Hour, 2 * time.Minute},
+	{24 * time.Hour, 10 * time.Minute},
+}
+
+type trackRecord struct {
+	Icao24 string `json:"icao24"`
+	TrackPoint
+}
+
+// TrackHistory persists every aircraft's path to disk for history features
+// such as time-lapses and heatmaps. Recent points are stored as recorded and
+// thinned hourly; whole days beyond the retention or size limit are deleted.
+type TrackHistory struct {
+	store         Storage
+	retentionDays int
+	maxBytes      int64
+
+	mu          sync.Mutex
+	lastPoint   map[string]time.Time
+	lastCompact time.Time
+	compacted   map[string]compaction // by day, since the app started
+	maintaining atomic.Bool
+}
+
+// compaction records when a day file was last thinned and the file's
+// modification time just after, to tell when appends have changed it since
+type compaction struct {
+	at      time.Time
+	modTime time.Time
+}
+
+// defaultTrackDays is TRACK_RETENTION_DAYS, or defaultTrackRetentionDays
+func defaultTrackDays() int {
+	if v, err := strconv.Atoi(os.Getenv("TRACK_RETENTION_DAYS")); err == nil && v > 0 {
+		return v
+	}
+	return defaultTrackRetentionDays
+}
+
+// NewTrackHistory keeps the history in dm's storage, using
+// TRACK_RETENTION_DAYS and TRACK_MAX_MB to bound disk use
+func NewTrackHistory(dm *DataManager) *TrackHistory {
+	h := &TrackHistory{
+		store:         dm.storage(),
+		retentionDays: defaultTrackDays(),
+		maxBytes:      defaultTrackMaxMB << 20,
+		lastPoint:     make(map[string]time.Time),
+		compacted:     make(map[string]compaction),
+	}
+	if v, err := strconv.Atoi(os.Getenv("TRACK_MAX_MB")); err == nil && v > 0 {
+		h.maxBytes = int64(v) << 20
+	}
+	return h
+}
+
+// SetRetentionDays changes how many days are kept from the next pruning,
+// which is on the first Append and hourly after
+func (h *TrackHistory) SetRetentionDays(days int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.retentionDays = days
+}
+
+// dayFile is the name in storage of a day's file
+func dayFile(day string) string {
+	return path.Join(trackHistoryDir, day+".jsonl")
+}
+
+// Append stores the airborne positions from one poll, sampled like the
+// TrackRecorder, and kicks off compaction/pruning once an hour
+func (h *TrackHistory) Append(flights []Flight, now time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var lines []byte
+	for _, f := range flights {
+		if f.OnGround {
+			continue
+		}
+		if last, ok := h.lastPoint[f.Icao24]; ok && now.Sub(last) < trackSampleInterval {
+			continue
+		}
+		h.lastPoint[f.Icao24] = now
+
+		line, err := encodeRecord(trackHistoryRecord, trackRecord{
+			Icao24:     f.Icao24,
+			TrackPoint: TrackPoint{Lat: f.Lat, Lon: f.Lon, AltitudeFt: f.AltitudeFt, Time: now},
+		})
+		if err != nil {
+			return err
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+
+	for icao, last := range h.lastPoint {
+		if now.Sub(last) > time.Hour {
+			delete(h.lastPoint, icao)
+		}
+	}
+
+	if now.Sub(h.lastCompact) >= historyCompactEvery && h.maintaining.CompareAndSwap(false, true) {
+		h.lastCompact = now
+		go func() {
+			defer h.maintaining.Store(false)
+			h.maintain(now)
+		}()
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+	return h.store.Append(dayFile(now.Format("2006-01-02")), lines)
+}
+
+// maintain thins the day files that need it and applies the retention limits
+func (h *TrackHistory) maintain(now time.Time) {
+	days, err := h.days()
+	if err != nil {
+		return
+	}
+	for _, day := range days {
+		if !h.needsCompaction(day, now) {
+			continue
+		}
+		if err := h.compactDay(day, now); err != nil {
+			log.Printf("Track history compaction of %s failed: %v", day, err)
+		}
+	}
+	if err := h.prune(now); err != nil {
+		log.Printf("Track history pruning failed: %v", err)
+	}
+}
+
+// needsCompaction reports whether thinning day's file again could change it:
+// it hasn't been thinned since the app started, points were appended since,
+// or some of its points were still short of the coarsest tier last time
+func (h *TrackHistory) needsCompaction(day string, now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	last, ok := h.compacted[day]
+	if !ok {
+		return true
+	}
+	info, err := h.store.Stat(dayFile(day))
+	if err != nil {
+		return false
+	}
+	if !info.ModTime.Equal(last.modTime) {
+		return true
+	}
+	end, err := time.ParseInLocation("2006-01-02", day, time.Local)
+	if err != nil {
+		return false
+	}
+	settled := end.AddDate(0, 0, 1).Add(historyTiers[len(historyTiers)-1].olderThan)
+	return last.at.Before(settled)
+}
+
+// compactDay rewrites one day file with old points thinned out
+func (h *TrackHistory) compactDay(day string, now time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	tracks, err := h.readDay(day)
+	if err != nil {
+		return err
+	}
+
+	icaos := make([]string, 0, len(tracks))
+	for icao := range tracks {
+		icaos = append(icaos, icao)
+	}
+	sort.Strings(icaos)
+
+	var out []byte
+	for _, icao := range icaos {
+		for _, p := range thinTrack(tracks[icao], now) {
+			line, err := encodeRecord(trackHistoryRecord, trackRecord{Icao24: icao, TrackPoint: p})
+			if err != nil {
+				return err
+			}
+			out = append(append(out, line...), '\n')
+		}
+	}
+
+	// Storage writes are all-or-nothing, so a crash mid-compaction can't
+	// lose the day
+	if err := h.store.WriteFile(dayFile(day), out); err != nil {
+		return err
+	}
+	info, err := h.store.Stat(dayFile(day))
+	if err != nil {
+		return err
+	}
+	h.compacted[day] = compaction{at: now, modTime: info.ModTime}
+	return nil
+}
+
+// thinTrack keeps recent points and at most one point per tier spacing for older ones
+func thinTrack(points []TrackPoint, now time.Time) []TrackPoint {
+	var kept []TrackPoint
+	for _, p := range points {
+		age := now.Sub(p.Time)
+		var spacing time.Duration
+		for _, tier := range historyTiers {
+			if age >= tier.olderThan {
+				spacing = tier.spacing
+			}
+		}
+		if n := len(kept); n > 0 && spacing > 0 && p.Time.Sub(kept[n-1].Time) < spacing {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return kept
+}
+
+// prune deletes days beyond the retention period, then the oldest days until
+// the history fits in maxBytes. Today's file is never removed.
+func (h *TrackHistory) prune(now time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	files, err := h.dayFiles()
+	if err != nil {
+		return err
+	}
+
+	today := now.Format("2006-01-02")
+	cutoff := now.AddDate(0, 0, -h.retentionDays).Format("2006-01-02")
+	var total int64
+	var keep []StoredFile
+	for _, f := range files {
+		day := fileDay(f)
+		if day < cutoff && day != today {
+			if err := h.store.Remove(f.Name); err != nil {
+				return err
+			}
+			delete(h.compacted, day)
+			continue
+		}
+		total += f.Size
+		keep = append(keep, f)
+	}
+
+	for _, f := range keep {
+		if total <= h.maxBytes || fileDay(f) == today {
+			break
+		}
+		if err := h.store.Remove(f.Name); err != nil {
+			return err
+		}
+		delete(h.compacted, fileDay(f))
+		total -= f.Size
+	}
+	return nil
+}
+
+// dayFiles lists the stored days' files, oldest first
+func (h *TrackHistory) dayFiles() ([]StoredFile, error) {
+	files, err := h.store.List(trackHistoryDir)
+	if err != nil {
+		return nil, err
+	}
+	var days []StoredFile
+	for _, f := range files {
+		// Only the day files directly in the folder
+		if strings.HasSuffix(f.Name, ".jsonl") && path.Dir(f.Name) == trackHistoryDir {
+			days = append(days, f)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Name < days[j].Name })
+	return days, nil
+}
+
+// fileDay is the date a day file is for
+func fileDay(f StoredFile) string {
+	return strings.TrimSuffix(path.Base(f.Name), ".jsonl")
+}
+
+// days lists the stored dates, oldest first
+func (h *TrackHistory) days() ([]string, error) {
+	files, err := h.dayFiles()
+	if err != nil {
+		return nil, err
+	}
+	days := make([]string, len(files))
+	for i, f := range files {
+		days[i] = fileDay(f)
+	}
+	return days, nil
+}
+
+// readDay parses one day file into tracks keyed by icao24. Caller must hold h.mu.
+func (h *TrackHistory) readDay(day string) (map[string][]TrackPoint, error) {
+	tracks := make(map[string][]TrackPoint)
+	file, err := h.store.Open(dayFile(day))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return tracks, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var rec trackRecord
+		if err := decodeRecord(trackHistoryRecord, scanner.Bytes(), &rec); err != nil {
+			continue // torn line
+		}
+		tracks[rec.Icao24] = append(tracks[rec.Icao24], rec.TrackPoint)
+	}
+	return tracks, scanner.Err()
+}
+
+// Load returns the stored tracks with points between from and to
+func (h *TrackHistory) Load(from, to time.Time) (map[string][]TrackPoint, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	out := make(map[string][]TrackPoint)
+	first, last := from.Format("2006-01-02"), to.Format("2006-01-02")
+	days, err := h.days()
+	if err != nil {
+		return nil, err
+	}
+	for _, day := range days {
+		if day < first || day > last {
+			continue
+		}
+		tracks, err := h.readDay(day)
+		if err != nil {
+			return nil, err
+		}
+		for icao, points := range tracks {
+			for _, p := range points {
+				if !p.Time.Before(from) && !p.Time.After(to) {
+					out[icao] = append(out[icao], p)
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// DiskUsage is the total size of the stored history in bytes
+func (h *TrackHistory) DiskUsage() (int64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	files, err := h.dayFiles()
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	return total, nil
+}
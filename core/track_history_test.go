@@ -0,0 +1,58 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+// A finished day keeps being thinned as it ages into coarser tiers, then is
+// left alone
+func TestTrackHistoryCompactsByAge(t *testing.T) {
+	h := NewTrackHistory(NewDataManager(NewMemoryStorage()))
+	const day = "2025-06-13"
+	start := time.Date(2025, 6, 13, 10, 0, 0, 0, time.Local)
+	var lines []byte
+	for at := start; at.Before(start.Add(2 * time.Hour)); at = at.Add(30 * time.Second) {
+		line, err := encodeRecord(trackHistoryRecord, trackRecord{Icao24: "4601f6", TrackPoint: TrackPoint{Lat: 60.3, Lon: 24.9, Time: at}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	if err := h.store.WriteFile(dayFile(day), lines); err != nil {
+		t.Fatal(err)
+	}
+	points := func() int {
+		tracks, err := h.readDay(day)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(tracks["4601f6"])
+	}
+
+	midnight := time.Date(2025, 6, 14, 0, 30, 0, 0, time.Local)
+	h.maintain(midnight)
+	if n := points(); n != 60 {
+		t.Errorf("%d points after the day ended, want one per 2 minutes", n)
+	}
+	h.maintain(midnight.Add(time.Hour))
+	if n := points(); n != 60 {
+		t.Errorf("%d points an hour later, want still 60", n)
+	}
+
+	nextDay := time.Date(2025, 6, 15, 1, 0, 0, 0, time.Local)
+	if !h.needsCompaction(day, nextDay) {
+		t.Fatal("day not compacted again once older than a day")
+	}
+	h.maintain(nextDay)
+	if n := points(); n != 12 {
+		t.Errorf("%d points a day later, want one per 10 minutes", n)
+	}
+	if h.needsCompaction(day, nextDay.Add(time.Hour)) {
+		t.Error("a settled day is compacted again")
+	}
+	h.store.Append(dayFile(day), lines[:len(lines)/2])
+	if !h.needsCompaction(day, nextDay.Add(time.Hour)) {
+		t.Error("a day appended to since is not compacted")
+	}
+}
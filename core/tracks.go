@@ -0,0 +1,89 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// Minimum spacing between recorded points of the same aircraft.
+	// Polls are every 5s; a point every 30s is plenty for a day-long replay.
+	trackSampleInterval = 30 * time.Second
+)
+
+// TrackPoint is a single recorded position
+type TrackPoint struct {
+	Lat        float64   `json:"lat"`
+	Lon        float64   `json:"lon"`
+	AltitudeFt int       `json:"altitude_ft"`
+	Time       time.Time `json:"time"`
+}
+
+// TrackRecorder accumulates the current day's flight paths keyed by icao24
+type TrackRecorder struct {
+	mu     sync.Mutex
+	day    string
+	tracks map[string][]TrackPoint
+}
+
+func NewTrackRecorder() *TrackRecorder {
+	return &TrackRecorder{
+		tracks: make(map[string][]TrackPoint),
+	}
+}
+
+// Record adds the positions from one poll. When the local date changes the
+// previous day's tracks are returned (with their date) and recording restarts.
+func (tr *TrackRecorder) Record(flights []Flight, now time.Time) (string, map[string][]TrackPoint) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	today := now.Format("2006-01-02")
+	var finishedDay string
+	var finished map[string][]TrackPoint
+
+	if tr.day != today {
+		if tr.day != "" && len(tr.tracks) > 0 {
+			finishedDay, finished = tr.day, tr.tracks
+		}
+		tr.day = today
+		tr.tracks = make(map[string][]TrackPoint)
+	}
+
+	for _, f := range flights {
+		if f.OnGround {
+			continue
+		}
+		points := tr.tracks[f.Icao24]
+		if n := len(points); n > 0 && now.Sub(points[n-1].Time) < trackSampleInterval {
+			continue
+		}
+		tr.tracks[f.Icao24] = append(points, TrackPoint{
+			Lat:        f.Lat,
+			Lon:        f.Lon,
+			AltitudeFt: f.AltitudeFt,
+			Time:       now,
+		})
+	}
+
+	return finishedDay, finished
+}
+
+// Track returns a copy of today's recorded points for one aircraft
+func (tr *TrackRecorder) Track(icao24 string) []TrackPoint {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return append([]TrackPoint(nil), tr.tracks[icao24]...)
+}
+
+// Snapshot returns a copy of today's tracks recorded so far
+func (tr *TrackRecorder) Snapshot() (string, map[string][]TrackPoint) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	copied := make(map[string][]TrackPoint, len(tr.tracks))
+	for k, v := range tr.tracks {
+		copied[k] = append([]TrackPoint(nil), v...)
+	}
+	return tr.day, copied
+}
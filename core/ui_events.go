@@ -0,0 +1,105 @@
+package core
+
+import "fmt"
+
+// UIWidget is a clickable area in one frame's layout. Its ID stays the same
+// from frame to frame while the widget does, so a press can be matched with
+// the same widget in a newer layout.
+type UIWidget struct {
+	ID         string
+	X, Y, W, H int
+	Action     func()
+}
+
+// Contains reports whether the point is on the widget, edges included
+func (w UIWidget) Contains(x, y int) bool {
+	return x >= w.X && x <= w.X+w.W && y >= w.Y && y <= w.Y+w.H
+}
+
+// WidgetID is the ID of a button known by its label and where it is; two
+// buttons with both the same are the same button
+func WidgetID(label string, x, y int) string {
+	return fmt.Sprintf("%s@%d,%d", label, x, y)
+}
+
+// uiPress is a press queued for dispatch: the widget it landed on and the
+// screen that was showing
+type uiPress struct {
+	screen int
+	id     string
+}
+
+// UIEvents separates the frontends' input from their drawing. Each frame's
+// draw pass builds the layout for the screen it shows and publishes it
+// whole; Update queues presses as it reads input and dispatches them at one
+// point, running the action of the same widget in the newest layout.
+// Presses never reach a layout built for another screen, and once an
+// action changes the screen the rest of the queue is dropped, so a click
+// can't land on buttons that are gone.
+type UIEvents struct {
+	screen  int // the screen layout was built for; -1 before the first
+	layout  []UIWidget
+	pending []uiPress
+}
+
+func NewUIEvents() *UIEvents {
+	return &UIEvents{screen: -1}
+}
+
+// SetLayout publishes the widgets a frame built for screen, topmost last
+func (q *UIEvents) SetLayout(screen int, widgets []UIWidget) {
+	q.screen = screen
+	q.layout = append(q.layout[:0], widgets...)
+}
+
+// find is the topmost widget at x, y in the layout
+func (q *UIEvents) find(x, y int) (UIWidget, bool) {
+	for i := len(q.layout) - 1; i >= 0; i-- {
+		if q.layout[i].Contains(x, y) {
+			return q.layout[i], true
+		}
+	}
+	return UIWidget{}, false
+}
+
+// Press queues a press at x, y while screen is showing. It reports whether
+// the press was taken by the UI, and so shouldn't also reach the map under
+// it: when it lands on a widget, or when the screen changed and its layout
+// isn't drawn yet, so what the player saw isn't known.
+func (q *UIEvents) Press(screen, x, y int) bool {
+	if screen != q.screen {
+		return true
+	}
+	w, ok := q.find(x, y)
+	if !ok {
+		return false
+	}
+	q.pending = append(q.pending, uiPress{screen: screen, id: w.ID})
+	return true
+}
+
+// Dispatch runs the queued presses in order and empties the queue. screen
+// tells which screen is showing now; presses from another screen, or on a
+// widget the layout no longer has, are dropped.
+func (q *UIEvents) Dispatch(screen func() int) {
+	pending := q.pending
+	q.pending = nil
+	for _, p := range pending {
+		now := screen()
+		if p.screen != now || q.screen != now {
+			continue
+		}
+		for i := len(q.layout) - 1; i >= 0; i-- {
+			if w := q.layout[i]; w.ID == p.id {
+				if w.Action != nil {
+					w.Action()
+				}
+				break
+			}
+		}
+		if screen() != now {
+			q.screen = -1 // the layout belongs to the old screen
+			q.layout = q.layout[:0]
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	latestReleaseURL = "https://api.github.com/repos/aapoleppanen/overhead_flights_monitor/releases/latest"
+	// Releases are rare; once a day is plenty and far inside GitHub's
+	// anonymous rate limit
+	updateCheckInterval = 24 * time.Hour
+)
+
+// Release is a published GitHub release
+type Release struct {
+	Tag       string    `json:"tag_name"`
+	Name      string    `json:"name"`
+	Notes     string    `json:"body"` // markdown
+	URL       string    `json:"html_url"`
+	Published time.Time `json:"published_at"`
+}
+
+// UpdateCheckEnabled reports whether UPDATE_CHECK asks for the update
+// checker. It is off by default: the app doesn't phone home unasked.
+func UpdateCheckEnabled() bool {
+	v := os.Getenv("UPDATE_CHECK")
+	return v != "" && v != "0" && !strings.EqualFold(v, "false")
+}
+
+// UpdateChecker compares the running version with the latest GitHub
+// release. It only tells; downloading and installing is left to the owner.
+type UpdateChecker struct {
+	client  *http.Client
+	current string
+
+	mu     sync.Mutex
+	latest *Release // newer than current, nil if none found
+}
+
+func NewUpdateChecker() *UpdateChecker {
+	return &UpdateChecker{
+		client:  &http.Client{Timeout: 10 * time.Second},
+		current: Build().Version,
+	}
+}
+
+// Run checks now and then daily until ctx is cancelled
+func (u *UpdateChecker) Run(ctx context.Context) {
+	for {
+		rel, err := u.Check(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Println("Update check failed:", err)
+		} else if rel != nil {
+			log.Printf("Update available: %s (running %s) %s", rel.Tag, u.current, rel.URL)
+		}
+		select {
+		case <-time.After(updateCheckInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Check fetches the latest release and returns it if it is newer than the
+// running version. An unstamped development build can't be compared, so it
+// is never offered one.
+func (u *UpdateChecker) Check(ctx context.Context) (*Release, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", latestReleaseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("latest release: status %d", resp.StatusCode)
+	}
+
+	var rel Release
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return nil, err
+	}
+	var newer *Release
+	if versionNewer(rel.Tag, u.current) {
+		newer = &rel
+	}
+	u.mu.Lock()
+	u.latest = newer
+	u.mu.Unlock()
+	return newer, nil
+}
+
+// Available is the newer release found by the last check, nil if there is
+// none or the checker is off (nil)
+func (u *UpdateChecker) Available() *Release {
+	if u == nil {
+		return nil
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.latest
+}
+
+// versionNewer reports whether latest is a higher vMAJOR.MINOR.PATCH than
+// current. Anything that doesn't parse, like "(devel)", compares as false.
+func versionNewer(latest, current string) bool {
+	l, ok1 := parseVersion(latest)
+	c, ok2 := parseVersion(current)
+	if !ok1 || !ok2 {
+		return false
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+// parseVersion reads "v1.2.3" (or "1.2", with missing parts 0), ignoring
+// any pre-release or build suffix
+func parseVersion(v string) ([3]int, bool) {
+	var out [3]int
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) > 3 {
+		return out, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return out, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
+
+// NoteLines wraps the release notes to width characters for the in-app
+// panel, dropping markdown heading marks and blank lines and cutting off
+// after max lines
+func (r Release) NoteLines(width, max int) []string {
+	var lines []string
+	for _, para := range strings.Split(r.Notes, "\n") {
+		para = strings.TrimSpace(strings.TrimLeft(para, "# "))
+		line := ""
+		for _, word := range strings.Fields(para) {
+			if line != "" && len(line)+1+len(word) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > max {
+		lines = append(lines[:max-1], "...")
+	}
+	return lines
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"sort"
+	"sync"
+)
+
+// UserStore holds the known users and the logged-in player.
+// Draw reads it every frame while logins, game saves and refreshes write to it,
+// so every read hands out a copy rather than the underlying map.
+type UserStore struct {
+	mu      sync.RWMutex
+	users   map[string]UserStats
+	current UserStats
+}
+
+func NewUserStore() *UserStore {
+	return &UserStore{
+		users: make(map[string]UserStats),
+	}
+}
+
+// SetAll replaces the known users, e.g. after reloading users.json
+func (s *UserStore) SetAll(users map[string]UserStats) {
+	copied := make(map[string]UserStats, len(users))
+	for k, v := range users {
+		copied[k] = v
+	}
+
+	s.mu.Lock()
+	s.users = copied
+	s.mu.Unlock()
+}
+
+// All returns a copy of the known users
+func (s *UserStore) All() map[string]UserStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	copied := make(map[string]UserStats, len(s.users))
+	for k, v := range s.users {
+		copied[k] = v
+	}
+	return copied
+}
+
+// Names returns the known user names sorted alphabetically
+func (s *UserStore) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.users))
+	for k := range s.users {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get looks up a single user
+func (s *UserStore) Get(name string) (UserStats, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[name]
+	return u, ok
+}
+
+// Put stores updated stats, refreshing the current user if it is the same player
+func (s *UserStore) Put(u UserStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[u.Name] = u
+	if s.current.Name == u.Name {
+		s.current = u
+	}
+}
+
+// Current returns the logged-in user
+func (s *UserStore) Current() UserStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// Login makes name the current user, creating fresh stats for unknown names
+func (s *UserStore) Login(name string) UserStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[name]
+	if !ok {
+		u = UserStats{Name: name}
+	}
+	s.current = u
+	return u
+}
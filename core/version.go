@@ -0,0 +1,94 @@
+package core
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// Stamped at build time, e.g.
+//
+//	go build -ldflags "-X flight-monitor/core.Version=v1.2.0 \
+//	  -X flight-monitor/core.Commit=$(git rev-parse --short HEAD) \
+//	  -X flight-monitor/core.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Left empty, they fall back to what Go embeds in the binary itself.
+var (
+	Version   string
+	Commit    string
+	BuildDate string
+)
+
+// BuildInfo identifies the running binary
+type BuildInfo struct {
+	Version   string // e.g. "v1.2.0", "(devel)" for an unstamped local build
+	Commit    string // short VCS revision, "+dirty" if built with local changes
+	Date      string
+	GoVersion string
+}
+
+// Build reports the stamped version, commit and build date, filling any
+// that weren't stamped from Go's embedded module and VCS information. It is
+// worked out once; the login screen asks every frame.
+var Build = sync.OnceValue(readBuild)
+
+func readBuild() BuildInfo {
+	b := BuildInfo{Version: Version, Commit: Commit, Date: BuildDate}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return b
+	}
+	b.GoVersion = info.GoVersion
+	if b.Version == "" {
+		b.Version = info.Main.Version
+	}
+	settings := map[string]string{}
+	for _, s := range info.Settings {
+		settings[s.Key] = s.Value
+	}
+	if rev := settings["vcs.revision"]; b.Commit == "" && rev != "" {
+		if len(rev) > 12 {
+			rev = rev[:12]
+		}
+		if settings["vcs.modified"] == "true" {
+			rev += "+dirty"
+		}
+		b.Commit = rev
+	}
+	if b.Date == "" {
+		b.Date = settings["vcs.time"]
+	}
+	return b
+}
+
+// projectURL is where the project lives, for servers that want to know who
+// is calling
+const projectURL = "https://github.com/aapoleppanen/overhead_flights_monitor"
+
+// UserAgent identifies the app to the servers it fetches from, e.g.
+// "flight-monitor/v1.2.0 (+https://github.com/...)". Tile and photo
+// services ask for one that names the app and where to find it.
+func UserAgent() string {
+	v := Build().Version
+	if v == "" || v == "(devel)" {
+		v = "dev"
+	}
+	return "flight-monitor/" + v + " (+" + projectURL + ")"
+}
+
+// String reads like "v1.2.0 (3ba3015, 2026-01-02T15:04:05Z)"
+func (b BuildInfo) String() string {
+	s := b.Version
+	if s == "" {
+		s = "unknown"
+	}
+	switch {
+	case b.Commit != "" && b.Date != "":
+		s += fmt.Sprintf(" (%s, %s)", b.Commit, b.Date)
+	case b.Commit != "":
+		s += " (" + b.Commit + ")"
+	case b.Date != "":
+		s += " (" + b.Date + ")"
+	}
+	return s
+}
@@ -0,0 +1,25 @@
+package core
+
+// VerticalTrend says whether a flight is climbing, descending or level
+type VerticalTrend int
+
+const (
+	TrendLevel VerticalTrend = iota
+	TrendClimbing
+	TrendDescending
+)
+
+// Rates within this many ft/min of zero count as level flight; turbulence
+// and altitude holds wobble by a few hundred
+const levelFlightFpm = 300
+
+// Trend classifies the flight's reported vertical rate
+func (f Flight) Trend() VerticalTrend {
+	switch {
+	case f.VerticalRateFpm > levelFlightFpm:
+		return TrendClimbing
+	case f.VerticalRateFpm < -levelFlightFpm:
+		return TrendDescending
+	}
+	return TrendLevel
+}
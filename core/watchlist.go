@@ -0,0 +1,166 @@
+package core
+
+import (
+	"bufio"
+	"encoding/hex"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	watchlistFile = "watchlist.csv"
+	// An aircraft that leaves range for this long alerts again when it returns
+	watchRealertAfter = 30 * time.Minute
+)
+
+// WatchEntry is one aircraft of interest
+type WatchEntry struct {
+	Icao24       string
+	Registration string
+	Label        string // e.g. "Finnish Air Force", "Moomin livery"
+}
+
+// Name is what the UI shows for a hit
+func (e WatchEntry) Name() string {
+	if e.Label != "" {
+		return e.Label
+	}
+	if e.Registration != "" {
+		return e.Registration
+	}
+	return strings.ToUpper(e.Icao24)
+}
+
+// WatchHit is a watchlisted aircraft that has just come into range
+type WatchHit struct {
+	Entry  WatchEntry
+	Flight Flight
+}
+
+// Watchlist matches flights against imported hex codes and registrations
+// and reports when one comes into range
+type Watchlist struct {
+	mu     sync.Mutex
+	byHex  map[string]WatchEntry
+	byReg  map[string]WatchEntry
+	alerts arrivalTracker
+}
+
+func NewWatchlist() *Watchlist {
+	return &Watchlist{
+		byHex: make(map[string]WatchEntry),
+		byReg: make(map[string]WatchEntry),
+	}
+}
+
+// WatchlistPath is WATCHLIST if set, otherwise watchlist.csv in the data dir
+func WatchlistPath() string {
+	if p := os.Getenv("WATCHLIST"); p != "" {
+		return p
+	}
+	return dataPath(watchlistFile)
+}
+
+// Import reads a text or CSV file with one aircraft per line: a 6-digit hex
+// code or a registration, optionally followed by a label. Separators may be
+// commas, semicolons or tabs; blank lines, '#' comments and a header row are
+// skipped. Returns the number of entries added.
+func (w *Watchlist) Import(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	added := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ';' || r == '\t' })
+		if len(fields) == 0 {
+			continue
+		}
+		id := strings.Trim(strings.TrimSpace(fields[0]), `"`)
+		label := ""
+		if len(fields) > 1 {
+			label = strings.Trim(strings.TrimSpace(strings.Join(fields[1:], ", ")), `"`)
+		}
+
+		if isHexCode(id) {
+			hexID := strings.ToLower(id)
+			w.byHex[hexID] = WatchEntry{Icao24: hexID, Label: label}
+			added++
+			continue
+		}
+		lower := strings.ToLower(id)
+		if lower == "icao24" || lower == "hex" || lower == "registration" || lower == "reg" {
+			continue // header row
+		}
+		reg := normalizeRegistration(id)
+		w.byReg[reg] = WatchEntry{Registration: strings.ToUpper(id), Label: label}
+		added++
+	}
+	return added, scanner.Err()
+}
+
+// Len is the number of watched aircraft
+func (w *Watchlist) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.byHex) + len(w.byReg)
+}
+
+// Match looks f up by hex code, then by registration. Flights carry no
+// registration field, but GA traffic usually flies with its registration as
+// the callsign, so that is compared instead.
+func (w *Watchlist) Match(f Flight) (WatchEntry, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.match(f)
+}
+
+func (w *Watchlist) match(f Flight) (WatchEntry, bool) {
+	if e, ok := w.byHex[strings.ToLower(f.Icao24)]; ok {
+		return e, true
+	}
+	if e, ok := w.byReg[normalizeRegistration(f.Callsign)]; ok {
+		return e, true
+	}
+	return WatchEntry{}, false
+}
+
+// Arrivals returns watched aircraft in flights that weren't in range at the
+// previous poll (or were last seen more than watchRealertAfter ago)
+func (w *Watchlist) Arrivals(flights []Flight, now time.Time) []WatchHit {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var hits []WatchHit
+	for _, f := range flights {
+		if e, ok := w.match(f); ok && w.alerts.arrived(f.Icao24, now) {
+			hits = append(hits, WatchHit{Entry: e, Flight: f})
+		}
+	}
+	return hits
+}
+
+func isHexCode(s string) bool {
+	if len(s) != 6 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// normalizeRegistration drops case and dashes so "OH-LWA" matches "OHLWA"
+func normalizeRegistration(s string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(s), "-", ""))
+}
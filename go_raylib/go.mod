@@ -5,7 +5,12 @@ go 1.25.4
 require github.com/gen2brain/raylib-go/raylib v0.55.1
 
 require (
-	github.com/ebitengine/purego v0.7.1 // indirect
+	github.com/ebitengine/purego v0.9.0 // indirect
 	golang.org/x/exp v0.0.0-20240506185415-9bf2ced13842 // indirect
-	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/image v0.33.0 // indirect
+	golang.org/x/sys v0.36.0 // indirect
 )
+
+require flight-monitor v0.0.0
+
+replace flight-monitor => ../
@@ -1,41 +1,91 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"math"
 	"math/rand"
 	"os"
-	"sort"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"flight-monitor/core"
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
 const (
-	// Physical screen dimensions (Portrait)
-	// We'll treat the screen as natively landscape for simplicity if rotated via config,
-	// or we can stick to the 800x600 user mentioned.
-	// Let's assume the user wants the 854x480 logical size we settled on.
+	// The virtual landscape canvas everything is drawn on; fitScreen turns
+	// and scales it onto the physical screen
 	screenWidth  = 1280
 	screenHeight = 720
 
 	defaultZoom = 11
-
-	// UI Colors
-	colBgDark     = 0x0f172aff // #0f172a
-	colAccent     = 0x38bdf8ff // #38bdf8
-	colGlass      = 0x0f172af2 // 95% opacity
-	colGlassLight = 0x334155ff // #334155
-	colText       = 0xf1f5f9ff // #f1f5f9
-	colTextMuted  = 0x94a3b8ff // #94a3b8
-	colSuccess    = 0x4ade80ff // #4ade80
-	colDanger     = 0xf87171ff // #f87171
+	// Arrivals listed under the runways in use; the rest are only
+	// numbered on the map
+	arrivalQueueShown = 5
+	// Distance between the ticks along an approach corridor
+	approachTickKm = 5.0
+	// Zoom levels a notch of the mouse wheel moves
+	wheelZoomStep = 0.5
+
+	// Frames a second, and fewer while saving energy
+	normalFPS = 60
+	ecoFPS    = 20
+
+	// How long quitting waits for polling and history writes to wind down
+	shutdownTimeout = 3 * time.Second
+
+	// The splash gives up on the first flight fetch after this; polling
+	// carries on trying in the background
+	startupFetchTimeout = 20 * time.Second
+	// How long startup errors stay on the splash before moving on, so an
+	// unattended kiosk still reaches the map
+	splashErrorHold = 10 * time.Second
+
+	// Days of noise events the noise screen charts
+	noiseChartDays = 14
+
+	// UI Colors, shared with UI drawn through core.Renderer
+	colBgDark     = core.ColBgDark
+	colAccent     = core.ColAccent
+	colGlass      = core.ColGlass
+	colGlassLight = core.ColGlassLight
+	colText       = core.ColText
+	colTextMuted  = core.ColTextMuted
+	colSuccess    = core.ColSuccess
+	colDanger     = core.ColDanger
+	colGold       = core.ColGold
+	colAlert      = core.ColAlert
 )
 
+// drawTrendArrow draws a small up or down triangle centred on (x, y) for a
+// climbing or descending flight
+func drawTrendArrow(x, y float32, trend core.VerticalTrend) {
+	if trend == core.TrendDescending {
+		rl.DrawTriangle(rl.Vector2{X: x - 6, Y: y - 5}, rl.Vector2{X: x, Y: y + 6}, rl.Vector2{X: x + 6, Y: y - 5}, getRlColor(colGold))
+		return
+	}
+	rl.DrawTriangle(rl.Vector2{X: x, Y: y - 6}, rl.Vector2{X: x - 6, Y: y + 5}, rl.Vector2{X: x + 6, Y: y + 5}, getRlColor(colSuccess))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
 func getRlColor(hex uint32) rl.Color {
+	if name, ok := colorNames[hex]; ok {
+		hex = devLayout.Color(name, hex)
+	}
 	return rl.Color{
 		R: uint8(hex >> 24),
 		G: uint8(hex >> 16),
@@ -49,20 +99,57 @@ var (
 	myLon = 24.780103286993022
 )
 
+// devLayout is set when UI_LAYOUT names a file to tweak the layout and
+// colours from while the app runs; nil otherwise
+var devLayout *core.DevLayout
+
+// colorNames are the theme colours' names in the UI_LAYOUT file
+var colorNames = map[uint32]string{
+	colBgDark:     "bg_dark",
+	colAccent:     "accent",
+	colGlass:      "glass",
+	colGlassLight: "glass_light",
+	colText:       "text",
+	colTextMuted:  "text_muted",
+	colSuccess:    "success",
+	colDanger:     "danger",
+	colGold:       "gold",
+	colAlert:      "alert",
+}
+
+// lay is the layout value called name, from the UI_LAYOUT file when there
+// is one
+func lay(name string, def int) int {
+	return devLayout.Int(name, def)
+}
+
 // GameState enum
 type State int
 
 const (
-	StateLogin State = iota
+	StateSplash State = iota // startup work in progress
+	StateLogin
 	StateMap
 	StateGameBriefing
 	StateGamePlaying
 	StateRoundSetup
 	StateGameOver
 	StateLeaderboard
+	StateSettings
+	StateReplayEntry
+	StateStatus       // health of the provider, caches and data dir
+	StateNoise        // low overhead flights per hour and day
+	StateRegulars     // flights that pass at about the same time most days
+	StateArrivalBonus // guessing minutes to landing after the last round
+	StateDiary        // notes and photos on overhead passes
+	StateStorage      // retention settings and disk usage
+	StateResolvers    // how each route resolver is doing
+	StateCalibrate    // tapping targets to correct the touch panel
+	StateOddOneOut    // picking the flight that doesn't match the others
 )
 
 type Button struct {
+	ID         string // see core.WidgetID
 	X, Y, W, H int
 	Text       string
 	Action     func()
@@ -71,20 +158,98 @@ type Button struct {
 }
 
 type Game struct {
-	flightClient *FlightClient
-	tileLoader   *TileLoader
-	dataManager  *DataManager
-	scraper      *Scraper
-	flights      []Flight
-	state        State
-	shouldQuit   bool
+	ctx         context.Context // cancelled by main on quit
+	bg          sync.WaitGroup  // background loops shutdown waits for
+	clock       core.GameClock  // round timers; pauses across suspend
+	wake        chan struct{}   // cuts the poll wait short after a resume
+	provider    core.FlightProvider
+	profile     core.ResourceProfile
+	tileLoader  *TileLoader
+	attribution core.Attribution // credit for the tiles, over a corner of the map
+	power       *core.PowerMonitor
+	targetFPS   int32          // as last set with rl.SetTargetFPS
+	prefetched  core.TileCoord // centre tile of the view tiles were last prefetched for
+	dataManager *core.DataManager
+	resolver    core.DetailsResolver
+	tracks      *core.TrackRecorder
+	history     *core.TrackHistory
+	watchlist   *core.Watchlist
+	tags        *core.TagDB
+	aircraft    *core.AircraftDB
+	exporter    *core.DailyExporter
+	receiver    *core.ReceiverMonitor // nil unless RECEIVER_URL is set
+	updates     *core.UpdateChecker   // nil unless UPDATE_CHECK is set
+	backup      *core.Backup          // nil unless BACKUP_URL is set
+	scoreSync   *core.ScoreSync       // nil unless LEADERBOARD_URL is set
+	metar       *core.MetarClient     // sky report near home, nil if METAR_STATION=off
+	photos      *core.PhotoCache      // planespotters thumbnails for the info panel
+	party       *core.PartyServer     // nil unless PARTY_ADDR is set
+	polar       *core.PolarRange      // nil unless reading our own receiver
+	pipeline    *core.FlightPipeline
+	snapshot    *core.FlightSnapshot // last snapshot picked up by syncFlights
+	flights     *core.FlightStore    // merged from snapshots
+	state       State
+	shouldQuit  bool
+
+	// The selected aircraft's photo, uploaded once
+	photoKey string
+	photoTex rl.Texture2D
 
 	// Data
-	currentUser   UserStats
-	usersMap      map[string]UserStats
-	highScores    []ScoreEntry
-	userStatsList []UserStats
-	airports      []string
+	users         *core.UserStore
+	highScores    []core.ScoreEntry
+	todayStats    core.DayStats
+	userStatsList []core.UserStats
+	settings      *core.SettingsStore
+	activeRegion  int // index into settings.WatchRegions; the camera's home
+
+	startup      core.Startup
+	splashDoneAt time.Time // when startup finished, zero until then
+
+	fetchHealth core.FetchHealth  // recorded by the polling goroutine
+	health      core.HealthReport // taken when the status screen opens
+
+	noise      *core.NoiseCounter // nil when the flights aren't live
+	noiseStats core.NoiseStats    // taken when the noise screen opens
+	noiseMsg   string             // result of the last CSV export
+
+	routeCache    *core.RouteCache
+	learned       *core.LearnedRoutes
+	prefetch      *core.Prefetcher     // nil when PREFETCH_PER_MIN is off or nothing needs resolving
+	overhead      *core.OverheadLog    // nil when the flights aren't live
+	runways       *core.RunwayMonitor  // nil when the flights aren't live
+	runwayUse     core.RunwaysInUse    // shown on the map, e.g. "Arrivals RWY 22L"
+	arrivalQueue  []core.QueuedArrival // inbound to runwayUse, nearest touchdown first
+	regulars      []core.Regular       // for early/late alerts; pipeline goroutine only
+	regularsAt    time.Time            // when regulars was worked out; pipeline goroutine only
+	regularAlerts chan string          // pipeline goroutine to UI
+	regularsList  []core.Regular       // taken when the regulars screen opens
+
+	// Spotting diary, loaded when its screen opens
+	diary       []core.SpottingEntry
+	diaryPasses []core.OverheadPass
+	diaryView   int
+	diaryPage   int
+	diaryEdit   *core.SpottingEntry // being written, nil when browsing
+	diaryMsg    string
+	diaryFiles  []string // photos to pick from
+	diaryFile   int
+	diaryTexKey string
+	diaryTex    rl.Texture2D
+
+	storageUsage []core.DirEntryUsage // measured when the storage screen opens
+	storageMsg   string               // result of the last manual prune
+
+	showReleaseNotes bool // the update badge's notes panel is open
+
+	flightsSavedAt time.Time // last save of last_flights.json; pipeline goroutine only
+
+	watchAlert      string
+	watchAlertUntil time.Time
+	emergencies     []core.Flight // in range and squawking 7500/7600/7700
+	airlineLegend   []core.LegendEntry
+	airports        []string
+	airportDB       *core.AirportDB // empty until imported with -import-openflights
 
 	// Login Input
 	inputText         string
@@ -96,7 +261,7 @@ type Game struct {
 	// Camera
 	camLat  float64
 	camLon  float64
-	camZoom int
+	camZoom float64 // fractional while pinching, see core.TileLevel
 
 	// Touch/Input
 	isDragging    bool
@@ -110,44 +275,108 @@ type Game struct {
 	planeTex rl.Texture2D
 
 	// Selected Plane
-	selectedPlane   *Flight
-	resolvedDetails *ResolvedDetails
+	selectedPlane   *core.Flight
+	resolvedDetails *core.ResolvedDetails
 	resolving       bool
+	selectedTrack   []core.TrackPoint    // flown path of selectedPlane
+	selectCancel    context.CancelFunc   // stops the selected plane's lookups
+	selectSeq       int                  // bumped on every change of selection
+	selectResults   chan selectionResult // lookups to UI
+	roundCancel     context.CancelFunc   // stops the round setup's lookup
+	roundSeq        int                  // bumped on every round setup
+	roundResults    chan roundResult     // round setup lookups to UI
 
 	// Game Logic
-	score           int
-	targetPlane     *Flight
-	round           int
-	roundStartTime  time.Time
-	questionText    string
-	options         []string
-	correctOption   string
+	score          int
+	targetPlane    *core.Flight
+	round          int
+	roundStartTime time.Duration // on clock
+	questionText   string
+	options        []string
+	correctOption  string
+	typeRound      bool   // asking for the aircraft type rather than the route
+	typeHint       string // masked model string shown during a type round
+	strategy       string // distractor strategy of a route round; see core.DistractorExperiment
+	difficulty     core.Difficulty
+	roundTime      time.Duration
+
+	// Arrival bonus round after the last question
+	bonusTarget  string                // icao24 of a quizzed flight whose landing time can be estimated
+	bonusDetails *core.ResolvedDetails // its resolved route
+	bonusName    string                // what the question calls it
+	bonusGuess   int                   // minutes
+	bonusResult  string                // how the guess scored, for the game over panel
+
+	// Odd-one-out puzzles from the flights on the map
+	odd      core.OddOneOut
+	oddErr   error // why no puzzle could be made from the traffic
+	oddPick  int   // the option picked, -1 until the player answers
+	oddRight int   // puzzles solved since the screen was opened
+	oddTried int
+
+	// Party mode: phones that joined answer instead of the kiosk
+	partyGame bool
+
+	// Share codes and replays of logged games
+	gameSeed    uint16
+	roundLog    []core.RoundRecord
+	shareCode   string
+	replay      *core.GameRecord // set while replaying a shared game
+	replayScore int              // the other player's score from the code
+	replayError string
+
+	// New-best celebration on the game over panel
+	particles       core.Particles
+	celebration     core.AchievementKind // record the finished game set, if any
+	podium          []core.ScoreEntry    // top three including the finished game
+	podiumPlace     int                  // the finished game's index in podium, -1 if off it
 	wrongGuess      string
 	showResult      bool
 	resultCorrect   bool
-	resultStartTime time.Time
+	resultStartTime time.Duration // on clock
 
 	// UI Elements
-	buttons []Button
+	buttons []Button       // built by the draw pass, drawn and published to ui
+	ui      *core.UIEvents // presses queued in Update, dispatched against the last layout
 
 	// Rendering
 	renderTexture rl.RenderTexture2D
-	isPortrait    bool
-	destRect      rl.Rectangle
-	sourceRect    rl.Rectangle
-	origin        rl.Vector2
+	orientation   core.Orientation     // as configured; may be auto
+	screenXf      core.ScreenTransform // render texture to the screen, see fitScreen
+	touchCal      core.TouchCalibration
+
+	calibrator     *core.TouchCalibrator // the calibration screen's run
+	calibrationMsg string
 }
 
-func NewGame(fc *FlightClient) *Game {
+func NewGame(ctx context.Context, provider core.FlightProvider, profile core.ResourceProfile) *Game {
+	dm := &core.DataManager{}
 	g := &Game{
-		flightClient: fc,
-		tileLoader:   NewTileLoader(),
-		dataManager:  &DataManager{},
-		scraper:      NewScraper(),
-		camLat:       myLat,
-		camLon:       myLon,
-		camZoom:      defaultZoom,
-		state:        StateLogin,
+		ctx:           ctx,
+		ui:            core.NewUIEvents(),
+		wake:          make(chan struct{}, 1),
+		provider:      provider,
+		profile:       profile,
+		power:         core.NewPowerMonitor(),
+		attribution:   core.MapAttribution(),
+		tileLoader:    NewTileLoader(ctx, profile.MaxTiles, profile.TileResolution, profile.TileFetches),
+		dataManager:   dm,
+		users:         core.NewUserStore(),
+		tracks:        core.NewTrackRecorder(),
+		history:       core.NewTrackHistory(dm),
+		watchlist:     core.NewWatchlist(),
+		tags:          core.NewTagDB(),
+		aircraft:      core.NewAircraftDB(),
+		pipeline:      core.NewFlightPipeline(myLat, myLon),
+		flights:       core.NewFlightStore(),
+		regularAlerts: make(chan string, 4),
+		selectResults: make(chan selectionResult, 4),
+		roundResults:  make(chan roundResult, 4),
+		exporter:      core.NewDailyExporter(dm, myLat, myLon, 1.0),
+		camLat:        myLat,
+		camLon:        myLon,
+		camZoom:       defaultZoom,
+		state:         StateSplash,
 		keyboardLayout: []string{
 			"QWERTYUIOP",
 			"ASDFGHJKL",
@@ -155,24 +384,285 @@ func NewGame(fc *FlightClient) *Game {
 		},
 	}
 
-	g.refreshUsers()
-	g.refreshAirports()
-	go g.refreshFlights()
+	// Settings are needed by nearly everything and are a small local file;
+	// the rest loads behind the splash screen
+	s, err := g.dataManager.LoadSettings()
+	if err != nil {
+		log.Println("Error loading settings:", err)
+	}
+	if g.touchCal, err = g.dataManager.LoadTouchCalibration(); err != nil {
+		log.Println("Error loading touch calibration:", err)
+	}
+	g.settings = core.NewSettingsStore(s)
+	g.history.SetRetentionDays(s.Retention.Tracks())
+
+	g.routeCache = core.NewRouteCache(g.dataManager)
+	g.learned = core.NewLearnedRoutes(g.dataManager)
+	g.resolver = core.NewDetailsResolver(ctx, g.routeCache, g.learned)
+	// The simulator answers route lookups for its own callsigns, offline
+	if sim, ok := provider.(*core.SimulatedProvider); ok {
+		g.resolver = sim
+	} else if provider.Name() != "replay" {
+		g.prefetch = core.NewPrefetcher(g.routeCache, g.learned)
+	}
+
+	if provider.Name() == "local" {
+		g.polar = core.NewPolarRange(myLat, myLon)
+		if err := g.polar.Load(g.dataManager); err != nil {
+			log.Println("Error loading polar range:", err)
+		}
+	}
+
+	g.snapshot = g.pipeline.Snapshot()
+	// Show where planes were at the end of the last run until the first poll lands
+	if flights, at, err := g.dataManager.LoadLastFlights(provider.Name(), time.Now()); err != nil {
+		log.Println("Error loading last flights:", err)
+	} else if len(flights) > 0 {
+		g.flights.Merge(flights, at)
+	}
+	g.particles.Disabled = !profile.Particles
+	g.pipeline.AddStage(g.recordHistory)
+	g.pipeline.AddStage(g.saveLastFlights)
+	// Simulated and replayed traffic mustn't end up in a noise complaint
+	if name := provider.Name(); name != "sim" && name != "replay" {
+		g.noise = core.NewNoiseCounter(myLat, myLon)
+		g.pipeline.AddStage(g.recordNoise)
+		g.overhead = core.NewOverheadLog(myLat, myLon)
+		g.pipeline.AddStage(g.recordOverhead)
+		g.runways = core.NewRunwayMonitor()
+		g.pipeline.AddStage(g.recordRunways)
+	}
+	g.spawn(func() { g.pipeline.Run(ctx) })
+	g.spawn(func() { core.RunProvider(ctx, provider) })
+	g.spawn(func() { g.power.Run(ctx) })
+	g.spawn(func() {
+		g.startup.Run(ctx, g.startupSteps())
+		for _, e := range g.startup.Progress().Errors {
+			log.Println("Startup:", e)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		g.spawn(g.refreshFlights)
+		if provider.Name() != "sim" { // simulated flights carry their types already
+			g.spawn(g.loadAircraftDB)
+		}
+	})
+
+	if url := os.Getenv("RECEIVER_URL"); url != "" {
+		g.receiver = core.NewReceiverMonitor(url, myLat, myLon)
+		g.spawn(func() { g.receiver.Run(ctx) })
+	}
+	if g.prefetch != nil {
+		g.spawn(func() { g.prefetch.Run(ctx) })
+	}
+	if g.metar = core.NewMetarClient(myLat, myLon); g.metar != nil {
+		g.spawn(func() { g.metar.Run(ctx) })
+	}
+	if exporter := core.NewMapExporter(myLat, myLon); exporter != nil {
+		g.spawn(func() { exporter.Run(ctx, g.pipeline, g.settings) })
+	}
+	if b, err := core.NewBackup(); err != nil {
+		log.Println("Backups off:", err)
+	} else if b != nil {
+		g.backup = b
+		g.spawn(func() { b.Run(ctx) })
+	}
+	if s, err := core.NewScoreSync(g.dataManager); err != nil {
+		log.Println("Leaderboard sync off:", err)
+	} else if s != nil {
+		g.scoreSync = s
+		g.spawn(func() { s.Run(ctx) })
+	}
+	g.photos = core.NewPhotoCache(ctx, g.dataManager)
+	if addr := core.PartyAddr(); addr != "" {
+		g.party = core.NewPartyServer(addr)
+		g.spawn(func() { g.party.Run(ctx) })
+	}
+	if core.UpdateCheckEnabled() {
+		g.updates = core.NewUpdateChecker()
+		g.spawn(func() { g.updates.Run(ctx) })
+	}
+	if path := core.DevLayoutPath(); path != "" {
+		devLayout = core.NewDevLayout(path)
+		g.spawn(func() { devLayout.Run(ctx) })
+	}
 
 	return g
 }
 
+// startupSteps is the launch work done behind the splash screen
+func (g *Game) startupSteps() []core.StartupStep {
+	return []core.StartupStep{
+		// Before anything reads the logs, so it reads less
+		{Name: "Pruning old data", Run: func(context.Context) error {
+			r, err := g.dataManager.Prune(g.settings.Get().Retention, time.Now())
+			if err == nil {
+				log.Println("Retention:", r)
+			}
+			return err
+		}},
+		{Name: "Loading players", Run: func(context.Context) error {
+			users, err := g.dataManager.LoadUsers()
+			if err != nil {
+				return err
+			}
+			g.users.SetAll(users)
+			return nil
+		}},
+		{Name: "Loading airport database", Run: func(context.Context) error {
+			db, err := g.dataManager.LoadAirportDB()
+			if err != nil {
+				return err
+			}
+			g.airportDB = db
+			g.applyHomeAirport()
+			return nil
+		}},
+		{Name: "Loading airports", Run: func(context.Context) error {
+			g.refreshAirports() // falls back to a built-in list
+			return nil
+		}},
+		{Name: "Loading watchlist", Run: func(context.Context) error {
+			n, err := g.watchlist.Import(core.WatchlistPath())
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err == nil {
+				log.Printf("Loaded %d watchlist entries", n)
+			}
+			return err
+		}},
+		{Name: "Loading tag database", Run: func(context.Context) error {
+			n, err := g.tags.Load(core.TagDBPath())
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err == nil {
+				log.Printf("Loaded %d tagged aircraft", n)
+			}
+			return err
+		}},
+		{Name: "Opening offline map", Run: func(context.Context) error {
+			path := core.MBTilesPath()
+			if path == "" {
+				return nil
+			}
+			m, err := core.OpenMBTiles(path)
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			log.Println("Offline map tiles from", path)
+			g.tileLoader.SetArchive(m)
+			return nil
+		}},
+		// Signs in to OpenSky with the loaded credentials on the way
+		{Name: "Fetching flights from " + g.provider.Name(), Run: g.fetchInitialFlights},
+	}
+}
+
+// fetchInitialFlights fills the map before the first poll would, giving up
+// after startupFetchTimeout so a dead network can't hold up the splash
+func (g *Game) fetchInitialFlights(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, startupFetchTimeout)
+	defer cancel()
+	settings := g.settings.Get()
+	flights, err := core.FetchRegions(ctx, g.provider, settings, settings.WatchRegions(myLat, myLon))
+	g.fetchHealth.Record(len(flights), err, time.Now())
+	if err != nil {
+		return err
+	}
+	g.aircraft.Enrich(flights)
+	g.pipeline.Submit(flights, time.Now())
+	return nil
+}
+
+// updateSplash moves on to the login screen once startup is done, holding
+// for a while first if anything failed so the errors can be read
+func (g *Game) updateSplash() {
+	p := g.startup.Progress()
+	if !p.Finished {
+		return
+	}
+	if g.splashDoneAt.IsZero() {
+		g.splashDoneAt = time.Now()
+	}
+	if len(p.Errors) == 0 || time.Since(g.splashDoneAt) > splashErrorHold {
+		g.state = StateLogin
+	}
+}
+
+// splashStatus is the line under the progress bar
+func splashStatus(p core.StartupProgress) string {
+	switch {
+	case !p.Finished:
+		return p.Step + "..."
+	case len(p.Errors) > 0:
+		return "Started with problems:"
+	}
+	return "Ready"
+}
+
+// spawn runs fn on a goroutine that shutdown waits for
+func (g *Game) spawn(fn func()) {
+	g.bg.Add(1)
+	go func() {
+		defer g.bg.Done()
+		fn()
+	}()
+}
+
+// shutdown waits for the background loops to notice the cancelled context,
+// giving up after shutdownTimeout so a hung request can't block exit
+func (g *Game) shutdown() {
+	done := make(chan struct{})
+	go func() {
+		g.bg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		log.Println("Timed out waiting for background work to stop")
+	}
+	if err := g.dataManager.SaveLastFlights(g.provider.Name(), g.pipeline.Snapshot()); err != nil {
+		log.Println("Error saving last flights:", err)
+	}
+}
+
+// loadAircraftDB refreshes the cached aircraft database if it is out of date
+// and loads it; until then flights simply go without registrations
+func (g *Game) loadAircraftDB() {
+	path := core.AircraftDBPath()
+	if fetched, err := g.aircraft.Download(g.ctx, path); err != nil {
+		if g.ctx.Err() != nil {
+			return
+		}
+		log.Println("Error downloading aircraft database:", err)
+	} else if fetched {
+		log.Println("Downloaded aircraft database to", path)
+	}
+	if n, err := g.aircraft.Load(path); err == nil {
+		log.Printf("Loaded %d aircraft", n)
+	} else if !os.IsNotExist(err) {
+		log.Println("Error loading aircraft database:", err)
+	}
+}
+
 func (g *Game) refreshUsers() {
 	users, err := g.dataManager.LoadUsers()
 	if err == nil {
-		g.usersMap = users
+		g.users.SetAll(users)
 	}
 }
 
 func (g *Game) refreshAirports() {
 	airports, err := g.dataManager.LoadAirports()
-	if err == nil && len(airports) > 0 {
-		g.airports = airports
+	if pool := g.airportDB.QuizPool(airports); err == nil && len(pool) > 0 {
+		g.airports = pool
 	} else {
 		g.airports = []string{"London", "Paris", "Berlin", "Helsinki", "Tokyo", "New York", "Dubai", "Rome"}
 	}
@@ -184,40 +674,294 @@ func (g *Game) refreshLeaderboard() {
 		g.highScores = scores
 		g.userStatsList = stats
 	}
+	if history, err := g.dataManager.LoadScoreHistory(); err == nil {
+		g.todayStats = core.DailyStats(history, time.Now())
+	}
+}
+
+// eco reports whether to save energy: on battery unless the player says
+// otherwise
+func (g *Game) eco() bool {
+	return g.power.Eco(g.settings.Get().EcoMode)
+}
+
+// pollInterval is how long to wait between polls, stretched while saving
+// energy
+func (g *Game) pollInterval(settings core.Settings) time.Duration {
+	interval := g.profile.PollInterval(settings.PollInterval(g.provider))
+	if g.eco() {
+		interval = core.EcoPollInterval(interval)
+	}
+	return interval
+}
+
+// fps is the frame rate to aim for, lower while saving energy
+func (g *Game) fps() int32 {
+	if g.eco() {
+		return ecoFPS
+	}
+	return normalFPS
+}
+
+// toggleEco flips whether energy is saved, overriding the power source
+func (g *Game) toggleEco() {
+	onBattery := g.power.OnBattery()
+	g.updateSettings(func(s *core.Settings) { s.EcoMode = s.EcoMode.Toggle(onBattery) })
+	g.prefetched = core.TileCoord{} // prefetch again, or stop
 }
 
 func (g *Game) refreshFlights() {
 	for {
-		flights, err := g.flightClient.FetchFlights(myLat, myLon, 1.0)
+		settings := g.settings.Get()
+		flights, err := core.FetchRegions(g.ctx, g.provider, settings, settings.WatchRegions(myLat, myLon))
+		if g.ctx.Err() != nil {
+			return
+		}
+		g.fetchHealth.Record(len(flights), err, time.Now())
 		if err != nil {
 			log.Println("Error fetching flights:", err)
 		} else {
-			g.flights = flights
-			// Update selected/target references
-			if g.selectedPlane != nil {
-				found := false
-				for _, f := range flights {
-					if f.Icao24 == g.selectedPlane.Icao24 {
-						g.selectedPlane = &f
-						found = true
-						break
-					}
-				}
-				if !found {
-					// Plane disappeared
+			g.aircraft.Enrich(flights)
+			g.pipeline.Submit(flights, time.Now())
+		}
+		// The poll may have shown the clock right at last
+		if err := g.dataManager.StampHeldScores(time.Now()); err != nil {
+			log.Println("Error saving held scores:", err)
+		}
+		select {
+		case <-time.After(g.pollInterval(settings)):
+		case <-g.wake:
+		case <-g.ctx.Done():
+			return
+		}
+	}
+}
+
+// resume runs on the first frame after a suspend or stall. The round timer
+// has already skipped the gap; the flights on screen are out of date, so
+// poll again straight away.
+func (g *Game) resume(gap time.Duration) {
+	log.Printf("Resumed after %s, refreshing flights", gap.Round(time.Second))
+	select {
+	case g.wake <- struct{}{}:
+	default:
+	}
+}
+
+// recordHistory runs on the pipeline goroutine for every new snapshot
+func (g *Game) recordHistory(s *core.FlightSnapshot) {
+	if g.profile.History {
+		// Export yesterday's traffic once the date rolls over
+		if day, finished := g.tracks.Record(s.Flights, s.FetchedAt); finished != nil {
+			go func() {
+				path, err := g.exporter.Export(day, finished)
+				if err != nil {
+					log.Println("Daily export failed:", err)
+					return
 				}
+				log.Println("Saved daily traffic GIF:", path)
+			}()
+		}
+
+		if err := g.history.Append(s.Flights, s.FetchedAt); err != nil {
+			log.Println("Error saving track history:", err)
+		}
+	}
+
+	if g.polar != nil {
+		g.polar.Add(s.Flights)
+		if err := g.polar.SaveIfDirty(g.dataManager); err != nil {
+			log.Println("Error saving polar range:", err)
+		}
+	}
+}
+
+// saveLastFlights runs on the pipeline goroutine, keeping last_flights.json
+// fresh enough to fill the map at the next start
+func (g *Game) saveLastFlights(s *core.FlightSnapshot) {
+	if core.Elapsed(g.flightsSavedAt, s.FetchedAt) < core.LastFlightsSaveInterval {
+		return
+	}
+	g.flightsSavedAt = s.FetchedAt
+	if err := g.dataManager.SaveLastFlights(g.provider.Name(), s); err != nil {
+		log.Println("Error saving last flights:", err)
+	}
+}
+
+// recordNoise runs on the pipeline goroutine, logging aircraft that pass
+// low overhead
+func (g *Game) recordNoise(s *core.FlightSnapshot) {
+	events := g.noise.Observe(s, g.settings.Get().NoiseCeiling())
+	for _, e := range events {
+		log.Printf("Noise event: %s at %d ft, %.1f km away", e.Callsign, e.AltitudeFt, e.DistanceKm)
+	}
+	if err := g.dataManager.SaveNoiseEvents(events); err != nil {
+		log.Println("Error saving noise events:", err)
+	}
+}
+
+// recordRunways runs on the pipeline goroutine, following arrivals onto
+// final at the home airport
+func (g *Game) recordRunways(s *core.FlightSnapshot) {
+	g.runways.Observe(s.Flights, s.FetchedAt)
+}
+
+// recordOverhead runs on the pipeline goroutine, logging passes near home
+// and spotting regulars that turn up early or late
+func (g *Game) recordOverhead(s *core.FlightSnapshot) {
+	arrived, ended := g.overhead.Observe(s)
+	if err := g.dataManager.SaveOverheadPasses(ended); err != nil {
+		log.Println("Error saving overhead passes:", err)
+	}
+	settings := g.settings.Get()
+	arrived = settings.AlertFilter.Apply(arrived)
+	if len(arrived) == 0 || !settings.AlertRegulars {
+		return
+	}
+	if g.regularsAt.IsZero() || core.Elapsed(g.regularsAt, s.FetchedAt) > time.Hour {
+		g.regulars, g.regularsAt = g.loadRegulars(s.FetchedAt), s.FetchedAt
+	}
+	for _, f := range arrived {
+		if msg, ok := core.RegularAlert(g.regulars, f, s.FetchedAt); ok {
+			select {
+			case g.regularAlerts <- msg:
+			default: // the UI hasn't caught up; this one can go
 			}
-			if g.targetPlane != nil {
-				for _, f := range flights {
-					if f.Icao24 == g.targetPlane.Icao24 {
-						g.targetPlane = &f
-						break
-					}
-				}
+		}
+	}
+}
+
+// loadRegulars works out the regulars from the recent overhead log
+func (g *Game) loadRegulars(now time.Time) []core.Regular {
+	passes, err := g.dataManager.LoadOverheadPasses(now.AddDate(0, 0, -core.RegularsWindowDays))
+	if err != nil {
+		log.Println("Error loading overhead passes:", err)
+	}
+	return core.FindRegulars(passes, g.routeCache.Known)
+}
+
+// syncFlights merges the latest pipeline snapshot into the flight store on
+// the UI thread. Selected/target planes are store pointers, so they update in place.
+func (g *Game) syncFlights() {
+	for len(g.regularAlerts) > 0 {
+		msg := <-g.regularAlerts
+		log.Println("Regular:", msg)
+		g.watchAlert = msg
+		g.watchAlertUntil = time.Now().Add(30 * time.Second)
+	}
+
+	s := g.pipeline.Snapshot()
+	if s == g.snapshot {
+		return
+	}
+	g.snapshot = s
+	g.flights.Merge(s.Flights, s.FetchedAt)
+
+	// Everything shown may be on the watchlist, but only what passes the
+	// alert filter raises an alert
+	settings := g.settings.Get()
+	alertable := settings.AlertFilter.Apply(s.Flights)
+	for _, hit := range g.watchlist.Arrivals(alertable, s.FetchedAt) {
+		msg := fmt.Sprintf("WATCHLIST: %s (%s) in range", hit.Entry.Name(), hit.Flight.Callsign) + g.spotNote(hit.Flight)
+		log.Println(msg)
+		g.watchAlert = msg
+		g.watchAlertUntil = time.Now().Add(30 * time.Second)
+		g.pulseAt(hit.Flight, colAlert)
+	}
+	if settings.AlertInteresting {
+		for _, hit := range g.tags.Arrivals(alertable, s.FetchedAt) {
+			msg := fmt.Sprintf("%s: %s", strings.ToUpper(string(hit.Info.Tag)), hit.Flight.Callsign)
+			if hit.Info.Operator != "" {
+				msg += " (" + hit.Info.Operator + ")"
 			}
+			msg += g.spotNote(hit.Flight)
+			log.Println("Interesting traffic:", msg)
+			g.watchAlert = msg
+			g.watchAlertUntil = time.Now().Add(30 * time.Second)
+			g.pulseAt(hit.Flight, colAlert)
+		}
+	}
+
+	g.airlineLegend = core.AirlineLegend(s.Flights, 6)
+	g.runwayUse = g.runways.InUse(g.metar.Sky(), time.Now())
+	g.arrivalQueue = core.ArrivalQueue(s.Flights, g.runwayUse.Approaches, core.HomeAirport())
+	g.prefetchVisible()
+
+	prev := g.emergencies
+	g.emergencies = core.Emergencies(g.flights.Snapshot())
+	for _, f := range g.emergencies {
+		if !slices.ContainsFunc(prev, func(p core.Flight) bool { return p.Icao24 == f.Icao24 && p.Squawk == f.Squawk }) {
+			log.Printf("EMERGENCY: %s squawking %s (%s)", f.Callsign, f.Squawk, f.Emergency)
+			g.pulseAt(f, colDanger)
+		}
+	}
+}
+
+// prefetchVisible queues the flights on screen for a background route
+// lookup, those nearest the middle first. Saving energy, nothing is.
+func (g *Game) prefetchVisible() {
+	if g.prefetch == nil {
+		return
+	}
+	if g.eco() {
+		g.prefetch.Want(nil)
+		return
+	}
+	offCentre := func(f core.Flight) float64 {
+		x, y := g.screenPos(f.Lat, f.Lon)
+		return math.Hypot(x-screenWidth/2, y-screenHeight/2)
+	}
+	var visible []core.Flight
+	for _, f := range g.flights.Snapshot() {
+		if x, y := g.screenPos(f.Lat, f.Lon); x >= 0 && y >= 0 && x <= screenWidth && y <= screenHeight {
+			visible = append(visible, f)
 		}
-		time.Sleep(5 * time.Second)
 	}
+	slices.SortFunc(visible, func(a, b core.Flight) int { return cmp.Compare(offCentre(a), offCentre(b)) })
+	g.prefetch.Want(visible)
+}
+
+// spotNote tells an alert whether the flight can be seen from home
+func (g *Game) spotNote(f core.Flight) string {
+	if f.OnGround {
+		return ""
+	}
+	return " - " + core.Spotability(f, g.metar.Sky(), myLat, myLon, time.Now()).Reason
+}
+
+// airframeLine is the registration, type and operator line of the flight
+// info panel. The type is left out for the quiz target, where it could give
+// a type round away. A registration only the resolver knew is shown too.
+func (g *Game) airframeLine(f *core.Flight) string {
+	reg := f.Registration
+	if reg == "" && g.resolvedDetails != nil {
+		reg = g.resolvedDetails.Registration
+	}
+	parts := []string{reg}
+	if g.state != StateGamePlaying || g.targetPlane == nil || f.Icao24 != g.targetPlane.Icao24 {
+		parts = append(parts, f.TypeCode)
+	}
+	parts = append(parts, f.Operator)
+	return strings.Join(slices.DeleteFunc(parts, func(s string) bool { return s == "" }), "  ")
+}
+
+// screenPos projects a map position onto the screen
+func (g *Game) screenPos(lat, lon float64) (float64, float64) {
+	centerX, centerY := core.LatLonToPixels(g.camLat, g.camLon, g.camZoom)
+	x, y := core.LatLonToPixels(lat, lon, g.camZoom)
+	return x - centerX + float64(screenWidth)/2, y - centerY + float64(screenHeight)/2
+}
+
+// pulseAt rings a flight that raised an alert, if it is on screen
+func (g *Game) pulseAt(f core.Flight, col uint32) {
+	if g.state != StateMap && g.state != StateGamePlaying {
+		return
+	}
+	x, y := g.screenPos(f.Lat, f.Lon)
+	if x < 0 || y < 0 || x > screenWidth || y > screenHeight {
+		return
+	}
+	g.particles.Pulse(x, y, col, 3)
 }
 
 // createPlaneTexture generates a simple plane sprite
@@ -261,38 +1005,28 @@ func (g *Game) Init() {
 	g.renderTexture = rl.LoadRenderTexture(screenWidth, screenHeight) // 1280x720
 	rl.SetTextureFilter(g.renderTexture.Texture, rl.FilterBilinear)
 
-	// Check physical screen dimensions
-	pW := float32(rl.GetScreenWidth())
-	pH := float32(rl.GetScreenHeight())
-
-	fmt.Printf("Display initialized at: %.0fx%.0f\n", pW, pH)
-
-	// If physical width is less than height, assume Portrait display (e.g. 720x1280)
-	// We want to rotate our 1280x720 content to fit.
-	if pW < pH {
-		g.isPortrait = true
-		fmt.Println("Portrait mode detected. Enabling software rotation.")
-
-		// Source: The virtual 1280x720 texture (flipped vertically due to OpenGL coords)
-		g.sourceRect = rl.Rectangle{X: 0, Y: 0, Width: float32(screenWidth), Height: -float32(screenHeight)}
+	g.fitScreen()
+	fmt.Printf("Display initialized at: %dx%d, orientation %v\n", g.screenXf.ScreenW, g.screenXf.ScreenH, g.screenXf.Orientation)
+}
 
-		// Destination: The physical screen (e.g. 720x1280)
-		// We center the rotation point.
-		// If exact match (720x1280), we fill it.
-		g.destRect = rl.Rectangle{X: pW / 2, Y: pH / 2, Width: float32(screenWidth), Height: float32(screenHeight)}
-		g.origin = rl.Vector2{X: float32(screenWidth) / 2, Y: float32(screenHeight) / 2}
-	} else {
-		g.isPortrait = false
-		// Landscape - draw normally, maybe scale if resolution differs
-		g.sourceRect = rl.Rectangle{X: 0, Y: 0, Width: float32(screenWidth), Height: -float32(screenHeight)}
-		g.destRect = rl.Rectangle{X: 0, Y: 0, Width: pW, Height: pH}
-		g.origin = rl.Vector2{X: 0, Y: 0}
-	}
+// fitScreen works out how the 1280x720 render texture is turned and scaled
+// onto the physical screen; a screen taller than wide (e.g. 720x1280) gets
+// it rotated unless an orientation was set. Called again when toggling
+// fullscreen changes the screen size.
+func (g *Game) fitScreen() {
+	g.screenXf = core.NewScreenTransform(g.orientation, screenWidth, screenHeight, rl.GetScreenWidth(), rl.GetScreenHeight())
 }
 
 func (g *Game) Unload() {
 	rl.UnloadRenderTexture(g.renderTexture)
 	rl.UnloadTexture(g.planeTex)
+	if g.photoKey != "" {
+		rl.UnloadTexture(g.photoTex)
+	}
+	if g.diaryTexKey != "" {
+		rl.UnloadTexture(g.diaryTex)
+	}
+	unloadUITextures()
 	g.tileLoader.Unload()
 }
 
@@ -306,52 +1040,32 @@ func (g *Game) getVirtualTouchPosition(index int) (int, int) {
 	return g.transformInput(tp)
 }
 
+// transformInput maps a physical screen point to the virtual 1280x720
+// canvas: corrected by the touch calibration, then the rotation and
+// scaling of fitScreen undone
 func (g *Game) transformInput(p rl.Vector2) (int, int) {
-	if !g.isPortrait {
-		// Map from physical to virtual (1280x720)
-		// If physical matches virtual, 1:1
-		pW := float32(rl.GetScreenWidth())
-		pH := float32(rl.GetScreenHeight())
-		if pW == 0 || pH == 0 {
-			return int(p.X), int(p.Y)
-		}
-		scaleX := float32(screenWidth) / pW
-		scaleY := float32(screenHeight) / pH
-		return int(p.X * scaleX), int(p.Y * scaleY)
-	}
-
-	// Portrait Mode (90 deg rotation)
-	// Screen (Physical) -> Virtual
-	// Virtual X = Screen Y
-	// Virtual Y = Screen Width - Screen X
-	// We need to account for scaling if physical dimensions aren't exactly 720x1280
-	// But assuming they are close or we want to fill.
-
-	// Map p (Screen Space) to 0..1 range first?
-	// Or just use the logic derived:
-	// Rotated 90 deg clockwise means:
-	// Dest Top-Right (Visual Top-Left) is Phys Top-Left? No.
-	// Visual Top-Left (0,0) is at Phys Top-Right (ScreenW, 0).
-	// Let's re-verify:
-	// Center (640, 360) -> Center (360, 640).
-	// Top-Left (0,0) -> Rotated 90 around Center -> (360 + (0-640)*0 - (0-360)*1, ...) ?
-	// Let's use the explicit mapping we found:
-	// V_x = Screen_y * (Virtual_W / Screen_H)
-	// V_y = (Screen_W - Screen_x) * (Virtual_H / Screen_W)
-
-	pW := float32(rl.GetScreenWidth())
-	pH := float32(rl.GetScreenHeight())
-
-	scaleX := float32(screenWidth) / pH  // 1280 / 1280 = 1
-	scaleY := float32(screenHeight) / pW // 720 / 720 = 1
-
-	vx := p.Y * scaleX
-	vy := (pW - p.X) * scaleY
-
+	cx, cy := g.touchCal.Apply(float64(p.X), float64(p.Y))
+	vx, vy := g.screenXf.ToLogical(cx, cy)
 	return int(vx), int(vy)
 }
 
 func (g *Game) Update() {
+	if gap := g.clock.Tick(time.Now()); gap > 0 {
+		g.resume(gap)
+	}
+	if fps := g.fps(); fps != g.targetFPS {
+		rl.SetTargetFPS(fps)
+		g.targetFPS = fps
+	}
+	g.particles.Update(float64(rl.GetFrameTime()))
+	g.syncFlights()
+	g.applySelection()
+	g.applyRoundSetup()
+
+	if g.state == StateSplash {
+		g.updateSplash()
+	}
+
 	// 1. Text Input
 	if g.state == StateLogin && !g.showDeleteConfirm {
 		key := rl.GetCharPressed()
@@ -371,6 +1085,38 @@ func (g *Game) Update() {
 		}
 	}
 
+	if g.state == StateReplayEntry {
+		key := rl.GetCharPressed()
+		for key > 0 {
+			g.inputText += strings.ToUpper(string(key))
+			key = rl.GetCharPressed()
+		}
+		if rl.IsKeyPressed(rl.KeyBackspace) && len(g.inputText) > 0 {
+			g.inputText = g.inputText[:len(g.inputText)-1]
+		}
+		if rl.IsKeyPressed(rl.KeyEnter) {
+			g.submitReplayCode(g.inputText)
+		}
+	}
+
+	if g.state == StateDiary && g.diaryEdit != nil {
+		key := rl.GetCharPressed()
+		for key > 0 {
+			g.inputText += string(key)
+			key = rl.GetCharPressed()
+		}
+		if rl.IsKeyPressed(rl.KeyBackspace) && len(g.inputText) > 0 {
+			g.inputText = g.inputText[:len(g.inputText)-1]
+		}
+		if rl.IsKeyPressed(rl.KeyEnter) {
+			g.saveDiary()
+		}
+	}
+
+	if g.state == StateCalibrate && g.updateCalibration() {
+		return
+	}
+
 	// 2. Pinch Zoom
 	// Raylib Touch
 	touchCount := rl.GetTouchPointCount()
@@ -380,23 +1126,10 @@ func (g *Game) Update() {
 
 		dist := math.Sqrt(math.Pow(float64(t2x-t1x), 2) + math.Pow(float64(t2y-t1y), 2))
 
-		if g.lastPinchDist > 0 {
-			threshold := 10.0
-			diff := dist - g.lastPinchDist
-			if math.Abs(diff) > threshold {
-				if diff > 0 {
-					g.camZoom++
-				} else {
-					g.camZoom--
-				}
-				if g.camZoom < 4 {
-					g.camZoom = 4
-				}
-				if g.camZoom > 18 {
-					g.camZoom = 18
-				}
-				g.lastPinchDist = dist
-			}
+		if g.lastPinchDist > 0 && dist > 0 {
+			// Twice as far apart is one zoom level in, half as far one out
+			g.camZoom = core.ClampZoom(g.camZoom + math.Log2(dist/g.lastPinchDist))
+			g.lastPinchDist = dist
 		} else {
 			g.lastPinchDist = dist
 		}
@@ -428,20 +1161,21 @@ func (g *Game) Update() {
 		} else {
 			if g.isKeyboardOpen {
 				g.isDragging = false
-			} else if g.state == StateMap || g.state == StateGamePlaying {
+			} else if g.state == StateMap || g.state == StateGamePlaying || g.state == StateOddOneOut {
 				g.checkPlaneClick(mx, my)
 			}
 		}
 	}
+	g.dispatchUI()
 
 	if g.isDragging {
 		if isDown {
 			dx := mx - g.dragStartX
 			dy := my - g.dragStartY
 
-			if g.state == StateMap || g.state == StateGamePlaying {
+			if g.state == StateMap || g.state == StateGamePlaying || g.state == StateOddOneOut {
 				// Pan Logic
-				scale := 360.0 / math.Pow(2, float64(g.camZoom)) / 256.0
+				scale := 360.0 / math.Pow(2, g.camZoom) / 256.0
 				g.camLon = g.startCamLon - float64(dx)*scale
 				latScale := scale * math.Cos(g.camLat*math.Pi/180.0)
 				g.camLat = g.startCamLat + float64(dy)*latScale
@@ -454,23 +1188,30 @@ func (g *Game) Update() {
 	// Mouse Wheel
 	wheel := rl.GetMouseWheelMove()
 	if wheel != 0 {
-		g.camZoom += int(wheel)
-		if g.camZoom < 4 {
-			g.camZoom = 4
-		}
-		if g.camZoom > 18 {
-			g.camZoom = 18
-		}
+		// Touchpads scroll in fractions of a notch, which zoom smoothly
+		g.camZoom = core.ClampZoom(g.camZoom + float64(wheel)*wheelZoomStep)
 	}
 
 	// Fullscreen Toggle
 	if rl.IsKeyPressed(rl.KeyF) {
 		rl.ToggleFullscreen()
+		g.fitScreen()
 	}
 
 	// Game State Transitions
+	if g.state == StateGamePlaying && !g.showResult && g.clock.Since(g.roundStartTime) > g.roundTime {
+		g.guess("") // out of time counts as a miss
+	}
+	if g.state == StateGamePlaying && !g.showResult && g.partyGame && g.party.AllAnswered() {
+		g.guess("") // every phone is in; no need to wait out the timer
+	}
 	if g.state == StateGamePlaying && g.showResult {
-		if time.Since(g.resultStartTime) > 2*time.Second {
+		// Leave a party time to read the scoreboard
+		pause := 2 * time.Second
+		if g.partyGame {
+			pause = 5 * time.Second
+		}
+		if g.clock.Since(g.resultStartTime) > pause {
 			g.nextRound()
 		}
 	}
@@ -481,60 +1222,56 @@ func (g *Game) Update() {
 
 func (g *Game) login(name string) {
 	g.isKeyboardOpen = false
-	if u, ok := g.usersMap[name]; ok {
-		g.currentUser = u
-	} else {
-		g.currentUser = UserStats{Name: name}
-	}
+	g.difficulty = g.users.Login(name).Difficulty
 	g.state = StateMap
 }
 
 func (g *Game) checkUIClick(x, y int) bool {
-	// Reverse iterate
-	for i := len(g.buttons) - 1; i >= 0; i-- {
-		b := g.buttons[i]
-		if x >= b.X && x <= b.X+b.W && y >= b.Y && y <= b.Y+b.H {
-			if b.Action != nil {
-				b.Action()
-			}
-			return true
-		}
+	// Buttons queue the press; dispatchUI runs it once input is read
+	if g.ui.Press(int(g.state), x, y) {
+		return true
 	}
 
 	if g.selectedPlane != nil && x > screenWidth-300 {
 		return true
 	}
-	if g.state == StateGamePlaying && x < 300 {
+	if (g.state == StateGamePlaying || g.state == StateArrivalBonus || g.state == StateOddOneOut) && x < 300 {
 		return true
 	}
 
 	return false
 }
 
+// publishLayout hands the buttons this frame drew to the event queue, so
+// presses are matched against what is on screen
+func (g *Game) publishLayout() {
+	widgets := make([]core.UIWidget, len(g.buttons))
+	for i, b := range g.buttons {
+		widgets[i] = core.UIWidget{ID: b.ID, X: b.X, Y: b.Y, W: b.W, H: b.H, Action: b.Action}
+	}
+	g.ui.SetLayout(int(g.state), widgets)
+}
+
+// dispatchUI runs the button actions for this frame's presses
+func (g *Game) dispatchUI() {
+	g.ui.Dispatch(func() int { return int(g.state) })
+}
+
 func (g *Game) checkPlaneClick(x, y int) {
-	minDist := 40.0
-	var found *Flight
+	const clickRadius = 40.0
+	var found *core.Flight
 
-	centerX, centerY := LatLonToPixels(g.camLat, g.camLon, g.camZoom)
+	centerX, centerY := core.LatLonToPixels(g.camLat, g.camLon, g.camZoom)
 	screenCX, screenCY := float64(screenWidth)/2, float64(screenHeight)/2
 	minWX := centerX - screenCX
 	minWY := centerY - screenCY
 
-	for i := range g.flights {
-		f := &g.flights[i]
-		fX, fY := LatLonToPixels(f.Lat, f.Lon, g.camZoom)
-		sX := fX - minWX
-		sY := fY - minWY
-
-		dist := math.Sqrt(math.Pow(sX-float64(x), 2) + math.Pow(sY-float64(y), 2))
-		if dist < minDist {
-			minDist = dist
-			found = f
-		}
-	}
+	found = g.flights.Nearest(minWX+float64(x), minWY+float64(y), g.camZoom, clickRadius)
 
 	if found != nil {
 		g.selectPlane(found)
+		x, y := g.screenPos(g.flights.Position(found, time.Now()))
+		g.particles.Sparks(x, y, 16, colAccent)
 		if g.state == StateMap {
 			g.camLat = found.Lat
 			g.camLon = found.Lon
@@ -542,46 +1279,152 @@ func (g *Game) checkPlaneClick(x, y int) {
 	}
 }
 
-func (g *Game) selectPlane(f *Flight) {
+func (g *Game) selectPlane(f *core.Flight) {
+	ctx, seq := g.newSelection()
 	g.selectedPlane = f
 	g.resolvedDetails = nil
 	g.resolving = true
+	g.selectedTrack = nil
+	// Prefetched details show at once; the lookup below confirms them
+	if d, ok := g.prefetch.Cached(*f); ok {
+		g.resolvedDetails, g.resolving = d, false
+	}
 
-	go func(callsign string) {
-		details, err := g.scraper.FetchFlightDetails(callsign)
-		if err != nil {
-			log.Printf("Failed to resolve %s: %v", callsign, err)
-			g.resolving = false
+	// Without trails the track is never drawn, so don't fetch it
+	if g.profile.Trails {
+		go func(icao24 string) {
+			track, err := core.FetchTrack(ctx, g.provider, icao24)
+			if ctx.Err() != nil {
+				return // another plane was selected meanwhile
+			}
+			if err != nil {
+				if err != core.ErrTracksUnsupported {
+					log.Printf("Failed to fetch track for %s: %v", icao24, err)
+				}
+				// Fall back to what we have recorded ourselves today
+				track = g.tracks.Track(icao24)
+			}
+			g.deliverSelection(ctx, selectionResult{seq: seq, track: track, isTrack: true})
+		}(f.Icao24)
+	}
+
+	// Trigger scrape
+	go func(target core.Flight) {
+		details, err := core.ResolveFlight(ctx, g.resolver, target)
+		if ctx.Err() != nil {
 			return
 		}
-		if details != nil {
+		if err != nil {
+			log.Printf("Failed to resolve %s: %v", core.QuizName(target, nil), err)
+		} else if details != nil {
+			// Store scraped airports for future use
 			go func() {
-				g.dataManager.SaveAirport(details.RealDestination)
-				g.dataManager.SaveAirport(details.Origin)
+				g.dataManager.SaveAirport(details.RealDestination, g.airportDB)
+				g.dataManager.SaveAirport(details.Origin, g.airportDB)
 			}()
 		}
-		if g.selectedPlane != nil && g.selectedPlane.Callsign == callsign {
-			g.resolvedDetails = details
-			g.resolving = false
-		}
-	}(f.Callsign)
+		g.deliverSelection(ctx, selectionResult{seq: seq, details: details})
+	}(*f)
 }
 
-func (g *Game) Draw() {
-	// 1. Draw Game to Virtual Texture
-	rl.BeginTextureMode(g.renderTexture)
-	rl.ClearBackground(getRlColor(colBgDark))
+// selectionResult is a lookup for a selected plane, handed back to the UI
+// goroutine. seq tells it apart from lookups for earlier selections.
+type selectionResult struct {
+	seq     int
+	isTrack bool
+	track   []core.TrackPoint
+	details *core.ResolvedDetails // nil if the lookup failed
+}
 
-	if g.state == StateLogin {
-		g.drawLogin()
-	} else if g.state == StateLeaderboard {
-		g.drawLeaderboard()
-	} else {
-		g.drawMap()
-		g.drawHomeMarker()
-		g.drawPlanes()
-		g.drawUI()
-	}
+// newSelection cancels the previous selection's lookups and starts a
+// context for the next one's
+func (g *Game) newSelection() (context.Context, int) {
+	g.cancelSelection()
+	ctx, cancel := context.WithCancel(g.ctx)
+	g.selectCancel = cancel
+	return ctx, g.selectSeq
+}
+
+// cancelSelection abandons the selected plane's lookups, so a late answer
+// can't overwrite whatever is shown next
+func (g *Game) cancelSelection() {
+	if g.selectCancel != nil {
+		g.selectCancel()
+		g.selectCancel = nil
+	}
+	g.selectSeq++
+}
+
+// deselectPlane closes the info panel
+func (g *Game) deselectPlane() {
+	g.cancelSelection()
+	g.selectedPlane = nil
+}
+
+// deliverSelection passes r to the UI goroutine, or drops it once the
+// selection has moved on
+func (g *Game) deliverSelection(ctx context.Context, r selectionResult) {
+	select {
+	case g.selectResults <- r:
+	case <-ctx.Done():
+	}
+}
+
+// applySelection stores the lookups that are for the current selection
+func (g *Game) applySelection() {
+	for len(g.selectResults) > 0 {
+		r := <-g.selectResults
+		if r.seq != g.selectSeq {
+			continue
+		}
+		if r.isTrack {
+			g.selectedTrack = r.track
+		} else {
+			g.resolvedDetails, g.resolving = r.details, false
+		}
+	}
+}
+
+func (g *Game) Draw() {
+	// 1. Draw Game to Virtual Texture
+	rl.BeginTextureMode(g.renderTexture)
+	rl.ClearBackground(getRlColor(colBgDark))
+
+	if g.state == StateSplash {
+		g.drawSplash()
+	} else if g.state == StateLogin {
+		g.drawLogin()
+	} else if g.state == StateLeaderboard {
+		g.drawLeaderboard()
+	} else if g.state == StateSettings {
+		g.drawSettings()
+	} else if g.state == StateReplayEntry {
+		g.drawReplayEntry()
+	} else if g.state == StateStatus {
+		g.drawStatus()
+	} else if g.state == StateNoise {
+		g.drawNoise()
+	} else if g.state == StateRegulars {
+		g.drawRegulars()
+	} else if g.state == StateDiary {
+		g.drawDiary()
+	} else if g.state == StateStorage {
+		g.drawStorage()
+	} else if g.state == StateResolvers {
+		g.drawResolvers()
+	} else if g.state == StateCalibrate {
+		g.drawCalibration()
+	} else {
+		g.drawMap()
+		g.drawPolarRange()
+		g.drawSelectedTrack()
+		g.drawApproaches()
+		g.drawHomeMarker()
+		g.drawPlanes()
+		g.drawAttribution()
+		g.drawUI()
+	}
+	g.particles.Draw(particleRenderer{})
 
 	// Debug
 	rl.DrawFPS(10, screenHeight-20)
@@ -591,31 +1434,39 @@ func (g *Game) Draw() {
 	rl.BeginDrawing()
 	rl.ClearBackground(rl.Black)
 
-	if g.isPortrait {
-		// Rotated 90 degrees
-		rl.DrawTexturePro(g.renderTexture.Texture, g.sourceRect, g.destRect, g.origin, 90, rl.White)
-	} else {
-		// Normal draw (scaled if needed)
-		// If using DrawTexturePro with rotation 0, origin is usually 0,0 unless we want to center.
-		// My Init set origin to 0,0 for landscape.
-		rl.DrawTexturePro(g.renderTexture.Texture, g.sourceRect, g.destRect, g.origin, 0, rl.White)
-	}
+	// Source: the virtual texture, flipped vertically due to OpenGL coords.
+	// It is drawn scaled around the screen's centre, turned to the
+	// orientation.
+	xf := g.screenXf
+	w, h := float32(float64(screenWidth)*xf.Scale()), float32(float64(screenHeight)*xf.Scale())
+	source := rl.Rectangle{X: 0, Y: 0, Width: float32(screenWidth), Height: -float32(screenHeight)}
+	dest := rl.Rectangle{X: float32(xf.ScreenW) / 2, Y: float32(xf.ScreenH) / 2, Width: w, Height: h}
+	rl.DrawTexturePro(g.renderTexture.Texture, source, dest, rl.Vector2{X: w / 2, Y: h / 2}, float32(xf.Orientation), rl.White)
 
 	rl.EndDrawing()
+	g.publishLayout()
 }
 
+// drawMap fills the screen with tiles from the level nearest the zoom,
+// scaled to it. Tile edges are rounded to whole pixels so scaled tiles
+// meet without seams.
 func (g *Game) drawMap() {
-	centerX, centerY := LatLonToPixels(g.camLat, g.camLon, g.camZoom)
-	screenCX, screenCY := float64(screenWidth)/2, float64(screenHeight)/2
+	level, scale := core.TileLevel(g.camZoom)
+	centerX, centerY := core.LatLonToPixels(g.camLat, g.camLon, float64(level))
+	// The view in the level's pixels
+	screenCX, screenCY := float64(screenWidth)/2/scale, float64(screenHeight)/2/scale
 	minWX := centerX - screenCX
 	minWY := centerY - screenCY
 
-	minTileX := int(math.Floor(minWX / tileSize))
-	maxTileX := int(math.Floor((centerX + screenCX) / tileSize))
-	minTileY := int(math.Floor(minWY / tileSize))
-	maxTileY := int(math.Floor((centerY + screenCY) / tileSize))
+	minTileX := int(math.Floor(minWX / core.TileSize))
+	maxTileX := int(math.Floor((centerX + screenCX) / core.TileSize))
+	minTileY := int(math.Floor(minWY / core.TileSize))
+	maxTileY := int(math.Floor((centerY + screenCY) / core.TileSize))
 
-	maxIndex := int(math.Pow(2, float64(g.camZoom))) - 1
+	maxIndex := 1<<level - 1
+	edge := func(tile int, origin float64) float32 {
+		return float32(math.Round((float64(tile*core.TileSize) - origin) * scale))
+	}
 
 	for x := minTileX; x <= maxTileX; x++ {
 		for y := minTileY; y <= maxTileY; y++ {
@@ -631,25 +1482,95 @@ func (g *Game) drawMap() {
 				continue
 			}
 
-			tex := g.tileLoader.GetTile(g.camZoom, tileX, y)
+			dst := rl.NewRectangle(edge(x, minWX), edge(y, minWY), 0, 0)
+			dst.Width, dst.Height = edge(x+1, minWX)-dst.X, edge(y+1, minWY)-dst.Y
+
+			tex := g.tileLoader.GetTile(level, tileX, y)
 			// Check if valid texture (id > 0)
 			if tex.ID > 0 {
-				screenX := float64(x*tileSize) - minWX
-				screenY := float64(y*tileSize) - minWY
-
-				rl.DrawTexture(tex, int32(screenX), int32(screenY), rl.White)
+				src := rl.NewRectangle(0, 0, float32(tex.Width), float32(tex.Height))
+				rl.DrawTexturePro(tex, src, dst, rl.NewVector2(0, 0), 0, rl.White)
+			} else {
+				g.drawTilePlaceholder(level, tileX, y, dst)
 			}
 		}
 	}
+	g.prefetchTiles()
+}
+
+// prefetchTiles starts loading the tiles around the view and at the zoom
+// levels either side of it whenever the view moves onto another centre
+// tile. The ring is a tile wide, so it still covers the view until then.
+// Downloads of tiles the view has left behind are dropped at the same time.
+func (g *Game) prefetchTiles() {
+	level, scale := core.TileLevel(g.camZoom)
+	cx, cy := core.LatLonToPixels(g.camLat, g.camLon, float64(level))
+	centre := core.TileCoord{Z: level, X: int(cx / core.TileSize), Y: int(cy / core.TileSize)}
+	if centre == g.prefetched {
+		return
+	}
+	g.prefetched = centre
+	w, h := screenWidth/scale, screenHeight/scale // zoomed out, the view spans more tiles
+	near := core.ViewTiles(g.camLat, g.camLon, level, w, h, 1)
+	if g.eco() {
+		g.tileLoader.Prefetch(near, nil) // only drops what the view left
+		return
+	}
+	// A quarter of the cache is left for placeholders and the view just left
+	budget := g.profile.MaxTiles * 3 / 4
+	g.tileLoader.Prefetch(near, core.PrefetchTiles(g.camLat, g.camLon, level, w, h, budget))
+}
+
+// drawTilePlaceholder fills the square of a tile still loading with what
+// is cached: the covering part of the nearest ancestor stretched up, or
+// failing that whichever of its four children are there, shrunk down.
+// Nothing is fetched for it, so the square stays black at worst. dst is
+// the tile's square on screen.
+func (g *Game) drawTilePlaceholder(z, x, y int, dst rl.Rectangle) {
+	for _, a := range core.TileAncestors(z, x, y) {
+		tex := g.tileLoader.Cached(a.Z, a.X, a.Y)
+		if tex.ID == 0 {
+			continue
+		}
+		sx, sy, side := a.Sub(int(tex.Width))
+		if side < 1 {
+			break
+		}
+		src := rl.NewRectangle(float32(sx), float32(sy), float32(side), float32(side))
+		rl.DrawTexturePro(tex, src, dst, rl.NewVector2(0, 0), 0, rl.White)
+		return
+	}
+
+	halfW, halfH := dst.Width/2, dst.Height/2
+	for i := 0; i < 4; i++ {
+		cx, cy := i%2, i/2
+		tex := g.tileLoader.Cached(z+1, 2*x+cx, 2*y+cy)
+		if tex.ID == 0 {
+			continue
+		}
+		src := rl.NewRectangle(0, 0, float32(tex.Width), float32(tex.Height))
+		quarter := rl.NewRectangle(dst.X+float32(cx)*halfW, dst.Y+float32(cy)*halfH, halfW, halfH)
+		rl.DrawTexturePro(tex, src, quarter, rl.NewVector2(0, 0), 0, rl.White)
+	}
+}
+
+// drawAttribution credits the map tiles in a corner, on a dark strip so it
+// reads over any tile. The default font has no ©.
+func (g *Game) drawAttribution() {
+	s := g.attribution.ASCII()
+	w, h := int(rl.MeasureText(s, 14))+10, 20
+	x, y := g.attribution.Place(w, h, screenWidth, screenHeight, 0)
+	rl.DrawRectangle(int32(x), int32(y), int32(w), int32(h), rl.NewColor(0, 0, 0, 160))
+	rl.DrawText(s, int32(x+5), int32(y+3), 14, getRlColor(colTextMuted))
 }
 
 func (g *Game) drawHomeMarker() {
-	centerX, centerY := LatLonToPixels(g.camLat, g.camLon, g.camZoom)
+	centerX, centerY := core.LatLonToPixels(g.camLat, g.camLon, g.camZoom)
 	screenCX, screenCY := float64(screenWidth)/2, float64(screenHeight)/2
 	minWX := centerX - screenCX
 	minWY := centerY - screenCY
 
-	hX, hY := LatLonToPixels(myLat, myLon, g.camZoom)
+	hX, hY := core.LatLonToPixels(myLat, myLon, g.camZoom)
 	sX := hX - minWX
 	sY := hY - minWY
 
@@ -658,21 +1579,75 @@ func (g *Game) drawHomeMarker() {
 	}
 }
 
+// drawPolarRange outlines the furthest distance our receiver has heard in each direction
+// drawSelectedTrack draws the path flown by the selected aircraft up to its current position
+func (g *Game) drawSelectedTrack() {
+	track := g.selectedTrack
+	if g.selectedPlane == nil || len(track) == 0 {
+		return
+	}
+	centerX, centerY := core.LatLonToPixels(g.camLat, g.camLon, g.camZoom)
+	minWX := centerX - float64(screenWidth)/2
+	minWY := centerY - float64(screenHeight)/2
+
+	pathCol := rl.Fade(rl.Orange, 0.8)
+	prevX, prevY := core.LatLonToPixels(track[0].Lat, track[0].Lon, g.camZoom)
+	endLat, endLon := g.flights.Position(g.selectedPlane, time.Now())
+	for i := 1; i <= len(track); i++ {
+		lat, lon := endLat, endLon
+		if i < len(track) {
+			lat, lon = track[i].Lat, track[i].Lon
+		}
+		x, y := core.LatLonToPixels(lat, lon, g.camZoom)
+		rl.DrawLineEx(
+			rl.Vector2{X: float32(prevX - minWX), Y: float32(prevY - minWY)},
+			rl.Vector2{X: float32(x - minWX), Y: float32(y - minWY)},
+			2, pathCol)
+		prevX, prevY = x, y
+	}
+}
+
+func (g *Game) drawPolarRange() {
+	if g.polar == nil {
+		return
+	}
+	centerX, centerY := core.LatLonToPixels(g.camLat, g.camLon, g.camZoom)
+	minWX := centerX - float64(screenWidth)/2
+	minWY := centerY - float64(screenHeight)/2
+
+	sectors := g.polar.Sectors()
+	pts := make([]rl.Vector2, len(sectors))
+	for i, km := range sectors {
+		lat, lon := core.Destination(myLat, myLon, core.SectorBearing(i), km)
+		x, y := core.LatLonToPixels(lat, lon, g.camZoom)
+		pts[i] = rl.Vector2{X: float32(x - minWX), Y: float32(y - minWY)}
+	}
+
+	ringCol := rl.Fade(getRlColor(colAccent), 0.6)
+	for i := range sectors {
+		j := (i + 1) % len(sectors)
+		if sectors[i] == 0 || sectors[j] == 0 {
+			continue // no coverage data for this direction yet
+		}
+		rl.DrawLineEx(pts[i], pts[j], 2, ringCol)
+	}
+}
+
 func (g *Game) drawPlanes() {
-	centerX, centerY := LatLonToPixels(g.camLat, g.camLon, g.camZoom)
+	centerX, centerY := core.LatLonToPixels(g.camLat, g.camLon, g.camZoom)
 	screenCX, screenCY := float64(screenWidth)/2, float64(screenHeight)/2
 	minWX := centerX - screenCX
 	minWY := centerY - screenCY
 
-	for _, f := range g.flights {
-		fX, fY := LatLonToPixels(f.Lat, f.Lon, g.camZoom)
+	now := time.Now()
+	airlineColors := g.settings.Get().AirlineColors
+	visible := g.flights.InRect(minWX-50, minWY-50, minWX+float64(screenWidth)+50, minWY+float64(screenHeight)+50, g.camZoom)
+	for _, f := range visible {
+		lat, lon := g.flights.Position(f, now)
+		fX, fY := core.LatLonToPixels(lat, lon, g.camZoom)
 		sX := fX - minWX
 		sY := fY - minWY
 
-		if sX < -50 || sX > float64(screenWidth)+50 || sY < -50 || sY > float64(screenHeight)+50 {
-			continue
-		}
-
 		// Rotation
 		// Raylib rotation is in degrees.
 		destRect := rl.Rectangle{X: float32(sX), Y: float32(sY), Width: 32, Height: 32}
@@ -680,9 +1655,18 @@ func (g *Game) drawPlanes() {
 
 		tint := rl.White
 		// Highlight if playing OR if just selected
-		if (g.state == StateGamePlaying && g.targetPlane != nil && f.Icao24 == g.targetPlane.Icao24) ||
-			(g.selectedPlane != nil && f.Icao24 == g.selectedPlane.Icao24) {
+		if f.IsEmergency() {
+			tint = rl.Fade(rl.Red, float32(emergencyPulse()))
+		} else if (g.state == StateGamePlaying && g.targetPlane != nil && f.Icao24 == g.targetPlane.Icao24) ||
+			(g.selectedPlane != nil && f.Icao24 == g.selectedPlane.Icao24) || g.inOddOneOut(f.Icao24) {
 			tint = rl.Orange // Highlight
+		} else if _, watched := g.watchlist.Match(*f); watched {
+			tint = rl.Magenta
+		} else if c, ok := core.AirlineColor(f.Callsign); ok && airlineColors {
+			tint = getRlColor(c)
+		}
+		if f.Stale {
+			tint = rl.Fade(tint, 0.4) // not heard from lately
 		}
 
 		rl.DrawTexturePro(g.planeTex,
@@ -693,6 +1677,11 @@ func (g *Game) drawPlanes() {
 			tint)
 
 		rl.DrawText(f.Callsign, int32(sX)+20, int32(sY), 10, rl.White)
+
+		if info, ok := g.tags.Lookup(f.Icao24); ok {
+			rl.DrawRectangle(int32(sX)-26, int32(sY)-26, 14, 14, tagColor(info.Tag))
+			rl.DrawText(info.Tag.Badge(), int32(sX)-23, int32(sY)-24, 10, rl.White)
+		}
 	}
 }
 
@@ -702,58 +1691,128 @@ func (g *Game) drawUI() {
 	// User Info
 	if g.state == StateMap {
 		// Smaller user text
-		info := fmt.Sprintf("User: %s (%d)", g.currentUser.Name, g.currentUser.BestScore)
+		user := g.users.Current()
+		info := fmt.Sprintf("User: %s (%d)", user.Name, user.BestScore)
 		rl.DrawText(info, 10, 10, 14, getRlColor(colAccent))
 
 		g.addButton(screenWidth-130, 10, 120, 30, "LEADERBOARD", func() {
 			g.refreshLeaderboard()
 			g.state = StateLeaderboard
 		}, getRlColor(colGlass))
+		g.addButton(screenWidth-330, 10, 100, 30, "SETTINGS", func() { g.state = StateSettings }, getRlColor(colGlass))
+		ecoX := screenWidth - 440
+		if regions := g.settings.Get().WatchRegions(myLat, myLon); len(regions) > 1 {
+			name := regions[g.activeRegion%len(regions)].Name
+			g.addButton(screenWidth-480, 10, 140, 30, truncate(name, 10), g.cycleRegion, getRlColor(colGlassLight))
+			ecoX -= 150
+		}
+		// Shown while on battery or overridden, as the switch to override it
+		if g.power.OnBattery() || g.settings.Get().EcoMode != core.EcoAuto {
+			if g.eco() {
+				g.addButton(ecoX, 10, 100, 30, "ECO", g.toggleEco, getRlColor(colSuccess))
+			} else {
+				g.addButton(ecoX, 10, 100, 30, "ECO OFF", g.toggleEco, getRlColor(colGlassLight))
+			}
+		}
 		g.addButton(screenWidth-220, 10, 80, 30, "LOGOUT", func() {
 			g.state = StateLogin
 			g.inputText = ""
 		}, getRlColor(colDanger))
 	}
 
+	if g.state == StateMap {
+		g.drawReceiverWidget()
+		g.drawRunways()
+		g.drawThrottleBanner()
+		g.drawWatchAlert()
+		g.drawEmergencyBanner()
+		g.drawAirlineLegend()
+	}
+
 	// Sidebar
 	if g.selectedPlane != nil {
-		panelW := 300
-		panelX := screenWidth - panelW - 20
-		g.drawPanel(panelX, 90, panelW, 350, "FLIGHT INFO")
+		panelW := lay("info_panel_w", 300)
+		panelX := screenWidth - panelW - lay("info_panel_margin", 20)
+		panelY := lay("info_panel_y", 90)
+		g.drawPanel(panelX, panelY, panelW, lay("info_panel_h", 600), "FLIGHT INFO")
 
 		p := g.selectedPlane
-		y := 140
+		y := panelY + 50
 		txtX := panelX + 20
 
 		rl.DrawText(p.Callsign, int32(txtX), int32(y), 20, getRlColor(colAccent))
 		y += 30
 		rl.DrawText(fmt.Sprintf("Alt: %d ft", p.AltitudeFt), int32(txtX), int32(y), 16, rl.White)
+		if trend := p.Trend(); trend != core.TrendLevel {
+			drawTrendArrow(float32(txtX+140), float32(y+8), trend)
+			rl.DrawText(fmt.Sprintf("%d fpm", abs(p.VerticalRateFpm)), int32(txtX+152), int32(y), 16, getRlColor(colTextMuted))
+		}
 		y += 25
 		rl.DrawText(fmt.Sprintf("Spd: %d kts", p.VelocityKts), int32(txtX), int32(y), 16, rl.White)
 		y += 25
 		rl.DrawText(fmt.Sprintf("Pos: %.2f, %.2f", p.Lat, p.Lon), int32(txtX), int32(y), 16, rl.White)
+		y += 25
+		brg := core.FormatBearing(core.Bearing(myLat, myLon, p.Lat, p.Lon), g.settings.Get().MagneticBearings, myLat, myLon)
+		rl.DrawText(fmt.Sprintf("Brg: %s  %.0f km", brg, core.Distance(myLat, myLon, p.Lat, p.Lon)), int32(txtX), int32(y), 16, rl.White)
+		g.addButton(panelX+panelW-60, y-4, 45, 24, "T/M", g.toggleMagneticBearings, getRlColor(colGlassLight))
+		if p.Source != "" {
+			y += 20
+			rl.DrawText("Src: "+p.SourceLabel(time.Now()), int32(txtX), int32(y), 14, getRlColor(colTextMuted))
+		}
+		if !p.OnGround {
+			spot := core.Spotability(*p, g.metar.Sky(), myLat, myLon, time.Now())
+			col := getRlColor(colTextMuted)
+			if spot.Visible {
+				col = getRlColor(colSuccess)
+			}
+			y += 20
+			rl.DrawText(spot.Reason, int32(txtX), int32(y), 14, col)
+		}
+		if reg := g.airframeLine(p); reg != "" {
+			y += 20
+			rl.DrawText(reg, int32(txtX), int32(y), 14, getRlColor(colTextMuted))
+		}
+		if age := g.resolvedDetails.AgeLabel(time.Now()); age != "" && !g.resolving {
+			col := getRlColor(colTextMuted)
+			if g.resolvedDetails.NewAirframe(time.Now()) {
+				col = getRlColor(colSuccess)
+			}
+			y += 20
+			rl.DrawText("Age: "+age, int32(txtX), int32(y), 14, col)
+		}
 		y += 35
 
 		if g.resolving {
 			rl.DrawText("Fetching details...", int32(txtX), int32(y), 16, getRlColor(colTextMuted))
 		} else if g.resolvedDetails != nil {
-			rl.DrawText("Model:", int32(txtX), int32(y), 16, rl.White)
-			y += 20
-			rl.DrawText(truncate(g.resolvedDetails.Model, 35), int32(txtX), int32(y), 16, getRlColor(colAccent))
-			y += 30
-
+			model := g.resolvedDetails.Model
 			orig := g.resolvedDetails.Origin
 			dest := g.resolvedDetails.RealDestination
+			airline := g.resolvedDetails.Airline
+			if model == "" {
+				model = "Unknown" // route databases don't know the aircraft
+			}
 
 			if g.state == StateGamePlaying && g.targetPlane != nil && g.selectedPlane.Icao24 == g.targetPlane.Icao24 {
+				if g.typeRound {
+					model = g.typeHint
+				}
 				if g.correctOption == orig {
 					orig = "???"
 				}
 				if g.correctOption == dest {
 					dest = "???"
 				}
+				if g.correctOption == airline {
+					airline = "???"
+				}
 			}
 
+			rl.DrawText("Model:", int32(txtX), int32(y), 16, rl.White)
+			y += 20
+			rl.DrawText(truncate(model, 35), int32(txtX), int32(y), 16, getRlColor(colAccent))
+			y += 30
+
 			rl.DrawText("From:", int32(txtX), int32(y), 16, rl.White)
 			y += 20
 			rl.DrawText(truncate(orig, 28), int32(txtX), int32(y), 16, getRlColor(colAccent))
@@ -762,26 +1821,42 @@ func (g *Game) drawUI() {
 			rl.DrawText("To:", int32(txtX), int32(y), 16, rl.White)
 			y += 20
 			rl.DrawText(truncate(dest, 28), int32(txtX), int32(y), 16, getRlColor(colAccent))
+
+			if airline != "" {
+				y += 30
+				rl.DrawText("Airline:", int32(txtX), int32(y), 16, rl.White)
+				y += 20
+				rl.DrawText(truncate(airline, 28), int32(txtX), int32(y), 16, getRlColor(colAccent))
+			}
+			// The bonus round asks for exactly this, so it stays hidden then
+			if prog, ok := g.resolvedDetails.Progress(*p, time.Now()); ok && g.state != StateArrivalBonus {
+				y += 30
+				rl.DrawText(prog.String(), int32(txtX), int32(y), 16, getRlColor(colTextMuted))
+			}
 		} else {
 			rl.DrawText("Details unavailable", int32(txtX), int32(y), 16, getRlColor(colTextMuted))
 		}
+		g.drawPhoto(p, txtX, 530, panelW-40)
 
-		g.addButton(screenWidth-50, 95, 30, 30, "X", func() { g.selectedPlane = nil }, rl.Color{R: 255, G: 255, B: 255, A: 50}, rl.Black)
+		g.addButton(screenWidth-50, 95, 30, 30, "X", g.deselectPlane, rl.Color{R: 255, G: 255, B: 255, A: 50}, rl.Black)
 	}
 
 	// Game Panel
 	if g.state == StateRoundSetup {
-		g.drawPanel(20, 90, 300, 150, fmt.Sprintf("ROUND %d/5", g.round))
+		g.drawPanel(20, 90, 300, 150, fmt.Sprintf("ROUND %d/%d", g.round, core.RoundsPerGame))
 		rl.DrawText("Tracking target...", 40, 140, 20, rl.White)
 	} else if g.state == StateGamePlaying && g.targetPlane != nil {
 		// Increased height from 340 to 400 to fit score
-		g.drawPanel(20, 90, 300, 375, fmt.Sprintf("ROUND %d/5", g.round))
+		g.drawPanel(20, 90, 300, 375, fmt.Sprintf("ROUND %d/%d", g.round, core.RoundsPerGame))
 
 		qText := g.questionText
 		if len(qText) > 30 {
 			qText = qText[:30] + "..."
 		}
 		rl.DrawText(qText, 30, 140, 20, rl.White)
+		if g.typeRound {
+			rl.DrawText("Hint: "+truncate(g.typeHint, 30), 30, 162, 14, getRlColor(colTextMuted))
+		}
 
 		y := 180
 		for _, opt := range g.options {
@@ -804,12 +1879,44 @@ func (g *Game) drawUI() {
 			// Capture
 			o := opt
 			// Reduced height to 35, wider width 280
-			g.addButton(30, y, 280, 35, truncate(o, 32), func() { g.guess(o) }, col, textColor)
+			g.addButton(30, y, 280, 35, truncate(o, 32), func() {
+				if !g.partyGame {
+					g.guess(o)
+				}
+			}, col, textColor)
 			y += 45
 		}
 
-		rl.DrawText(fmt.Sprintf("Score: %d", g.score), 30, int32(y)+10, 20, getRlColor(colAccent))
+		if g.partyGame {
+			answered, players := g.party.Progress()
+			rl.DrawText(fmt.Sprintf("Phones: %d/%d", answered, players), 30, int32(y)+10, 20, getRlColor(colAccent))
+			if g.showResult {
+				g.drawPartyStandings(screenWidth/2-150, 90, 10)
+			}
+		} else {
+			rl.DrawText(fmt.Sprintf("Score: %d", g.score), 30, int32(y)+10, 20, getRlColor(colAccent))
+		}
+		if !g.showResult {
+			left := max(0, g.roundTime-g.clock.Since(g.roundStartTime))
+			rl.DrawText(fmt.Sprintf("Time: %.0fs", left.Seconds()), 200, int32(y)+10, 20, rl.White)
+		}
 		g.addButton(25, 425, 100, 30, "QUIT", func() { g.endGame() }, getRlColor(colDanger))
+	} else if g.state == StateArrivalBonus {
+		g.drawPanel(20, 90, 300, 375, "BONUS ROUND")
+		rl.DrawText("Minutes until "+truncate(g.bonusName, 12), 30, 140, 20, rl.White)
+		rl.DrawText("lands in", 30, 165, 20, rl.White)
+		rl.DrawText(truncate(g.bonusDetails.RealDestination, 26), 30, 190, 20, getRlColor(colAccent))
+
+		guess := fmt.Sprintf("%d min", g.bonusGuess)
+		rl.DrawText(guess, 170-rl.MeasureText(guess, 30)/2, 235, 30, getRlColor(colGold))
+		for i, step := range []int{-10, -1, 1, 10} {
+			g.addButton(30+i*70, 285, 60, 35, fmt.Sprintf("%+d", step), func() { g.stepArrivalGuess(step) }, getRlColor(colGlassLight))
+		}
+		g.addButton(30, 340, 270, 40, "LOCK IN", g.lockArrivalGuess, getRlColor(colAccent))
+		rl.DrawText(fmt.Sprintf("Up to %d points", core.ArrivalBonusPoints), 30, 395, 16, getRlColor(colTextMuted))
+		rl.DrawText(fmt.Sprintf("Score: %d", g.score), 30, 420, 20, getRlColor(colAccent))
+	} else if g.state == StateOddOneOut {
+		g.drawOddOneOut()
 	}
 
 	// Bottom Controls
@@ -817,26 +1924,47 @@ func (g *Game) drawUI() {
 	if g.state == StateMap {
 		g.addButton(screenWidth/2-60, screenHeight-60, 120, 40, "PLAY GAME", func() { g.startGame() }, getRlColor(colAccent))
 		g.addButton(20, screenHeight-60, 80, 40, "CENTER", func() { g.camLat, g.camLon = myLat, myLon }, getRlColor(colGlass))
+		g.addButton(screenWidth/2+70, screenHeight-60, 100, 40, "REPLAY CODE", g.openReplayEntry, getRlColor(colGlass))
+		g.addButton(screenWidth/2-180, screenHeight-60, 110, 40, "ODD ONE OUT", g.startOddOneOut, getRlColor(colGlass))
 	}
 
 	// Zoom buttons (Always show in Map AND GamePlaying)
 	if g.state == StateMap || g.state == StateGamePlaying {
+		// They step to the next whole level, where tiles are drawn unscaled
 		g.addButton(screenWidth-110, screenHeight-60, 40, 40, "-", func() {
-			if g.camZoom > 4 {
-				g.camZoom--
-			}
+			g.camZoom = core.ClampZoom(math.Ceil(g.camZoom) - 1)
 		}, getRlColor(colGlass))
 		g.addButton(screenWidth-60, screenHeight-60, 40, 40, "+", func() {
-			if g.camZoom < 18 {
-				g.camZoom++
-			}
+			g.camZoom = core.ClampZoom(math.Floor(g.camZoom) + 1)
 		}, getRlColor(colGlass))
 	}
 
 	if g.state == StateGameOver {
-		g.drawPanel(screenWidth/2-150, screenHeight/2-100, 300, 200, "GAME OVER")
-		rl.DrawText(fmt.Sprintf("Final Score: %d", g.score), int32(screenWidth)/2-250, int32(screenHeight)/2, 20, rl.White)
-		g.addButton(screenWidth/2-60, screenHeight/2+40, 120, 40, "CLOSE", func() { g.endGame() }, getRlColor(colAccent))
+		top := screenHeight/2 - 100
+		if len(g.podium) > 0 {
+			top -= 140
+		}
+		if g.partyGame {
+			g.drawPartyStandings(screenWidth/2-460, top, 10)
+		}
+		g.drawPanel(screenWidth/2-150, top, 300, screenHeight/2+100-top, "GAME OVER")
+		if len(g.podium) > 0 {
+			g.drawPodium(top + 50)
+		}
+		if s := g.party.Standings(); g.partyGame && len(s) > 0 {
+			rl.DrawText("Winner: "+s[0].Name, int32(screenWidth)/2-100, int32(screenHeight)/2, 20, getRlColor(colGold))
+		} else {
+			rl.DrawText(fmt.Sprintf("Final Score: %d", g.score), int32(screenWidth)/2-250, int32(screenHeight)/2, 20, rl.White)
+		}
+		if g.replay != nil {
+			rl.DrawText(fmt.Sprintf("%s scored %d", g.replay.Player, g.replayScore), int32(screenWidth)/2-130, int32(screenHeight)/2-40, 16, getRlColor(colAccent))
+		} else if g.shareCode != "" {
+			rl.DrawText("Share code: "+g.shareCode, int32(screenWidth)/2-130, int32(screenHeight)/2-40, 16, getRlColor(colAccent))
+		}
+		if g.bonusResult != "" {
+			rl.DrawText(g.bonusResult, int32(screenWidth)/2-130, int32(screenHeight)/2+28, 16, getRlColor(colGold))
+		}
+		g.addButton(screenWidth/2-60, screenHeight/2+52, 120, 40, "CLOSE", func() { g.endGame() }, getRlColor(colAccent))
 	}
 
 	// Draw Buttons
@@ -853,9 +1981,308 @@ func (g *Game) drawUI() {
 	}
 }
 
+// tagColor gives each interesting-traffic tag its own badge colour
+func tagColor(t core.Tag) rl.Color {
+	switch t {
+	case core.TagMilitary:
+		return rl.NewColor(85, 107, 47, 255)
+	case core.TagGovernment, core.TagPolice:
+		return rl.NewColor(30, 64, 175, 255)
+	case core.TagTest:
+		return rl.NewColor(202, 138, 4, 255)
+	}
+	return rl.NewColor(190, 24, 93, 255)
+}
+
+// drawWatchAlert announces a watchlisted aircraft coming into range
+func (g *Game) drawWatchAlert() {
+	if g.watchAlert == "" || time.Now().After(g.watchAlertUntil) {
+		return
+	}
+	w := int(rl.MeasureText(g.watchAlert, 18)) + 24
+	x := (screenWidth - w) / 2
+	rl.DrawRectangle(int32(x), 94, int32(w), 28, rl.NewColor(160, 40, 160, 220))
+	rl.DrawText(g.watchAlert, int32(x+12), 99, 18, rl.White)
+}
+
+// drawPhoto shows the aircraft's planespotters thumbnail at (x, y), at
+// most w wide and 130 high, with the photographer's credit their terms ask
+// for. The texture is swapped when the selection changes.
+func (g *Game) drawPhoto(f *core.Flight, x, y, w int) {
+	photo := g.photos.Photo(*f)
+	if photo == nil {
+		return
+	}
+	if g.photoKey != f.Icao24 {
+		if g.photoKey != "" {
+			rl.UnloadTexture(g.photoTex)
+		}
+		img := rl.NewImageFromImage(photo.Image)
+		g.photoKey, g.photoTex = f.Icao24, rl.LoadTextureFromImage(img)
+		rl.UnloadImage(img)
+		rl.SetTextureFilter(g.photoTex, rl.FilterBilinear)
+	}
+	scale := min(float32(w)/float32(g.photoTex.Width), 130/float32(g.photoTex.Height))
+	rl.DrawTextureEx(g.photoTex, rl.Vector2{X: float32(x), Y: float32(y)}, 0, scale, rl.White)
+	h := int32(float32(g.photoTex.Height) * scale)
+	rl.DrawText(truncate(photo.Credit(), 40), int32(x), int32(y)+h+4, 12, getRlColor(colTextMuted))
+}
+
+// drawPartyStandings lists the phones' scores in a panel at (x, y), at
+// most n of them
+func (g *Game) drawPartyStandings(x, y, n int) {
+	standings := g.party.Standings()
+	if len(standings) > n {
+		standings = standings[:n]
+	}
+	g.drawPanel(x, y, 300, 60+len(standings)*24, "SCOREBOARD")
+	for i, s := range standings {
+		row := int32(y + 55 + i*24)
+		rl.DrawText(fmt.Sprintf("%d. %s", i+1, s.Name), int32(x)+20, row, 18, rl.White)
+		score := fmt.Sprint(s.Score)
+		rl.DrawText(score, int32(x+280)-rl.MeasureText(score, 18), row, 18, getRlColor(colGold))
+	}
+}
+
+// drawAirlineLegend names the busiest carriers' colours above the CENTER
+// button when planes are coloured by airline
+func (g *Game) drawAirlineLegend() {
+	if !g.settings.Get().AirlineColors || len(g.airlineLegend) == 0 {
+		return
+	}
+	y := screenHeight - 75 - len(g.airlineLegend)*22
+	rl.DrawRectangle(20, int32(y-8), 110, int32(len(g.airlineLegend)*22+10), getRlColor(colGlass))
+	for _, e := range g.airlineLegend {
+		rl.DrawRectangle(30, int32(y+2), 14, 14, getRlColor(e.Color))
+		rl.DrawText(fmt.Sprintf("%s %d", e.Code, e.Count), 52, int32(y), 18, getRlColor(colText))
+		y += 22
+	}
+}
+
+// drawEmergencyBanner stays up for as long as an aircraft in range squawks
+// an emergency code
+func (g *Game) drawEmergencyBanner() {
+	if len(g.emergencies) == 0 {
+		return
+	}
+	f := g.emergencies[0]
+	msg := fmt.Sprintf("EMERGENCY: %s squawking %s (%s)", f.Callsign, f.Squawk, f.Emergency)
+	if n := len(g.emergencies) - 1; n > 0 {
+		msg += fmt.Sprintf(" +%d more", n)
+	}
+	w := int(rl.MeasureText(msg, 18)) + 24
+	x := (screenWidth - w) / 2
+	rl.DrawRectangle(int32(x), 128, int32(w), 28, rl.Fade(getRlColor(colDanger), float32(emergencyPulse())))
+	rl.DrawText(msg, int32(x+12), 133, 18, rl.White)
+}
+
+// emergencyPulse is the opacity emergency highlights throb with, about
+// once a second
+func emergencyPulse() float64 {
+	return 0.7 + 0.3*math.Sin(float64(time.Now().UnixMilli())/160)
+}
+
+// drawThrottleBanner tells the user when the flight source has asked us to
+// back off, or when polling has slowed down to make the credits last
+func (g *Game) drawThrottleBanner() {
+	info := g.provider.RateLimitInfo()
+	msg, col := "Throttled until "+info.ThrottledUntil.Format("15:04"), uint32(colDanger)
+	if !time.Now().Before(info.ThrottledUntil) {
+		settings := g.settings.Get()
+		interval := settings.PollInterval(g.provider)
+		if interval <= settings.ConfiguredPollInterval(g.provider) {
+			return
+		}
+		msg = fmt.Sprintf("%d credits left, updating every %s", info.Remaining, interval.Round(time.Second))
+		col = colTextMuted
+	}
+	w := int(rl.MeasureText(msg, 18)) + 24
+	x := (screenWidth - w) / 2
+	rl.DrawRectangle(int32(x), 60, int32(w), 28, getRlColor(colGlass))
+	rl.DrawText(msg, int32(x+12), 65, 18, getRlColor(col))
+}
+
+// drawRunways names the runways arrivals at the home airport are using and
+// lists the queue for them, under the receiver widget when there is one
+func (g *Game) drawRunways() {
+	title := g.runwayUse.String()
+	if title == "" {
+		return
+	}
+	lines := []string{title}
+	for _, q := range g.arrivalQueue[:min(len(g.arrivalQueue), arrivalQueueShown)] {
+		lines = append(lines, q.Line(len(g.runwayUse.Arrivals) > 1))
+	}
+	w := int32(0)
+	for _, l := range lines {
+		w = max(w, rl.MeasureText(l, 16)+20)
+	}
+	y := int32(40)
+	if g.receiver != nil {
+		y += 90
+	}
+	rl.DrawRectangle(10, y, w, int32(len(lines))*22+8, getRlColor(colGlass))
+	for i, l := range lines {
+		col := getRlColor(colText)
+		if i == 0 {
+			col = getRlColor(colAccent)
+		}
+		rl.DrawText(l, 20, y+6+int32(i)*22, 16, col)
+	}
+}
+
+// drawApproaches draws the corridor arrivals fly down onto each runway in
+// use, the extended centreline ticked every approachTickKm, and numbers
+// the aircraft queued for it, as on a tower's radar
+func (g *Game) drawApproaches() {
+	if g.state != StateMap || len(g.runwayUse.Approaches) == 0 {
+		return
+	}
+	lineCol := rl.NewColor(56, 189, 248, 150)
+	line := func(a core.Approach, out1, side1, out2, side2 float64, width float32) {
+		x1, y1 := g.screenPos(a.Point(out1, side1))
+		x2, y2 := g.screenPos(a.Point(out2, side2))
+		rl.DrawLineEx(rl.NewVector2(float32(x1), float32(y1)), rl.NewVector2(float32(x2), float32(y2)), width, lineCol)
+	}
+	far := core.ApproachCorridorKm
+	for _, a := range g.runwayUse.Approaches {
+		line(a, 0, 0, far, 0, 1.5)
+		for _, side := range []float64{-1, 1} {
+			line(a, 0, side*core.CorridorHalfWidthKm(0), far, side*core.CorridorHalfWidthKm(far), 1.5)
+		}
+		for d := approachTickKm; d < far; d += approachTickKm {
+			line(a, d, -0.5, d, 0.5, 3)
+		}
+		x, y := g.screenPos(a.Point(far, 0))
+		rl.DrawText(a.Runway, int32(x)-14, int32(y)-20, 18, getRlColor(colAccent))
+	}
+
+	now := time.Now()
+	for _, q := range g.arrivalQueue {
+		if f := g.flights.Get(q.Flight.Icao24); f != nil {
+			x, y := g.screenPos(g.flights.Position(f, now))
+			rl.DrawText(strconv.Itoa(q.Number), int32(x)+18, int32(y)-24, 18, getRlColor(colGold))
+		}
+	}
+}
+
+// drawReceiverWidget shows local ADS-B feeder health and warns if the feed dies
+func (g *Game) drawReceiverWidget() {
+	if g.receiver == nil {
+		return
+	}
+	st := g.receiver.Status()
+
+	x, y := int32(10), int32(40)
+	rl.DrawRectangle(x, y, 280, 80, getRlColor(colGlass))
+	rl.DrawText("RECEIVER", x+10, y+10, 16, getRlColor(colAccent))
+
+	if st.Err != nil || st.Dead(time.Now()) {
+		rl.DrawText("FEED DOWN", x+110, y+10, 16, getRlColor(colDanger))
+		since := "never"
+		if !st.LastMessages.IsZero() {
+			since = st.LastMessages.Format("15:04:05")
+		}
+		rl.DrawText("Last msg: "+since, x+10, y+35, 14, getRlColor(colTextMuted))
+		if st.Err != nil {
+			rl.DrawText(truncate(st.Err.Error(), 34), x+10, y+55, 14, getRlColor(colTextMuted))
+		}
+		return
+	}
+
+	rl.DrawText(fmt.Sprintf("%.0f msg/s  %d aircraft", st.MessageRate, st.Aircraft), x+10, y+35, 14, rl.White)
+	rl.DrawText(fmt.Sprintf("Range: %.0f km", st.MaxRangeKm), x+10, y+55, 14, rl.White)
+}
+
+// toggleMagneticBearings switches bearing readouts between true and magnetic north
+func (g *Game) toggleMagneticBearings() {
+	g.updateSettings(func(s *core.Settings) { s.MagneticBearings = !s.MagneticBearings })
+}
+
+// cycleRegion jumps the camera to the next watch region
+func (g *Game) cycleRegion() {
+	regions := g.settings.Get().WatchRegions(myLat, myLon)
+	g.activeRegion = (g.activeRegion + 1) % len(regions)
+	r := regions[g.activeRegion]
+	g.camLat, g.camLon = r.Lat, r.Lon
+}
+
+// applyHomeAirport makes the airport chosen in settings, or else the major
+// airport nearest home, the reference for inbound questions, arrivals and
+// the weather
+func (g *Game) applyHomeAirport() {
+	core.SetHomeAirport(core.FindHomeAirport(g.airportDB, myLat, myLon, g.settings.Get().HomeAirport))
+}
+
+// updateSettings applies a change from the settings screen and persists it
+func (g *Game) updateSettings(fn func(*core.Settings)) {
+	s := g.settings.Update(fn)
+	if err := g.dataManager.SaveSettings(s); err != nil {
+		log.Println("Error saving settings:", err)
+	}
+}
+
 func (g *Game) drawPanel(x, y, w, h int, title string) {
 	rl.DrawRectangle(int32(x), int32(y), int32(w), int32(h), getRlColor(colGlass))
-	rl.DrawText(title, int32(x)+20, int32(y)+20, 20, getRlColor(colAccent))
+	rl.DrawText(title, int32(x+lay("panel_pad", 20)), int32(y+lay("panel_title_y", 20)), 20, getRlColor(colAccent))
+}
+
+// drawSplash shows startup progress and any steps that failed
+func (g *Game) drawSplash() {
+	g.buttons = g.buttons[:0]
+	p := g.startup.Progress()
+
+	title := "VANTAA FLIGHTRADAR24"
+	tw := rl.MeasureText(title, 30)
+	rl.DrawText(title, int32(screenWidth-int(tw))/2, 220, 30, getRlColor(colAccent))
+
+	barW := 450
+	x := (screenWidth - barW) / 2
+	rl.DrawRectangle(int32(x), 270, int32(barW), 12, getRlColor(colGlass))
+	rl.DrawRectangle(int32(x), 270, int32(float64(barW)*p.Fraction()), 12, getRlColor(colAccent))
+	rl.DrawText(splashStatus(p), int32(x), 295, 20, getRlColor(colTextMuted))
+
+	y := 335
+	for _, e := range p.Errors {
+		rl.DrawText(truncate(e, 100), int32(screenWidth-1000)/2, int32(y), 18, getRlColor(colDanger))
+		y += 26
+	}
+	if p.Finished && len(p.Errors) > 0 {
+		left := int((splashErrorHold - time.Since(g.splashDoneAt)).Seconds()) + 1
+		g.addButton(screenWidth/2-90, y+15, 180, 40, fmt.Sprintf("CONTINUE (%d)", max(left, 1)), func() { g.state = StateLogin }, getRlColor(colAccent))
+	}
+
+	for _, b := range g.buttons {
+		rl.DrawRectangle(int32(b.X), int32(b.Y), int32(b.W), int32(b.H), b.Color)
+		tw := rl.MeasureText(b.Text, 20)
+		tx := b.X + (b.W-int(tw))/2
+		ty := b.Y + (b.H-20)/2 + 2
+		rl.DrawText(b.Text, int32(tx), int32(ty), 20, b.TextColor)
+	}
+}
+
+// drawReleaseNotes shows the newer release the update checker found, for
+// the owner to download by hand
+func (g *Game) drawReleaseNotes(rel *core.Release) {
+	panelX, panelY, panelW, panelH := screenWidth/2-350, 140, 700, 440
+	rl.DrawRectangle(int32(panelX), int32(panelY), int32(panelW), int32(panelH), getRlColor(colGlass))
+
+	name := rel.Name
+	if name == "" {
+		name = rel.Tag
+	}
+	rl.DrawText("Update available: "+truncate(name, 40), int32(panelX+20), int32(panelY+20), 20, getRlColor(colGold))
+	if !rel.Published.IsZero() {
+		rl.DrawText("Released "+rel.Published.Local().Format("2 Jan 2006"), int32(panelX+20), int32(panelY+48), 16, getRlColor(colTextMuted))
+	}
+	y := panelY + 85
+	for _, line := range rel.NoteLines(70, 12) {
+		rl.DrawText(truncate(line, 70), int32(panelX+20), int32(y), 16, getRlColor(colText))
+		y += 22
+	}
+	rl.DrawText(truncate(rel.URL, 70), int32(panelX+20), int32(panelY+panelH-75), 16, getRlColor(colAccent))
+
+	g.addButton(panelX+panelW-120, panelY+panelH-45, 100, 30, "CLOSE", func() { g.showReleaseNotes = false }, getRlColor(colGlassLight))
 }
 
 func (g *Game) drawLogin() {
@@ -866,7 +2293,16 @@ func (g *Game) drawLogin() {
 	tw := rl.MeasureText(title, 30)
 	rl.DrawText(title, int32(screenWidth-int(tw))/2, 80, 30, getRlColor(colAccent))
 
-	if g.showDeleteConfirm {
+	rel := g.updates.Available()
+	if rel != nil {
+		g.addButton(screenWidth-210, 10, 200, 30, "UPDATE "+truncate(rel.Tag, 10), func() {
+			g.showReleaseNotes = !g.showReleaseNotes
+		}, getRlColor(colGold), getRlColor(colBgDark))
+	}
+
+	if rel != nil && g.showReleaseNotes {
+		g.drawReleaseNotes(rel)
+	} else if g.showDeleteConfirm {
 		// Dialog
 		panelX, panelY := screenWidth/2-150, 200
 		rl.DrawRectangle(int32(panelX), int32(panelY), 300, 150, getRlColor(colGlass))
@@ -888,40 +2324,12 @@ func (g *Game) drawLogin() {
 		g.addButton(screenWidth/2-100, 180, 200, 30, "", func() { g.isKeyboardOpen = !g.isKeyboardOpen }, rl.Fade(rl.White, 0.0))
 
 		if g.isKeyboardOpen {
-			kbW, kbH := 520, 250
-			kbX, kbY := (screenWidth-kbW)/2, 225
-			rl.DrawRectangle(int32(kbX-10), int32(kbY-10), int32(kbW+20), int32(kbH+20), getRlColor(colBgDark))
-
-			for r, row := range g.keyboardLayout {
-				rowW := len(row) * 50
-				rowStart := kbX + (kbW-rowW)/2
-				for c, char := range row {
-					charStr := string(char)
-					bx := rowStart + c*50
-					by := kbY + r*50
-					g.addButton(bx, by, 45, 45, charStr, func() { g.inputText += charStr }, getRlColor(colGlassLight))
-				}
-			}
-
-			ctrlY := kbY + 3*50 + 10
-			g.addButton(kbX, ctrlY, 100, 45, "HIDE", func() { g.isKeyboardOpen = false }, getRlColor(colGlass))
-			g.addButton(kbX+120, ctrlY, 100, 45, "DEL", func() {
-				if len(g.inputText) > 0 {
-					g.inputText = g.inputText[:len(g.inputText)-1]
-				}
-			}, getRlColor(colDanger))
-			g.addButton(kbX+kbW-120, ctrlY, 120, 45, "ENTER", func() { g.login(g.inputText) }, getRlColor(colSuccess))
+			g.drawKeyboard(func() { g.login(g.inputText) })
 		} else {
 			// User List
 			y := 240
-			var keys []string
-			for k := range g.usersMap {
-				keys = append(keys, k)
-			}
-			sort.Strings(keys)
-
-			for _, name := range keys {
-				u := g.usersMap[name]
+			for _, name := range g.users.Names() {
+				u, _ := g.users.Get(name)
 				n := name
 				label := fmt.Sprintf("%s (%d)", u.Name, u.BestScore)
 
@@ -935,6 +2343,9 @@ func (g *Game) drawLogin() {
 		}
 	}
 
+	version := core.Build().String()
+	rl.DrawText(version, int32(screenWidth)-rl.MeasureText(version, 14)-10, screenHeight-25, 14, getRlColor(colTextMuted))
+
 	g.addButton(20, screenHeight-50, 100, 30, "QUIT", func() { g.shouldQuit = true }, getRlColor(colDanger))
 
 	// Draw Buttons
@@ -951,30 +2362,107 @@ func (g *Game) drawLogin() {
 	}
 }
 
-func (g *Game) drawLeaderboard() {
+func (g *Game) drawSettings() {
 	g.buttons = g.buttons[:0]
-	rl.DrawText("LEADERBOARD", 20, 30, 20, getRlColor(colAccent))
+	s := g.settings.Get()
 
-	rl.DrawText("TOP SCORES", 50, 70, 20, rl.White)
-	y := 100
-	for i, s := range g.highScores {
-		line := fmt.Sprintf("%d. %s - %d", i+1, s.Name, s.Score)
-		rl.DrawText(line, 50, int32(y), 20, rl.White)
-		y += 25
+	rl.DrawText("SETTINGS", 20, 30, 20, getRlColor(colAccent))
+
+	row := func(y int, label, value string, step func(dir int)) {
+		rl.DrawText(label, 50, int32(y+5), 20, rl.White)
+		g.addButton(300, y, 40, 30, "-", func() { step(-1) }, getRlColor(colGlassLight))
+		rl.DrawText(value, 360, int32(y+5), 20, rl.White)
+		g.addButton(520, y, 40, 30, "+", func() { step(1) }, getRlColor(colGlassLight))
 	}
+	row(80, "Poll interval", s.PollIntervalLabel(g.provider), func(dir int) {
+		g.updateSettings(func(s *core.Settings) {
+			s.PollIntervalSec = core.StepSetting(core.PollIntervalSteps, s.PollIntervalSec, dir)
+		})
+	})
+	row(130, "Search radius", fmt.Sprintf("%.0f km", s.RadiusKm), func(dir int) {
+		g.updateSettings(func(s *core.Settings) { s.RadiusKm = core.StepSetting(core.RadiusStepsKm, s.RadiusKm, dir) })
+	})
+
+	row(180, "Min altitude", fmt.Sprintf("%d ft", s.Filter.MinAltitudeFt), func(dir int) {
+		g.updateSettings(func(s *core.Settings) {
+			s.Filter.MinAltitudeFt = core.StepSetting(core.MinAltitudeSteps, s.Filter.MinAltitudeFt, dir)
+		})
+	})
+	ground := "SHOWN"
+	if s.Filter.ExcludeOnGround {
+		ground = "HIDDEN"
+	}
+	rl.DrawText("On-ground aircraft", 50, 235, 20, rl.White)
+	g.addButton(300, 230, 260, 30, ground, func() {
+		g.updateSettings(func(s *core.Settings) { s.Filter.ExcludeOnGround = !s.Filter.ExcludeOnGround })
+	}, getRlColor(colGlassLight))
+
+	interesting := "OFF"
+	if s.AlertInteresting {
+		interesting = "ON"
+	}
+	rl.DrawText("Interesting alerts", 50, 335, 20, rl.White)
+	g.addButton(300, 330, 260, 30, interesting, func() {
+		g.updateSettings(func(s *core.Settings) { s.AlertInteresting = !s.AlertInteresting })
+	}, getRlColor(colGlassLight))
+
+	planeColors := "PLAIN"
+	if s.AirlineColors {
+		planeColors = "BY AIRLINE"
+	}
+	rl.DrawText("Plane colours", 50, 385, 20, rl.White)
+	g.addButton(300, 380, 260, 30, planeColors, func() {
+		g.updateSettings(func(s *core.Settings) { s.AirlineColors = !s.AirlineColors })
+	}, getRlColor(colGlassLight))
+
+	bearings := "TRUE NORTH"
+	if s.MagneticBearings {
+		bearings = "MAGNETIC"
+	}
+	rl.DrawText("Bearings", 50, 285, 20, rl.White)
+	g.addButton(300, 280, 260, 30, bearings, g.toggleMagneticBearings, getRlColor(colGlassLight))
 
-	rl.DrawText("PLAYER STATS", 400, 70, 20, rl.White)
-	y = 100
-	for i, u := range g.userStatsList {
-		if i >= 10 {
-			break
-		}
-		line := fmt.Sprintf("%s: Best %d | Played %d", u.Name, u.BestScore, u.GamesPlayed)
-		rl.DrawText(line, 400, int32(y), 20, rl.White)
-		y += 25
+	bonus := "OFF"
+	if s.ArrivalBonus {
+		bonus = "ON"
 	}
+	rl.DrawText("Arrival bonus round", 50, 435, 20, rl.White)
+	g.addButton(300, 430, 260, 30, bonus, func() {
+		g.updateSettings(func(s *core.Settings) { s.ArrivalBonus = !s.ArrivalBonus })
+	}, getRlColor(colGlassLight))
+
+	row(480, "Home airport", core.HomeAirportLabel(s.HomeAirport), func(dir int) {
+		g.updateSettings(func(s *core.Settings) {
+			s.HomeAirport = core.StepChoice(core.HomeAirportChoices(g.airportDB, myLat, myLon, s.HomeAirport), s.HomeAirport, dir)
+		})
+		g.applyHomeAirport()
+	})
+
+	// The alert filter applies on top of the map filters above
+	row(530, "Alert altitude", s.AlertCeilingLabel(), func(dir int) {
+		g.updateSettings(func(s *core.Settings) {
+			s.AlertFilter.MaxAltitudeFt = core.StepSetting(core.AlertAltitudeSteps, s.AlertFilter.MaxAltitudeFt, dir)
+		})
+	})
+	rl.DrawText("Alert on", 50, 585, 20, rl.White)
+	g.addButton(300, 580, 260, 30, core.CategoryPresetName(core.AlertCategoryPresets, s.AlertFilter.Categories), func() {
+		g.updateSettings(func(s *core.Settings) {
+			s.AlertFilter.Categories = core.NextCategoryPreset(core.AlertCategoryPresets, s.AlertFilter.Categories)
+		})
+	}, getRlColor(colGlassLight))
+
+	rl.DrawText("Power saving", 50, 635, 20, rl.White)
+	g.addButton(300, 630, 260, 30, s.EcoMode.Label(g.power.OnBattery()), func() {
+		g.updateSettings(func(s *core.Settings) { s.EcoMode = s.EcoMode.Next() })
+		g.prefetched = core.TileCoord{}
+	}, getRlColor(colGlassLight))
 
 	g.addButton(20, screenHeight-50, 100, 30, "BACK", func() { g.state = StateMap }, getRlColor(colDanger))
+	g.addButton(130, screenHeight-50, 100, 30, "STATUS", g.openStatus, getRlColor(colGlassLight))
+	g.addButton(240, screenHeight-50, 100, 30, "NOISE", g.openNoise, getRlColor(colGlassLight))
+	g.addButton(350, screenHeight-50, 120, 30, "REGULARS", g.openRegulars, getRlColor(colGlassLight))
+	g.addButton(480, screenHeight-50, 100, 30, "DIARY", g.openDiary, getRlColor(colGlassLight))
+	g.addButton(590, screenHeight-50, 120, 30, "STORAGE", g.openStorage, getRlColor(colGlassLight))
 
 	for _, b := range g.buttons {
 		rl.DrawRectangle(int32(b.X), int32(b.Y), int32(b.W), int32(b.H), b.Color)
@@ -985,149 +2473,1290 @@ func (g *Game) drawLeaderboard() {
 	}
 }
 
-func (g *Game) addButton(x, y, w, h int, label string, action func(), col rl.Color, txtCol ...rl.Color) {
-	tc := rl.White
-	if len(txtCol) > 0 {
+// openStatus takes a fresh health report and shows it. The report walks the
+// data dir, so it is taken here rather than every frame.
+func (g *Game) openStatus() {
+	r := core.CheckHealth(g.provider, &g.fetchHealth)
+	r.Caches = []core.CacheSize{
+		{Name: "Flights tracked", Entries: g.flights.Len()},
+		{Name: "Map tiles", Entries: g.tileLoader.Len()},
+		{Name: "Tile queue", Entries: g.tileLoader.Fetching()},
+		{Name: "Aircraft DB", Entries: g.aircraft.Len()},
+		{Name: "Tag DB", Entries: g.tags.Len()},
+		{Name: "Aircraft photos", Entries: g.photos.Len()},
+		{Name: "Airport DB", Entries: g.airportDB.Len()},
+	}
+	r.Startup = g.startup.Progress().Errors
+	r.Update = g.updates.Available()
+	r.Backup = g.backup.Status()
+	if results, err := g.dataManager.DistractorResults(); err != nil {
+		log.Println("Error reading distractor results:", err)
+	} else {
+		r.Experiments = results
+	}
+	g.health = r
+	g.state = StateStatus
+}
+
+// drawStatus lists the health report, problems in red: the first place to
+// look when no planes are showing
+func (g *Game) drawStatus() {
+	g.buttons = g.buttons[:0]
+
+	rl.DrawText("STATUS", 20, 30, 20, getRlColor(colAccent))
+	rl.DrawText("as of "+g.health.CheckedAt.Format("15:04:05"), 120, 33, 16, getRlColor(colTextMuted))
+
+	y := int32(80)
+	for _, l := range g.health.Lines() {
+		valueCol := getRlColor(colText)
+		if l.Problem {
+			valueCol = getRlColor(colDanger)
+		}
+		rl.DrawText(l.Label, 50, y, 18, getRlColor(colTextMuted))
+		rl.DrawText(truncate(l.Value, 90), 260, y, 18, valueCol)
+		y += 28
+	}
+
+	g.addButton(20, screenHeight-50, 100, 30, "BACK", func() { g.state = StateSettings }, getRlColor(colDanger))
+	g.addButton(130, screenHeight-50, 100, 30, "REFRESH", g.openStatus, getRlColor(colGlassLight))
+	g.addButton(240, screenHeight-50, 130, 30, "RESOLVERS", func() { g.state = StateResolvers }, getRlColor(colGlassLight))
+	g.addButton(380, screenHeight-50, 130, 30, "CALIBRATE", g.openCalibration, getRlColor(colGlassLight))
+
+	for _, b := range g.buttons {
+		rl.DrawRectangle(int32(b.X), int32(b.Y), int32(b.W), int32(b.H), b.Color)
+		tw := rl.MeasureText(b.Text, 20)
+		tx := b.X + (b.W-int(tw))/2
+		ty := b.Y + (b.H-20)/2 + 2
+		rl.DrawText(b.Text, int32(tx), int32(ty), 20, b.TextColor)
+	}
+}
+
+// drawResolvers is the debug view of the route resolver chain: how each
+// resolver is doing and which are demoted for failing
+func (g *Game) drawResolvers() {
+	g.buttons = g.buttons[:0]
+	now := time.Now()
+
+	rl.DrawText("RESOLVERS", 20, 30, 20, getRlColor(colAccent))
+	rl.DrawText("tried in this order; one that keeps failing moves to the back for a while", 150, 33, 16, getRlColor(colTextMuted))
+
+	stats := core.ResolverHealth(g.resolver)
+	if stats == nil {
+		rl.DrawText("Lookups are answered by the "+g.provider.Name()+" provider itself", 50, 85, 18, getRlColor(colTextMuted))
+	}
+	y := int32(85)
+	for _, s := range stats {
+		status, col := "ok", getRlColor(colSuccess)
+		switch {
+		case s.IsDemoted(now):
+			status, col = "demoted until "+s.Demoted.Local().Format("15:04"), getRlColor(colDanger)
+		case s.Streak > 0:
+			status, col = fmt.Sprintf("%d failures in a row", s.Streak), getRlColor(colGold)
+		case s.Attempts == 0:
+			status, col = "not asked yet", getRlColor(colTextMuted)
+		}
+		rl.DrawText(s.Name, 50, y, 20, rl.White)
+		rl.DrawText(status, 300, y, 20, col)
+		y += 26
+		rl.DrawText(s.String(), 70, y, 18, getRlColor(colTextMuted))
+		if s.LastError != "" {
+			y += 24
+			rl.DrawText(truncate("last error: "+s.LastError, 110), 70, y, 18, getRlColor(colTextMuted))
+		}
+		y += 44
+	}
+	if stats != nil {
+		trusted, hits := g.learned.Stats(now)
+		rl.DrawText("Learned routes", 50, y, 20, rl.White)
+		rl.DrawText(fmt.Sprintf("%d callsigns known, %d lookups answered", trusted, hits), 300, y, 20, getRlColor(colTextMuted))
+	}
+
+	g.addButton(20, screenHeight-50, 100, 30, "BACK", func() { g.state = StateStatus }, getRlColor(colDanger))
+
+	for _, b := range g.buttons {
+		rl.DrawRectangle(int32(b.X), int32(b.Y), int32(b.W), int32(b.H), b.Color)
+		tw := rl.MeasureText(b.Text, 20)
+		tx := b.X + (b.W-int(tw))/2
+		ty := b.Y + (b.H-20)/2 + 2
+		rl.DrawText(b.Text, int32(tx), int32(ty), 20, b.TextColor)
+	}
+}
+
+// openCalibration starts a touch calibration run: the player taps five
+// crosses and the correction is saved once they are all in
+func (g *Game) openCalibration() {
+	g.calibrator = core.NewTouchCalibrator(screenWidth, screenHeight)
+	g.calibrationMsg = ""
+	g.state = StateCalibrate
+}
+
+// updateCalibration takes a tap on each target, uncorrected, then fits and
+// saves the correction. It reports whether it used the input, which is
+// until every target is tapped; the result's buttons are ordinary ones.
+func (g *Game) updateCalibration() bool {
+	// Escape is raylib's quit key
+	if rl.IsKeyPressed(rl.KeyBackspace) {
+		g.state = StateStatus
+		return true
+	}
+	if g.calibrator.Done() {
+		return false
+	}
+	if !rl.IsMouseButtonPressed(rl.MouseLeftButton) {
+		return true
+	}
+	p := rl.GetMousePosition() // raylib reports the first touch as the mouse
+	g.calibrator.Tap(float64(p.X), float64(p.Y))
+	if !g.calibrator.Done() {
+		return true
+	}
+
+	cal, err := g.calibrator.Fit(g.screenXf)
+	if err != nil {
+		g.calibrationMsg = err.Error()
+		g.calibrator.Restart()
+		return true
+	}
+	g.touchCal = cal
+	g.calibrationMsg = "Saved. Check that DONE lines up with your tap."
+	if err := g.dataManager.SaveTouchCalibration(cal); err != nil {
+		log.Println("Error saving touch calibration:", err)
+		g.calibrationMsg = "Applied, but couldn't be saved: " + err.Error()
+	}
+	return true
+}
+
+// drawCalibration shows the target to tap next, or the result
+func (g *Game) drawCalibration() {
+	g.buttons = g.buttons[:0]
+
+	rl.DrawText("TOUCH CALIBRATION", 20, 30, 20, getRlColor(colAccent))
+	if x, y, n, ok := g.calibrator.Target(); ok {
+		msg := fmt.Sprintf("Tap the centre of the cross (%d of %d). Backspace cancels.", n, g.calibrator.Targets())
+		rl.DrawText(msg, 250, 33, 16, getRlColor(colTextMuted))
+		cx, cy := float32(x), float32(y)
+		col := getRlColor(colGold)
+		rl.DrawLineEx(rl.Vector2{X: cx - 22, Y: cy}, rl.Vector2{X: cx + 22, Y: cy}, 3, col)
+		rl.DrawLineEx(rl.Vector2{X: cx, Y: cy - 22}, rl.Vector2{X: cx, Y: cy + 22}, 3, col)
+		rl.DrawCircleLines(int32(x), int32(y), 12, col)
+	} else {
+		g.addButton(screenWidth/2-140, screenHeight/2-20, 130, 40, "DONE", func() { g.state = StateStatus }, getRlColor(colSuccess))
+		g.addButton(screenWidth/2+10, screenHeight/2-20, 130, 40, "AGAIN", g.openCalibration, getRlColor(colGlassLight))
+	}
+	if g.calibrationMsg != "" {
+		tw := rl.MeasureText(g.calibrationMsg, 20)
+		rl.DrawText(g.calibrationMsg, (screenWidth-tw)/2, screenHeight/2+50, 20, rl.White)
+	}
+
+	for _, b := range g.buttons {
+		rl.DrawRectangle(int32(b.X), int32(b.Y), int32(b.W), int32(b.H), b.Color)
+		tw := rl.MeasureText(b.Text, 20)
+		tx := b.X + (b.W-int(tw))/2
+		ty := b.Y + (b.H-20)/2 + 2
+		rl.DrawText(b.Text, int32(tx), int32(ty), 20, b.TextColor)
+	}
+}
+
+// openNoise summarises the noise log for the chart
+func (g *Game) openNoise() {
+	now := time.Now()
+	events, err := g.dataManager.LoadNoiseEvents(core.NoiseSince(now, noiseChartDays))
+	if err != nil {
+		log.Println("Error loading noise events:", err)
+	}
+	g.noiseStats = core.SummarizeNoise(events, now, noiseChartDays)
+	g.noiseMsg = ""
+	g.state = StateNoise
+}
+
+// exportNoise writes the whole noise log to a CSV in the captures folder
+func (g *Game) exportNoise() {
+	events, err := g.dataManager.LoadNoiseEvents(time.Time{})
+	path := ""
+	if err == nil {
+		path, err = g.dataManager.ExportNoiseCSV(events, time.Now())
+	}
+	if err != nil {
+		log.Println("Noise export failed:", err)
+		g.noiseMsg = "Export failed: " + err.Error()
+		return
+	}
+	log.Println("Saved noise events:", path)
+	g.noiseMsg = fmt.Sprintf("Saved %d events to %s", len(events), path)
+}
+
+// drawNoise charts today's noise events by hour and the last two weeks by
+// day, for documenting a flight path to the authorities
+func (g *Game) drawNoise() {
+	g.buttons = g.buttons[:0]
+	s := g.settings.Get()
+
+	rl.DrawText("NOISE EVENTS", 20, 30, 20, getRlColor(colAccent))
+	rl.DrawText(fmt.Sprintf("flights below %d ft within %.0f km of home", s.NoiseCeiling(), core.NoiseRadiusKm), 180, 33, 16, getRlColor(colTextMuted))
+	if g.noise == nil {
+		rl.DrawText("(not counted for "+g.provider.Name()+" traffic)", 180, 55, 16, getRlColor(colDanger))
+	}
+
+	rl.DrawText("Ceiling", 820, 30, 20, rl.White)
+	g.addButton(920, 22, 30, 30, "-", func() {
+		g.updateSettings(func(s *core.Settings) {
+			s.NoiseAltitudeFt = core.StepSetting(core.NoiseAltitudeSteps, s.NoiseCeiling(), -1)
+		})
+	}, getRlColor(colGlassLight))
+	rl.DrawText(fmt.Sprintf("%d ft", s.NoiseCeiling()), 965, 30, 20, rl.White)
+	g.addButton(1070, 22, 30, 30, "+", func() {
+		g.updateSettings(func(s *core.Settings) {
+			s.NoiseAltitudeFt = core.StepSetting(core.NoiseAltitudeSteps, s.NoiseCeiling(), 1)
+		})
+	}, getRlColor(colGlassLight))
+
+	today := 0
+	for _, n := range g.noiseStats.Hours {
+		today += n
+	}
+	rl.DrawText(fmt.Sprintf("Today by hour: %d", today), 50, 110, 20, rl.White)
+	core.DrawBars(uiRenderer{}, 50, 170, screenWidth-100, 150, g.noiseStats.Hours[:], func(i int) string {
+		if i%3 != 0 {
+			return ""
+		}
+		return fmt.Sprintf("%02d", i)
+	})
+
+	days := make([]int, len(g.noiseStats.Days))
+	for i, d := range g.noiseStats.Days {
+		days[i] = d.Count
+	}
+	rl.DrawText(fmt.Sprintf("Last %d days", len(days)), 50, 370, 20, rl.White)
+	core.DrawBars(uiRenderer{}, 50, 430, screenWidth-100, 150, days, func(i int) string {
+		return g.noiseStats.Days[i].Day.Format("2 Jan")
+	})
+
+	if g.noiseMsg != "" {
+		rl.DrawText(truncate(g.noiseMsg, 120), 20, 625, 16, getRlColor(colTextMuted))
+	}
+
+	g.addButton(20, screenHeight-50, 100, 30, "BACK", func() { g.state = StateSettings }, getRlColor(colDanger))
+	g.addButton(130, screenHeight-50, 140, 30, "EXPORT CSV", g.exportNoise, getRlColor(colGlassLight))
+
+	for _, b := range g.buttons {
+		rl.DrawRectangle(int32(b.X), int32(b.Y), int32(b.W), int32(b.H), b.Color)
+		tw := rl.MeasureText(b.Text, 20)
+		tx := b.X + (b.W-int(tw))/2
+		ty := b.Y + (b.H-20)/2 + 2
+		rl.DrawText(b.Text, int32(tx), int32(ty), 20, b.TextColor)
+	}
+}
+
+// openRegulars works out the regulars for the list
+func (g *Game) openRegulars() {
+	g.regularsList = g.loadRegulars(time.Now())
+	g.state = StateRegulars
+}
+
+// drawRegulars lists the flights that pass at about the same time most
+// days, with the option to be told when one is early or late
+func (g *Game) drawRegulars() {
+	g.buttons = g.buttons[:0]
+
+	rl.DrawText("REGULARS", 20, 30, 20, getRlColor(colAccent))
+	rl.DrawText(fmt.Sprintf("flights passing near home at the same time on most of the last %d days", core.RegularsWindowDays), 140, 33, 16, getRlColor(colTextMuted))
+
+	alerts := "OFF"
+	if g.settings.Get().AlertRegulars {
+		alerts = "ON"
+	}
+	rl.DrawText("Early/late alerts", 50, 85, 20, rl.White)
+	g.addButton(300, 80, 120, 30, alerts, func() {
+		g.updateSettings(func(s *core.Settings) { s.AlertRegulars = !s.AlertRegulars })
+	}, getRlColor(colGlassLight))
+
+	y := int32(140)
+	if len(g.regularsList) == 0 {
+		rl.DrawText("None yet: regulars show up after a few days of logging", 50, y, 18, getRlColor(colTextMuted))
+	}
+	for i, r := range g.regularsList {
+		if i >= 16 {
+			rl.DrawText(fmt.Sprintf("... and %d more", len(g.regularsList)-i), 50, y, 18, getRlColor(colTextMuted))
+			break
+		}
+		rl.DrawText(r.UsualTime(), 50, y, 18, getRlColor(colGold))
+		rl.DrawText(r.Callsign, 130, y, 18, rl.White)
+		rl.DrawText(fmt.Sprintf("%d days", r.Days), 250, y, 18, getRlColor(colTextMuted))
+		rl.DrawText(truncate(r.Route, 80), 360, y, 18, rl.White)
+		y += 28
+	}
+
+	g.addButton(20, screenHeight-50, 100, 30, "BACK", func() { g.state = StateSettings }, getRlColor(colDanger))
+
+	for _, b := range g.buttons {
+		rl.DrawRectangle(int32(b.X), int32(b.Y), int32(b.W), int32(b.H), b.Color)
+		tw := rl.MeasureText(b.Text, 20)
+		tx := b.X + (b.W-int(tw))/2
+		ty := b.Y + (b.H-20)/2 + 2
+		rl.DrawText(b.Text, int32(tx), int32(ty), 20, b.TextColor)
+	}
+}
+
+// Spotting diary views
+const (
+	diaryPasses     = iota // recent overhead passes to write about
+	diaryByDate            // written entries, by day
+	diaryByAircraft        // written entries, by airframe
+)
+
+const (
+	diaryPassDays = 7  // passes offered for notes
+	diaryRows     = 18 // lines per page
+)
+
+// diaryRow is one line of a diary page: a heading, or a pass with its entry
+type diaryRow struct {
+	heading string
+	entry   core.SpottingEntry
+	pass    *core.OverheadPass // in the passes view
+}
+
+// openDiary loads the diary and the recent passes for the diary screen
+func (g *Game) openDiary() {
+	var err error
+	if g.diary, err = g.dataManager.LoadSpottingDiary(); err != nil {
+		log.Println("Error loading spotting diary:", err)
+	}
+	passes, err := g.dataManager.LoadOverheadPasses(time.Now().AddDate(0, 0, -diaryPassDays))
+	if err != nil {
+		log.Println("Error loading overhead passes:", err)
+	}
+	slices.Reverse(passes) // newest first
+	g.diaryPasses = passes
+	g.diaryPage, g.diaryEdit, g.diaryMsg = 0, nil, ""
+	g.state = StateDiary
+}
+
+// diaryRows flattens the current view into lines
+func (g *Game) diaryRows() []diaryRow {
+	var rows []diaryRow
+	if g.diaryView == diaryPasses {
+		for i := range g.diaryPasses {
+			p := &g.diaryPasses[i]
+			rows = append(rows, diaryRow{entry: core.SpottingEntryFor(g.diary, *p), pass: p})
+		}
+		return rows
+	}
+	groups := core.GroupSpottingByDate(g.diary)
+	if g.diaryView == diaryByAircraft {
+		groups = core.GroupSpottingByAircraft(g.diary)
+	}
+	for _, grp := range groups {
+		rows = append(rows, diaryRow{heading: grp.Title})
+		for _, e := range grp.Entries {
+			rows = append(rows, diaryRow{entry: e})
+		}
+	}
+	return rows
+}
+
+// editDiary opens the note editor on e
+func (g *Game) editDiary(e core.SpottingEntry) {
+	g.diaryEdit = &e
+	g.inputText = e.Note
+	g.diaryMsg = ""
+	g.diaryFiles, _ = core.SpottingPhotoCandidates(core.SpottingPhotoDir())
+	g.diaryFile = 0
+}
+
+// saveDiary writes the entry being edited and goes back to the list
+func (g *Game) saveDiary() {
+	e := *g.diaryEdit
+	e.Note = g.inputText
+	if err := g.dataManager.SaveSpottingEntry(e); err != nil {
+		g.diaryMsg = "Save failed: " + err.Error()
+		return
+	}
+	g.isKeyboardOpen = false
+	view := g.diaryView
+	g.openDiary()
+	g.diaryView = view
+}
+
+// attachDiaryPhoto attaches the fetched thumbnail of the aircraft, or the
+// picked file when fromFile
+func (g *Game) attachDiaryPhoto(fromFile bool) {
+	var err error
+	if fromFile {
+		if g.diaryFile >= len(g.diaryFiles) {
+			g.diaryMsg = "No photos in " + core.SpottingPhotoDir()
+			return
+		}
+		err = g.dataManager.AttachSpottingFile(g.diaryEdit, g.diaryFiles[g.diaryFile])
+	} else {
+		photo := g.photos.Photo(core.Flight{Icao24: g.diaryEdit.Icao24})
+		if photo == nil {
+			g.diaryMsg = "No photo fetched yet; try again in a moment"
+			return
+		}
+		err = g.dataManager.AttachSpottingThumbnail(g.diaryEdit, photo)
+	}
+	if err != nil {
+		g.diaryMsg = "Photo: " + err.Error()
+		return
+	}
+	g.diaryMsg = "Photo attached"
+}
+
+// drawDiary is the spotting diary: notes and photos on overhead passes,
+// browsable by date and by aircraft
+func (g *Game) drawDiary() {
+	g.buttons = g.buttons[:0]
+	if g.diaryEdit != nil {
+		g.drawDiaryEditor()
+		return
+	}
+
+	rl.DrawText("SPOTTING DIARY", 20, 30, 20, getRlColor(colAccent))
+	for i, label := range []string{"RECENT PASSES", "BY DATE", "BY AIRCRAFT"} {
+		view := i
+		col := getRlColor(colGlassLight)
+		if g.diaryView == view {
+			col = getRlColor(colAccent)
+		}
+		g.addButton(240+i*190, 22, 180, 34, label, func() { g.diaryView, g.diaryPage = view, 0 }, col)
+	}
+
+	rows := g.diaryRows()
+	pages := max(1, (len(rows)+diaryRows-1)/diaryRows)
+	g.diaryPage = min(g.diaryPage, pages-1)
+	start := g.diaryPage * diaryRows
+	y := 80
+	if len(rows) == 0 {
+		msg := "No notes yet: write one from RECENT PASSES"
+		if g.diaryView == diaryPasses {
+			msg = fmt.Sprintf("No flights have passed overhead in the last %d days", diaryPassDays)
+		}
+		rl.DrawText(msg, 50, int32(y), 18, getRlColor(colTextMuted))
+	}
+	for _, r := range rows[start:min(start+diaryRows, len(rows))] {
+		if r.heading != "" {
+			rl.DrawText(r.heading, 30, int32(y), 18, getRlColor(colGold))
+			y += 32
+			continue
+		}
+		when := r.entry.At.Local().Format("15:04")
+		if g.diaryView != diaryByDate {
+			when = r.entry.At.Local().Format("02.01. 15:04")
+		}
+		rl.DrawText(when, 50, int32(y), 18, getRlColor(colTextMuted))
+		rl.DrawText(r.entry.Callsign, 200, int32(y), 18, rl.White)
+		note := r.entry.Note
+		if r.pass != nil && note == "" {
+			note = fmt.Sprintf("%.1f km, %d ft", r.pass.DistanceKm, r.pass.AltitudeFt)
+		}
+		if r.entry.Photo != "" {
+			note = "[photo] " + note
+		}
+		rl.DrawText(truncate(note, 90), 320, int32(y), 18, rl.White)
+		e := r.entry
+		g.addButton(screenWidth-110, y-4, 90, 26, "NOTE", func() { g.editDiary(e) }, getRlColor(colGlassLight))
+		y += 32
+	}
+
+	g.addButton(20, screenHeight-50, 100, 30, "BACK", func() { g.state = StateSettings }, getRlColor(colDanger))
+	if pages > 1 {
+		rl.DrawText(fmt.Sprintf("%d/%d", g.diaryPage+1, pages), screenWidth-300, screenHeight-45, 20, getRlColor(colTextMuted))
+		g.addButton(screenWidth-230, screenHeight-50, 100, 30, "PREV", func() { g.diaryPage = max(0, g.diaryPage-1) }, getRlColor(colGlassLight))
+		g.addButton(screenWidth-120, screenHeight-50, 100, 30, "NEXT", func() { g.diaryPage++ }, getRlColor(colGlassLight))
+	}
+
+	for _, b := range g.buttons {
+		rl.DrawRectangle(int32(b.X), int32(b.Y), int32(b.W), int32(b.H), b.Color)
+		tw := rl.MeasureText(b.Text, 20)
+		tx := b.X + (b.W-int(tw))/2
+		ty := b.Y + (b.H-20)/2 + 2
+		rl.DrawText(b.Text, int32(tx), int32(ty), 20, b.TextColor)
+	}
+}
+
+// drawDiaryEditor writes the note and picks the photo for one pass
+func (g *Game) drawDiaryEditor() {
+	e := g.diaryEdit
+	rl.DrawText("NOTE: "+e.Callsign+" "+e.At.Local().Format("Mon 2 Jan 15:04"), 20, 30, 20, getRlColor(colAccent))
+
+	if len(g.inputText) > core.SpottingNoteMax {
+		g.inputText = g.inputText[:core.SpottingNoteMax]
+	}
+	rl.DrawRectangle(20, 70, screenWidth-40, 36, rl.White)
+	shown := g.inputText
+	for len(shown) > 0 && rl.MeasureText(shown, 20) > screenWidth-60 {
+		shown = shown[1:] // keep the end, where the typing is, in view
+	}
+	rl.DrawText(shown, 28, 78, 20, rl.Black)
+	g.addButton(20, 70, screenWidth-40, 36, "", func() { g.isKeyboardOpen = !g.isKeyboardOpen }, rl.Fade(rl.White, 0.0))
+	rl.DrawText(fmt.Sprintf("%d/%d", len(g.inputText), core.SpottingNoteMax), screenWidth-90, 112, 16, getRlColor(colTextMuted))
+
+	if g.isKeyboardOpen {
+		g.drawKeyboard(g.saveDiary)
+	} else {
+		if e.Photo != "" {
+			if g.diaryTexKey != e.Photo {
+				if g.diaryTexKey != "" {
+					rl.UnloadTexture(g.diaryTex)
+					g.diaryTexKey = ""
+				}
+				if img, err := g.dataManager.LoadSpottingPhoto(*e); err == nil {
+					rimg := rl.NewImageFromImage(img)
+					g.diaryTexKey, g.diaryTex = e.Photo, rl.LoadTextureFromImage(rimg)
+					rl.UnloadImage(rimg)
+					rl.SetTextureFilter(g.diaryTex, rl.FilterBilinear)
+				}
+			}
+			if g.diaryTexKey == e.Photo {
+				scale := min(480/float32(g.diaryTex.Width), 400/float32(g.diaryTex.Height))
+				rl.DrawTextureEx(g.diaryTex, rl.Vector2{X: screenWidth - 520, Y: 140}, 0, scale, rl.White)
+			}
+			if e.PhotoCredit != "" {
+				rl.DrawText(truncate(e.PhotoCredit, 50), screenWidth-520, 550, 14, getRlColor(colTextMuted))
+			}
+		}
+
+		rl.DrawText("Photo", 50, 155, 20, rl.White)
+		g.addButton(200, 150, 300, 30, "FETCHED THUMBNAIL", func() { g.attachDiaryPhoto(false) }, getRlColor(colGlassLight))
+		if len(g.diaryFiles) > 0 {
+			name := filepath.Base(g.diaryFiles[g.diaryFile])
+			g.addButton(200, 190, 300, 30, "FILE: "+truncate(name, 20), func() { g.diaryFile = (g.diaryFile + 1) % len(g.diaryFiles) }, getRlColor(colGlassLight))
+			g.addButton(510, 190, 120, 30, "ATTACH", func() { g.attachDiaryPhoto(true) }, getRlColor(colGlassLight))
+		} else {
+			rl.DrawText(truncate("No photos to pick in "+core.SpottingPhotoDir(), 50), 200, 195, 18, getRlColor(colTextMuted))
+		}
+		if e.Photo != "" {
+			g.addButton(200, 230, 300, 30, "REMOVE PHOTO", func() { e.Photo, e.PhotoCredit = "", "" }, getRlColor(colDanger))
+		}
+		rl.DrawText(truncate(g.diaryMsg, 60), 50, 290, 18, getRlColor(colTextMuted))
+
+		g.addButton(20, screenHeight-50, 100, 30, "CANCEL", func() { g.diaryEdit, g.isKeyboardOpen = nil, false }, getRlColor(colDanger))
+		g.addButton(130, screenHeight-50, 100, 30, "SAVE", g.saveDiary, getRlColor(colSuccess))
+	}
+
+	for _, b := range g.buttons {
+		rl.DrawRectangle(int32(b.X), int32(b.Y), int32(b.W), int32(b.H), b.Color)
+		tw := rl.MeasureText(b.Text, 20)
+		tx := b.X + (b.W-int(tw))/2
+		ty := b.Y + (b.H-20)/2 + 2
+		rl.DrawText(b.Text, int32(tx), int32(ty), 20, b.TextColor)
+	}
+}
+
+// openStorage measures the data directory for the storage screen
+func (g *Game) openStorage() {
+	var err error
+	g.storageUsage, err = g.dataManager.DataDirBreakdown()
+	if err != nil {
+		log.Println("Error measuring data dir:", err)
+	}
+	g.storageMsg = ""
+	g.state = StateStorage
+}
+
+// pruneNow applies the retention settings without waiting for a restart
+func (g *Game) pruneNow() {
+	r, err := g.dataManager.Prune(g.settings.Get().Retention, time.Now())
+	g.openStorage()
+	g.storageMsg = r.String()
+	if err != nil {
+		g.storageMsg = "Prune failed: " + err.Error()
+	}
+}
+
+// retentionLabel shows a retention choice, marking the default
+func retentionLabel(set, days int) string {
+	if set <= 0 {
+		return fmt.Sprintf("%d days (default)", days)
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+// drawStorage sets how long the growing logs are kept and shows what the
+// data directory holds, so a kiosk doesn't quietly fill its SD card
+func (g *Game) drawStorage() {
+	g.buttons = g.buttons[:0]
+	r := g.settings.Get().Retention
+
+	rl.DrawText("STORAGE", 20, 30, 20, getRlColor(colAccent))
+	rl.DrawText("older data is pruned at startup", 130, 33, 16, getRlColor(colTextMuted))
+
+	row := func(y int, label, value string, field func(*core.Retention) *int) {
+		step := func(dir int) {
+			g.updateSettings(func(s *core.Settings) {
+				f := field(&s.Retention)
+				*f = core.StepSetting(core.RetentionSteps, *f, dir)
+			})
+			g.history.SetRetentionDays(g.settings.Get().Retention.Tracks())
+		}
+		rl.DrawText(label, 50, int32(y+5), 20, rl.White)
+		g.addButton(250, y, 40, 30, "-", func() { step(-1) }, getRlColor(colGlassLight))
+		rl.DrawText(value, 305, int32(y+5), 20, rl.White)
+		g.addButton(500, y, 40, 30, "+", func() { step(1) }, getRlColor(colGlassLight))
+	}
+	row(80, "Overhead log", retentionLabel(r.OverheadDays, r.Overhead()), func(r *core.Retention) *int { return &r.OverheadDays })
+	row(130, "Track history", retentionLabel(r.TrackDays, r.Tracks()), func(r *core.Retention) *int { return &r.TrackDays })
+	row(180, "Game history", retentionLabel(r.GameDays, r.Games()), func(r *core.Retention) *int { return &r.GameDays })
+	row(230, "Captures", retentionLabel(r.CaptureDays, r.Captures()), func(r *core.Retention) *int { return &r.CaptureDays })
+	rl.DrawText("Scores and player stats are kept for good.", 50, 290, 18, getRlColor(colTextMuted))
+	rl.DrawText(truncate(g.storageMsg, 60), 50, 320, 18, getRlColor(colTextMuted))
+
+	var total core.DirUsage
+	for _, u := range g.storageUsage {
+		total.Bytes += u.Bytes
+		total.Files += u.Files
+	}
+	rl.DrawText("DISK USAGE", 660, 85, 20, rl.White)
+	rl.DrawText(total.String(), 800, 85, 20, getRlColor(colGold))
+	y := int32(125)
+	for i, u := range g.storageUsage {
+		if i >= 16 {
+			rl.DrawText(fmt.Sprintf("... and %d more", len(g.storageUsage)-i), 660, y, 18, getRlColor(colTextMuted))
+			break
+		}
+		rl.DrawText(truncate(u.Name, 36), 660, y, 18, rl.White)
+		size := core.FormatBytes(u.Bytes)
+		rl.DrawText(size, screenWidth-40-rl.MeasureText(size, 18), y, 18, getRlColor(colTextMuted))
+		y += 28
+	}
+
+	g.addButton(20, screenHeight-50, 100, 30, "BACK", func() { g.state = StateSettings }, getRlColor(colDanger))
+	g.addButton(130, screenHeight-50, 140, 30, "PRUNE NOW", g.pruneNow, getRlColor(colGlassLight))
+
+	for _, b := range g.buttons {
+		rl.DrawRectangle(int32(b.X), int32(b.Y), int32(b.W), int32(b.H), b.Color)
+		tw := rl.MeasureText(b.Text, 20)
+		tx := b.X + (b.W-int(tw))/2
+		ty := b.Y + (b.H-20)/2 + 2
+		rl.DrawText(b.Text, int32(tx), int32(ty), 20, b.TextColor)
+	}
+}
+
+// drawReplayEntry asks for a share code to replay another player's game
+func (g *Game) drawReplayEntry() {
+	g.buttons = g.buttons[:0]
+
+	rl.DrawText("Enter a share code to play the same flights:", int32(screenWidth)/2-220, 120, 20, rl.White)
+	rl.DrawRectangle(int32(screenWidth)/2-100, 150, 200, 30, rl.White)
+	rl.DrawText(g.inputText, int32(screenWidth)/2-95, 155, 20, rl.Black)
+	if g.replayError != "" {
+		rl.DrawText(g.replayError, int32(screenWidth)/2-95, 190, 16, getRlColor(colDanger))
+	}
+	g.addButton(screenWidth/2-100, 150, 200, 30, "", func() { g.isKeyboardOpen = !g.isKeyboardOpen }, rl.Fade(rl.White, 0.0))
+
+	if g.isKeyboardOpen {
+		g.drawKeyboard(func() { g.submitReplayCode(g.inputText) })
+	}
+
+	g.addButton(20, screenHeight-50, 100, 30, "BACK", func() {
+		g.isKeyboardOpen = false
+		g.state = StateMap
+	}, getRlColor(colDanger))
+
+	for _, b := range g.buttons {
+		rl.DrawRectangle(int32(b.X), int32(b.Y), int32(b.W), int32(b.H), b.Color)
+		fontSize := int32(14)
+		tw := rl.MeasureText(b.Text, fontSize)
+		rl.DrawText(b.Text, int32(b.X+(b.W-int(tw))/2), int32(b.Y+(b.H-int(fontSize))/2+2), fontSize, b.TextColor)
+	}
+}
+
+// drawKeyboard renders the on-screen keyboard that types into g.inputText
+func (g *Game) drawKeyboard(onEnter func()) {
+	kbW, kbH := 520, 250
+	kbX, kbY := (screenWidth-kbW)/2, 225
+	rl.DrawRectangle(int32(kbX-10), int32(kbY-10), int32(kbW+20), int32(kbH+20), getRlColor(colBgDark))
+
+	for r, row := range g.keyboardLayout {
+		rowW := len(row) * 50
+		rowStart := kbX + (kbW-rowW)/2
+		for c, char := range row {
+			charStr := string(char)
+			bx := rowStart + c*50
+			by := kbY + r*50
+			g.addButton(bx, by, 45, 45, charStr, func() { g.inputText += charStr }, getRlColor(colGlassLight))
+		}
+	}
+
+	ctrlY := kbY + 3*50 + 10
+	g.addButton(kbX, ctrlY, 100, 45, "HIDE", func() { g.isKeyboardOpen = false }, getRlColor(colGlass))
+	g.addButton(kbX+120, ctrlY, 100, 45, "DEL", func() {
+		if len(g.inputText) > 0 {
+			g.inputText = g.inputText[:len(g.inputText)-1]
+		}
+	}, getRlColor(colDanger))
+	// SPACE, for diary notes; names and share codes have none
+	if g.state == StateDiary {
+		g.addButton(kbX+240, ctrlY, 140, 45, "SPACE", func() { g.inputText += " " }, getRlColor(colGlassLight))
+	}
+	g.addButton(kbX+kbW-120, ctrlY, 120, 45, "ENTER", onEnter, getRlColor(colSuccess))
+}
+
+func (g *Game) drawLeaderboard() {
+	g.buttons = g.buttons[:0]
+	rl.DrawText("LEADERBOARD", 20, 30, 20, getRlColor(colAccent))
+
+	rl.DrawText("TOP SCORES", 50, 70, 20, rl.White)
+	y := 100
+	for i, s := range g.highScores {
+		line := fmt.Sprintf("%d. %s - %d  %s", i+1, s.Name, s.Score, s.Day())
+		rl.DrawText(line, 50, int32(y), 20, rl.White)
+		y += 25
+	}
+	if t := g.todayStats; t.Games > 0 {
+		line := fmt.Sprintf("Today: %d games, best %s - %d", t.Games, t.Best.Name, t.Best.Score)
+		rl.DrawText(line, 50, int32(y+10), 20, getRlColor(colGold))
+	}
+
+	rl.DrawText("PLAYER STATS", 400, 70, 20, rl.White)
+	y = 100
+	for i, u := range g.userStatsList {
+		if i >= 10 {
+			break
+		}
+		line := fmt.Sprintf("%s: Best %d | Played %d", u.Name, u.BestScore, u.GamesPlayed)
+		rl.DrawText(line, 400, int32(y), 20, rl.White)
+		y += 25
+	}
+
+	g.addButton(20, screenHeight-50, 100, 30, "BACK", func() { g.state = StateMap }, getRlColor(colDanger))
+
+	for _, b := range g.buttons {
+		rl.DrawRectangle(int32(b.X), int32(b.Y), int32(b.W), int32(b.H), b.Color)
+		tw := rl.MeasureText(b.Text, 20)
+		tx := b.X + (b.W-int(tw))/2
+		ty := b.Y + (b.H-20)/2 + 2
+		rl.DrawText(b.Text, int32(tx), int32(ty), 20, b.TextColor)
+	}
+}
+
+func (g *Game) addButton(x, y, w, h int, label string, action func(), col rl.Color, txtCol ...rl.Color) {
+	tc := rl.White
+	if len(txtCol) > 0 {
 		tc = txtCol[0]
 	}
-	g.buttons = append(g.buttons, Button{X: x, Y: y, W: w, H: h, Text: label, Action: action, Color: col, TextColor: tc})
+	g.buttons = append(g.buttons, Button{ID: core.WidgetID(label, x, y), X: x, Y: y, W: w, H: h, Text: label, Action: action, Color: col, TextColor: tc})
 }
 
 // Helper methods from original (startGame, endGame, etc) need to be ported too
 func (g *Game) startGame() {
-	if len(g.flights) == 0 {
+	if g.flights.Len() == 0 {
+		return
+	}
+	g.score = 0
+	g.round = 0
+	g.replay = nil
+	g.gameSeed = core.NewGameSeed()
+	g.roundLog = nil
+	g.shareCode = ""
+	g.bonusTarget, g.bonusDetails, g.bonusResult = "", nil, ""
+	g.partyGame = g.party.Players() > 0
+	g.party.NewGame()
+	g.nextRound()
+}
+
+// openReplayEntry shows the share code prompt
+func (g *Game) openReplayEntry() {
+	g.inputText = ""
+	g.replayError = ""
+	g.isKeyboardOpen = true
+	g.state = StateReplayEntry
+}
+
+// submitReplayCode looks up the game behind a share code and starts replaying it
+func (g *Game) submitReplayCode(code string) {
+	info, err := core.ParseShareCode(code)
+	if err != nil {
+		g.replayError = "Invalid code"
+		return
+	}
+	rec, err := g.dataManager.FindGame(info.Date, info.Seed)
+	if err != nil {
+		log.Println("Replay lookup failed:", err)
+		g.replayError = "Game not found on this device"
 		return
 	}
+
+	g.isKeyboardOpen = false
+	g.replay = &rec
+	g.replayScore = info.Score
 	g.score = 0
 	g.round = 0
 	g.nextRound()
 }
 
+// setupReplayRound poses the logged question for the current round again
+func (g *Game) setupReplayRound() {
+	if g.round > len(g.replay.Rounds) {
+		g.state = StateGameOver
+		return
+	}
+	r := g.replay.Rounds[g.round-1]
+
+	g.showResult = false
+	g.wrongGuess = ""
+	// The aircraft has long since landed; stand in a copy where it was
+	g.targetPlane = &core.Flight{Callsign: r.Callsign, Lat: r.Lat, Lon: r.Lon}
+	g.deselectPlane()
+	g.resolvedDetails = nil
+	g.resolving = false
+	g.camLat, g.camLon = r.Lat, r.Lon
+
+	g.typeRound = r.Hint != ""
+	g.typeHint = r.Hint
+	g.questionText = r.Question
+	g.correctOption = r.Correct
+	g.options = append([]string(nil), r.Options...)
+	g.roundStartTime = g.clock.Now()
+	g.roundTime = g.difficulty.RoundTime()
+	g.state = StateGamePlaying
+	if g.partyGame {
+		g.party.Ask(g.round, g.questionText, g.options, g.roundTime)
+	}
+}
+
+// finishGame logs a completed game so it can be replayed from its share code
+func (g *Game) finishGame() {
+	g.celebration, g.podium = "", nil
+	if g.replay != nil || g.partyGame {
+		return
+	}
+	g.celebrate(g.users.Current(), g.score)
+	rec := core.GameRecord{
+		Date:   time.Now().Format("2006-01-02"),
+		Seed:   g.gameSeed,
+		Player: g.users.Current().Name,
+		Score:  g.score,
+		Rounds: g.roundLog,
+	}
+	if err := g.dataManager.SaveGame(rec); err != nil {
+		log.Println("Error saving game log:", err)
+		return
+	}
+	g.scoreSync.Submit(rec)
+	code, err := core.ShareCode(rec)
+	if err != nil {
+		log.Println("Error creating share code:", err)
+		return
+	}
+	g.shareCode = code
+}
+
+// celebrate places the finished game on the podium and throws confetti if
+// it set a new best. Runs before the game is saved, so the leaderboard and
+// u are still as they were before it.
+func (g *Game) celebrate(u core.UserStats, score int) {
+	top, _, err := g.dataManager.GetLeaderboard()
+	if err != nil {
+		log.Println("Error loading leaderboard:", err)
+		return
+	}
+	g.podium, g.podiumPlace = core.Podium(top, core.ScoreEntry{Name: u.Name, Score: score}, 3)
+
+	kind, ok := core.NewBest(score, u.BestScore, top)
+	if !ok {
+		return
+	}
+	g.celebration = kind
+	g.particles.Confetti(screenWidth/2, screenHeight/2-200, 160)
+	err = g.dataManager.RecordAchievement(core.Achievement{
+		Kind:   kind,
+		Player: u.Name,
+		Score:  score,
+		Date:   time.Now().Format("2006-01-02"),
+	})
+	if err != nil {
+		log.Println("Error recording achievement:", err)
+	}
+}
+
+// drawPodium shows the top three with the finished game highlighted, second
+// place on the left and third on the right
+func (g *Game) drawPodium(y int) {
+	if g.celebration != "" {
+		msg := "NEW PERSONAL BEST!"
+		if g.celebration == core.AchievementAllTimeBest {
+			msg = "NEW ALL-TIME BEST!"
+		}
+		rl.DrawText(msg, int32(screenWidth/2)-rl.MeasureText(msg, 20)/2, int32(y), 20, getRlColor(colGold))
+	}
+
+	const blockW, base = 90, 140
+	heights := []int{70, 50, 34}
+	xs := []int{screenWidth/2 - blockW/2, screenWidth/2 - blockW*3/2 - 4, screenWidth/2 + blockW/2 + 4}
+	pulse := 0.5 + 0.5*math.Sin(rl.GetTime()*6)
+	for i, e := range g.podium {
+		x, h := xs[i], heights[i]
+		col := getRlColor(0xffffff30)
+		if i == g.podiumPlace {
+			col = rl.Fade(getRlColor(colGold), float32(0.6+0.4*pulse))
+		}
+		rl.DrawRectangle(int32(x), int32(y+base-h), blockW, int32(h), col)
+		rl.DrawText(fmt.Sprint(i+1), int32(x+blockW/2-5), int32(y+base-h+8), 20, rl.White)
+		rl.DrawText(truncate(e.Name, 9), int32(x+2), int32(y+base-h-36), 16, rl.White)
+		rl.DrawText(fmt.Sprint(e.Score), int32(x+2), int32(y+base-h-18), 16, getRlColor(colTextMuted))
+	}
+}
+
 func (g *Game) endGame() {
-	if g.round > 0 {
-		u, err := g.dataManager.SaveUser(g.currentUser.Name, g.score)
-		if err == nil {
-			g.currentUser = u
-			g.usersMap[u.Name] = u
+	if g.round > 0 && !g.partyGame {
+		u, err := g.dataManager.FinishGame(g.users.Current().Name, g.score, g.difficulty, time.Now())
+		if err != nil {
+			log.Println("Error saving game:", err)
+		}
+		if u.Name != "" {
+			g.users.Put(u)
 		}
-		g.dataManager.AddScore(ScoreEntry{Name: g.currentUser.Name, Score: g.score, Date: time.Now().Format("2006-01-02")})
 	}
 	g.state = StateMap
-	g.selectedPlane = nil
+	g.cancelRoundSetup()
+	g.deselectPlane()
+	g.replay = nil
+	g.partyGame = false
 }
 
 func (g *Game) nextRound() {
 	g.round++
-	if g.round > 5 {
+	if g.round > core.RoundsPerGame {
+		if g.startArrivalBonus() {
+			return
+		}
+		g.finishGame()
 		g.state = StateGameOver
 		return
 	}
+	if g.replay != nil {
+		g.setupReplayRound()
+		return
+	}
 	g.pickNewTarget()
 }
 
+// startArrivalBonus asks how long one of the game's flights has left before
+// it lands, when the bonus round is on and such a flight is still in range
+func (g *Game) startArrivalBonus() bool {
+	if g.replay != nil || g.partyGame || !g.settings.Get().ArrivalBonus || g.bonusTarget == "" {
+		return false
+	}
+	f := g.flights.Get(g.bonusTarget)
+	if f == nil {
+		return false
+	}
+	if _, ok := core.TimeToArrival(*f, g.bonusDetails, time.Now()); !ok {
+		return false
+	}
+	g.bonusGuess = 30
+	g.cancelSelection()
+	g.selectedPlane = f
+	g.resolvedDetails, g.resolving = g.bonusDetails, false
+	g.camLat, g.camLon = f.Lat, f.Lon
+	g.state = StateArrivalBonus
+	return true
+}
+
+// stepArrivalGuess nudges the guess by minutes, keeping it sensible
+func (g *Game) stepArrivalGuess(minutes int) {
+	g.bonusGuess = min(max(g.bonusGuess+minutes, 1), 360)
+}
+
+// lockArrivalGuess scores the guess against the flight's latest position
+// and ends the game
+func (g *Game) lockArrivalGuess() {
+	g.bonusResult = "Bonus: flight lost from view"
+	if f := g.flights.Get(g.bonusTarget); f != nil {
+		if eta, ok := core.TimeToArrival(*f, g.bonusDetails, time.Now()); ok {
+			points := core.ArrivalBonusScore(g.bonusGuess, eta)
+			g.score += points
+			g.bonusResult = fmt.Sprintf("Bonus: %.0f min to go, +%d", eta.Minutes(), points)
+		}
+	}
+	g.finishGame()
+	g.state = StateGameOver
+}
+
+// startOddOneOut deals a puzzle from the flights being tracked
+func (g *Game) startOddOneOut() {
+	g.odd, g.oddErr = core.NewOddOneOut(g.flights.Snapshot())
+	g.oddPick = -1
+	g.deselectPlane()
+	g.state = StateOddOneOut
+}
+
+// answerOddOneOut takes the player's pick; later taps are ignored until
+// the next puzzle
+func (g *Game) answerOddOneOut(i int) {
+	if g.oddPick >= 0 {
+		return
+	}
+	g.oddPick = i
+	g.oddTried++
+	if g.odd.Answer(i) {
+		g.oddRight++
+	}
+}
+
+func (g *Game) closeOddOneOut() {
+	g.oddRight, g.oddTried = 0, 0
+	g.deselectPlane()
+	g.state = StateMap
+}
+
+// inOddOneOut reports whether the flight is one of the puzzle's four
+func (g *Game) inOddOneOut(icao24 string) bool {
+	if g.state != StateOddOneOut || g.oddErr != nil {
+		return false
+	}
+	for _, f := range g.odd.Flights {
+		if f.Icao24 == icao24 {
+			return true
+		}
+	}
+	return false
+}
+
+// drawOddOneOut is the puzzle panel: the question, the four callsigns to
+// pick from and, once answered, why the odd one is odd
+func (g *Game) drawOddOneOut() {
+	title := "ODD ONE OUT"
+	if g.oddTried > 0 {
+		title += fmt.Sprintf("  %d/%d", g.oddRight, g.oddTried)
+	}
+	g.drawPanel(20, 90, 300, 375, title)
+	if g.oddErr != nil {
+		for i, line := range wrapWords("No puzzle right now: "+g.oddErr.Error(), 26) {
+			rl.DrawText(line, 30, int32(140+i*23), 20, getRlColor(colTextMuted))
+		}
+		g.addButton(25, 425, 130, 30, "TRY AGAIN", g.startOddOneOut, getRlColor(colAccent))
+		g.addButton(165, 425, 130, 30, "CLOSE", g.closeOddOneOut, getRlColor(colGlass))
+		return
+	}
+
+	for i, line := range wrapWords(g.odd.Prompt(), 26) {
+		rl.DrawText(line, 30, int32(140+i*23), 20, rl.White)
+	}
+	y := 190
+	for i, f := range g.odd.Flights {
+		col, textColor := rl.White, rl.Black
+		if g.oddPick >= 0 {
+			if g.odd.Answer(i) {
+				col = getRlColor(colSuccess)
+			} else if i == g.oddPick {
+				col, textColor = getRlColor(colDanger), rl.White
+			} else {
+				col = rl.Fade(rl.White, 0.5)
+			}
+		}
+		g.addButton(30, y, 280, 35, f.Callsign, func() { g.answerOddOneOut(i) }, col, textColor)
+		y += 45
+	}
+	if g.oddPick < 0 {
+		rl.DrawText("Tap a plane for its details", 30, 375, 16, getRlColor(colTextMuted))
+		g.addButton(165, 425, 130, 30, "CLOSE", g.closeOddOneOut, getRlColor(colGlass))
+		return
+	}
+	for i, line := range wrapWords(g.odd.Explain(), 32) {
+		if i < 2 {
+			rl.DrawText(line, 30, int32(375+i*20), 16, getRlColor(colAccent))
+		}
+	}
+	g.addButton(25, 425, 130, 30, "NEXT", g.startOddOneOut, getRlColor(colAccent))
+	g.addButton(165, 425, 130, 30, "CLOSE", g.closeOddOneOut, getRlColor(colGlass))
+}
+
 func (g *Game) pickNewTarget() {
+	if g.ctx.Err() != nil {
+		return // quitting
+	}
+	ctx, seq := g.newRoundSetup()
 	g.state = StateRoundSetup
 	g.showResult = false
 	g.wrongGuess = ""
 
-	if len(g.flights) == 0 {
-		time.AfterFunc(1*time.Second, g.pickNewTarget)
+	candidates := core.QuizCandidates(g.flights.Snapshot(), g.resolver)
+	if len(candidates) == 0 {
+		// Wait for some to fly in
+		g.retryRound(ctx, seq)
 		return
 	}
 
-	idx := rand.Intn(len(g.flights))
-	g.targetPlane = &g.flights[idx]
-	g.camLat = g.targetPlane.Lat
-	g.camLon = g.targetPlane.Lon
+	pick := candidates[rand.Intn(len(candidates))]
+	g.targetPlane = g.flights.Get(pick.Icao24)
+	if g.targetPlane == nil {
+		// Expired since the snapshot was taken
+		g.retryRound(ctx, seq)
+		return
+	}
+	g.camLat = pick.Lat
+	g.camLon = pick.Lon
+	g.cancelSelection()
 	g.selectedPlane = g.targetPlane
 	g.resolvedDetails = nil
 	g.resolving = true
 
 	go func() {
-		details, err := g.scraper.FetchFlightDetails(g.targetPlane.Callsign)
-		if err == nil && details != nil {
-			g.setupRoundWithData(details)
-		} else {
-			g.pickNewTarget()
+		details, err := core.ResolveFlight(ctx, g.resolver, pick)
+		if ctx.Err() != nil {
+			return // the game moved on meanwhile
+		}
+		if err != nil {
+			details = nil
 		}
+		g.deliverRound(ctx, roundResult{seq: seq, details: details})
 	}()
 }
 
-func (g *Game) setupRoundWithData(details *ResolvedDetails) {
-	g.resolvedDetails = details
-	g.resolving = false
-	if details.RealDestination == "" || details.RealDestination == "Unknown" {
-		g.pickNewTarget()
-		return
-	}
+// roundResult is the lookup for a round's target, handed back to the UI
+// goroutine; nil details mean another target is to be picked. seq tells it
+// apart from lookups for earlier round setups.
+type roundResult struct {
+	seq     int
+	details *core.ResolvedDetails
+}
 
-	g.dataManager.SaveAirport(details.RealDestination)
-	g.dataManager.SaveAirport(details.Origin)
+// newRoundSetup abandons any earlier round setup and starts a context for
+// the next one's lookup
+func (g *Game) newRoundSetup() (context.Context, int) {
+	g.cancelRoundSetup()
+	ctx, cancel := context.WithCancel(g.ctx)
+	g.roundCancel = cancel
+	return ctx, g.roundSeq
+}
 
-	if strings.Contains(details.RealDestination, "Helsinki") {
-		g.questionText = fmt.Sprintf("Where is %s from?", g.targetPlane.Callsign)
-		g.correctOption = details.Origin
-	} else {
-		g.questionText = fmt.Sprintf("Where is %s going?", g.targetPlane.Callsign)
-		g.correctOption = details.RealDestination
+// cancelRoundSetup stops the lookup for the next round's target
+func (g *Game) cancelRoundSetup() {
+	if g.roundCancel != nil {
+		g.roundCancel()
+		g.roundCancel = nil
 	}
+	g.roundSeq++
+}
 
-	g.generateOptions()
-	g.roundStartTime = time.Now()
-	g.state = StateGamePlaying
+// retryRound has another target picked in a second
+func (g *Game) retryRound(ctx context.Context, seq int) {
+	go func() {
+		select {
+		case <-time.After(time.Second):
+			g.deliverRound(ctx, roundResult{seq: seq})
+		case <-ctx.Done():
+		}
+	}()
 }
 
-func (g *Game) generateOptions() {
-	g.refreshAirports()
-	dist := make([]string, len(g.airports))
-	copy(dist, g.airports)
-	rand.Shuffle(len(dist), func(i, j int) { dist[i], dist[j] = dist[j], dist[i] })
+// deliverRound passes r to the UI goroutine, or drops it once the round
+// setup has been abandoned
+func (g *Game) deliverRound(ctx context.Context, r roundResult) {
+	select {
+	case g.roundResults <- r:
+	case <-ctx.Done():
+	}
+}
 
-	opts := []string{g.correctOption}
-	for _, c := range dist {
-		if len(opts) >= 4 {
-			break
+// applyRoundSetup starts the round once its target's lookup is in
+func (g *Game) applyRoundSetup() {
+	for len(g.roundResults) > 0 {
+		r := <-g.roundResults
+		if r.seq != g.roundSeq || g.state != StateRoundSetup {
+			continue
 		}
-		if c != g.correctOption && c != "Unknown" {
-			opts = append(opts, c)
+		if r.details == nil {
+			g.pickNewTarget()
+		} else {
+			g.setupRoundWithData(r.details)
 		}
 	}
-	if len(opts) < 4 {
-		fallback := []string{"London", "Paris", "Berlin", "Helsinki"}
-		for _, c := range fallback {
-			if len(opts) >= 4 {
-				break
-			}
-			exists := false
-			for _, o := range opts {
-				if o == c {
-					exists = true
-					break
-				}
-			}
-			if !exists {
-				opts = append(opts, c)
-			}
-		}
+}
+
+func (g *Game) setupRoundWithData(details *core.ResolvedDetails) {
+	g.resolvedDetails = details
+	g.resolving = false
+	g.refreshAirports()
+
+	// Now and then quiz the type or the airline instead of the route
+	q, err := core.SetupRound(g.dataManager, g.airportDB, *g.targetPlane, details, core.RoundConfig{
+		Airports: g.airports,
+		Level:    g.difficulty.Level,
+		Kind:     core.PickRoundKind(true),
+		Strategy: core.DistractorStrategyFor(g.users.Current().Name),
+	})
+	if err != nil {
+		log.Println("Unusable details, trying new target:", err)
+		g.pickNewTarget()
+		return
+	}
+	if _, ok := core.TimeToArrival(*g.targetPlane, details, time.Now()); ok {
+		g.bonusTarget, g.bonusDetails = g.targetPlane.Icao24, details
+		g.bonusName = core.QuizName(*g.targetPlane, details)
+	}
+
+	g.typeRound = q.IsTypeRound()
+	g.typeHint = q.Hint
+	g.strategy = q.Strategy
+	g.questionText = q.Text
+	g.correctOption = q.Correct
+	g.options = q.Options
+	g.roundStartTime = g.clock.Now()
+	g.roundTime = g.difficulty.RoundTime()
+	g.state = StateGamePlaying
+	if g.partyGame {
+		g.party.Ask(g.round, g.questionText, g.options, g.roundTime)
 	}
-	rand.Shuffle(len(opts), func(i, j int) { opts[i], opts[j] = opts[j], opts[i] })
-	g.options = opts
 }
 
 func (g *Game) guess(city string) {
 	if g.showResult {
 		return
 	}
-	g.resultCorrect = (city == g.correctOption)
-	if g.resultCorrect {
-		elapsed := time.Since(g.roundStartTime).Seconds()
-		bonus := int(math.Max(0, (20.0-elapsed)/20.0*100.0))
-		g.score += 100 + bonus
+	if g.partyGame {
+		// The phones did the answering; the kiosk just reveals
+		g.party.Close(g.correctOption)
 	} else {
-		g.wrongGuess = city
+		g.resultCorrect = (city == g.correctOption)
+		g.score += core.RoundScore(g.resultCorrect, g.clock.Since(g.roundStartTime), g.roundTime)
+		if !g.resultCorrect {
+			g.wrongGuess = city
+		}
+		g.difficulty.Record(g.resultCorrect)
+	}
+	if g.replay == nil && g.targetPlane != nil {
+		r := core.RoundRecord{
+			Callsign: g.targetPlane.Callsign,
+			Lat:      g.targetPlane.Lat,
+			Lon:      g.targetPlane.Lon,
+			Question: g.questionText,
+			Correct:  g.correctOption,
+			Options:  append([]string(nil), g.options...),
+		}
+		if g.typeRound {
+			r.Hint = g.typeHint
+		}
+		if !g.partyGame {
+			r.Strategy, r.Right = g.strategy, g.resultCorrect
+		}
+		g.roundLog = append(g.roundLog, r)
 	}
 	g.showResult = true
-	g.resultStartTime = time.Now()
+	g.resultStartTime = g.clock.Now()
+}
+
+// wrapWords breaks s into lines of at most width characters, at spaces
+func wrapWords(s string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
 }
 
 func truncate(s string, max int) string {
@@ -1138,6 +3767,47 @@ func truncate(s string, max int) string {
 }
 
 func main() {
+	providerName := flag.String("provider", "auto", "flight data source ("+strings.Join(core.ProviderNames(), ", ")+")")
+	lowMem := flag.Bool("lowmem", false, "constrained profile for Pi Zero class devices: small tile cache, no trails, history or effects, slower polling")
+	recordDir := flag.String("record", "", "save every raw OpenSky response into this directory")
+	replayDir := flag.String("replay", "", "play back a directory saved with -record instead of fetching live")
+	replaySpeed := flag.Float64("replay-speed", 1, "how many times faster than real time -replay plays")
+	importDir := flag.String("import-openflights", "", "import airports.dat and routes.dat from this directory into the airport database, then exit")
+	restore := flag.Bool("restore-backup", false, "download the backup from BACKUP_URL into a data directory without player data, then exit")
+	orientationName := flag.String("orientation", core.DefaultOrientation(), "auto, landscape, portrait, landscape-flipped or portrait-flipped; auto turns the UI for a display taller than it is wide")
+	flag.Parse()
+
+	orientation, err := core.ParseOrientation(*orientationName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("Flight Monitor", core.Build())
+
+	if *importDir != "" {
+		r, err := (&core.DataManager{}).ImportOpenFlights(*importDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Println("OpenFlights:", r)
+		return
+	}
+	if *restore {
+		b, err := core.NewBackup()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if b == nil {
+			log.Fatal("Set BACKUP_URL to restore from")
+		}
+		n, err := b.Restore(context.Background())
+		if err != nil {
+			log.Fatal("Restore failed: ", err)
+		}
+		log.Printf("Restored %d files", n)
+		return
+	}
+
 	if l := os.Getenv("MY_LAT"); l != "" {
 		if v, err := strconv.ParseFloat(l, 64); err == nil {
 			myLat = v
@@ -1155,10 +3825,41 @@ func main() {
 	// rl.InitWindow(screenWidth, screenHeight, "Flight Monitor Raylib")
 	rl.InitWindow(0, 0, "Flight Monitor Raylib")
 
-	rl.SetTargetFPS(60)
+	rl.SetTargetFPS(normalFPS)
+
+	var provider core.FlightProvider
+	if *replayDir != "" {
+		var replay *core.ReplayProvider
+		replay, err = core.NewReplayProvider(*replayDir, *replaySpeed)
+		if err == nil {
+			from, to := replay.Span()
+			log.Printf("Replaying %s to %s at %gx", from.Local().Format(time.DateTime), to.Local().Format(time.DateTime), *replaySpeed)
+			provider = replay
+		}
+	} else {
+		provider, err = core.NewProvider(*providerName)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Using flight provider:", provider.Name())
+	if *recordDir != "" {
+		if err := core.RecordResponses(provider, *recordDir); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("Recording raw responses to", *recordDir)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	client := NewFlightClient()
-	game := NewGame(client)
+	profile := core.StandardProfile
+	if *lowMem {
+		profile = core.LowMemoryProfile
+	}
+	log.Println("Resource profile:", profile.Name)
+	game := NewGame(ctx, provider, profile)
+	game.orientation = orientation
 	game.Init()
 	defer game.Unload()
 
@@ -1167,5 +3868,9 @@ func main() {
 		game.Draw()
 	}
 
+	// Stop polling, scrapes and tile fetches before tearing down the window
+	cancel()
+	game.shutdown()
+
 	rl.CloseWindow()
 }
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// particleRenderer draws core particles with raylib
+type particleRenderer struct{}
+
+func (particleRenderer) Rect(x, y, w, h, angle float64, col uint32, alpha float64) {
+	rl.DrawRectanglePro(rl.Rectangle{X: float32(x), Y: float32(y), Width: float32(w), Height: float32(h)},
+		rl.Vector2{X: float32(w / 2), Y: float32(h / 2)}, float32(angle*180/math.Pi), rl.Fade(getRlColor(col), float32(alpha)))
+}
+
+func (particleRenderer) Ring(x, y, radius float64, col uint32, alpha float64) {
+	rl.DrawRing(rl.Vector2{X: float32(x), Y: float32(y)}, float32(radius-1), float32(radius+1), 0, 360, 36, rl.Fade(getRlColor(col), float32(alpha)))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"image"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+
+	"flight-monitor/core"
+)
+
+// Uploaded images kept for uiRenderer.DrawTexture; past this many the cache
+// starts over
+const maxUITextures = 32
+
+// uiTextures are the images shared UI has drawn, uploaded. Only touched
+// from the draw loop; unloaded with the game.
+var uiTextures = make(map[image.Image]rl.Texture2D)
+
+// uiFontSizes are the raylib font sizes for each core.TextSize
+var uiFontSizes = [...]int32{core.TextSmall: 16, core.TextNormal: 20, core.TextLarge: 30}
+
+// uiRenderer draws shared core UI with raylib
+type uiRenderer struct{}
+
+func (uiRenderer) DrawRect(x, y, w, h int, col uint32) {
+	rl.DrawRectangle(int32(x), int32(y), int32(w), int32(h), getRlColor(col))
+}
+
+func (uiRenderer) DrawTexture(img image.Image, x, y, w, h int) {
+	tex, ok := uiTextures[img]
+	if !ok {
+		if len(uiTextures) >= maxUITextures {
+			unloadUITextures()
+		}
+		rimg := rl.NewImageFromImage(img)
+		tex = rl.LoadTextureFromImage(rimg)
+		rl.UnloadImage(rimg)
+		rl.SetTextureFilter(tex, rl.FilterBilinear)
+		uiTextures[img] = tex
+	}
+	src := rl.Rectangle{Width: float32(tex.Width), Height: float32(tex.Height)}
+	dst := rl.Rectangle{X: float32(x), Y: float32(y), Width: float32(w), Height: float32(h)}
+	rl.DrawTexturePro(tex, src, dst, rl.Vector2{}, 0, rl.White)
+}
+
+func (uiRenderer) DrawText(s string, x, y int, size core.TextSize, col uint32) {
+	rl.DrawText(s, int32(x), int32(y), uiFontSizes[size], getRlColor(col))
+}
+
+func (uiRenderer) Measure(s string, size core.TextSize) (int, int) {
+	return int(rl.MeasureText(s, uiFontSizes[size])), int(uiFontSizes[size])
+}
+
+func (uiRenderer) BeginClip(x, y, w, h int) func() {
+	rl.BeginScissorMode(int32(x), int32(y), int32(w), int32(h))
+	return rl.EndScissorMode
+}
+
+// unloadUITextures frees every texture uploaded for shared UI
+func unloadUITextures() {
+	for img, tex := range uiTextures {
+		rl.UnloadTexture(tex)
+		delete(uiTextures, img)
+	}
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"image"
 	"io"
 	"net/http"
+	"slices"
 	"sync"
 
+	"flight-monitor/core"
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
@@ -16,23 +21,38 @@ type TileKey struct {
 type TileResponse struct {
 	Key  TileKey
 	Data []byte
+	Ext  string // file type hint for the decoder, e.g. ".png"
 }
 
 type TileLoader struct {
+	ctx          context.Context // no new fetches once cancelled
 	cache        map[TileKey]rl.Texture2D
+	lastUsed     map[TileKey]uint64 // main thread only, like cache
+	uses         uint64
 	pending      map[TileKey]bool
 	responseChan chan TileResponse
 	mutex        sync.Mutex
 	httpClient   *http.Client
+	archive      *core.MBTiles // offline tiles tried before the network; guarded by mutex
+	queue        *core.TileQueue
+
+	maxTiles   int // cached textures beyond this evict the least recently used
+	resolution int // tiles are downscaled to this many pixels per side
 }
 
-func NewTileLoader() *TileLoader {
-	return &TileLoader{
+func NewTileLoader(ctx context.Context, maxTiles, resolution, fetches int) *TileLoader {
+	tl := &TileLoader{
+		ctx:          ctx,
 		cache:        make(map[TileKey]rl.Texture2D),
+		lastUsed:     make(map[TileKey]uint64),
 		pending:      make(map[TileKey]bool),
 		responseChan: make(chan TileResponse, 10), // Buffer slightly
 		httpClient:   &http.Client{},
+		maxTiles:     maxTiles,
+		resolution:   resolution,
 	}
+	tl.queue = core.NewTileQueue(ctx, fetches, tl.fetchTile)
+	return tl
 }
 
 // GetTile returns the texture if available. Returns empty texture (id=0) if not.
@@ -44,25 +64,79 @@ func (tl *TileLoader) GetTile(z, x, y int) rl.Texture2D {
 	// Note: Maps are not safe for concurrent R/W, but we mainly access on main thread here.
 	// However, fetching is async. The cache writing happens in Update() which is main thread.
 	// So reading here is safe if GetTile is called from main thread.
+	tl.uses++
 	if tex, ok := tl.cache[key]; ok {
+		tl.lastUsed[key] = tl.uses
 		return tex
 	}
 
+	if tl.ctx.Err() != nil {
+		return rl.Texture2D{}
+	}
+
 	// 2. Check Pending
+	tile := core.TileCoord{Z: z, X: x, Y: y}
 	tl.mutex.Lock()
 	if tl.pending[key] {
 		tl.mutex.Unlock()
+		tl.queue.Urge(tile)   // may have been queued as a prefetch
 		return rl.Texture2D{} // Return empty/invalid texture
 	}
 	tl.pending[key] = true
 	tl.mutex.Unlock()
 
-	// 3. Start Fetch
-	go tl.fetchTile(z, x, y)
+	// 3. Queue Fetch, unless it failed lately and is backing off
+	if !tl.queue.Add(tile, true) {
+		tl.mutex.Lock()
+		delete(tl.pending, key)
+		tl.mutex.Unlock()
+	}
 
 	return rl.Texture2D{}
 }
 
+// Prefetch queues tiles that aren't drawn yet but soon may be, behind those
+// on screen, and counts those already loaded as used, so they stay cached.
+// Fetches for tiles in neither tiles nor near, the tiles in and around the
+// view, are dropped: the view has moved away from them.
+func (tl *TileLoader) Prefetch(near, tiles []core.TileCoord) {
+	keep := make(map[core.TileCoord]bool, len(near)+len(tiles))
+	for _, t := range slices.Concat(near, tiles) {
+		keep[t] = true
+	}
+	dropped := tl.queue.Retain(func(t core.TileCoord) bool { return keep[t] })
+
+	tl.mutex.Lock()
+	defer tl.mutex.Unlock()
+	for _, t := range dropped {
+		delete(tl.pending, TileKey{t.Z, t.X, t.Y})
+	}
+	for _, t := range tiles {
+		key := TileKey{t.Z, t.X, t.Y}
+		if _, ok := tl.cache[key]; ok {
+			tl.lastUsed[key] = tl.uses
+		} else if !tl.pending[key] && tl.queue.Add(t, false) {
+			tl.pending[key] = true
+		}
+	}
+}
+
+// Fetching is the number of tiles waiting to download or downloading
+func (tl *TileLoader) Fetching() int {
+	return tl.queue.Len()
+}
+
+// Cached returns the texture if it is already loaded, without fetching it
+// when it isn't (id=0); for placeholders drawn while another tile loads
+func (tl *TileLoader) Cached(z, x, y int) rl.Texture2D {
+	key := TileKey{z, x, y}
+	tex, ok := tl.cache[key]
+	if ok {
+		tl.lastUsed[key] = tl.uses
+	}
+	return tex
+}
+
 // Update processes loaded images and uploads them to GPU. Must call on Main Thread.
 func (tl *TileLoader) Update() {
 	// Drain the channel up to a limit to avoid stuttering? Or just all.
@@ -72,22 +146,34 @@ Loop:
 		select {
 		case resp := <-tl.responseChan:
 			// Load Image from RAM
-			// ".png" is the file type hint
-			img := rl.LoadImageFromMemory(".png", resp.Data, int32(len(resp.Data)))
+			img := rl.LoadImageFromMemory(resp.Ext, resp.Data, int32(len(resp.Data)))
 			if img.Width == 0 {
 				fmt.Println("Failed to load image from memory for tile", resp.Key)
-				// Clean up pending so we might retry later? Or just leave it broken.
+				// Retried once the queue's backoff has passed
+				tl.queue.Fail(core.TileCoord{Z: resp.Key.Z, X: resp.Key.X, Y: resp.Key.Y})
+				tl.mutex.Lock()
+				delete(tl.pending, resp.Key)
+				tl.mutex.Unlock()
 				continue
 			}
 
+			if img.Width > int32(tl.resolution) {
+				rl.ImageResize(img, int32(tl.resolution), int32(tl.resolution))
+			}
+
 			// Upload to GPU
 			tex := rl.LoadTextureFromImage(img)
+			if tex.Width < core.TileSize {
+				rl.SetTextureFilter(tex, rl.FilterBilinear) // drawn scaled up
+			}
 
 			// Free CPU RAM
 			rl.UnloadImage(img)
 
 			// Store in cache
+			tl.evict()
 			tl.cache[resp.Key] = tex
+			tl.lastUsed[resp.Key] = tl.uses
 
 			// Cleanup pending (optional, but good for logic)
 			tl.mutex.Lock()
@@ -100,31 +186,100 @@ Loop:
 	}
 }
 
-func (tl *TileLoader) fetchTile(z, x, y int) {
-	key := TileKey{z, x, y}
-	url := fmt.Sprintf("https://basemaps.cartocdn.com/dark_all/%d/%d/%d.png", z, x, y)
+// Len is the number of textures cached
+func (tl *TileLoader) Len() int {
+	return len(tl.cache)
+}
 
-	resp, err := tl.httpClient.Get(url)
-	if err != nil {
-		fmt.Println("Failed to fetch tile:", err)
+// evict unloads least recently used textures until there is room for one
+// more. Update runs before anything is drawn, so none is in use.
+func (tl *TileLoader) evict() {
+	for len(tl.cache) >= tl.maxTiles {
+		var oldest TileKey
+		first := true
+		for k := range tl.cache {
+			if first || tl.lastUsed[k] < tl.lastUsed[oldest] {
+				oldest, first = k, false
+			}
+		}
+		rl.UnloadTexture(tl.cache[oldest])
+		delete(tl.cache, oldest)
+		delete(tl.lastUsed, oldest)
+	}
+}
+
+// fetchTile downloads a tile and hands it to Update. The queue backs off
+// from tiles it returns an error for.
+func (tl *TileLoader) fetchTile(ctx context.Context, t core.TileCoord) error {
+	z, x, y := t.Z, t.X, t.Y
+	key := TileKey{z, x, y}
+	fail := func(msg string, err error) {
+		if ctx.Err() == nil { // not scrolled away or quitting
+			fmt.Println(msg, err)
+		}
 		tl.mutex.Lock()
 		delete(tl.pending, key)
 		tl.mutex.Unlock()
-		return
+	}
+
+	tl.mutex.Lock()
+	archive := tl.archive
+	tl.mutex.Unlock()
+	if data, ok := archive.Tile(z, x, y); ok {
+		tl.deliver(TileResponse{Key: key, Data: data, Ext: archive.Ext()})
+		return nil
+	}
+
+	url := fmt.Sprintf("https://basemaps.cartocdn.com/dark_all/%d/%d/%d.png", z, x, y)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		fail("Failed to fetch tile:", err)
+		return err
+	}
+	req.Header.Set("User-Agent", core.UserAgent())
+	resp, err := tl.httpClient.Do(req)
+	if err != nil {
+		fail("Failed to fetch tile:", err)
+		return err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("tile %d/%d/%d: %s", z, x, y, resp.Status)
+		fail("Failed to fetch tile:", err)
+		return err
+	}
 
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Println("Failed to read tile body:", err)
-		tl.mutex.Lock()
-		delete(tl.pending, key)
-		tl.mutex.Unlock()
-		return
+		fail("Failed to read tile body:", err)
+		return err
 	}
+	// Catch what isn't an image here, where the queue can back off from it
+	if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		fail("Failed to read tile body:", err)
+		return err
+	}
+
+	tl.deliver(TileResponse{Key: key, Data: data, Ext: ".png"})
+	return nil
+}
 
-	// Send to main thread
-	tl.responseChan <- TileResponse{Key: key, Data: data}
+// deliver sends a fetched tile to the main thread; once quitting nothing
+// drains the channel
+func (tl *TileLoader) deliver(resp TileResponse) {
+	select {
+	case tl.responseChan <- resp:
+	case <-tl.ctx.Done():
+	}
+}
+
+// SetArchive makes the loader read tiles from an offline archive first,
+// going to the network only for those it doesn't have
+func (tl *TileLoader) SetArchive(m *core.MBTiles) {
+	tl.mutex.Lock()
+	defer tl.mutex.Unlock()
+	tl.archive = m
 }
 
 // Unload cleans up all textures
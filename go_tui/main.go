@@ -0,0 +1,402 @@
+// Command go_tui is the flight monitor for a terminal: a table of the
+// flights in range, a ticker for the nearest one and a log of alerts, for a
+// server with no display, watched over SSH.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"flight-monitor/core"
+)
+
+const (
+	defaultCols, defaultRows = 100, 30
+
+	// Alerts kept for the log; the newest that fit are shown
+	maxAlerts = 50
+	// How often the screen is redrawn; flights arrive at the poll interval
+	frameInterval = time.Second
+)
+
+var (
+	myLat = 60.25881233034921
+	myLon = 24.780103286993022
+)
+
+// alert is one line of the alert log
+type alert struct {
+	at  time.Time
+	msg string
+	col uint32
+}
+
+// tui is the terminal frontend's state. Only the draw loop touches it; the
+// poll loop hands flights over through the pipeline.
+type tui struct {
+	ctx         context.Context
+	provider    core.FlightProvider
+	settings    *core.SettingsStore
+	pipeline    *core.FlightPipeline
+	fetchHealth core.FetchHealth
+	aircraft    *core.AircraftDB
+	watchlist   *core.Watchlist
+	tags        *core.TagDB
+	routeCache  *core.RouteCache
+	prefetch    *core.Prefetcher
+
+	snapshot    *core.FlightSnapshot
+	emergencies []core.Flight
+	alerts      []alert // newest last
+	screen      *termScreen
+}
+
+func main() {
+	providerName := flag.String("provider", "auto", "flight data source ("+strings.Join(core.ProviderNames(), ", ")+")")
+	cols := flag.Int("cols", envInt("COLUMNS", defaultCols), "terminal width in characters")
+	rows := flag.Int("rows", envInt("LINES", defaultRows), "terminal height in lines")
+	logPath := flag.String("log", "", "append log messages to this file; they would garble the screen otherwise")
+	flag.Parse()
+
+	log.SetOutput(io.Discard)
+	if *logPath != "" {
+		f, err := os.OpenFile(*logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		log.SetOutput(f)
+	}
+	log.Println("Flight Monitor (terminal)", core.Build())
+
+	if l := os.Getenv("MY_LAT"); l != "" {
+		if v, err := strconv.ParseFloat(l, 64); err == nil {
+			myLat = v
+		}
+	}
+	if l := os.Getenv("MY_LON"); l != "" {
+		if v, err := strconv.ParseFloat(l, 64); err == nil {
+			myLon = v
+		}
+	}
+
+	provider, err := core.NewProvider(*providerName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	dm := &core.DataManager{}
+	s, err := dm.LoadSettings()
+	if err != nil {
+		log.Println("Error loading settings:", err)
+	}
+	t := &tui{
+		ctx:        ctx,
+		provider:   provider,
+		settings:   core.NewSettingsStore(s),
+		pipeline:   core.NewFlightPipeline(myLat, myLon),
+		aircraft:   core.NewAircraftDB(),
+		watchlist:  core.NewWatchlist(),
+		tags:       core.NewTagDB(),
+		routeCache: core.NewRouteCache(dm),
+		screen:     newTermScreen(max(*cols, 40), max(*rows, 10)),
+	}
+	if provider.Name() != "sim" {
+		t.prefetch = core.NewPrefetcher(t.routeCache, core.NewLearnedRoutes(dm))
+	}
+	if _, err := t.watchlist.Import(core.WatchlistPath()); err != nil && !os.IsNotExist(err) {
+		log.Println("Error loading watchlist:", err)
+	}
+	if _, err := t.tags.Load(core.TagDBPath()); err != nil && !os.IsNotExist(err) {
+		log.Println("Error loading tag database:", err)
+	}
+	if db, err := dm.LoadAirportDB(); err != nil {
+		log.Println("Error loading airport database:", err)
+	} else {
+		core.SetHomeAirport(core.FindHomeAirport(db, myLat, myLon, s.HomeAirport))
+	}
+
+	go t.pipeline.Run(ctx)
+	go core.RunProvider(ctx, provider)
+	go t.loadAircraftDB()
+	go t.poll()
+	if t.prefetch != nil {
+		go t.prefetch.Run(ctx)
+	}
+	if exporter := core.NewMapExporter(myLat, myLon); exporter != nil {
+		go exporter.Run(ctx, t.pipeline, t.settings)
+	}
+	if b, err := core.NewBackup(); err != nil {
+		log.Println("Backups off:", err)
+	} else {
+		go b.Run(ctx)
+	}
+	go waitForQuit(cancel)
+
+	fmt.Print("\x1b[?25l\x1b[2J") // hide the cursor, clear
+	defer fmt.Print("\x1b[0m\x1b[?25h\r\n")
+	ticker := time.NewTicker(frameInterval)
+	defer ticker.Stop()
+	for {
+		t.update()
+		t.draw(time.Now())
+		if err := t.screen.Flush(os.Stdout); err != nil {
+			log.Println("Error drawing:", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// envInt reads a positive number from the environment, or def
+func envInt(name string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+// waitForQuit calls quit when "q" is entered. The terminal stays in line
+// mode, so it takes Enter too; Ctrl-C works as well.
+func waitForQuit(quit func()) {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if strings.EqualFold(strings.TrimSpace(scanner.Text()), "q") {
+			quit()
+			return
+		}
+	}
+}
+
+func (t *tui) loadAircraftDB() {
+	path := core.AircraftDBPath()
+	if _, err := t.aircraft.Download(t.ctx, path); err != nil && t.ctx.Err() == nil {
+		log.Println("Error downloading aircraft database:", err)
+	}
+	if n, err := t.aircraft.Load(path); err == nil {
+		log.Printf("Loaded %d aircraft", n)
+	} else if !os.IsNotExist(err) {
+		log.Println("Error loading aircraft database:", err)
+	}
+}
+
+// poll fetches flights at the provider's interval for the life of ctx
+func (t *tui) poll() {
+	for {
+		settings := t.settings.Get()
+		flights, err := core.FetchRegions(t.ctx, t.provider, settings, settings.WatchRegions(myLat, myLon))
+		if t.ctx.Err() != nil {
+			return
+		}
+		t.fetchHealth.Record(len(flights), err, time.Now())
+		if err != nil {
+			log.Println("Error fetching flights:", err)
+		} else {
+			t.aircraft.Enrich(flights)
+			t.pipeline.Submit(flights, time.Now())
+		}
+		select {
+		case <-time.After(core.StandardProfile.PollInterval(settings.PollInterval(t.provider))):
+		case <-t.ctx.Done():
+			return
+		}
+	}
+}
+
+// update takes the pipeline's latest snapshot and raises the alerts the
+// graphical frontends would
+func (t *tui) update() {
+	s := t.pipeline.Snapshot()
+	if s == t.snapshot {
+		return
+	}
+	t.snapshot = s
+
+	settings := t.settings.Get()
+	alertable := settings.AlertFilter.Apply(s.Flights)
+	for _, hit := range t.watchlist.Arrivals(alertable, s.FetchedAt) {
+		t.raise(fmt.Sprintf("WATCHLIST: %s (%s) in range", hit.Entry.Name(), hit.Flight.Callsign), core.ColAlert)
+	}
+	if settings.AlertInteresting {
+		for _, hit := range t.tags.Arrivals(alertable, s.FetchedAt) {
+			msg := fmt.Sprintf("%s: %s", strings.ToUpper(string(hit.Info.Tag)), hit.Flight.Callsign)
+			if hit.Info.Operator != "" {
+				msg += " (" + hit.Info.Operator + ")"
+			}
+			t.raise(msg, core.ColAlert)
+		}
+	}
+	prev := t.emergencies
+	t.emergencies = core.Emergencies(s.Flights)
+	for _, f := range t.emergencies {
+		if !slices.ContainsFunc(prev, func(p core.Flight) bool { return p.Icao24 == f.Icao24 && p.Squawk == f.Squawk }) {
+			t.raise(fmt.Sprintf("EMERGENCY: %s squawking %s (%s)", f.Callsign, f.Squawk, f.Emergency), core.ColDanger)
+		}
+	}
+
+	t.prefetch.Want(s.Flights) // nearest first already
+}
+
+func (t *tui) raise(msg string, col uint32) {
+	log.Println(msg)
+	t.alerts = append(t.alerts, alert{at: time.Now(), msg: msg, col: col})
+	if len(t.alerts) > maxAlerts {
+		t.alerts = t.alerts[len(t.alerts)-maxAlerts:]
+	}
+}
+
+// route is the cached route of f, or "" when it hasn't been looked up
+func (t *tui) route(f core.Flight) string {
+	if !f.HasCallsign() {
+		return ""
+	}
+	d, ok := t.routeCache.Known(f.Callsign)
+	if !ok {
+		return ""
+	}
+	return d.Origin + " > " + d.RealDestination
+}
+
+// flightLine is the table row for f at dist km
+func (t *tui) flightLine(f core.Flight, dist float64) string {
+	trend := " "
+	switch f.Trend() {
+	case core.TrendClimbing:
+		trend = "^"
+	case core.TrendDescending:
+		trend = "v"
+	}
+	alt := fmt.Sprintf("%6d%s", f.AltitudeFt, trend)
+	if f.OnGround {
+		alt = " ground"
+	}
+	brg := core.CompassPoint(core.Bearing(myLat, myLon, f.Lat, f.Lon))
+	return fmt.Sprintf("%-8s %-5s %-7s %s %4d %6.1f %-2s  %s",
+		truncate(f.Callsign, 8), truncate(f.TypeCode, 5), truncate(f.Registration, 7), alt, f.VelocityKts, dist, brg, t.route(f))
+}
+
+// draw composes the frame: header, nearest flight ticker, flight table and
+// alert log
+func (t *tui) draw(now time.Time) {
+	s, w, h := t.screen, t.screen.w, t.screen.h
+	s.Clear()
+
+	s.DrawRect(0, 0, w, 1, core.ColGlassLight)
+	s.DrawText("FLIGHT MONITOR", 1, 0, core.TextNormal, core.ColAccent)
+	status := fmt.Sprintf("%s  %s  %s", core.HomeAirport().Code(), t.provider.Name(), now.Format("15:04:05"))
+	if st := t.fetchHealth.Status(); st.Failures > 0 {
+		status = "fetch failing: " + st.LastError.Error() + "  " + status
+	}
+	s.DrawText(truncate(status, w-17), w-1-min(len(status), w-17), 0, core.TextNormal, core.ColTextMuted)
+
+	snap := t.snapshot
+	if snap == nil || len(snap.Flights) == 0 {
+		s.DrawText("Waiting for flights...", 1, 2, core.TextNormal, core.ColTextMuted)
+	} else {
+		t.drawTicker(snap, now)
+		t.drawTable(snap, 3, h-13)
+	}
+	t.drawAlerts(h-8, 7)
+	s.DrawText("q + Enter to quit", 1, h-1, core.TextSmall, core.ColTextMuted)
+}
+
+// drawTicker scrolls the nearest airborne flight along the second line
+func (t *tui) drawTicker(snap *core.FlightSnapshot, now time.Time) {
+	s, w := t.screen, t.screen.w
+	f := snap.Closest()
+	if f == nil {
+		return
+	}
+	i := slices.IndexFunc(snap.Flights, func(c core.Flight) bool { return c.Icao24 == f.Icao24 })
+	msg := fmt.Sprintf("Nearest: %s %s, %.1f km %s at %d ft, %d kt",
+		f.Callsign, f.TypeCode, snap.DistanceKm[i], core.CompassPoint(core.Bearing(myLat, myLon, f.Lat, f.Lon)), f.AltitudeFt, f.VelocityKts)
+	if r := t.route(*f); r != "" {
+		msg += ", " + r
+	}
+	if f.Operator != "" {
+		msg += " (" + f.Operator + ")"
+	}
+	room := w - 2
+	if len(msg) > room {
+		loop := msg + "   ·   "
+		off := int(now.Unix()) % len(loop)
+		msg = (loop + loop)[off : off+room]
+	}
+	s.DrawText(msg, 1, 1, core.TextNormal, core.ColGold)
+}
+
+// drawTable lists the flights nearest first in n lines under a heading
+// at y
+func (t *tui) drawTable(snap *core.FlightSnapshot, y, n int) {
+	s, w := t.screen, t.screen.w
+	s.DrawText(truncate("CALLSIGN TYPE  REG         ALT  KTS     KM DIR ROUTE", w-2), 1, y, core.TextSmall, core.ColAccent)
+	end := s.BeginClip(1, y+1, w-2, n)
+	defer end()
+	fit := n
+	if len(snap.Flights) > n {
+		fit = n - 1 // leave the last line to say how many more
+	}
+	for i, f := range snap.Flights {
+		if i >= fit {
+			break
+		}
+		col := uint32(core.ColText)
+		switch {
+		case f.Emergency != "":
+			col = core.ColDanger
+		case f.OnGround || f.Stale:
+			col = core.ColTextMuted
+		}
+		s.DrawText(t.flightLine(f, snap.DistanceKm[i]), 1, y+1+i, core.TextNormal, col)
+	}
+	if extra := len(snap.Flights) - fit; extra > 0 {
+		s.DrawText(fmt.Sprintf("... and %d further away", extra), 1, y+1+fit, core.TextSmall, core.ColTextMuted)
+	}
+}
+
+// drawAlerts shows the newest alerts in the n lines from y
+func (t *tui) drawAlerts(y, n int) {
+	s, w := t.screen, t.screen.w
+	s.DrawRect(0, y, w, 1, core.ColGlassLight)
+	s.DrawText("ALERTS", 1, y, core.TextNormal, core.ColAccent)
+	if len(t.alerts) == 0 {
+		s.DrawText("None yet", 1, y+1, core.TextSmall, core.ColTextMuted)
+		return
+	}
+	shown := t.alerts[max(0, len(t.alerts)-(n-1)):]
+	for i := len(shown) - 1; i >= 0; i-- {
+		a := shown[i]
+		y++
+		s.DrawText(a.at.Format("15:04"), 1, y, core.TextSmall, core.ColTextMuted)
+		s.DrawText(truncate(a.msg, w-8), 7, y, core.TextNormal, a.col)
+	}
+}
+
+// truncate shortens s to at most n characters, marking the cut with "..."
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	if n <= 3 {
+		return s[:max(n, 0)]
+	}
+	return s[:n-3] + "..."
+}
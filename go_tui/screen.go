@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"unicode/utf8"
+
+	"flight-monitor/core"
+)
+
+// cell is one character position on the terminal
+type cell struct {
+	ch     rune
+	fg, bg uint32 // 0xRRGGBBAA; a zero bg is the terminal's own
+}
+
+// termScreen is a grid of cells that implements core.Renderer at one cell
+// per unit, so UI shared with the graphical frontends can draw in a
+// terminal. A frame is composed in the grid and written out in one go.
+type termScreen struct {
+	w, h  int
+	cells []cell
+	clip  image.Rectangle
+}
+
+func newTermScreen(w, h int) *termScreen {
+	s := &termScreen{w: w, h: h, cells: make([]cell, w*h)}
+	s.Clear()
+	return s
+}
+
+// Clear blanks the grid for the next frame
+func (s *termScreen) Clear() {
+	for i := range s.cells {
+		s.cells[i] = cell{ch: ' ', fg: core.ColText}
+	}
+	s.clip = image.Rect(0, 0, s.w, s.h)
+}
+
+func (s *termScreen) at(x, y int) *cell {
+	if !image.Pt(x, y).In(s.clip) {
+		return nil
+	}
+	return &s.cells[y*s.w+x]
+}
+
+func (s *termScreen) DrawRect(x, y, w, h int, col uint32) {
+	for cy := y; cy < y+h; cy++ {
+		for cx := x; cx < x+w; cx++ {
+			if c := s.at(cx, cy); c != nil {
+				*c = cell{ch: ' ', fg: c.fg, bg: col}
+			}
+		}
+	}
+}
+
+// DrawTexture can't show an image; it shades the box it would fill
+func (s *termScreen) DrawTexture(img image.Image, x, y, w, h int) {
+	for cy := y; cy < y+h; cy++ {
+		for cx := x; cx < x+w; cx++ {
+			if c := s.at(cx, cy); c != nil {
+				c.ch, c.fg = '░', core.ColTextMuted
+			}
+		}
+	}
+}
+
+// DrawText writes s from (x, y); there is one text size
+func (s *termScreen) DrawText(str string, x, y int, size core.TextSize, col uint32) {
+	for _, r := range str {
+		if c := s.at(x, y); c != nil {
+			c.ch, c.fg = r, col
+		}
+		x++
+	}
+}
+
+func (s *termScreen) Measure(str string, size core.TextSize) (int, int) {
+	return utf8.RuneCountInString(str), 1
+}
+
+func (s *termScreen) BeginClip(x, y, w, h int) func() {
+	prev := s.clip
+	s.clip = prev.Intersect(image.Rect(x, y, x+w, y+h))
+	return func() { s.clip = prev }
+}
+
+// Flush draws the grid over the terminal from its top left corner, with
+// 24-bit colour escapes
+func (s *termScreen) Flush(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	out.WriteString("\x1b[H")
+	for y := 0; y < s.h; y++ {
+		var fg, bg uint32 = 1, 1 // neither is a colour the cells use, so the first one is always set
+		for _, c := range s.cells[y*s.w : (y+1)*s.w] {
+			if c.fg != fg {
+				fg = c.fg
+				fmt.Fprintf(out, "\x1b[38;2;%d;%d;%dm", uint8(fg>>24), uint8(fg>>16), uint8(fg>>8))
+			}
+			if c.bg != bg {
+				bg = c.bg
+				if bg == 0 {
+					out.WriteString("\x1b[49m")
+				} else {
+					fmt.Fprintf(out, "\x1b[48;2;%d;%d;%dm", uint8(bg>>24), uint8(bg>>16), uint8(bg>>8))
+				}
+			}
+			out.WriteRune(c.ch)
+		}
+		out.WriteString("\x1b[0m")
+		if y < s.h-1 {
+			out.WriteString("\r\n")
+		}
+	}
+	return out.Flush()
+}
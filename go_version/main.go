@@ -1,29 +1,37 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"flag"
 	"fmt"
+	"image"
 	"image/color"
 	"log"
 	"math"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"runtime"
-	"sort"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"flight-monitor/core"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 	"golang.org/x/image/font/basicfont"
 )
 
 const (
-	// Physical screen dimensions (Portrait)
-	physicalWidth  = 720
-	physicalHeight = 1280
+	// Window size when not fullscreen, before turning for the orientation
+	windowWidth  = 1280
+	windowHeight = 720
 
 	// Game logic dimensions (Landscape)
 	// PERFORMANCE: 854x480 is a "safe mode" resolution (approx 1.5x scale)
@@ -32,16 +40,42 @@ const (
 	logicalHeight = 480
 
 	defaultZoom = 11
-
-	// UI Colors
-	colBgDark     = 0x0f172aff // #0f172a
-	colAccent     = 0x38bdf8ff // #38bdf8
-	colGlass      = 0x0f172af2 // #0f172a (95% opacity)
-	colGlassLight = 0x334155ff // #334155 (lighter, more opaque)
-	colText       = 0xf1f5f9ff // #f1f5f9
-	colTextMuted  = 0x94a3b8ff // #94a3b8
-	colSuccess    = 0x4ade80ff // #4ade80
-	colDanger     = 0xf87171ff // #f87171
+	// Arrivals listed under the runways in use; the rest are only
+	// numbered on the map
+	arrivalQueueShown = 5
+	// Distance between the ticks along an approach corridor
+	approachTickKm = 5.0
+	// Zoom levels a notch of the mouse wheel moves
+	wheelZoomStep = 0.5
+
+	// Ticks a second, and fewer while saving energy
+	normalTPS = 24
+	ecoTPS    = 10
+
+	// How long quitting waits for polling and history writes to wind down
+	shutdownTimeout = 3 * time.Second
+
+	// The splash gives up on the first flight fetch after this; polling
+	// carries on trying in the background
+	startupFetchTimeout = 20 * time.Second
+	// How long startup errors stay on the splash before moving on, so an
+	// unattended kiosk still reaches the map
+	splashErrorHold = 10 * time.Second
+
+	// Days of noise events the noise screen charts
+	noiseChartDays = 14
+
+	// UI Colors, shared with UI drawn through core.Renderer
+	colBgDark     = core.ColBgDark
+	colAccent     = core.ColAccent
+	colGlass      = core.ColGlass
+	colGlassLight = core.ColGlassLight
+	colText       = core.ColText
+	colTextMuted  = core.ColTextMuted
+	colSuccess    = core.ColSuccess
+	colDanger     = core.ColDanger
+	colGold       = core.ColGold
+	colAlert      = core.ColAlert
 )
 
 var (
@@ -49,37 +83,156 @@ var (
 	myLon = 24.780103286993022
 )
 
+// devLayout is set when UI_LAYOUT names a file to tweak the layout and
+// colours from while the app runs; nil otherwise
+var devLayout *core.DevLayout
+
+// colorNames are the theme colours' names in the UI_LAYOUT file
+var colorNames = map[uint32]string{
+	colBgDark:     "bg_dark",
+	colAccent:     "accent",
+	colGlass:      "glass",
+	colGlassLight: "glass_light",
+	colText:       "text",
+	colTextMuted:  "text_muted",
+	colSuccess:    "success",
+	colDanger:     "danger",
+	colGold:       "gold",
+	colAlert:      "alert",
+}
+
+// lay is the layout value called name, from the UI_LAYOUT file when there
+// is one
+func lay(name string, def int) int {
+	return devLayout.Int(name, def)
+}
+
 // GameState enum
 type State int
 
 const (
-	StateLogin State = iota
+	StateSplash State = iota // startup work in progress
+	StateLogin
 	StateMap
 	StateGameBriefing
 	StateGamePlaying
 	StateRoundSetup // New state for fetching details
 	StateGameOver
 	StateLeaderboard
+	StateSettings
+	StateReplayEntry
+	StateStatus       // health of the provider, caches and data dir
+	StateNoise        // low overhead flights per hour and day
+	StateRegulars     // flights that pass at about the same time most days
+	StateArrivalBonus // guessing minutes to landing after the last round
+	StateDiary        // notes and photos on overhead passes
+	StateStorage      // retention settings and disk usage
+	StateResolvers    // how each route resolver is doing
+	StateCalibrate    // tapping targets to correct the touch panel
+	StateOddOneOut    // picking the flight that doesn't match the others
 )
 
 type Game struct {
-	flightClient *FlightClient
-	tileLoader   *TileLoader
-	dataManager  *DataManager
-	scraper      *Scraper
-	flights      []Flight
-	state        State
-	shouldQuit   bool
+	ctx         context.Context // cancelled by main on quit
+	bg          sync.WaitGroup  // background loops shutdown waits for
+	clock       core.GameClock  // round timers; pauses across suspend
+	wake        chan struct{}   // cuts the poll wait short after a resume
+	provider    core.FlightProvider
+	profile     core.ResourceProfile
+	tileLoader  *TileLoader
+	attribution core.Attribution // credit for the tiles, over a corner of the map
+	power       *core.PowerMonitor
+	prefetched  core.TileCoord // centre tile of the view tiles were last prefetched for
+	dataManager *core.DataManager
+	resolver    core.DetailsResolver
+	tracks      *core.TrackRecorder
+	history     *core.TrackHistory
+	watchlist   *core.Watchlist
+	tags        *core.TagDB
+	aircraft    *core.AircraftDB
+	exporter    *core.DailyExporter
+	receiver    *core.ReceiverMonitor // nil unless RECEIVER_URL is set
+	updates     *core.UpdateChecker   // nil unless UPDATE_CHECK is set
+	backup      *core.Backup          // nil unless BACKUP_URL is set
+	scoreSync   *core.ScoreSync       // nil unless LEADERBOARD_URL is set
+	metar       *core.MetarClient     // sky report near home, nil if METAR_STATION=off
+	photos      *core.PhotoCache      // planespotters thumbnails for the info panel
+	party       *core.PartyServer     // nil unless PARTY_ADDR is set
+	polar       *core.PolarRange      // nil unless reading our own receiver
+	pipeline    *core.FlightPipeline
+	snapshot    *core.FlightSnapshot // last snapshot picked up by syncFlights
+	flights     *core.FlightStore    // merged from snapshots
+	state       State
+	shouldQuit  bool
 
 	// Offscreen buffer for rotation
-	offscreen *ebiten.Image
+	offscreen   *ebiten.Image
+	orientation core.Orientation     // as configured; may be auto
+	screenXf    core.ScreenTransform // offscreen to the window, set by Layout
+	touchCal    core.TouchCalibration
+
+	calibrator     *core.TouchCalibrator // the calibration screen's run
+	calibrationMsg string
+
+	// The selected aircraft's photo, converted once
+	photoKey string
+	photoImg *ebiten.Image
 
 	// Data
-	currentUser   UserStats
-	usersMap      map[string]UserStats
-	highScores    []ScoreEntry
-	userStatsList []UserStats
-	airports      []string
+	users         *core.UserStore
+	highScores    []core.ScoreEntry
+	todayStats    core.DayStats
+	userStatsList []core.UserStats
+	settings      *core.SettingsStore
+	activeRegion  int // index into settings.WatchRegions; the camera's home
+
+	startup      core.Startup
+	splashDoneAt time.Time // when startup finished, zero until then
+
+	fetchHealth core.FetchHealth  // recorded by the polling goroutine
+	health      core.HealthReport // taken when the status screen opens
+
+	noise      *core.NoiseCounter // nil when the flights aren't live
+	noiseStats core.NoiseStats    // taken when the noise screen opens
+	noiseMsg   string             // result of the last CSV export
+
+	routeCache    *core.RouteCache
+	learned       *core.LearnedRoutes
+	prefetch      *core.Prefetcher     // nil when PREFETCH_PER_MIN is off or nothing needs resolving
+	overhead      *core.OverheadLog    // nil when the flights aren't live
+	runways       *core.RunwayMonitor  // nil when the flights aren't live
+	runwayUse     core.RunwaysInUse    // shown on the map, e.g. "Arrivals RWY 22L"
+	arrivalQueue  []core.QueuedArrival // inbound to runwayUse, nearest touchdown first
+	regulars      []core.Regular       // for early/late alerts; pipeline goroutine only
+	regularsAt    time.Time            // when regulars was worked out; pipeline goroutine only
+	regularAlerts chan string          // pipeline goroutine to UI
+	regularsList  []core.Regular       // taken when the regulars screen opens
+
+	// Spotting diary, loaded when its screen opens
+	diary       []core.SpottingEntry
+	diaryPasses []core.OverheadPass
+	diaryView   int
+	diaryPage   int
+	diaryEdit   *core.SpottingEntry // being written, nil when browsing
+	diaryMsg    string
+	diaryFiles  []string // photos to pick from
+	diaryFile   int
+	diaryImgKey string
+	diaryImg    *ebiten.Image
+
+	storageUsage []core.DirEntryUsage // measured when the storage screen opens
+	storageMsg   string               // result of the last manual prune
+
+	showReleaseNotes bool // the update badge's notes panel is open
+
+	flightsSavedAt time.Time // last save of last_flights.json; pipeline goroutine only
+
+	watchAlert      string
+	watchAlertUntil time.Time
+	emergencies     []core.Flight // in range and squawking 7500/7600/7700
+	airlineLegend   []core.LegendEntry
+	airports        []string
+	airportDB       *core.AirportDB // empty until imported with -import-openflights
 
 	// Login Input
 	inputText         string
@@ -91,7 +244,7 @@ type Game struct {
 	// Camera
 	camLat  float64
 	camLon  float64
-	camZoom int
+	camZoom float64 // fractional while pinching, see core.TileLevel
 
 	// Touch/Input
 	isDragging    bool
@@ -105,31 +258,76 @@ type Game struct {
 	planeImg *ebiten.Image
 
 	// Selected Plane
-	selectedPlane   *Flight
-	resolvedDetails *ResolvedDetails
+	selectedPlane   *core.Flight
+	resolvedDetails *core.ResolvedDetails
 	resolving       bool
+	selectedTrack   []core.TrackPoint    // flown path of selectedPlane
+	selectCancel    context.CancelFunc   // stops the selected plane's lookups
+	selectSeq       int                  // bumped on every change of selection
+	selectResults   chan selectionResult // lookups to UI
+	roundCancel     context.CancelFunc   // stops the round setup's lookup
+	roundSeq        int                  // bumped on every round setup
+	roundResults    chan roundResult     // round setup lookups to UI
 
 	// Game Logic
-	score           int
-	targetPlane     *Flight
-	round           int
-	roundStartTime  time.Time
-	questionText    string // Dynamic question
-	options         []string
-	correctOption   string
-	wrongGuess      string // Store the wrong guess for red feedback
+	score          int
+	targetPlane    *core.Flight
+	round          int
+	roundStartTime time.Duration // on clock
+	questionText   string        // Dynamic question
+	options        []string
+	correctOption  string
+	typeRound      bool   // asking for the aircraft type rather than the route
+	typeHint       string // masked model string shown during a type round
+	strategy       string // distractor strategy of a route round; see core.DistractorExperiment
+	difficulty     core.Difficulty
+	roundTime      time.Duration
+
+	// Arrival bonus round after the last question
+	bonusTarget  string                // icao24 of a quizzed flight whose landing time can be estimated
+	bonusDetails *core.ResolvedDetails // its resolved route
+	bonusName    string                // what the question calls it
+	bonusGuess   int                   // minutes
+	bonusResult  string                // how the guess scored, for the game over panel
+
+	// Odd-one-out puzzles from the flights on the map
+	odd      core.OddOneOut
+	oddErr   error // why no puzzle could be made from the traffic
+	oddPick  int   // the option picked, -1 until the player answers
+	oddRight int   // puzzles solved since the screen was opened
+	oddTried int
+
+	// Party mode: phones that joined answer instead of the kiosk
+	partyGame bool
+
+	// Share codes and replays of logged games
+	gameSeed    uint16
+	roundLog    []core.RoundRecord
+	shareCode   string
+	replay      *core.GameRecord // set while replaying a shared game
+	replayScore int              // the other player's score from the code
+	replayError string
+
+	// New-best celebration on the game over panel
+	particles       core.Particles
+	celebration     core.AchievementKind // record the finished game set, if any
+	podium          []core.ScoreEntry    // top three including the finished game
+	podiumPlace     int                  // the finished game's index in podium, -1 if off it
+	wrongGuess      string               // Store the wrong guess for red feedback
 	showResult      bool
 	resultCorrect   bool
-	resultStartTime time.Time
+	resultStartTime time.Duration // on clock
 
 	// UI Elements (Simple rects for click detection)
-	buttons []Button
+	buttons []Button       // built by the draw pass, drawn and published to ui
+	ui      *core.UIEvents // presses queued in Update, dispatched against the last layout
 
 	// reusable render object
 	op *ebiten.DrawImageOptions
 }
 
 type Button struct {
+	ID         string // see core.WidgetID
 	X, Y, W, H int
 	Text       string
 	Action     func()
@@ -137,18 +335,36 @@ type Button struct {
 	TextColor  color.Color
 }
 
-func NewGame(fc *FlightClient) *Game {
+func NewGame(ctx context.Context, provider core.FlightProvider, profile core.ResourceProfile) *Game {
+	dm := &core.DataManager{}
 	g := &Game{
-		flightClient: fc,
-		tileLoader:   NewTileLoader(),
-		dataManager:  &DataManager{},
-		scraper:      NewScraper(),
-		camLat:       myLat,
-		camLon:       myLon,
-		camZoom:      defaultZoom,
-		planeImg:     createPlaneImage(),
-		state:        StateLogin,
-		offscreen:    ebiten.NewImage(logicalWidth, logicalHeight),
+		ctx:           ctx,
+		ui:            core.NewUIEvents(),
+		wake:          make(chan struct{}, 1),
+		provider:      provider,
+		profile:       profile,
+		power:         core.NewPowerMonitor(),
+		attribution:   core.MapAttribution(),
+		tileLoader:    NewTileLoader(ctx, profile.MaxTiles, profile.TileResolution, profile.TileFetches),
+		dataManager:   dm,
+		users:         core.NewUserStore(),
+		tracks:        core.NewTrackRecorder(),
+		history:       core.NewTrackHistory(dm),
+		watchlist:     core.NewWatchlist(),
+		tags:          core.NewTagDB(),
+		aircraft:      core.NewAircraftDB(),
+		pipeline:      core.NewFlightPipeline(myLat, myLon),
+		flights:       core.NewFlightStore(),
+		regularAlerts: make(chan string, 4),
+		selectResults: make(chan selectionResult, 4),
+		roundResults:  make(chan roundResult, 4),
+		exporter:      core.NewDailyExporter(dm, myLat, myLon, 1.0),
+		camLat:        myLat,
+		camLon:        myLon,
+		camZoom:       defaultZoom,
+		planeImg:      createPlaneImage(),
+		state:         StateSplash,
+		offscreen:     ebiten.NewImage(logicalWidth, logicalHeight),
 		keyboardLayout: []string{
 			"QWERTYUIOP",
 			"ASDFGHJKL",
@@ -157,25 +373,285 @@ func NewGame(fc *FlightClient) *Game {
 		op: &ebiten.DrawImageOptions{},
 	}
 
-	// Load initial data
-	g.refreshUsers()
-	g.refreshAirports()
-	go g.refreshFlights()
+	// Settings are needed by nearly everything and are a small local file;
+	// the rest loads behind the splash screen
+	s, err := g.dataManager.LoadSettings()
+	if err != nil {
+		log.Println("Error loading settings:", err)
+	}
+	if g.touchCal, err = g.dataManager.LoadTouchCalibration(); err != nil {
+		log.Println("Error loading touch calibration:", err)
+	}
+	g.settings = core.NewSettingsStore(s)
+	g.history.SetRetentionDays(s.Retention.Tracks())
+
+	g.routeCache = core.NewRouteCache(g.dataManager)
+	g.learned = core.NewLearnedRoutes(g.dataManager)
+	g.resolver = core.NewDetailsResolver(ctx, g.routeCache, g.learned)
+	// The simulator answers route lookups for its own callsigns, offline
+	if sim, ok := provider.(*core.SimulatedProvider); ok {
+		g.resolver = sim
+	} else if provider.Name() != "replay" {
+		g.prefetch = core.NewPrefetcher(g.routeCache, g.learned)
+	}
+
+	if provider.Name() == "local" {
+		g.polar = core.NewPolarRange(myLat, myLon)
+		if err := g.polar.Load(g.dataManager); err != nil {
+			log.Println("Error loading polar range:", err)
+		}
+	}
+
+	g.snapshot = g.pipeline.Snapshot()
+	// Show where planes were at the end of the last run until the first poll lands
+	if flights, at, err := g.dataManager.LoadLastFlights(provider.Name(), time.Now()); err != nil {
+		log.Println("Error loading last flights:", err)
+	} else if len(flights) > 0 {
+		g.flights.Merge(flights, at)
+	}
+	g.particles.Disabled = !profile.Particles
+	g.pipeline.AddStage(g.recordHistory)
+	g.pipeline.AddStage(g.saveLastFlights)
+	// Simulated and replayed traffic mustn't end up in a noise complaint
+	if name := provider.Name(); name != "sim" && name != "replay" {
+		g.noise = core.NewNoiseCounter(myLat, myLon)
+		g.pipeline.AddStage(g.recordNoise)
+		g.overhead = core.NewOverheadLog(myLat, myLon)
+		g.pipeline.AddStage(g.recordOverhead)
+		g.runways = core.NewRunwayMonitor()
+		g.pipeline.AddStage(g.recordRunways)
+	}
+	g.spawn(func() { g.pipeline.Run(ctx) })
+	g.spawn(func() { core.RunProvider(ctx, provider) })
+	g.spawn(func() { g.power.Run(ctx) })
+	g.spawn(func() {
+		g.startup.Run(ctx, g.startupSteps())
+		for _, e := range g.startup.Progress().Errors {
+			log.Println("Startup:", e)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		g.spawn(g.refreshFlights)
+		if provider.Name() != "sim" { // simulated flights carry their types already
+			g.spawn(g.loadAircraftDB)
+		}
+	})
+
+	if url := os.Getenv("RECEIVER_URL"); url != "" {
+		g.receiver = core.NewReceiverMonitor(url, myLat, myLon)
+		g.spawn(func() { g.receiver.Run(ctx) })
+	}
+	if g.prefetch != nil {
+		g.spawn(func() { g.prefetch.Run(ctx) })
+	}
+	if g.metar = core.NewMetarClient(myLat, myLon); g.metar != nil {
+		g.spawn(func() { g.metar.Run(ctx) })
+	}
+	if exporter := core.NewMapExporter(myLat, myLon); exporter != nil {
+		g.spawn(func() { exporter.Run(ctx, g.pipeline, g.settings) })
+	}
+	if b, err := core.NewBackup(); err != nil {
+		log.Println("Backups off:", err)
+	} else if b != nil {
+		g.backup = b
+		g.spawn(func() { b.Run(ctx) })
+	}
+	if s, err := core.NewScoreSync(g.dataManager); err != nil {
+		log.Println("Leaderboard sync off:", err)
+	} else if s != nil {
+		g.scoreSync = s
+		g.spawn(func() { s.Run(ctx) })
+	}
+	g.photos = core.NewPhotoCache(ctx, g.dataManager)
+	if addr := core.PartyAddr(); addr != "" {
+		g.party = core.NewPartyServer(addr)
+		g.spawn(func() { g.party.Run(ctx) })
+	}
+	if core.UpdateCheckEnabled() {
+		g.updates = core.NewUpdateChecker()
+		g.spawn(func() { g.updates.Run(ctx) })
+	}
+	if path := core.DevLayoutPath(); path != "" {
+		devLayout = core.NewDevLayout(path)
+		g.spawn(func() { devLayout.Run(ctx) })
+	}
 
 	return g
 }
 
+// startupSteps is the launch work done behind the splash screen
+func (g *Game) startupSteps() []core.StartupStep {
+	return []core.StartupStep{
+		// Before anything reads the logs, so it reads less
+		{Name: "Pruning old data", Run: func(context.Context) error {
+			r, err := g.dataManager.Prune(g.settings.Get().Retention, time.Now())
+			if err == nil {
+				log.Println("Retention:", r)
+			}
+			return err
+		}},
+		{Name: "Loading players", Run: func(context.Context) error {
+			users, err := g.dataManager.LoadUsers()
+			if err != nil {
+				return err
+			}
+			g.users.SetAll(users)
+			return nil
+		}},
+		{Name: "Loading airport database", Run: func(context.Context) error {
+			db, err := g.dataManager.LoadAirportDB()
+			if err != nil {
+				return err
+			}
+			g.airportDB = db
+			g.applyHomeAirport()
+			return nil
+		}},
+		{Name: "Loading airports", Run: func(context.Context) error {
+			g.refreshAirports() // falls back to a built-in list
+			return nil
+		}},
+		{Name: "Loading watchlist", Run: func(context.Context) error {
+			n, err := g.watchlist.Import(core.WatchlistPath())
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err == nil {
+				log.Printf("Loaded %d watchlist entries", n)
+			}
+			return err
+		}},
+		{Name: "Loading tag database", Run: func(context.Context) error {
+			n, err := g.tags.Load(core.TagDBPath())
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err == nil {
+				log.Printf("Loaded %d tagged aircraft", n)
+			}
+			return err
+		}},
+		{Name: "Opening offline map", Run: func(context.Context) error {
+			path := core.MBTilesPath()
+			if path == "" {
+				return nil
+			}
+			m, err := core.OpenMBTiles(path)
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			log.Println("Offline map tiles from", path)
+			g.tileLoader.SetArchive(m)
+			return nil
+		}},
+		// Signs in to OpenSky with the loaded credentials on the way
+		{Name: "Fetching flights from " + g.provider.Name(), Run: g.fetchInitialFlights},
+	}
+}
+
+// fetchInitialFlights fills the map before the first poll would, giving up
+// after startupFetchTimeout so a dead network can't hold up the splash
+func (g *Game) fetchInitialFlights(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, startupFetchTimeout)
+	defer cancel()
+	settings := g.settings.Get()
+	flights, err := core.FetchRegions(ctx, g.provider, settings, settings.WatchRegions(myLat, myLon))
+	g.fetchHealth.Record(len(flights), err, time.Now())
+	if err != nil {
+		return err
+	}
+	g.aircraft.Enrich(flights)
+	g.pipeline.Submit(flights, time.Now())
+	return nil
+}
+
+// updateSplash moves on to the login screen once startup is done, holding
+// for a while first if anything failed so the errors can be read
+func (g *Game) updateSplash() {
+	p := g.startup.Progress()
+	if !p.Finished {
+		return
+	}
+	if g.splashDoneAt.IsZero() {
+		g.splashDoneAt = time.Now()
+	}
+	if len(p.Errors) == 0 || time.Since(g.splashDoneAt) > splashErrorHold {
+		g.state = StateLogin
+	}
+}
+
+// splashStatus is the line under the progress bar
+func splashStatus(p core.StartupProgress) string {
+	switch {
+	case !p.Finished:
+		return p.Step + "..."
+	case len(p.Errors) > 0:
+		return "Started with problems:"
+	}
+	return "Ready"
+}
+
+// spawn runs fn on a goroutine that shutdown waits for
+func (g *Game) spawn(fn func()) {
+	g.bg.Add(1)
+	go func() {
+		defer g.bg.Done()
+		fn()
+	}()
+}
+
+// shutdown waits for the background loops to notice the cancelled context,
+// giving up after shutdownTimeout so a hung request can't block exit
+func (g *Game) shutdown() {
+	done := make(chan struct{})
+	go func() {
+		g.bg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		log.Println("Timed out waiting for background work to stop")
+	}
+	if err := g.dataManager.SaveLastFlights(g.provider.Name(), g.pipeline.Snapshot()); err != nil {
+		log.Println("Error saving last flights:", err)
+	}
+}
+
+// loadAircraftDB refreshes the cached aircraft database if it is out of date
+// and loads it; until then flights simply go without registrations
+func (g *Game) loadAircraftDB() {
+	path := core.AircraftDBPath()
+	if fetched, err := g.aircraft.Download(g.ctx, path); err != nil {
+		if g.ctx.Err() != nil {
+			return
+		}
+		log.Println("Error downloading aircraft database:", err)
+	} else if fetched {
+		log.Println("Downloaded aircraft database to", path)
+	}
+	if n, err := g.aircraft.Load(path); err == nil {
+		log.Printf("Loaded %d aircraft", n)
+	} else if !os.IsNotExist(err) {
+		log.Println("Error loading aircraft database:", err)
+	}
+}
+
 func (g *Game) refreshUsers() {
 	users, err := g.dataManager.LoadUsers()
 	if err == nil {
-		g.usersMap = users
+		g.users.SetAll(users)
 	}
 }
 
 func (g *Game) refreshAirports() {
 	airports, err := g.dataManager.LoadAirports()
-	if err == nil && len(airports) > 0 {
-		g.airports = airports
+	if pool := g.airportDB.QuizPool(airports); err == nil && len(pool) > 0 {
+		g.airports = pool
 	} else {
 		// Fallback if load failed or file empty
 		g.airports = []string{"London", "Paris", "Berlin", "Helsinki", "Tokyo", "New York", "Dubai", "Rome"}
@@ -188,68 +664,314 @@ func (g *Game) refreshLeaderboard() {
 		g.highScores = scores
 		g.userStatsList = stats
 	}
+	if history, err := g.dataManager.LoadScoreHistory(); err == nil {
+		g.todayStats = core.DailyStats(history, time.Now())
+	}
+}
+
+// eco reports whether to save energy: on battery unless the player says
+// otherwise
+func (g *Game) eco() bool {
+	return g.power.Eco(g.settings.Get().EcoMode)
+}
+
+// pollInterval is how long to wait between polls, stretched while saving
+// energy
+func (g *Game) pollInterval(settings core.Settings) time.Duration {
+	interval := g.profile.PollInterval(settings.PollInterval(g.provider))
+	if g.eco() {
+		interval = core.EcoPollInterval(interval)
+	}
+	return interval
+}
+
+// tps is the tick rate to run at, lower while saving energy
+func (g *Game) tps() int {
+	if g.eco() {
+		return ecoTPS
+	}
+	return normalTPS
+}
+
+// toggleEco flips whether energy is saved, overriding the power source
+func (g *Game) toggleEco() {
+	onBattery := g.power.OnBattery()
+	g.updateSettings(func(s *core.Settings) { s.EcoMode = s.EcoMode.Toggle(onBattery) })
+	g.prefetched = core.TileCoord{} // prefetch again, or stop
 }
 
 func (g *Game) refreshFlights() {
 	for {
-		flights, err := g.flightClient.FetchFlights(myLat, myLon, 1.0)
+		settings := g.settings.Get()
+		flights, err := core.FetchRegions(g.ctx, g.provider, settings, settings.WatchRegions(myLat, myLon))
+		if g.ctx.Err() != nil {
+			return
+		}
+		g.fetchHealth.Record(len(flights), err, time.Now())
 		if err != nil {
 			log.Println("Error fetching flights:", err)
 		} else {
-			g.flights = flights
-			// Update selected/target references if they still exist
-			if g.selectedPlane != nil {
-				found := false
-				for _, f := range flights {
-					if f.Icao24 == g.selectedPlane.Icao24 {
-						g.selectedPlane = &f
-						found = true
-						break
-					}
-				}
-				if !found {
-					// Plane disappeared
+			g.aircraft.Enrich(flights)
+			g.pipeline.Submit(flights, time.Now())
+		}
+		// The poll may have shown the clock right at last
+		if err := g.dataManager.StampHeldScores(time.Now()); err != nil {
+			log.Println("Error saving held scores:", err)
+		}
+		select {
+		case <-time.After(g.pollInterval(settings)):
+		case <-g.wake:
+		case <-g.ctx.Done():
+			return
+		}
+	}
+}
+
+// resume runs on the first frame after a suspend or stall. The round timer
+// has already skipped the gap; the flights on screen are out of date, so
+// poll again straight away.
+func (g *Game) resume(gap time.Duration) {
+	log.Printf("Resumed after %s, refreshing flights", gap.Round(time.Second))
+	select {
+	case g.wake <- struct{}{}:
+	default:
+	}
+}
+
+// recordHistory runs on the pipeline goroutine for every new snapshot
+func (g *Game) recordHistory(s *core.FlightSnapshot) {
+	if g.profile.History {
+		// Export yesterday's traffic once the date rolls over
+		if day, finished := g.tracks.Record(s.Flights, s.FetchedAt); finished != nil {
+			go func() {
+				path, err := g.exporter.Export(day, finished)
+				if err != nil {
+					log.Println("Daily export failed:", err)
+					return
 				}
+				log.Println("Saved daily traffic GIF:", path)
+			}()
+		}
+
+		if err := g.history.Append(s.Flights, s.FetchedAt); err != nil {
+			log.Println("Error saving track history:", err)
+		}
+	}
+
+	if g.polar != nil {
+		g.polar.Add(s.Flights)
+		if err := g.polar.SaveIfDirty(g.dataManager); err != nil {
+			log.Println("Error saving polar range:", err)
+		}
+	}
+}
+
+// saveLastFlights runs on the pipeline goroutine, keeping last_flights.json
+// fresh enough to fill the map at the next start
+func (g *Game) saveLastFlights(s *core.FlightSnapshot) {
+	if core.Elapsed(g.flightsSavedAt, s.FetchedAt) < core.LastFlightsSaveInterval {
+		return
+	}
+	g.flightsSavedAt = s.FetchedAt
+	if err := g.dataManager.SaveLastFlights(g.provider.Name(), s); err != nil {
+		log.Println("Error saving last flights:", err)
+	}
+}
+
+// recordNoise runs on the pipeline goroutine, logging aircraft that pass
+// low overhead
+func (g *Game) recordNoise(s *core.FlightSnapshot) {
+	events := g.noise.Observe(s, g.settings.Get().NoiseCeiling())
+	for _, e := range events {
+		log.Printf("Noise event: %s at %d ft, %.1f km away", e.Callsign, e.AltitudeFt, e.DistanceKm)
+	}
+	if err := g.dataManager.SaveNoiseEvents(events); err != nil {
+		log.Println("Error saving noise events:", err)
+	}
+}
+
+// recordRunways runs on the pipeline goroutine, following arrivals onto
+// final at the home airport
+func (g *Game) recordRunways(s *core.FlightSnapshot) {
+	g.runways.Observe(s.Flights, s.FetchedAt)
+}
+
+// recordOverhead runs on the pipeline goroutine, logging passes near home
+// and spotting regulars that turn up early or late
+func (g *Game) recordOverhead(s *core.FlightSnapshot) {
+	arrived, ended := g.overhead.Observe(s)
+	if err := g.dataManager.SaveOverheadPasses(ended); err != nil {
+		log.Println("Error saving overhead passes:", err)
+	}
+	settings := g.settings.Get()
+	arrived = settings.AlertFilter.Apply(arrived)
+	if len(arrived) == 0 || !settings.AlertRegulars {
+		return
+	}
+	if g.regularsAt.IsZero() || core.Elapsed(g.regularsAt, s.FetchedAt) > time.Hour {
+		g.regulars, g.regularsAt = g.loadRegulars(s.FetchedAt), s.FetchedAt
+	}
+	for _, f := range arrived {
+		if msg, ok := core.RegularAlert(g.regulars, f, s.FetchedAt); ok {
+			select {
+			case g.regularAlerts <- msg:
+			default: // the UI hasn't caught up; this one can go
 			}
-			if g.targetPlane != nil {
-				for _, f := range flights {
-					if f.Icao24 == g.targetPlane.Icao24 {
-						g.targetPlane = &f
-						break
-					}
-				}
+		}
+	}
+}
+
+// loadRegulars works out the regulars from the recent overhead log
+func (g *Game) loadRegulars(now time.Time) []core.Regular {
+	passes, err := g.dataManager.LoadOverheadPasses(now.AddDate(0, 0, -core.RegularsWindowDays))
+	if err != nil {
+		log.Println("Error loading overhead passes:", err)
+	}
+	return core.FindRegulars(passes, g.routeCache.Known)
+}
+
+// syncFlights merges the latest pipeline snapshot into the flight store on
+// the UI thread. Selected/target planes are store pointers, so they update in place.
+func (g *Game) syncFlights() {
+	for len(g.regularAlerts) > 0 {
+		msg := <-g.regularAlerts
+		log.Println("Regular:", msg)
+		g.watchAlert = msg
+		g.watchAlertUntil = time.Now().Add(30 * time.Second)
+	}
+
+	s := g.pipeline.Snapshot()
+	if s == g.snapshot {
+		return
+	}
+	g.snapshot = s
+	g.flights.Merge(s.Flights, s.FetchedAt)
+
+	// Everything shown may be on the watchlist, but only what passes the
+	// alert filter raises an alert
+	settings := g.settings.Get()
+	alertable := settings.AlertFilter.Apply(s.Flights)
+	for _, hit := range g.watchlist.Arrivals(alertable, s.FetchedAt) {
+		msg := fmt.Sprintf("WATCHLIST: %s (%s) in range", hit.Entry.Name(), hit.Flight.Callsign) + g.spotNote(hit.Flight)
+		log.Println(msg)
+		g.watchAlert = msg
+		g.watchAlertUntil = time.Now().Add(30 * time.Second)
+		g.pulseAt(hit.Flight, colAlert)
+	}
+	if settings.AlertInteresting {
+		for _, hit := range g.tags.Arrivals(alertable, s.FetchedAt) {
+			msg := fmt.Sprintf("%s: %s", strings.ToUpper(string(hit.Info.Tag)), hit.Flight.Callsign)
+			if hit.Info.Operator != "" {
+				msg += " (" + hit.Info.Operator + ")"
 			}
+			msg += g.spotNote(hit.Flight)
+			log.Println("Interesting traffic:", msg)
+			g.watchAlert = msg
+			g.watchAlertUntil = time.Now().Add(30 * time.Second)
+			g.pulseAt(hit.Flight, colAlert)
+		}
+	}
+
+	g.airlineLegend = core.AirlineLegend(s.Flights, 6)
+	g.runwayUse = g.runways.InUse(g.metar.Sky(), time.Now())
+	g.arrivalQueue = core.ArrivalQueue(s.Flights, g.runwayUse.Approaches, core.HomeAirport())
+	g.prefetchVisible()
+
+	prev := g.emergencies
+	g.emergencies = core.Emergencies(g.flights.Snapshot())
+	for _, f := range g.emergencies {
+		if !slices.ContainsFunc(prev, func(p core.Flight) bool { return p.Icao24 == f.Icao24 && p.Squawk == f.Squawk }) {
+			log.Printf("EMERGENCY: %s squawking %s (%s)", f.Callsign, f.Squawk, f.Emergency)
+			g.pulseAt(f, colDanger)
 		}
-		time.Sleep(5 * time.Second) // Faster polling
 	}
 }
 
-// getLogicalCursorPosition returns the game logic coordinates (Landscape)
-// derived from physical screen coordinates (Portrait)
-func (g *Game) getLogicalCursorPosition() (int, int) {
-	var x, y int
-	// Check touches first
-	ids := ebiten.AppendTouchIDs(nil)
-	if len(ids) > 0 {
-		x, y = ebiten.TouchPosition(ids[0])
-	} else {
-		x, y = ebiten.CursorPosition()
+// prefetchVisible queues the flights on screen for a background route
+// lookup, those nearest the middle first. Saving energy, nothing is.
+func (g *Game) prefetchVisible() {
+	if g.prefetch == nil {
+		return
+	}
+	if g.eco() {
+		g.prefetch.Want(nil)
+		return
+	}
+	offCentre := func(f core.Flight) float64 {
+		x, y := g.screenPos(f.Lat, f.Lon)
+		return math.Hypot(x-logicalWidth/2, y-logicalHeight/2)
+	}
+	var visible []core.Flight
+	for _, f := range g.flights.Snapshot() {
+		if x, y := g.screenPos(f.Lat, f.Lon); x >= 0 && y >= 0 && x <= logicalWidth && y <= logicalHeight {
+			visible = append(visible, f)
+		}
+	}
+	slices.SortFunc(visible, func(a, b core.Flight) int { return cmp.Compare(offCentre(a), offCentre(b)) })
+	g.prefetch.Want(visible)
+}
+
+// spotNote tells an alert whether the flight can be seen from home
+func (g *Game) spotNote(f core.Flight) string {
+	if f.OnGround {
+		return ""
+	}
+	return " - " + core.Spotability(f, g.metar.Sky(), myLat, myLon, time.Now()).Reason
+}
+
+// airframeLine is the registration, type and operator line of the flight
+// info panel. The type is left out for the quiz target, where it could give
+// a type round away. A registration only the resolver knew is shown too.
+func (g *Game) airframeLine(f *core.Flight) string {
+	reg := f.Registration
+	if reg == "" && g.resolvedDetails != nil {
+		reg = g.resolvedDetails.Registration
 	}
+	parts := []string{reg}
+	if g.state != StateGamePlaying || g.targetPlane == nil || f.Icao24 != g.targetPlane.Icao24 {
+		parts = append(parts, f.TypeCode)
+	}
+	parts = append(parts, f.Operator)
+	return strings.Join(slices.DeleteFunc(parts, func(s string) bool { return s == "" }), "  ")
+}
 
-	// Calculate scale dynamically based on current resolution settings
-	// Physical Height (1280) corresponds to Logical Width (854)
-	scale := float64(physicalHeight) / float64(logicalWidth)
+// screenPos projects a map position onto the screen
+func (g *Game) screenPos(lat, lon float64) (float64, float64) {
+	centerX, centerY := core.LatLonToPixels(g.camLat, g.camLon, g.camZoom)
+	x, y := core.LatLonToPixels(lat, lon, g.camZoom)
+	return x - centerX + float64(logicalWidth)/2, y - centerY + float64(logicalHeight)/2
+}
 
-	// Remap Logic:
-	// Physical X (0-720) becomes Game Y (0-480)
-	// Physical Y (0-1280) becomes Game X (0-854)
+// pulseAt rings a flight that raised an alert, if it is on screen
+func (g *Game) pulseAt(f core.Flight, col uint32) {
+	if g.state != StateMap && g.state != StateGamePlaying {
+		return
+	}
+	x, y := g.screenPos(f.Lat, f.Lon)
+	if x < 0 || y < 0 || x > logicalWidth || y > logicalHeight {
+		return
+	}
+	g.particles.Pulse(x, y, col, 3)
+}
 
-	// GameX = PhysicalY / Scale
-	gameX := int(float64(y) / scale)
-	// GameY = (ScreenWidth - PhysicalX) / Scale
-	gameY := int(float64(physicalWidth-x) / scale)
+// rawCursorPosition is where the panel reports the first touch, or the
+// mouse, in physical screen coordinates
+func rawCursorPosition() (int, int) {
+	// Check touches first
+	if ids := ebiten.AppendTouchIDs(nil); len(ids) > 0 {
+		return ebiten.TouchPosition(ids[0])
+	}
+	return ebiten.CursorPosition()
+}
 
-	return gameX, gameY
+// getLogicalCursorPosition returns the game logic coordinates (Landscape)
+// derived from physical screen coordinates, corrected by the touch
+// calibration, in whatever orientation
+func (g *Game) getLogicalCursorPosition() (int, int) {
+	x, y := rawCursorPosition()
+	cx, cy := g.touchCal.Apply(float64(x), float64(y))
+	gameX, gameY := g.screenXf.ToLogical(cx, cy)
+	return int(gameX), int(gameY)
 }
 
 func (g *Game) Update() error {
@@ -257,6 +979,21 @@ func (g *Game) Update() error {
 	if g.shouldQuit {
 		return ebiten.Termination
 	}
+	if gap := g.clock.Tick(time.Now()); gap > 0 {
+		g.resume(gap)
+	}
+	if tps := g.tps(); ebiten.TPS() != tps {
+		ebiten.SetTPS(tps)
+	}
+	g.particles.Update(1 / float64(ebiten.TPS()))
+
+	g.syncFlights()
+	g.applySelection()
+	g.applyRoundSetup()
+
+	if g.state == StateSplash {
+		g.updateSplash()
+	}
 
 	// Text Input for Login
 	if g.state == StateLogin {
@@ -275,6 +1012,30 @@ func (g *Game) Update() error {
 		}
 	}
 
+	if g.state == StateReplayEntry {
+		g.inputText += strings.ToUpper(string(ebiten.InputChars()))
+		if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(g.inputText) > 0 {
+			g.inputText = g.inputText[:len(g.inputText)-1]
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+			g.submitReplayCode(g.inputText)
+		}
+	}
+
+	if g.state == StateDiary && g.diaryEdit != nil {
+		g.inputText += string(ebiten.InputChars())
+		if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(g.inputText) > 0 {
+			g.inputText = g.inputText[:len(g.inputText)-1]
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+			g.saveDiary()
+		}
+	}
+
+	if g.state == StateCalibrate && g.updateCalibration() {
+		return nil
+	}
+
 	// Keyboard Input Logic (Overlay)
 	// Note: We do NOT return early here because we need checkUIClick to run
 	// so that keyboard buttons can be pressed.
@@ -295,30 +1056,11 @@ func (g *Game) Update() error {
 		// We use physical coordinates; rotation doesn't change distance.
 		currentDist := math.Hypot(float64(x2-x1), float64(y2-y1))
 
-		if g.lastPinchDist > 0 {
-			// Sensitivity threshold (pixels) to prevent jitter
-			threshold := 10.0
-			diff := currentDist - g.lastPinchDist
-
-			// If fingers moved enough to warrant a zoom change
-			if math.Abs(diff) > threshold {
-				if diff > 0 {
-					g.camZoom++ // Spread fingers = Zoom In
-				} else {
-					g.camZoom-- // Pinch fingers = Zoom Out
-				}
-
-				// Clamp Zoom
-				if g.camZoom < 4 {
-					g.camZoom = 4
-				}
-				if g.camZoom > 18 {
-					g.camZoom = 18
-				}
-
-				// Reset baseline to current to avoid rapid-fire zooming
-				g.lastPinchDist = currentDist
-			}
+		if g.lastPinchDist > 0 && currentDist > 0 {
+			// The map follows the fingers: twice as far apart is one
+			// zoom level in, half as far one level out
+			g.camZoom = core.ClampZoom(g.camZoom + math.Log2(currentDist/g.lastPinchDist))
+			g.lastPinchDist = currentDist
 		} else {
 			// First frame of the pinch, just establish baseline
 			g.lastPinchDist = currentDist
@@ -349,12 +1091,14 @@ func (g *Game) Update() error {
 			if g.isKeyboardOpen {
 				// If keyboard is open, ignore map clicks
 				g.isDragging = false
-			} else if g.state == StateMap || g.state == StateGamePlaying {
+			} else if g.state == StateMap || g.state == StateGamePlaying || g.state == StateOddOneOut {
 				g.checkPlaneClick(g.dragStartX, g.dragStartY)
 			}
 		}
 	}
 
+	g.dispatchUI()
+
 	// Check if Held
 	isHeld := ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) || len(touchIDs) == 1
 
@@ -365,9 +1109,9 @@ func (g *Game) Update() error {
 			dy := currY - g.dragStartY
 
 			// Only pan in Map/Game mode
-			if g.state == StateMap || g.state == StateGamePlaying {
+			if g.state == StateMap || g.state == StateGamePlaying || g.state == StateOddOneOut {
 				// Convert pixels to lat/lon delta
-				scale := 360.0 / math.Pow(2, float64(g.camZoom)) / 256.0
+				scale := 360.0 / math.Pow(2, g.camZoom) / 256.0
 				g.camLon = g.startCamLon - float64(dx)*scale
 				latScale := scale * math.Cos(g.camLat*math.Pi/180.0)
 				g.camLat = g.startCamLat + float64(dy)*latScale
@@ -380,19 +1124,24 @@ func (g *Game) Update() error {
 	// 3. Mouse Wheel Zoom (Keep this for desktop testing)
 	_, wheelDy := ebiten.Wheel()
 	if wheelDy != 0 {
-		g.camZoom += int(wheelDy)
-		// Clamp Zoom
-		if g.camZoom < 4 {
-			g.camZoom = 4
-		}
-		if g.camZoom > 18 {
-			g.camZoom = 18
-		}
+		// Touchpads scroll in fractions of a notch, which zoom smoothly
+		g.camZoom = core.ClampZoom(g.camZoom + wheelDy*wheelZoomStep)
 	}
 
 	// Game Logic Transitions
+	if g.state == StateGamePlaying && !g.showResult && g.clock.Since(g.roundStartTime) > g.roundTime {
+		g.guess("") // out of time counts as a miss
+	}
+	if g.state == StateGamePlaying && !g.showResult && g.partyGame && g.party.AllAnswered() {
+		g.guess("") // every phone is in; no need to wait out the timer
+	}
 	if g.state == StateGamePlaying && g.showResult {
-		if time.Since(g.resultStartTime) > 2*time.Second {
+		// Leave a party time to read the scoreboard
+		pause := 2 * time.Second
+		if g.partyGame {
+			pause = 5 * time.Second
+		}
+		if g.clock.Since(g.resultStartTime) > pause {
 			g.nextRound()
 		}
 	}
@@ -402,92 +1151,164 @@ func (g *Game) Update() error {
 
 func (g *Game) login(name string) {
 	g.isKeyboardOpen = false
-	if u, ok := g.usersMap[name]; ok {
-		g.currentUser = u
-	} else {
-		g.currentUser = UserStats{Name: name}
-	}
+	g.difficulty = g.users.Login(name).Difficulty
 	g.state = StateMap
 }
 
 func (g *Game) checkUIClick(x, y int) bool {
-	// Iterate buttons in reverse (topmost first)
-	for i := len(g.buttons) - 1; i >= 0; i-- {
-		b := g.buttons[i]
-		if x >= b.X && x <= b.X+b.W && y >= b.Y && y <= b.Y+b.H {
-			if b.Action != nil {
-				b.Action()
-			}
-			return true
-		}
+	// Buttons queue the press; dispatchUI runs it once input is read
+	if g.ui.Press(int(g.state), x, y) {
+		return true
 	}
 	// Also catch clicks on sidebars to prevent map panning through them
 	// Adjusted width for 854px screen (Sidebar is now 220px)
 	if g.selectedPlane != nil && x > logicalWidth-220 {
 		return true
 	}
-	if g.state == StateGamePlaying && x < 220 {
+	if (g.state == StateGamePlaying || g.state == StateArrivalBonus || g.state == StateOddOneOut) && x < 220 {
 		return true
 	}
 	return false
 }
 
-// selectPlane handles selection logic including firing the scraper
-func (g *Game) selectPlane(f *Flight) {
+// publishLayout hands the buttons this frame drew to the event queue, so
+// presses are matched against what is on screen
+func (g *Game) publishLayout() {
+	widgets := make([]core.UIWidget, len(g.buttons))
+	for i, b := range g.buttons {
+		widgets[i] = core.UIWidget{ID: b.ID, X: b.X, Y: b.Y, W: b.W, H: b.H, Action: b.Action}
+	}
+	g.ui.SetLayout(int(g.state), widgets)
+}
+
+// dispatchUI runs the button actions for this frame's presses
+func (g *Game) dispatchUI() {
+	g.ui.Dispatch(func() int { return int(g.state) })
+}
+
+// selectPlane handles selection logic including resolving the route
+func (g *Game) selectPlane(f *core.Flight) {
+	ctx, seq := g.newSelection()
 	g.selectedPlane = f
 	g.resolvedDetails = nil
 	g.resolving = true
+	g.selectedTrack = nil
+	// Prefetched details show at once; the lookup below confirms them
+	if d, ok := g.prefetch.Cached(*f); ok {
+		g.resolvedDetails, g.resolving = d, false
+	}
+
+	// Without trails the track is never drawn, so don't fetch it
+	if g.profile.Trails {
+		go func(icao24 string) {
+			track, err := core.FetchTrack(ctx, g.provider, icao24)
+			if ctx.Err() != nil {
+				return // another plane was selected meanwhile
+			}
+			if err != nil {
+				if err != core.ErrTracksUnsupported {
+					log.Printf("Failed to fetch track for %s: %v", icao24, err)
+				}
+				// Fall back to what we have recorded ourselves today
+				track = g.tracks.Track(icao24)
+			}
+			g.deliverSelection(ctx, selectionResult{seq: seq, track: track, isTrack: true})
+		}(f.Icao24)
+	}
 
 	// Trigger scrape
-	go func(callsign string) {
-		details, err := g.scraper.FetchFlightDetails(callsign)
-		if err != nil {
-			log.Printf("Failed to resolve %s: %v", callsign, err)
-			g.resolving = false
+	go func(target core.Flight) {
+		details, err := core.ResolveFlight(ctx, g.resolver, target)
+		if ctx.Err() != nil {
 			return
 		}
-		// Store scraped airports for future use
-		if details != nil {
-			// Save async
+		if err != nil {
+			log.Printf("Failed to resolve %s: %v", core.QuizName(target, nil), err)
+		} else if details != nil {
+			// Store scraped airports for future use
 			go func() {
-				g.dataManager.SaveAirport(details.RealDestination)
-				g.dataManager.SaveAirport(details.Origin)
+				g.dataManager.SaveAirport(details.RealDestination, g.airportDB)
+				g.dataManager.SaveAirport(details.Origin, g.airportDB)
 			}()
 		}
+		g.deliverSelection(ctx, selectionResult{seq: seq, details: details})
+	}(*f)
+}
+
+// selectionResult is a lookup for a selected plane, handed back to the UI
+// goroutine. seq tells it apart from lookups for earlier selections.
+type selectionResult struct {
+	seq     int
+	isTrack bool
+	track   []core.TrackPoint
+	details *core.ResolvedDetails // nil if the lookup failed
+}
+
+// newSelection cancels the previous selection's lookups and starts a
+// context for the next one's
+func (g *Game) newSelection() (context.Context, int) {
+	g.cancelSelection()
+	ctx, cancel := context.WithCancel(g.ctx)
+	g.selectCancel = cancel
+	return ctx, g.selectSeq
+}
 
-		// Only update if selection hasn't changed
-		if g.selectedPlane != nil && g.selectedPlane.Callsign == callsign {
-			g.resolvedDetails = details
-			g.resolving = false
+// cancelSelection abandons the selected plane's lookups, so a late answer
+// can't overwrite whatever is shown next
+func (g *Game) cancelSelection() {
+	if g.selectCancel != nil {
+		g.selectCancel()
+		g.selectCancel = nil
+	}
+	g.selectSeq++
+}
+
+// deselectPlane closes the info panel
+func (g *Game) deselectPlane() {
+	g.cancelSelection()
+	g.selectedPlane = nil
+}
+
+// deliverSelection passes r to the UI goroutine, or drops it once the
+// selection has moved on
+func (g *Game) deliverSelection(ctx context.Context, r selectionResult) {
+	select {
+	case g.selectResults <- r:
+	case <-ctx.Done():
+	}
+}
+
+// applySelection stores the lookups that are for the current selection
+func (g *Game) applySelection() {
+	for len(g.selectResults) > 0 {
+		r := <-g.selectResults
+		if r.seq != g.selectSeq {
+			continue
+		}
+		if r.isTrack {
+			g.selectedTrack = r.track
+		} else {
+			g.resolvedDetails, g.resolving = r.details, false
 		}
-	}(f.Callsign)
+	}
 }
 
 func (g *Game) checkPlaneClick(x, y int) {
 	// Find closest plane
-	minDist := 40.0 // Click radius
-	var found *Flight
+	const clickRadius = 40.0
+	var found *core.Flight
 
-	centerX, centerY := LatLonToPixels(g.camLat, g.camLon, g.camZoom)
+	centerX, centerY := core.LatLonToPixels(g.camLat, g.camLon, g.camZoom)
 	screenCX, screenCY := float64(logicalWidth)/2, float64(logicalHeight)/2
 	minWX := centerX - screenCX
 	minWY := centerY - screenCY
 
-	for i := range g.flights {
-		f := &g.flights[i]
-		fX, fY := LatLonToPixels(f.Lat, f.Lon, g.camZoom)
-		sX := fX - minWX
-		sY := fY - minWY
-
-		dist := math.Sqrt(math.Pow(sX-float64(x), 2) + math.Pow(sY-float64(y), 2))
-		if dist < minDist {
-			minDist = dist
-			found = f
-		}
-	}
+	found = g.flights.Nearest(minWX+float64(x), minWY+float64(y), g.camZoom, clickRadius)
 
 	if found != nil {
 		g.selectPlane(found)
+		x, y := g.screenPos(g.flights.Position(found, time.Now()))
+		g.particles.Sparks(x, y, 16, colAccent)
 
 		// Auto-center if game is not active
 		if g.state == StateMap {
@@ -501,16 +1322,41 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	// Draw logic to offscreen buffer (Landscape)
 	g.offscreen.Fill(color.RGBA{15, 23, 42, 255})
 
-	if g.state == StateLogin {
+	if g.state == StateSplash {
+		g.drawSplash(g.offscreen)
+	} else if g.state == StateLogin {
 		g.drawLogin(g.offscreen)
 	} else if g.state == StateLeaderboard {
 		g.drawLeaderboard(g.offscreen)
+	} else if g.state == StateSettings {
+		g.drawSettings(g.offscreen)
+	} else if g.state == StateReplayEntry {
+		g.drawReplayEntry(g.offscreen)
+	} else if g.state == StateStatus {
+		g.drawStatus(g.offscreen)
+	} else if g.state == StateNoise {
+		g.drawNoise(g.offscreen)
+	} else if g.state == StateRegulars {
+		g.drawRegulars(g.offscreen)
+	} else if g.state == StateDiary {
+		g.drawDiary(g.offscreen)
+	} else if g.state == StateStorage {
+		g.drawStorage(g.offscreen)
+	} else if g.state == StateResolvers {
+		g.drawResolvers(g.offscreen)
+	} else if g.state == StateCalibrate {
+		g.drawCalibration(g.offscreen)
 	} else {
 		g.drawMap(g.offscreen)
+		g.drawPolarRange(g.offscreen)
+		g.drawSelectedTrack(g.offscreen)
+		g.drawApproaches(g.offscreen)
 		g.drawHomeMarker(g.offscreen)
 		g.drawPlanes(g.offscreen)
+		g.drawAttribution(g.offscreen)
 		g.drawUI(g.offscreen)
 	}
+	g.particles.Draw(particleRenderer{g.offscreen})
 
 	// Render offscreen to physical screen with rotation
 	op := &ebiten.DrawImageOptions{}
@@ -518,42 +1364,106 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	// 1. Move image to center so we rotate around the center
 	op.GeoM.Translate(-float64(logicalWidth)/2, -float64(logicalHeight)/2)
 
-	// 2. Rotate 90 degrees (Pi/2 radians)
-	op.GeoM.Rotate(math.Pi / 2)
+	// 2. Turn to the display's orientation (90 degrees on a portrait panel)
+	op.GeoM.Rotate(g.screenXf.Radians())
 
-	// 3. Scale up to fit physical screen
-	// Calculate scale: 1280 / 854 = ~1.5
-	scale := float64(physicalHeight) / float64(logicalWidth)
+	// 3. Scale up to fit physical screen, e.g. 1280 / 854 = ~1.5
+	scale := g.screenXf.Scale()
 	op.GeoM.Scale(scale, scale)
 
 	// 4. Move back to center of the destination screen
-	op.GeoM.Translate(float64(physicalWidth)/2, float64(physicalHeight)/2)
+	op.GeoM.Translate(float64(g.screenXf.ScreenW)/2, float64(g.screenXf.ScreenH)/2)
 
 	// Filter: Nearest for retro look/speed, or Linear for smooth
 	op.Filter = ebiten.FilterNearest
 
 	screen.DrawImage(g.offscreen, op)
+	g.publishLayout()
 
 	// DEBUG: Draw touch count on top of everything to verify hardware support
 	// ebitenutil.DebugPrint(screen, fmt.Sprintf("FPS: %0.2f | Touches: %d", ebiten.ActualFPS(), len(ebiten.AppendTouchIDs(nil))))
 }
 
-func (g *Game) drawLogin(screen *ebiten.Image) {
+// drawSplash shows startup progress and any steps that failed
+func (g *Game) drawSplash(screen *ebiten.Image) {
 	g.buttons = g.buttons[:0]
+	p := g.startup.Progress()
 
 	title := "VANTAA FLIGHTRADAR24"
-	titleW := len(title) * 7 * 2 // approx scale 2
-	// Use scale for title
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Scale(2, 2)
-	op.GeoM.Translate(float64(logicalWidth-titleW)/2, 80)
-	text.Draw(screen, title, basicfont.Face7x13, (logicalWidth/2)-(len(title)*7), 100, hexToColor(colAccent))
+	text.Draw(screen, title, basicfont.Face7x13, (logicalWidth-len(title)*7)/2, 150, hexToColor(colAccent))
+
+	barW := 300
+	x := (logicalWidth - barW) / 2
+	ebitenutil.DrawRect(screen, float64(x), 175, float64(barW), 8, hexToColor(colGlass))
+	ebitenutil.DrawRect(screen, float64(x), 175, float64(barW)*p.Fraction(), 8, hexToColor(colAccent))
+	text.Draw(screen, splashStatus(p), basicfont.Face7x13, x, 205, hexToColor(colTextMuted))
+
+	y := 230
+	for _, e := range p.Errors {
+		text.Draw(screen, truncate(e, 90), basicfont.Face7x13, (logicalWidth-640)/2, y, hexToColor(colDanger))
+		y += 18
+	}
+	if p.Finished && len(p.Errors) > 0 {
+		left := int((splashErrorHold - time.Since(g.splashDoneAt)).Seconds()) + 1
+		g.addButton(logicalWidth/2-70, y+10, 140, 30, fmt.Sprintf("CONTINUE (%d)", max(left, 1)), func() { g.state = StateLogin }, hexToColor(colAccent))
+	}
 
-	if g.showDeleteConfirm {
-		// Confirmation Dialog
-		ebitenutil.DrawRect(screen, float64(logicalWidth/2-150), 200, 300, 150, hexToColor(colGlass))
-		text.Draw(screen, fmt.Sprintf("Delete user '%s'?", g.userToDelete), basicfont.Face7x13, logicalWidth/2-130, 240, color.White)
-		text.Draw(screen, "This cannot be undone.", basicfont.Face7x13, logicalWidth/2-130, 260, hexToColor(colDanger))
+	for _, b := range g.buttons {
+		ebitenutil.DrawRect(screen, float64(b.X), float64(b.Y), float64(b.W), float64(b.H), b.Color)
+		tW := len(b.Text) * 7
+		text.Draw(screen, b.Text, basicfont.Face7x13, b.X+(b.W-tW)/2, b.Y+b.H/2+4, b.TextColor)
+	}
+}
+
+// drawReleaseNotes shows the newer release the update checker found, for
+// the owner to download by hand
+func (g *Game) drawReleaseNotes(screen *ebiten.Image, rel *core.Release) {
+	panelX, panelY, panelW, panelH := logicalWidth/2-250, 130, 500, 290
+	ebitenutil.DrawRect(screen, float64(panelX), float64(panelY), float64(panelW), float64(panelH), hexToColor(colGlass))
+
+	name := rel.Name
+	if name == "" {
+		name = rel.Tag
+	}
+	text.Draw(screen, "Update available: "+truncate(name, 50), basicfont.Face7x13, panelX+20, panelY+25, hexToColor(colGold))
+	if !rel.Published.IsZero() {
+		text.Draw(screen, "Released "+rel.Published.Local().Format("2 Jan 2006"), basicfont.Face7x13, panelX+20, panelY+43, hexToColor(colTextMuted))
+	}
+	y := panelY + 70
+	for _, line := range rel.NoteLines((panelW-40)/7, 10) {
+		text.Draw(screen, truncate(line, (panelW-40)/7), basicfont.Face7x13, panelX+20, y, hexToColor(colText))
+		y += 16
+	}
+	text.Draw(screen, truncate(rel.URL, (panelW-40)/7), basicfont.Face7x13, panelX+20, panelY+panelH-50, hexToColor(colAccent))
+
+	g.addButton(panelX+panelW-120, panelY+panelH-40, 100, 30, "CLOSE", func() { g.showReleaseNotes = false }, hexToColor(colGlassLight))
+}
+
+func (g *Game) drawLogin(screen *ebiten.Image) {
+	g.buttons = g.buttons[:0]
+
+	title := "VANTAA FLIGHTRADAR24"
+	titleW := len(title) * 7 * 2 // approx scale 2
+	// Use scale for title
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(2, 2)
+	op.GeoM.Translate(float64(logicalWidth-titleW)/2, 80)
+	text.Draw(screen, title, basicfont.Face7x13, (logicalWidth/2)-(len(title)*7), 100, hexToColor(colAccent))
+
+	rel := g.updates.Available()
+	if rel != nil {
+		g.addButton(logicalWidth-150, 10, 140, 24, "UPDATE "+truncate(rel.Tag, 10), func() {
+			g.showReleaseNotes = !g.showReleaseNotes
+		}, hexToColor(colGold), hexToColor(colBgDark))
+	}
+
+	if rel != nil && g.showReleaseNotes {
+		g.drawReleaseNotes(screen, rel)
+	} else if g.showDeleteConfirm {
+		// Confirmation Dialog
+		ebitenutil.DrawRect(screen, float64(logicalWidth/2-150), 200, 300, 150, hexToColor(colGlass))
+		text.Draw(screen, fmt.Sprintf("Delete user '%s'?", g.userToDelete), basicfont.Face7x13, logicalWidth/2-130, 240, color.White)
+		text.Draw(screen, "This cannot be undone.", basicfont.Face7x13, logicalWidth/2-130, 260, hexToColor(colDanger))
 
 		g.addButton(logicalWidth/2-110, 290, 100, 30, "CANCEL", func() {
 			g.showDeleteConfirm = false
@@ -586,68 +1496,20 @@ func (g *Game) drawLogin(screen *ebiten.Image) {
 
 		// Render Keyboard if Open
 		if g.isKeyboardOpen {
-			// Standard QWERTY: max 10 cols. 50px/key -> 500px wide.
-			kbW := 520
-			kbH := 250
-			kbY := 225
-			kbX := (logicalWidth - kbW) / 2
-
-			// Background
-			ebitenutil.DrawRect(screen, float64(kbX-10), float64(kbY-10), float64(kbW+20), float64(kbH+20), hexToColor(colBgDark))
-
-			for rowIdx, row := range g.keyboardLayout {
-				// Center each row
-				rowLen := len(row)
-				rowWidth := rowLen * 50
-				rowStart := kbX + (kbW-rowWidth)/2
-
-				for colIdx, char := range row {
-					charStr := string(char)
-					btnX := rowStart + colIdx*50
-					btnY := kbY + rowIdx*50
-
-					g.addButton(btnX, btnY, 45, 45, charStr, func() {
-						g.inputText += charStr
-					}, hexToColor(colGlassLight))
-				}
-			}
-
-			// Bottom Row Controls
-			ctrlY := kbY + 3*50 + 10
-
-			// HIDE (Left)
-			g.addButton(kbX, ctrlY, 100, 45, "HIDE", func() {
-				g.isKeyboardOpen = false
-			}, hexToColor(colGlass))
-
-			// DEL (Center-ish)
-			g.addButton(kbX+120, ctrlY, 100, 45, "DEL", func() {
-				if len(g.inputText) > 0 {
-					g.inputText = g.inputText[:len(g.inputText)-1]
-				}
-			}, hexToColor(colDanger))
-
-			// ENTER (Right)
-			g.addButton(kbX+kbW-120, ctrlY, 120, 45, "ENTER", func() {
+			g.drawKeyboard(screen, func() {
 				// Enter acts as Login if text exists, else just closes
 				if len(g.inputText) > 0 {
 					g.login(g.inputText)
 				} else {
 					g.isKeyboardOpen = false
 				}
-			}, hexToColor(colSuccess))
+			})
 
 		} else {
 			// User List (Only show if keyboard is closed)
 			y := 240
-			keys := make([]string, 0, len(g.usersMap))
-			for k := range g.usersMap {
-				keys = append(keys, k)
-			}
-			sort.Strings(keys)
-
-			for _, name := range keys {
-				u := g.usersMap[name]
+			for _, name := range g.users.Names() {
+				u, _ := g.users.Get(name)
 				label := fmt.Sprintf("%s (Best: %d)", u.Name, u.BestScore)
 				// Capture loop var
 				n := name
@@ -666,6 +1528,9 @@ func (g *Game) drawLogin(screen *ebiten.Image) {
 		}
 	}
 
+	version := core.Build().String()
+	text.Draw(screen, version, basicfont.Face7x13, logicalWidth-len(version)*7-10, logicalHeight-20, hexToColor(colTextMuted))
+
 	// Add a bottom-left EXIT button on the login screen
 	g.addButton(20, logicalHeight-50, 100, 30, "QUIT", func() {
 		g.shouldQuit = true
@@ -679,6 +1544,90 @@ func (g *Game) drawLogin(screen *ebiten.Image) {
 	}
 }
 
+// drawReplayEntry asks for a share code to replay another player's game
+func (g *Game) drawReplayEntry(screen *ebiten.Image) {
+	g.buttons = g.buttons[:0]
+
+	text.Draw(screen, "Enter a share code to play the same flights:", basicfont.Face7x13, logicalWidth/2-155, 130, color.White)
+	ebitenutil.DrawRect(screen, float64(logicalWidth/2-100), 150, 200, 30, color.White)
+	text.Draw(screen, g.inputText, basicfont.Face7x13, logicalWidth/2-95, 170, color.Black)
+	if g.replayError != "" {
+		text.Draw(screen, g.replayError, basicfont.Face7x13, logicalWidth/2-95, 200, hexToColor(colDanger))
+	}
+	g.addButton(logicalWidth/2-100, 150, 200, 30, "", func() {
+		g.isKeyboardOpen = !g.isKeyboardOpen
+	}, color.Transparent)
+
+	if g.isKeyboardOpen {
+		g.drawKeyboard(screen, func() { g.submitReplayCode(g.inputText) })
+	}
+
+	g.addButton(20, logicalHeight-50, 100, 30, "BACK", func() {
+		g.isKeyboardOpen = false
+		g.state = StateMap
+	}, hexToColor(colDanger))
+
+	for _, b := range g.buttons {
+		ebitenutil.DrawRect(screen, float64(b.X), float64(b.Y), float64(b.W), float64(b.H), b.Color)
+		tW := len(b.Text) * 7
+		text.Draw(screen, b.Text, basicfont.Face7x13, b.X+(b.W-tW)/2, b.Y+b.H/2+4, b.TextColor)
+	}
+}
+
+// drawKeyboard renders the on-screen keyboard that types into g.inputText
+func (g *Game) drawKeyboard(screen *ebiten.Image, onEnter func()) {
+	// Standard QWERTY: max 10 cols. 50px/key -> 500px wide.
+	kbW := 520
+	kbH := 250
+	kbY := 225
+	kbX := (logicalWidth - kbW) / 2
+
+	// Background
+	ebitenutil.DrawRect(screen, float64(kbX-10), float64(kbY-10), float64(kbW+20), float64(kbH+20), hexToColor(colBgDark))
+
+	for rowIdx, row := range g.keyboardLayout {
+		// Center each row
+		rowLen := len(row)
+		rowWidth := rowLen * 50
+		rowStart := kbX + (kbW-rowWidth)/2
+
+		for colIdx, char := range row {
+			charStr := string(char)
+			btnX := rowStart + colIdx*50
+			btnY := kbY + rowIdx*50
+
+			g.addButton(btnX, btnY, 45, 45, charStr, func() {
+				g.inputText += charStr
+			}, hexToColor(colGlassLight))
+		}
+	}
+
+	// Bottom Row Controls
+	ctrlY := kbY + 3*50 + 10
+
+	// HIDE (Left)
+	g.addButton(kbX, ctrlY, 100, 45, "HIDE", func() {
+		g.isKeyboardOpen = false
+	}, hexToColor(colGlass))
+
+	// DEL (Center-ish)
+	g.addButton(kbX+120, ctrlY, 100, 45, "DEL", func() {
+		if len(g.inputText) > 0 {
+			g.inputText = g.inputText[:len(g.inputText)-1]
+		}
+	}, hexToColor(colDanger))
+
+	// SPACE, for diary notes; names and share codes have none
+	if g.state == StateDiary {
+		g.addButton(kbX+240, ctrlY, 140, 45, "SPACE", func() {
+			g.inputText += " "
+		}, hexToColor(colGlassLight))
+	}
+
+	// ENTER (Right)
+	g.addButton(kbX+kbW-120, ctrlY, 120, 45, "ENTER", onEnter, hexToColor(colSuccess))
+}
+
 func (g *Game) drawLeaderboard(screen *ebiten.Image) {
 	g.buttons = g.buttons[:0]
 
@@ -688,10 +1637,14 @@ func (g *Game) drawLeaderboard(screen *ebiten.Image) {
 	text.Draw(screen, "TOP SCORES", basicfont.Face7x13, 50, 70, color.White)
 	y := 100
 	for i, s := range g.highScores {
-		line := fmt.Sprintf("%d. %s - %d", i+1, s.Name, s.Score)
+		line := fmt.Sprintf("%d. %s - %d  %s", i+1, s.Name, s.Score, s.Day())
 		text.Draw(screen, line, basicfont.Face7x13, 50, y, color.White)
 		y += 25
 	}
+	if t := g.todayStats; t.Games > 0 {
+		line := fmt.Sprintf("Today: %d games, best %s - %d", t.Games, t.Best.Name, t.Best.Score)
+		text.Draw(screen, line, basicfont.Face7x13, 50, y+10, hexToColor(colGold))
+	}
 
 	// User Stats Column
 	text.Draw(screen, "PLAYER STATS", basicfont.Face7x13, 400, 70, color.White)
@@ -715,182 +1668,1178 @@ func (g *Game) drawLeaderboard(screen *ebiten.Image) {
 	}
 }
 
-func (g *Game) drawMap(screen *ebiten.Image) {
-	centerX, centerY := LatLonToPixels(g.camLat, g.camLon, g.camZoom)
-	screenCX, screenCY := float64(logicalWidth)/2, float64(logicalHeight)/2
-	minWX := centerX - screenCX
-	minWY := centerY - screenCY
-
-	minTileX := int(math.Floor(minWX / tileSize))
-	maxTileX := int(math.Floor((centerX + screenCX) / tileSize))
-	minTileY := int(math.Floor(minWY / tileSize))
-	maxTileY := int(math.Floor((centerY + screenCY) / tileSize))
+func (g *Game) drawSettings(screen *ebiten.Image) {
+	g.buttons = g.buttons[:0]
+	s := g.settings.Get()
 
-	maxIndex := int(math.Pow(2, float64(g.camZoom))) - 1
+	text.Draw(screen, "SETTINGS", basicfont.Face7x13, 20, 30, hexToColor(colAccent))
 
-	for x := minTileX; x <= maxTileX; x++ {
-		for y := minTileY; y <= maxTileY; y++ {
-			tileX := x
-			for tileX < 0 {
-				tileX += maxIndex + 1
-			}
-			for tileX > maxIndex {
-				tileX -= maxIndex + 1
-			}
+	row := func(y int, label, value string, step func(dir int)) {
+		text.Draw(screen, label, basicfont.Face7x13, 50, y+19, color.White)
+		g.addButton(250, y, 30, 30, "-", func() { step(-1) }, hexToColor(colGlassLight))
+		text.Draw(screen, value, basicfont.Face7x13, 300, y+19, color.White)
+		g.addButton(420, y, 30, 30, "+", func() { step(1) }, hexToColor(colGlassLight))
+	}
+	row(70, "Poll interval", s.PollIntervalLabel(g.provider), func(dir int) {
+		g.updateSettings(func(s *core.Settings) {
+			s.PollIntervalSec = core.StepSetting(core.PollIntervalSteps, s.PollIntervalSec, dir)
+		})
+	})
+	row(120, "Search radius", fmt.Sprintf("%.0f km", s.RadiusKm), func(dir int) {
+		g.updateSettings(func(s *core.Settings) { s.RadiusKm = core.StepSetting(core.RadiusStepsKm, s.RadiusKm, dir) })
+	})
 
-			if y < 0 || y > maxIndex {
-				continue
-			}
+	row(170, "Min altitude", fmt.Sprintf("%d ft", s.Filter.MinAltitudeFt), func(dir int) {
+		g.updateSettings(func(s *core.Settings) {
+			s.Filter.MinAltitudeFt = core.StepSetting(core.MinAltitudeSteps, s.Filter.MinAltitudeFt, dir)
+		})
+	})
+	ground := "SHOWN"
+	if s.Filter.ExcludeOnGround {
+		ground = "HIDDEN"
+	}
+	text.Draw(screen, "On-ground aircraft", basicfont.Face7x13, 50, 239, color.White)
+	g.addButton(250, 220, 200, 30, ground, func() {
+		g.updateSettings(func(s *core.Settings) { s.Filter.ExcludeOnGround = !s.Filter.ExcludeOnGround })
+	}, hexToColor(colGlassLight))
+
+	interesting := "OFF"
+	if s.AlertInteresting {
+		interesting = "ON"
+	}
+	text.Draw(screen, "Interesting alerts", basicfont.Face7x13, 50, 339, color.White)
+	g.addButton(250, 320, 200, 30, interesting, func() {
+		g.updateSettings(func(s *core.Settings) { s.AlertInteresting = !s.AlertInteresting })
+	}, hexToColor(colGlassLight))
+
+	planeColors := "PLAIN"
+	if s.AirlineColors {
+		planeColors = "BY AIRLINE"
+	}
+	text.Draw(screen, "Plane colours", basicfont.Face7x13, 50, 389, color.White)
+	g.addButton(250, 370, 200, 30, planeColors, func() {
+		g.updateSettings(func(s *core.Settings) { s.AirlineColors = !s.AirlineColors })
+	}, hexToColor(colGlassLight))
+
+	bearings := "TRUE NORTH"
+	if s.MagneticBearings {
+		bearings = "MAGNETIC"
+	}
+	text.Draw(screen, "Bearings", basicfont.Face7x13, 50, 289, color.White)
+	g.addButton(250, 270, 200, 30, bearings, g.toggleMagneticBearings, hexToColor(colGlassLight))
 
-			img := g.tileLoader.GetTile(g.camZoom, tileX, y)
-			if img != nil {
-				screenX := float64(x*tileSize) - minWX
-				screenY := float64(y*tileSize) - minWY
+	bonus := "OFF"
+	if s.ArrivalBonus {
+		bonus = "ON"
+	}
+	text.Draw(screen, "Arrival bonus round", basicfont.Face7x13, 490, 89, color.White)
+	g.addButton(640, 70, 150, 30, bonus, func() {
+		g.updateSettings(func(s *core.Settings) { s.ArrivalBonus = !s.ArrivalBonus })
+	}, hexToColor(colGlassLight))
+
+	text.Draw(screen, "Home airport", basicfont.Face7x13, 490, 139, color.White)
+	stepHome := func(dir int) {
+		g.updateSettings(func(s *core.Settings) {
+			s.HomeAirport = core.StepChoice(core.HomeAirportChoices(g.airportDB, myLat, myLon, s.HomeAirport), s.HomeAirport, dir)
+		})
+		g.applyHomeAirport()
+	}
+	g.addButton(640, 120, 30, 30, "-", func() { stepHome(-1) }, hexToColor(colGlassLight))
+	text.Draw(screen, core.HomeAirportLabel(s.HomeAirport), basicfont.Face7x13, 678, 139, color.White)
+	g.addButton(760, 120, 30, 30, "+", func() { stepHome(1) }, hexToColor(colGlassLight))
+
+	// The alert filter applies on top of the map filters on the left
+	text.Draw(screen, "Alert altitude", basicfont.Face7x13, 490, 189, color.White)
+	stepCeiling := func(dir int) {
+		g.updateSettings(func(s *core.Settings) {
+			s.AlertFilter.MaxAltitudeFt = core.StepSetting(core.AlertAltitudeSteps, s.AlertFilter.MaxAltitudeFt, dir)
+		})
+	}
+	g.addButton(640, 170, 30, 30, "-", func() { stepCeiling(-1) }, hexToColor(colGlassLight))
+	text.Draw(screen, s.AlertCeilingLabel(), basicfont.Face7x13, 678, 189, color.White)
+	g.addButton(760, 170, 30, 30, "+", func() { stepCeiling(1) }, hexToColor(colGlassLight))
+
+	text.Draw(screen, "Power saving", basicfont.Face7x13, 490, 289, color.White)
+	g.addButton(640, 270, 150, 30, s.EcoMode.Label(g.power.OnBattery()), func() {
+		g.updateSettings(func(s *core.Settings) { s.EcoMode = s.EcoMode.Next() })
+		g.prefetched = core.TileCoord{}
+	}, hexToColor(colGlassLight))
+
+	text.Draw(screen, "Alert on", basicfont.Face7x13, 490, 239, color.White)
+	g.addButton(640, 220, 150, 30, core.CategoryPresetName(core.AlertCategoryPresets, s.AlertFilter.Categories), func() {
+		g.updateSettings(func(s *core.Settings) {
+			s.AlertFilter.Categories = core.NextCategoryPreset(core.AlertCategoryPresets, s.AlertFilter.Categories)
+		})
+	}, hexToColor(colGlassLight))
 
-				// REUSE the op object instead of creating new
-				g.op.GeoM.Reset()
-				g.op.ColorScale.Reset()
-				g.op.Filter = ebiten.FilterNearest // Explicitly use nearest for speed
+	g.addButton(20, logicalHeight-50, 100, 30, "BACK", func() { g.state = StateMap }, hexToColor(colDanger))
+	g.addButton(130, logicalHeight-50, 100, 30, "STATUS", g.openStatus, hexToColor(colGlassLight))
+	g.addButton(240, logicalHeight-50, 100, 30, "NOISE", g.openNoise, hexToColor(colGlassLight))
+	g.addButton(350, logicalHeight-50, 100, 30, "REGULARS", g.openRegulars, hexToColor(colGlassLight))
+	g.addButton(460, logicalHeight-50, 100, 30, "DIARY", g.openDiary, hexToColor(colGlassLight))
+	g.addButton(570, logicalHeight-50, 100, 30, "STORAGE", g.openStorage, hexToColor(colGlassLight))
 
-				g.op.GeoM.Translate(screenX, screenY)
-				screen.DrawImage(img, g.op)
+	for _, b := range g.buttons {
+		ebitenutil.DrawRect(screen, float64(b.X), float64(b.Y), float64(b.W), float64(b.H), b.Color)
+		tW := len(b.Text) * 7
+		text.Draw(screen, b.Text, basicfont.Face7x13, b.X+(b.W-tW)/2, b.Y+b.H/2+4, b.TextColor)
+	}
+}
 
-				// op := &ebiten.DrawImageOptions{}
-				// op.GeoM.Translate(screenX, screenY)
-				// screen.DrawImage(img, op)
-			}
-		}
+// openStatus takes a fresh health report and shows it. The report walks the
+// data dir, so it is taken here rather than every frame.
+func (g *Game) openStatus() {
+	r := core.CheckHealth(g.provider, &g.fetchHealth)
+	r.Caches = []core.CacheSize{
+		{Name: "Flights tracked", Entries: g.flights.Len()},
+		{Name: "Map tiles", Entries: g.tileLoader.Len()},
+		{Name: "Tile queue", Entries: g.tileLoader.Fetching()},
+		{Name: "Aircraft DB", Entries: g.aircraft.Len()},
+		{Name: "Tag DB", Entries: g.tags.Len()},
+		{Name: "Aircraft photos", Entries: g.photos.Len()},
+		{Name: "Airport DB", Entries: g.airportDB.Len()},
+	}
+	r.Startup = g.startup.Progress().Errors
+	r.Update = g.updates.Available()
+	r.Backup = g.backup.Status()
+	if results, err := g.dataManager.DistractorResults(); err != nil {
+		log.Println("Error reading distractor results:", err)
+	} else {
+		r.Experiments = results
 	}
+	g.health = r
+	g.state = StateStatus
 }
 
-func (g *Game) drawHomeMarker(screen *ebiten.Image) {
-	centerX, centerY := LatLonToPixels(g.camLat, g.camLon, g.camZoom)
-	screenCX, screenCY := float64(logicalWidth)/2, float64(logicalHeight)/2
-	minWX := centerX - screenCX
-	minWY := centerY - screenCY
+// drawStatus lists the health report, problems in red: the first place to
+// look when no planes are showing
+func (g *Game) drawStatus(screen *ebiten.Image) {
+	g.buttons = g.buttons[:0]
 
-	hX, hY := LatLonToPixels(myLat, myLon, g.camZoom)
-	sX := hX - minWX
-	sY := hY - minWY
+	text.Draw(screen, "STATUS", basicfont.Face7x13, 20, 30, hexToColor(colAccent))
+	text.Draw(screen, "as of "+g.health.CheckedAt.Format("15:04:05"), basicfont.Face7x13, 90, 30, hexToColor(colTextMuted))
 
-	if sX >= 0 && sX <= float64(logicalWidth) && sY >= 0 && sY <= float64(logicalHeight) {
-		// Draw a dot (6x6 square)
-		size := 6.0
-		ebitenutil.DrawRect(screen, sX-size/2, sY-size/2, size, size, hexToColor(colAccent))
+	y := 60
+	for _, l := range g.health.Lines() {
+		valueCol := hexToColor(colText)
+		if l.Problem {
+			valueCol = hexToColor(colDanger)
+		}
+		text.Draw(screen, l.Label, basicfont.Face7x13, 50, y, hexToColor(colTextMuted))
+		text.Draw(screen, truncate(l.Value, (logicalWidth-220)/7), basicfont.Face7x13, 200, y, valueCol)
+		y += 20
 	}
-}
 
-func (g *Game) drawPlanes(screen *ebiten.Image) {
-	centerX, centerY := LatLonToPixels(g.camLat, g.camLon, g.camZoom)
-	screenCX, screenCY := float64(logicalWidth)/2, float64(logicalHeight)/2
-	minWX := centerX - screenCX
-	minWY := centerY - screenCY
+	g.addButton(20, logicalHeight-50, 100, 30, "BACK", func() { g.state = StateSettings }, hexToColor(colDanger))
+	g.addButton(130, logicalHeight-50, 100, 30, "REFRESH", g.openStatus, hexToColor(colGlassLight))
+	g.addButton(240, logicalHeight-50, 100, 30, "RESOLVERS", func() { g.state = StateResolvers }, hexToColor(colGlassLight))
+	g.addButton(350, logicalHeight-50, 100, 30, "CALIBRATE", g.openCalibration, hexToColor(colGlassLight))
 
-	for _, f := range g.flights {
-		fX, fY := LatLonToPixels(f.Lat, f.Lon, g.camZoom)
-		sX := fX - minWX
-		sY := fY - minWY
+	for _, b := range g.buttons {
+		ebitenutil.DrawRect(screen, float64(b.X), float64(b.Y), float64(b.W), float64(b.H), b.Color)
+		tW := len(b.Text) * 7
+		text.Draw(screen, b.Text, basicfont.Face7x13, b.X+(b.W-tW)/2, b.Y+b.H/2+4, b.TextColor)
+	}
+}
 
-		if sX < -50 || sX > float64(logicalWidth)+50 || sY < -50 || sY > float64(logicalHeight)+50 {
-			continue
-		}
+// drawResolvers is the debug view of the route resolver chain: how each
+// resolver is doing and which are demoted for failing
+func (g *Game) drawResolvers(screen *ebiten.Image) {
+	g.buttons = g.buttons[:0]
+	now := time.Now()
 
-		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Translate(-16, -16)
-		op.GeoM.Rotate(f.Heading * math.Pi / 180.0)
-		op.GeoM.Translate(sX, sY)
+	text.Draw(screen, "RESOLVERS", basicfont.Face7x13, 20, 30, hexToColor(colAccent))
+	text.Draw(screen, "tried in this order; one that keeps failing moves to the back for a while", basicfont.Face7x13, 100, 30, hexToColor(colTextMuted))
 
-		// Highlight target
-		if g.state == StateGamePlaying && g.targetPlane != nil && f.Icao24 == g.targetPlane.Icao24 {
-			op.ColorScale.Scale(1, 0.8, 0.2, 1) // Orange tint
+	stats := core.ResolverHealth(g.resolver)
+	if stats == nil {
+		text.Draw(screen, "Lookups are answered by the "+g.provider.Name()+" provider itself", basicfont.Face7x13, 50, 70, hexToColor(colTextMuted))
+	}
+	y := 70
+	for _, s := range stats {
+		status, col := "ok", hexToColor(colSuccess)
+		switch {
+		case s.IsDemoted(now):
+			status, col = "demoted until "+s.Demoted.Local().Format("15:04"), hexToColor(colDanger)
+		case s.Streak > 0:
+			status, col = fmt.Sprintf("%d failures in a row", s.Streak), hexToColor(colGold)
+		case s.Attempts == 0:
+			status, col = "not asked yet", hexToColor(colTextMuted)
+		}
+		text.Draw(screen, s.Name, basicfont.Face7x13, 50, y, color.White)
+		text.Draw(screen, status, basicfont.Face7x13, 200, y, col)
+		y += 18
+		text.Draw(screen, s.String(), basicfont.Face7x13, 70, y, hexToColor(colTextMuted))
+		if s.LastError != "" {
+			y += 18
+			text.Draw(screen, truncate("last error: "+s.LastError, (logicalWidth-90)/7), basicfont.Face7x13, 70, y, hexToColor(colTextMuted))
 		}
+		y += 30
+	}
+	if stats != nil {
+		trusted, hits := g.learned.Stats(now)
+		text.Draw(screen, "Learned routes", basicfont.Face7x13, 50, y, color.White)
+		text.Draw(screen, fmt.Sprintf("%d callsigns known, %d lookups answered", trusted, hits), basicfont.Face7x13, 200, y, hexToColor(colTextMuted))
+	}
 
-		screen.DrawImage(g.planeImg, op)
+	g.addButton(20, logicalHeight-50, 100, 30, "BACK", func() { g.state = StateStatus }, hexToColor(colDanger))
 
-		// Label
-		text.Draw(screen, f.Callsign, basicfont.Face7x13, int(sX)+20, int(sY), color.White)
+	for _, b := range g.buttons {
+		ebitenutil.DrawRect(screen, float64(b.X), float64(b.Y), float64(b.W), float64(b.H), b.Color)
+		tW := len(b.Text) * 7
+		text.Draw(screen, b.Text, basicfont.Face7x13, b.X+(b.W-tW)/2, b.Y+b.H/2+4, b.TextColor)
 	}
 }
 
-func (g *Game) drawUI(screen *ebiten.Image) {
-	g.buttons = g.buttons[:0] // Reset buttons from previous frame
+// openCalibration starts a touch calibration run: the player taps five
+// crosses and the correction is saved once they are all in
+func (g *Game) openCalibration() {
+	g.calibrator = core.NewTouchCalibrator(logicalWidth, logicalHeight)
+	g.calibrationMsg = ""
+	g.state = StateCalibrate
+}
 
-	// Top Bar: User info
-	if g.state == StateMap {
-		text.Draw(screen, fmt.Sprintf("User: %s (Best: %d)", g.currentUser.Name, g.currentUser.BestScore), basicfont.Face7x13, 10, 20, hexToColor(colAccent))
-		g.addButton(logicalWidth-110, 10, 100, 30, "LEADERBOARD", func() {
-			g.refreshLeaderboard()
-			g.state = StateLeaderboard
-		}, hexToColor(colGlass))
-		g.addButton(logicalWidth-220, 10, 100, 30, "LOGOUT", func() { g.state = StateLogin; g.inputText = "" }, hexToColor(colDanger))
+// updateCalibration takes a tap on each target, uncorrected, then fits and
+// saves the correction. It reports whether it used the input, which is
+// until every target is tapped; the result's buttons are ordinary ones.
+func (g *Game) updateCalibration() bool {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.state = StateStatus
+		return true
+	}
+	if g.calibrator.Done() {
+		return false
+	}
+	if !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && len(inpututil.JustPressedTouchIDs()) == 0 {
+		return true
+	}
+	x, y := rawCursorPosition()
+	g.calibrator.Tap(float64(x), float64(y))
+	if !g.calibrator.Done() {
+		return true
 	}
 
-	// DEBUG: Show Touch Count in UI (Top Left under User)
-	touchCount := len(ebiten.AppendTouchIDs(nil))
-	if touchCount > 0 {
-		text.Draw(screen, fmt.Sprintf("Touches: %d", touchCount), basicfont.Face7x13, 10, 40, color.White)
+	cal, err := g.calibrator.Fit(g.screenXf)
+	if err != nil {
+		g.calibrationMsg = err.Error()
+		g.calibrator.Restart()
+		return true
+	}
+	g.touchCal = cal
+	g.calibrationMsg = "Saved. Check that DONE lines up with your tap."
+	if err := g.dataManager.SaveTouchCalibration(cal); err != nil {
+		log.Println("Error saving touch calibration:", err)
+		g.calibrationMsg = "Applied, but couldn't be saved: " + err.Error()
 	}
+	return true
+}
 
-	// Sidebar (Right) - Plane Info
-	if g.selectedPlane != nil {
-		// Reduced width from 300 to 220, and adjusted X position
-		panelW := 220
-		panelX := logicalWidth - panelW - 10
-		g.drawPanel(screen, panelX, 90, panelW, 350, "FLIGHT INFO")
+// drawCalibration shows the target to tap next, or the result
+func (g *Game) drawCalibration(screen *ebiten.Image) {
+	g.buttons = g.buttons[:0]
 
-		// Content
-		p := g.selectedPlane
-		y := 140
-		textW := panelX + 20
-		text.Draw(screen, p.Callsign, basicfont.Face7x13, textW, y, hexToColor(colAccent))
-		y += 30
-		text.Draw(screen, fmt.Sprintf("Alt: %d ft", p.AltitudeFt), basicfont.Face7x13, textW, y, color.White)
-		y += 20
-		text.Draw(screen, fmt.Sprintf("Spd: %d kts", p.VelocityKts), basicfont.Face7x13, textW, y, color.White)
-		y += 20
-		text.Draw(screen, fmt.Sprintf("Lat/Lon: %.2f, %.2f", p.Lat, p.Lon), basicfont.Face7x13, textW, y, color.White)
+	text.Draw(screen, "TOUCH CALIBRATION", basicfont.Face7x13, 20, 30, hexToColor(colAccent))
+	if x, y, n, ok := g.calibrator.Target(); ok {
+		msg := fmt.Sprintf("Tap the centre of the cross (%d of %d). Esc cancels.", n, g.calibrator.Targets())
+		text.Draw(screen, msg, basicfont.Face7x13, 160, 30, hexToColor(colTextMuted))
+		fx, fy := float32(x), float32(y)
+		col := hexToColor(colGold)
+		vector.StrokeLine(screen, fx-15, fy, fx+15, fy, 2, col, true)
+		vector.StrokeLine(screen, fx, fy-15, fx, fy+15, 2, col, true)
+		vector.StrokeCircle(screen, fx, fy, 8, 1.5, col, true)
+	} else {
+		g.addButton(logicalWidth/2-110, logicalHeight/2-15, 100, 30, "DONE", func() { g.state = StateStatus }, hexToColor(colSuccess))
+		g.addButton(logicalWidth/2+10, logicalHeight/2-15, 100, 30, "AGAIN", g.openCalibration, hexToColor(colGlassLight))
+	}
+	if g.calibrationMsg != "" {
+		tW := len(g.calibrationMsg) * 7
+		text.Draw(screen, g.calibrationMsg, basicfont.Face7x13, (logicalWidth-tW)/2, logicalHeight/2+40, color.White)
+	}
 
-		y += 30
-		// Extended Details
-		if g.resolving {
-			text.Draw(screen, "Fetching details...", basicfont.Face7x13, textW, y, hexToColor(colTextMuted))
-		} else if g.resolvedDetails != nil {
-			text.Draw(screen, "Model: "+truncate(g.resolvedDetails.Model, 25), basicfont.Face7x13, textW, y, color.White)
+	for _, b := range g.buttons {
+		ebitenutil.DrawRect(screen, float64(b.X), float64(b.Y), float64(b.W), float64(b.H), b.Color)
+		tW := len(b.Text) * 7
+		text.Draw(screen, b.Text, basicfont.Face7x13, b.X+(b.W-tW)/2, b.Y+b.H/2+4, b.TextColor)
+	}
+}
 
-			// Masking logic: If we are playing and this is the target, hide the answer
-			showOrigin := g.resolvedDetails.Origin
-			showDest := g.resolvedDetails.RealDestination
+// openNoise summarises the noise log for the chart
+func (g *Game) openNoise() {
+	now := time.Now()
+	events, err := g.dataManager.LoadNoiseEvents(core.NoiseSince(now, noiseChartDays))
+	if err != nil {
+		log.Println("Error loading noise events:", err)
+	}
+	g.noiseStats = core.SummarizeNoise(events, now, noiseChartDays)
+	g.noiseMsg = ""
+	g.state = StateNoise
+}
 
-			if g.state == StateGamePlaying && g.targetPlane != nil && g.selectedPlane.Icao24 == g.targetPlane.Icao24 {
-				// Hide answer based on question type
-				// If correct option matches one of these, hide it
-				if g.correctOption == g.resolvedDetails.Origin {
-					showOrigin = "???"
-				}
-				if g.correctOption == g.resolvedDetails.RealDestination {
-					showDest = "???"
-				}
-			}
+// exportNoise writes the whole noise log to a CSV in the captures folder
+func (g *Game) exportNoise() {
+	events, err := g.dataManager.LoadNoiseEvents(time.Time{})
+	path := ""
+	if err == nil {
+		path, err = g.dataManager.ExportNoiseCSV(events, time.Now())
+	}
+	if err != nil {
+		log.Println("Noise export failed:", err)
+		g.noiseMsg = "Export failed: " + err.Error()
+		return
+	}
+	log.Println("Saved noise events:", path)
+	g.noiseMsg = fmt.Sprintf("Saved %d events to %s", len(events), path)
+}
 
-			y += 20
-			text.Draw(screen, "Origin: "+truncate(showOrigin, 20), basicfont.Face7x13, textW, y, color.White)
-			y += 20
-			text.Draw(screen, "Dest: "+truncate(showDest, 20), basicfont.Face7x13, textW, y, color.White)
-		} else {
-			text.Draw(screen, "Details unavailable", basicfont.Face7x13, textW, y, hexToColor(colTextMuted))
-		}
+// drawNoise charts today's noise events by hour and the last two weeks by
+// day, for documenting a flight path to the authorities
+func (g *Game) drawNoise(screen *ebiten.Image) {
+	g.buttons = g.buttons[:0]
+	s := g.settings.Get()
 
-		// Close Button
-		g.addButton(logicalWidth-40, 95, 30, 30, "X", func() { g.selectedPlane = nil }, color.RGBA{255, 255, 255, 50}, color.Black)
+	text.Draw(screen, "NOISE EVENTS", basicfont.Face7x13, 20, 30, hexToColor(colAccent))
+	text.Draw(screen, fmt.Sprintf("flights below %d ft within %.0f km of home", s.NoiseCeiling(), core.NoiseRadiusKm), basicfont.Face7x13, 120, 30, hexToColor(colTextMuted))
+	if g.noise == nil {
+		text.Draw(screen, "(not counted for "+g.provider.Name()+" traffic)", basicfont.Face7x13, 120, 45, hexToColor(colDanger))
+	}
+
+	text.Draw(screen, "Ceiling", basicfont.Face7x13, 560, 30, color.White)
+	g.addButton(620, 12, 30, 30, "-", func() {
+		g.updateSettings(func(s *core.Settings) {
+			s.NoiseAltitudeFt = core.StepSetting(core.NoiseAltitudeSteps, s.NoiseCeiling(), -1)
+		})
+	}, hexToColor(colGlassLight))
+	text.Draw(screen, fmt.Sprintf("%d ft", s.NoiseCeiling()), basicfont.Face7x13, 660, 30, color.White)
+	g.addButton(720, 12, 30, 30, "+", func() {
+		g.updateSettings(func(s *core.Settings) {
+			s.NoiseAltitudeFt = core.StepSetting(core.NoiseAltitudeSteps, s.NoiseCeiling(), 1)
+		})
+	}, hexToColor(colGlassLight))
+
+	today := 0
+	for _, n := range g.noiseStats.Hours {
+		today += n
+	}
+	text.Draw(screen, fmt.Sprintf("Today by hour: %d", today), basicfont.Face7x13, 50, 80, color.White)
+	core.DrawBars(&uiRenderer{screen}, 50, 105, logicalWidth-100, 100, g.noiseStats.Hours[:], func(i int) string {
+		if i%3 != 0 {
+			return ""
+		}
+		return fmt.Sprintf("%02d", i)
+	})
+
+	days := make([]int, len(g.noiseStats.Days))
+	for i, d := range g.noiseStats.Days {
+		days[i] = d.Count
+	}
+	text.Draw(screen, fmt.Sprintf("Last %d days", len(days)), basicfont.Face7x13, 50, 250, color.White)
+	core.DrawBars(&uiRenderer{screen}, 50, 270, logicalWidth-100, 100, days, func(i int) string {
+		return g.noiseStats.Days[i].Day.Format("2 Jan")
+	})
+
+	if g.noiseMsg != "" {
+		text.Draw(screen, truncate(g.noiseMsg, (logicalWidth-40)/7), basicfont.Face7x13, 20, 415, hexToColor(colTextMuted))
+	}
+
+	g.addButton(20, logicalHeight-50, 100, 30, "BACK", func() { g.state = StateSettings }, hexToColor(colDanger))
+	g.addButton(130, logicalHeight-50, 100, 30, "EXPORT CSV", g.exportNoise, hexToColor(colGlassLight))
+
+	for _, b := range g.buttons {
+		ebitenutil.DrawRect(screen, float64(b.X), float64(b.Y), float64(b.W), float64(b.H), b.Color)
+		tW := len(b.Text) * 7
+		text.Draw(screen, b.Text, basicfont.Face7x13, b.X+(b.W-tW)/2, b.Y+b.H/2+4, b.TextColor)
+	}
+}
+
+// openRegulars works out the regulars for the list
+func (g *Game) openRegulars() {
+	g.regularsList = g.loadRegulars(time.Now())
+	g.state = StateRegulars
+}
+
+// drawRegulars lists the flights that pass at about the same time most
+// days, with the option to be told when one is early or late
+func (g *Game) drawRegulars(screen *ebiten.Image) {
+	g.buttons = g.buttons[:0]
+
+	text.Draw(screen, "REGULARS", basicfont.Face7x13, 20, 30, hexToColor(colAccent))
+	text.Draw(screen, fmt.Sprintf("flights passing near home at the same time on most of the last %d days", core.RegularsWindowDays), basicfont.Face7x13, 100, 30, hexToColor(colTextMuted))
+
+	alerts := "OFF"
+	if g.settings.Get().AlertRegulars {
+		alerts = "ON"
+	}
+	text.Draw(screen, "Early/late alerts", basicfont.Face7x13, 50, 69, color.White)
+	g.addButton(200, 50, 100, 30, alerts, func() {
+		g.updateSettings(func(s *core.Settings) { s.AlertRegulars = !s.AlertRegulars })
+	}, hexToColor(colGlassLight))
+
+	y := 110
+	if len(g.regularsList) == 0 {
+		text.Draw(screen, "None yet: regulars show up after a few days of logging", basicfont.Face7x13, 50, y, hexToColor(colTextMuted))
+	}
+	for i, r := range g.regularsList {
+		if i >= 15 {
+			text.Draw(screen, fmt.Sprintf("... and %d more", len(g.regularsList)-i), basicfont.Face7x13, 50, y, hexToColor(colTextMuted))
+			break
+		}
+		text.Draw(screen, r.UsualTime(), basicfont.Face7x13, 50, y, hexToColor(colGold))
+		text.Draw(screen, r.Callsign, basicfont.Face7x13, 100, y, color.White)
+		text.Draw(screen, fmt.Sprintf("%d days", r.Days), basicfont.Face7x13, 180, y, hexToColor(colTextMuted))
+		text.Draw(screen, truncate(r.Route, (logicalWidth-300)/7), basicfont.Face7x13, 250, y, color.White)
+		y += 20
+	}
+
+	g.addButton(20, logicalHeight-50, 100, 30, "BACK", func() { g.state = StateSettings }, hexToColor(colDanger))
+
+	for _, b := range g.buttons {
+		ebitenutil.DrawRect(screen, float64(b.X), float64(b.Y), float64(b.W), float64(b.H), b.Color)
+		tW := len(b.Text) * 7
+		text.Draw(screen, b.Text, basicfont.Face7x13, b.X+(b.W-tW)/2, b.Y+b.H/2+4, b.TextColor)
+	}
+}
+
+// Spotting diary views
+const (
+	diaryPasses     = iota // recent overhead passes to write about
+	diaryByDate            // written entries, by day
+	diaryByAircraft        // written entries, by airframe
+)
+
+const (
+	diaryPassDays = 7  // passes offered for notes
+	diaryRows     = 15 // lines per page
+)
+
+// diaryRow is one line of a diary page: a heading, or a pass with its entry
+type diaryRow struct {
+	heading string
+	entry   core.SpottingEntry
+	pass    *core.OverheadPass // in the passes view
+}
+
+// openDiary loads the diary and the recent passes for the diary screen
+func (g *Game) openDiary() {
+	var err error
+	if g.diary, err = g.dataManager.LoadSpottingDiary(); err != nil {
+		log.Println("Error loading spotting diary:", err)
+	}
+	passes, err := g.dataManager.LoadOverheadPasses(time.Now().AddDate(0, 0, -diaryPassDays))
+	if err != nil {
+		log.Println("Error loading overhead passes:", err)
+	}
+	slices.Reverse(passes) // newest first
+	g.diaryPasses = passes
+	g.diaryPage, g.diaryEdit, g.diaryMsg = 0, nil, ""
+	g.state = StateDiary
+}
+
+// diaryRows flattens the current view into lines
+func (g *Game) diaryRows() []diaryRow {
+	var rows []diaryRow
+	if g.diaryView == diaryPasses {
+		for i := range g.diaryPasses {
+			p := &g.diaryPasses[i]
+			rows = append(rows, diaryRow{entry: core.SpottingEntryFor(g.diary, *p), pass: p})
+		}
+		return rows
+	}
+	groups := core.GroupSpottingByDate(g.diary)
+	if g.diaryView == diaryByAircraft {
+		groups = core.GroupSpottingByAircraft(g.diary)
+	}
+	for _, grp := range groups {
+		rows = append(rows, diaryRow{heading: grp.Title})
+		for _, e := range grp.Entries {
+			rows = append(rows, diaryRow{entry: e})
+		}
+	}
+	return rows
+}
+
+// editDiary opens the note editor on e
+func (g *Game) editDiary(e core.SpottingEntry) {
+	g.diaryEdit = &e
+	g.inputText = e.Note
+	g.diaryMsg = ""
+	g.diaryFiles, _ = core.SpottingPhotoCandidates(core.SpottingPhotoDir())
+	g.diaryFile = 0
+}
+
+// saveDiary writes the entry being edited and goes back to the list
+func (g *Game) saveDiary() {
+	e := *g.diaryEdit
+	e.Note = g.inputText
+	if err := g.dataManager.SaveSpottingEntry(e); err != nil {
+		g.diaryMsg = "Save failed: " + err.Error()
+		return
+	}
+	g.isKeyboardOpen = false
+	view := g.diaryView
+	g.openDiary()
+	g.diaryView = view
+}
+
+// attachDiaryPhoto attaches the fetched thumbnail of the aircraft, or the
+// picked file when fromFile
+func (g *Game) attachDiaryPhoto(fromFile bool) {
+	var err error
+	if fromFile {
+		if g.diaryFile >= len(g.diaryFiles) {
+			g.diaryMsg = "No photos in " + core.SpottingPhotoDir()
+			return
+		}
+		err = g.dataManager.AttachSpottingFile(g.diaryEdit, g.diaryFiles[g.diaryFile])
+	} else {
+		photo := g.photos.Photo(core.Flight{Icao24: g.diaryEdit.Icao24})
+		if photo == nil {
+			g.diaryMsg = "No photo fetched yet; try again in a moment"
+			return
+		}
+		err = g.dataManager.AttachSpottingThumbnail(g.diaryEdit, photo)
+	}
+	if err != nil {
+		g.diaryMsg = "Photo: " + err.Error()
+		return
+	}
+	g.diaryMsg = "Photo attached"
+}
+
+// drawDiary is the spotting diary: notes and photos on overhead passes,
+// browsable by date and by aircraft
+func (g *Game) drawDiary(screen *ebiten.Image) {
+	g.buttons = g.buttons[:0]
+	if g.diaryEdit != nil {
+		g.drawDiaryEditor(screen)
+		return
+	}
+
+	text.Draw(screen, "SPOTTING DIARY", basicfont.Face7x13, 20, 30, hexToColor(colAccent))
+	for i, label := range []string{"RECENT PASSES", "BY DATE", "BY AIRCRAFT"} {
+		view := i
+		col := hexToColor(colGlassLight)
+		if g.diaryView == view {
+			col = hexToColor(colAccent)
+		}
+		g.addButton(180+i*120, 12, 110, 26, label, func() { g.diaryView, g.diaryPage = view, 0 }, col)
+	}
+
+	rows := g.diaryRows()
+	pages := max(1, (len(rows)+diaryRows-1)/diaryRows)
+	g.diaryPage = min(g.diaryPage, pages-1)
+	start := g.diaryPage * diaryRows
+	y := 70
+	if len(rows) == 0 {
+		msg := "No notes yet: write one from RECENT PASSES"
+		if g.diaryView == diaryPasses {
+			msg = fmt.Sprintf("No flights have passed overhead in the last %d days", diaryPassDays)
+		}
+		text.Draw(screen, msg, basicfont.Face7x13, 50, y, hexToColor(colTextMuted))
+	}
+	for _, r := range rows[start:min(start+diaryRows, len(rows))] {
+		if r.heading != "" {
+			text.Draw(screen, r.heading, basicfont.Face7x13, 30, y, hexToColor(colGold))
+			y += 22
+			continue
+		}
+		when := r.entry.At.Local().Format("15:04")
+		if g.diaryView != diaryByDate {
+			when = r.entry.At.Local().Format("02.01. 15:04")
+		}
+		text.Draw(screen, when, basicfont.Face7x13, 50, y, hexToColor(colTextMuted))
+		text.Draw(screen, r.entry.Callsign, basicfont.Face7x13, 150, y, color.White)
+		note := r.entry.Note
+		if r.pass != nil && note == "" {
+			note = fmt.Sprintf("%.1f km, %d ft", r.pass.DistanceKm, r.pass.AltitudeFt)
+		}
+		if r.entry.Photo != "" {
+			note = "[photo] " + note
+		}
+		text.Draw(screen, truncate(note, 60), basicfont.Face7x13, 230, y, color.White)
+		e := r.entry
+		g.addButton(logicalWidth-90, y-14, 70, 20, "NOTE", func() { g.editDiary(e) }, hexToColor(colGlassLight))
+		y += 22
+	}
+
+	g.addButton(20, logicalHeight-50, 100, 30, "BACK", func() { g.state = StateSettings }, hexToColor(colDanger))
+	if pages > 1 {
+		text.Draw(screen, fmt.Sprintf("%d/%d", g.diaryPage+1, pages), basicfont.Face7x13, logicalWidth-265, logicalHeight-31, hexToColor(colTextMuted))
+		g.addButton(logicalWidth-220, logicalHeight-50, 100, 30, "PREV", func() { g.diaryPage = max(0, g.diaryPage-1) }, hexToColor(colGlassLight))
+		g.addButton(logicalWidth-110, logicalHeight-50, 100, 30, "NEXT", func() { g.diaryPage++ }, hexToColor(colGlassLight))
+	}
+
+	for _, b := range g.buttons {
+		ebitenutil.DrawRect(screen, float64(b.X), float64(b.Y), float64(b.W), float64(b.H), b.Color)
+		tW := len(b.Text) * 7
+		text.Draw(screen, b.Text, basicfont.Face7x13, b.X+(b.W-tW)/2, b.Y+b.H/2+4, b.TextColor)
+	}
+}
+
+// drawDiaryEditor writes the note and picks the photo for one pass
+func (g *Game) drawDiaryEditor(screen *ebiten.Image) {
+	e := g.diaryEdit
+	text.Draw(screen, "NOTE: "+e.Callsign+" "+e.At.Local().Format("Mon 2 Jan 15:04"), basicfont.Face7x13, 20, 30, hexToColor(colAccent))
+
+	ebitenutil.DrawRect(screen, 20, 50, float64(logicalWidth-40), 30, color.White)
+	if len(g.inputText) > core.SpottingNoteMax {
+		g.inputText = g.inputText[:core.SpottingNoteMax]
+	}
+	shown := g.inputText
+	if fit := (logicalWidth - 50) / 7; len(shown) > fit {
+		shown = shown[len(shown)-fit:] // keep the end, where the typing is, in view
+	}
+	text.Draw(screen, shown, basicfont.Face7x13, 25, 70, color.Black)
+	g.addButton(20, 50, logicalWidth-40, 30, "", func() { g.isKeyboardOpen = !g.isKeyboardOpen }, color.Transparent)
+	text.Draw(screen, fmt.Sprintf("%d/%d", len(g.inputText), core.SpottingNoteMax), basicfont.Face7x13, logicalWidth-75, 95, hexToColor(colTextMuted))
+
+	if g.isKeyboardOpen {
+		g.drawKeyboard(screen, g.saveDiary)
+	} else {
+		if e.Photo != "" {
+			if g.diaryImgKey != e.Photo {
+				g.diaryImgKey, g.diaryImg = e.Photo, nil
+				if img, err := g.dataManager.LoadSpottingPhoto(*e); err == nil {
+					g.diaryImg = ebiten.NewImageFromImage(img)
+				}
+			}
+			if g.diaryImg != nil {
+				b := g.diaryImg.Bounds()
+				scale := math.Min(240/float64(b.Dx()), 200/float64(b.Dy()))
+				op := &ebiten.DrawImageOptions{}
+				op.GeoM.Scale(scale, scale)
+				op.GeoM.Translate(logicalWidth-260, 110)
+				op.Filter = ebiten.FilterLinear
+				screen.DrawImage(g.diaryImg, op)
+			}
+			if e.PhotoCredit != "" {
+				text.Draw(screen, truncate(e.PhotoCredit, 34), basicfont.Face7x13, logicalWidth-260, 330, hexToColor(colTextMuted))
+			}
+		}
+
+		text.Draw(screen, "Photo", basicfont.Face7x13, 20, 129, color.White)
+		g.addButton(100, 110, 200, 30, "FETCHED THUMBNAIL", func() { g.attachDiaryPhoto(false) }, hexToColor(colGlassLight))
+		if len(g.diaryFiles) > 0 {
+			name := filepath.Base(g.diaryFiles[g.diaryFile])
+			g.addButton(100, 150, 200, 30, "FILE: "+truncate(name, 20), func() { g.diaryFile = (g.diaryFile + 1) % len(g.diaryFiles) }, hexToColor(colGlassLight))
+			g.addButton(310, 150, 100, 30, "ATTACH", func() { g.attachDiaryPhoto(true) }, hexToColor(colGlassLight))
+		} else {
+			text.Draw(screen, truncate("No photos to pick in "+core.SpottingPhotoDir(), 45), basicfont.Face7x13, 100, 169, hexToColor(colTextMuted))
+		}
+		if e.Photo != "" {
+			g.addButton(100, 190, 200, 30, "REMOVE PHOTO", func() { e.Photo, e.PhotoCredit = "", "" }, hexToColor(colDanger))
+		}
+		text.Draw(screen, truncate(g.diaryMsg, 45), basicfont.Face7x13, 20, 250, hexToColor(colTextMuted))
+
+		g.addButton(20, logicalHeight-50, 100, 30, "CANCEL", func() { g.diaryEdit, g.isKeyboardOpen = nil, false }, hexToColor(colDanger))
+		g.addButton(130, logicalHeight-50, 100, 30, "SAVE", g.saveDiary, hexToColor(colSuccess))
+	}
+
+	for _, b := range g.buttons {
+		ebitenutil.DrawRect(screen, float64(b.X), float64(b.Y), float64(b.W), float64(b.H), b.Color)
+		tW := len(b.Text) * 7
+		text.Draw(screen, b.Text, basicfont.Face7x13, b.X+(b.W-tW)/2, b.Y+b.H/2+4, b.TextColor)
+	}
+}
+
+// openStorage measures the data directory for the storage screen
+func (g *Game) openStorage() {
+	var err error
+	g.storageUsage, err = g.dataManager.DataDirBreakdown()
+	if err != nil {
+		log.Println("Error measuring data dir:", err)
+	}
+	g.storageMsg = ""
+	g.state = StateStorage
+}
+
+// pruneNow applies the retention settings without waiting for a restart
+func (g *Game) pruneNow() {
+	r, err := g.dataManager.Prune(g.settings.Get().Retention, time.Now())
+	g.openStorage()
+	g.storageMsg = r.String()
+	if err != nil {
+		g.storageMsg = "Prune failed: " + err.Error()
+	}
+}
+
+// retentionLabel shows a retention choice, marking the default
+func retentionLabel(set, days int) string {
+	if set <= 0 {
+		return fmt.Sprintf("%d days (default)", days)
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+// drawStorage sets how long the growing logs are kept and shows what the
+// data directory holds, so a kiosk doesn't quietly fill its SD card
+func (g *Game) drawStorage(screen *ebiten.Image) {
+	g.buttons = g.buttons[:0]
+	r := g.settings.Get().Retention
+
+	text.Draw(screen, "STORAGE", basicfont.Face7x13, 20, 30, hexToColor(colAccent))
+	text.Draw(screen, "older data is pruned at startup", basicfont.Face7x13, 100, 30, hexToColor(colTextMuted))
+
+	row := func(y int, label, value string, field func(*core.Retention) *int) {
+		step := func(dir int) {
+			g.updateSettings(func(s *core.Settings) {
+				f := field(&s.Retention)
+				*f = core.StepSetting(core.RetentionSteps, *f, dir)
+			})
+			g.history.SetRetentionDays(g.settings.Get().Retention.Tracks())
+		}
+		text.Draw(screen, label, basicfont.Face7x13, 50, y+19, color.White)
+		g.addButton(200, y, 30, 30, "-", func() { step(-1) }, hexToColor(colGlassLight))
+		text.Draw(screen, value, basicfont.Face7x13, 240, y+19, color.White)
+		g.addButton(370, y, 30, 30, "+", func() { step(1) }, hexToColor(colGlassLight))
+	}
+	row(70, "Overhead log", retentionLabel(r.OverheadDays, r.Overhead()), func(r *core.Retention) *int { return &r.OverheadDays })
+	row(120, "Track history", retentionLabel(r.TrackDays, r.Tracks()), func(r *core.Retention) *int { return &r.TrackDays })
+	row(170, "Game history", retentionLabel(r.GameDays, r.Games()), func(r *core.Retention) *int { return &r.GameDays })
+	row(220, "Captures", retentionLabel(r.CaptureDays, r.Captures()), func(r *core.Retention) *int { return &r.CaptureDays })
+	text.Draw(screen, "Scores and player stats are kept for good.", basicfont.Face7x13, 50, 285, hexToColor(colTextMuted))
+	text.Draw(screen, truncate(g.storageMsg, 50), basicfont.Face7x13, 50, 310, hexToColor(colTextMuted))
+
+	var total core.DirUsage
+	for _, u := range g.storageUsage {
+		total.Bytes += u.Bytes
+		total.Files += u.Files
+	}
+	text.Draw(screen, "DISK USAGE", basicfont.Face7x13, 460, 70, color.White)
+	text.Draw(screen, total.String(), basicfont.Face7x13, 560, 70, hexToColor(colGold))
+	y := 95
+	for i, u := range g.storageUsage {
+		if i >= 14 {
+			text.Draw(screen, fmt.Sprintf("... and %d more", len(g.storageUsage)-i), basicfont.Face7x13, 460, y, hexToColor(colTextMuted))
+			break
+		}
+		text.Draw(screen, truncate(u.Name, 24), basicfont.Face7x13, 460, y, color.White)
+		size := core.FormatBytes(u.Bytes)
+		text.Draw(screen, size, basicfont.Face7x13, logicalWidth-30-len(size)*7, y, hexToColor(colTextMuted))
+		y += 20
+	}
+
+	g.addButton(20, logicalHeight-50, 100, 30, "BACK", func() { g.state = StateSettings }, hexToColor(colDanger))
+	g.addButton(130, logicalHeight-50, 100, 30, "PRUNE NOW", g.pruneNow, hexToColor(colGlassLight))
+
+	for _, b := range g.buttons {
+		ebitenutil.DrawRect(screen, float64(b.X), float64(b.Y), float64(b.W), float64(b.H), b.Color)
+		tW := len(b.Text) * 7
+		text.Draw(screen, b.Text, basicfont.Face7x13, b.X+(b.W-tW)/2, b.Y+b.H/2+4, b.TextColor)
+	}
+}
+
+// drawMap fills the screen with tiles from the level nearest the zoom,
+// scaled to it. Tile edges are rounded to whole pixels so scaled tiles
+// meet without seams.
+func (g *Game) drawMap(screen *ebiten.Image) {
+	level, scale := core.TileLevel(g.camZoom)
+	centerX, centerY := core.LatLonToPixels(g.camLat, g.camLon, float64(level))
+	// The view in the level's pixels
+	screenCX, screenCY := float64(logicalWidth)/2/scale, float64(logicalHeight)/2/scale
+	minWX := centerX - screenCX
+	minWY := centerY - screenCY
+
+	minTileX := int(math.Floor(minWX / core.TileSize))
+	maxTileX := int(math.Floor((centerX + screenCX) / core.TileSize))
+	minTileY := int(math.Floor(minWY / core.TileSize))
+	maxTileY := int(math.Floor((centerY + screenCY) / core.TileSize))
+
+	maxIndex := 1<<level - 1
+	edge := func(tile int, origin float64) float64 {
+		return math.Round((float64(tile*core.TileSize) - origin) * scale)
+	}
+
+	for x := minTileX; x <= maxTileX; x++ {
+		for y := minTileY; y <= maxTileY; y++ {
+			tileX := x
+			for tileX < 0 {
+				tileX += maxIndex + 1
+			}
+			for tileX > maxIndex {
+				tileX -= maxIndex + 1
+			}
+
+			if y < 0 || y > maxIndex {
+				continue
+			}
+
+			screenX, screenY := edge(x, minWX), edge(y, minWY)
+			w, h := edge(x+1, minWX)-screenX, edge(y+1, minWY)-screenY
+
+			img := g.tileLoader.GetTile(level, tileX, y)
+			if img == nil {
+				g.drawTilePlaceholder(screen, level, tileX, y, screenX, screenY, w, h)
+			} else {
+				// REUSE the op object instead of creating new
+				g.op.GeoM.Reset()
+				g.op.ColorScale.Reset()
+				g.op.Filter = ebiten.FilterNearest // Explicitly use nearest for speed
+
+				if d := float64(img.Bounds().Dx()); w != d || h != d {
+					g.op.GeoM.Scale(w/d, h/d)
+					g.op.Filter = ebiten.FilterLinear
+				}
+				g.op.GeoM.Translate(screenX, screenY)
+				screen.DrawImage(img, g.op)
+
+				// op := &ebiten.DrawImageOptions{}
+				// op.GeoM.Translate(screenX, screenY)
+				// screen.DrawImage(img, op)
+			}
+		}
+	}
+	g.prefetchTiles()
+}
+
+// prefetchTiles starts loading the tiles around the view and at the zoom
+// levels either side of it whenever the view moves onto another centre
+// tile. The ring is a tile wide, so it still covers the view until then.
+// Downloads of tiles the view has left behind are dropped at the same time.
+func (g *Game) prefetchTiles() {
+	level, scale := core.TileLevel(g.camZoom)
+	cx, cy := core.LatLonToPixels(g.camLat, g.camLon, float64(level))
+	centre := core.TileCoord{Z: level, X: int(cx / core.TileSize), Y: int(cy / core.TileSize)}
+	if centre == g.prefetched {
+		return
+	}
+	g.prefetched = centre
+	w, h := logicalWidth/scale, logicalHeight/scale // zoomed out, the view spans more tiles
+	near := core.ViewTiles(g.camLat, g.camLon, level, w, h, 1)
+	if g.eco() {
+		g.tileLoader.Prefetch(near, nil) // only drops what the view left
+		return
+	}
+	// A quarter of the cache is left for placeholders and the view just left
+	budget := g.profile.MaxTiles * 3 / 4
+	g.tileLoader.Prefetch(near, core.PrefetchTiles(g.camLat, g.camLon, level, w, h, budget))
+}
+
+// drawTilePlaceholder fills the square of a tile still loading with what
+// is cached: the covering part of the nearest ancestor stretched up, or
+// failing that whichever of its four children are there, shrunk down.
+// Nothing is fetched for it, so the square stays black at worst. The
+// square is w by h on screen.
+func (g *Game) drawTilePlaceholder(screen *ebiten.Image, z, x, y int, screenX, screenY, w, h float64) {
+	for _, a := range core.TileAncestors(z, x, y) {
+		img := g.tileLoader.Cached(a.Z, a.X, a.Y)
+		if img == nil {
+			continue
+		}
+		sx, sy, side := a.Sub(img.Bounds().Dx())
+		if side < 1 {
+			break
+		}
+		g.op.GeoM.Reset()
+		g.op.ColorScale.Reset()
+		g.op.Filter = ebiten.FilterLinear
+		g.op.GeoM.Scale(w/float64(side), h/float64(side))
+		g.op.GeoM.Translate(screenX, screenY)
+		screen.DrawImage(img.SubImage(image.Rect(sx, sy, sx+side, sy+side)).(*ebiten.Image), g.op)
+		return
+	}
+
+	halfW, halfH := w/2, h/2
+	for i := 0; i < 4; i++ {
+		cx, cy := i%2, i/2
+		img := g.tileLoader.Cached(z+1, 2*x+cx, 2*y+cy)
+		if img == nil {
+			continue
+		}
+		g.op.GeoM.Reset()
+		g.op.ColorScale.Reset()
+		g.op.Filter = ebiten.FilterLinear
+		g.op.GeoM.Scale(halfW/float64(img.Bounds().Dx()), halfH/float64(img.Bounds().Dx()))
+		g.op.GeoM.Translate(screenX+float64(cx)*halfW, screenY+float64(cy)*halfH)
+		screen.DrawImage(img, g.op)
+	}
+}
+
+// drawAttribution credits the map tiles in a corner, on a dark strip so it
+// reads over any tile. The bitmap font has no ©.
+func (g *Game) drawAttribution(screen *ebiten.Image) {
+	s := g.attribution.ASCII()
+	w, h := len(s)*7+8, 16
+	x, y := g.attribution.Place(w, h, logicalWidth, logicalHeight, 0)
+	ebitenutil.DrawRect(screen, float64(x), float64(y), float64(w), float64(h), color.RGBA{0, 0, 0, 160})
+	text.Draw(screen, s, basicfont.Face7x13, x+4, y+12, hexToColor(colTextMuted))
+}
+
+func (g *Game) drawHomeMarker(screen *ebiten.Image) {
+	centerX, centerY := core.LatLonToPixels(g.camLat, g.camLon, g.camZoom)
+	screenCX, screenCY := float64(logicalWidth)/2, float64(logicalHeight)/2
+	minWX := centerX - screenCX
+	minWY := centerY - screenCY
+
+	hX, hY := core.LatLonToPixels(myLat, myLon, g.camZoom)
+	sX := hX - minWX
+	sY := hY - minWY
+
+	if sX >= 0 && sX <= float64(logicalWidth) && sY >= 0 && sY <= float64(logicalHeight) {
+		// Draw a dot (6x6 square)
+		size := 6.0
+		ebitenutil.DrawRect(screen, sX-size/2, sY-size/2, size, size, hexToColor(colAccent))
+	}
+}
+
+// drawPolarRange outlines the furthest distance our receiver has heard in each direction
+// drawSelectedTrack draws the path flown by the selected aircraft up to its current position
+func (g *Game) drawSelectedTrack(screen *ebiten.Image) {
+	track := g.selectedTrack
+	if g.selectedPlane == nil || len(track) == 0 {
+		return
+	}
+	centerX, centerY := core.LatLonToPixels(g.camLat, g.camLon, g.camZoom)
+	minWX := centerX - float64(logicalWidth)/2
+	minWY := centerY - float64(logicalHeight)/2
+
+	pathCol := color.RGBA{251, 191, 36, 200}
+	prevX, prevY := core.LatLonToPixels(track[0].Lat, track[0].Lon, g.camZoom)
+	endLat, endLon := g.flights.Position(g.selectedPlane, time.Now())
+	for i := 1; i <= len(track); i++ {
+		lat, lon := endLat, endLon
+		if i < len(track) {
+			lat, lon = track[i].Lat, track[i].Lon
+		}
+		x, y := core.LatLonToPixels(lat, lon, g.camZoom)
+		vector.StrokeLine(screen, float32(prevX-minWX), float32(prevY-minWY), float32(x-minWX), float32(y-minWY), 2, pathCol, true)
+		prevX, prevY = x, y
+	}
+}
+
+func (g *Game) drawPolarRange(screen *ebiten.Image) {
+	if g.polar == nil {
+		return
+	}
+	centerX, centerY := core.LatLonToPixels(g.camLat, g.camLon, g.camZoom)
+	minWX := centerX - float64(logicalWidth)/2
+	minWY := centerY - float64(logicalHeight)/2
+
+	sectors := g.polar.Sectors()
+	pts := make([][2]float32, len(sectors))
+	for i, km := range sectors {
+		lat, lon := core.Destination(myLat, myLon, core.SectorBearing(i), km)
+		x, y := core.LatLonToPixels(lat, lon, g.camZoom)
+		pts[i] = [2]float32{float32(x - minWX), float32(y - minWY)}
+	}
+
+	ringCol := color.RGBA{56, 189, 248, 160}
+	for i := range sectors {
+		j := (i + 1) % len(sectors)
+		if sectors[i] == 0 || sectors[j] == 0 {
+			continue // no coverage data for this direction yet
+		}
+		vector.StrokeLine(screen, pts[i][0], pts[i][1], pts[j][0], pts[j][1], 1.5, ringCol, false)
+	}
+}
+
+func (g *Game) drawPlanes(screen *ebiten.Image) {
+	centerX, centerY := core.LatLonToPixels(g.camLat, g.camLon, g.camZoom)
+	screenCX, screenCY := float64(logicalWidth)/2, float64(logicalHeight)/2
+	minWX := centerX - screenCX
+	minWY := centerY - screenCY
+
+	now := time.Now()
+	airlineColors := g.settings.Get().AirlineColors
+	visible := g.flights.InRect(minWX-50, minWY-50, minWX+float64(logicalWidth)+50, minWY+float64(logicalHeight)+50, g.camZoom)
+	for _, f := range visible {
+		lat, lon := g.flights.Position(f, now)
+		fX, fY := core.LatLonToPixels(lat, lon, g.camZoom)
+		sX := fX - minWX
+		sY := fY - minWY
+
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(-16, -16)
+		op.GeoM.Rotate(f.Heading * math.Pi / 180.0)
+		op.GeoM.Translate(sX, sY)
+
+		// Highlight target
+		if f.IsEmergency() {
+			op.ColorScale.Scale(1, 0.2, 0.2, 1) // Red, pulsing
+			op.ColorScale.ScaleAlpha(float32(emergencyPulse()))
+		} else if g.state == StateGamePlaying && g.targetPlane != nil && f.Icao24 == g.targetPlane.Icao24 {
+			op.ColorScale.Scale(1, 0.8, 0.2, 1) // Orange tint
+		} else if g.inOddOneOut(f.Icao24) {
+			op.ColorScale.Scale(1, 0.8, 0.2, 1) // Orange tint, like a quiz target
+		} else if _, watched := g.watchlist.Match(*f); watched {
+			op.ColorScale.Scale(1, 0.3, 1, 1) // Magenta tint
+		} else if c, ok := core.AirlineColor(f.Callsign); ok && airlineColors {
+			op.ColorScale.Scale(float32(c>>24)/255, float32(c>>16&0xff)/255, float32(c>>8&0xff)/255, 1)
+		}
+		if f.Stale {
+			op.ColorScale.ScaleAlpha(0.4) // not heard from lately
+		}
+
+		screen.DrawImage(g.planeImg, op)
+
+		if info, ok := g.tags.Lookup(f.Icao24); ok {
+			ebitenutil.DrawRect(screen, sX-24, sY-24, 12, 14, tagColor(info.Tag))
+			text.Draw(screen, info.Tag.Badge(), basicfont.Face7x13, int(sX)-22, int(sY)-13, color.White)
+		}
+
+		// Label
+		text.Draw(screen, f.Callsign, basicfont.Face7x13, int(sX)+20, int(sY), color.White)
+	}
+}
+
+func (g *Game) drawUI(screen *ebiten.Image) {
+	g.buttons = g.buttons[:0] // Reset buttons from previous frame
+
+	// Top Bar: User info
+	if g.state == StateMap {
+		user := g.users.Current()
+		text.Draw(screen, fmt.Sprintf("User: %s (Best: %d)", user.Name, user.BestScore), basicfont.Face7x13, 10, 20, hexToColor(colAccent))
+		g.addButton(logicalWidth-110, 10, 100, 30, "LEADERBOARD", func() {
+			g.refreshLeaderboard()
+			g.state = StateLeaderboard
+		}, hexToColor(colGlass))
+		g.addButton(logicalWidth-220, 10, 100, 30, "LOGOUT", func() { g.state = StateLogin; g.inputText = "" }, hexToColor(colDanger))
+		g.addButton(logicalWidth-300, 10, 70, 30, "SETTINGS", func() { g.state = StateSettings }, hexToColor(colGlass))
+		ecoX := logicalWidth - 370
+		if regions := g.settings.Get().WatchRegions(myLat, myLon); len(regions) > 1 {
+			name := regions[g.activeRegion%len(regions)].Name
+			g.addButton(logicalWidth-410, 10, 100, 30, truncate(name, 12), g.cycleRegion, hexToColor(colGlassLight))
+			ecoX -= 110
+		}
+		// Shown while on battery or overridden, as the switch to override it
+		if g.power.OnBattery() || g.settings.Get().EcoMode != core.EcoAuto {
+			if g.eco() {
+				g.addButton(ecoX, 10, 60, 30, "ECO", g.toggleEco, hexToColor(colSuccess))
+			} else {
+				g.addButton(ecoX, 10, 60, 30, "ECO OFF", g.toggleEco, hexToColor(colGlassLight))
+			}
+		}
+	}
+
+	if g.state == StateMap {
+		g.drawReceiverWidget(screen)
+		g.drawRunways(screen)
+		g.drawThrottleBanner(screen)
+		g.drawWatchAlert(screen)
+		g.drawEmergencyBanner(screen)
+		g.drawAirlineLegend(screen)
+	}
+
+	// DEBUG: Show Touch Count in UI (Top Left under User)
+	touchCount := len(ebiten.AppendTouchIDs(nil))
+	if touchCount > 0 {
+		text.Draw(screen, fmt.Sprintf("Touches: %d", touchCount), basicfont.Face7x13, 10, 40, color.White)
+	}
+
+	// Sidebar (Right) - Plane Info
+	if g.selectedPlane != nil {
+		// Reduced width from 300 to 220, and adjusted X position
+		panelW := lay("info_panel_w", 220)
+		panelX := logicalWidth - panelW - lay("info_panel_margin", 10)
+		panelY := lay("info_panel_y", 90)
+		g.drawPanel(screen, panelX, panelY, panelW, lay("info_panel_h", 350), "FLIGHT INFO")
+		g.drawPhoto(screen, g.selectedPlane, panelX)
+
+		// Content
+		p := g.selectedPlane
+		y := panelY + 50
+		textW := panelX + 20
+		text.Draw(screen, p.Callsign, basicfont.Face7x13, textW, y, hexToColor(colAccent))
+		y += 30
+		text.Draw(screen, fmt.Sprintf("Alt: %d ft", p.AltitudeFt), basicfont.Face7x13, textW, y, color.White)
+		if trend := p.Trend(); trend != core.TrendLevel {
+			drawTrendArrow(screen, float32(textW+104), float32(y-5), trend)
+			text.Draw(screen, fmt.Sprintf("%d fpm", abs(p.VerticalRateFpm)), basicfont.Face7x13, textW+114, y, hexToColor(colTextMuted))
+		}
+		y += 20
+		text.Draw(screen, fmt.Sprintf("Spd: %d kts", p.VelocityKts), basicfont.Face7x13, textW, y, color.White)
+		y += 20
+		text.Draw(screen, fmt.Sprintf("Lat/Lon: %.2f, %.2f", p.Lat, p.Lon), basicfont.Face7x13, textW, y, color.White)
+		y += 20
+		brg := core.FormatBearing(core.Bearing(myLat, myLon, p.Lat, p.Lon), g.settings.Get().MagneticBearings, myLat, myLon)
+		text.Draw(screen, fmt.Sprintf("Brg: %s  %.0f km", brg, core.Distance(myLat, myLon, p.Lat, p.Lon)), basicfont.Face7x13, textW, y, color.White)
+		g.addButton(panelX+panelW-45, y-13, 35, 18, "T/M", g.toggleMagneticBearings, hexToColor(colGlassLight))
+		if p.Source != "" {
+			y += 20
+			text.Draw(screen, "Src: "+p.SourceLabel(time.Now()), basicfont.Face7x13, textW, y, hexToColor(colTextMuted))
+		}
+		if !p.OnGround {
+			spot := core.Spotability(*p, g.metar.Sky(), myLat, myLon, time.Now())
+			col := hexToColor(colTextMuted)
+			if spot.Visible {
+				col = hexToColor(colSuccess)
+			}
+			y += 20
+			text.Draw(screen, truncate(spot.Reason, 28), basicfont.Face7x13, textW, y, col)
+		}
+		if reg := g.airframeLine(p); reg != "" {
+			y += 20
+			text.Draw(screen, reg, basicfont.Face7x13, textW, y, hexToColor(colTextMuted))
+		}
+		if age := g.resolvedDetails.AgeLabel(time.Now()); age != "" && !g.resolving {
+			col := hexToColor(colTextMuted)
+			if g.resolvedDetails.NewAirframe(time.Now()) {
+				col = hexToColor(colSuccess)
+			}
+			y += 20
+			text.Draw(screen, "Age: "+age, basicfont.Face7x13, textW, y, col)
+		}
+
+		y += 30
+		// Extended Details
+		if g.resolving {
+			text.Draw(screen, "Fetching details...", basicfont.Face7x13, textW, y, hexToColor(colTextMuted))
+		} else if g.resolvedDetails != nil {
+			// Masking logic: If we are playing and this is the target, hide the answer
+			showModel := g.resolvedDetails.Model
+			showOrigin := g.resolvedDetails.Origin
+			showDest := g.resolvedDetails.RealDestination
+			showAirline := g.resolvedDetails.Airline
+			if showModel == "" {
+				showModel = "Unknown" // route databases don't know the aircraft
+			}
+
+			if g.state == StateGamePlaying && g.targetPlane != nil && g.selectedPlane.Icao24 == g.targetPlane.Icao24 {
+				if g.typeRound {
+					showModel = g.typeHint
+				}
+				// Hide answer based on question type
+				// If correct option matches one of these, hide it
+				if g.correctOption == g.resolvedDetails.Origin {
+					showOrigin = "???"
+				}
+				if g.correctOption == g.resolvedDetails.RealDestination {
+					showDest = "???"
+				}
+				if g.correctOption == g.resolvedDetails.Airline {
+					showAirline = "???"
+				}
+			}
+
+			text.Draw(screen, "Model: "+truncate(showModel, 25), basicfont.Face7x13, textW, y, color.White)
+
+			y += 20
+			text.Draw(screen, "Origin: "+truncate(showOrigin, 20), basicfont.Face7x13, textW, y, color.White)
+			y += 20
+			text.Draw(screen, "Dest: "+truncate(showDest, 20), basicfont.Face7x13, textW, y, color.White)
+			if showAirline != "" {
+				y += 20
+				text.Draw(screen, "Airline: "+truncate(showAirline, 18), basicfont.Face7x13, textW, y, color.White)
+			}
+			// The bonus round asks for exactly this, so it stays hidden then
+			if prog, ok := g.resolvedDetails.Progress(*p, time.Now()); ok && g.state != StateArrivalBonus {
+				y += 20
+				text.Draw(screen, prog.String(), basicfont.Face7x13, textW, y, hexToColor(colTextMuted))
+			}
+		} else {
+			text.Draw(screen, "Details unavailable", basicfont.Face7x13, textW, y, hexToColor(colTextMuted))
+		}
+
+		// Close Button
+		g.addButton(logicalWidth-40, 95, 30, 30, "X", g.deselectPlane, color.RGBA{255, 255, 255, 50}, color.Black)
 	}
 
 	// Game Panel (Left)
 	if g.state == StateRoundSetup {
-		g.drawPanel(screen, 20, 90, 220, 150, fmt.Sprintf("ROUND %d/5", g.round))
+		g.drawPanel(screen, 20, 90, 220, 150, fmt.Sprintf("ROUND %d/%d", g.round, core.RoundsPerGame))
 		text.Draw(screen, "Tracking target...", basicfont.Face7x13, 40, 140, color.White)
 		text.Draw(screen, "Please wait", basicfont.Face7x13, 40, 160, hexToColor(colTextMuted))
 	} else if g.state == StateGamePlaying && g.targetPlane != nil {
-		g.drawPanel(screen, 20, 90, 220, 340, fmt.Sprintf("ROUND %d/5", g.round))
+		g.drawPanel(screen, 20, 90, 220, 340, fmt.Sprintf("ROUND %d/%d", g.round, core.RoundsPerGame))
 
 		// Wrap question text if needed or truncate
 		qText := g.questionText
@@ -898,6 +2847,9 @@ func (g *Game) drawUI(screen *ebiten.Image) {
 			qText = qText[:28] + "..."
 		}
 		text.Draw(screen, qText, basicfont.Face7x13, 30, 140, color.White)
+		if g.typeRound {
+			text.Draw(screen, "Hint: "+truncate(g.typeHint, 22), basicfont.Face7x13, 30, 158, hexToColor(colTextMuted))
+		}
 
 		// Options
 		y := 170
@@ -913,63 +2865,358 @@ func (g *Game) drawUI(screen *ebiten.Image) {
 				}
 			}
 
-			// Capture variable for closure
-			btnOpt := opt
-			// Reduced button width to fit panel
-			g.addButton(30, y, 200, 40, truncate(opt, 25), func() { g.guess(btnOpt) }, col, color.Black)
-			y += 50
+			// Capture variable for closure
+			btnOpt := opt
+			// Reduced button width to fit panel
+			g.addButton(30, y, 200, 40, truncate(opt, 25), func() {
+				if !g.partyGame {
+					g.guess(btnOpt)
+				}
+			}, col, color.Black)
+			y += 50
+		}
+
+		// Score
+		if g.partyGame {
+			answered, players := g.party.Progress()
+			text.Draw(screen, fmt.Sprintf("Phones: %d/%d", answered, players), basicfont.Face7x13, 30, y+20, hexToColor(colAccent))
+			if g.showResult {
+				g.drawPartyStandings(screen, logicalWidth/2-110, 90, 8)
+			}
+		} else {
+			text.Draw(screen, fmt.Sprintf("Score: %d", g.score), basicfont.Face7x13, 30, y+20, hexToColor(colAccent))
+		}
+		if !g.showResult {
+			left := max(0, g.roundTime-g.clock.Since(g.roundStartTime))
+			text.Draw(screen, fmt.Sprintf("Time: %.0fs", left.Seconds()), basicfont.Face7x13, 150, y+20, color.White)
+		}
+
+		y += 40 // Add margin after the score
+
+		// Quit Button
+		g.addButton(20, 400, 100, 30, "QUIT", func() { g.endGame() }, hexToColor(colDanger))
+	}
+
+	// Bottom Controls
+	if g.state == StateMap {
+		g.addButton(logicalWidth/2-60, logicalHeight-60, 120, 40, "PLAY GAME", func() { g.startGame() }, hexToColor(colAccent))
+		g.addButton(logicalWidth/2+70, logicalHeight-60, 90, 40, "REPLAY", g.openReplayEntry, hexToColor(colGlass))
+		g.addButton(logicalWidth/2-170, logicalHeight-60, 100, 40, "ODD ONE OUT", g.startOddOneOut, hexToColor(colGlass))
+		g.addButton(20, logicalHeight-60, 80, 40, "CENTER", func() {
+			g.camLat = myLat
+			g.camLon = myLon
+		}, hexToColor(colGlass))
+
+		// Zoom Buttons (Bottom Right)
+		// They step to the next whole level, where tiles are drawn unscaled
+		g.addButton(logicalWidth-110, logicalHeight-60, 40, 40, "-", func() {
+			g.camZoom = core.ClampZoom(math.Ceil(g.camZoom) - 1)
+		}, hexToColor(colGlass))
+		g.addButton(logicalWidth-60, logicalHeight-60, 40, 40, "+", func() {
+			g.camZoom = core.ClampZoom(math.Floor(g.camZoom) + 1)
+		}, hexToColor(colGlass))
+	} else if g.state == StateGameOver {
+		top := logicalHeight/2 - 100
+		if len(g.podium) > 0 {
+			top -= 60
+		}
+		if g.partyGame {
+			g.drawPartyStandings(screen, logicalWidth/2-380, top, 8)
+		}
+		g.drawPanel(screen, logicalWidth/2-150, top, 300, logicalHeight/2+100-top, "GAME OVER")
+		if len(g.podium) > 0 {
+			g.drawPodium(screen, top+40)
+		}
+		if s := g.party.Standings(); g.partyGame && len(s) > 0 {
+			text.Draw(screen, "Winner: "+s[0].Name, basicfont.Face7x13, logicalWidth/2-50, logicalHeight/2, hexToColor(colGold))
+		} else {
+			text.Draw(screen, fmt.Sprintf("Final Score: %d", g.score), basicfont.Face7x13, logicalWidth/2-50, logicalHeight/2, color.White)
+		}
+		if g.replay != nil {
+			text.Draw(screen, fmt.Sprintf("%s scored %d", g.replay.Player, g.replayScore), basicfont.Face7x13, logicalWidth/2-130, logicalHeight/2+22, hexToColor(colAccent))
+		} else if g.shareCode != "" {
+			text.Draw(screen, "Share code: "+g.shareCode, basicfont.Face7x13, logicalWidth/2-130, logicalHeight/2+22, hexToColor(colAccent))
+		}
+		if g.bonusResult != "" {
+			text.Draw(screen, g.bonusResult, basicfont.Face7x13, logicalWidth/2-130, logicalHeight/2+42, hexToColor(colGold))
+		}
+		g.addButton(logicalWidth/2-60, logicalHeight/2+52, 120, 40, "CLOSE", func() { g.endGame() }, hexToColor(colAccent))
+	} else if g.state == StateArrivalBonus {
+		g.drawPanel(screen, 20, 90, 220, 340, "BONUS ROUND")
+		text.Draw(screen, "Minutes until "+truncate(g.bonusName, 10), basicfont.Face7x13, 30, 140, color.White)
+		text.Draw(screen, "lands in", basicfont.Face7x13, 30, 158, color.White)
+		text.Draw(screen, truncate(g.bonusDetails.RealDestination, 28), basicfont.Face7x13, 30, 176, hexToColor(colAccent))
+
+		guess := fmt.Sprintf("%d min", g.bonusGuess)
+		text.Draw(screen, guess, basicfont.Face7x13, 130-len(guess)*7/2, 220, hexToColor(colGold))
+		for i, step := range []int{-10, -1, 1, 10} {
+			g.addButton(30+i*50, 240, 45, 30, fmt.Sprintf("%+d", step), func() { g.stepArrivalGuess(step) }, hexToColor(colGlassLight))
+		}
+		g.addButton(30, 290, 195, 35, "LOCK IN", g.lockArrivalGuess, hexToColor(colAccent))
+		text.Draw(screen, fmt.Sprintf("Up to %d points", core.ArrivalBonusPoints), basicfont.Face7x13, 30, 345, hexToColor(colTextMuted))
+		text.Draw(screen, fmt.Sprintf("Score: %d", g.score), basicfont.Face7x13, 30, 365, hexToColor(colAccent))
+	} else if g.state == StateOddOneOut {
+		g.drawOddOneOut(screen)
+	}
+
+	// Register Buttons in UI pass
+	for _, b := range g.buttons {
+		ebitenutil.DrawRect(screen, float64(b.X), float64(b.Y), float64(b.W), float64(b.H), b.Color)
+		// Center text roughly
+		tW := len(b.Text) * 7
+		text.Draw(screen, b.Text, basicfont.Face7x13, b.X+(b.W-tW)/2, b.Y+b.H/2+4, b.TextColor)
+	}
+
+	ebitenutil.DebugPrint(screen, fmt.Sprintf("FPS: %0.2f", ebiten.ActualFPS()))
+}
+
+// tagColor gives each interesting-traffic tag its own badge colour
+func tagColor(t core.Tag) color.Color {
+	switch t {
+	case core.TagMilitary:
+		return color.RGBA{85, 107, 47, 255}
+	case core.TagGovernment, core.TagPolice:
+		return color.RGBA{30, 64, 175, 255}
+	case core.TagTest:
+		return color.RGBA{202, 138, 4, 255}
+	}
+	return color.RGBA{190, 24, 93, 255}
+}
+
+// drawWatchAlert announces a watchlisted aircraft coming into range
+func (g *Game) drawWatchAlert(screen *ebiten.Image) {
+	if g.watchAlert == "" || time.Now().After(g.watchAlertUntil) {
+		return
+	}
+	w := len(g.watchAlert)*7 + 20
+	x := (logicalWidth - w) / 2
+	ebitenutil.DrawRect(screen, float64(x), 76, float64(w), 22, color.RGBA{160, 40, 160, 220})
+	text.Draw(screen, g.watchAlert, basicfont.Face7x13, x+10, 92, color.White)
+}
+
+// drawPhoto hangs the aircraft's planespotters thumbnail off the left edge
+// of the info panel, with the photographer's credit their terms ask for
+func (g *Game) drawPhoto(screen *ebiten.Image, f *core.Flight, panelX int) {
+	photo := g.photos.Photo(*f)
+	if photo == nil {
+		return
+	}
+	if g.photoKey != f.Icao24 {
+		g.photoKey, g.photoImg = f.Icao24, ebiten.NewImageFromImage(photo.Image)
+	}
+	const w = 160
+	b := g.photoImg.Bounds()
+	scale := float64(w) / float64(b.Dx())
+	h := int(float64(b.Dy()) * scale)
+	x := panelX - w - 10
+	ebitenutil.DrawRect(screen, float64(x-5), 90, w+10, float64(h+26), hexToColor(colGlass))
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate(float64(x), 95)
+	op.Filter = ebiten.FilterLinear
+	screen.DrawImage(g.photoImg, op)
+	text.Draw(screen, truncate(photo.Credit(), w/7), basicfont.Face7x13, x, 95+h+15, hexToColor(colTextMuted))
+}
+
+// drawPartyStandings lists the phones' scores in a panel at (x, y), at
+// most n of them
+func (g *Game) drawPartyStandings(screen *ebiten.Image, x, y, n int) {
+	standings := g.party.Standings()
+	if len(standings) > n {
+		standings = standings[:n]
+	}
+	g.drawPanel(screen, x, y, 220, 50+len(standings)*16, "SCOREBOARD")
+	for i, s := range standings {
+		row := y + 50 + i*16
+		text.Draw(screen, fmt.Sprintf("%d. %s", i+1, s.Name), basicfont.Face7x13, x+20, row, color.White)
+		score := fmt.Sprint(s.Score)
+		text.Draw(screen, score, basicfont.Face7x13, x+200-len(score)*7, row, hexToColor(colGold))
+	}
+}
+
+// drawAirlineLegend names the busiest carriers' colours above the CENTER
+// button when planes are coloured by airline
+func (g *Game) drawAirlineLegend(screen *ebiten.Image) {
+	if !g.settings.Get().AirlineColors || len(g.airlineLegend) == 0 {
+		return
+	}
+	y := logicalHeight - 70 - len(g.airlineLegend)*16
+	ebitenutil.DrawRect(screen, 20, float64(y-6), 80, float64(len(g.airlineLegend)*16+8), hexToColor(colGlass))
+	for _, e := range g.airlineLegend {
+		ebitenutil.DrawRect(screen, 28, float64(y), 10, 10, hexToColor(e.Color))
+		text.Draw(screen, fmt.Sprintf("%s %d", e.Code, e.Count), basicfont.Face7x13, 44, y+10, hexToColor(colText))
+		y += 16
+	}
+}
+
+// drawEmergencyBanner stays up for as long as an aircraft in range squawks
+// an emergency code
+func (g *Game) drawEmergencyBanner(screen *ebiten.Image) {
+	if len(g.emergencies) == 0 {
+		return
+	}
+	f := g.emergencies[0]
+	msg := fmt.Sprintf("EMERGENCY: %s squawking %s (%s)", f.Callsign, f.Squawk, f.Emergency)
+	if n := len(g.emergencies) - 1; n > 0 {
+		msg += fmt.Sprintf(" +%d more", n)
+	}
+	w := len(msg)*7 + 20
+	x := (logicalWidth - w) / 2
+	ebitenutil.DrawRect(screen, float64(x), 102, float64(w), 22, fadeColor(colDanger, emergencyPulse()))
+	text.Draw(screen, msg, basicfont.Face7x13, x+10, 118, color.White)
+}
+
+// emergencyPulse is the opacity emergency highlights throb with, about
+// once a second
+func emergencyPulse() float64 {
+	return 0.7 + 0.3*math.Sin(float64(time.Now().UnixMilli())/160)
+}
+
+// drawThrottleBanner tells the user when the flight source has asked us to
+// back off, or when polling has slowed down to make the credits last
+func (g *Game) drawThrottleBanner(screen *ebiten.Image) {
+	info := g.provider.RateLimitInfo()
+	msg, col := "Throttled until "+info.ThrottledUntil.Format("15:04"), uint32(colDanger)
+	if !time.Now().Before(info.ThrottledUntil) {
+		settings := g.settings.Get()
+		interval := settings.PollInterval(g.provider)
+		if interval <= settings.ConfiguredPollInterval(g.provider) {
+			return
+		}
+		msg = fmt.Sprintf("%d credits left, updating every %s", info.Remaining, interval.Round(time.Second))
+		col = colTextMuted
+	}
+	w := len(msg)*7 + 20
+	x := (logicalWidth - w) / 2
+	ebitenutil.DrawRect(screen, float64(x), 50, float64(w), 22, hexToColor(colGlass))
+	text.Draw(screen, msg, basicfont.Face7x13, x+10, 66, hexToColor(col))
+}
+
+// drawRunways names the runways arrivals at the home airport are using and
+// lists the queue for them, under the receiver widget when there is one
+func (g *Game) drawRunways(screen *ebiten.Image) {
+	title := g.runwayUse.String()
+	if title == "" {
+		return
+	}
+	lines := []string{title}
+	for _, q := range g.arrivalQueue[:min(len(g.arrivalQueue), arrivalQueueShown)] {
+		lines = append(lines, q.Line(len(g.runwayUse.Arrivals) > 1))
+	}
+	w := 0
+	for _, l := range lines {
+		w = max(w, len(l)*7+20)
+	}
+	y := 50
+	if g.receiver != nil {
+		y += 66
+	}
+	ebitenutil.DrawRect(screen, 10, float64(y), float64(w), float64(len(lines)*16+6), hexToColor(colGlass))
+	for i, l := range lines {
+		col := hexToColor(colText)
+		if i == 0 {
+			col = hexToColor(colAccent)
+		}
+		text.Draw(screen, l, basicfont.Face7x13, 20, y+16+i*16, col)
+	}
+}
+
+// drawApproaches draws the corridor arrivals fly down onto each runway in
+// use, the extended centreline ticked every approachTickKm, and numbers
+// the aircraft queued for it, as on a tower's radar
+func (g *Game) drawApproaches(screen *ebiten.Image) {
+	if g.state != StateMap || len(g.runwayUse.Approaches) == 0 {
+		return
+	}
+	lineCol := color.RGBA{56, 189, 248, 150}
+	line := func(a core.Approach, out1, side1, out2, side2 float64, width float32) {
+		x1, y1 := g.screenPos(a.Point(out1, side1))
+		x2, y2 := g.screenPos(a.Point(out2, side2))
+		vector.StrokeLine(screen, float32(x1), float32(y1), float32(x2), float32(y2), width, lineCol, true)
+	}
+	far := core.ApproachCorridorKm
+	for _, a := range g.runwayUse.Approaches {
+		line(a, 0, 0, far, 0, 1)
+		for _, side := range []float64{-1, 1} {
+			line(a, 0, side*core.CorridorHalfWidthKm(0), far, side*core.CorridorHalfWidthKm(far), 1)
+		}
+		for d := approachTickKm; d < far; d += approachTickKm {
+			line(a, d, -0.5, d, 0.5, 2)
+		}
+		x, y := g.screenPos(a.Point(far, 0))
+		text.Draw(screen, a.Runway, basicfont.Face7x13, int(x)-10, int(y)-6, hexToColor(colAccent))
+	}
+
+	now := time.Now()
+	for _, q := range g.arrivalQueue {
+		if f := g.flights.Get(q.Flight.Icao24); f != nil {
+			x, y := g.screenPos(g.flights.Position(f, now))
+			text.Draw(screen, strconv.Itoa(q.Number), basicfont.Face7x13, int(x)+14, int(y)-10, hexToColor(colGold))
 		}
+	}
+}
 
-		// Score
-		text.Draw(screen, fmt.Sprintf("Score: %d", g.score), basicfont.Face7x13, 30, y+20, hexToColor(colAccent))
+// drawReceiverWidget shows local ADS-B feeder health and warns if the feed dies
+func (g *Game) drawReceiverWidget(screen *ebiten.Image) {
+	if g.receiver == nil {
+		return
+	}
+	st := g.receiver.Status()
 
-		y += 40 // Add margin after the score
+	x, y := 10, 50
+	ebitenutil.DrawRect(screen, float64(x), float64(y), 200, 58, hexToColor(colGlass))
+	text.Draw(screen, "RECEIVER", basicfont.Face7x13, x+10, y+16, hexToColor(colAccent))
 
-		// Quit Button
-		g.addButton(20, 400, 100, 30, "QUIT", func() { g.endGame() }, hexToColor(colDanger))
+	if st.Err != nil || st.Dead(time.Now()) {
+		text.Draw(screen, "FEED DOWN", basicfont.Face7x13, x+80, y+16, hexToColor(colDanger))
+		since := "never"
+		if !st.LastMessages.IsZero() {
+			since = st.LastMessages.Format("15:04:05")
+		}
+		text.Draw(screen, "Last msg: "+since, basicfont.Face7x13, x+10, y+34, hexToColor(colTextMuted))
+		if st.Err != nil {
+			text.Draw(screen, truncate(st.Err.Error(), 24), basicfont.Face7x13, x+10, y+50, hexToColor(colTextMuted))
+		}
+		return
 	}
 
-	// Bottom Controls
-	if g.state == StateMap {
-		g.addButton(logicalWidth/2-60, logicalHeight-60, 120, 40, "PLAY GAME", func() { g.startGame() }, hexToColor(colAccent))
-		g.addButton(20, logicalHeight-60, 80, 40, "CENTER", func() {
-			g.camLat = myLat
-			g.camLon = myLon
-		}, hexToColor(colGlass))
+	text.Draw(screen, fmt.Sprintf("%.0f msg/s  %d aircraft", st.MessageRate, st.Aircraft), basicfont.Face7x13, x+10, y+34, color.White)
+	text.Draw(screen, fmt.Sprintf("Range: %.0f km", st.MaxRangeKm), basicfont.Face7x13, x+10, y+50, color.White)
+}
 
-		// Zoom Buttons (Bottom Right)
-		g.addButton(logicalWidth-110, logicalHeight-60, 40, 40, "-", func() {
-			if g.camZoom > 4 {
-				g.camZoom--
-			}
-		}, hexToColor(colGlass))
-		g.addButton(logicalWidth-60, logicalHeight-60, 40, 40, "+", func() {
-			if g.camZoom < 18 {
-				g.camZoom++
-			}
-		}, hexToColor(colGlass))
-	} else if g.state == StateGameOver {
-		g.drawPanel(screen, logicalWidth/2-150, logicalHeight/2-100, 300, 200, "GAME OVER")
-		text.Draw(screen, fmt.Sprintf("Final Score: %d", g.score), basicfont.Face7x13, logicalWidth/2-50, logicalHeight/2, color.White)
-		g.addButton(logicalWidth/2-60, logicalHeight/2+40, 120, 40, "CLOSE", func() { g.endGame() }, hexToColor(colAccent))
-	}
+// toggleMagneticBearings switches bearing readouts between true and magnetic north
+func (g *Game) toggleMagneticBearings() {
+	g.updateSettings(func(s *core.Settings) { s.MagneticBearings = !s.MagneticBearings })
+}
 
-	// Register Buttons in UI pass
-	for _, b := range g.buttons {
-		ebitenutil.DrawRect(screen, float64(b.X), float64(b.Y), float64(b.W), float64(b.H), b.Color)
-		// Center text roughly
-		tW := len(b.Text) * 7
-		text.Draw(screen, b.Text, basicfont.Face7x13, b.X+(b.W-tW)/2, b.Y+b.H/2+4, b.TextColor)
-	}
+// cycleRegion jumps the camera to the next watch region
+func (g *Game) cycleRegion() {
+	regions := g.settings.Get().WatchRegions(myLat, myLon)
+	g.activeRegion = (g.activeRegion + 1) % len(regions)
+	r := regions[g.activeRegion]
+	g.camLat, g.camLon = r.Lat, r.Lon
+}
 
-	ebitenutil.DebugPrint(screen, fmt.Sprintf("FPS: %0.2f", ebiten.ActualFPS()))
+// applyHomeAirport makes the airport chosen in settings, or else the major
+// airport nearest home, the reference for inbound questions, arrivals and
+// the weather
+func (g *Game) applyHomeAirport() {
+	core.SetHomeAirport(core.FindHomeAirport(g.airportDB, myLat, myLon, g.settings.Get().HomeAirport))
+}
+
+// updateSettings applies a change from the settings screen and persists it
+func (g *Game) updateSettings(fn func(*core.Settings)) {
+	s := g.settings.Update(fn)
+	if err := g.dataManager.SaveSettings(s); err != nil {
+		log.Println("Error saving settings:", err)
+	}
 }
 
 func (g *Game) drawPanel(screen *ebiten.Image, x, y, w, h int, title string) {
 	// Background
 	ebitenutil.DrawRect(screen, float64(x), float64(y), float64(w), float64(h), hexToColor(colGlass))
 	// Title
-	text.Draw(screen, title, basicfont.Face7x13, x+20, y+30, hexToColor(colAccent))
+	text.Draw(screen, title, basicfont.Face7x13, x+lay("panel_pad", 20), y+lay("panel_title_y", 30), hexToColor(colAccent))
 }
 
 func (g *Game) addButton(x, y, w, h int, label string, action func(), col color.Color, txtCol ...color.Color) {
@@ -977,121 +3224,473 @@ func (g *Game) addButton(x, y, w, h int, label string, action func(), col color.
 	if len(txtCol) > 0 {
 		textColor = txtCol[0]
 	}
-	g.buttons = append(g.buttons, Button{X: x, Y: y, W: w, H: h, Text: label, Action: action, Color: col, TextColor: textColor})
+	g.buttons = append(g.buttons, Button{ID: core.WidgetID(label, x, y), X: x, Y: y, W: w, H: h, Text: label, Action: action, Color: col, TextColor: textColor})
 }
 
 func (g *Game) startGame() {
-	if len(g.flights) == 0 {
+	if g.flights.Len() == 0 {
 		return
 	}
 	g.score = 0
 	g.round = 0
+	g.replay = nil
+	g.gameSeed = core.NewGameSeed()
+	g.roundLog = nil
+	g.shareCode = ""
+	g.bonusTarget, g.bonusDetails, g.bonusResult = "", nil, ""
+	g.partyGame = g.party.Players() > 0
+	g.party.NewGame()
 	g.nextRound()
 }
 
-func (g *Game) endGame() {
-	// Save stats only if round > 0 and user played
-	if g.round > 0 {
-		u, err := g.dataManager.SaveUser(g.currentUser.Name, g.score)
-		if err == nil {
-			g.currentUser = u      // update local ref
-			g.usersMap[u.Name] = u // update list ref
-		} else {
-			log.Println("Error saving user:", err)
+// openReplayEntry shows the share code prompt
+func (g *Game) openReplayEntry() {
+	g.inputText = ""
+	g.replayError = ""
+	g.isKeyboardOpen = true
+	g.state = StateReplayEntry
+}
+
+// submitReplayCode looks up the game behind a share code and starts replaying it
+func (g *Game) submitReplayCode(code string) {
+	info, err := core.ParseShareCode(code)
+	if err != nil {
+		g.replayError = "Invalid code"
+		return
+	}
+	rec, err := g.dataManager.FindGame(info.Date, info.Seed)
+	if err != nil {
+		log.Println("Replay lookup failed:", err)
+		g.replayError = "Game not found on this device"
+		return
+	}
+
+	g.isKeyboardOpen = false
+	g.replay = &rec
+	g.replayScore = info.Score
+	g.score = 0
+	g.round = 0
+	g.nextRound()
+}
+
+// setupReplayRound poses the logged question for the current round again
+func (g *Game) setupReplayRound() {
+	if g.round > len(g.replay.Rounds) {
+		g.state = StateGameOver
+		return
+	}
+	r := g.replay.Rounds[g.round-1]
+
+	g.showResult = false
+	g.wrongGuess = ""
+	// The aircraft has long since landed; stand in a copy where it was
+	g.targetPlane = &core.Flight{Callsign: r.Callsign, Lat: r.Lat, Lon: r.Lon}
+	g.deselectPlane()
+	g.resolvedDetails = nil
+	g.resolving = false
+	g.camLat, g.camLon = r.Lat, r.Lon
+
+	g.typeRound = r.Hint != ""
+	g.typeHint = r.Hint
+	g.questionText = r.Question
+	g.correctOption = r.Correct
+	g.options = append([]string(nil), r.Options...)
+	g.roundStartTime = g.clock.Now()
+	g.roundTime = g.difficulty.RoundTime()
+	g.state = StateGamePlaying
+	if g.partyGame {
+		g.party.Ask(g.round, g.questionText, g.options, g.roundTime)
+	}
+}
+
+// finishGame logs a completed game so it can be replayed from its share code
+func (g *Game) finishGame() {
+	g.celebration, g.podium = "", nil
+	if g.replay != nil || g.partyGame {
+		return
+	}
+	g.celebrate(g.users.Current(), g.score)
+	rec := core.GameRecord{
+		Date:   time.Now().Format("2006-01-02"),
+		Seed:   g.gameSeed,
+		Player: g.users.Current().Name,
+		Score:  g.score,
+		Rounds: g.roundLog,
+	}
+	if err := g.dataManager.SaveGame(rec); err != nil {
+		log.Println("Error saving game log:", err)
+		return
+	}
+	g.scoreSync.Submit(rec)
+	code, err := core.ShareCode(rec)
+	if err != nil {
+		log.Println("Error creating share code:", err)
+		return
+	}
+	g.shareCode = code
+}
+
+// celebrate places the finished game on the podium and throws confetti if
+// it set a new best. Runs before the game is saved, so the leaderboard and
+// u are still as they were before it.
+func (g *Game) celebrate(u core.UserStats, score int) {
+	top, _, err := g.dataManager.GetLeaderboard()
+	if err != nil {
+		log.Println("Error loading leaderboard:", err)
+		return
+	}
+	g.podium, g.podiumPlace = core.Podium(top, core.ScoreEntry{Name: u.Name, Score: score}, 3)
+
+	kind, ok := core.NewBest(score, u.BestScore, top)
+	if !ok {
+		return
+	}
+	g.celebration = kind
+	g.particles.Confetti(logicalWidth/2, logicalHeight/2-100, 120)
+	err = g.dataManager.RecordAchievement(core.Achievement{
+		Kind:   kind,
+		Player: u.Name,
+		Score:  score,
+		Date:   time.Now().Format("2006-01-02"),
+	})
+	if err != nil {
+		log.Println("Error recording achievement:", err)
+	}
+}
+
+// drawPodium shows the top three with the finished game highlighted, second
+// place on the left and third on the right
+func (g *Game) drawPodium(screen *ebiten.Image, y int) {
+	if g.celebration != "" {
+		msg := "NEW PERSONAL BEST!"
+		if g.celebration == core.AchievementAllTimeBest {
+			msg = "NEW ALL-TIME BEST!"
+		}
+		text.Draw(screen, msg, basicfont.Face7x13, logicalWidth/2-len(msg)*7/2, y+8, hexToColor(colGold))
+	}
+
+	const blockW, base = 70, 100
+	heights := []int{50, 36, 24}
+	xs := []int{logicalWidth/2 - blockW/2, logicalWidth/2 - blockW*3/2 - 4, logicalWidth/2 + blockW/2 + 4}
+	pulse := 0.5 + 0.5*math.Sin(float64(time.Now().UnixMilli())/150)
+	for i, e := range g.podium {
+		x, h := xs[i], heights[i]
+		col := hexToColor(0xffffff30)
+		if i == g.podiumPlace {
+			c := hexToColor(colGold).(color.RGBA)
+			col = color.NRGBA{c.R, c.G, c.B, uint8(150 + 105*pulse)}
 		}
+		ebitenutil.DrawRect(screen, float64(x), float64(y+base-h), blockW, float64(h), col)
+		text.Draw(screen, fmt.Sprint(i+1), basicfont.Face7x13, x+blockW/2-3, y+base-h+16, color.White)
+		text.Draw(screen, truncate(e.Name, 9), basicfont.Face7x13, x+2, y+base-h-18, color.White)
+		text.Draw(screen, fmt.Sprint(e.Score), basicfont.Face7x13, x+2, y+base-h-4, hexToColor(colTextMuted))
+	}
+}
 
-		_, err = g.dataManager.AddScore(ScoreEntry{
-			Name:  g.currentUser.Name,
-			Score: g.score,
-			Date:  time.Now().Format("2006-01-02"),
-		})
+func (g *Game) endGame() {
+	// Save stats only if round > 0 and user played
+	if g.round > 0 && !g.partyGame {
+		u, err := g.dataManager.FinishGame(g.users.Current().Name, g.score, g.difficulty, time.Now())
 		if err != nil {
-			log.Println("Error saving score:", err)
+			log.Println("Error saving game:", err)
+		}
+		if u.Name != "" {
+			g.users.Put(u) // updates current user too
 		}
 	}
 
 	g.state = StateMap
-	g.selectedPlane = nil
+	g.cancelRoundSetup()
+	g.deselectPlane()
+	g.replay = nil
+	g.partyGame = false
 }
 
 func (g *Game) nextRound() {
 	g.round++
-	if g.round > 5 {
+	if g.round > core.RoundsPerGame {
+		if g.startArrivalBonus() {
+			return
+		}
+		g.finishGame()
 		g.state = StateGameOver
 		return
 	}
+	if g.replay != nil {
+		g.setupReplayRound()
+		return
+	}
 
 	g.pickNewTarget()
 }
 
+// startArrivalBonus asks how long one of the game's flights has left before
+// it lands, when the bonus round is on and such a flight is still in range
+func (g *Game) startArrivalBonus() bool {
+	if g.replay != nil || g.partyGame || !g.settings.Get().ArrivalBonus || g.bonusTarget == "" {
+		return false
+	}
+	f := g.flights.Get(g.bonusTarget)
+	if f == nil {
+		return false
+	}
+	if _, ok := core.TimeToArrival(*f, g.bonusDetails, time.Now()); !ok {
+		return false
+	}
+	g.bonusGuess = 30
+	g.cancelSelection()
+	g.selectedPlane = f
+	g.resolvedDetails, g.resolving = g.bonusDetails, false
+	g.camLat, g.camLon = f.Lat, f.Lon
+	g.state = StateArrivalBonus
+	return true
+}
+
+// stepArrivalGuess nudges the guess by minutes, keeping it sensible
+func (g *Game) stepArrivalGuess(minutes int) {
+	g.bonusGuess = min(max(g.bonusGuess+minutes, 1), 360)
+}
+
+// lockArrivalGuess scores the guess against the flight's latest position
+// and ends the game
+func (g *Game) lockArrivalGuess() {
+	g.bonusResult = "Bonus: flight lost from view"
+	if f := g.flights.Get(g.bonusTarget); f != nil {
+		if eta, ok := core.TimeToArrival(*f, g.bonusDetails, time.Now()); ok {
+			points := core.ArrivalBonusScore(g.bonusGuess, eta)
+			g.score += points
+			g.bonusResult = fmt.Sprintf("Bonus: %.0f min to go, +%d", eta.Minutes(), points)
+		}
+	}
+	g.finishGame()
+	g.state = StateGameOver
+}
+
+// startOddOneOut deals a puzzle from the flights being tracked
+func (g *Game) startOddOneOut() {
+	g.odd, g.oddErr = core.NewOddOneOut(g.flights.Snapshot())
+	g.oddPick = -1
+	g.deselectPlane()
+	g.state = StateOddOneOut
+}
+
+// answerOddOneOut takes the player's pick; later taps are ignored until
+// the next puzzle
+func (g *Game) answerOddOneOut(i int) {
+	if g.oddPick >= 0 {
+		return
+	}
+	g.oddPick = i
+	g.oddTried++
+	if g.odd.Answer(i) {
+		g.oddRight++
+	}
+}
+
+func (g *Game) closeOddOneOut() {
+	g.oddRight, g.oddTried = 0, 0
+	g.deselectPlane()
+	g.state = StateMap
+}
+
+// inOddOneOut reports whether the flight is one of the puzzle's four
+func (g *Game) inOddOneOut(icao24 string) bool {
+	if g.state != StateOddOneOut || g.oddErr != nil {
+		return false
+	}
+	for _, f := range g.odd.Flights {
+		if f.Icao24 == icao24 {
+			return true
+		}
+	}
+	return false
+}
+
+// drawOddOneOut is the puzzle panel: the question, the four callsigns to
+// pick from and, once answered, why the odd one is odd
+func (g *Game) drawOddOneOut(screen *ebiten.Image) {
+	title := "ODD ONE OUT"
+	if g.oddTried > 0 {
+		title += fmt.Sprintf("  %d/%d", g.oddRight, g.oddTried)
+	}
+	g.drawPanel(screen, 20, 90, 220, 340, title)
+	if g.oddErr != nil {
+		for i, line := range wrapWords("No puzzle right now: "+g.oddErr.Error(), 28) {
+			text.Draw(screen, line, basicfont.Face7x13, 30, 140+i*18, hexToColor(colTextMuted))
+		}
+		g.addButton(30, 395, 95, 30, "TRY AGAIN", g.startOddOneOut, hexToColor(colAccent))
+		g.addButton(135, 395, 95, 30, "CLOSE", g.closeOddOneOut, hexToColor(colGlass))
+		return
+	}
+
+	for i, line := range wrapWords(g.odd.Prompt(), 28) {
+		text.Draw(screen, line, basicfont.Face7x13, 30, 140+i*18, color.White)
+	}
+	y := 180
+	for i, f := range g.odd.Flights {
+		col := hexToColor(0xffffff20)
+		if g.oddPick >= 0 {
+			if g.odd.Answer(i) {
+				col = hexToColor(colSuccess)
+			} else if i == g.oddPick {
+				col = hexToColor(colDanger)
+			}
+		}
+		g.addButton(30, y, 200, 40, f.Callsign, func() { g.answerOddOneOut(i) }, col, color.Black)
+		y += 45
+	}
+	if g.oddPick < 0 {
+		text.Draw(screen, "Tap a plane for its details", basicfont.Face7x13, 30, 372, hexToColor(colTextMuted))
+		g.addButton(135, 395, 95, 30, "CLOSE", g.closeOddOneOut, hexToColor(colGlass))
+		return
+	}
+	for i, line := range wrapWords(g.odd.Explain(), 28) {
+		if i < 2 {
+			text.Draw(screen, line, basicfont.Face7x13, 30, 372+i*16, hexToColor(colAccent))
+		}
+	}
+	g.addButton(30, 395, 95, 30, "NEXT", g.startOddOneOut, hexToColor(colAccent))
+	g.addButton(135, 395, 95, 30, "CLOSE", g.closeOddOneOut, hexToColor(colGlass))
+}
+
 func (g *Game) pickNewTarget() {
+	if g.ctx.Err() != nil {
+		return // quitting
+	}
+	ctx, seq := g.newRoundSetup()
 	g.state = StateRoundSetup
 	g.showResult = false
 	g.wrongGuess = ""
 
-	if len(g.flights) == 0 {
-		// No flights, wait and retry?
-		// For simplicity, let's just reset state or wait.
-		// Since this is async, we can just re-schedule.
-		// But g.flights is updated by another goroutine.
-		// Let's just retry in 1 sec.
-		time.AfterFunc(1*time.Second, g.pickNewTarget)
+	candidates := core.QuizCandidates(g.flights.Snapshot(), g.resolver)
+	if len(candidates) == 0 {
+		// Wait for some to fly in
+		g.retryRound(ctx, seq)
 		return
 	}
 
-	idx := rand.Intn(len(g.flights))
-	g.targetPlane = &g.flights[idx]
-
-	g.camLat = g.targetPlane.Lat
-	g.camLon = g.targetPlane.Lon
-
+	pick := candidates[rand.Intn(len(candidates))]
+	g.targetPlane = g.flights.Get(pick.Icao24)
+	if g.targetPlane == nil {
+		// Expired since the snapshot was taken
+		g.retryRound(ctx, seq)
+		return
+	}
+	g.camLat = pick.Lat
+	g.camLon = pick.Lon
+	g.cancelSelection()
 	g.selectedPlane = g.targetPlane
 	g.resolvedDetails = nil
 	g.resolving = true
 
 	go func() {
-		details, err := g.scraper.FetchFlightDetails(g.targetPlane.Callsign)
-
-		if err == nil && details != nil {
-			g.setupRoundWithData(details)
-		} else {
+		details, err := core.ResolveFlight(ctx, g.resolver, pick)
+		if ctx.Err() != nil {
+			return // the game moved on meanwhile
+		}
+		if err != nil || details == nil {
 			log.Println("Scrape failed, trying new target:", err)
-			g.pickNewTarget()
+			details = nil
 		}
+		g.deliverRound(ctx, roundResult{seq: seq, details: details})
 	}()
 }
 
-func (g *Game) setupRoundWithData(details *ResolvedDetails) {
-	g.resolvedDetails = details
-	g.resolving = false
+// roundResult is the lookup for a round's target, handed back to the UI
+// goroutine; nil details mean another target is to be picked. seq tells it
+// apart from lookups for earlier round setups.
+type roundResult struct {
+	seq     int
+	details *core.ResolvedDetails
+}
 
-	// Validate Data - must not be Unknown or empty
-	if details.RealDestination == "" || details.RealDestination == "Unknown" ||
-		details.Origin == "" || details.Origin == "Unknown" {
-		log.Println("Invalid data (Unknown), trying new target")
-		g.pickNewTarget()
-		return
+// newRoundSetup abandons any earlier round setup and starts a context for
+// the next one's lookup
+func (g *Game) newRoundSetup() (context.Context, int) {
+	g.cancelRoundSetup()
+	ctx, cancel := context.WithCancel(g.ctx)
+	g.roundCancel = cancel
+	return ctx, g.roundSeq
+}
+
+// cancelRoundSetup stops the lookup for the next round's target
+func (g *Game) cancelRoundSetup() {
+	if g.roundCancel != nil {
+		g.roundCancel()
+		g.roundCancel = nil
 	}
+	g.roundSeq++
+}
+
+// retryRound has another target picked in a second
+func (g *Game) retryRound(ctx context.Context, seq int) {
+	go func() {
+		select {
+		case <-time.After(time.Second):
+			g.deliverRound(ctx, roundResult{seq: seq})
+		case <-ctx.Done():
+		}
+	}()
+}
 
-	g.dataManager.SaveAirport(details.RealDestination)
-	g.dataManager.SaveAirport(details.Origin)
+// deliverRound passes r to the UI goroutine, or drops it once the round
+// setup has been abandoned
+func (g *Game) deliverRound(ctx context.Context, r roundResult) {
+	select {
+	case g.roundResults <- r:
+	case <-ctx.Done():
+	}
+}
 
-	origin := details.Origin
-	dest := details.RealDestination
+// applyRoundSetup starts the round once its target's lookup is in
+func (g *Game) applyRoundSetup() {
+	for len(g.roundResults) > 0 {
+		r := <-g.roundResults
+		if r.seq != g.roundSeq || g.state != StateRoundSetup {
+			continue
+		}
+		if r.details == nil {
+			g.pickNewTarget()
+		} else {
+			g.setupRoundWithData(r.details)
+		}
+	}
+}
 
-	isInbound := strings.Contains(dest, "Helsinki") || strings.Contains(dest, "Vantaa")
+func (g *Game) setupRoundWithData(details *core.ResolvedDetails) {
+	g.resolvedDetails = details
+	g.resolving = false
+	g.refreshAirports()
 
-	if isInbound {
-		g.questionText = fmt.Sprintf("Where is %s from?", g.targetPlane.Callsign)
-		g.correctOption = origin
-	} else {
-		g.questionText = fmt.Sprintf("Where is %s going?", g.targetPlane.Callsign)
-		g.correctOption = dest
+	// Now and then quiz the type or the airline instead of the route
+	q, err := core.SetupRound(g.dataManager, g.airportDB, *g.targetPlane, details, core.RoundConfig{
+		Airports: g.airports,
+		Level:    g.difficulty.Level,
+		Kind:     core.PickRoundKind(true),
+		Strategy: core.DistractorStrategyFor(g.users.Current().Name),
+	})
+	if err != nil {
+		log.Println("Unusable details, trying new target:", err)
+		g.pickNewTarget()
+		return
+	}
+	if _, ok := core.TimeToArrival(*g.targetPlane, details, time.Now()); ok {
+		g.bonusTarget, g.bonusDetails = g.targetPlane.Icao24, details
+		g.bonusName = core.QuizName(*g.targetPlane, details)
 	}
 
-	g.generateOptions()
-	g.roundStartTime = time.Now()
+	g.typeRound = q.IsTypeRound()
+	g.typeHint = q.Hint
+	g.strategy = q.Strategy
+	g.questionText = q.Text
+	g.correctOption = q.Correct
+	g.options = q.Options
+	g.roundStartTime = g.clock.Now()
+	g.roundTime = g.difficulty.RoundTime()
 	g.state = StateGamePlaying
+	if g.partyGame {
+		g.party.Ask(g.round, g.questionText, g.options, g.roundTime)
+	}
 }
 
 func (g *Game) setupRoundFallback() {
@@ -1101,71 +3700,68 @@ func (g *Game) setupRoundFallback() {
 	g.pickNewTarget()
 }
 
-func (g *Game) generateOptions() {
-	g.refreshAirports()
-
-	distractors := make([]string, len(g.airports))
-	copy(distractors, g.airports)
-
-	rand.Shuffle(len(distractors), func(i, j int) {
-		distractors[i], distractors[j] = distractors[j], distractors[i]
-	})
+func (g *Game) guess(city string) {
+	if g.showResult {
+		return
+	}
 
-	opts := []string{g.correctOption}
-	for _, c := range distractors {
-		if len(opts) >= 4 {
-			break
-		}
-		if c != g.correctOption && c != "Unknown" {
-			opts = append(opts, c)
+	if g.partyGame {
+		// The phones did the answering; the kiosk just reveals
+		g.party.Close(g.correctOption)
+	} else {
+		g.resultCorrect = (city == g.correctOption)
+		// Base points plus a bonus for answering quickly
+		g.score += core.RoundScore(g.resultCorrect, g.clock.Since(g.roundStartTime), g.roundTime)
+		if !g.resultCorrect {
+			g.wrongGuess = city
 		}
+		g.difficulty.Record(g.resultCorrect)
 	}
-
-	// Fill if needed
-	if len(opts) < 4 {
-		fallbacks := []string{"London", "Paris", "Berlin", "Helsinki", "Tokyo", "New York"}
-		for _, c := range fallbacks {
-			if len(opts) >= 4 {
-				break
-			}
-			exists := false
-			for _, o := range opts {
-				if o == c {
-					exists = true
-					break
-				}
-			}
-			if !exists {
-				opts = append(opts, c)
-			}
+	if g.replay == nil && g.targetPlane != nil {
+		r := core.RoundRecord{
+			Callsign: g.targetPlane.Callsign,
+			Lat:      g.targetPlane.Lat,
+			Lon:      g.targetPlane.Lon,
+			Question: g.questionText,
+			Correct:  g.correctOption,
+			Options:  append([]string(nil), g.options...),
+		}
+		if g.typeRound {
+			r.Hint = g.typeHint
 		}
+		if !g.partyGame {
+			r.Strategy, r.Right = g.strategy, g.resultCorrect
+		}
+		g.roundLog = append(g.roundLog, r)
 	}
-
-	rand.Shuffle(len(opts), func(i, j int) {
-		opts[i], opts[j] = opts[j], opts[i]
-	})
-	g.options = opts
+	g.showResult = true
+	g.resultStartTime = g.clock.Now()
 }
 
-func (g *Game) guess(city string) {
-	if g.showResult {
-		return
+// drawTrendArrow draws a small up or down arrow centred on (x, y) for a
+// climbing or descending flight
+func drawTrendArrow(screen *ebiten.Image, x, y float32, trend core.VerticalTrend) {
+	dir, col := float32(-1), hexToColor(colSuccess)
+	if trend == core.TrendDescending {
+		dir, col = 1, hexToColor(colGold)
 	}
+	tip := y + 5*dir
+	vector.StrokeLine(screen, x, y-5*dir, x, tip, 1.5, col, true)
+	vector.StrokeLine(screen, x-3, tip-3*dir, x, tip, 1.5, col, true)
+	vector.StrokeLine(screen, x+3, tip-3*dir, x, tip, 1.5, col, true)
+}
 
-	g.resultCorrect = (city == g.correctOption)
-	if g.resultCorrect {
-		// Time bonus
-		elapsed := time.Since(g.roundStartTime).Seconds()
-		bonus := int(math.Max(0, (20.0-elapsed)/20.0*100.0))
-		g.score += 100 + bonus
-	} else {
-		g.wrongGuess = city
+func abs(n int) int {
+	if n < 0 {
+		return -n
 	}
-	g.showResult = true
-	g.resultStartTime = time.Now()
+	return n
 }
 
 func hexToColor(hex uint32) color.Color {
+	if name, ok := colorNames[hex]; ok {
+		hex = devLayout.Color(name, hex)
+	}
 	return color.RGBA{
 		R: uint8(hex >> 24),
 		G: uint8(hex >> 16),
@@ -1174,6 +3770,26 @@ func hexToColor(hex uint32) color.Color {
 	}
 }
 
+// wrapWords breaks s into lines of at most width characters, at spaces
+func wrapWords(s string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
 func truncate(s string, max int) string {
 	if len(s) > max {
 		return s[:max] + "..."
@@ -1181,11 +3797,55 @@ func truncate(s string, max int) string {
 	return s
 }
 
+// Layout draws at the window's own size; the orientation is worked out
+// from its shape unless one was set
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
-	return physicalWidth, physicalHeight
+	g.screenXf = core.NewScreenTransform(g.orientation, logicalWidth, logicalHeight, outsideWidth, outsideHeight)
+	return outsideWidth, outsideHeight
 }
 
 func main() {
+	providerName := flag.String("provider", "auto", "flight data source ("+strings.Join(core.ProviderNames(), ", ")+")")
+	lowMem := flag.Bool("lowmem", false, "constrained profile for Pi Zero class devices: small tile cache, no trails, history or effects, slower polling")
+	recordDir := flag.String("record", "", "save every raw OpenSky response into this directory")
+	replayDir := flag.String("replay", "", "play back a directory saved with -record instead of fetching live")
+	replaySpeed := flag.Float64("replay-speed", 1, "how many times faster than real time -replay plays")
+	importDir := flag.String("import-openflights", "", "import airports.dat and routes.dat from this directory into the airport database, then exit")
+	restore := flag.Bool("restore-backup", false, "download the backup from BACKUP_URL into a data directory without player data, then exit")
+	orientationName := flag.String("orientation", core.DefaultOrientation(), "auto, landscape, portrait, landscape-flipped or portrait-flipped; auto turns the UI for a display taller than it is wide")
+	flag.Parse()
+
+	orientation, err := core.ParseOrientation(*orientationName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("Flight Monitor", core.Build())
+
+	if *importDir != "" {
+		r, err := (&core.DataManager{}).ImportOpenFlights(*importDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Println("OpenFlights:", r)
+		return
+	}
+	if *restore {
+		b, err := core.NewBackup()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if b == nil {
+			log.Fatal("Set BACKUP_URL to restore from")
+		}
+		n, err := b.Restore(context.Background())
+		if err != nil {
+			log.Fatal("Restore failed: ", err)
+		}
+		log.Printf("Restored %d files", n)
+		return
+	}
+
 	if l := os.Getenv("MY_LAT"); l != "" {
 		if v, err := strconv.ParseFloat(l, 64); err == nil {
 			myLat = v
@@ -1197,20 +3857,61 @@ func main() {
 		}
 	}
 
-	// Initialize flight client with auth and caching
-	client := NewFlightClient()
+	// Initialize the selected flight provider
+	var provider core.FlightProvider
+	if *replayDir != "" {
+		var replay *core.ReplayProvider
+		replay, err = core.NewReplayProvider(*replayDir, *replaySpeed)
+		if err == nil {
+			from, to := replay.Span()
+			log.Printf("Replaying %s to %s at %gx", from.Local().Format(time.DateTime), to.Local().Format(time.DateTime), *replaySpeed)
+			provider = replay
+		}
+	} else {
+		provider, err = core.NewProvider(*providerName)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Using flight provider:", provider.Name())
+	if *recordDir != "" {
+		if err := core.RecordResponses(provider, *recordDir); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("Recording raw responses to", *recordDir)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	// Start the Game
-	game := NewGame(client)
-	ebiten.SetWindowSize(physicalWidth, physicalHeight)
-	ebiten.SetWindowTitle("Flight Monitor (Rotated)")
+	profile := core.StandardProfile
+	if *lowMem {
+		profile = core.LowMemoryProfile
+	}
+	log.Println("Resource profile:", profile.Name)
+	game := NewGame(ctx, provider, profile)
+	game.orientation = orientation
+	if m := ebiten.Monitor(); m != nil {
+		orientation = orientation.For(m.Size())
+	}
+	log.Println("Orientation:", orientation)
+	ebiten.SetWindowSize(orientation.For(windowWidth, windowHeight).WindowSize(windowWidth, windowHeight))
+	ebiten.SetWindowTitle("Flight Monitor")
 
-	ebiten.SetTPS(24)
+	ebiten.SetTPS(normalTPS)
 	if runtime.GOOS != "darwin" {
 		ebiten.SetFullscreen(true)
 	}
 
-	if err := ebiten.RunGame(game); err != nil {
+	err = ebiten.RunGame(game)
+
+	// RunGame returns on Termination or window close; stop polling, scrapes
+	// and tile fetches so the process exits cleanly
+	cancel()
+	game.shutdown()
+
+	if err != nil {
 		log.Fatal(err)
 	}
 }
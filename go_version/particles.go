@@ -0,0 +1,29 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// particleRenderer draws core particles onto an ebiten image
+type particleRenderer struct {
+	screen *ebiten.Image
+}
+
+// Rect draws the rectangle as a line h pixels thick, which rotates for free
+func (r particleRenderer) Rect(x, y, w, h, angle float64, col uint32, alpha float64) {
+	dx, dy := math.Cos(angle)*w/2, math.Sin(angle)*w/2
+	vector.StrokeLine(r.screen, float32(x-dx), float32(y-dy), float32(x+dx), float32(y+dy), float32(h), fadeColor(col, alpha), true)
+}
+
+func (r particleRenderer) Ring(x, y, radius float64, col uint32, alpha float64) {
+	vector.StrokeCircle(r.screen, float32(x), float32(y), float32(radius), 2, fadeColor(col, alpha), true)
+}
+
+// fadeColor turns a palette colour into one with its alpha scaled
+func fadeColor(hex uint32, alpha float64) color.Color {
+	return color.NRGBA{R: uint8(hex >> 24), G: uint8(hex >> 16), B: uint8(hex >> 8), A: uint8(float64(uint8(hex)) * alpha)}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"golang.org/x/image/font/basicfont"
+
+	"flight-monitor/core"
+)
+
+// Uploaded images kept for uiRenderer.DrawTexture; past this many the cache
+// starts over
+const maxUITextures = 32
+
+// uiTextures are the images shared UI has drawn, uploaded. Only touched
+// from Draw.
+var uiTextures = make(map[image.Image]*ebiten.Image)
+
+// uiRenderer draws shared core UI onto an ebiten image. There is one
+// bitmap font, so every core.TextSize comes out the same.
+type uiRenderer struct {
+	screen *ebiten.Image
+}
+
+func (r *uiRenderer) DrawRect(x, y, w, h int, col uint32) {
+	ebitenutil.DrawRect(r.screen, float64(x), float64(y), float64(w), float64(h), hexToColor(col))
+}
+
+func (r *uiRenderer) DrawTexture(img image.Image, x, y, w, h int) {
+	tex, ok := uiTextures[img]
+	if !ok {
+		if len(uiTextures) >= maxUITextures {
+			for k, t := range uiTextures {
+				t.Deallocate()
+				delete(uiTextures, k)
+			}
+		}
+		tex = ebiten.NewImageFromImage(img)
+		uiTextures[img] = tex
+	}
+	b := tex.Bounds()
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+	op.GeoM.Scale(float64(w)/float64(b.Dx()), float64(h)/float64(b.Dy()))
+	op.GeoM.Translate(float64(x), float64(y))
+	r.screen.DrawImage(tex, op)
+}
+
+func (r *uiRenderer) DrawText(s string, x, y int, size core.TextSize, col uint32) {
+	text.Draw(r.screen, s, basicfont.Face7x13, x, y+basicfont.Face7x13.Metrics().Ascent.Ceil(), hexToColor(col))
+}
+
+func (r *uiRenderer) Measure(s string, size core.TextSize) (int, int) {
+	return len(s) * 7, 13
+}
+
+// BeginClip draws into a sub-image, which keeps the screen's coordinates
+func (r *uiRenderer) BeginClip(x, y, w, h int) func() {
+	prev := r.screen
+	r.screen = prev.SubImage(image.Rect(x, y, x+w, y+h)).(*ebiten.Image)
+	return func() { r.screen = prev }
+}
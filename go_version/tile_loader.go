@@ -1,12 +1,18 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"image"
+	"image/draw"
 	"net/http"
+	"slices"
 	"sync"
 
+	"flight-monitor/core"
 	"github.com/hajimehoshi/ebiten/v2"
+	xdraw "golang.org/x/image/draw"
 )
 
 type TileKey struct {
@@ -15,60 +21,179 @@ type TileKey struct {
 
 type TileLoader struct {
 	cache      map[TileKey]*ebiten.Image
+	lastUsed   map[TileKey]uint64 // value of uses when each tile was last asked for
+	uses       uint64
+	queue      *core.TileQueue
 	mutex      sync.Mutex
 	httpClient *http.Client
+	archive    *core.MBTiles // offline tiles tried before the network; guarded by mutex
+
+	maxTiles   int // cached tiles beyond this evict the least recently used
+	resolution int // tiles are downscaled to this many pixels per side
 }
 
-func NewTileLoader() *TileLoader {
-	return &TileLoader{
+func NewTileLoader(ctx context.Context, maxTiles, resolution, fetches int) *TileLoader {
+	tl := &TileLoader{
 		cache:      make(map[TileKey]*ebiten.Image),
+		lastUsed:   make(map[TileKey]uint64),
 		httpClient: &http.Client{},
+		maxTiles:   maxTiles,
+		resolution: resolution,
 	}
+	tl.queue = core.NewTileQueue(ctx, fetches, tl.fetchTile)
+	return tl
 }
 
 func (tl *TileLoader) GetTile(z, x, y int) *ebiten.Image {
 	key := TileKey{z, x, y}
 
 	tl.mutex.Lock()
+	tl.uses++
 	if img, ok := tl.cache[key]; ok {
+		tl.lastUsed[key] = tl.uses
 		tl.mutex.Unlock()
 		return img
 	}
 	tl.mutex.Unlock()
 
 	// If not in cache, return nil (or a placeholder) and fetch in background
-	go tl.fetchTile(z, x, y)
+	tl.queue.Add(core.TileCoord{Z: z, X: x, Y: y}, true)
 	return nil
 }
 
-func (tl *TileLoader) fetchTile(z, x, y int) {
-	// Check cache again before fetching
-	tl.mutex.Lock()
-	if _, ok := tl.cache[TileKey{z, x, y}]; ok {
+// Prefetch queues tiles that aren't drawn yet but soon may be, behind those
+// on screen, and counts those already loaded as used, so they stay cached.
+// Fetches for tiles in neither tiles nor near, the tiles in and around the
+// view, are dropped: the view has moved away from them.
+func (tl *TileLoader) Prefetch(near, tiles []core.TileCoord) {
+	keep := make(map[core.TileCoord]bool, len(near)+len(tiles))
+	for _, t := range slices.Concat(near, tiles) {
+		keep[t] = true
+	}
+	tl.queue.Retain(func(t core.TileCoord) bool { return keep[t] })
+
+	for _, t := range tiles {
+		key := TileKey{t.Z, t.X, t.Y}
+		tl.mutex.Lock()
+		_, ok := tl.cache[key]
+		if ok {
+			tl.lastUsed[key] = tl.uses
+		}
 		tl.mutex.Unlock()
-		return
+		if !ok {
+			tl.queue.Add(t, false)
+		}
+	}
+}
+
+// Fetching is the number of tiles waiting to download or downloading
+func (tl *TileLoader) Fetching() int {
+	return tl.queue.Len()
+}
+
+// Cached is a tile if it is already loaded, without fetching it when it
+// isn't; for placeholders drawn while another tile loads
+func (tl *TileLoader) Cached(z, x, y int) *ebiten.Image {
+	key := TileKey{z, x, y}
+
+	tl.mutex.Lock()
+	defer tl.mutex.Unlock()
+	img, ok := tl.cache[key]
+	if ok {
+		tl.lastUsed[key] = tl.uses
+	}
+	return img
+}
+
+// fetchTile loads a tile into the cache. The queue backs off from tiles
+// it returns an error for.
+func (tl *TileLoader) fetchTile(ctx context.Context, t core.TileCoord) error {
+	z, x, y := t.Z, t.X, t.Y
+	key := TileKey{z, x, y}
+
+	tl.mutex.Lock()
+	archive := tl.archive
+	tl.mutex.Unlock()
+
+	var img image.Image
+	var err error
+	if data, ok := archive.Tile(z, x, y); ok {
+		img, _, err = image.Decode(bytes.NewReader(data))
+	} else {
+		img, err = tl.download(ctx, z, x, y)
+	}
+	if err != nil {
+		if ctx.Err() == nil { // not scrolled away or quitting
+			fmt.Println("Failed to load tile:", err)
+		}
+		return err
+	}
+
+	if b := img.Bounds(); b.Dx() > tl.resolution {
+		small := image.NewRGBA(image.Rect(0, 0, tl.resolution, tl.resolution))
+		xdraw.ApproxBiLinear.Scale(small, small.Bounds(), img, b, draw.Src, nil)
+		img = small
 	}
+	ebitenImg := ebiten.NewImageFromImage(img)
+
+	tl.mutex.Lock()
+	tl.evict()
+	tl.cache[key] = ebitenImg
+	tl.lastUsed[key] = tl.uses
 	tl.mutex.Unlock()
+	return nil
+}
 
-	// CartoDB Dark Matter URL
+// download fetches a tile from the CartoDB Dark Matter server
+func (tl *TileLoader) download(ctx context.Context, z, x, y int) (image.Image, error) {
 	url := fmt.Sprintf("https://basemaps.cartocdn.com/dark_all/%d/%d/%d.png", z, x, y)
 
-	resp, err := tl.httpClient.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		fmt.Println("Failed to fetch tile:", err)
-		return
+		return nil, err
+	}
+	req.Header.Set("User-Agent", core.UserAgent())
+	resp, err := tl.httpClient.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tile %d/%d/%d: %s", z, x, y, resp.Status)
+	}
 
 	img, _, err := image.Decode(resp.Body)
-	if err != nil {
-		fmt.Println("Failed to decode tile:", err)
-		return
-	}
+	return img, err
+}
 
-	ebitenImg := ebiten.NewImageFromImage(img)
+// SetArchive makes the loader read tiles from an offline archive first,
+// going to the network only for those it doesn't have
+func (tl *TileLoader) SetArchive(m *core.MBTiles) {
+	tl.mutex.Lock()
+	defer tl.mutex.Unlock()
+	tl.archive = m
+}
 
+// Len is the number of tiles cached
+func (tl *TileLoader) Len() int {
 	tl.mutex.Lock()
-	tl.cache[TileKey{z, x, y}] = ebitenImg
-	tl.mutex.Unlock()
+	defer tl.mutex.Unlock()
+	return len(tl.cache)
+}
+
+// evict drops least recently used tiles until there is room for one more.
+// The images aren't deallocated since a frame may still be drawing them;
+// the garbage collector frees them. Callers hold the mutex.
+func (tl *TileLoader) evict() {
+	for len(tl.cache) >= tl.maxTiles {
+		var oldest TileKey
+		first := true
+		for k := range tl.cache {
+			if first || tl.lastUsed[k] < tl.lastUsed[oldest] {
+				oldest, first = k, false
+			}
+		}
+		delete(tl.cache, oldest)
+		delete(tl.lastUsed, oldest)
+	}
 }